@@ -0,0 +1,251 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mandiant/GoReSym/goresym"
+)
+
+// batchConfig is how a batch run extracts each file and where the results go
+type batchConfig struct {
+	workers int
+	// each Report is written to <sha256>.json in outDir instead of inline in its record, empty for inline
+	outDir string
+	// with -outputformat sqlite, the database each file is appended to as one transaction instead of the Report in its record
+	sqliteDB string
+	// the longest an extraction may take, the file then fails with what was recovered dropped. 0 for no limit
+	timeout     time.Duration
+	opts        goresym.Options
+	diagnostics bool // keep the Diagnostics of each Report, -diagnostics
+	timings     bool // keep the Timings of each Report, -timings
+}
+
+// batchRecord is the record of one file, keyed by its path and the sha256 of its contents. A file that can't be read has no sha256.
+type batchRecord struct {
+	Path     string
+	SHA256   string          `json:",omitempty"`
+	Output   string          `json:",omitempty"` // the file the Report was written to, with -out-dir, or the sqlite database
+	Metadata json.RawMessage `json:",omitempty"` // the Report, inline without -out-dir
+	Error    string          `json:",omitempty"`
+	Class    string          `json:",omitempty"` // of the error, see goresym.ErrorClass
+}
+
+// batchResult is what a worker hands back for one file, the Report is encoded already since it can't outlive its file
+type batchResult struct {
+	record  batchRecord
+	report  []byte
+	skipped bool
+}
+
+// batchSummary ends a batch run's output. Processed counts the files extracted, Skipped the ones that don't look like Go binaries
+// and Failed the ones that failed to read or to extract.
+type batchSummary struct {
+	Processed int
+	Skipped   int
+	Failed    int
+}
+
+// isBatchInput reports whether the argument starts a batch run: a directory to walk, or - for a list of files on stdin
+func isBatchInput(arg string) bool {
+	if arg == "-" {
+		return true
+	}
+	info, err := os.Stat(arg)
+	return err == nil && info.IsDir()
+}
+
+// batchPaths sends the regular files under the directory arg, or the paths listed one per line on stdin for -. A path that can't be
+// walked becomes a failed record right away.
+func batchPaths(arg string, paths chan<- string, results chan<- batchResult) {
+	defer close(paths)
+
+	if arg == "-" {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if path := strings.TrimSpace(scanner.Text()); len(path) > 0 {
+				paths <- path
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			results <- batchResult{record: batchRecord{Path: "-", Error: fmt.Sprintf("failed to read the file list: %s", err), Class: goresym.ErrorClass(err)}}
+		}
+		return
+	}
+
+	filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			results <- batchResult{record: batchRecord{Path: path, Error: err.Error(), Class: goresym.ErrorClass(err)}}
+			return nil
+		}
+		// links aren't followed, a link into the walked tree would extract its target twice
+		if d.Type().IsRegular() {
+			paths <- path
+		}
+		return nil
+	})
+}
+
+// batchFile extracts one file of a batch. A panic on a hostile file fails that file only.
+func batchFile(path string, config batchConfig) (result batchResult) {
+	result.record.Path = path
+	defer func() {
+		if r := recover(); r != nil {
+			result.report = nil
+			result.record.Error = fmt.Sprintf("panic: %v", r)
+			result.record.Class = "Internal"
+		}
+	}()
+
+	// the sniff reads through the hash, the rest of the file is hashed after it
+	f, err := os.Open(path)
+	if err != nil {
+		result.record.Error, result.record.Class = err.Error(), goresym.ErrorClass(err)
+		return result
+	}
+	hash := sha256.New()
+	likely, err := goresym.LikelyGo(io.TeeReader(f, hash))
+	if err == nil {
+		_, err = io.Copy(hash, f)
+	}
+	f.Close()
+	if err != nil {
+		result.record.Error, result.record.Class = err.Error(), "IOError"
+		return result
+	}
+	result.record.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	if !likely {
+		logProgress(1, "skipping %s, it doesn't look like a Go binary", path)
+		result.skipped = true
+		return result
+	}
+
+	ctx := context.Background()
+	if config.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.timeout)
+		defer cancel()
+	}
+	report, err := goresym.Extract(ctx, path, config.opts)
+	defer report.Close()
+	if err != nil {
+		result.record.Error, result.record.Class = fmt.Sprintf("Failed to parse file: %s", err), goresym.ErrorClass(err)
+		return result
+	}
+
+	if !config.diagnostics {
+		report.Diagnostics = nil
+	}
+	if config.timings {
+		serializationStart := time.Now()
+		json.Marshal(report)
+		report.Timings.AddSerialization(time.Since(serializationStart))
+	} else {
+		report.Timings = nil
+	}
+
+	// the Report reads from the mapped file, it's encoded before the file is closed
+	if len(config.sqliteDB) > 0 {
+		var script bytes.Buffer
+		if err := printSqlite(&script, path, result.record.SHA256, *report); err != nil {
+			result.record.Error, result.record.Class = fmt.Sprintf("failed to format output: %s", err), "Internal"
+		}
+		result.report = script.Bytes()
+	} else if len(config.outDir) > 0 {
+		result.report = []byte(DataToJson(report))
+	} else if result.report, err = json.Marshal(report); err != nil {
+		result.record.Error, result.record.Class = fmt.Sprintf("failed to format output: %s", err), "Internal"
+	}
+	return result
+}
+
+// runBatch extracts every file of the directory or the stdin list arg with config.workers goroutines, and writes an NDJSON record
+// per file then the summary to output: a file record with the Report, inline, as the name of its file in config.outDir or of the
+// config.sqliteDB it was appended to, or an error record. Files that don't look like Go binaries are only counted. Only writing the output can fail the run.
+func runBatch(arg string, config batchConfig, output io.Writer) error {
+	if len(config.outDir) > 0 {
+		if err := os.MkdirAll(config.outDir, 0o755); err != nil {
+			return err
+		}
+	}
+	workers := config.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	paths := make(chan string)
+	results := make(chan batchResult)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				results <- batchFile(path, config)
+			}
+		}()
+	}
+	go func() {
+		// the walk's own failures are sent before paths closes, so before the workers are done
+		batchPaths(arg, paths, results)
+		wg.Wait()
+		close(results)
+	}()
+
+	// one goroutine writes, the records of two files never interleave
+	stream := newNdjsonStream(output)
+	var summary batchSummary
+	var writeErr error
+	for result := range results {
+		if result.skipped {
+			summary.Skipped++
+			continue
+		}
+		record := result.record
+		if len(record.Error) == 0 && len(config.sqliteDB) > 0 {
+			// only this goroutine writes the database, each file is its own transaction
+			record.Output = config.sqliteDB
+			if err := runSqlite(config.sqliteDB, result.report); err != nil {
+				record.Output, record.Error, record.Class = "", fmt.Sprintf("failed to write the result: %s", err), "IOError"
+			}
+		} else if len(record.Error) == 0 && len(config.outDir) > 0 {
+			record.Output = filepath.Join(config.outDir, record.SHA256+".json")
+			if err := os.WriteFile(record.Output, result.report, 0o644); err != nil {
+				record.Output, record.Error, record.Class = "", fmt.Sprintf("failed to write the result: %s", err), "IOError"
+			}
+		} else if len(record.Error) == 0 {
+			record.Metadata = result.report
+		}
+
+		if len(record.Error) > 0 {
+			summary.Failed++
+			stream.record("error", record)
+		} else {
+			summary.Processed++
+			stream.record("file", record)
+		}
+		// a record is out as soon as its file is done, a large batch shows progress
+		if err := stream.flush(); err != nil && writeErr == nil {
+			writeErr = err
+		}
+	}
+
+	stream.record("summary", summary)
+	if err := stream.flush(); err != nil && writeErr == nil {
+		writeErr = err
+	}
+	return writeErr
+}
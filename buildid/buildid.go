@@ -35,7 +35,12 @@ func ReadFile(name string) (id string, err error) {
 		return "", err
 	}
 	defer f.Close()
+	return Read(name, f)
+}
 
+// Read reads the build ID from an archive or executable file read through r, ex: held in memory. name is for the errors.
+func Read(name string, r io.ReaderAt) (id string, err error) {
+	f := io.NewSectionReader(r, 0, 1<<62)
 	buf := make([]byte, 8)
 	if _, err := f.ReadAt(buf, 0); err != nil {
 		return "", err
@@ -107,7 +112,7 @@ func ReadFile(name string) (id string, err error) {
 // archive file, and fetch the build ID from the _buildid.o entry.
 // The _buildid.o entry is written by (*Builder).gccgoBuildIDELFFile
 // in cmd/go/internal/work/exec.go.
-func readGccgoArchive(name string, f *os.File) (string, error) {
+func readGccgoArchive(name string, f *io.SectionReader) (string, error) {
 	bad := func() (string, error) {
 		return "", &fs.PathError{Op: "parse", Path: name, Err: errBuildIDMalformed}
 	}
@@ -165,7 +170,7 @@ func readGccgoArchive(name string, f *os.File) (string, error) {
 // archive file, and fetch the build ID from the _buildid.o entry.
 // The _buildid.o entry is written by (*Builder).gccgoBuildIDXCOFFFile
 // in cmd/go/internal/work/exec.go.
-func readGccgoBigArchive(name string, f *os.File) (string, error) {
+func readGccgoBigArchive(name string, f *io.SectionReader) (string, error) {
 	bad := func() (string, error) {
 		return "", &fs.PathError{Op: "parse", Path: name, Err: errBuildIDMalformed}
 	}
@@ -265,7 +270,7 @@ var readSize = 32 * 1024 // changed for testing
 // of the text segment, which should appear near the beginning
 // of the file. This is clumsy but fairly portable. Custom locations
 // can be added for other binary types as needed, like we did for ELF.
-func readBinary(name string, f *os.File) (id string, err error) {
+func readBinary(name string, f *io.SectionReader) (id string, err error) {
 	// Read the first 32 kB of the binary file.
 	// That should be enough to find the build ID.
 	// In ELF files, the build ID is in the leading headers,
@@ -299,6 +304,17 @@ func readBinary(name string, f *os.File) (id string, err error) {
 	return readRaw(name, data)
 }
 
+// ReadMachoSlice reads the build ID of one slice of a fat Mach-O, r holds the slice alone.
+// ReadFile only sees the first slice.
+func ReadMachoSlice(name string, r io.ReaderAt) (id string, err error) {
+	data := make([]byte, readSize)
+	n, err := r.ReadAt(data, 0)
+	if n == 0 && err != nil {
+		return "", err
+	}
+	return readMacho(name, r, data[:n])
+}
+
 // readRaw finds the raw build ID stored in text segment data.
 func readRaw(name string, data []byte) (id string, err error) {
 	i := bytes.Index(data, goBuildPrefix)
@@ -344,3 +360,14 @@ func HashToString(h [32]byte) string {
 	}
 	return string(dst[:])
 }
+
+// ReadImage reads the build ID of an image already mapped in memory. Only the raw ID the linker puts at the start of the text
+// is found, an ELF image keeps its ID in a note and reads as empty.
+func ReadImage(r io.ReaderAt) (id string, err error) {
+	data := make([]byte, readSize)
+	n, err := r.ReadAt(data, 0)
+	if n == 0 && err != nil {
+		return "", err
+	}
+	return readRaw("image", data[:n])
+}
@@ -13,7 +13,6 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
-	"os"
 
 	"github.com/mandiant/GoReSym/io/fs"
 )
@@ -79,7 +78,7 @@ var elfGNUNote = []byte("GNU\x00")
 // The Go build ID is stored in a note described by an ELF PT_NOTE prog
 // header. The caller has already opened filename, to get f, and read
 // at least 4 kB out, in data.
-func readELF(name string, f *os.File, data []byte) (buildid string, err error) {
+func readELF(name string, f *io.SectionReader, data []byte) (buildid string, err error) {
 	// Assume the note content is in the data, already read.
 	// Rewrite the ELF header to set shnum to 0, so that we can pass
 	// Rewrite the ELF header to set shoff and shnum to 0, so that we can pass
@@ -181,7 +180,7 @@ func readELF(name string, f *os.File, data []byte) (buildid string, err error) {
 // The caller has already opened filename, to get f, and read a few kB out, in data.
 // Sadly, that's not guaranteed to hold the note, because there is an arbitrary amount
 // of other junk placed in the file ahead of the main text.
-func readMacho(name string, f *os.File, data []byte) (buildid string, err error) {
+func readMacho(name string, f io.ReaderAt, data []byte) (buildid string, err error) {
 	// If the data we want has already been read, don't worry about Mach-O parsing.
 	// This is both an optimization and a hedge against the Mach-O parsing failing
 	// in the future due to, for example, the name of the __text section changing.
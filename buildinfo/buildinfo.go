@@ -16,8 +16,6 @@ package buildinfo
 import (
 	"bytes"
 	"debug/elf"
-	"debug/macho"
-	"debug/pe"
 	"debug/plan9obj"
 	"encoding/binary"
 	"errors"
@@ -25,7 +23,12 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"regexp"
+	"strings"
 
+	"github.com/mandiant/GoReSym/debug/macho"
+	"github.com/mandiant/GoReSym/debug/pe"
+	"github.com/mandiant/GoReSym/debug/wasm"
 	"github.com/mandiant/GoReSym/runtime/debug"
 	"github.com/mandiant/GoReSym/saferio"
 	"github.com/mandiant/GoReSym/xcoff"
@@ -81,7 +84,7 @@ func Read(r io.ReaderAt) (*BuildInfo, error) {
 	if err != nil {
 		return nil, err
 	}
-	bi, err := debug.ParseBuildInfo(mod)
+	bi, err := parseModInfo(mod)
 	if err != nil {
 		return nil, err
 	}
@@ -89,6 +92,52 @@ func Read(r io.ReaderAt) (*BuildInfo, error) {
 	return bi, nil
 }
 
+// parseModInfo parses the module info line by line when it doesn't parse whole, skipping the lines that don't, so a blob partly
+// stripped or overwritten still gives the rest
+func parseModInfo(mod string) (*BuildInfo, error) {
+	bi, err := debug.ParseBuildInfo(mod)
+	if err == nil {
+		return bi, nil
+	}
+
+	bi = new(BuildInfo)
+	var last *debug.Module
+	for _, line := range strings.SplitAfter(mod, "\n") {
+		// like ParseBuildInfo, a line without its newline is cut off
+		if !strings.HasSuffix(line, "\n") {
+			break
+		}
+		// a replacement only parses after the module it replaces
+		if strings.HasPrefix(line, "=>\t") {
+			if last != nil {
+				if parsed, err := debug.ParseBuildInfo("dep\t" + last.Path + "\t" + last.Version + "\n" + line); err == nil && len(parsed.Deps) == 1 {
+					last.Replace = parsed.Deps[0].Replace
+				}
+			}
+			last = nil
+			continue
+		}
+
+		parsed, err := debug.ParseBuildInfo(line)
+		last = nil
+		if err != nil {
+			continue
+		}
+		switch {
+		case len(parsed.Path) > 0:
+			bi.Path = parsed.Path
+		case parsed.Main != (debug.Module{}):
+			bi.Main = parsed.Main
+			last = &bi.Main
+		case len(parsed.Deps) > 0:
+			bi.Deps = append(bi.Deps, parsed.Deps...)
+			last = parsed.Deps[0]
+		}
+		bi.Settings = append(bi.Settings, parsed.Settings...)
+	}
+	return bi, nil
+}
+
 type exe interface {
 	// ReadData reads and returns up to size bytes starting at virtual address addr.
 	ReadData(addr, size uint64) ([]byte, error)
@@ -115,7 +164,10 @@ func readRawBuildInfo(r io.ReaderAt) (vers, mod string, err error) {
 	case bytes.HasPrefix(ident, []byte("\x7FELF")):
 		f, err := elf.NewFile(r)
 		if err != nil {
-			return "", "", errUnrecognizedFormat
+			// a bogus section header table, the program headers are enough
+			if f, err = elf.NewFile(withoutSectionHeaders(r, ident)); err != nil {
+				return "", "", errUnrecognizedFormat
+			}
 		}
 		x = &elfExe{f}
 	case bytes.HasPrefix(ident, []byte("MZ")):
@@ -142,6 +194,8 @@ func readRawBuildInfo(r io.ReaderAt) (vers, mod string, err error) {
 			return "", "", errUnrecognizedFormat
 		}
 		x = &xcoffExe{f}
+	case bytes.HasPrefix(ident, []byte(wasm.Magic)):
+		return readWasmBuildInfo(r)
 	case hasPlan9Magic(ident):
 		f, err := plan9obj.NewFile(r)
 		if err != nil {
@@ -214,18 +268,74 @@ func readRawBuildInfo(r io.ReaderAt) (vers, mod string, err error) {
 		vers = readString(x, ptrSize, readPtr, readPtr(data[16:]))
 		mod = readString(x, ptrSize, readPtr, readPtr(data[16+ptrSize:]))
 	}
-	if vers == "" {
+	mod = unframeModInfo(mod)
+	// a stomped version doesn't lose the module info
+	if vers == "" && mod == "" {
 		return "", "", errNotGoExe
 	}
+	return vers, mod, nil
+}
+
+// unframeModInfo strips the framing of the module info, the sentinels cmd/go/internal/modload.infoStart and infoEnd. A blob damaged
+// past the framing keeps the lines between whatever sentinels are left, an empty one has no lines.
+func unframeModInfo(mod string) string {
 	if len(mod) >= 33 && mod[len(mod)-17] == '\n' {
-		// Strip module framing: sentinel strings delimiting the module info.
-		// These are cmd/go/internal/modload.infoStart and infoEnd.
-		mod = mod[16 : len(mod)-16]
-	} else {
-		mod = ""
+		return mod[16 : len(mod)-16]
+	}
+	if i := strings.Index(mod, string(modInfoStart)); i >= 0 {
+		mod = mod[i+len(modInfoStart):]
+	}
+	if i := strings.Index(mod, string(modInfoEnd)); i >= 0 {
+		mod = mod[:i]
+	}
+	if !strings.Contains(mod, "\t") {
+		return ""
+	}
+	return mod
+}
+
+// the sentinels framing the module info, cmd/go/internal/modload.infoStart and infoEnd
+var (
+	modInfoStart = []byte("\x30\x77\xaf\x0c\x92\x74\x08\x02\x41\xe1\xc1\x07\xe6\xd6\x18\xe6")
+	modInfoEnd   = []byte("\xf9\x32\x43\x31\x86\x18\x20\x72\x00\x82\x42\x10\x41\x16\xd8\xf2")
+)
+
+// runtime.buildVersion, a release or a devel version
+var buildVersionPattern = regexp.MustCompile(`go1\.[0-9]+(\.[0-9]+)?|devel go1\.[0-9]+-[0-9a-f]+`)
+
+// the initial linear memory of a wasm module is read whole, larger spans are cut off
+const maxWasmMemory = 1 << 30
+
+// readWasmBuildInfo reads the build info of a Go WebAssembly module. Its linker emits no build info blob, the module info is found by its
+// sentinels in the initial linear memory instead, and the version is the first Go version string there.
+func readWasmBuildInfo(r io.ReaderAt) (vers, mod string, err error) {
+	f, err := wasm.NewFile(r)
+	if err != nil || len(f.Segments) == 0 {
+		return "", "", errUnrecognizedFormat
 	}
 
-	return vers, mod, nil
+	start, end := f.Segments[0].Offset, uint64(0)
+	for _, s := range f.Segments {
+		if s.Memory == 0 {
+			start = min(start, s.Offset)
+			end = max(end, s.Offset+uint64(len(s.Data)))
+		}
+	}
+	if end <= start {
+		return "", "", errNotGoExe
+	}
+	memory := f.ReadMemory(start, min(end-start, maxWasmMemory))
+
+	if i := bytes.Index(memory, modInfoStart); i >= 0 {
+		if j := bytes.Index(memory[i:], modInfoEnd); j >= 0 {
+			mod = string(memory[i : i+j+len(modInfoEnd)])
+		}
+	}
+	vers = string(buildVersionPattern.Find(memory))
+	if vers == "" {
+		return "", "", errNotGoExe
+	}
+	return vers, unframeModInfo(mod), nil
 }
 
 func hasPlan9Magic(magic []byte) bool {
@@ -262,6 +372,35 @@ func readString(x exe, ptrSize int, readPtr func([]byte) uint64, addr uint64) st
 	return string(data)
 }
 
+// headerReaderAt reads r with its start replaced by header
+type headerReaderAt struct {
+	r      io.ReaderAt
+	header []byte
+}
+
+func (h headerReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := h.r.ReadAt(p, off)
+	if off < int64(len(h.header)) {
+		copy(p[:n], h.header[off:])
+	}
+	return n, err
+}
+
+// withoutSectionHeaders reads an ELF with e_shoff, e_shnum and e_shstrndx zeroed
+func withoutSectionHeaders(r io.ReaderAt, ident []byte) io.ReaderAt {
+	header := make([]byte, 0x40)
+	n, _ := r.ReadAt(header, 0)
+	header = header[:n]
+	if elf.Class(ident[elf.EI_CLASS]) == elf.ELFCLASS64 && n >= 0x40 {
+		clear(header[0x28:0x30])
+		clear(header[0x3c:0x40])
+	} else if n >= 0x34 {
+		clear(header[0x20:0x24])
+		clear(header[0x30:0x34])
+	}
+	return headerReaderAt{r, header}
+}
+
 // elfExe is the ELF implementation of the exe interface.
 type elfExe struct {
 	f *elf.File
@@ -286,8 +425,16 @@ func (x *elfExe) DataStart() (uint64, uint64) {
 			return s.Addr, s.Size
 		}
 	}
+	// without sections, ex: a zeroed section header table, the build info starts the data segment. A pie's first writable segment is
+	// the relro one, which starts where PT_GNU_RELRO does, skip it.
+	var relro uint64
+	for _, p := range x.f.Progs {
+		if p.Type == elf.PT_GNU_RELRO {
+			relro = p.Vaddr
+		}
+	}
 	for _, p := range x.f.Progs {
-		if p.Type == elf.PT_LOAD && p.Flags&(elf.PF_X|elf.PF_W) == elf.PF_W {
+		if p.Type == elf.PT_LOAD && p.Flags&(elf.PF_X|elf.PF_W) == elf.PF_W && (relro == 0 || p.Vaddr != relro) {
 			return p.Vaddr, p.Memsz
 		}
 	}
@@ -339,7 +486,8 @@ func (x *peExe) DataStart() (uint64, uint64) {
 	for _, sect := range x.f.Sections {
 		if sect.VirtualAddress != 0 && sect.Size != 0 &&
 			sect.Characteristics&^IMAGE_SCN_ALIGN_32BYTES == IMAGE_SCN_CNT_INITIALIZED_DATA|IMAGE_SCN_MEM_READ|IMAGE_SCN_MEM_WRITE {
-			return uint64(sect.VirtualAddress) + x.imageBase(), uint64(sect.VirtualSize)
+			// a raw size past the virtual one is still read, packers inflate it to hide data there
+			return uint64(sect.VirtualAddress) + x.imageBase(), max(uint64(sect.VirtualSize), uint64(sect.Size))
 		}
 	}
 	return 0, 0
@@ -0,0 +1,355 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mandiant/GoReSym/goresym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// the C and C++ keywords that are valid Go identifiers, a field named one gets a _ appended
+var cKeywords = map[string]bool{
+	"asm": true, "auto": true, "bool": true, "catch": true, "char": true, "class": true, "const_cast": true, "delete": true,
+	"do": true, "double": true, "enum": true, "explicit": true, "export": true, "extern": true, "false": true, "float": true,
+	"friend": true, "inline": true, "int": true, "long": true, "mutable": true, "namespace": true, "new": true, "operator": true,
+	"private": true, "protected": true, "public": true, "register": true, "restrict": true, "short": true, "signed": true,
+	"sizeof": true, "static": true, "template": true, "this": true, "throw": true, "true": true, "try": true, "typedef": true,
+	"typeid": true, "typename": true, "union": true, "unsigned": true, "using": true, "virtual": true, "void": true,
+	"volatile": true, "wchar_t": true, "while": true,
+}
+
+// the C types of the Go kinds that don't refer to other types, the runtime headers are in the prelude
+var cBasicTypes = map[string]string{
+	"Bool": "go_bool", "Int": "go_int", "Int8": "int8_t", "Int16": "int16_t", "Int32": "int32_t", "Int64": "int64_t",
+	"Uint": "go_uint", "Uint8": "uint8_t", "Uint16": "uint16_t", "Uint32": "uint32_t", "Uint64": "uint64_t", "Uintptr": "uintptr_t",
+	"Float32": "float", "Float64": "double", "Complex64": "go_complex64", "Complex128": "go_complex128", "String": "go_string",
+	"Slice": "go_slice", "Map": "void *", "Chan": "void *", "Func": "void *", "UnsafePointer": "void *",
+}
+
+// cIdentifier is str with every run of characters C doesn't allow in identifiers replaced by a _, ex: main_Stack_int for
+// main.Stack[int]
+func cIdentifier(str string) string {
+	var out strings.Builder
+	separate := false
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if c != '_' && (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			separate = true
+			continue
+		}
+		if separate && out.Len() > 0 {
+			out.WriteByte('_')
+		}
+		separate = false
+		out.WriteByte(c)
+	}
+	ident := out.String()
+	if len(ident) == 0 || (ident[0] >= '0' && ident[0] <= '9') {
+		ident = "_" + ident
+	}
+	return ident
+}
+
+// cHeader lays out the recovered types as C, each declared type once under a unique identifier
+type cHeader struct {
+	byVA  map[uint64]objfile.Type
+	byStr map[string]objfile.Type
+	names map[uint64]string // of the declared types, by VA
+	taken map[string]bool
+	order []objfile.Type
+	decls map[uint64]string
+	deps  map[uint64][]uint64 // the declared types each one needs declared before it
+	sizes map[uint64]string   // the structs to check the sizeof of, by VA
+}
+
+func newCHeader(metadata goresym.Report) *cHeader {
+	h := &cHeader{byVA: make(map[uint64]objfile.Type), byStr: make(map[string]objfile.Type), names: make(map[uint64]string),
+		taken: make(map[string]bool), decls: make(map[uint64]string), deps: make(map[uint64][]uint64), sizes: make(map[uint64]string)}
+	for _, typ := range append(append([]objfile.Type{}, metadata.Types...), metadata.Interfaces...) {
+		if _, ok := h.byVA[typ.VA]; !ok {
+			h.byVA[typ.VA] = typ
+		}
+		if _, ok := h.byStr[typ.Str]; !ok {
+			h.byStr[typ.Str] = typ
+		}
+	}
+	for _, typ := range declaredTypes(metadata) {
+		h.declare(typ, cIdentifier(typ.Str))
+	}
+	for i := 0; i < len(h.order); i++ {
+		h.declareStructs(h.order[i])
+		for _, field := range h.order[i].Fields {
+			if typ, ok := h.byVA[field.TypeVA]; ok {
+				h.declareStructs(typ)
+			}
+		}
+	}
+	return h
+}
+
+// declareStructs declares the struct typ is, or has as the element of its arrays and pointers. Struct literals are named after
+// their address, and named ones are declared here when another type of the same name took their place in declaredTypes, ex: the
+// atomic.Uint64 of sync/atomic and of runtime/internal/atomic.
+func (h *cHeader) declareStructs(typ objfile.Type) {
+	for {
+		switch typ.Kind {
+		case "Struct":
+			if _, _, named := splitTypeName(typ.Str); named {
+				h.declare(typ, cIdentifier(typ.Str))
+			} else {
+				h.declare(typ, fmt.Sprintf("struct_%x", typ.VA))
+			}
+			return
+		case "Array":
+			_, elemStr, ok := splitArray(literal(typ))
+			if typ, ok = h.byStr[elemStr]; !ok {
+				return
+			}
+		case "Pointer":
+			elem, ok := h.byStr[strings.TrimPrefix(literal(typ), "*")]
+			if !ok || elem.VA == typ.VA {
+				return
+			}
+			typ = elem
+		default:
+			return
+		}
+	}
+}
+
+// declare gives typ its C name, with its address appended when another type has it, ex: for main.Stack[int] and main.Stack[*int]
+func (h *cHeader) declare(typ objfile.Type, name string) {
+	// C has no empty structs or arrays, the fields of zero size types are left out
+	if _, ok := h.names[typ.VA]; ok || typ.Size == 0 {
+		return
+	}
+	if h.taken[name] {
+		name = fmt.Sprintf("%s_%x", name, typ.VA)
+	}
+	h.taken[name] = true
+	h.names[typ.VA] = name
+	h.order = append(h.order, typ)
+}
+
+// literal is the type expression of typ to lay out, the declared type for a named one
+func literal(typ objfile.Type) string {
+	if _, _, named := splitTypeName(typ.Str); named && len(typ.Underlying) > 0 {
+		return typ.Underlying
+	}
+	return typ.Str
+}
+
+// splitArray splits an array type expression into its length and element, ex: 4 and int32 for [4]int32
+func splitArray(expr string) (uint64, string, bool) {
+	end := strings.IndexByte(expr, ']')
+	if !strings.HasPrefix(expr, "[") || end < 0 {
+		return 0, "", false
+	}
+	length, err := strconv.ParseUint(expr[1:end], 10, 64)
+	return length, expr[end+1:], err == nil
+}
+
+// decl is the C declaration of name as a typ, the declared types it refers to are added to the deps of owner. A struct behind a
+// pointer only needs its forward declaration. ok is false when the layout of typ isn't known.
+func (h *cHeader) decl(owner uint64, typ objfile.Type, name string, declared bool) (string, bool) {
+	if cName, ok := h.names[typ.VA]; ok && declared {
+		h.deps[owner] = append(h.deps[owner], typ.VA)
+		return cName + " " + name, true
+	}
+	switch typ.Kind {
+	case "Struct":
+		// only declared structs have a layout
+		return "", false
+	case "Interface":
+		if len(typ.InterfaceMethods) == 0 {
+			return "go_eface " + name, true
+		}
+		return "go_iface " + name, true
+	case "Pointer":
+		elem, ok := h.byStr[strings.TrimPrefix(literal(typ), "*")]
+		if cName, declared := h.names[elem.VA]; ok && declared {
+			if elem.Kind != "Struct" {
+				h.deps[owner] = append(h.deps[owner], elem.VA)
+			}
+			return cName + " *" + name, true
+		}
+		if basic, isBasic := cBasicTypes[elem.Kind]; ok && isBasic && !strings.HasSuffix(basic, "*") {
+			return basic + " *" + name, true
+		}
+		return "void *" + name, true
+	case "Array":
+		length, elemStr, ok := splitArray(literal(typ))
+		elem, found := h.byStr[elemStr]
+		if !ok || !found || elem.Size*length != typ.Size {
+			return "", false
+		}
+		return h.decl(owner, elem, fmt.Sprintf("%s[%d]", name, length), true)
+	}
+	if basic, ok := cBasicTypes[typ.Kind]; ok {
+		if strings.HasSuffix(basic, "*") {
+			return basic + name, true
+		}
+		return basic + " " + name, true
+	}
+	return "", false
+}
+
+// fieldName is a unique C identifier for a field, blank and clashing fields are named after their offset
+func fieldName(field objfile.StructField, used map[string]bool) string {
+	name := cIdentifier(field.Name)
+	if cKeywords[name] {
+		name += "_"
+	}
+	if field.Name == "_" {
+		name = fmt.Sprintf("_%d", field.Offset)
+	} else if used[name] {
+		name = fmt.Sprintf("%s_%d", name, field.Offset)
+	}
+	used[name] = true
+	return name
+}
+
+// layout renders the declaration of a declared type. Structs get a padding array wherever the fields leave a gap, so the offsets
+// are the Go ones in a packed struct. A struct whose fields don't add up to its size is declared as its bytes.
+func (h *cHeader) layout(typ objfile.Type) string {
+	name := h.names[typ.VA]
+	if typ.Kind != "Struct" {
+		if decl, ok := h.decl(typ.VA, typ, name, false); ok {
+			return fmt.Sprintf("typedef %s;\n", decl)
+		}
+		return fmt.Sprintf("typedef uint8_t %s[%d]; // %s, the layout wasn't recovered\n", name, typ.Size, typ.Kind)
+	}
+
+	var body strings.Builder
+	used := make(map[string]bool)
+	end := uint64(0)
+	consistent := true
+	for i, field := range typ.Fields {
+		if field.Offset < end {
+			consistent = false
+			break
+		}
+		if field.Offset > end {
+			fmt.Fprintf(&body, "\tuint8_t _pad%d[%d];\n", end, field.Offset-end)
+		}
+		member := fieldName(field, used)
+		fieldType, ok := h.byVA[field.TypeVA]
+		if ok && len(field.Type) > 0 && fieldType.Size == 0 {
+			fmt.Fprintf(&body, "\t// %s %s, zero size\n", member, field.Type)
+			end = field.Offset
+			continue
+		}
+		decl := ""
+		size := fieldType.Size
+		if ok && len(field.Type) > 0 {
+			decl, ok = h.decl(typ.VA, fieldType, member, true)
+		}
+		if !ok {
+			// the bytes up to the next field
+			next := typ.Size
+			if i+1 < len(typ.Fields) {
+				next = typ.Fields[i+1].Offset
+			}
+			if next < field.Offset || (size > 0 && size != next-field.Offset) {
+				consistent = false
+				break
+			}
+			size = next - field.Offset
+			fmt.Fprintf(&body, "\tuint8_t %s[%d]; // %s, offset %d, the layout wasn't recovered\n", member, size, field.Type, field.Offset)
+		} else {
+			fmt.Fprintf(&body, "\t%s; // offset %d\n", decl, field.Offset)
+		}
+		end = field.Offset + size
+	}
+	if consistent && end < typ.Size {
+		fmt.Fprintf(&body, "\tuint8_t _pad%d[%d];\n", end, typ.Size-end)
+	}
+	h.sizes[typ.VA] = typ.Str
+	if !consistent || end > typ.Size {
+		h.deps[typ.VA] = nil
+		return fmt.Sprintf("struct %s {\n\tuint8_t data[%d]; // the fields don't add up to the size\n};\n", name, typ.Size)
+	}
+	return fmt.Sprintf("struct %s {\n%s};\n", name, body.String())
+}
+
+// printCHeader writes the named types of metadata, and the struct literals their fields have, as a C header for IDA's local types
+// or Ghidra's C parser. A prelude declares the fixed width integers, Go's int and bool and the string, slice and interface headers
+// of the runtime. Structs are packed and padded explicitly, so their layout is the one of the binary's architecture. The types are
+// declared before the types using them, structs behind pointers are forward declared. Compiled for the binary's architecture with
+// GORESYM_CHECK_SIZES defined, every sizeof is checked against the size of the rtype.
+func printCHeader(w io.Writer, fileName string, metadata goresym.Report) error {
+	h := newCHeader(metadata)
+	ptrSize := uint64(8)
+	if typ, ok := h.byStr["unsafe.Pointer"]; ok {
+		ptrSize = typ.Size
+	} else if typ, ok := h.byStr["uintptr"]; ok {
+		ptrSize = typ.Size
+	}
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "// Code generated by GoReSym from %s, DO NOT EDIT.\n", filepath.Base(fileName))
+	fmt.Fprintf(out, "// The types of Go %s for %s, reconstructed from their runtime type descriptors.\n\n", metadata.Version, metadata.Arch)
+	fmt.Fprintln(out, "#if defined(__GNUC__) || defined(_MSC_VER)")
+	fmt.Fprintln(out, "#include <stdint.h>")
+	fmt.Fprintln(out, "#else")
+	fmt.Fprintln(out, "typedef signed char int8_t;\ntypedef unsigned char uint8_t;\ntypedef short int16_t;\ntypedef unsigned short uint16_t;")
+	fmt.Fprintln(out, "typedef int int32_t;\ntypedef unsigned int uint32_t;\ntypedef long long int64_t;\ntypedef unsigned long long uint64_t;")
+	fmt.Fprintf(out, "typedef uint%d_t uintptr_t;\n", ptrSize*8)
+	fmt.Fprintln(out, "#endif")
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "typedef int%d_t go_int;\ntypedef uint%d_t go_uint;\ntypedef uint8_t go_bool;\n", ptrSize*8, ptrSize*8)
+	fmt.Fprintln(out, "typedef struct go_string { uint8_t *str; go_int len; } go_string;")
+	fmt.Fprintln(out, "typedef struct go_slice { void *array; go_int len; go_int cap; } go_slice;")
+	fmt.Fprintln(out, "typedef struct go_iface { void *tab; void *data; } go_iface; // tab is the *itab")
+	fmt.Fprintln(out, "typedef struct go_eface { void *_type; void *data; } go_eface; // an interface{}")
+	fmt.Fprintln(out, "typedef struct go_complex64 { float real; float imag; } go_complex64;")
+	fmt.Fprintln(out, "typedef struct go_complex128 { double real; double imag; } go_complex128;")
+
+	fmt.Fprintln(out, "\n#pragma pack(push, 1)")
+	fmt.Fprintln(out)
+	for _, typ := range h.order {
+		if typ.Kind == "Struct" {
+			fmt.Fprintf(out, "typedef struct %s %s;\n", h.names[typ.VA], h.names[typ.VA])
+		}
+	}
+	for _, typ := range h.order {
+		h.decls[typ.VA] = h.layout(typ)
+	}
+
+	// depth first, so a type follows the ones it depends on
+	done := make(map[uint64]bool)
+	var emit func(typ objfile.Type)
+	emit = func(typ objfile.Type) {
+		if done[typ.VA] {
+			return
+		}
+		done[typ.VA] = true
+		for _, dep := range h.deps[typ.VA] {
+			emit(h.byVA[dep])
+		}
+		fmt.Fprintf(out, "\n// %s at 0x%x, %d bytes\n%s", typ.Str, typ.VA, typ.Size, h.decls[typ.VA])
+	}
+	for _, typ := range h.order {
+		emit(typ)
+	}
+	fmt.Fprintln(out, "\n#pragma pack(pop)")
+
+	var checked []uint64
+	for va := range h.sizes {
+		checked = append(checked, va)
+	}
+	sort.Slice(checked, func(i, j int) bool { return h.names[checked[i]] < h.names[checked[j]] })
+	fmt.Fprintln(out, "\n#ifdef GORESYM_CHECK_SIZES")
+	for _, va := range checked {
+		fmt.Fprintf(out, "_Static_assert(sizeof(%s) == %d, %s);\n", h.names[va], h.byVA[va].Size, strconv.Quote(h.sizes[va]))
+	}
+	fmt.Fprintln(out, "#endif")
+	return out.Flush()
+}
@@ -0,0 +1,130 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mandiant/GoReSym/goresym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// csvFunctionColumns is the stable column order of -outputformat csv, append new columns to the end only
+var csvFunctionColumns = []string{"StartVA", "EndVA", "FullName", "PackageName", "Kind", "Size", "SourceFile", "StartLine", "EndLine"}
+
+// csvTypeColumns is the stable column order of the types table, append new columns to the end only
+var csvTypeColumns = []string{"VA", "Name", "Kind", "Size", "Interface"}
+
+// csvLine is a source line cell, empty when the line isn't known
+func csvLine(line int) string {
+	if line <= 0 {
+		return ""
+	}
+	return fmt.Sprint(line)
+}
+
+// printCsv emits one row per recovered function, user functions first, of every result under one header, ex: of each slice of a
+// fat Mach-O. Types and interfaces are in printCsvTypes.
+func printCsv(w io.Writer, header bool, metadatas ...goresym.Report) error {
+	writer := csv.NewWriter(w)
+	if header {
+		if err := writer.Write(csvFunctionColumns); err != nil {
+			return err
+		}
+	}
+
+	writeFuncs := func(funcs []goresym.FuncMetadata, kind string) error {
+		for _, fn := range funcs {
+			// StartLine is the entry's line for the functions that only know that one
+			startLine := fn.StartLine
+			if startLine == 0 {
+				startLine = fn.SourceLine
+			}
+			row := []string{fmt.Sprintf("0x%x", fn.Start), fmt.Sprintf("0x%x", fn.End), fn.FullName, fn.PackageName, kind,
+				fmt.Sprint(fn.End - fn.Start), fn.SourceFile, csvLine(startLine), csvLine(fn.EndLine)}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, metadata := range metadatas {
+		if err := writeFuncs(metadata.UserFunctions, "user"); err != nil {
+			return err
+		}
+		if err := writeFuncs(metadata.StdFunctions, "std"); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// printCsvTypes emits one row per recovered type then per interface, they need -t
+func printCsvTypes(w io.Writer, header bool, metadatas ...goresym.Report) error {
+	writer := csv.NewWriter(w)
+	if header {
+		if err := writer.Write(csvTypeColumns); err != nil {
+			return err
+		}
+	}
+
+	writeTypes := func(types []objfile.Type, iface bool) error {
+		for _, typ := range types {
+			row := []string{fmt.Sprintf("0x%x", typ.VA), typ.Str, typ.Kind, fmt.Sprint(typ.Size), fmt.Sprint(iface)}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, metadata := range metadatas {
+		if err := writeTypes(metadata.Types, false); err != nil {
+			return err
+		}
+		if err := writeTypes(metadata.Interfaces, true); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvTypesPath is where the types table goes next to the -out file of the functions, ex: out.csv and out_types.csv
+func csvTypesPath(outFile string) string {
+	ext := filepath.Ext(outFile)
+	return strings.TrimSuffix(outFile, ext) + "_types" + ext
+}
+
+// writeCsv prints the table of the results picked by -csv-table, or without one the functions and, next to an -out file, the types
+// in a second file. It exits on failure.
+func writeCsv(w io.Writer, outFile string, table string, header bool, metadatas ...goresym.Report) {
+	var err error
+	switch table {
+	case "types":
+		err = printCsvTypes(w, header, metadatas...)
+	default:
+		err = printCsv(w, header, metadatas...)
+		if err == nil && len(table) == 0 && len(outFile) > 0 {
+			var types *os.File
+			if types, err = os.Create(csvTypesPath(outFile)); err == nil {
+				err = printCsvTypes(types, header, metadatas...)
+				if closeErr := types.Close(); err == nil {
+					err = closeErr
+				}
+			}
+		}
+	}
+	if err != nil {
+		fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write csv: %s", err)))
+		os.Exit(1)
+	}
+}
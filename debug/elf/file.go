@@ -16,8 +16,10 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/mandiant/GoReSym/debug/dwarf"
+	"github.com/mandiant/GoReSym/debug/internal/view"
 	"github.com/mandiant/GoReSym/saferio"
 )
 
@@ -60,7 +62,9 @@ type File struct {
 	gnuNeed   []verneed
 	gnuVersym []byte
 
-	dataAfterSectionCache map[uint64][]byte // secVA -> dataAfterSection
+	dataAfterSectionCache map[uint64][]byte  // secVA -> dataAfterSection
+	dataAfterSectionLock  sync.Mutex         // the scans read sections from several goroutines
+	sectionsData          *view.SectionsData // the copy of the sections DataAfterSection slices, made once
 }
 
 // A SectionHeader represents a single ELF section header.
@@ -98,7 +102,8 @@ type Section struct {
 	// in a random-access form. For example, a compressed section
 	// may have a nil ReaderAt.
 	io.ReaderAt
-	sr *io.SectionReader
+	sr   *io.SectionReader
+	view *view.Section // of sr, when the file is a view.Viewer
 
 	compressionType   CompressionType
 	compressionOffset int64
@@ -106,13 +111,26 @@ type Section struct {
 
 // Data reads and returns the contents of the ELF section.
 // Even if the section is stored compressed in the ELF file,
-// Data returns uncompressed data.
+// Data returns uncompressed data. The data of an uncompressed
+// section of a file that's a view.Viewer is a view of it, which must
+// not be written to.
 func (s *Section) Data() ([]byte, error) {
+	if data, ok := s.uncompressedView(); ok {
+		return data, nil
+	}
 	dat := make([]byte, s.Size)
 	n, err := io.ReadFull(s.Open(), dat)
 	return dat[0:n], err
 }
 
+// uncompressedView returns the data of the section in place, when it has a view and isn't compressed
+func (s *Section) uncompressedView() ([]byte, bool) {
+	if s.Flags&SHF_COMPRESSED != 0 {
+		return nil, false
+	}
+	return s.view.Of(s.sr)
+}
+
 // stringTable reads and returns the string table given by the
 // specified link value.
 func (f *File) stringTable(link uint32) ([]byte, error) {
@@ -165,7 +183,8 @@ type Prog struct {
 	// Open() to avoid fighting over the seek offset
 	// with other clients.
 	io.ReaderAt
-	sr *io.SectionReader
+	sr   *io.SectionReader
+	view *view.Section // of sr, when the file is a view.Viewer
 }
 
 // Open returns a new ReadSeeker reading the ELF program body.
@@ -403,6 +422,7 @@ func NewFile(r io.ReaderAt) (*File, error) {
 			return nil, &FormatError{off, "invalid program header file size", p.Filesz}
 		}
 		p.sr = io.NewSectionReader(r, int64(p.Off), int64(p.Filesz))
+		p.view = view.NewSection(r, p.sr, int64(p.Off), int64(p.Filesz))
 		p.ReaderAt = p.sr
 		f.Progs[i] = p
 	}
@@ -511,6 +531,7 @@ func NewFile(r io.ReaderAt) (*File, error) {
 			return nil, &FormatError{off, "invalid section size", int64(s.FileSize)}
 		}
 		s.sr = io.NewSectionReader(r, int64(s.Offset), int64(s.FileSize))
+		s.view = view.NewSection(r, s.sr, int64(s.Offset), int64(s.FileSize))
 
 		if s.Flags&SHF_COMPRESSED == 0 {
 			s.ReaderAt = s.sr
@@ -688,27 +709,195 @@ func getString(section []byte, start int) (string, bool) {
 	return "", false
 }
 
+// SectionsFromProgs replaces the sections with one per file backed PT_LOAD segment, named load<index>, for files whose section headers
+// are missing or bogus. The flags come from the segment permissions.
+func (f *File) SectionsFromProgs() {
+	f.Sections = nil
+	f.dataAfterSectionCache = make(map[uint64][]byte)
+	f.sectionsData = nil
+	for i, p := range f.Progs {
+		if p.Type != PT_LOAD || p.Filesz == 0 {
+			continue
+		}
+
+		flags := SHF_ALLOC
+		if p.Flags&PF_X != 0 {
+			flags |= SHF_EXECINSTR
+		}
+		if p.Flags&PF_W != 0 {
+			flags |= SHF_WRITE
+		}
+		s := &Section{
+			SectionHeader: SectionHeader{
+				Name:      fmt.Sprintf("load%d", i),
+				Type:      SHT_PROGBITS,
+				Flags:     flags,
+				Addr:      p.Vaddr,
+				Offset:    p.Off,
+				FileSize:  p.Filesz,
+				Size:      p.Filesz,
+				Addralign: p.Align,
+			},
+			sr:   p.sr,
+			view: p.view,
+		}
+		s.ReaderAt = s.sr
+		f.Sections = append(f.Sections, s)
+	}
+}
+
+// absoluteRelocation reports whether t is the relocation of the machine that stores a pointer sized symbol address
+func (f *File) absoluteRelocation(t uint32) bool {
+	switch f.Machine {
+	case EM_X86_64:
+		return R_X86_64(t) == R_X86_64_64
+	case EM_AARCH64:
+		return R_AARCH64(t) == R_AARCH64_ABS64
+	case EM_386:
+		return R_386(t) == R_386_32
+	case EM_ARM:
+		return R_ARM(t) == R_ARM_ABS32
+	case EM_PPC64:
+		return R_PPC64(t) == R_PPC64_ADDR64
+	case EM_RISCV:
+		return R_RISCV(t) == R_RISCV_64
+	case EM_S390:
+		return R_390(t) == R_390_64
+	}
+	return false
+}
+
+// LayoutRelocatable loads a relocatable object at base: the allocated sections are placed one after the other like a linker would,
+// then the absolute pointers between them are relocated. Every section of an object is at address 0 until then. Only the pointer sized
+// absolute relocations are applied, which is what the Go data structures hold, code stays unrelocated.
+func (f *File) LayoutRelocatable(base uint64) error {
+	if f.Type != ET_REL {
+		return errors.New("not a relocatable object")
+	}
+
+	addr := base
+	for _, s := range f.Sections {
+		if s.Flags&SHF_ALLOC == 0 || s.Flags&SHF_TLS != 0 {
+			continue
+		}
+		if s.Addralign > 1 {
+			addr = (addr + s.Addralign - 1) &^ (s.Addralign - 1)
+		}
+		s.Addr = addr
+		addr += s.Size
+	}
+
+	symbols, _, err := f.getSymbols(SHT_SYMTAB)
+	if err != nil {
+		return err
+	}
+	for _, rels := range f.Sections {
+		if (rels.Type != SHT_RELA && rels.Type != SHT_REL) || int(rels.Info) <= 0 || int(rels.Info) >= len(f.Sections) {
+			continue
+		}
+		target := f.Sections[rels.Info]
+		if target.Flags&SHF_ALLOC == 0 || target.Type == SHT_NOBITS {
+			continue
+		}
+		data, err := target.Data()
+		if err != nil {
+			return err
+		}
+		// the relocations are applied to a copy, the data may be a view of the file
+		data = append([]byte(nil), data...)
+		relData, err := rels.Data()
+		if err != nil {
+			return err
+		}
+
+		entrySize, ptrSize := 24, 8
+		if f.Class == ELFCLASS32 {
+			entrySize, ptrSize = 12, 4
+		}
+		if rels.Type == SHT_REL {
+			entrySize -= ptrSize
+		}
+		for i := 0; i+entrySize <= len(relData); i += entrySize {
+			var off, info uint64
+			var addend int64
+			var symNo uint64
+			var typ uint32
+			if f.Class == ELFCLASS64 {
+				off, info = f.ByteOrder.Uint64(relData[i:]), f.ByteOrder.Uint64(relData[i+8:])
+				symNo, typ = info>>32, uint32(info)
+				if rels.Type == SHT_RELA {
+					addend = int64(f.ByteOrder.Uint64(relData[i+16:]))
+				}
+			} else {
+				off, info = uint64(f.ByteOrder.Uint32(relData[i:])), uint64(f.ByteOrder.Uint32(relData[i+4:]))
+				symNo, typ = info>>8, uint32(info&0xff)
+				if rels.Type == SHT_RELA {
+					addend = int64(int32(f.ByteOrder.Uint32(relData[i+8:])))
+				}
+			}
+			if !f.absoluteRelocation(typ) || symNo == 0 || symNo > uint64(len(symbols)) || off+uint64(ptrSize) > uint64(len(data)) {
+				continue
+			}
+			sym := &symbols[symNo-1]
+			if !canApplyRelocation(sym) || int(sym.Section) >= len(f.Sections) {
+				continue
+			}
+
+			// REL keeps the addend in the field
+			value := f.Sections[sym.Section].Addr + sym.Value + uint64(addend)
+			if ptrSize == 8 {
+				if rels.Type == SHT_REL {
+					value += f.ByteOrder.Uint64(data[off:])
+				}
+				f.ByteOrder.PutUint64(data[off:], value)
+			} else {
+				if rels.Type == SHT_REL {
+					value += uint64(f.ByteOrder.Uint32(data[off:]))
+				}
+				f.ByteOrder.PutUint32(data[off:], uint32(value))
+			}
+		}
+
+		target.sr = io.NewSectionReader(bytes.NewReader(data), 0, int64(len(data)))
+		target.ReaderAt = target.sr
+		target.Flags &^= SHF_COMPRESSED
+		target.FileSize = uint64(len(data))
+	}
+	f.dataAfterSectionCache = make(map[uint64][]byte)
+	f.sectionsData = nil
+	return nil
+}
+
+// DataAfterSection returns the data of target and of the sections after it, back to back, up to the first section that can't be
+// read whole. It's a view of the file when they're laid out back to back in it, else of one copy of every section, shared by every
+// target. It must not be written to.
 func (f *File) DataAfterSection(target *Section) []byte {
+	f.dataAfterSectionLock.Lock()
+	defer f.dataAfterSectionLock.Unlock()
 	if cached, ok := f.dataAfterSectionCache[uint64(target.Addr)]; ok {
 		return cached
 	}
 
 	data := []byte{}
-	found := false
-	for _, s := range f.Sections {
+	for i, s := range f.Sections {
 		if s.Addr == target.Addr && s.Name == target.Name {
-			found = true
-		}
-
-		if found {
-			raw, err := s.Data()
-			if raw != nil {
-				data = append(data, raw[:]...)
+			views := make([]*view.Section, 0, len(f.Sections)-i)
+			for _, after := range f.Sections[i:] {
+				if _, ok := after.uncompressedView(); ok {
+					views = append(views, after.view)
+				} else {
+					views = append(views, nil)
+				}
 			}
-
-			if err != nil {
-				break
+			if viewed, ok := view.After(views); ok {
+				data = viewed
+			} else {
+				if f.sectionsData == nil {
+					f.sectionsData = view.NewSectionsData(len(f.Sections), func(i int) ([]byte, error) { return f.Sections[i].Data() })
+				}
+				data = f.sectionsData.From(i)
 			}
+			break
 		}
 	}
 
@@ -1277,6 +1466,7 @@ func (f *File) DWARF() (*dwarf.Data, error) {
 		if err != nil && uint64(len(b)) < s.Size {
 			return nil, err
 		}
+		copied := false
 
 		if len(b) >= 12 && string(b[:4]) == "ZLIB" {
 			dlen := binary.BigEndian.Uint64(b[4:12])
@@ -1291,7 +1481,7 @@ func (f *File) DWARF() (*dwarf.Data, error) {
 			if err := r.Close(); err != nil {
 				return nil, err
 			}
-			b = dbuf
+			b, copied = dbuf, true
 		}
 
 		for _, r := range f.Sections {
@@ -1305,6 +1495,10 @@ func (f *File) DWARF() (*dwarf.Data, error) {
 			if err != nil {
 				return nil, err
 			}
+			if _, isView := s.uncompressedView(); isView && !copied {
+				// the data is the file's, relocate a copy
+				b, copied = append([]byte(nil), b...), true
+			}
 			err = f.applyRelocations(b, rd)
 			if err != nil {
 				return nil, err
@@ -15,8 +15,10 @@ import (
 	"encoding/binary"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"unicode/utf8"
 )
 
 // version of the pclntab
@@ -93,6 +95,35 @@ type LineTable struct {
 	// For ver12, it maps the name to the index in the file table.
 	// For ver116, it maps the name to the offset in filetab.
 	fileMap map[string]uint32
+
+	// nfunc as written in the header. Differs from the recovered count when the header was tampered with.
+	DeclaredFuncs uint32
+	// functab entries that passed validFuncEntry, the declared count when it holds up and none when nothing validated
+	ValidatedFuncs uint32
+	// the declared count held up and was kept without walking the functab
+	countKept bool
+
+	// Optional, resolves the pcHeader sub-tables that fall outside Data. Some layouts place funcnametab and friends in other sections.
+	// ReadMemory returns the bytes from VA to the end of its section, HeaderVA is the address of Data[0].
+	ReadMemory func(VA uint64) ([]byte, error)
+	HeaderVA   uint64
+
+	// Optional, contains the failures to parse a function to that function instead of losing the whole table to them, see Corruption
+	Tolerant bool
+	// what a Tolerant parse skipped or replaced and why, in functab order
+	Corruption []Corruption
+	// the functab indexes of the functions whose pcfile or pcln table doesn't decode, a Tolerant parse drops their line info
+	noLineInfo map[int]bool
+}
+
+// A Corruption is a problem with the function at Index in the functab, or with the table as a whole when Index is -1, that a
+// Tolerant parse worked around. Kind is name for a placeholder name, funcdata for an unreadable _func, lines for dropped line
+// info, order for an entry out of order or overlapping the next and count for a functab that stops validating early.
+type Corruption struct {
+	Index  int
+	Entry  uint64
+	Kind   string
+	Reason string
 }
 
 // NOTE(rsc): This is wrong for GOARCH=arm, which uses a quantum of 4,
@@ -209,6 +240,52 @@ func (t *LineTable) uintptr(b []byte) uint64 {
 	return t.Binary.Uint64(b)
 }
 
+// pclntabVersionForGoVersion maps a Go runtime version (ex: 1.14.15, go1.21) to the pclntab layout it emits.
+// Every runtime from 1.2 through 1.15 emits the same 0xfffffffb layout, the newer layouts are each shared by two or more runtimes.
+// Unparseable versions map to ver11, same as a failed parse.
+func pclntabVersionForGoVersion(goVersion string) version {
+	goVersion = strings.TrimPrefix(strings.TrimSpace(goVersion), "go")
+	parts := strings.SplitN(goVersion, ".", 3)
+	if len(parts) < 2 || parts[0] != "1" {
+		return ver11
+	}
+
+	// tolerate suffixes such as 1.21rc1 or 1.18beta2
+	minorStr := parts[1]
+	for i, c := range minorStr {
+		if c < '0' || c > '9' {
+			minorStr = minorStr[:i]
+			break
+		}
+	}
+
+	minor, err := strconv.Atoi(minorStr)
+	if err != nil {
+		return ver11
+	}
+
+	switch {
+	case minor >= 20:
+		return ver120
+	case minor >= 18:
+		return ver118
+	case minor >= 16:
+		return ver116
+	case minor >= 2:
+		return ver12
+	}
+	return ver11
+}
+
+// PclntabLayoutForGoVersion is the pclntab layout a Go runtime version emits, as LineTable.Version prints it, or empty when the version doesn't parse.
+// The layout decides the magic, so it's what to expect from a table whose magic was stomped.
+func PclntabLayoutForGoVersion(goVersion string) string {
+	if v := pclntabVersionForGoVersion(goVersion); v != ver11 {
+		return v.String()
+	}
+	return ""
+}
+
 // parsePclnTab parses the pclntab, setting the version.
 func (t *LineTable) parsePclnTab(versionOverride string) {
 	t.mu.Lock()
@@ -264,29 +341,7 @@ func (t *LineTable) parsePclnTab(versionOverride string) {
 	t.Version = possibleVersion
 
 	if len(versionOverride) > 0 {
-		if strings.Contains(versionOverride, "1.20") {
-			t.Version = ver120
-		} else if strings.Contains(versionOverride, "1.19") {
-			t.Version = ver118
-		} else if strings.Contains(versionOverride, "1.18") {
-			t.Version = ver118
-		} else if strings.Contains(versionOverride, "1.17") {
-			t.Version = ver116
-		} else if strings.Contains(versionOverride, "1.16") {
-			t.Version = ver116
-		} else if strings.Contains(versionOverride, "1.15") {
-			t.Version = ver12
-		} else if strings.Contains(versionOverride, "1.14") {
-			t.Version = ver12
-		} else if strings.Contains(versionOverride, "1.13") {
-			t.Version = ver12
-		} else if strings.Contains(versionOverride, "1.12") {
-			t.Version = ver12
-		} else if strings.Contains(versionOverride, "1.11") {
-			t.Version = ver11
-		} else {
-			t.Version = ver11
-		}
+		t.Version = pclntabVersionForGoVersion(versionOverride)
 	}
 
 	// quantum and ptrSize are the same between 1.2, 1.16, and 1.18
@@ -297,7 +352,11 @@ func (t *LineTable) parsePclnTab(versionOverride string) {
 		return t.uintptr(t.Data[8+word*t.Ptrsize:])
 	}
 	data := func(word uint32) []byte {
-		return t.Data[offset(word):]
+		off := offset(word)
+		if off < uint64(len(t.Data)) || t.ReadMemory == nil {
+			return t.Data[off:]
+		}
+		return t.subtable(off)
 	}
 
 	switch t.Version {
@@ -305,23 +364,25 @@ func (t *LineTable) parsePclnTab(versionOverride string) {
 		t.nfunctab = uint32(offset(0))
 		t.nfiletab = uint32(offset(1))
 		t.textStart = t.PC // use the start PC instead of reading from the table, which may be unrelocated
-		t.funcnametab = data(3)
+		t.funcnametab = t.boundedNameTable(data(3), offset(3), offset(4))
 		t.cutab = data(4)
 		t.filetab = data(5)
 		t.pctab = data(6)
 		t.funcdata = data(7)
 		t.functab = data(7)
+		t.checkFuncCount()
 		functabsize := (int(t.nfunctab)*2 + 1) * t.functabFieldSize()
 		t.functab = t.functab[:functabsize]
 	case ver116:
 		t.nfunctab = uint32(offset(0))
 		t.nfiletab = uint32(offset(1))
-		t.funcnametab = data(2)
+		t.funcnametab = t.boundedNameTable(data(2), offset(2), offset(3))
 		t.cutab = data(3)
 		t.filetab = data(4)
 		t.pctab = data(5)
 		t.funcdata = data(6)
 		t.functab = data(6)
+		t.checkFuncCount()
 		functabsize := (int(t.nfunctab)*2 + 1) * t.functabFieldSize()
 		t.functab = t.functab[:functabsize]
 	case ver12:
@@ -330,6 +391,7 @@ func (t *LineTable) parsePclnTab(versionOverride string) {
 		t.funcnametab = t.Data
 		t.functab = t.Data[8+t.Ptrsize:]
 		t.pctab = t.Data
+		t.checkFuncCount()
 		functabsize := (int(t.nfunctab)*2 + 1) * t.functabFieldSize()
 		fileoff := t.Binary.Uint32(t.functab[functabsize:])
 		t.functab = t.functab[:functabsize]
@@ -341,8 +403,155 @@ func (t *LineTable) parsePclnTab(versionOverride string) {
 	}
 }
 
+// boundedNameTable cuts the funcnametab at start for a Tolerant parse at next, the offset of the cutab which follows it, so a
+// name offset past the names is caught instead of read from the tables after them. Kept whole when next doesn't follow start.
+func (t *LineTable) boundedNameTable(funcnametab []byte, start uint64, next uint64) []byte {
+	if t.Tolerant && next > start && next-start < uint64(len(funcnametab)) {
+		return funcnametab[:next-start]
+	}
+	return funcnametab
+}
+
+// subtable resolves a sub-table outside Data through the section map. The header stores offsets from itself, tampered tables may store VAs instead.
+// Panics if neither resolves, like an out of bounds slice of Data would.
+func (t *LineTable) subtable(off uint64) []byte {
+	if sub, err := t.ReadMemory(t.HeaderVA + off); err == nil {
+		return sub
+	}
+
+	if off >= t.HeaderVA {
+		if sub, err := t.ReadMemory(off); err == nil {
+			return sub
+		}
+	}
+	panic("pclntab sub-table out of bounds")
+}
+
+// maxFuncs bounds the function count to avoid OOM on corrupt binaries, see go12Funcs
+const maxFuncs = 350000
+
+// the most steps go12CodeEnd runs a table for, a corrupt one doesn't end
+const maxCodeEndSteps = 1 << 20
+
+// validFuncEntry reports whether the i'th slot of the (unbounded) functab looks like a real function.
+// The PCs must be sorted, the _func records laid out in order, and the name offset in bounds.
+// A strict check additionally requires the _func entry to point back at the functab PC, obfuscators such as garble
+// scramble that field so it's only used to decide if entries past the declared count are real.
+func (t *LineTable) validFuncEntry(i int, prevPC uint64, prevOff uint64, strict bool) bool {
+	ft := t.funcTab()
+	// entry pc, func offset, and the next pc which is the end of this function
+	if (2*i+3)*ft.sz > len(t.functab) {
+		return false
+	}
+
+	pc := ft.pc(i)
+	if pc < prevPC || ft.pc(i+1) < pc {
+		return false
+	}
+
+	off := ft.funcOff(i)
+	entrySize := uint64(t.Ptrsize)
+	if t.Version >= ver118 {
+		entrySize = 4
+	}
+	// the fields go12Funcs reads, up to the deferreturn, and a terminated name, a truncated table ends at the last whole one
+	if off <= prevOff || off+entrySize+3*4 > uint64(len(t.funcdata)) {
+		return false
+	}
+
+	info := funcData{t: t, data: t.funcdata[off:]}
+	if uint64(info.nameoff()) >= uint64(len(t.funcnametab)) || bytes.IndexByte(t.funcnametab[info.nameoff():], 0) < 0 {
+		return false
+	}
+	return !strict || info.entryPC() == pc
+}
+
+// declaredCountValid reports whether nfunc holds up without walking the functab: the first and the last entries it declares are
+// functions, the end pc follows the last, and the slot after the end isn't one more function.
+func (t *LineTable) declaredCountValid() bool {
+	n := int(t.nfunctab)
+	if n == 0 || n >= maxFuncs || !t.validFuncEntry(0, 0, 0, false) || !t.validFuncEntry(n-1, 0, 0, false) {
+		return false
+	}
+	ft := t.funcTab()
+	return !t.validFuncEntry(n, ft.pc(n-1), ft.funcOff(n-1), true)
+}
+
+// checkFuncCount keeps nfunc when it holds up, else recovers the count from the functab. A Tolerant parse always walks it, to
+// report the corrupt entries. Must be called before t.functab is bounded by nfunctab.
+func (t *LineTable) checkFuncCount() {
+	if !t.Tolerant && t.declaredCountValid() {
+		t.DeclaredFuncs, t.ValidatedFuncs, t.countKept = t.nfunctab, t.nfunctab, true
+		return
+	}
+	t.recoverFuncCount()
+}
+
+// recoverFuncCount walks the functab rather than trusting nfunc, which may have been tampered with.
+// Entries are accepted until one is invalid, so a count that is too large is truncated and one that is too small is extended.
+// Must be called before t.functab is bounded by nfunctab.
+func (t *LineTable) recoverFuncCount() {
+	t.DeclaredFuncs = t.nfunctab
+
+	walked := 0
+	prevPC := uint64(0)
+	prevOff := uint64(0)
+	for walked < maxFuncs && t.validFuncEntry(walked, prevPC, prevOff, walked >= int(t.DeclaredFuncs)) {
+		prevPC = t.funcTab().pc(walked)
+		prevOff = t.funcTab().funcOff(walked)
+		walked++
+	}
+
+	// if nothing validated our checks don't understand this table, leave it as is
+	t.ValidatedFuncs = uint32(walked)
+	if t.Tolerant {
+		walked = t.tolerantFuncCount(walked)
+	}
+	if walked == 0 {
+		return
+	}
+	t.nfunctab = uint32(walked)
+}
+
+// tolerantFuncCount is the function count of a Tolerant parse given the walked count of recoverFuncCount. The entries after one
+// that fails validation are kept up to the last declared one that looks real on its own, so a corrupt entry costs only its function.
+func (t *LineTable) tolerantFuncCount(walked int) int {
+	declared := min(int(t.DeclaredFuncs), maxFuncs)
+	count := walked
+	for i := declared - 1; i >= walked; i-- {
+		if t.validFuncEntry(i, 0, 0, false) {
+			count = i + 1
+			break
+		}
+	}
+	if count > walked {
+		t.corrupt(-1, 0, "count", fmt.Sprintf("the functab stops validating at entry %d, the %d entries from there to the last valid one are kept", walked, count-walked))
+	}
+	return count
+}
+
+// corrupt records a problem a Tolerant parse worked around
+func (t *LineTable) corrupt(index int, entry uint64, kind string, reason string) {
+	t.Corruption = append(t.Corruption, Corruption{Index: index, Entry: entry, Kind: kind, Reason: reason})
+}
+
 // go12Funcs returns a slice of Funcs derived from the Go 1.2+ pcln table.
 func (t *LineTable) go12Funcs() []Func {
+	if t.Tolerant {
+		return t.go12TolerantFuncs()
+	}
+	funcs := t.go12StrictFuncs()
+	if funcs == nil && t.countKept {
+		// nfunc held up but an entry it declares doesn't read, the functions are the ones before the first such entry
+		t.countKept = false
+		t.recoverFuncCount()
+		funcs = t.go12StrictFuncs()
+	}
+	return funcs
+}
+
+// go12StrictFuncs is go12Funcs of the functab as bounded by nfunctab, nil when an entry doesn't read
+func (t *LineTable) go12StrictFuncs() []Func {
 	// Assume it is malformed and return nil on error.
 	if !disableRecover {
 		defer func() {
@@ -353,7 +562,7 @@ func (t *LineTable) go12Funcs() []Func {
 	// avoid OOM error on corrupt binaries
 	// empirically gathered. Most binaries are <= UINT16_MAX, but some truly huge have >= 100000 functions
 	ft := t.funcTab()
-	if ft.Count() >= 350000 {
+	if ft.Count() >= maxFuncs {
 		return make([]Func, 0)
 	}
 
@@ -379,19 +588,191 @@ func (t *LineTable) go12Funcs() []Func {
 	return funcs
 }
 
+// go12TolerantFuncs is go12Funcs for a Tolerant parse. A function whose _func or name doesn't read gets the placeholder name
+// sub_<entry>, one whose pcfile or pcln table doesn't decode loses its line info, and entries out of order are reported and sorted
+// rather than ending the table.
+func (t *LineTable) go12TolerantFuncs() []Func {
+	// a table that isn't a pclntab at all, only one with corrupt functions, is still all or nothing
+	if !disableRecover {
+		defer func() {
+			recover()
+		}()
+	}
+
+	ft := t.funcTab()
+	if ft.Count() >= maxFuncs {
+		return make([]Func, 0)
+	}
+
+	order := make([]int, ft.Count())
+	for i := range order {
+		order[i] = i
+		entry, next := ft.pc(i), ft.pc(i+1)
+		if i > 0 && entry < ft.pc(i-1) {
+			t.corrupt(i, entry, "order", fmt.Sprintf("the entry comes before the entry 0x%x of the function before it", ft.pc(i-1)))
+		} else if next < entry {
+			t.corrupt(i, entry, "order", fmt.Sprintf("the entry of the function after it, 0x%x, overlaps it", next))
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return ft.pc(order[a]) < ft.pc(order[b]) })
+
+	t.noLineInfo = make(map[int]bool)
+	funcs := make([]Func, len(order))
+	syms := make([]Sym, len(funcs))
+	for n, i := range order {
+		f := &funcs[n]
+		f.Entry = ft.pc(i)
+		// an overlapping function ends where the next one starts
+		f.End = ft.pc(i + 1)
+		if n+1 < len(order) && (f.End < f.Entry || ft.pc(order[n+1]) < f.End) {
+			f.End = ft.pc(order[n+1])
+		} else if f.End < f.Entry {
+			f.End = f.Entry
+		}
+		f.LineTable = t
+		name, frameSize := t.tolerantFunc(i, f.Entry)
+		f.FrameSize = frameSize
+		syms[n] = Sym{
+			Value:     f.Entry,
+			Type:      'T',
+			Name:      name,
+			GoType:    0,
+			Func:      f,
+			GoVersion: t.Version,
+		}
+		f.Sym = &syms[n]
+	}
+	return funcs
+}
+
+// tolerantFunc reads the name and frame size of the i'th function of the functab for go12TolerantFuncs and checks its pcfile and
+// pcln tables decode. The name is sub_<entry> when the _func is out of bounds, the name offset is past the names or the name isn't
+// one the compiler could have written.
+func (t *LineTable) tolerantFunc(i int, entry uint64) (name string, frameSize int) {
+	name = fmt.Sprintf("sub_%x", entry)
+	defer func() {
+		if !disableRecover && recover() != nil {
+			t.corrupt(i, entry, "funcdata", "the _func runs past the function data")
+			frameSize = 0
+			t.noLineInfo[i] = true
+		}
+	}()
+
+	if off := t.funcTab().funcOff(i); off >= uint64(len(t.funcdata)) {
+		t.corrupt(i, entry, "funcdata", fmt.Sprintf("the _func offset 0x%x is past the function data", off))
+		t.noLineInfo[i] = true
+		return name, 0
+	}
+	info := t.funcData(uint32(i))
+	frameSize = int(info.deferreturn())
+
+	for _, table := range []struct {
+		name string
+		off  uint32
+	}{{"pcfile", info.pcfile()}, {"pcln", info.pcln()}} {
+		if reason := t.undecodable(table.off, entry); len(reason) > 0 {
+			t.corrupt(i, entry, "lines", fmt.Sprintf("the %s table %s, the line info is dropped", table.name, reason))
+			t.noLineInfo[i] = true
+			break
+		}
+	}
+
+	nameoff := info.nameoff()
+	if uint64(nameoff) >= uint64(len(t.funcnametab)) {
+		t.corrupt(i, entry, "name", fmt.Sprintf("the name offset 0x%x is past the 0x%x bytes of names", nameoff, len(t.funcnametab)))
+		return name, frameSize
+	}
+	end := bytes.IndexByte(t.funcnametab[nameoff:], 0)
+	switch {
+	case end < 0:
+		t.corrupt(i, entry, "name", fmt.Sprintf("the name at 0x%x runs past the names", nameoff))
+	case end == 0:
+		t.corrupt(i, entry, "name", fmt.Sprintf("the name at 0x%x is empty", nameoff))
+	case !printableName(t.funcnametab[nameoff : nameoff+uint32(end)]):
+		t.corrupt(i, entry, "name", fmt.Sprintf("the name at 0x%x isn't printable", nameoff))
+	default:
+		name = t.funcName(nameoff)
+	}
+	return name, frameSize
+}
+
+// undecodable is why the pc-value table at off of the function at entry doesn't decode, empty when it does or there's none
+func (t *LineTable) undecodable(off uint32, entry uint64) (reason string) {
+	if off == 0 {
+		return ""
+	}
+	if uint64(off) >= uint64(len(t.pctab)) {
+		return fmt.Sprintf("offset 0x%x is past the pc tables", off)
+	}
+	defer func() {
+		if !disableRecover && recover() != nil {
+			reason = fmt.Sprintf("at 0x%x runs past the pc tables", off)
+		}
+	}()
+	p := t.pctab[off:]
+	pc, val := entry, int32(-1)
+	for t.step(&p, &pc, &val, pc == entry) {
+	}
+	return ""
+}
+
+// printableName reports whether a function name is valid UTF-8 without control characters, as every name the compiler writes is
+func printableName(name []byte) bool {
+	if !utf8.Valid(name) {
+		return false
+	}
+	for _, r := range string(name) {
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
 // findFunc returns the funcData corresponding to the given program counter.
 func (t *LineTable) findFunc(pc uint64) funcData {
-	ft := t.funcTab()
-	if pc < ft.pc(0) || pc >= ft.pc(ft.Count()) {
+	idx, ok := t.findFuncIndex(pc)
+	if !ok {
 		return funcData{}
 	}
-	idx := sort.Search(int(t.nfunctab), func(i int) bool {
-		return ft.pc(i) > pc
-	})
-	idx--
 	return t.funcData(uint32(idx))
 }
 
+// findFuncIndex returns the functab index of the function containing pc, false when none does
+func (t *LineTable) findFuncIndex(pc uint64) (int, bool) {
+	ft := t.funcTab()
+	if pc >= ft.pc(0) && pc < ft.pc(ft.Count()) {
+		idx := sort.Search(int(t.nfunctab), func(i int) bool {
+			return ft.pc(i) > pc
+		})
+		idx--
+		if !t.Tolerant || (ft.pc(idx) <= pc && pc < ft.pc(idx+1)) {
+			return idx, true
+		}
+	}
+	if !t.Tolerant {
+		return 0, false
+	}
+
+	// the entries of a Tolerant parse may be out of order, the search above only holds for the ordered ones
+	best := -1
+	for i := 0; i < ft.Count(); i++ {
+		if entry := ft.pc(i); entry <= pc && (best < 0 || entry > ft.pc(best)) {
+			best = i
+		}
+	}
+	return best, best >= 0
+}
+
+// lineInfoDropped reports whether a Tolerant parse dropped the line info of the function containing pc
+func (t *LineTable) lineInfoDropped(pc uint64) bool {
+	if len(t.noLineInfo) == 0 {
+		return false
+	}
+	idx, ok := t.findFuncIndex(pc)
+	return ok && t.noLineInfo[idx]
+}
+
 // readvarint reads, removes, and returns a varint from *pp.
 func (t *LineTable) readvarint(pp *[]byte) uint32 {
 	var v, shift uint32
@@ -512,7 +893,9 @@ func (f *funcData) entryPC() uint64 {
 }
 
 func (f funcData) nameoff() uint32     { return f.field(1) }
+func (f funcData) args() int32         { return int32(f.field(2)) }
 func (f funcData) deferreturn() uint32 { return f.field(3) }
+func (f funcData) pcsp() uint32        { return f.field(4) }
 func (f funcData) pcfile() uint32      { return f.field(5) }
 func (f funcData) pcln() uint32        { return f.field(6) }
 func (f funcData) cuOffset() uint32    { return f.field(8) }
@@ -535,6 +918,69 @@ func (f funcData) field(n uint32) uint32 {
 	return f.t.Binary.Uint32(data)
 }
 
+// the pcdata table whose value is the index in the inline tree of the code at a pc, -1 outside inlined code
+const pcdataInlTreeIndex = 2
+
+// the funcdata of the stack maps of the arguments and of the locals, each a runtime.stackmap
+const (
+	funcdataArgsPointerMaps   = 0
+	funcdataLocalsPointerMaps = 1
+)
+
+// the funcdata of the inline tree, an array of runtime.inlinedCall, the register maps before it were dropped in Go 1.16
+const (
+	funcdataInlTree        = 3
+	funcdataInlTreeRegMaps = 4
+)
+
+// funcID is the byte before nfuncdata, the last of the fixed fields from Go 1.12 on
+func (f funcData) funcID() uint8 { return f.data[f.fixedSize()-4] }
+
+// fixedSize is the size of the fixed fields of _func, the pcdata offsets follow them. It grew cuOffset in Go 1.16 and startLine
+// in Go 1.20.
+func (f funcData) fixedSize() uint32 {
+	switch {
+	case f.t.Version >= ver120:
+		return 4 + 10*4
+	case f.t.Version >= ver118:
+		return 4 + 9*4
+	case f.t.Version >= ver116:
+		return f.t.Ptrsize + 9*4
+	}
+	return f.t.Ptrsize + 8*4
+}
+
+// pcdata returns the offset of the nth pcdata table in pctab, 0 when the function has none.
+func (f funcData) pcdata(n uint32) uint32 {
+	if n >= f.field(7) { // npcdata
+		return 0
+	}
+	return f.t.Binary.Uint32(f.data[f.fixedSize()+n*4:])
+}
+
+// funcdata returns the nth funcdata of the function, ok is false when it has none. From Go 1.18 on it's an offset from the
+// go:func.* symbol of the module, before it's an address. nfuncdata is the last byte of the fixed fields from Go 1.12 on, before
+// it's the whole last field, wideCount.
+func (f funcData) funcdata(n uint32, wideCount bool) (value uint64, ok bool) {
+	size := f.fixedSize()
+	count := uint32(f.data[size-1])
+	if wideCount {
+		count = f.field(8)
+	}
+	if n >= count {
+		return 0, false
+	}
+	off := size + f.field(7)*4
+	if f.t.Version >= ver118 {
+		value := f.t.Binary.Uint32(f.data[off+n*4:])
+		return uint64(value), value != ^uint32(0)
+	}
+	// the pointers are aligned
+	off = (off + f.t.Ptrsize - 1) &^ (f.t.Ptrsize - 1)
+	value = f.t.uintptr(f.data[off+n*f.t.Ptrsize:])
+	return value, value != 0
+}
+
 // step advances to the next pc, value pair in the encoded table.
 func (t *LineTable) step(p *[]byte, pc *uint64, val *int32, first bool) bool {
 	uvdelta := t.readvarint(p)
@@ -625,7 +1071,7 @@ func (t *LineTable) go12PCToLine(pc uint64) (line int) {
 	}()
 
 	f := t.findFunc(pc)
-	if f.IsZero() {
+	if f.IsZero() || t.lineInfoDropped(pc) {
 		return -1
 	}
 	entry := f.entryPC()
@@ -642,12 +1088,16 @@ func (t *LineTable) go12PCToFile(pc uint64) (file string) {
 	}()
 
 	f := t.findFunc(pc)
-	if f.IsZero() {
+	if f.IsZero() || t.lineInfoDropped(pc) {
 		return ""
 	}
 	entry := f.entryPC()
 	filetab := f.pcfile()
-	fno := t.pcvalue(filetab, entry, pc)
+	return t.go12FileName(f, t.pcvalue(filetab, entry, pc))
+}
+
+// go12FileName maps a file number of f's pcfile table to the file name.
+func (t *LineTable) go12FileName(f funcData, fno int32) string {
 	if t.Version == ver12 {
 		if fno <= 0 {
 			return ""
@@ -665,6 +1115,179 @@ func (t *LineTable) go12PCToFile(pc uint64) (file string) {
 	return ""
 }
 
+// go12LineRows runs the pcfile and pcln tables of the function at entry together, a row starts wherever the file or the line changes.
+func (t *LineTable) go12LineRows(entry uint64, end uint64) (rows []LineRow) {
+	defer func() {
+		if !disableRecover && recover() != nil {
+			rows = nil
+		}
+	}()
+
+	f := t.findFunc(entry)
+	if f.IsZero() || f.pcfile() == 0 || f.pcln() == 0 || t.lineInfoDropped(entry) {
+		return nil
+	}
+	fp := t.pctab[f.pcfile():]
+	fl := t.pctab[f.pcln():]
+	// each step gives the value in effect up to the pc it advances to
+	fileVal, filePC, fileEnd := int32(-1), entry, entry
+	lineVal, linePC, lineEnd := int32(-1), entry, entry
+	// without an inline tree index table nothing was inlined, it's -1 up to the end
+	var fi []byte
+	inlVal, inlPC, inlEnd := int32(-1), entry, end
+	if off := f.pcdata(pcdataInlTreeIndex); off != 0 {
+		fi, inlEnd = t.pctab[off:], entry
+	}
+	pc := entry
+	for pc < end {
+		for fileEnd <= pc && t.step(&fp, &filePC, &fileVal, filePC == entry) {
+			fileEnd = filePC
+		}
+		for lineEnd <= pc && t.step(&fl, &linePC, &lineVal, linePC == entry) {
+			lineEnd = linePC
+		}
+		for fi != nil && inlEnd <= pc && t.step(&fi, &inlPC, &inlVal, inlPC == entry) {
+			inlEnd = inlPC
+		}
+		if fileEnd <= pc || lineEnd <= pc {
+			break
+		}
+		if inlEnd <= pc {
+			inlVal, inlEnd = -1, end
+		}
+		file := t.go12FileName(f, fileVal)
+		last := len(rows) - 1
+		if last < 0 || rows[last].File != file || rows[last].Line != int(lineVal) || rows[last].Inlined != (inlVal >= 0) {
+			rows = append(rows, LineRow{PC: pc, File: file, Line: int(lineVal), Inlined: inlVal >= 0})
+		}
+		pc = min(fileEnd, lineEnd, inlEnd)
+	}
+	return rows
+}
+
+// go12SPRows runs the pcsp table of the function at entry, a row starts wherever the sp delta changes.
+func (t *LineTable) go12SPRows(entry uint64, end uint64) (rows []SPRow) {
+	defer func() {
+		if !disableRecover && recover() != nil {
+			rows = nil
+		}
+	}()
+
+	f := t.findFunc(entry)
+	if f.IsZero() || f.pcsp() == 0 {
+		return nil
+	}
+	p := t.pctab[f.pcsp():]
+	// the value before the first step is -1, no code has it
+	pc, val := entry, int32(-1)
+	for start := entry; start < end && t.step(&p, &pc, &val, pc == entry); start = pc {
+		if last := len(rows) - 1; last < 0 || rows[last].SPDelta != int(val) {
+			rows = append(rows, SPRow{PC: start, SPDelta: int(val)})
+		}
+	}
+	return rows
+}
+
+// go12PCDataRanges runs the nth pcdata table of the function at entry, a range ends wherever the value changes or at end.
+func (t *LineTable) go12PCDataRanges(entry uint64, end uint64, n uint32) (ranges []PCValueRange) {
+	defer func() {
+		if !disableRecover && recover() != nil {
+			ranges = nil
+		}
+	}()
+
+	f := t.findFunc(entry)
+	if f.IsZero() {
+		return nil
+	}
+	off := f.pcdata(n)
+	if off == 0 {
+		return nil
+	}
+	p := t.pctab[off:]
+	pc, val := entry, int32(-1)
+	for start := entry; start < end && t.step(&p, &pc, &val, pc == entry); start = pc {
+		stop := min(pc, end)
+		if last := len(ranges) - 1; last >= 0 && ranges[last].Value == int(val) && ranges[last].End == start {
+			ranges[last].End = stop
+			continue
+		}
+		ranges = append(ranges, PCValueRange{Start: start, End: stop, Value: int(val)})
+	}
+	return ranges
+}
+
+// go12StackMaps locates the runtime.stackmap of the arguments and of the locals of the function at entry, see Table.StackMaps.
+func (t *LineTable) go12StackMaps(entry uint64, gofunc uint64, wideCount bool) (args uint64, locals uint64) {
+	defer func() {
+		if !disableRecover && recover() != nil {
+			args, locals = 0, 0
+		}
+	}()
+
+	f := t.findFunc(entry)
+	if f.IsZero() || (t.Version >= ver118 && gofunc == 0) {
+		return 0, 0
+	}
+	locate := func(index uint32) uint64 {
+		value, ok := f.funcdata(index, wideCount)
+		if !ok {
+			return 0
+		}
+		if t.Version >= ver118 {
+			value += gofunc
+		}
+		return value
+	}
+	return locate(funcdataArgsPointerMaps), locate(funcdataLocalsPointerMaps)
+}
+
+// go12CodeEnd runs a pc-value table of the function at entry to its end, the pcsp table or when it has none the pcln table. The linker
+// writes them over every byte of the function's code, so the pc the last step reaches is where the code ends, before the padding to
+// the next function. 0 when the function has neither.
+func (t *LineTable) go12CodeEnd(entry uint64) (end uint64) {
+	defer func() {
+		if !disableRecover && recover() != nil {
+			end = 0
+		}
+	}()
+
+	f := t.findFunc(entry)
+	if f.IsZero() {
+		return 0
+	}
+	off := f.pcsp()
+	if off == 0 {
+		off = f.pcln()
+	}
+	if off == 0 {
+		return 0
+	}
+	p := t.pctab[off:]
+	pc, val := entry, int32(-1)
+	for steps := 0; steps < maxCodeEndSteps; steps++ {
+		if !t.step(&p, &pc, &val, pc == entry) {
+			break
+		}
+	}
+	return pc
+}
+
+// go12FuncFields reads the fields of the _func of the function at entry, see Table.FuncFields.
+func (t *LineTable) go12FuncFields(entry uint64) (fields FuncFields, ok bool) {
+	defer func() {
+		if !disableRecover && recover() != nil {
+			fields, ok = FuncFields{}, false
+		}
+	}()
+
+	f := t.findFunc(entry)
+	if f.IsZero() {
+		return FuncFields{}, false
+	}
+	return FuncFields{Args: f.args(), DeferReturn: f.deferreturn(), FuncID: f.funcID()}, true
+}
+
 // go12LineToPC maps a (file, line) pair to a program counter for the Go 1.2+ pcln table.
 func (t *LineTable) go12LineToPC(file string, line int) (pc uint64) {
 	defer func() {
@@ -744,3 +1367,127 @@ func (t *LineTable) go12MapFiles(m map[string]*Obj, obj *Obj) {
 // disableRecover causes this package not to swallow panics.
 // This is useful when making changes.
 const disableRecover = false
+
+// the size of a runtime.inlinedCall, Go 1.20 dropped the parent, file and line for the start line of the callee
+func (t *LineTable) inlinedCallSize() uint64 {
+	if t.Version >= ver120 {
+		return 16
+	}
+	return 20
+}
+
+// go12InlineTree locates the inline tree of the function at entry, see Table.InlineTree.
+func (t *LineTable) go12InlineTree(entry uint64, gofunc uint64, regMaps bool) (addr uint64, size uint64, ok bool) {
+	defer func() {
+		if !disableRecover && recover() != nil {
+			addr, size, ok = 0, 0, false
+		}
+	}()
+
+	f := t.findFunc(entry)
+	if f.IsZero() {
+		return 0, 0, false
+	}
+	off := f.pcdata(pcdataInlTreeIndex)
+	index := uint32(funcdataInlTree)
+	if regMaps {
+		index = funcdataInlTreeRegMaps
+	}
+	value, hasTree := f.funcdata(index, false)
+	if off == 0 || !hasTree {
+		return 0, 0, false
+	}
+	// parents come before their children in the tree, the last index in use is its last entry
+	p := t.pctab[off:]
+	pc, val, last := entry, int32(-1), int32(-1)
+	for t.step(&p, &pc, &val, pc == entry) {
+		last = max(last, val)
+	}
+	if last < 0 {
+		return 0, 0, false
+	}
+	if t.Version >= ver118 {
+		if gofunc == 0 {
+			return 0, 0, false
+		}
+		value += gofunc
+	}
+	return value, uint64(last+1) * t.inlinedCallSize(), true
+}
+
+// go12InlinedCalls decodes the inline tree of the function from entry to end, see Table.InlinedCalls.
+func (t *LineTable) go12InlinedCalls(entry uint64, end uint64, tree []byte) (calls []InlinedCall) {
+	defer func() {
+		if !disableRecover && recover() != nil {
+			calls = nil
+		}
+	}()
+
+	f := t.findFunc(entry)
+	off := f.pcdata(pcdataInlTreeIndex)
+	if f.IsZero() || off == 0 {
+		return nil
+	}
+	size := t.inlinedCallSize()
+	count := uint64(len(tree)) / size
+
+	// the tree index in effect at each pc
+	var ranges []struct {
+		start, end uint64
+		index      int32
+	}
+	p := t.pctab[off:]
+	pc, val := entry, int32(-1)
+	for start := entry; t.step(&p, &pc, &val, pc == entry) && start < end; start = pc {
+		ranges = append(ranges, struct {
+			start, end uint64
+			index      int32
+		}{start, min(pc, end), val})
+	}
+	indexAt := func(pc uint64) int32 {
+		for _, r := range ranges {
+			if pc >= r.start && pc < r.end {
+				return r.index
+			}
+		}
+		return -1
+	}
+
+	for i := uint64(0); i < count; i++ {
+		entryData := tree[i*size : (i+1)*size]
+		var nameOff, parentPC uint32
+		if t.Version >= ver120 {
+			// funcID, 3 bytes of padding, nameOff, parentPc, startLine
+			nameOff, parentPC = t.Binary.Uint32(entryData[4:]), t.Binary.Uint32(entryData[8:])
+		} else {
+			// parent, funcID, a byte of padding, file, line, func_, parentPc
+			nameOff, parentPC = t.Binary.Uint32(entryData[12:]), t.Binary.Uint32(entryData[16:])
+		}
+		if uint64(nameOff) >= uint64(len(t.funcnametab)) {
+			return nil
+		}
+		// the instruction at parentPc is at the call site, in the frame of the parent
+		callPC := entry + uint64(parentPC)
+		call := InlinedCall{
+			Name:     t.funcName(nameOff),
+			Parent:   int(indexAt(callPC)),
+			CallFile: t.go12FileName(f, t.pcvalue(f.pcfile(), entry, callPC)),
+			CallLine: int(t.pcvalue(f.pcln(), entry, callPC)),
+		}
+		if call.Parent >= int(i) {
+			return nil
+		}
+		for _, r := range ranges {
+			if r.index != int32(i) {
+				continue
+			}
+			if last := len(call.Ranges) - 1; last >= 0 && call.Ranges[last].End == r.start {
+				call.Ranges[last].End = r.end
+			} else {
+				call.Ranges = append(call.Ranges, PCRange{r.start, r.end})
+			}
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
@@ -0,0 +1,492 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package gosym
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPclntabVersionForGoVersion(t *testing.T) {
+	cases := map[string]version{
+		"1.2":       ver12,
+		"1.5":       ver12,
+		"1.10":      ver12,
+		"1.11":      ver12,
+		"1.14.15":   ver12,
+		"go1.15":    ver12,
+		"1.16":      ver116,
+		"1.17.3":    ver116,
+		"1.18beta2": ver118,
+		"1.19":      ver118,
+		"1.20":      ver120,
+		"go1.21rc1": ver120,
+		"1.22.0":    ver120,
+		"1.1":       ver11,
+		"1.0":       ver11,
+		"2.0":       ver11,
+		"garbage":   ver11,
+	}
+
+	for goVersion, expected := range cases {
+		if got := pclntabVersionForGoVersion(goVersion); got != expected {
+			t.Errorf("%s: expected pclntab %s, got %s", goVersion, expected, got)
+		}
+	}
+}
+
+func TestPclntabLayoutForGoVersion(t *testing.T) {
+	cases := map[string]string{
+		"1.14.15": "1.2",
+		"1.17":    "1.16",
+		"1.22.12": "1.20",
+		"1.1":     "",
+		"":        "",
+	}
+
+	for goVersion, expected := range cases {
+		if got := PclntabLayoutForGoVersion(goVersion); got != expected {
+			t.Errorf("%s: expected layout %q, got %q", goVersion, expected, got)
+		}
+	}
+}
+
+// buildGo12Pclntab lays out a minimal 64bit little endian Go 1.2-1.15 style pclntab.
+// Unlike the newer layouts there is no header of offsets, nfunctab is the only header word and
+// function names are offsets from the very start of the table.
+func buildGo12Pclntab(entries []uint64, names []string) []byte {
+	return buildGo12PclntabPtrSize(8, entries, names)
+}
+
+// buildGo12PclntabPtrSize is buildGo12Pclntab for a pointer size of 4 or 8, the functab and the entry of each _func are pointer
+// sized while the other fields stay 4 bytes.
+func buildGo12PclntabPtrSize(ptrSize int, entries []uint64, names []string) []byte {
+	funcSize := ptrSize + 9*4
+	putPtr := func(b []byte, v uint64) {
+		if ptrSize == 4 {
+			binary.LittleEndian.PutUint32(b, uint32(v))
+			return
+		}
+		binary.LittleEndian.PutUint64(b, v)
+	}
+
+	nfunc := len(entries)
+	functabOff := 8 + ptrSize
+	funcdataOff := functabOff + (2*nfunc+1)*ptrSize + 4
+	nameOff := funcdataOff + nfunc*funcSize
+	size := nameOff
+	for _, name := range names {
+		size += len(name) + 1
+	}
+	fileOff := size
+	size += 8
+
+	data := make([]byte, size)
+	binary.LittleEndian.PutUint32(data, 0xfffffffb)
+	data[6] = 1 // quantum
+	data[7] = byte(ptrSize)
+	putPtr(data[8:], uint64(nfunc))
+
+	for i, entry := range entries {
+		funcOff := funcdataOff + i*funcSize
+		putPtr(data[functabOff+2*i*ptrSize:], entry)
+		putPtr(data[functabOff+(2*i+1)*ptrSize:], uint64(funcOff))
+
+		putPtr(data[funcOff:], entry)
+		binary.LittleEndian.PutUint32(data[funcOff+ptrSize:], uint32(nameOff))
+		nameOff += copy(data[nameOff:], names[i]) + 1
+	}
+
+	// end PC sentinel, then the offset of the filetab
+	putPtr(data[functabOff+2*nfunc*ptrSize:], entries[nfunc-1]+0x10)
+	binary.LittleEndian.PutUint32(data[functabOff+(2*nfunc+1)*ptrSize:], uint32(fileOff))
+	binary.LittleEndian.PutUint32(data[fileOff:], 1)
+	return data
+}
+
+func TestGo12Funcs(t *testing.T) {
+	entries := []uint64{0x401000, 0x401040, 0x401100}
+	names := []string{"runtime.main", "main.foo", "main.main"}
+
+	for _, override := range []string{"", "1.10", "1.15"} {
+		table, err := NewTable(nil, NewLineTable(buildGo12Pclntab(entries, names), 0x401000), override)
+		if err != nil {
+			t.Fatalf("override %q: %s", override, err)
+		}
+
+		if table.Go12line == nil || table.Go12line.Version != ver12 {
+			t.Fatalf("override %q: expected a 1.2 pclntab", override)
+		}
+
+		if len(table.Funcs) != len(entries) {
+			t.Fatalf("override %q: expected %d functions, got %d", override, len(entries), len(table.Funcs))
+		}
+
+		for i, fn := range table.Funcs {
+			if fn.Name != names[i] || fn.Entry != entries[i] {
+				t.Errorf("override %q: function %d is %s@%x, expected %s@%x", override, i, fn.Name, fn.Entry, names[i], entries[i])
+			}
+		}
+
+		// the last function ends at the sentinel PC, not past the table
+		if last := table.Funcs[len(entries)-1]; last.End != entries[len(entries)-1]+0x10 {
+			t.Errorf("override %q: last function has wrong end %x", override, last.End)
+		}
+	}
+}
+
+func TestGo12PtrSize4(t *testing.T) {
+	const ptrSize = 4
+	entries := []uint64{0x8049000, 0x8049040}
+	names := []string{"main.main", "main.helper"}
+	files := []string{"/src/main.go", "/src/helper.go"}
+	data := buildGo12PclntabPtrSize(ptrSize, entries, names)
+
+	// a filetab of the two files past the table, its count takes slot 0 as file 0 is none and the names are offsets from the start of the table
+	fileOff := len(data)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(files)+1))
+	nameOff := len(data) + 4*len(files)
+	for _, file := range files {
+		data = binary.LittleEndian.AppendUint32(data, uint32(nameOff))
+		nameOff += len(file) + 1
+	}
+	for _, file := range files {
+		data = append(append(data, file...), 0)
+	}
+	binary.LittleEndian.PutUint32(data[8+ptrSize+(2*len(entries)+1)*ptrSize:], uint32(fileOff))
+
+	// function i is in file i+1, line 10 for 0x10 bytes then line 12
+	for i := range entries {
+		funcOff := binary.LittleEndian.Uint32(data[8+ptrSize+(2*i+1)*ptrSize:])
+		binary.LittleEndian.PutUint32(data[funcOff+ptrSize+4*4:], uint32(len(data)))
+		data = append(data, byte(2*(i+2)), 0x40, 0)
+		binary.LittleEndian.PutUint32(data[funcOff+ptrSize+5*4:], uint32(len(data)))
+		data = append(data, 22, 0x10, 4, 0x30, 0)
+	}
+
+	table, err := NewTable(nil, NewLineTable(data, entries[0]), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Go12line.Version != ver12 || table.Go12line.Ptrsize != ptrSize {
+		t.Fatalf("expected a 32bit 1.2 pclntab, got version %d pointer size %d", table.Go12line.Version, table.Go12line.Ptrsize)
+	}
+	if len(table.Funcs) != len(entries) {
+		t.Fatalf("expected %d functions, got %d", len(entries), len(table.Funcs))
+	}
+	for i, fn := range table.Funcs {
+		if fn.Name != names[i] || fn.Entry != entries[i] {
+			t.Errorf("function %d is %s@%x, expected %s@%x", i, fn.Name, fn.Entry, names[i], entries[i])
+		}
+	}
+
+	tests := []struct {
+		pc   uint64
+		file string
+		line int
+		fn   string
+	}{
+		{entries[0] + 4, files[0], 10, names[0]},
+		{entries[0] + 0x14, files[0], 12, names[0]},
+		{entries[1] + 4, files[1], 10, names[1]},
+	}
+	for _, test := range tests {
+		file, line, fn := table.PCToLine(test.pc)
+		if file != test.file || line != test.line || fn == nil || fn.Name != test.fn {
+			t.Errorf("0x%x: expected %s:%d in %s, got %s:%d in %v", test.pc, test.file, test.line, test.fn, file, line, fn)
+		}
+	}
+	if pc, fn, err := table.LineToPC(files[0], 12); err != nil || pc != entries[0]+0x10 || fn.Name != names[0] {
+		t.Errorf("expected %s:12 at 0x%x, got 0x%x %v", files[0], entries[0]+0x10, pc, err)
+	}
+}
+
+func TestSPRows(t *testing.T) {
+	const ptrSize = 8
+	entries := []uint64{0x401000, 0x401040}
+	names := []string{"main.main", "runtime.morestack"}
+	// sp -1 to 0 for 1 pc quantum, 0 to 8 for 12, 8 to 0 for 3, then the end
+	pcsp := []byte{2, 1, 16, 12, 15, 3, 0}
+
+	for _, quantum := range []uint64{1, 4} {
+		data := buildGo12Pclntab(entries, names)
+		data[6] = byte(quantum)
+		// only the first function has a pcsp table, the second is like an assembly stub
+		funcOff := binary.LittleEndian.Uint64(data[8+ptrSize+ptrSize:])
+		binary.LittleEndian.PutUint32(data[funcOff+ptrSize+3*4:], uint32(len(data)))
+		data = append(data, pcsp...)
+
+		table, err := NewTable(nil, NewLineTable(data, entries[0]), "")
+		if err != nil {
+			t.Fatalf("quantum %d: %s", quantum, err)
+		}
+		expected := []SPRow{{entries[0], 0}, {entries[0] + quantum, 8}, {entries[0] + 13*quantum, 0}}
+		rows := table.SPRows(&table.Funcs[0])
+		if len(rows) != len(expected) {
+			t.Fatalf("quantum %d: expected %+v, got %+v", quantum, expected, rows)
+		}
+		for i := range rows {
+			if rows[i] != expected[i] {
+				t.Errorf("quantum %d: expected %+v, got %+v", quantum, expected, rows)
+			}
+		}
+		if size := table.MaxFrameSize(&table.Funcs[0]); size != 8 {
+			t.Errorf("quantum %d: expected a frame of 8, got %d", quantum, size)
+		}
+		if rows := table.SPRows(&table.Funcs[1]); rows != nil || table.MaxFrameSize(&table.Funcs[1]) != 0 {
+			t.Errorf("quantum %d: expected no sp deltas without a pcsp table, got %+v", quantum, rows)
+		}
+	}
+}
+
+func TestPCDataRanges(t *testing.T) {
+	const ptrSize = 8
+	entries := []uint64{0x401000, 0x401040}
+	names := []string{"main.main", "runtime.morestack"}
+	// -1 for 4 pcs, -2 for 2, -1 again for 10, then the end
+	unsafePoints := []byte{0, 4, 1, 2, 2, 10, 0}
+
+	data := buildGo12Pclntab(entries, names)
+	// the first function has a single pcdata table, right after its fixed fields
+	funcOff := binary.LittleEndian.Uint64(data[8+ptrSize+ptrSize:])
+	binary.LittleEndian.PutUint32(data[funcOff+ptrSize+6*4:], 1)
+	binary.LittleEndian.PutUint32(data[funcOff+ptrSize+8*4:], uint32(len(data)))
+	data = append(data, unsafePoints...)
+
+	table, err := NewTable(nil, NewLineTable(data, entries[0]), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := entries[0]
+	expected := []PCValueRange{{entry, entry + 4, -1}, {entry + 4, entry + 6, -2}, {entry + 6, entry + 16, -1}}
+	ranges := table.PCDataRanges(&table.Funcs[0], 0)
+	if len(ranges) != len(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, ranges)
+	}
+	for i := range ranges {
+		if ranges[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected, ranges)
+		}
+	}
+	if ranges := table.PCDataRanges(&table.Funcs[0], 1); ranges != nil {
+		t.Errorf("expected no ranges past npcdata, got %+v", ranges)
+	}
+	if ranges := table.PCDataRanges(&table.Funcs[1], 0); ranges != nil {
+		t.Errorf("expected no ranges without a pcdata table, got %+v", ranges)
+	}
+	if args, locals := table.StackMaps(&table.Funcs[0], 0, false); args != 0 || locals != 0 {
+		t.Errorf("expected no stack maps without funcdata, got 0x%x 0x%x", args, locals)
+	}
+}
+
+func TestCodeEnd(t *testing.T) {
+	const ptrSize = 8
+	// a function padded to the next, a label sharing its entry with the next function, one overlapping the next and one without tables
+	entries := []uint64{0x401000, 0x401040, 0x401040, 0x401080, 0x4010c0}
+	names := []string{"main.main", "runtime.label", "main.foo", "main.wrapper", "runtime.asm"}
+	data := buildGo12Pclntab(entries, names)
+	funcOff := func(i int) uint64 { return binary.LittleEndian.Uint64(data[8+ptrSize+(2*i+1)*ptrSize:]) }
+	// sp -1 to 0 for 0x16 then the end, and for 0x50 past the next entry
+	binary.LittleEndian.PutUint32(data[funcOff(0)+ptrSize+3*4:], uint32(len(data)))
+	data = append(data, 2, 0x16, 0)
+	binary.LittleEndian.PutUint32(data[funcOff(3)+ptrSize+3*4:], uint32(len(data)))
+	data = append(data, 2, 0x50, 0)
+
+	table, err := NewTable(nil, NewLineTable(data, entries[0]), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint64{0x401016, 0x401040, 0x401080, 0x4010c0, 0x4010d0}
+	for i := range table.Funcs {
+		if end := table.CodeEnd(&table.Funcs[i]); end != expected[i] {
+			t.Errorf("%s: expected its code to end at 0x%x, got 0x%x", table.Funcs[i].Name, expected[i], end)
+		}
+	}
+}
+
+func TestTamperedNfunc(t *testing.T) {
+	entries := []uint64{0x401000, 0x401040, 0x401100, 0x401180}
+	names := []string{"runtime.main", "main.foo", "main.bar", "main.main"}
+
+	for _, declared := range []uint64{1, 3, 4, 5, 1000} {
+		data := buildGo12Pclntab(entries, names)
+		binary.LittleEndian.PutUint64(data[8:], declared)
+
+		table, err := NewTable(nil, NewLineTable(data, 0x401000), "")
+		if err != nil {
+			t.Fatalf("nfunc %d: %s", declared, err)
+		}
+
+		if table.Go12line.DeclaredFuncs != uint32(declared) {
+			t.Errorf("nfunc %d: declared count not reported, got %d", declared, table.Go12line.DeclaredFuncs)
+		}
+
+		if len(table.Funcs) != len(entries) {
+			t.Fatalf("nfunc %d: expected to recover %d functions, got %d", declared, len(entries), len(table.Funcs))
+		}
+
+		if table.Funcs[len(entries)-1].Name != "main.main" {
+			t.Errorf("nfunc %d: last function is %s", declared, table.Funcs[len(entries)-1].Name)
+		}
+
+		if table.Go12line.ValidatedFuncs != uint32(len(entries)) {
+			t.Errorf("nfunc %d: expected %d validated functions, got %d", declared, len(entries), table.Go12line.ValidatedFuncs)
+		}
+
+		// only the right count is kept without walking the functab
+		if kept := declared == uint64(len(entries)); table.Go12line.countKept != kept {
+			t.Errorf("nfunc %d: expected the count kept to be %v", declared, kept)
+		}
+	}
+}
+
+// buildGo120Pclntab lays out a minimal 64bit little endian Go 1.20+ pclntab whose funcnametab lives elsewhere, at nameTabOff from the header.
+// The other sub-tables are empty and stay contiguous with the header.
+func buildGo120Pclntab(entries []uint32, nameOffs []uint32, nameTabOff uint64) []byte {
+	const ptrSize = 8
+	const headerSize = 8 + 8*ptrSize
+	const funcSize = 44
+
+	nfunc := len(entries)
+	functabOff := headerSize
+	funcdataOff := (2*nfunc + 1) * 4
+	data := make([]byte, functabOff+funcdataOff+nfunc*funcSize)
+	binary.LittleEndian.PutUint32(data, 0xfffffff1)
+	data[6] = 1 // quantum
+	data[7] = ptrSize
+
+	words := []uint64{uint64(nfunc), 1, 0, nameTabOff, headerSize, headerSize, headerSize, uint64(functabOff)}
+	for i, word := range words {
+		binary.LittleEndian.PutUint64(data[8+i*ptrSize:], word)
+	}
+
+	functab := data[functabOff:]
+	for i, entry := range entries {
+		funcOff := funcdataOff + i*funcSize
+		binary.LittleEndian.PutUint32(functab[2*i*4:], entry)
+		binary.LittleEndian.PutUint32(functab[(2*i+1)*4:], uint32(funcOff))
+		binary.LittleEndian.PutUint32(functab[funcOff:], entry)
+		binary.LittleEndian.PutUint32(functab[funcOff+4:], nameOffs[i])
+	}
+	binary.LittleEndian.PutUint32(functab[2*nfunc*4:], entries[nfunc-1]+0x10)
+	return data
+}
+
+func TestSplitFuncnametab(t *testing.T) {
+	const headerVA = 0x500000
+	const nameTabVA = 0x600000
+	names := []byte("runtime.main\x00main.foo\x00main.main\x00")
+	entries := []uint32{0x0, 0x40, 0x100}
+	nameOffs := []uint32{0, 13, 22}
+
+	readMemory := func(VA uint64) ([]byte, error) {
+		if VA == nameTabVA {
+			return names, nil
+		}
+		return nil, errors.New("unmapped")
+	}
+
+	// the header normally stores offsets from itself, a tampered one may store the VA
+	for _, nameTabOff := range []uint64{nameTabVA - headerVA, nameTabVA} {
+		lineTable := NewLineTable(buildGo120Pclntab(entries, nameOffs, nameTabOff), 0x401000)
+		lineTable.HeaderVA = headerVA
+		lineTable.ReadMemory = readMemory
+
+		table, err := NewTable(nil, lineTable, "")
+		if err != nil {
+			t.Fatalf("offset 0x%x: %s", nameTabOff, err)
+		}
+
+		if table.Go12line == nil || table.Go12line.Version != ver120 || len(table.Funcs) != len(entries) {
+			t.Fatalf("offset 0x%x: split pclntab not parsed", nameTabOff)
+		}
+
+		for i, expected := range []string{"runtime.main", "main.foo", "main.main"} {
+			if fn := table.Funcs[i]; fn.Name != expected || fn.Entry != 0x401000+uint64(entries[i]) {
+				t.Errorf("offset 0x%x: function %d is %s@%x, expected %s", nameTabOff, i, fn.Name, fn.Entry, expected)
+			}
+		}
+	}
+
+	// without the section map the sub-table can't be found
+	table, err := NewTable(nil, NewLineTable(buildGo120Pclntab(entries, nameOffs, nameTabVA-headerVA), 0x401000), "")
+	if err == nil {
+		for _, fn := range table.Funcs {
+			if len(fn.Name) > 0 {
+				t.Errorf("function name %s recovered without the sub-table", fn.Name)
+			}
+		}
+	}
+}
+
+func TestTolerant(t *testing.T) {
+	const ptrSize = 8
+	const functabOff = 8 + ptrSize
+	entries := []uint64{0x401000, 0x401040, 0x401100, 0x401180, 0x401200}
+	names := []string{"runtime.main", "main.foo", "main.bar", "main.baz", "main.main"}
+
+	data := buildGo12Pclntab(entries, names)
+	funcOff := func(i int) uint64 { return binary.LittleEndian.Uint64(data[functabOff+(2*i+1)*ptrSize:]) }
+	// main.foo's name is past the table, main.bar's entry comes before runtime.main and main.baz's pcln table runs off the end
+	binary.LittleEndian.PutUint32(data[funcOff(1)+ptrSize:], 0xffffff)
+	binary.LittleEndian.PutUint64(data[functabOff+2*2*ptrSize:], 0x400f00)
+	binary.LittleEndian.PutUint32(data[funcOff(3)+ptrSize+5*4:], uint32(len(data)))
+	data = append(data, 0x80, 0x80)
+
+	// strict, the walk stops at main.foo
+	table, err := NewTable(nil, NewLineTable(data, entries[0]), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Funcs) != 1 || table.Go12line.Corruption != nil {
+		t.Fatalf("expected the strict parse to stop at the first corrupt entry, got %d functions and %+v", len(table.Funcs), table.Go12line.Corruption)
+	}
+
+	lineTable := NewLineTable(data, entries[0])
+	lineTable.Tolerant = true
+	table, err = NewTable(nil, lineTable, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		name  string
+		entry uint64
+	}{{"main.bar", 0x400f00}, {"runtime.main", 0x401000}, {"sub_401040", 0x401040}, {"main.baz", 0x401180}, {"main.main", 0x401200}}
+	if len(table.Funcs) != len(expected) {
+		t.Fatalf("expected %d functions, got %d", len(expected), len(table.Funcs))
+	}
+	for i, fn := range table.Funcs {
+		if fn.Name != expected[i].name || fn.Entry != expected[i].entry || fn.Sym.Func != &table.Funcs[i] {
+			t.Errorf("function %d is %s@%x, expected %s@%x", i, fn.Name, fn.Entry, expected[i].name, expected[i].entry)
+		}
+	}
+	// the function overlapping main.baz's entry ends there
+	if fn := table.PCToFunc(0x401050); fn == nil || fn.Name != "sub_401040" || fn.End != 0x401180 {
+		t.Errorf("expected sub_401040 to end at main.baz, got %+v", fn)
+	}
+	if rows := table.LineRows(&table.Funcs[3]); rows != nil {
+		t.Errorf("expected main.baz to have no line info, got %+v", rows)
+	}
+
+	kinds := map[int][]string{}
+	for _, corruption := range table.Go12line.Corruption {
+		kinds[corruption.Index] = append(kinds[corruption.Index], corruption.Kind)
+	}
+	for index, expected := range map[int]string{-1: "count", 1: "order name", 2: "order", 3: "lines"} {
+		if got := strings.Join(kinds[index], " "); got != expected {
+			t.Errorf("expected the corruption of entry %d to be %q, got %q", index, expected, got)
+		}
+	}
+	if len(kinds) != 4 {
+		t.Errorf("expected corruption for 4 entries, got %+v", table.Go12line.Corruption)
+	}
+
+	// a clean table parses the same and reports nothing
+	lineTable = NewLineTable(buildGo12Pclntab(entries, names), entries[0])
+	lineTable.Tolerant = true
+	table, err = NewTable(nil, lineTable, "")
+	if err != nil || len(table.Funcs) != len(entries) || table.Go12line.Corruption != nil {
+		t.Fatalf("expected a clean table to parse without corruption, got %v and %+v", err, table.Go12line.Corruption)
+	}
+}
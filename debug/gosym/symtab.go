@@ -567,6 +567,178 @@ func (t *Table) PCToLine(pc uint64) (file string, line int, fn *Func) {
 	return
 }
 
+// A LineRow is the source of the code from PC on, up to the next row.
+type LineRow struct {
+	PC   uint64
+	File string
+	Line int
+	// the code was inlined from another function, File and Line are the callee's. Only known from Go 1.9 on.
+	Inlined bool
+}
+
+// ArgsSizeUnknown is the Args of the functions whose argument size isn't declared, ex: assembly and C varargs.
+const ArgsSizeUnknown = -0x80000000
+
+// FuncFields are the fields of the _func of a function in the pclntab besides its name and tables. The layout is the same from Go
+// 1.12 on, the tables of Go 1.2 to 1.11 have the legacy frame size for DeferReturn and no FuncID, it's a byte of nfuncdata.
+type FuncFields struct {
+	Args int32 // the size of the arguments and results in bytes, ArgsSizeUnknown when not declared
+	// the offset from the entry of the call to runtime.deferreturn, 0 without one
+	DeferReturn uint32
+	// what special runtime function it is, ex: runtime.goexit, 0 for the others. The numbering changes between Go versions.
+	FuncID uint8
+}
+
+// An SPRow is how far the stack pointer is below its value at the entry of the function from PC on, up to the next row.
+type SPRow struct {
+	PC      uint64
+	SPDelta int
+}
+
+// A PCValueRange is the value of a pc-value table over the code from Start up to End.
+type PCValueRange struct {
+	Start uint64
+	End   uint64
+	Value int
+}
+
+// A PCRange is the code from Start up to End.
+type PCRange struct {
+	Start uint64
+	End   uint64
+}
+
+// An InlinedCall is a function the compiler inlined into another, an entry of the inline tree of the function it was inlined into.
+type InlinedCall struct {
+	Name string
+	// the index of the inlined call it was inlined into, in the same tree, -1 when it's the function of the tree
+	Parent   int
+	CallFile string
+	CallLine int
+	// the code of the call, not counting the calls inlined into it in turn
+	Ranges []PCRange
+}
+
+// InlineTree locates the inline tree of fn, size bytes at addr in the module. From Go 1.18 on the funcdata are relative to the
+// go:func.* symbol of the module, its address is gofunc. regMaps is for Go 1.12 to 1.15, their register maps come before the tree in
+// the funcdata and the table doesn't tell them from 1.16. ok is false when nothing was inlined into fn, the table is older than Go
+// 1.2 or gofunc is needed and 0.
+func (t *Table) InlineTree(fn *Func, gofunc uint64, regMaps bool) (addr uint64, size uint64, ok bool) {
+	if t.Go12line == nil {
+		return 0, 0, false
+	}
+	return t.Go12line.go12InlineTree(fn.Entry, gofunc, regMaps)
+}
+
+// InlinedCalls decodes the inline tree of fn, the bytes that InlineTree located, in tree order: a call comes after the one it was
+// inlined into. The layout is that of Go 1.12 and later, before it the entries were smaller and have no call site pc. A tree that
+// doesn't decode gives nil.
+func (t *Table) InlinedCalls(fn *Func, tree []byte) []InlinedCall {
+	if t.Go12line == nil {
+		return nil
+	}
+	return t.Go12line.go12InlinedCalls(fn.Entry, fn.End, tree)
+}
+
+// LineRange returns the file fn is declared in, the file of its entry, and the first and last line of its own code in that file.
+// Code inlined into fn doesn't count, the tables only mark it from Go 1.9 on. The lines are 0 when fn has no line table.
+func (t *Table) LineRange(fn *Func) (file string, start int, end int) {
+	rows := t.LineRows(fn)
+	if len(rows) == 0 {
+		return "", 0, 0
+	}
+	file = rows[0].File
+	for _, row := range rows {
+		if row.File != file || row.Inlined || row.Line <= 0 {
+			continue
+		}
+		if start == 0 || row.Line < start {
+			start = row.Line
+		}
+		end = max(end, row.Line)
+	}
+	return file, start, end
+}
+
+// LineRows lists the source lines of fn's code in order, a row wherever the file or the line changes.
+// The first row is at the entry of fn.
+func (t *Table) LineRows(fn *Func) []LineRow {
+	if t.Go12line != nil {
+		return t.Go12line.go12LineRows(fn.Entry, fn.End)
+	}
+	// the old tables only map a single pc at a time
+	var rows []LineRow
+	for pc := fn.Entry; pc < fn.End; pc++ {
+		file, line := fn.Obj.lineFromAline(fn.LineTable.PCToLine(pc))
+		if len(rows) == 0 || rows[len(rows)-1].File != file || rows[len(rows)-1].Line != line {
+			rows = append(rows, LineRow{PC: pc, File: file, Line: line})
+		}
+	}
+	return rows
+}
+
+// FuncFields reads the _func of fn, ok is false when the table is older than Go 1.2 and has none.
+func (t *Table) FuncFields(fn *Func) (FuncFields, bool) {
+	if t.Go12line == nil {
+		return FuncFields{}, false
+	}
+	return t.Go12line.go12FuncFields(fn.Entry)
+}
+
+// SPRows lists the sp deltas of fn's code in order from its pcsp table, a row wherever the delta changes. nil when fn has no pcsp
+// table or the table is older than Go 1.2.
+func (t *Table) SPRows(fn *Func) []SPRow {
+	if t.Go12line == nil {
+		return nil
+	}
+	return t.Go12line.go12SPRows(fn.Entry, fn.End)
+}
+
+// PCDataRanges lists the values of the nth pcdata table of fn over its code in order, a range wherever the value changes. What the
+// tables are changed between Go versions, ex: the unsafe points are the table 0 from Go 1.14 on. nil when fn has no such table or the
+// table is older than Go 1.2.
+func (t *Table) PCDataRanges(fn *Func, n uint32) []PCValueRange {
+	if t.Go12line == nil {
+		return nil
+	}
+	return t.Go12line.go12PCDataRanges(fn.Entry, fn.End, n)
+}
+
+// StackMaps locates the runtime.stackmap of the arguments of fn and that of its locals in the module, 0 for the one fn doesn't have.
+// From Go 1.18 on the funcdata are relative to the go:func.* symbol of the module, its address is gofunc, both are 0 without it.
+// wideCount is for Go 1.2 to 1.11, their count of funcdata is a whole field and the table doesn't tell them from 1.12 to 1.15.
+func (t *Table) StackMaps(fn *Func, gofunc uint64, wideCount bool) (args uint64, locals uint64) {
+	if t.Go12line == nil {
+		return 0, 0
+	}
+	return t.Go12line.go12StackMaps(fn.Entry, gofunc, wideCount)
+}
+
+// MaxFrameSize returns the largest sp delta of fn, the size of its frame without the return address the call pushed. It's 0 for the
+// functions with no frame or no pcsp table, ex: most assembly.
+func (t *Table) MaxFrameSize(fn *Func) int {
+	size := 0
+	for _, row := range t.SPRows(fn) {
+		size = max(size, row.SPDelta)
+	}
+	return size
+}
+
+// CodeEnd returns where the code of fn ends, fn.End without the alignment padding before the next function. It's fn.Entry for a
+// function of no code, ex: an assembly label sharing its entry with the next, and fn.End when the table is older than Go 1.2 or the
+// pc-value tables of fn don't read. A function whose code runs past fn.End overlaps the next entry, its end is cut at fn.End since
+// the pcs from there are the next function's.
+func (t *Table) CodeEnd(fn *Func) uint64 {
+	if fn.End <= fn.Entry || t.Go12line == nil {
+		return fn.End
+	}
+	end := t.Go12line.go12CodeEnd(fn.Entry)
+	if end <= fn.Entry || end > fn.End {
+		return fn.End
+	}
+	return end
+}
+
 // LineToPC looks up the first program counter on the given line in
 // the named file. It returns UnknownPathError or UnknownLineError if
 // there is an error looking up this line.
@@ -0,0 +1,101 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+
+// Package view has the in place section data the pe, elf and macho files share: a file read through a Viewer, ex: a memory mapped
+// one, gives its sections as views of it rather than copies.
+package view
+
+import "io"
+
+// Viewer is a reader whose bytes can be had in place, ex: a memory mapped file. The data of the sections read through one is a
+// view of it rather than a copy, which must not be written to.
+type Viewer interface {
+	View(off int64, n int64) ([]byte, bool)
+}
+
+// Section is the data of a section in place, valid as long as it still reads through sr
+type Section struct {
+	sr   *io.SectionReader
+	off  int64
+	data []byte
+	v    Viewer
+}
+
+// NewSection views the n bytes at off of r which sr reads, nil unless r is a Viewer holding all of them
+func NewSection(r io.ReaderAt, sr *io.SectionReader, off int64, n int64) *Section {
+	v, ok := r.(Viewer)
+	if !ok {
+		return nil
+	}
+	data, ok := v.View(off, n)
+	if !ok {
+		return nil
+	}
+	return &Section{sr: sr, off: off, data: data, v: v}
+}
+
+// Of returns the view when it's still of sr
+func (v *Section) Of(sr *io.SectionReader) ([]byte, bool) {
+	if v == nil || v.sr != sr {
+		return nil, false
+	}
+	return v.data, true
+}
+
+// After views the data of sections laid out back to back in the file in place, the empty ones can be anywhere
+func After(views []*Section) ([]byte, bool) {
+	var first *Section
+	var end int64
+	for _, v := range views {
+		if v == nil {
+			return nil, false
+		}
+		if len(v.data) == 0 {
+			continue
+		}
+		if first == nil {
+			first = v
+		} else if v.off != end {
+			return nil, false
+		}
+		end = v.off + int64(len(v.data))
+	}
+	if first == nil {
+		return []byte{}, true
+	}
+	return first.v.View(first.off, end-first.off)
+}
+
+// SectionsData is the data of every section back to back, DataAfterSection returns it from a section on when the sections aren't
+// laid out back to back in the file. One copy serves every section. The data from a section on stops after the first section
+// that couldn't be read whole.
+type SectionsData struct {
+	data  []byte
+	start []int
+	stop  []int
+}
+
+// NewSectionsData copies the data of the n sections read returns, in order
+func NewSectionsData(n int, read func(i int) ([]byte, error)) *SectionsData {
+	d := &SectionsData{start: make([]int, n), stop: make([]int, n)}
+	end := make([]int, n)
+	failed := make([]bool, n)
+	for i := 0; i < n; i++ {
+		d.start[i] = len(d.data)
+		raw, err := read(i)
+		d.data = append(d.data, raw...)
+		end[i], failed[i] = len(d.data), err != nil
+	}
+	stop := len(d.data)
+	for i := n - 1; i >= 0; i-- {
+		if failed[i] {
+			stop = end[i]
+		}
+		d.stop[i] = stop
+	}
+	return d
+}
+
+// From returns the data from the section at i on, appending to it copies
+func (d *SectionsData) From(i int) []byte {
+	return d.data[d.start[i]:d.stop[i]:d.stop[i]]
+}
@@ -21,15 +21,19 @@ type FatFile struct {
 }
 
 // A FatArchHeader represents a fat header for a specific image architecture.
+// Offset and Size are 64 bit in a MagicFat64 file.
 type FatArchHeader struct {
 	Cpu    Cpu
 	SubCpu uint32
-	Offset uint32
-	Size   uint32
+	Offset uint64
+	Size   uint64
 	Align  uint32
 }
 
-const fatArchHeaderSize = 5 * 4
+const (
+	fatArchHeaderSize   = 5 * 4
+	fatArchHeaderSize64 = 8 * 4 // with a reserved word at the end
+)
 
 // A FatArch is a Mach-O File inside a FatFile.
 type FatArch struct {
@@ -53,7 +57,7 @@ func NewFatFile(r io.ReaderAt) (*FatFile, error) {
 	err := binary.Read(sr, binary.BigEndian, &ff.Magic)
 	if err != nil {
 		return nil, &FormatError{0, "error reading magic number", nil}
-	} else if ff.Magic != MagicFat {
+	} else if ff.Magic != MagicFat && ff.Magic != MagicFat64 {
 		// See if this is a Mach-O file via its magic number. The magic
 		// must be converted to little endian first though.
 		var buf [4]byte
@@ -90,11 +94,26 @@ func NewFatFile(r io.ReaderAt) (*FatFile, error) {
 	ff.Arches = make([]FatArch, narch)
 	for i := uint32(0); i < narch; i++ {
 		fa := &ff.Arches[i]
-		err = binary.Read(sr, binary.BigEndian, &fa.FatArchHeader)
-		if err != nil {
+		headerSize := fatArchHeaderSize
+		if ff.Magic == MagicFat64 {
+			headerSize = fatArchHeaderSize64
+		}
+		header := make([]byte, headerSize)
+		if _, err := io.ReadFull(sr, header); err != nil {
 			return nil, &FormatError{offset, "invalid fat_arch header", nil}
 		}
-		offset += fatArchHeaderSize
+		fa.Cpu = Cpu(binary.BigEndian.Uint32(header))
+		fa.SubCpu = binary.BigEndian.Uint32(header[4:])
+		if ff.Magic == MagicFat64 {
+			fa.Offset = binary.BigEndian.Uint64(header[8:])
+			fa.Size = binary.BigEndian.Uint64(header[16:])
+			fa.Align = binary.BigEndian.Uint32(header[24:])
+		} else {
+			fa.Offset = uint64(binary.BigEndian.Uint32(header[8:]))
+			fa.Size = uint64(binary.BigEndian.Uint32(header[12:]))
+			fa.Align = binary.BigEndian.Uint32(header[16:])
+		}
+		offset += int64(headerSize)
 
 		fr := io.NewSectionReader(r, int64(fa.Offset), int64(fa.Size))
 		fa.File, err = NewFile(fr)
@@ -17,8 +17,10 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/mandiant/GoReSym/debug/dwarf"
+	"github.com/mandiant/GoReSym/debug/internal/view"
 )
 
 // A File represents an open Mach-O file.
@@ -32,7 +34,9 @@ type File struct {
 	Dysymtab *Dysymtab
 
 	closer                io.Closer
-	dataAfterSectionCache map[uint64][]byte // secVA -> dataAfterSection
+	dataAfterSectionCache map[uint64][]byte  // secVA -> dataAfterSection
+	dataAfterSectionLock  sync.Mutex         // the scans read sections from several goroutines
+	sectionsData          *view.SectionsData // the copy of the sections DataAfterSection slices, made once
 }
 
 // A Load represents any Mach-O load command.
@@ -125,11 +129,17 @@ type Section struct {
 	// Open() to avoid fighting over the seek offset
 	// with other clients.
 	io.ReaderAt
-	sr *io.SectionReader
+	sr   *io.SectionReader
+	view *view.Section // of sr, when the file is a view.Viewer
 }
 
-// Data reads and returns the contents of the Mach-O section.
+// Data reads and returns the contents of the Mach-O section. The
+// data of a file that's a view.Viewer is a view of it, which must not
+// be written to.
 func (s *Section) Data() ([]byte, error) {
+	if data, ok := s.view.Of(s.sr); ok {
+		return data, nil
+	}
 	dat := make([]byte, s.sr.Size())
 	n, err := s.sr.ReadAt(dat, 0)
 	if n == len(dat) {
@@ -324,7 +334,11 @@ func NewFile(r io.ReaderAt) (*File, error) {
 				return nil, err
 			}
 			strtab := make([]byte, hdr.Strsize)
-			if _, err := r.ReadAt(strtab, int64(hdr.Stroff)); err != nil {
+			if _, err := r.ReadAt(strtab, int64(hdr.Stroff)); err == io.EOF || err == io.ErrUnexpectedEOF {
+				// the symbols of a truncated file are past its end, its segments may still be there
+				f.Loads[i] = LoadBytes(cmddat)
+				continue
+			} else if err != nil {
 				return nil, err
 			}
 			var symsz int
@@ -334,7 +348,10 @@ func NewFile(r io.ReaderAt) (*File, error) {
 				symsz = 12
 			}
 			symdat := make([]byte, int(hdr.Nsyms)*symsz)
-			if _, err := r.ReadAt(symdat, int64(hdr.Symoff)); err != nil {
+			if _, err := r.ReadAt(symdat, int64(hdr.Symoff)); err == io.EOF || err == io.ErrUnexpectedEOF {
+				f.Loads[i] = LoadBytes(cmddat)
+				continue
+			} else if err != nil {
 				return nil, err
 			}
 			st, err := f.parseSymtab(symdat, strtab, cmddat, &hdr, offset)
@@ -351,7 +368,10 @@ func NewFile(r io.ReaderAt) (*File, error) {
 				return nil, err
 			}
 			dat := make([]byte, hdr.Nindirectsyms*4)
-			if _, err := r.ReadAt(dat, int64(hdr.Indirectsymoff)); err != nil {
+			if _, err := r.ReadAt(dat, int64(hdr.Indirectsymoff)); err == io.EOF || err == io.ErrUnexpectedEOF {
+				f.Loads[i] = LoadBytes(cmddat)
+				continue
+			} else if err != nil {
 				return nil, err
 			}
 			x := make([]uint32, hdr.Nindirectsyms)
@@ -503,6 +523,7 @@ type relocInfo struct {
 func (f *File) pushSection(sh *Section, r io.ReaderAt) error {
 	f.Sections = append(f.Sections, sh)
 	sh.sr = io.NewSectionReader(r, int64(sh.Offset), int64(sh.Size))
+	sh.view = view.NewSection(r, sh.sr, int64(sh.Offset), int64(sh.Size))
 	sh.ReaderAt = sh.sr
 
 	if sh.Nreloc > 0 {
@@ -574,27 +595,36 @@ func (f *File) Segment(name string) *Segment {
 	return nil
 }
 
+// DataAfterSection returns the data of target and of the sections after it, back to back, up to the first section that can't be
+// read whole. It's a view of the file when they're laid out back to back in it, else of one copy of every section, shared by every
+// target. It must not be written to.
 func (f *File) DataAfterSection(target *Section) []byte {
+	f.dataAfterSectionLock.Lock()
+	defer f.dataAfterSectionLock.Unlock()
 	if cached, ok := f.dataAfterSectionCache[target.Addr]; ok {
 		return cached
 	}
 
 	data := []byte{}
-	found := false
-	for _, s := range f.Sections {
+	for i, s := range f.Sections {
 		if s.Addr == target.Addr && s.Name == target.Name {
-			found = true
-		}
-
-		if found {
-			raw, err := s.Data()
-			if raw != nil {
-				data = append(data, raw[:]...)
+			views := make([]*view.Section, 0, len(f.Sections)-i)
+			for _, after := range f.Sections[i:] {
+				if _, ok := after.view.Of(after.sr); ok {
+					views = append(views, after.view)
+				} else {
+					views = append(views, nil)
+				}
 			}
-
-			if err != nil {
-				break
+			if viewed, ok := view.After(views); ok {
+				data = viewed
+			} else {
+				if f.sectionsData == nil {
+					f.sectionsData = view.NewSectionsData(len(f.Sections), func(i int) ([]byte, error) { return f.Sections[i].Data() })
+				}
+				data = f.sectionsData.From(i)
 			}
+			break
 		}
 	}
 	f.dataAfterSectionCache[target.Addr] = data
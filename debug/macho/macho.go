@@ -33,9 +33,10 @@ const (
 )
 
 const (
-	Magic32  uint32 = 0xfeedface
-	Magic64  uint32 = 0xfeedfacf
-	MagicFat uint32 = 0xcafebabe
+	Magic32    uint32 = 0xfeedface
+	Magic64    uint32 = 0xfeedfacf
+	MagicFat   uint32 = 0xcafebabe
+	MagicFat64 uint32 = 0xcafebabf
 )
 
 // A Type is the Mach-O file type, e.g. an object file, executable, or dynamic library.
@@ -11,12 +11,15 @@ import (
 	"bytes"
 	"compress/zlib"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/mandiant/GoReSym/debug/dwarf"
+	"github.com/mandiant/GoReSym/debug/internal/view"
 )
 
 // Avoid use of post-Go 1.4 io features, to make safe for toolchain bootstrap.
@@ -32,7 +35,9 @@ type File struct {
 	StringTable    StringTable
 
 	closer                io.Closer
-	dataAfterSectionCache map[uint64][]byte // secVA -> dataAfterSection
+	dataAfterSectionCache map[uint64][]byte  // secVA -> dataAfterSection
+	dataAfterSectionLock  sync.Mutex         // the scans read sections from several goroutines
+	sectionsData          *view.SectionsData // the copy of the sections DataAfterSection slices, made once
 }
 
 // Open opens the named file using os.Open and prepares it for use as a PE binary.
@@ -114,11 +119,17 @@ func NewFile(r io.ReaderAt) (*File, error) {
 
 	// Read symbol table.
 	f.COFFSymbols, err = readCOFFSymbols(&f.FileHeader, sr)
-	if err != nil {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		// the symbols of a truncated file are past its end, its sections may still be there
+		f.COFFSymbols = nil
+	} else if err != nil {
 		return nil, err
 	}
 	f.Symbols, err = removeAuxSymbols(f.COFFSymbols, f.StringTable)
-	if err != nil {
+	if err != nil && f.StringTable == nil {
+		// the long names are in the string table, which is past the end of a truncated file
+		f.COFFSymbols, f.Symbols = nil, nil
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -142,7 +153,10 @@ func NewFile(r io.ReaderAt) (*File, error) {
 			return nil, err
 		}
 		name, err := sh.fullName(f.StringTable)
-		if err != nil {
+		if err != nil && f.StringTable == nil {
+			// the string table of a truncated file is past its end, the long names are left as their /offset
+			name = cstring(sh.Name[:])
+		} else if err != nil {
 			return nil, err
 		}
 		s := new(Section)
@@ -163,6 +177,7 @@ func NewFile(r io.ReaderAt) (*File, error) {
 			r2 = zeroReaderAt{}
 		}
 		s.sr = io.NewSectionReader(r2, int64(s.SectionHeader.Offset), int64(s.SectionHeader.Size))
+		s.view = view.NewSection(r2, s.sr, int64(s.SectionHeader.Offset), int64(s.SectionHeader.Size))
 		s.ReaderAt = s.sr
 		f.Sections[i] = s
 	}
@@ -212,27 +227,36 @@ func (f *File) Section(name string) *Section {
 	return nil
 }
 
+// DataAfterSection returns the data of target and of the sections after it, back to back, up to the first section that can't be
+// read whole. It's a view of the file when they're laid out back to back in it, else of one copy of every section, shared by every
+// target. It must not be written to.
 func (f *File) DataAfterSection(target *Section) []byte {
+	f.dataAfterSectionLock.Lock()
+	defer f.dataAfterSectionLock.Unlock()
 	if cached, ok := f.dataAfterSectionCache[uint64(target.VirtualAddress)]; ok {
 		return cached
 	}
 
 	data := []byte{}
-	found := false
-	for _, s := range f.Sections {
+	for i, s := range f.Sections {
 		if s.VirtualAddress == target.VirtualAddress && s.Name == target.Name {
-			found = true
-		}
-
-		if found {
-			raw, err := s.Data()
-			if raw != nil {
-				data = append(data, raw[:]...)
+			views := make([]*view.Section, 0, len(f.Sections)-i)
+			for _, after := range f.Sections[i:] {
+				if _, ok := after.view.Of(after.sr); ok {
+					views = append(views, after.view)
+				} else {
+					views = append(views, nil)
+				}
 			}
-
-			if err != nil {
-				break
+			if viewed, ok := view.After(views); ok {
+				data = viewed
+			} else {
+				if f.sectionsData == nil {
+					f.sectionsData = view.NewSectionsData(len(f.Sections), func(i int) ([]byte, error) { return f.Sections[i].Data() })
+				}
+				data = f.sectionsData.From(i)
 			}
+			break
 		}
 	}
 	f.dataAfterSectionCache[uint64(target.VirtualAddress)] = data
@@ -11,6 +11,8 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+
+	"github.com/mandiant/GoReSym/debug/internal/view"
 )
 
 // SectionHeader32 represents real PE COFF section header.
@@ -94,11 +96,17 @@ type Section struct {
 	// Open() to avoid fighting over the seek offset
 	// with other clients.
 	io.ReaderAt
-	sr *io.SectionReader
+	sr   *io.SectionReader
+	view *view.Section // of sr, when the file is a view.Viewer
 }
 
-// Data reads and returns the contents of the PE section s.
+// Data reads and returns the contents of the PE section s. The data
+// of a file that's a view.Viewer is a view of it, which must not be
+// written to.
 func (s *Section) Data() ([]byte, error) {
+	if data, ok := s.view.Of(s.sr); ok {
+		return data, nil
+	}
 	dat := make([]byte, s.sr.Size())
 	n, err := s.sr.ReadAt(dat, 0)
 	if n == len(dat) {
@@ -8,8 +8,11 @@ package pe
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+
+	"github.com/mandiant/GoReSym/saferio"
 )
 
 const COFFSymbolSize = 18
@@ -35,10 +38,18 @@ func readCOFFSymbols(fh *FileHeader, r io.ReadSeeker) ([]COFFSymbol, error) {
 	if err != nil {
 		return nil, fmt.Errorf("fail to seek to symbol table: %v", err)
 	}
-	syms := make([]COFFSymbol, fh.NumberOfSymbols)
-	err = binary.Read(r, binary.LittleEndian, syms)
-	if err != nil {
-		return nil, fmt.Errorf("fail to read symbol table: %v", err)
+	// the count is read from the header, grow the table as it reads rather than trusting it with one allocation
+	c := saferio.SliceCap((*COFFSymbol)(nil), uint64(fh.NumberOfSymbols))
+	if c < 0 {
+		return nil, errors.New("too many symbols; file may be corrupt")
+	}
+	syms := make([]COFFSymbol, 0, c)
+	for k := uint32(0); k < fh.NumberOfSymbols; k++ {
+		var sym COFFSymbol
+		if err := binary.Read(r, binary.LittleEndian, &sym); err != nil {
+			return nil, fmt.Errorf("fail to read symbol table: %w", err)
+		}
+		syms = append(syms, sym)
 	}
 	return syms, nil
 }
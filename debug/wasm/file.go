@@ -0,0 +1,297 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+
+// Package wasm implements access to WebAssembly modules, as far as finding the data Go keeps in linear memory and the function names
+// goes: the section list, the data segments and the name section.
+package wasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Magic starts every module, the version follows it
+const Magic = "\x00asm"
+
+// section ids of the binary format
+const (
+	SectionCustom   = 0
+	SectionType     = 1
+	SectionImport   = 2
+	SectionFunction = 3
+	SectionTable    = 4
+	SectionMemory   = 5
+	SectionGlobal   = 6
+	SectionExport   = 7
+	SectionStart    = 8
+	SectionElement  = 9
+	SectionCode     = 10
+	SectionData     = 11
+)
+
+// constant expression opcodes
+const (
+	opEnd      = 0x0b
+	opI32Const = 0x41
+	opI64Const = 0x42
+)
+
+// A Section is one section of the module, custom sections carry a name
+type Section struct {
+	ID     byte
+	Name   string
+	Offset int64 // of the contents, behind the id and size
+	Size   int64
+	io.ReaderAt
+	sr *io.SectionReader
+}
+
+// Data reads and returns the contents of the section
+func (s *Section) Data() ([]byte, error) {
+	data := make([]byte, s.Size)
+	n, err := s.sr.ReadAt(data, 0)
+	if n == len(data) {
+		err = nil
+	}
+	return data[:n], err
+}
+
+// A Segment is an active data segment, copied to Offset in the linear memory when the module is instantiated.
+// Passive segments and ones placed by a global have no fixed address and are left out.
+type Segment struct {
+	Memory uint32
+	Offset uint64
+	Data   []byte
+}
+
+// A File is a parsed WebAssembly module
+type File struct {
+	Version  uint32
+	Sections []*Section
+	Segments []*Segment
+}
+
+// FormatError is a malformed module
+type FormatError struct {
+	Offset int64
+	Msg    string
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("wasm: %s at offset 0x%x", e.Msg, e.Offset)
+}
+
+// NewFile parses the module r reads
+func NewFile(r io.ReaderAt) (*File, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+	if string(header[:4]) != Magic {
+		return nil, &FormatError{0, "bad magic"}
+	}
+	f := &File{Version: uint32(header[4]) | uint32(header[5])<<8 | uint32(header[6])<<16 | uint32(header[7])<<24}
+
+	sr := io.NewSectionReader(r, 0, 1<<63-1)
+	offset := int64(8)
+	for {
+		head := make([]byte, 11)
+		n, _ := r.ReadAt(head, offset)
+		if n == 0 {
+			break
+		}
+		size, m := uleb(head[1:n])
+		if m <= 0 {
+			return nil, &FormatError{offset, "bad section size"}
+		}
+
+		s := &Section{ID: head[0], Offset: offset + 1 + int64(m), Size: int64(size)}
+		s.sr = io.NewSectionReader(sr, s.Offset, s.Size)
+		s.ReaderAt = s.sr
+		offset = s.Offset + s.Size
+		if offset < s.Offset {
+			return nil, &FormatError{s.Offset, "section size overflows"}
+		}
+		f.Sections = append(f.Sections, s)
+
+		switch s.ID {
+		case SectionCustom:
+			prefix := make([]byte, min(s.Size, 5+256))
+			if n, _ := s.ReadAt(prefix, 0); n > 0 {
+				if length, m := uleb(prefix[:n]); m > 0 && uint64(m)+length <= uint64(n) {
+					s.Name = string(prefix[m : uint64(m)+length])
+				}
+			}
+		case SectionData:
+			data, err := s.Data()
+			if err != nil {
+				return nil, fmt.Errorf("wasm: data section: %w", err)
+			}
+			if f.Segments, err = parseSegments(data); err != nil {
+				return nil, &FormatError{s.Offset, err.Error()}
+			}
+		}
+	}
+	return f, nil
+}
+
+// parseSegments decodes the vector of data segments. The flags tell an active segment of memory 0 (0), a passive one (1)
+// and an active one with an explicit memory (2) apart.
+func parseSegments(data []byte) ([]*Segment, error) {
+	count, n := uleb(data)
+	if n <= 0 {
+		return nil, errors.New("bad segment count")
+	}
+	data = data[n:]
+
+	var segments []*Segment
+	for i := uint64(0); i < count; i++ {
+		flags, n := uleb(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("segment %d: bad flags", i)
+		}
+		data = data[n:]
+
+		var memory uint64
+		active := flags != 1
+		if flags == 2 {
+			if memory, n = uleb(data); n <= 0 {
+				return nil, fmt.Errorf("segment %d: bad memory index", i)
+			}
+			data = data[n:]
+		}
+		var offset uint64
+		placed := false
+		if active {
+			end := bytes.IndexByte(data, opEnd)
+			if end < 0 {
+				return nil, fmt.Errorf("segment %d: unterminated offset", i)
+			}
+			if expr := data[:end]; len(expr) > 1 && (expr[0] == opI32Const || expr[0] == opI64Const) {
+				value, n := sleb(expr[1:])
+				placed = n == len(expr)-1
+				offset = uint64(value)
+				if expr[0] == opI32Const {
+					offset = uint64(uint32(value))
+				}
+			}
+			data = data[end+1:]
+		}
+
+		size, n := uleb(data)
+		if n <= 0 || size > uint64(len(data)-n) {
+			return nil, fmt.Errorf("segment %d: bad size", i)
+		}
+		if placed {
+			segments = append(segments, &Segment{Memory: uint32(memory), Offset: offset, Data: data[n : uint64(n)+size]})
+		}
+		data = data[uint64(n)+size:]
+	}
+	return segments, nil
+}
+
+// Section returns the first section with the id, or the custom section with the name when id is SectionCustom
+func (f *File) Section(id byte, name string) *Section {
+	for _, s := range f.Sections {
+		if s.ID == id && (id != SectionCustom || s.Name == name) {
+			return s
+		}
+	}
+	return nil
+}
+
+// FunctionNames decodes the function names subsection of the custom "name" section, by function index. The imported functions
+// come first in the index space. Returns nil when the module has no names.
+func (f *File) FunctionNames() (map[uint32]string, error) {
+	s := f.Section(SectionCustom, "name")
+	if s == nil {
+		return nil, nil
+	}
+	data, err := s.Data()
+	if err != nil {
+		return nil, fmt.Errorf("wasm: name section: %w", err)
+	}
+	length, m := uleb(data)
+	if m <= 0 || uint64(m)+length > uint64(len(data)) {
+		return nil, &FormatError{s.Offset, "bad custom section name"}
+	}
+	data = data[uint64(m)+length:]
+
+	for len(data) > 0 {
+		id := data[0]
+		size, m := uleb(data[1:])
+		if m <= 0 || uint64(1+m)+size > uint64(len(data)) {
+			return nil, &FormatError{s.Offset, "bad name subsection"}
+		}
+		contents := data[1+m : uint64(1+m)+size]
+		data = data[uint64(1+m)+size:]
+		const functionNames = 1
+		if id != functionNames {
+			continue
+		}
+
+		count, m := uleb(contents)
+		if m <= 0 {
+			return nil, &FormatError{s.Offset, "bad function name count"}
+		}
+		contents = contents[m:]
+		names := make(map[uint32]string)
+		for i := uint64(0); i < count; i++ {
+			index, m := uleb(contents)
+			if m <= 0 {
+				return nil, &FormatError{s.Offset, "bad function index"}
+			}
+			length, n := uleb(contents[m:])
+			if n <= 0 || uint64(m+n)+length > uint64(len(contents)) {
+				return nil, &FormatError{s.Offset, "bad function name"}
+			}
+			names[uint32(index)] = string(contents[m+n : uint64(m+n)+length])
+			contents = contents[uint64(m+n)+length:]
+		}
+		return names, nil
+	}
+	return nil, nil
+}
+
+// ReadMemory returns size bytes of the initial linear memory 0 at addr, what no segment initializes reads as zero
+func (f *File) ReadMemory(addr uint64, size uint64) []byte {
+	data := make([]byte, size)
+	for _, s := range f.Segments {
+		if s.Memory != 0 || s.Offset >= addr+size || s.Offset+uint64(len(s.Data)) <= addr {
+			continue
+		}
+		if s.Offset >= addr {
+			copy(data[s.Offset-addr:], s.Data)
+		} else {
+			copy(data, s.Data[addr-s.Offset:])
+		}
+	}
+	return data
+}
+
+// uleb decodes an unsigned LEB128, the length is 0 when data ends first or the value overflows
+func uleb(data []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		value |= uint64(data[i]&0x7f) << (7 * i)
+		if data[i] < 0x80 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
+
+func sleb(data []byte) (int64, int) {
+	var value int64
+	for i := 0; i < len(data) && i < 10; i++ {
+		value |= int64(data[i]&0x7f) << (7 * i)
+		if data[i] < 0x80 {
+			if shift := 7 * (i + 1); shift < 64 && data[i]&0x40 != 0 {
+				value |= -1 << shift
+			}
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
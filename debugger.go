@@ -0,0 +1,153 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mandiant/GoReSym/goresym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// x64dbgHeader is the start of the generated script, the labels follow it
+const x64dbgHeader = `// Generated by GoReSym for %s, run it with Script > Load Script and Run once the module is loaded
+// The addresses are relative to the module base, they hold wherever the loader put it. For a DLL set $base to its module base instead.
+var $base
+mov $base, mod.main()
+`
+
+// the longest label x64dbg keeps is MAX_LABEL_SIZE less the terminator, IDA's longest name for a map
+const (
+	x64dbgNameLimit = 255
+	mapNameLimit    = 511
+)
+
+// x64dbgName replaces the characters that would end the quoted arguments of a command, quotes, backslashes and controls
+func x64dbgName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// mapName replaces the whitespace and controls that would split the line of a map
+func mapName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// truncateName cuts name to at most limit bytes without splitting a character
+func truncateName(name string, limit int) string {
+	if len(name) <= limit {
+		return name
+	}
+	name = name[:limit]
+	for len(name) > 0 && !utf8.ValidString(name) {
+		name = name[:len(name)-1]
+	}
+	return name
+}
+
+// limitedName is name cut to limit bytes and unique in namespace, the address of a colliding name is appended within the limit
+func (u uniqueNames) limitedName(namespace string, name string, limit int, va uint64) string {
+	name = truncateName(name, limit)
+	if u[[2]string{namespace, name}] {
+		suffix := fmt.Sprintf("_%x", va)
+		name = truncateName(name, limit-len(suffix)) + suffix
+	}
+	u[[2]string{namespace, name}] = true
+	return name
+}
+
+// debuggerSymbol is a name at an RVA
+type debuggerSymbol struct {
+	rva     uint64
+	name    string
+	comment string
+}
+
+// debuggerSymbols lists the functions and types of metadata at their RVAs, each address once and functions first. The RVAs need the
+// image base of a PE, other files have none and fail.
+func debuggerSymbols(metadata goresym.Report) ([]debuggerSymbol, error) {
+	if metadata.ImageBase == 0 {
+		return nil, fmt.Errorf("no image base, module relative addresses need a PE")
+	}
+
+	var symbols []debuggerSymbol
+	named := make(map[uint64]bool)
+	add := func(va uint64, name string, comment string) {
+		if named[va] || va < metadata.ImageBase {
+			return
+		}
+		named[va] = true
+		symbols = append(symbols, debuggerSymbol{va - metadata.ImageBase, name, comment})
+	}
+	for _, funcs := range [][]goresym.FuncMetadata{metadata.UserFunctions, metadata.StdFunctions} {
+		for _, fn := range funcs {
+			if fn.Unmapped {
+				continue
+			}
+			comment := ""
+			if len(fn.SourceFile) > 0 {
+				comment = fmt.Sprintf("%s:%d", fn.SourceFile, fn.SourceLine)
+			}
+			add(fn.Start, fn.FullName, comment)
+		}
+	}
+	for _, types := range [][]objfile.Type{metadata.Types, metadata.Interfaces} {
+		for _, typ := range types {
+			add(typ.VA, "type:"+typ.Str, "")
+		}
+	}
+	return symbols, nil
+}
+
+// printX64dbgScript writes an x64dbg script labeling the functions and types of metadata relative to the module base and commenting
+// the functions with their source lines. fileName goes in the header. Std functions are included when they were extracted, with -d.
+func printX64dbgScript(w io.Writer, fileName string, metadata goresym.Report) error {
+	symbols, err := debuggerSymbols(metadata)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, x64dbgHeader, headerName(fileName))
+	// labels are global, a label on a second address would move it
+	names := make(uniqueNames)
+	for _, symbol := range symbols {
+		fmt.Fprintf(&b, "lbl $base+0x%x, \"%s\"\n", symbol.rva, names.limitedName("", x64dbgName(symbol.name), x64dbgNameLimit, symbol.rva))
+		if len(symbol.comment) > 0 {
+			fmt.Fprintf(&b, "cmt $base+0x%x, \"%s\"\n", symbol.rva, truncateName(x64dbgName(symbol.comment), x64dbgNameLimit))
+		}
+	}
+	b.WriteString("ret\n")
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// printMapFile writes the functions and types of metadata as a flat map of 'RVA name' lines, the RVAs in hex relative to the image base
+func printMapFile(w io.Writer, metadata goresym.Report) error {
+	symbols, err := debuggerSymbols(metadata)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	names := make(uniqueNames)
+	for _, symbol := range symbols {
+		fmt.Fprintf(&b, "%08x %s\n", symbol.rva, names.limitedName("", mapName(symbol.name), mapNameLimit, symbol.rva))
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,51 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mandiant/GoReSym/goresym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// embeddedDirName is the directory of an embed.FS under -extract-embedded, its variable name with the package path flattened,
+// ex: github.com_x_y.assets, or its address
+func embeddedDirName(embedded objfile.EmbeddedFS) string {
+	name := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(embedded.Name)
+	if len(name) == 0 || name == "." || name == ".." {
+		return fmt.Sprintf("fs_0x%x", embedded.VA)
+	}
+	return name
+}
+
+// writeEmbeddedFiles writes the files of every embed.FS of the metadatas to a directory of its own under dir. The names are valid
+// io/fs paths, so nothing lands outside of it.
+func writeEmbeddedFiles(dir string, metadatas ...goresym.Report) error {
+	for _, metadata := range metadatas {
+		for _, embedded := range metadata.EmbeddedFS {
+			root := filepath.Join(dir, embeddedDirName(embedded))
+			if err := os.MkdirAll(root, 0755); err != nil {
+				return err
+			}
+			for _, file := range embedded.Files {
+				path := filepath.Join(root, filepath.FromSlash(file.Name))
+				if strings.HasSuffix(file.Name, "/") {
+					if err := os.MkdirAll(path, 0755); err != nil {
+						return err
+					}
+					continue
+				}
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					return err
+				}
+				if err := os.WriteFile(path, file.Data, 0644); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"runtime/debug"
+
+	"github.com/mandiant/GoReSym/goresym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// the exit code of a failed extraction by the class of its error, see goresym.ErrorClass. 1 is every other failure, ex: a bad
+// combination of flags or an output that can't be written, and 2 a flag that doesn't parse.
+var exitCodes = []struct {
+	class       string
+	code        int
+	description string
+}{
+	{"NotGo", 3, "not a Go binary, or not an executable at all"},
+	{"UnsupportedArch", 4, "no moduledata signature covers the architecture"},
+	{"NoModuledata", 5, "the pclntab parses but no moduledata was found"},
+	{"NoPclntab", 6, "a Go binary whose pclntab is gone or doesn't parse, ex: packed"},
+	{"CorruptModuledata", 7, "moduledata candidates were found, or one was given, but none validates"},
+	{"IOError", 8, "the file can't be read"},
+	{"Internal", 9, "a bug of the parsers, ex: a panic on a hostile file"},
+}
+
+// exitCode is the exit code of a failed extraction
+func exitCode(err error) int {
+	class := goresym.ErrorClass(err)
+	for _, exit := range exitCodes {
+		if exit.class == class {
+			return exit.code
+		}
+	}
+	return 1
+}
+
+// usage is the default -help with the exit codes after the flags
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
+	flag.PrintDefaults()
+	fmt.Fprintln(out, "\nExit codes:")
+	fmt.Fprintln(out, "  0\tsuccess")
+	fmt.Fprintln(out, "  1\tbad flags or a failure to write the output")
+	fmt.Fprintln(out, "  2\ta flag that doesn't parse")
+	for _, exit := range exitCodes {
+		fmt.Fprintf(out, "  %d\t%s: %s\n", exit.code, exit.class, exit.description)
+	}
+}
+
+// jsonError is the error of -json-errors, its class and what the extraction gathered before it failed. Like a Report every key is
+// always there, what wasn't gathered is null or empty.
+type jsonError struct {
+	SchemaVersion  int
+	Error          string `json:"error"`
+	Class          string `json:"class"`
+	Stack          string `json:"stack"` // of a panic
+	Version        string // the guess, from the build info or a version string
+	VersionStrings []objfile.VersionString
+	Arch           string
+	OS             string
+	BuildId        string
+	Packer         string
+	LikelyPacked   *objfile.PackingInfo
+	Truncated      bool
+	Attempts       []goresym.CandidateAttempt
+	Diagnostics    *objfile.ScanDiagnostics
+	Failed         map[string]string // the error of each slice of a fat Mach-O
+}
+
+func newJsonError(message string, err error, metadata goresym.Report) jsonError {
+	failure := jsonError{
+		SchemaVersion:  goresym.SchemaVersion,
+		Error:          message,
+		Class:          goresym.ErrorClass(err),
+		Version:        metadata.Version,
+		VersionStrings: metadata.VersionStrings,
+		Arch:           metadata.Arch,
+		OS:             metadata.OS,
+		BuildId:        metadata.BuildId,
+		Packer:         metadata.Packer,
+		LikelyPacked:   metadata.LikelyPacked,
+		Truncated:      metadata.Truncated,
+		Attempts:       metadata.Attempts,
+		Diagnostics:    metadata.Diagnostics,
+	}
+	var panicErr *goresym.PanicError
+	if errors.As(err, &panicErr) {
+		failure.Stack = panicErr.Stack
+	}
+	return failure
+}
+
+// exitOnPanic is deferred by main, a panic outside the extraction, ex: in an output format, is an Internal error rather than a crash
+func exitOnPanic(jsonErrors *bool) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	err := &goresym.PanicError{Value: r, Stack: string(debug.Stack())}
+	if *jsonErrors {
+		fmt.Println(DataToJson(newJsonError(err.Error(), err, goresym.Report{})))
+	} else {
+		fmt.Println(TextToJson("error", err.Error()))
+	}
+	os.Exit(exitCode(err))
+}
@@ -8,7 +8,7 @@ auth_token = ""
 TREE_API_URL = "https://api.github.com/repos/golang/go/git/trees"
 TAG_API_URL = "https://api.github.com/repos/golang/go/git/refs/tags"
 DIR = "src"
-OUTPUT_FILE = "stdpackages.go"
+OUTPUT_FILE = "goresym/stdpackages.go"
 VAR_NAME = "standardPackages"
 
 def get_tree(tree_sha):
@@ -65,4 +65,4 @@ print(f"Writing paths to {OUTPUT_FILE}")
 # paths in the following format: {"path1", "path2", ...}
 paths_str = '{"' + '", "'.join(paths) + '"}'
 with open(OUTPUT_FILE, "w") as f:
-    f.write(f"package main\n\nvar {VAR_NAME} = []string{paths_str}")
+    f.write(f"package goresym\n\nvar {VAR_NAME} = []string{paths_str}")
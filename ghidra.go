@@ -0,0 +1,199 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+	"github.com/mandiant/GoReSym/goresym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// ghidraHeader is the start of the generated script, the recovered names follow it and ghidraBody applies them
+const ghidraHeader = `# Generated by GoReSym for %s, run it after the import in a headless batch:
+#   analyzeHeadless <project dir> <project name> -import %s -postScript <this script>
+# or from the Script Manager. Jython and PyGhidra both run it.
+# Running it again is harmless: what's already applied is left as is, and names and comments set by hand are only replaced with FORCE.
+#@category GoReSym
+from ghidra.program.model.address import AddressSet
+from ghidra.program.model.symbol import SourceType
+
+FORCE = False
+
+# a position independent ELF linked at 0 is loaded at an image base of Ghidra's choosing, the addresses move with it
+LINKED_AT_ZERO = %s
+
+`
+
+const ghidraBody = `
+
+OFFSET = 0
+if LINKED_AT_ZERO and currentProgram.getMetadata()["Executable Format"] == "Executable and Linking Format (ELF)":
+    OFFSET = currentProgram.getImageBase().getOffset()
+
+symbol_table = currentProgram.getSymbolTable()
+
+
+def address(va):
+    return toAddr(va + OFFSET)
+
+
+def namespace(path):
+    ns = currentProgram.getGlobalNamespace()
+    for part in path:
+        existing = symbol_table.getNamespace(part, ns)
+        ns = existing if existing is not None else symbol_table.createNameSpace(ns, part, SourceType.IMPORTED)
+    return ns
+
+
+def keep(symbol):
+    return symbol.getSource() == SourceType.USER_DEFINED and not FORCE
+
+
+def apply_comment(ea, comment):
+    current = getPreComment(ea)
+    if current != comment and (not current or FORCE):
+        setPreComment(ea, comment)
+
+
+def apply_function(start, end, path, name, comment):
+    entry = address(start)
+    func = getFunctionAt(entry)
+    if func is None:
+        disassemble(entry)
+        func = createFunction(entry, None)
+    if func is None:
+        print("GoReSym: no function at 0x%x" % start)
+        return
+    body = AddressSet(entry, address(end - 1))
+    if not func.getBody().equals(body):
+        try:
+            func.setBody(body)
+        except Exception as e:
+            print("GoReSym: keeping the body of %s: %s" % (name, e))
+    ns = namespace(path)
+    symbol = func.getSymbol()
+    if (symbol.getName() != name or symbol.getParentNamespace() != ns) and not keep(symbol):
+        symbol.setNameAndNamespace(name, ns, SourceType.IMPORTED)
+    apply_comment(entry, comment)
+
+
+def apply_label(va, path, name, comment):
+    ea = address(va)
+    ns = namespace(path)
+    symbol = getSymbolAt(ea)
+    if symbol is None or ((symbol.getName() != name or symbol.getParentNamespace() != ns) and not keep(symbol)):
+        createLabel(ea, name, ns, True, SourceType.IMPORTED)
+    apply_comment(ea, comment)
+
+
+for start, end, path, name, comment in FUNCTIONS:
+    apply_function(start, end, path, name, comment)
+
+for va, path, name, comment in TYPES:
+    apply_label(va, path, name, comment)
+
+print("GoReSym: applied %d functions and %d types" % (len(FUNCTIONS), len(TYPES)))
+`
+
+// ghidraName replaces the characters Ghidra rejects in a name, whitespace and controls, and the colons of its namespace separator
+func ghidraName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || r == ':' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// ghidraNamespace splits a function into the namespace path of its package and its name in it, 'github.com/x/y.(*T).M' ->
+// [github.com x y] and '(*T).M'. Functions without a package stay in the global namespace.
+func ghidraNamespace(fn goresym.FuncMetadata) ([]string, string) {
+	name, found := strings.CutPrefix(fn.FullName, fn.PackageName+".")
+	if len(fn.PackageName) == 0 || !found {
+		return nil, fn.FullName
+	}
+	var path []string
+	for _, part := range strings.Split(fn.PackageName, "/") {
+		path = append(path, ghidraName(part))
+	}
+	return path, name
+}
+
+// pythonString formats s as a unicode literal, Jython's plain literals are bytes and leave the escapes of other characters alone
+func pythonString(s string) string {
+	return "u" + strconv.QuoteToASCII(s)
+}
+
+// pythonStrings formats strs as a python list of unicode literals
+func pythonStrings(strs []string) string {
+	var quoted []string
+	for _, s := range strs {
+		quoted = append(quoted, pythonString(s))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// linkedAtZero reports whether the file is a position independent ELF linked at 0, like the C toolchains link them. Ghidra moves
+// those to an image base of its choosing, gc links its own at 0x400000 and those stay where they are.
+func linkedAtZero(fileName string) bool {
+	file, err := elf.Open(fileName)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	for _, prog := range file.Progs {
+		if prog.Type == elf.PT_LOAD {
+			return file.Type == elf.ET_DYN && prog.Vaddr == 0
+		}
+	}
+	return false
+}
+
+// printGhidraScript writes a Ghidra python script creating the functions of metadata in the namespaces of their packages, with their
+// source lines as pre-comments, and labeling the types. linkedAtZero tells the addresses are relative to an ELF linked at 0.
+// fileName goes in the header. Std functions and types are included when they were extracted, with -d and -t.
+func printGhidraScript(w io.Writer, fileName string, linkedAtZero bool, metadata goresym.Report) error {
+	var b strings.Builder
+	relocate := "False"
+	if linkedAtZero {
+		relocate = "True"
+	}
+	fmt.Fprintf(&b, ghidraHeader, headerName(fileName), headerName(fileName), relocate)
+
+	// names are unique within a namespace, an address is named once
+	names := make(uniqueNames)
+	named := make(map[uint64]bool)
+	b.WriteString("# start, end, namespace path, name and the comment of each function\nFUNCTIONS = [\n")
+	for _, funcs := range [][]goresym.FuncMetadata{metadata.UserFunctions, metadata.StdFunctions} {
+		for _, fn := range funcs {
+			if fn.Unmapped || named[fn.Start] {
+				continue
+			}
+			named[fn.Start] = true
+			path, name := ghidraNamespace(fn)
+			name = names.name(strings.Join(path, "::"), ghidraName(name), fn.Start)
+			fmt.Fprintf(&b, "    (0x%x, 0x%x, %s, %s, %s),\n", fn.Start, fn.End, pythonStrings(path), pythonString(name), pythonString(functionComment(fn)))
+		}
+	}
+	b.WriteString("]\n\n# va, namespace path, name and the Go type of each type structure\nTYPES = [\n")
+	for _, types := range [][]objfile.Type{metadata.Types, metadata.Interfaces} {
+		for _, typ := range types {
+			if named[typ.VA] {
+				continue
+			}
+			named[typ.VA] = true
+			name := names.name("type", ghidraName(typ.Str), typ.VA)
+			fmt.Fprintf(&b, "    (0x%x, [u\"type\"], %s, %s),\n", typ.VA, pythonString(name), pythonString(typ.Str))
+		}
+	}
+	b.WriteString("]\n")
+	b.WriteString(ghidraBody)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
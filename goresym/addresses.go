@@ -0,0 +1,92 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import "github.com/mandiant/GoReSym/objfile"
+
+// addressSpace gives the RVA and the file offset of the VAs of the records, through the sections or segments of the file
+type addressSpace struct {
+	file *objfile.File
+	base uint64
+}
+
+func newAddressSpace(file *objfile.File) addressSpace {
+	return addressSpace{file: file, base: file.RVABase()}
+}
+
+// locate is the RVA and the file offset of VA, the offset is nil when VA isn't in the file. A VA below the base has no RVA, 0.
+func (s addressSpace) locate(VA uint64) (uint64, *uint64) {
+	var offset *uint64
+	if found, err := s.file.VAToFileOffset(VA); err == nil {
+		offset = &found
+	}
+	if VA < s.base {
+		return 0, offset
+	}
+	return VA - s.base, offset
+}
+
+// locateFunction locates fn unless it's of a pclntab in the PE overlay, its VAs are of the payload and not of the image
+func (s addressSpace) locateFunction(fn *FuncMetadata) {
+	if !fn.Overlay {
+		fn.RVA, fn.FileOffset = s.locate(fn.Start)
+	}
+}
+
+func (s addressSpace) locateFunctions(funcs []FuncMetadata) {
+	for i := range funcs {
+		s.locateFunction(&funcs[i])
+	}
+}
+
+func (s addressSpace) locateTypes(types []objfile.Type) {
+	for i := range types {
+		types[i].RVA, types[i].FileOffset = s.locate(types[i].VA)
+	}
+}
+
+func (s addressSpace) locateItabs(itabs []InterfaceItabs) {
+	for i := range itabs {
+		for j := range itabs[i].Implementations {
+			impl := &itabs[i].Implementations[j]
+			impl.RVA, impl.FileOffset = s.locate(impl.VA)
+		}
+	}
+}
+
+// locateReport fills the RVAs and the file offsets of the records of metadata, the streamed ones were located as they were streamed.
+// The pclntab found in the PE overlay keeps the file offset the scan gave, it's not loaded.
+func (s addressSpace) locateReport(metadata *Report) {
+	metadata.RVABase = s.base
+	if !metadata.TabMeta.Overlay && metadata.TabMeta.VA != 0 {
+		metadata.TabMeta.RVA, metadata.TabMeta.FileOffset = s.locate(metadata.TabMeta.VA)
+	}
+	if metadata.ModuleMeta.VA != 0 {
+		metadata.ModuleMeta.RVA, metadata.ModuleMeta.FileOffset = s.locate(metadata.ModuleMeta.VA)
+	}
+	for i := range metadata.Modules {
+		module := &metadata.Modules[i]
+		module.ModuleDataRVA, module.ModuleDataFileOffset = s.locate(module.ModuleDataVA)
+		s.locateFunctions(module.UserFunctions)
+		s.locateFunctions(module.StdFunctions)
+		s.locateTypes(module.Types)
+		s.locateTypes(module.Interfaces)
+		s.locateItabs(module.Itabs)
+	}
+	s.locateTypes(metadata.Types)
+	s.locateTypes(metadata.Interfaces)
+	s.locateItabs(metadata.Itabs)
+	s.locateFunctions(metadata.UserFunctions)
+	s.locateFunctions(metadata.StdFunctions)
+	if metadata.DebugLink != nil {
+		s.locateFunctions(metadata.DebugLink.Functions)
+	}
+	for i := range metadata.Cgo.Functions {
+		s.locateFunction(&metadata.Cgo.Functions[i].FuncMetadata)
+	}
+	if metadata.Heuristic != nil {
+		for i := range metadata.Heuristic.Functions {
+			fn := &metadata.Heuristic.Functions[i]
+			fn.RVA, fn.FileOffset = s.locate(fn.Start)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// kinds of cgo boundary function
+const (
+	cgoExport  = "export"  // Go function exported to C with //export, or the C side of it
+	cgoCall    = "call"    // Go stub calling a C function, C.name
+	cgoRuntime = "runtime" // glue from runtime/cgo and the generated _cgo_ helpers
+	cgoNative  = "native"  // text symbol with no pclntab entry, C code linked in
+)
+
+// CgoFunction is a function on the boundary between Go and C
+type CgoFunction struct {
+	FuncMetadata
+	Kind  string
+	CName string // C name of exports and calls
+}
+
+// CgoExport is a C entry point of a c-shared library, from the export table. GoFunction is the wrapper it calls into Go through,
+// Function the Go function the //export is on. A small one is inlined into the wrapper, it has no VA of its own then.
+type CgoExport struct {
+	Name       string
+	VA         uint64
+	GoFunction string
+	Function   string
+	FunctionVA uint64
+	Inlined    bool
+}
+
+// CgoMetadata describes the native code surface of a cgo binary. Evidence lists what gave it away, a CGO_ENABLED=1 build setting
+// alone only allowed cgo, it's evidence without making it Present.
+type CgoMetadata struct {
+	Present   bool
+	Evidence  []string
+	Functions []CgoFunction
+	Exports   []CgoExport
+}
+
+// classifyCgoFunction tags a pclntab function that only exists in cgo builds. These names survive stripping since they're in the pclntab.
+func classifyCgoFunction(name string) (kind string, cName string) {
+	// pkg._cgoexp_<hash>_Name, older toolchains omit the hash and newer ones the package
+	if idx := strings.Index(name, "_cgoexp_"); idx == 0 || (idx > 0 && name[idx-1] == '.') {
+		cName = name[idx+len("_cgoexp_"):]
+		if underscore := strings.Index(cName, "_"); underscore > 0 && isHexString(cName[:underscore]) {
+			cName = cName[underscore+1:]
+		}
+		return cgoExport, cName
+	}
+
+	// pkg._Cfunc_name
+	if idx := strings.Index(name, "._Cfunc_"); idx >= 0 {
+		return cgoCall, name[idx+len("._Cfunc_"):]
+	}
+
+	// crosscall2 is assembly in runtime/cgo, it's the entry point from C into Go
+	if name == "crosscall2" || strings.HasPrefix(name, "runtime/cgo.") || strings.HasPrefix(name, "_cgo_") || strings.HasPrefix(name, "x_cgo_") || strings.Contains(name, "._cgo_") {
+		return cgoRuntime, ""
+	}
+	return "", ""
+}
+
+func isHexString(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+// cSymbolName removes the leading underscore Mach-O adds to C symbols
+func cSymbolName(name string) string {
+	if strings.HasPrefix(name, "_") && !strings.HasPrefix(name, "_cgo_") {
+		return name[1:]
+	}
+	return name
+}
+
+// recoverCgo tags the cgo boundary functions of the pclntab, then adds the text symbols the pclntab doesn't cover, which are the C side.
+// Presence is decided from the pclntab alone so stripped binaries are still detected. table, the pclntab the funcs are of, syms and
+// settings, the build settings by key, may be nil.
+func recoverCgo(table *gosym.Table, funcs []gosym.Func, syms []objfile.Sym, settings map[string]string) CgoMetadata {
+	var cgo CgoMetadata
+
+	evidence := make(map[string]bool)
+	exports := make(map[string]bool)
+	knownFuncs := make(map[uint64]bool)
+	for _, fn := range funcs {
+		knownFuncs[fn.Entry] = true
+
+		kind, cName := classifyCgoFunction(fn.Name)
+		if len(kind) == 0 {
+			continue
+		}
+
+		cgo.Present = true
+		switch {
+		case kind == cgoExport:
+			exports[cName] = true
+			evidence["pclntab: _cgoexp_ wrappers of //export functions"] = true
+		case kind == cgoCall:
+			evidence["pclntab: _Cfunc_ calls into C"] = true
+		case fn.Name == "crosscall2":
+			evidence["pclntab: crosscall2, the entry from C into Go"] = true
+		default:
+			evidence["pclntab: runtime/cgo functions"] = true
+		}
+		end, size := funcExtent(table, &fn)
+		cgo.Functions = append(cgo.Functions, CgoFunction{
+			FuncMetadata: FuncMetadata{Start: fn.Entry, End: end, Size: size, PackageName: fn.PackageName(), FullName: fn.Name},
+			Kind:         kind,
+			CName:        cName,
+		})
+	}
+
+	// x_cgo_init is the C half of runtime._cgo_init, the pointer itself exists in every binary
+	for _, sym := range syms {
+		if cSymbolName(sym.Name) == "x_cgo_init" {
+			cgo.Present = true
+			evidence["symbols: x_cgo_init"] = true
+		}
+	}
+	if settings["CGO_ENABLED"] == "1" {
+		evidence["build setting: CGO_ENABLED=1"] = true
+	}
+	for found := range evidence {
+		cgo.Evidence = append(cgo.Evidence, found)
+	}
+	sort.Strings(cgo.Evidence)
+
+	// without cgo every text symbol is Go code, anything missing from the pclntab is a linker marker
+	if !cgo.Present {
+		return cgo
+	}
+
+	for _, sym := range syms {
+		if (sym.Code != 'T' && sym.Code != 't') || sym.Size <= 0 || knownFuncs[sym.Addr] {
+			continue
+		}
+
+		native := CgoFunction{
+			FuncMetadata: FuncMetadata{Start: sym.Addr, End: sym.Addr + uint64(sym.Size), FullName: sym.Name},
+			Kind:         cgoNative,
+		}
+
+		// the C wrapper _cgo_export.c generates for //export, or a runtime/cgo C helper
+		cName := cSymbolName(sym.Name)
+		if exports[cName] {
+			native.Kind = cgoExport
+			native.CName = cName
+		} else if kind, _ := classifyCgoFunction(cName); kind == cgoRuntime {
+			native.Kind = cgoRuntime
+		}
+		cgo.Functions = append(cgo.Functions, native)
+	}
+	return cgo
+}
+
+// exportedEntryPoints picks the //export functions out of the exports of the file, the names a _cgoexp_ wrapper in the pclntab is for.
+// The other exports are the runtime/cgo glue every c-shared library has. The export table survives stripping, unlike the C symbols.
+// The Go function of an export is pkg.Name, in the package of the wrapper when its name has one, else in main or any package,
+// else the one inlined into the wrapper. inlined gives the calls inlined into a function, it may be nil.
+func exportedEntryPoints(cgo CgoMetadata, exports []objfile.Export, funcs []gosym.Func, inlined func(fn *gosym.Func) []gosym.InlinedCall) []CgoExport {
+	wrappers := make(map[string]string)
+	for _, fn := range cgo.Functions {
+		if fn.Kind == cgoExport && strings.Contains(fn.FullName, "_cgoexp_") {
+			wrappers[fn.CName] = fn.FullName
+		}
+	}
+
+	var entryPoints []CgoExport
+	for _, export := range exports {
+		wrapper, ok := wrappers[export.Name]
+		if !ok {
+			continue
+		}
+		entryPoint := CgoExport{Name: export.Name, VA: export.VA, GoFunction: wrapper}
+		pkg := "main"
+		if idx := strings.Index(wrapper, "._cgoexp_"); idx > 0 {
+			pkg = wrapper[:idx]
+		}
+
+		var wrapperFunc, candidate *gosym.Func
+		for i := range funcs {
+			name := funcs[i].Name
+			switch {
+			case name == wrapper:
+				wrapperFunc = &funcs[i]
+			case name == pkg+"."+export.Name:
+				candidate = &funcs[i]
+			case candidate == nil && strings.HasSuffix(name, "."+export.Name) && funcs[i].PackageName()+"."+export.Name == name:
+				candidate = &funcs[i]
+			}
+		}
+		if candidate != nil {
+			entryPoint.Function, entryPoint.FunctionVA = candidate.Name, candidate.Entry
+		} else if wrapperFunc != nil && inlined != nil {
+			for _, call := range inlined(wrapperFunc) {
+				if call.Parent < 0 && strings.HasSuffix(call.Name, "."+export.Name) {
+					entryPoint.Function, entryPoint.Inlined = call.Name, true
+					break
+				}
+			}
+		}
+		entryPoints = append(entryPoints, entryPoint)
+	}
+	return entryPoints
+}
@@ -0,0 +1,59 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+)
+
+// over this many distinct standard library packages (excluding cmd/...) is atypical for application code.
+// Large applications like kubectl link ~150, the go command and gopls link most of the library.
+const largeStdFootprint = 200
+
+// go/types is the compiler's type checker, linked only by tools that compile or analyze Go source
+const goTypeChecker = "go/types"
+
+// isToolchainPackage reports whether pkg belongs to the Go toolchain itself (cmd/...) or its source front end (go/...)
+func isToolchainPackage(pkg string) bool {
+	return strings.HasPrefix(pkg, "cmd/") || strings.HasPrefix(pkg, "go/")
+}
+
+// BinaryComposition characterizes the mix of packages linked into the binary
+type BinaryComposition struct {
+	StdPackageCount   int      // distinct standard library packages, excluding cmd/...
+	LargeStdFootprint bool     // StdPackageCount is atypically large for application code
+	ToolchainPackages []string // cmd/... and go/... packages present, sorted
+	// cmd/... packages are internal to the toolchain and go/types is its type checker, either means compiler code is linked in
+	EmbedsToolchain bool
+}
+
+// analyzeComposition groups the functions by package and flags binaries that carry a Go toolchain or an unusually large part of the standard library
+func analyzeComposition(funcs []gosym.Func) BinaryComposition {
+	var composition BinaryComposition
+
+	seen := make(map[string]bool)
+	for _, fn := range funcs {
+		pkg := fn.PackageName()
+		if len(pkg) == 0 || seen[pkg] || !isStdPackage(pkg) {
+			continue
+		}
+		seen[pkg] = true
+
+		if isToolchainPackage(pkg) {
+			composition.ToolchainPackages = append(composition.ToolchainPackages, pkg)
+			if strings.HasPrefix(pkg, "cmd/") || pkg == goTypeChecker {
+				composition.EmbedsToolchain = true
+			}
+		}
+
+		if !strings.HasPrefix(pkg, "cmd/") {
+			composition.StdPackageCount++
+		}
+	}
+
+	sort.Strings(composition.ToolchainPackages)
+	composition.LargeStdFootprint = composition.StdPackageCount >= largeStdFootprint
+	return composition
+}
@@ -0,0 +1,33 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"strings"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// usesEmbed is set when the embed package is linked in, an embed.FS nothing reads is dropped by the linker with its files
+func usesEmbed(funcs []gosym.Func) bool {
+	for _, fn := range funcs {
+		if strings.HasPrefix(fn.Name, "embed.") {
+			return true
+		}
+	}
+	return false
+}
+
+// embeddedFS names each embed.FS after its variable in the symbols, ex: main.assets
+func embeddedFS(found []objfile.EmbeddedFS, syms []objfile.Sym) []objfile.EmbeddedFS {
+	names := make(map[uint64]string)
+	for _, sym := range syms {
+		if sym.Code != 'T' && sym.Code != 't' && sym.Code != 'U' {
+			names[sym.Addr] = sym.Name
+		}
+	}
+	for i := range found {
+		found[i].Name = names[found[i].VA]
+	}
+	return found
+}
@@ -0,0 +1,110 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"runtime/debug"
+)
+
+// The classes of the errors Extract fails with, errors.Is matches an error with its class. The message is the error's own, the
+// class only tells what kind of failure it is, ex: a sample that isn't Go from a Go binary stripped beyond recovery.
+var (
+	// the file isn't an executable, or one with no trace of Go: no pclntab candidate, no build info and no version string
+	ErrNotGo = errors.New("not a Go binary")
+	// the moduledata can't be found because no moduledata signature covers the architecture
+	ErrUnsupportedArch = errors.New("unsupported architecture")
+	// a Go binary whose pclntab is gone or doesn't parse, ex: stripped, packed or encrypted
+	ErrNoPclntab = errors.New("no valid pclntab found")
+	// the pclntab parses but no moduledata points at it
+	ErrNoModuleData = errors.New("no valid moduledata found")
+	// moduledata candidates were found, or one was given, but none validates against the pclntab
+	ErrCorruptModuleData = errors.New("corrupt moduledata")
+	// the file can't be read, ex: it doesn't exist or it can't be mapped
+	ErrIO = errors.New("I/O error")
+	// a bug of the parsers, ex: a panic on a hostile file. The error is a *PanicError.
+	ErrInternal = errors.New("internal error")
+)
+
+// the name of each class in ErrorClass, in the order they're checked
+var errorClasses = []struct {
+	class error
+	name  string
+}{
+	{ErrCanceled, "Canceled"},
+	{ErrInternal, "Internal"},
+	{ErrIO, "IOError"},
+	{ErrNotGo, "NotGo"},
+	{ErrUnsupportedArch, "UnsupportedArch"},
+	{ErrNoPclntab, "NoPclntab"},
+	{ErrCorruptModuleData, "CorruptModuledata"},
+	{ErrNoModuleData, "NoModuledata"},
+}
+
+// ErrorClass names the class of an error of Extract: NotGo, UnsupportedArch, NoPclntab, NoModuledata, CorruptModuledata, IOError,
+// Internal or Canceled. Every failure of Extract has one. Of other errors a *fs.PathError is an IOError and the rest are Internal.
+func ErrorClass(err error) string {
+	for _, class := range errorClasses {
+		if errors.Is(err, class.class) {
+			return class.name
+		}
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return "IOError"
+	}
+	return "Internal"
+}
+
+// classifiedError is err with its class, the message is err's own
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.class, e.err}
+}
+
+// classify gives err the class, errors.Is then matches both
+func classify(class error, err error) error {
+	return &classifiedError{class: class, err: err}
+}
+
+// classifyOpen is the class of a file that doesn't open: one that can't be read is an IOError, the rest aren't executables
+func classifyOpen(err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return classify(ErrIO, err)
+	}
+	return classify(ErrNotGo, err)
+}
+
+// PanicError is a panic of the parsers Extract recovered, the Report is then empty. It's an ErrInternal.
+type PanicError struct {
+	Value any
+	Stack string // of the goroutine that panicked
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	return ErrInternal
+}
+
+// recoverPanic turns a panic into a PanicError in *err and closes the file of *report, deferred by each entry point so one bad
+// sample can't crash a batch
+func recoverPanic(report *Report, err *error) {
+	if r := recover(); r != nil {
+		report.Close()
+		*report = Report{SchemaVersion: SchemaVersion}
+		*err = &PanicError{Value: r, Stack: string(debug.Stack())}
+	}
+}
@@ -0,0 +1,1207 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	// we copy the go src directly, then change every include to github.com/mandiant/GoReSym/<whatever>
+	// this is required since we're using internal files. Our modifications are directly inside the copied source
+	"github.com/mandiant/GoReSym/buildid"
+	"github.com/mandiant/GoReSym/buildinfo"
+	"github.com/mandiant/GoReSym/debug/gosym"
+	"github.com/mandiant/GoReSym/objfile"
+	"github.com/mandiant/GoReSym/runtime/debug"
+)
+
+// cancellable context constructors, the *Cause variants exist >= 1.20/1.21
+var contextConstructors = map[string]bool{
+	"context.WithCancel":        true,
+	"context.WithCancelCause":   true,
+	"context.WithDeadline":      true,
+	"context.WithDeadlineCause": true,
+	"context.WithTimeout":       true,
+	"context.WithTimeoutCause":  true,
+}
+
+// reflective struct field lookups by name. reflect.Type is an interface so only devirtualized calls resolve.
+var fieldByNameFuncs = map[string]bool{
+	"reflect.Value.FieldByName":         true,
+	"reflect.(*rtype).FieldByName":      true,
+	"reflect.(*structType).FieldByName": true,
+}
+
+// expvar registrations, the names are served as JSON keys on /debug/vars
+var expvarFuncs = map[string]bool{
+	"expvar.Publish":   true,
+	"expvar.NewInt":    true,
+	"expvar.NewFloat":  true,
+	"expvar.NewMap":    true,
+	"expvar.NewString": true,
+}
+
+// the compilers a binary can be built by
+const (
+	compilerGc     = "gc"
+	compilerTinyGo = "tinygo"
+)
+
+// the packages TinyGo ships on top of the standard library, ex: machine drives the pins of a microcontroller
+var tinygoStdPackages = []string{"machine", "device", "internal/task", "runtime/interrupt", "runtime/volatile"}
+
+func isStdPackage(pkg string) bool {
+	// Empty name is common for reflect/type functions and some runtime symbols
+	if len(strings.TrimSpace(pkg)) <= 0 {
+		return true
+	}
+
+	for _, v := range standardPackages {
+		if v == pkg {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canceled is the error Extract fails with once ctx is done, nil before
+func canceled(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrCanceled, err)
+	}
+	return nil
+}
+
+// extract recovers the Report of an opened file, either the file at fileName or the image read through image
+func extract(ctx context.Context, file *objfile.File, fileName string, image io.ReaderAt, clock *phaseClock, opts Options) (metadata Report, err error) {
+	extractMetadata := Report{}
+	// every return goes through here, the records are located once
+	space := newAddressSpace(file)
+	defer func() { space.locateReport(&metadata) }()
+	timings := &Timings{}
+	var moduleDataTime time.Duration
+	extractMetadata.Timings = timings
+	extractMetadata.Filtered = newFilterCounts(opts)
+
+	// packed files still open fine, only the stub is visible. Keep going in case the detection is wrong, but explain the failure if parsing fails.
+	if len(fileName) > 0 {
+		opts.Log.Printf(1, "extracting %s", fileName)
+	}
+	file.SetLogger(opts.Log)
+	file.SetDiagnostics(true)
+	file.SetTypeFilter(opts.TypeFilter)
+	file.SetContext(ctx)
+	file.SetScanRanges(opts.ScanRanges)
+	file.SetTolerant(opts.Tolerant)
+	file.SetGCData(opts.GCData)
+	file.SetMethods(opts.Methods)
+	if err := file.SetSignatures(opts.Signatures); err != nil {
+		return metadata, err
+	}
+
+	// a truncated file gives what lies within the bytes it has, Report.Unavailable lists what's past its end
+	truncation := file.Truncation()
+	if truncation != nil {
+		extractMetadata.Truncated = true
+		opts.Log.Printf(1, "the file is truncated: 0x%x of the 0x%x bytes its headers lay out", truncation.Size, truncation.Extent)
+	}
+
+	packer := file.Packer()
+	extractMetadata.Packer = packer
+	extractMetadata.LikelyPacked = file.LikelyPacked()
+	extractMetadata.BuildMode = file.BuildMode()
+	extractMetadata.ImageBase = file.ImageBase()
+	extractMetadata.Image = file.Image()
+	// the pclntabs of the embedded images are theirs, those of the images they embed are found when they're extracted
+	var embedded []objfile.EmbeddedImage
+	if file.Image() == nil {
+		embedded = file.EmbeddedImages()
+		extractMetadata.EmbeddedImages = embedded
+	}
+	if extractMetadata.Slide = file.Slide(); extractMetadata.Slide != 0 {
+		opts.Log.Printf(1, "the image is slid by 0x%x from the addresses of its headers", extractMetadata.Slide)
+	}
+
+	// a slice of a fat Mach-O is read alone, the file as a whole reads as its first slice
+	slice := file.FatSlice()
+	var buildId string
+	switch {
+	case slice != nil:
+		buildId, err = buildid.ReadMachoSlice(fileName, slice)
+	case image != nil:
+		buildId, err = buildid.ReadImage(image)
+	default:
+		buildId, err = buildid.Read(fileName, file.Reader())
+	}
+	if err == nil {
+		extractMetadata.BuildId = buildId
+	} else {
+		extractMetadata.BuildId = ""
+	}
+
+	// try to get version the 'correct' way, also fill out buildSettings if parsing was ok
+	bi, err := buildinfo.Read(file.Reader())
+	if slice != nil {
+		bi, err = buildinfo.Read(slice)
+	}
+	if err == nil {
+		extractMetadata.Version = bi.GoVersion
+
+		for _, setting := range bi.Settings {
+			if setting.Key == "GOOS" {
+				extractMetadata.OS = setting.Value
+			} else if setting.Key == "GOARCH" {
+				extractMetadata.Arch = setting.Value
+			} else if setting.Key == "-buildmode" {
+				extractMetadata.BuildMode = setting.Value
+			}
+			if extractMetadata.BuildSettings == nil {
+				extractMetadata.BuildSettings = make(map[string]string)
+			}
+			extractMetadata.BuildSettings[setting.Key] = setting.Value
+		}
+		if system, ok := extractMetadata.BuildSettings["vcs"]; ok {
+			extractMetadata.VCS = &VCSInfo{
+				System:   system,
+				Revision: extractMetadata.BuildSettings["vcs.revision"],
+				Time:     extractMetadata.BuildSettings["vcs.time"],
+				Modified: extractMetadata.BuildSettings["vcs.modified"] == "true",
+			}
+		}
+
+		extractMetadata.BuildInfo = *bi
+	} else if truncation != nil && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) {
+		extractMetadata.Unavailable = append(extractMetadata.Unavailable, "build info: its region extends past EOF")
+	}
+
+	// every artifact telling the version is a source, they're weighed once the structures they must agree with are parsed
+	var versionSources []VersionSource
+	if claim, ok := versionClaim(versionSourceBuildInfo, extractMetadata.BuildInfo.GoVersion); ok {
+		versionSources = append(versionSources, claim)
+	}
+	if buildVersion, err := file.BuildVersion(); err == nil {
+		if claim, ok := versionClaim(versionSourceBuildVersion, buildVersion); ok {
+			versionSources = append(versionSources, claim)
+		}
+	}
+
+	// Optional bruteforce any one of these, but only if they weren't previous found in the buildinfo
+	if extractMetadata.OS == "" || extractMetadata.Arch == "" || extractMetadata.Version == "" {
+		// GOARCH
+		if extractMetadata.Arch == "" {
+			extractMetadata.Arch = file.GOARCH()
+		}
+
+		// GOVERSION, from the version strings the string headers of the data point at
+		if extractMetadata.Version == "" {
+			extractMetadata.VersionStrings = file.VersionStrings()
+			if len(extractMetadata.VersionStrings) > 0 {
+				extractMetadata.Version = extractMetadata.VersionStrings[0].Version
+				if claim, ok := versionClaim(versionSourceStringScan, extractMetadata.Version); ok {
+					versionSources = append(versionSources, claim)
+				}
+			}
+		}
+
+		// GOVERSION, else the first go1. of the file, which is scanned a chunk at a time rather than read whole
+		if extractMetadata.Version == "" {
+			// go1.<varies><garbage data>
+			idx, after := file.IndexFile([]byte{0x67, 0x6F, 0x31, 0x2E}, 7)
+			if idx != -1 && len(after) > 6 {
+				extractMetadata.Version = "go1."
+				ver := after[:6]
+				for i, c := range ver {
+					// the string is _not_ null terminated, nor length delimited. So, filter till first non-numeric ascii
+					nextIsNumeric := (i+1) < len(ver) && ver[i+1] >= 0x30 && ver[i+1] <= 0x39
+
+					// careful not to end with a . at the end
+					if (c >= 0x30 && c <= 0x39 && c != ' ') || (c == '.' && nextIsNumeric) {
+						extractMetadata.Version += string([]byte{c})
+					} else {
+						break
+					}
+				}
+				if claim, ok := versionClaim(versionSourceString, extractMetadata.Version); ok {
+					versionSources = append(versionSources, claim)
+				}
+			}
+		}
+
+		// GOOS
+		if extractMetadata.OS == "" {
+			// try to find the OS by locating the source file name from https://github.com/golang/go/tree/master/src/runtime/os_<os name>.go or the asm file name rt0_<os name>_<arch>.s
+			// if this is bad, we can end up signaturing the asm file manually (todo)
+			// /src/runtime/os_
+			needleSrcFile := []byte{0x2F, 0x73, 0x72, 0x63, 0x2F, 0x72, 0x75, 0x6E, 0x74, 0x69, 0x6D, 0x65, 0x2F, 0x6F, 0x73, 0x5F}
+			idx, after := file.IndexFile(needleSrcFile, 21)
+			if idx != -1 && len(after) > 20 {
+				os_str := after[:20]
+				for _, c := range os_str {
+					// end our search at the first '.', which should be the .go soure file extension, or a space as fallback
+					if (c >= 0x30 && c <= 0x5a) || (c >= 0x61 && c <= 0x7a) && c != '.' && c != ' ' {
+						extractMetadata.OS += string([]byte{c})
+					} else {
+						break
+					}
+				}
+			} else {
+				// /src/runtime/rt0_
+				needleAsmFile := []byte{0x2F, 0x73, 0x72, 0x63, 0x2F, 0x72, 0x75, 0x6E, 0x74, 0x69, 0x6D, 0x65, 0x2F, 0x72, 0x74, 0x30, 0x5F}
+				idx, after := file.IndexFile(needleAsmFile, 21)
+				if idx != -1 && len(after) > 20 {
+					os_str := after[:20]
+					for _, c := range os_str {
+						// end our search at the first '_', which should be the _arch, space as fallback
+						if (c >= 0x30 && c <= 0x5a) || (c >= 0x61 && c <= 0x7a) && c != '_' && c != '.' && c != ' ' {
+							extractMetadata.OS += string([]byte{c})
+						} else {
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// what the claims tell until a pclntab backs them up, for the Report of a failure
+	extractMetadata.VersionDetection = decideVersion(versionSources)
+
+	timings.Open = milliseconds(clock.lap())
+	opts.Log.Printf(1, "opened in %.3fms: %s, build mode %s", timings.Open, extractMetadata.Arch, extractMetadata.BuildMode)
+	if err := canceled(ctx); err != nil {
+		return extractMetadata, err
+	}
+
+	if opts.ModuleDataOffset != 0 {
+		VA, err := file.FileOffsetToVA(opts.ModuleDataOffset)
+		if err != nil {
+			return failedReport(extractMetadata, file, nil), classify(ErrCorruptModuleData, fmt.Errorf("the moduledata offset 0x%x: %w", opts.ModuleDataOffset, err))
+		}
+		opts.Log.Printf(1, "the moduledata offset 0x%x is loaded at 0x%x", opts.ModuleDataOffset, VA)
+		opts.ModuleData = VA
+	}
+	if opts.PclntabOffset != 0 {
+		VA, err := file.FileOffsetToVA(opts.PclntabOffset)
+		if err != nil {
+			return failedReport(extractMetadata, file, nil), classify(ErrNoPclntab, fmt.Errorf("the pclntab offset 0x%x: %w", opts.PclntabOffset, err))
+		}
+		opts.Log.Printf(1, "the pclntab offset 0x%x is loaded at 0x%x", opts.PclntabOffset, VA)
+		opts.Pclntab = VA
+	}
+	// a given moduledata is the only candidate, the error of each step is the reason it didn't validate
+	file.SetModuleData(opts.ModuleData)
+	// a given pclntab goes without a moduledata, the moduledata wins when both are
+	if opts.ModuleData == 0 {
+		file.SetPclntab(opts.Pclntab, opts.TextStart)
+	} else {
+		opts.Pclntab = 0
+	}
+	var moduleDataErr error
+	// the candidates tried, for the Report of a failure
+	var attempts []CandidateAttempt
+
+	var knownPclntabVA = uint64(0)
+	var knownGoTextBase = uint64(0)
+
+restartParseWithRealTextBase:
+	scanCtx, cancelScan := context.WithCancel(ctx)
+	file.SetContext(scanCtx)
+	ch_tabs, err := file.PCLineTable(opts.Version, knownPclntabVA, knownGoTextBase)
+	// the scan reads the file in the background, it's stopped once a candidate is picked so it can't read the file after Close
+	stopScan := func() {
+		cancelScan()
+		for range ch_tabs {
+		}
+		file.SetContext(ctx)
+	}
+	if err != nil {
+		cancelScan()
+		file.SetContext(ctx)
+		if err := canceled(ctx); err != nil {
+			return extractMetadata, err
+		}
+		if opts.ModuleData != 0 {
+			return failedReport(extractMetadata, file, nil), classify(ErrCorruptModuleData, fmt.Errorf("the moduledata at 0x%x doesn't validate: %w", opts.ModuleData, err))
+		}
+		if opts.Pclntab != 0 {
+			return failedReport(extractMetadata, file, nil), classify(ErrNoPclntab, fmt.Errorf("the pclntab at 0x%x doesn't validate: %w", opts.Pclntab, err))
+		}
+		if tinygo := file.TinyGo(); tinygo != nil {
+			return extractTinyGo(file, fileName, extractMetadata, tinygo, clock, opts)
+		}
+		return failedReport(extractMetadata, file, nil), classify(noPclntabClass(extractMetadata, nil), fmt.Errorf("failed to read pclntab: %w", err))
+	}
+
+	var moduleData *objfile.ModuleData = nil
+	var finalTab *objfile.PclntabCandidate = nil
+	var overlayTab *objfile.PclntabCandidate = nil
+	var truncatedTab *objfile.PclntabCandidate = nil
+	for tab := range ch_tabs {
+		if canceled(ctx) != nil {
+			break
+		}
+		opts.Log.Printf(1, "trying the pclntab candidate at 0x%x in the section at 0x%x: %s layout, %d functions%s", tab.PclntabVA, tab.SecStart, tab.ParsedPclntab.Go12line.Version, len(tab.ParsedPclntab.Funcs), foundIn(&tab))
+		// the structure of this candidate backs the claims up or contradicts them, a stomped magic tells nothing
+		candidateSources := tabVersionSources(versionSources, &tab)
+		if len(opts.Version) > 0 {
+			extractMetadata.Version = opts.Version
+		} else if consensus := decideVersion(candidateSources).Consensus; len(consensus) > 0 {
+			extractMetadata.Version = consensus
+		}
+
+		// numeric only, go1.17 -> 1.17
+		extractMetadata.Version = normalizeGoVersion(extractMetadata.Version)
+
+		// a stomped magic is tried as every layout, the runtime version from the build info tells which one is right
+		if tab.ReconstructedMagic && len(opts.Version) == 0 {
+			if layout := gosym.PclntabLayoutForGoVersion(extractMetadata.Version); len(layout) > 0 && layout != tab.ParsedPclntab.Go12line.Version.String() {
+				reason := fmt.Sprintf("the magic restored as %s isn't the %s layout of Go %s", tab.ParsedPclntab.Go12line.Version, layout, extractMetadata.Version)
+				opts.Log.Printf(2, "pclntab candidate at 0x%x rejected: %s", tab.PclntabVA, reason)
+				attempts = append(attempts, candidateAttempt(&tab, reason))
+				continue
+			}
+		}
+
+		// a pclntab of an embedded image is left to its extraction, a given one is taken wherever it is
+		if opts.Pclntab == 0 {
+			if in := embeddedImageOf(file, embedded, &tab); in != nil {
+				reason := fmt.Sprintf("in the embedded Go image %d at file offset 0x%x, extracted on its own", in.Index, in.FileOffset)
+				opts.Log.Printf(2, "pclntab candidate at 0x%x rejected: %s", tab.PclntabVA, reason)
+				attempts = append(attempts, candidateAttempt(&tab, reason))
+				continue
+			}
+		}
+
+		// a given pclntab is taken as is, the moduledata that would confirm it is gone
+		if opts.Pclntab != 0 {
+			extractMetadata.TabMeta = tabMetadata(&tab)
+			finalTab = &tab
+			break
+		}
+
+		// no moduledata can point at a pclntab without a VA, or at the one of a payload in a resource, it's only used when nothing else parses
+		if tab.Overlay || tab.Resource {
+			if overlayTab == nil {
+				first := tab
+				overlayTab = &first
+			}
+			continue
+		}
+
+		extractMetadata.TabMeta = tabMetadata(&tab)
+
+		// this can be a little tricky to locate and parse properly across all go versions
+		// since moduledata holds a pointer to the pclntab, we can (hopefully) find the right candidate by using it to find the moduledata.
+		// if that location works, then we must have given it the correct pclntab VA. At least in theory...
+		// The resolved offsets within the pclntab might have used the wrong base though! We'll fix that later.
+		moduleDataStart := time.Now()
+		_, tmpModData, err := file.ModuleDataTable(tab.PclntabVA, extractMetadata.Version, extractMetadata.TabMeta.Version, extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian")
+		moduleDataTime += time.Since(moduleDataStart)
+		if err == nil && tmpModData != nil {
+			// if the search candidate relied on a moduledata va, make sure it lines up with ours now
+			stomppedMagicMetaConstraintsValid := true
+			if tab.StompMagicCandidateMeta != nil {
+				stomppedMagicMetaConstraintsValid = tab.StompMagicCandidateMeta.SuspectedModuleDataVa == tmpModData.VA
+			}
+
+			if knownGoTextBase == 0 && knownPclntabVA == 0 && stomppedMagicMetaConstraintsValid {
+				// assign real base and restart pclntab parsing with correct VAs!
+				knownGoTextBase = tmpModData.TextVA
+				knownPclntabVA = tab.PclntabVA
+				opts.Log.Printf(1, "moduledata at 0x%x points at the pclntab at 0x%x, parsing it again with the text base 0x%x", tmpModData.VA, tab.PclntabVA, knownGoTextBase)
+				stopScan()
+				goto restartParseWithRealTextBase
+			}
+
+			// we already have pclntab candidates with the right VA, but which candidate?? The one that finds a valid moduledata!
+			layout := tmpModData.Layout()
+			if tmpModData.LayoutSource == objfile.LayoutProbed {
+				layout = "probed"
+			}
+			opts.Log.Printf(1, "picked the pclntab at 0x%x and the %s moduledata at 0x%x", tab.PclntabVA, layout, tmpModData.VA)
+			finalTab = &tab
+			moduleData = tmpModData
+			break
+		}
+		opts.Log.Printf(1, "pclntab candidate at 0x%x rejected: %v", tab.PclntabVA, err)
+		moduleDataErr = err
+		attempts = append(attempts, candidateAttempt(&tab, err.Error()))
+		if truncation != nil && truncatedTab == nil && len(tab.ParsedPclntab.Funcs) > 0 {
+			first := tab
+			truncatedTab = &first
+		}
+	}
+
+	stopScan()
+
+	// the candidate that would have validated may be among those left
+	if err := canceled(ctx); err != nil {
+		return extractMetadata, err
+	}
+
+	if finalTab == nil && truncatedTab != nil && opts.ModuleData == 0 {
+		// the moduledata of a truncated file can be past its end, the first pclntab that parses is taken without it
+		opts.Log.Printf(1, "no moduledata validated in the truncated file, taking the pclntab at 0x%x without one", truncatedTab.PclntabVA)
+		finalTab = truncatedTab
+		extractMetadata.TabMeta = tabMetadata(truncatedTab)
+	}
+
+	if finalTab == nil && overlayTab != nil {
+		// the functions are all an overlay or resource pclntab gives, the types need the moduledata
+		finalTab = overlayTab
+		extractMetadata.TabMeta = tabMetadata(overlayTab)
+	}
+
+	if opts.ModuleData != 0 && moduleData == nil {
+		if moduleDataErr == nil {
+			moduleDataErr = fmt.Errorf("the pclntab it points at doesn't parse")
+		}
+		return failedReport(extractMetadata, file, attempts), classify(ErrCorruptModuleData, fmt.Errorf("the moduledata at 0x%x doesn't validate: %w", opts.ModuleData, moduleDataErr))
+	}
+
+	if opts.Pclntab != 0 && finalTab == nil {
+		return failedReport(extractMetadata, file, attempts), classify(ErrNoPclntab, fmt.Errorf("the pclntab at 0x%x doesn't parse", opts.Pclntab))
+	}
+
+	if finalTab == nil {
+		// TinyGo compiles through LLVM, it's expected to have no pclntab
+		if tinygo := file.TinyGo(); tinygo != nil {
+			return extractTinyGo(file, fileName, extractMetadata, tinygo, clock, opts)
+		}
+		if opts.HeuristicFunctions {
+			heuristic, err := file.HeuristicFunctions(normalizeGoVersion(extractMetadata.Version))
+			if err == nil {
+				return extractHeuristic(file, extractMetadata, heuristic, clock, opts), nil
+			}
+			opts.Log.Printf(1, "no heuristic functions: %v", err)
+		}
+		failure := failedReport(extractMetadata, file, attempts)
+		if likelyPacked := extractMetadata.LikelyPacked; likelyPacked != nil {
+			if len(packer) > 0 {
+				return failure, classify(ErrNoPclntab, fmt.Errorf("no valid pclntab found, the file is packed with %s. Unpack it first (ex: 'upx -d') and run GoReSym on the result", packer))
+			}
+			return failure, classify(ErrNoPclntab, fmt.Errorf("no valid pclntab found, the file looks packed: %s. Unpack it first and run GoReSym on the result", strings.Join(likelyPacked.Evidence, ", ")))
+		}
+		if truncation != nil {
+			return failure, classify(ErrNoPclntab, fmt.Errorf("no valid pclntab found, the file is truncated to 0x%x of the 0x%x bytes its headers lay out", truncation.Size, truncation.Extent))
+		}
+		return failure, classify(noPclntabClass(extractMetadata, attempts), fmt.Errorf("no valid pclntab found"))
+	}
+
+	// to be sure we got the right pclntab we had to have found a moduledat as well. If we didn't, then we failed to find the pclntab (correctly) as well
+	if moduleData == nil && !finalTab.Overlay && !finalTab.Resource && opts.Pclntab == 0 && finalTab != truncatedTab {
+		class := ErrNoModuleData
+		if !objfile.SupportedGoarch(file.GOARCH()) {
+			class = ErrUnsupportedArch
+		} else if errors.Is(moduleDataErr, objfile.ErrModuleDataInvalid) {
+			class = ErrCorruptModuleData
+		}
+		return failedReport(extractMetadata, file, attempts), classify(class, fmt.Errorf("no valid moduledata found"))
+	}
+
+	extractMetadata.Compiler = compilerGc
+
+	// the moduledata that validated corroborates the version it was parsed as
+	detectionSources := tabVersionSources(versionSources, finalTab)
+	if moduleData != nil {
+		if source, ok := layoutVersion(versionSourceModuleData, moduleDataLayoutVersions, moduleData.Layout()); ok {
+			detectionSources = append(detectionSources, source)
+		}
+	}
+	extractMetadata.VersionDetection = decideVersion(detectionSources)
+	if len(opts.Version) == 0 && len(extractMetadata.VersionDetection.Consensus) > 0 {
+		extractMetadata.Version = extractMetadata.VersionDetection.Consensus
+	}
+
+	timings.PclntabScan = milliseconds(clock.lap() - moduleDataTime)
+	timings.ModuleData = milliseconds(moduleDataTime)
+	opts.Log.Printf(1, "pclntab: %s layout at 0x%x, %d functions, Go %s with %s confidence, in %.3fms and %.3fms for the moduledata", extractMetadata.TabMeta.Version, extractMetadata.TabMeta.VA,
+		len(finalTab.ParsedPclntab.Funcs), extractMetadata.Version, extractMetadata.VersionDetection.Confidence, timings.PclntabScan, timings.ModuleData)
+
+	// the scan stops at the first working candidate, so on success this only covers the sections scanned until then
+	extractMetadata.Diagnostics = file.Diagnostics()
+	if moduleData != nil {
+		extractMetadata.ModuleMeta = *moduleData
+	}
+	// an overlay or a given pclntab has no moduledata, so no types
+	if moduleData == nil && finalTab == truncatedTab {
+		extractMetadata.Unavailable = append(extractMetadata.Unavailable, "moduledata: none validated, it's likely past the end of the truncated file")
+	}
+	if moduleData == nil {
+		extractMetadata.Unavailable = append(extractMetadata.Unavailable, unavailableWithoutModuleData(opts)...)
+	} else if truncation != nil {
+		extractMetadata.Unavailable = append(extractMetadata.Unavailable, unavailablePastEOF(file, moduleData, extractMetadata.TabMeta.PointerSize)...)
+	}
+	extractMetadata.Corruption = finalTab.ParsedPclntab.Go12line.Corruption
+	for _, corruption := range extractMetadata.Corruption {
+		opts.Log.Printf(2, "pclntab corruption at functab entry %d, 0x%x: %s", corruption.Index, corruption.Entry, corruption.Reason)
+	}
+	if moduleData != nil && opts.Types && opts.TypeAddress == 0 {
+		// the types walked before a cancellation are kept
+		types, err := file.ParseTypeLinks(extractMetadata.Version, moduleData, extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian")
+		if err == nil || canceled(ctx) != nil {
+			extractMetadata.Types = types
+		}
+
+		// a moduledata with its typelinks or types base damaged gives nothing, the rtypes are scanned for and the itabs use the base that validated
+		typesModule := moduleData
+		if len(extractMetadata.Types) == 0 && canceled(ctx) == nil {
+			if scanned, typesBase, err := file.ScanTypes(extractMetadata.Version, moduleData, extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian"); err == nil {
+				extractMetadata.Types = scanned
+				repaired := *moduleData
+				repaired.Types = typesBase
+				typesModule = &repaired
+			}
+		}
+
+		// the ITabLinks did not always exist, older versions it will be NULL
+		interfaces, itabs, err := file.ParseITabLinks(extractMetadata.Version, typesModule, extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian")
+		if err == nil || canceled(ctx) != nil {
+			extractMetadata.Interfaces = interfaces
+			extractMetadata.Itabs = groupItabs(itabs, finalTab.ParsedPclntab)
+		}
+	} else if moduleData != nil && opts.TypeAddress != 0 {
+		types, err := file.ParseType(extractMetadata.Version, moduleData, opts.TypeAddress, extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian")
+		if err == nil {
+			extractMetadata.Types = types
+		}
+	}
+	objfile.AttributeEmbeddedMethods(extractMetadata.Types, extractMetadata.Interfaces)
+
+	if err := canceled(ctx); err != nil {
+		return extractMetadata, err
+	}
+
+	// the types of the fingerprint are all of the typelinks, whatever -t and the type filter select
+	var typeNames []string
+	if moduleData != nil {
+		var parsed []objfile.Type
+		if opts.Types && opts.TypeAddress == 0 && !opts.TypeFilter.Active() {
+			parsed = extractMetadata.Types
+		}
+		typeNames = fingerprintTypes(file, extractMetadata.Version, moduleData, extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian", opts.TypeFilter, parsed)
+		if err := canceled(ctx); err != nil {
+			return extractMetadata, err
+		}
+	}
+	extractMetadata.MetadataFingerprint = metadataFingerprint(extractMetadata.Version, finalTab.ParsedPclntab.Funcs, typeNames)
+	// for the package list, the stream doesn't keep them
+	parsedTypes := append(append([]objfile.Type{}, extractMetadata.Types...), extractMetadata.Interfaces...)
+
+	// streamed records aren't kept, parsedTypes holds them for the package list
+	if opts.Stream != nil {
+		streamHeader(opts, fileName, extractMetadata)
+		streamTypes(opts, space, "type", extractMetadata.Types)
+		streamTypes(opts, space, "interface", extractMetadata.Interfaces)
+		extractMetadata.Types, extractMetadata.Interfaces = nil, nil
+	}
+
+	// the pclntab scan only finds the first module, the ones of plugins loaded after it are reached through its next pointer
+	if moduleData != nil {
+		is64bit, littleendian := extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian"
+		for i, module := range file.ModuleDataList(moduleData, extractMetadata.Version, extractMetadata.TabMeta.Version, is64bit, littleendian) {
+			meta := ModuleMetadata{ModuleDataVA: module.VA, TextVA: module.TextVA, ETextVA: module.ETextVA, PluginPath: module.PluginPath}
+			// the itabs resolve their methods to the functions of the module too
+			var table *gosym.Table
+			if i > 0 && (!opts.NoFunctions || opts.Types) {
+				if moduleTable, err := file.ModulePclntab(module, opts.Version, is64bit, littleendian); err == nil {
+					table = moduleTable
+				}
+			}
+			if table != nil && !opts.NoFunctions {
+				meta.UserFunctions, meta.StdFunctions = moduleFunctions(table, opts, extractMetadata.Filtered, extractMetadata.Version)
+				meta.Corruption = table.Go12line.Corruption
+			}
+			if i > 0 && opts.Types && opts.TypeAddress == 0 {
+				if types, err := file.ParseTypeLinks(extractMetadata.Version, module, is64bit, littleendian); err == nil {
+					meta.Types = types
+				}
+				if interfaces, itabs, err := file.ParseITabLinks(extractMetadata.Version, module, is64bit, littleendian); err == nil {
+					meta.Interfaces = interfaces
+					meta.Itabs = groupItabs(itabs, table)
+				}
+				objfile.AttributeEmbeddedMethods(meta.Types, meta.Interfaces)
+			}
+			extractMetadata.Modules = append(extractMetadata.Modules, meta)
+		}
+	}
+
+	if extractMetadata.Filtered != nil {
+		extractMetadata.Filtered.Types = file.FilteredTypes()
+	}
+	timings.Types = milliseconds(clock.lap())
+	opts.Log.Printf(1, "types: %d types and interfaces, %d modules, in %.3fms", len(parsedTypes), len(extractMetadata.Modules), timings.Types)
+	if err := canceled(ctx); err != nil {
+		return extractMetadata, err
+	}
+
+	if opts.FilePaths {
+		for k := range finalTab.ParsedPclntab.Files {
+			extractMetadata.Files = append(extractMetadata.Files, k)
+		}
+	}
+
+	extractMetadata.Dump = file.Dump(moduleData)
+	extractMetadata.Overlay = file.Overlay()
+	extractMetadata.SegmentOverlaps = file.SegmentOverlaps()
+	extractMetadata.RuntimeOffsets = file.RuntimeOffsets(extractMetadata.Version, extractMetadata.TabMeta.PointerSize == 8)
+	extractMetadata.RuntimeGlobals = file.RuntimeGlobals(finalTab.ParsedPclntab.Funcs, extractMetadata.Version, moduleData, extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian")
+
+	// the separate debug file is looked up next to the binary, the input of ExtractReader or ExtractImage has no path to look next to
+	if len(fileName) > 0 {
+		if link, err := file.DebugLink(fileName); err == nil {
+			extractMetadata.DebugLink = &DebugLinkMetadata{Name: link.Name, CRC: link.CRC, Path: link.Path}
+
+			// merge in whatever the pclntab doesn't already know about
+			if syms, err := link.Symbols(); err == nil {
+				knownFuncs := make(map[uint64]bool)
+				for _, elem := range finalTab.ParsedPclntab.Funcs {
+					knownFuncs[elem.Entry] = true
+				}
+
+				for _, sym := range syms {
+					if (sym.Code == 'T' || sym.Code == 't') && sym.Size > 0 && !knownFuncs[sym.Addr] {
+						extractMetadata.DebugLink.Functions = append(extractMetadata.DebugLink.Functions, FuncMetadata{
+							Start:    sym.Addr,
+							End:      sym.Addr + uint64(sym.Size),
+							FullName: sym.Name,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	// a stripped binary has no symbols, the pclntab still gives cgo away
+	syms, _ := file.Symbols()
+	extractMetadata.Cgo = recoverCgo(finalTab.ParsedPclntab, finalTab.ParsedPclntab.Funcs, syms, extractMetadata.BuildSettings)
+	if exports, err := file.Exports(); err == nil {
+		inlined := func(fn *gosym.Func) []gosym.InlinedCall {
+			calls, _ := file.InlinedCalls(finalTab.ParsedPclntab, fn, moduleData, extractMetadata.Version)
+			return calls
+		}
+		extractMetadata.Cgo.Exports = exportedEntryPoints(extractMetadata.Cgo, exports, finalTab.ParsedPclntab.Funcs, inlined)
+	}
+
+	if moduleData != nil && usesEmbed(finalTab.ParsedPclntab.Funcs) {
+		if found, err := file.FindEmbeddedFS(moduleData, extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian"); err == nil {
+			extractMetadata.EmbeddedFS = embeddedFS(found, syms)
+		}
+	}
+
+	// a bogus task ends the walk, the tasks before it are still in order
+	extractMetadata.Inits, _ = file.InitTasks(moduleData, extractMetadata.Version, finalTab.ParsedPclntab.Funcs, extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian")
+
+	if opts.Timestamps && moduleData != nil {
+		timeConstants, err := file.FindTimeConstants(moduleData, extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian")
+		if err == nil {
+			extractMetadata.TimeConstants = timeConstants
+		}
+	}
+
+	extractMetadata.Obfuscator, extractMetadata.ObfuscationEvidence = detectObfuscator(file, finalTab.ParsedPclntab, len(extractMetadata.BuildInfo.Main.Path) > 0 || len(extractMetadata.BuildInfo.Deps) > 0)
+	extractMetadata.ObfuscatorDetected = len(extractMetadata.Obfuscator) > 0
+
+	// the hashed packages that are really the standard library go with the rest of it
+	var hashedStd map[string]bool
+	if extractMetadata.ObfuscatorDetected {
+		hashedStd = recoverHashedStd(file, finalTab.ParsedPclntab)
+		if len(hashedStd) > 0 {
+			extractMetadata.ObfuscationEvidence = append(extractMetadata.ObfuscationEvidence, fmt.Sprintf("%d hashed packages identified as standard library", len(hashedStd)))
+		}
+	}
+	isStd := func(pkg string) bool {
+		return isStdPackage(pkg) || hashedStd[pkg]
+	}
+	extractMetadata.Composition = analyzeComposition(finalTab.ParsedPclntab.Funcs)
+
+	// the module list is ground truth for telling the main module apart from dependencies, trimmed paths alone are ambiguous
+	var buildInfo *debug.BuildInfo
+	if len(extractMetadata.BuildInfo.Main.Path) > 0 || len(extractMetadata.BuildInfo.Deps) > 0 {
+		buildInfo = &extractMetadata.BuildInfo
+	}
+	extractMetadata.Packages = recoverPackages(finalTab.ParsedPclntab.Funcs, parsedTypes, finalTab.ParsedPclntab.Files, buildInfo, isStd, extractMetadata.ObfuscatorDetected, opts.FuncFilter)
+	extractMetadata.pclntab = finalTab.ParsedPclntab
+
+	// the standard library assigns its own defaults, only other code counts as customizing them
+	var nonStdFuncs []gosym.Func
+	for _, elem := range finalTab.ParsedPclntab.Funcs {
+		if !isStd(elem.PackageName()) {
+			nonStdFuncs = append(nonStdFuncs, elem)
+		}
+	}
+	if moduleData != nil {
+		stdGlobals, err := file.FindStdGlobals(nonStdFuncs, extractMetadata.Version, moduleData, extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian")
+		if err == nil {
+			extractMetadata.StdGlobals = stdGlobals
+		}
+	}
+
+	if opts.DetectHooks {
+		hooks, err := detectHooks(ctx, file, finalTab.ParsedPclntab, extractMetadata.Modules, opts)
+		if err != nil {
+			return extractMetadata, err
+		}
+		extractMetadata.Hooks = hooks
+	}
+
+	timings.Analysis = milliseconds(clock.lap())
+	opts.Log.Printf(1, "analysis in %.3fms", timings.Analysis)
+	if err := canceled(ctx); err != nil {
+		return extractMetadata, err
+	}
+
+	if !opts.NoFunctions {
+		// only look for context usage in the functions we print, the standard library uses these internally all over
+		var scannedFuncs []gosym.Func
+		for _, elem := range finalTab.ParsedPclntab.Funcs {
+			if opts.StdFunctions || !isStd(elem.PackageName()) {
+				scannedFuncs = append(scannedFuncs, elem)
+			}
+		}
+
+		// the three passes each disassemble every function scanned
+		if opts.CallSites {
+			callSites, err := file.FindCallSites(scannedFuncs, finalTab.ParsedPclntab.Funcs, contextConstructors)
+			if err == nil {
+				extractMetadata.ContextCallSites = callSites
+			}
+
+			fieldAccesses, err := file.FindStringArgCallSites(scannedFuncs, finalTab.ParsedPclntab.Funcs, fieldByNameFuncs)
+			if err == nil {
+				extractMetadata.ReflectFieldAccesses = fieldAccesses
+			}
+
+			expvarNames, err := file.FindStringArgCallSites(scannedFuncs, finalTab.ParsedPclntab.Funcs, expvarFuncs)
+			if err == nil {
+				extractMetadata.ExpvarNames = expvarNames
+			}
+		}
+
+		literals := &objfile.StringLiterals{}
+		// without a moduledata only the string headers of the initialized data are missed
+		if opts.Strings {
+			found, err := file.FindStringLiterals(scannedFuncs, moduleData, extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian")
+			if err == nil {
+				literals = found
+				extractMetadata.UnattributedStrings = found.Unattributed
+			}
+		}
+
+		hashes := make(map[uint64]objfile.FunctionHash)
+		if opts.Hashes {
+			hashes = file.HashFunctions(scannedFuncs, opts.HashMinSize)
+			extractMetadata.FunctionHashes = hashSet(hashes)
+		}
+
+		names := make(map[string]bool)
+		var instantiations []GenericInstantiation
+		for i, elem := range finalTab.ParsedPclntab.Funcs {
+			// the functions listed so far are kept
+			if err := canceled(ctx); err != nil {
+				return extractMetadata, err
+			}
+			// a filtered function isn't looked up at all
+			if (isStd(elem.PackageName()) && !opts.StdFunctions) || !extractMetadata.Filtered.keepFunction(elem.Name) {
+				continue
+			}
+			sourceFile, sourceLine, _ := finalTab.ParsedPclntab.PCToLine(elem.Entry)
+			_, startLine, endLine := finalTab.ParsedPclntab.LineRange(&finalTab.ParsedPclntab.Funcs[i])
+			frameSize, spDeltas := frameSizes(finalTab.ParsedPclntab, &finalTab.ParsedPclntab.Funcs[i], opts.SPDeltas)
+			end, size := funcExtent(finalTab.ParsedPclntab, &finalTab.ParsedPclntab.Funcs[i])
+			argsSize, funcID, deferReturn := funcFields(finalTab.ParsedPclntab, &finalTab.ParsedPclntab.Funcs[i], extractMetadata.Version)
+			var inlined []gosym.InlinedCall
+			if opts.Inlined {
+				inlined, _ = file.InlinedCalls(finalTab.ParsedPclntab, &finalTab.ParsedPclntab.Funcs[i], moduleData, extractMetadata.Version)
+				names[elem.Name] = true
+				for _, call := range inlined {
+					names[call.Name] = true
+				}
+			}
+			var pcdata *objfile.FunctionPCData
+			if opts.PCData {
+				pcdata, _ = file.PCData(finalTab.ParsedPclntab, &finalTab.ParsedPclntab.Funcs[i], moduleData, extractMetadata.Version)
+			}
+			var hash *objfile.FunctionHash
+			if found, ok := hashes[elem.Entry]; ok {
+				hash = &found
+			}
+			origin, module := classifySource(sourceFile, elem.PackageName(), buildInfo)
+			genericName, typeArgs, shape := objfile.GenericFunctionName(elem.Name)
+			if len(genericName) > 0 {
+				instantiations = append(instantiations, GenericInstantiation{FullName: elem.Name, Start: elem.Entry, TypeArgs: typeArgs, Shape: shape})
+			}
+
+			if isStd(elem.PackageName()) {
+				if opts.StdFunctions {
+					fn := FuncMetadata{
+						Start:        elem.Entry,
+						End:          end,
+						Size:         size,
+						PackageName:  elem.PackageName(),
+						FullName:     elem.Name,
+						GenericName:  genericName,
+						TypeArgs:     typeArgs,
+						Shape:        shape,
+						Obfuscated:   hashedStd[elem.PackageName()],
+						SourceFile:   sourceFile,
+						SourceLine:   sourceLine,
+						StartLine:    startLine,
+						EndLine:      endLine,
+						Origin:       OriginStd,
+						Unmapped:     !file.Mapped(elem.Entry),
+						Overlay:      finalTab.Overlay,
+						MaxFrameSize: frameSize,
+						ArgsSize:     argsSize,
+						FuncID:       funcID,
+						DeferReturn:  deferReturn,
+						Inlined:      inlined,
+						SPDeltas:     spDeltas,
+						PCData:       pcdata,
+						Strings:      literals.Functions[elem.Entry],
+						Hash:         hash,
+					}
+					fn.Kind = functionKind(&fn, true, func() bool { return file.TailJumpStub(fn.Start, fn.End) })
+					extractMetadata.countKind(fn.Kind)
+					extractMetadata.StdFunctions = appendFunction(opts, space, extractMetadata.StdFunctions, fn)
+				}
+			} else {
+				fn := FuncMetadata{
+					Start:        elem.Entry,
+					End:          end,
+					Size:         size,
+					PackageName:  elem.PackageName(),
+					FullName:     elem.Name,
+					GenericName:  genericName,
+					TypeArgs:     typeArgs,
+					Shape:        shape,
+					Obfuscated:   extractMetadata.ObfuscatorDetected && looksHashedPackage(elem.PackageName()),
+					SourceFile:   sourceFile,
+					SourceLine:   sourceLine,
+					StartLine:    startLine,
+					EndLine:      endLine,
+					Origin:       origin,
+					Module:       module,
+					Unmapped:     !file.Mapped(elem.Entry),
+					Overlay:      finalTab.Overlay,
+					MaxFrameSize: frameSize,
+					ArgsSize:     argsSize,
+					FuncID:       funcID,
+					DeferReturn:  deferReturn,
+					Inlined:      inlined,
+					SPDeltas:     spDeltas,
+					PCData:       pcdata,
+					Strings:      literals.Functions[elem.Entry],
+					Hash:         hash,
+				}
+				fn.Kind = functionKind(&fn, false, func() bool { return file.TailJumpStub(fn.Start, fn.End) })
+				extractMetadata.countKind(fn.Kind)
+				extractMetadata.UserFunctions = appendFunction(opts, space, extractMetadata.UserFunctions, fn)
+			}
+		}
+		extractMetadata.Generics = groupGenerics(instantiations, syms)
+		for name := range names {
+			extractMetadata.AllFunctionNames = append(extractMetadata.AllFunctionNames, name)
+		}
+		sort.Strings(extractMetadata.AllFunctionNames)
+	}
+
+	timings.Functions = milliseconds(clock.lap())
+	timings.Total = milliseconds(clock.total())
+	opts.Log.Printf(1, "functions: %d user and %d standard library kept, in %.3fms", len(extractMetadata.UserFunctions), len(extractMetadata.StdFunctions), timings.Functions)
+	opts.Log.Printf(1, "total %.3fms", timings.Total)
+	return extractMetadata, nil
+}
+
+// extractTinyGo finishes the metadata of a TinyGo binary from its symbol table, what the headers and build info gave is kept.
+// The functions are all there is, without the symbol table only the detection is reported.
+func extractTinyGo(file *objfile.File, fileName string, extractMetadata Report, tinygo *objfile.TinyGoInfo, clock *phaseClock, opts Options) (Report, error) {
+	extractMetadata.Compiler = compilerTinyGo
+	extractMetadata.TinyGo = tinygo
+	extractMetadata.Version = ""
+	extractMetadata.VersionDetection = VersionDetection{}
+	if len(extractMetadata.Arch) == 0 {
+		extractMetadata.Arch = file.GOARCH()
+	}
+
+	syms, err := file.TinyGoFunctions()
+	if err != nil {
+		return extractMetadata, classify(ErrIO, fmt.Errorf("built by TinyGo, but the functions don't read: %w", err))
+	}
+	var funcs []gosym.Func
+	for _, sym := range syms {
+		fn := gosym.Func{Entry: sym.Addr, End: sym.Addr + uint64(sym.Size), Sym: &gosym.Sym{Name: sym.Name, Value: sym.Addr}}
+		// C functions of the bundled libc and compiler-rt have no package
+		if len(fn.PackageName()) > 0 {
+			funcs = append(funcs, fn)
+		}
+	}
+	extractMetadata.Composition = analyzeComposition(funcs)
+	extractMetadata.Packages = recoverPackages(funcs, nil, nil, nil, isStdPackage, false, opts.FuncFilter)
+
+	extractMetadata.MetadataFingerprint = metadataFingerprint(tinygo.Version, funcs, nil)
+	if opts.Stream != nil {
+		streamHeader(opts, fileName, extractMetadata)
+	}
+
+	if !opts.NoFunctions {
+		space := newAddressSpace(file)
+		for _, elem := range funcs {
+			origin, module := classifySource("", elem.PackageName(), nil)
+			isStd := isStdPackage(elem.PackageName())
+			for _, pkg := range tinygoStdPackages {
+				isStd = isStd || hasPathPrefix(elem.PackageName(), pkg)
+			}
+			if isStd {
+				origin, module = OriginStd, ""
+			}
+			if (isStd && !opts.StdFunctions) || !extractMetadata.Filtered.keepFunction(elem.Name) {
+				continue
+			}
+			fn := FuncMetadata{
+				Start:       elem.Entry,
+				End:         elem.End,
+				Size:        elem.End - elem.Entry,
+				PackageName: elem.PackageName(),
+				FullName:    elem.Name,
+				Origin:      origin,
+				Module:      module,
+			}
+			fn.Kind = functionKind(&fn, isStd, nil)
+			extractMetadata.countKind(fn.Kind)
+			if !isStd {
+				extractMetadata.UserFunctions = appendFunction(opts, space, extractMetadata.UserFunctions, fn)
+			} else if opts.StdFunctions {
+				extractMetadata.StdFunctions = appendFunction(opts, space, extractMetadata.StdFunctions, fn)
+			}
+		}
+	}
+
+	extractMetadata.Timings.Total = milliseconds(clock.total())
+	opts.Log.Printf(1, "tinygo: %d user and %d standard library functions, total %.3fms", len(extractMetadata.UserFunctions), len(extractMetadata.StdFunctions), extractMetadata.Timings.Total)
+	return extractMetadata, nil
+}
+
+// extractHeuristic is the metadata of a binary without a pclntab whose functions HeuristicFunctions found, what the headers and
+// build info gave is kept
+func extractHeuristic(file *objfile.File, extractMetadata Report, heuristic *objfile.HeuristicRecovery, clock *phaseClock, opts Options) Report {
+	if len(extractMetadata.Arch) == 0 {
+		extractMetadata.Arch = file.GOARCH()
+	}
+	if consensus := extractMetadata.VersionDetection.Consensus; len(consensus) > 0 && len(opts.Version) == 0 {
+		extractMetadata.Version = consensus
+	}
+	extractMetadata.Heuristic = heuristic
+	extractMetadata.Unavailable = append(extractMetadata.Unavailable, "pclntab: none found, the functions in Heuristic are guessed from the calls to runtime.morestack, without source files, lines or packages")
+	extractMetadata.Diagnostics = file.Diagnostics()
+	extractMetadata.Timings.Total = milliseconds(clock.total())
+	opts.Log.Printf(1, "heuristic: %d functions from the stack checks, total %.3fms", len(heuristic.Functions), extractMetadata.Timings.Total)
+	return extractMetadata
+}
+
+// moduleFunctions lists the functions of the pclntab of a module after the first, split like the top level ones
+func moduleFunctions(table *gosym.Table, opts Options, filtered *FilterCounts, version string) (user []FuncMetadata, std []FuncMetadata) {
+	for i, elem := range table.Funcs {
+		if (isStdPackage(elem.PackageName()) && !opts.StdFunctions) || !filtered.keepFunction(elem.Name) {
+			continue
+		}
+		sourceFile, sourceLine, _ := table.PCToLine(elem.Entry)
+		_, startLine, endLine := table.LineRange(&table.Funcs[i])
+		frameSize, spDeltas := frameSizes(table, &table.Funcs[i], opts.SPDeltas)
+		end, size := funcExtent(table, &table.Funcs[i])
+		argsSize, funcID, deferReturn := funcFields(table, &table.Funcs[i], version)
+		origin, module := classifySource(sourceFile, elem.PackageName(), nil)
+		genericName, typeArgs, shape := objfile.GenericFunctionName(elem.Name)
+		fn := FuncMetadata{
+			Start:        elem.Entry,
+			End:          end,
+			Size:         size,
+			PackageName:  elem.PackageName(),
+			FullName:     elem.Name,
+			GenericName:  genericName,
+			TypeArgs:     typeArgs,
+			Shape:        shape,
+			SourceFile:   sourceFile,
+			SourceLine:   sourceLine,
+			StartLine:    startLine,
+			EndLine:      endLine,
+			Origin:       origin,
+			Module:       module,
+			MaxFrameSize: frameSize,
+			ArgsSize:     argsSize,
+			FuncID:       funcID,
+			DeferReturn:  deferReturn,
+			SPDeltas:     spDeltas,
+		}
+		fn.Kind = functionKind(&fn, isStdPackage(elem.PackageName()), nil)
+		if !isStdPackage(elem.PackageName()) {
+			user = append(user, fn)
+		} else if opts.StdFunctions {
+			std = append(std, fn)
+		}
+	}
+	return user, std
+}
+
+// funcExtent is where the code of fn ends and its size, without the padding after it, see gosym.Table.CodeEnd. Without a table it
+// ends at fn.End.
+func funcExtent(table *gosym.Table, fn *gosym.Func) (uint64, uint64) {
+	end := fn.End
+	if table != nil {
+		end = table.CodeEnd(fn)
+	}
+	if end < fn.Entry {
+		return end, 0
+	}
+	return end, end - fn.Entry
+}
+
+// frameSizes reads the pcsp table of fn for its frame size, and its sp deltas with withDeltas
+func frameSizes(table *gosym.Table, fn *gosym.Func, withDeltas bool) (int, []gosym.SPRow) {
+	rows := table.SPRows(fn)
+	size := 0
+	for _, row := range rows {
+		size = max(size, row.SPDelta)
+	}
+	if !withDeltas {
+		rows = nil
+	}
+	return size, rows
+}
+
+// hashSet is the sorted distinct hashes of the functions, the position independent one when there's one
+func hashSet(hashes map[uint64]objfile.FunctionHash) []string {
+	seen := make(map[string]bool)
+	var set []string
+	for _, hash := range hashes {
+		value := hash.PositionIndependent
+		if len(value) == 0 {
+			value = hash.SHA256
+		}
+		if !seen[value] {
+			seen[value] = true
+			set = append(set, value)
+		}
+	}
+	sort.Strings(set)
+	return set
+}
+
+// unavailableWithoutModuleData is what opts asks for that needs the moduledata, for a pclntab no moduledata was found for
+func unavailableWithoutModuleData(opts Options) []string {
+	var unavailable []string
+	if opts.Types || opts.TypeAddress != 0 {
+		unavailable = append(unavailable, "types and interfaces: no moduledata, the typelinks and itablinks are found through it")
+	}
+	if opts.Timestamps {
+		unavailable = append(unavailable, "timestamps: no moduledata, the initialized data is found through it")
+	}
+	if opts.Strings {
+		unavailable = append(unavailable, "unattributed strings: no moduledata, the initialized data is found through it")
+	}
+	return unavailable
+}
+
+// unavailablePastEOF lists the regions of the moduledata of a truncated file the file ends before, what's read from them is lost
+func unavailablePastEOF(file *objfile.File, moduleData *objfile.ModuleData, ptrSize uint32) []string {
+	regions := []struct {
+		name      string
+		VA, size  uint64
+		component string
+	}{
+		{"types", moduleData.Types, moduleData.ETypes - moduleData.Types, "the types and interfaces"},
+		{"typelinks", uint64(moduleData.Typelinks.Data), moduleData.Typelinks.Len * 4, "the types"},
+		{"itablinks", uint64(moduleData.ITablinks.Data), moduleData.ITablinks.Len * uint64(ptrSize), "the interfaces and itabs"},
+		{"noptrdata", moduleData.Noptrdata, moduleData.Enoptrdata - moduleData.Noptrdata, "the timestamps and strings"},
+		{"data", moduleData.Data, moduleData.Edata - moduleData.Data, "the timestamps and strings"},
+	}
+
+	var unavailable []string
+	for _, region := range regions {
+		if region.VA != 0 && region.size > 0 && file.PastEOF(region.VA, region.size) {
+			unavailable = append(unavailable, fmt.Sprintf("%s region extends past EOF: some of %s are lost", region.name, region.component))
+		}
+	}
+	return unavailable
+}
+
+// failedReport is the Report of a failed extraction, what was gathered before it failed: the headers, the version guess and the
+// pclntab candidates tried
+func failedReport(metadata Report, file *objfile.File, attempts []CandidateAttempt) Report {
+	return Report{
+		Version:          metadata.Version,
+		VersionDetection: metadata.VersionDetection,
+		VersionStrings:   metadata.VersionStrings,
+		Arch:             metadata.Arch,
+		OS:               metadata.OS,
+		BuildId:          metadata.BuildId,
+		BuildMode:        metadata.BuildMode,
+		Packer:           metadata.Packer,
+		LikelyPacked:     metadata.LikelyPacked,
+		Truncated:        metadata.Truncated,
+		Attempts:         attempts,
+		Diagnostics:      file.Diagnostics(),
+	}
+}
+
+// noPclntabClass tells a file with no trace of Go, no pclntab candidate and no version, from a Go binary whose pclntab is gone
+func noPclntabClass(metadata Report, attempts []CandidateAttempt) error {
+	if len(attempts) == 0 && len(metadata.Version) == 0 {
+		return ErrNotGo
+	}
+	return ErrNoPclntab
+}
+
+// foundIn is where the scan found a candidate for the logs, ex: ', found in .xyz', empty when it didn't tell
+func foundIn(tab *objfile.PclntabCandidate) string {
+	if len(tab.Location) == 0 {
+		return ""
+	}
+	return ", found in " + tab.Location
+}
+
+func candidateAttempt(tab *objfile.PclntabCandidate, reason string) CandidateAttempt {
+	return CandidateAttempt{
+		PclntabVA: tab.PclntabVA,
+		SectionVA: tab.SecStart,
+		Layout:    tab.ParsedPclntab.Go12line.Version.String(),
+		Functions: len(tab.ParsedPclntab.Funcs),
+		Location:  tab.Location,
+		Rejected:  reason,
+	}
+}
+
+// tabMetadata describes a parsed pclntab candidate
+func tabMetadata(tab *objfile.PclntabCandidate) PcLnTabMetadata {
+	var meta PcLnTabMetadata
+	meta.CpuQuantum = tab.ParsedPclntab.Go12line.Quantum
+
+	// quantum is the minimal unit for a program counter (1 on x86, 4 on most other systems).
+	// 386: 1, amd64: 1, arm: 4, arm64: 4, mips: 4, mips/64/64le/64be: 4, ppc64/64le: 4, riscv64: 4, s390x: 2, wasm: 1
+	meta.CpuQuantumStr = "x86/x64/wasm"
+	if meta.CpuQuantum == 2 {
+		meta.CpuQuantumStr = "s390x"
+	} else if meta.CpuQuantum == 4 {
+		meta.CpuQuantumStr = "arm/mips/ppc/riscv"
+	}
+
+	meta.VA = tab.PclntabVA
+	meta.Version = tab.ParsedPclntab.Go12line.Version.String()
+	meta.Endianess = tab.ParsedPclntab.Go12line.Binary.String()
+	meta.PointerSize = tab.ParsedPclntab.Go12line.Ptrsize
+	meta.DeclaredFuncCount = tab.ParsedPclntab.Go12line.DeclaredFuncs
+	meta.RecoveredFuncCount = uint32(len(tab.ParsedPclntab.Funcs))
+	meta.ReconstructedMagic = tab.ReconstructedMagic
+	meta.Overlay = tab.Overlay
+	if tab.Overlay {
+		offset := tab.FileOffset
+		meta.FileOffset = &offset
+	}
+	meta.Location = tab.Location
+	return meta
+}
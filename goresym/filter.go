@@ -0,0 +1,29 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import "github.com/mandiant/GoReSym/objfile"
+
+// FilterCounts is how many entries the filters dropped, reported whenever a filter is set so a missing name is explained
+type FilterCounts struct {
+	Functions int
+	Types     int // distinct types, of the interface tables too
+
+	funcFilter *objfile.NameFilter
+}
+
+// newFilterCounts starts the counts of one extraction, nil when no filter is set
+func newFilterCounts(opts Options) *FilterCounts {
+	if !opts.FuncFilter.Active() && !opts.TypeFilter.Active() {
+		return nil
+	}
+	return &FilterCounts{funcFilter: opts.FuncFilter}
+}
+
+// keepFunction reports whether the function name passes the function filter, counting it when it doesn't
+func (c *FilterCounts) keepFunction(name string) bool {
+	if c == nil || c.funcFilter.Keep(name) {
+		return true
+	}
+	c.Functions++
+	return false
+}
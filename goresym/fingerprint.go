@@ -0,0 +1,80 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// fingerprintTypes is the names of the types of the typelinks of moduleData, or of the types scanned for when they give none, the
+// same walk as Options.Types without the type filter. parsed is the result of that walk when it already ran unfiltered.
+func fingerprintTypes(file *objfile.File, version string, moduleData *objfile.ModuleData, is64bit bool, littleendian bool, filter *objfile.NameFilter, parsed []objfile.Type) []string {
+	if parsed == nil {
+		file.SetTypeFilter(nil)
+		defer file.SetTypeFilter(filter)
+		parsed, _ = file.ParseTypeLinks(version, moduleData, is64bit, littleendian)
+		if len(parsed) == 0 {
+			parsed, _, _ = file.ScanTypes(version, moduleData, is64bit, littleendian)
+		}
+	}
+	names := make([]string, 0, len(parsed))
+	for _, typ := range parsed {
+		names = append(names, typ.Str)
+	}
+	return names
+}
+
+// sortedUnique returns the non-empty values of in, de-duplicated and sorted
+func sortedUnique(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	out := make([]string, 0, len(in))
+	for _, v := range in {
+		if len(v) == 0 || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// canonicalMetadata serializes the layout-independent parts of the recovered metadata.
+// VAs, offsets, and anything else that moves when a binary is relinked are intentionally excluded,
+// so two builds of the same source that differ only in layout produce the same output.
+// Every function of the pclntab and every type of the typelinks are covered, whatever -d, -nofuncs, -t and the filters select, so
+// a binary has one fingerprint.
+func canonicalMetadata(version string, funcs []gosym.Func, typeNames []string) string {
+	var funcNames []string
+	var packages []string
+	for _, fn := range funcs {
+		funcNames = append(funcNames, fn.Name)
+		packages = append(packages, fn.PackageName())
+	}
+
+	var sb strings.Builder
+	section := func(name string, values []string) {
+		sb.WriteString("[" + name + "]\n")
+		for _, v := range values {
+			sb.WriteString(v)
+			sb.WriteString("\n")
+		}
+	}
+
+	section("version", []string{version})
+	section("packages", sortedUnique(packages))
+	section("functions", sortedUnique(funcNames))
+	section("types", sortedUnique(typeNames))
+	return sb.String()
+}
+
+// metadataFingerprint is the hex encoded SHA-256 of canonicalMetadata, useful to cluster functionally identical binaries
+func metadataFingerprint(version string, funcs []gosym.Func, typeNames []string) string {
+	sum := sha256.Sum256([]byte(canonicalMetadata(version, funcs, typeNames)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,67 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import "github.com/mandiant/GoReSym/debug/gosym"
+
+// FuncID is the funcID the linker gave a special runtime function, Name is its symbolic name when the numbering of the Go version
+// is known, ex: goexit
+type FuncID struct {
+	Value int
+	Name  string
+}
+
+// funcIDNames are the funcIDs by the first minor version numbering them so, the runtime's funcID_* constants without the prefix.
+// The versions with nil names weren't checked against a build and aren't named.
+var funcIDNames = []struct {
+	minor int
+	names []string
+}{
+	{12, nil},
+	{15, []string{"normal", "runtime_main", "goexit", "jmpdefer", "mcall", "morestack", "mstart", "rt0_go", "asmcgocall", "sigpanic",
+		"runfinq", "gcBgMarkWorker", "systemstack_switch", "systemstack", "cgocallback", "gogo", "externalthreadhandler", "debugCallV1",
+		"gopanic", "panicwrap", "handleAsyncEvent", "asyncPreempt", "wrapper"}},
+	{17, nil},
+	// sorted from here on
+	{18, []string{"normal", "abort", "asmcgocall", "asyncPreempt", "cgocallback", "debugCallV2", "gcBgMarkWorker", "goexit", "gogo",
+		"gopanic", "handleAsyncEvent", "mcall", "morestack", "mstart", "panicwrap", "rt0_go", "runfinq", "runtime_main", "sigpanic",
+		"systemstack", "systemstack_switch", "wrapper"}},
+	{22, []string{"normal", "abort", "asmcgocall", "asyncPreempt", "cgocallback", "corostart", "debugCallV2", "gcBgMarkWorker", "goexit",
+		"gogo", "gopanic", "handleAsyncEvent", "mcall", "morestack", "mstart", "panicwrap", "rt0_go", "runfinq", "runtime_main",
+		"sigpanic", "systemstack", "systemstack_switch", "wrapper"}},
+}
+
+// funcIDName names the funcID id of the Go version, empty when the numbering of the version isn't known
+func funcIDName(id uint8, version string) string {
+	minor, ok := minorVersion(version)
+	if !ok {
+		return ""
+	}
+	for i := len(funcIDNames) - 1; i >= 0; i-- {
+		if minor >= funcIDNames[i].minor {
+			if int(id) < len(funcIDNames[i].names) {
+				return funcIDNames[i].names[id]
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// funcFields reads the args size, funcID and deferreturn offset of fn, the last two are only in the tables of Go 1.12 and later
+func funcFields(table *gosym.Table, fn *gosym.Func, version string) (argsSize int, funcID *FuncID, deferReturn uint32) {
+	fields, ok := table.FuncFields(fn)
+	if !ok {
+		return 0, nil, 0
+	}
+	argsSize = int(fields.Args)
+	if fields.Args == gosym.ArgsSizeUnknown {
+		argsSize = -1
+	}
+	if minor, ok := minorVersion(version); !ok || minor < 12 {
+		return argsSize, nil, 0
+	}
+	if fields.FuncID != 0 {
+		funcID = &FuncID{Value: int(fields.FuncID), Name: funcIDName(fields.FuncID, version)}
+	}
+	return argsSize, funcID, fields.DeferReturn
+}
@@ -0,0 +1,89 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"regexp"
+	"strings"
+)
+
+// what a function is, its FuncMetadata.Kind: code of the program or of the standard library, or what the compiler and the linker
+// generated around it
+const (
+	KindUser         = "user"
+	KindStd          = "std"
+	KindWrapper      = "wrapper"       // a method value (-fm) or an ABI wrapper, ex: of a linkname alias, calling the function it wraps
+	KindThunk        = "thunk"         // the method of *T the compiler generates for one of T, or an interface method expression
+	KindGenerated    = "generated"     // the type equality and hash functions, the go:/type: symbols and the defer, go and range bodies
+	KindAssemblyStub = "assembly-stub" // written in assembly, it declares no argument size
+)
+
+// FunctionKindNames are the kinds of FuncMetadata.Kind in the order they're listed in
+var FunctionKindNames = []string{KindUser, KindStd, KindWrapper, KindThunk, KindGenerated, KindAssemblyStub}
+
+// the source file of the functions the compiler generates, wrappers and thunks
+const autogeneratedSource = "<autogenerated>"
+
+// the suffixes of the closures the compiler generates for the call of a defer or go statement, ex: main.main.deferwrap1, and the
+// body of a range over func loop, ex: main.main-range1
+var generatedClosure = regexp.MustCompile(`(\.deferwrap|\.gowrap|-range)\d+$`)
+
+// a closure, ex: main.main.func1
+var wrapperClosure = regexp.MustCompile(`\.func\d+$`)
+
+// the prefixes of the symbols the compiler and the linker generate: the type functions, type:.eq.main.T of 1.20 on and
+// type..eq.main.T before, and the likes of go:buildid
+var generatedPrefixes = []string{"type:", "type..", "go:", "go.buildid"}
+
+// isMethod tells if the function name, trimmed of its package, is a method, ex: T.M, (*T).M or (*Stack[go.shape.int]).Push
+func isMethod(name string, pkg string) bool {
+	name = strings.TrimPrefix(name, pkg+".")
+	if depth := strings.IndexByte(name, '['); depth >= 0 {
+		// the type arguments may have dots of their own
+		if end := strings.LastIndexByte(name, ']'); end > depth {
+			name = name[:depth] + name[end+1:]
+		}
+	}
+	return strings.HasPrefix(name, "(*") || strings.Contains(name, ".")
+}
+
+// functionKind classifies fn by its name and source: the suffixes and prefixes of the names the compiler generates, the
+// <autogenerated> source of the wrappers and thunks, then the assembly. A function whose name was rewritten by an obfuscator is told
+// a wrapper when the linker marked it one or, when tailJump is given, it's a frameless stub tail jumping elsewhere.
+func functionKind(fn *FuncMetadata, std bool, tailJump func() bool) string {
+	name := fn.FullName
+	for _, prefix := range generatedPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return KindGenerated
+		}
+	}
+	switch {
+	case strings.HasSuffix(name, "-fm"):
+		return KindWrapper
+	case generatedClosure.MatchString(name):
+		return KindGenerated
+	case wrapperClosure.MatchString(name) && fn.FuncID != nil && fn.FuncID.Name == "wrapper":
+		// before 1.21 the closures of defer and go statements were named like the others, the linker marks them
+		return KindGenerated
+	case fn.SourceFile == autogeneratedSource && isMethod(name, fn.PackageName):
+		return KindThunk
+	case fn.SourceFile == autogeneratedSource:
+		return KindWrapper
+	case fn.ArgsSize == -1 || strings.HasSuffix(fn.SourceFile, ".s"):
+		return KindAssemblyStub
+	case fn.Obfuscated && fn.FuncID != nil && fn.FuncID.Name == "wrapper":
+		return KindWrapper
+	case fn.Obfuscated && fn.MaxFrameSize == 0 && tailJump != nil && tailJump():
+		return KindWrapper
+	case std:
+		return KindStd
+	}
+	return KindUser
+}
+
+// countKind counts a function of kind in FunctionKinds
+func (r *Report) countKind(kind string) {
+	if r.FunctionKinds == nil {
+		r.FunctionKinds = make(map[string]int)
+	}
+	r.FunctionKinds[kind]++
+}
@@ -0,0 +1,62 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"sort"
+
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// GenericFunction is a generic function or method with every instantiation of it the binary has, Name is without type arguments,
+// ex: main.(*Stack).Push for main.(*Stack[go.shape.int]).Push and main.(*Stack[go.shape.string]).Push
+type GenericFunction struct {
+	Name           string
+	Instantiations []GenericInstantiation
+	// the dictionaries of the generic function, or of the type of a method, from the symbol table. They have the real type arguments
+	// of the shaped instantiations.
+	Dictionaries []GenericDictionary
+}
+
+// GenericInstantiation is a function instantiating a generic function
+type GenericInstantiation struct {
+	FullName string
+	Start    uint64
+	TypeArgs []string
+	Shape    bool // shared by every type argument of the same GC shape, the TypeArgs are the shapes
+}
+
+// GenericDictionary is the dictionary at VA passed to the shaped code for the type arguments
+type GenericDictionary struct {
+	Name     string
+	VA       uint64
+	TypeArgs []string
+}
+
+// groupGenerics groups the instantiations by their generic function and attaches the dictionary symbols of each, sorted by name
+func groupGenerics(instantiations []GenericInstantiation, syms []objfile.Sym) []GenericFunction {
+	byName := make(map[string][]GenericInstantiation)
+	for _, inst := range instantiations {
+		base, _, _ := objfile.GenericFunctionName(inst.FullName)
+		byName[base] = append(byName[base], inst)
+	}
+	if len(byName) == 0 {
+		return nil
+	}
+
+	dicts := make(map[string][]GenericDictionary)
+	for _, sym := range syms {
+		if owner, typeArgs, ok := objfile.GenericDictionary(sym.Name); ok {
+			dicts[owner] = append(dicts[owner], GenericDictionary{Name: sym.Name, VA: sym.Addr, TypeArgs: typeArgs})
+		}
+	}
+
+	var grouped []GenericFunction
+	for name, insts := range byName {
+		sort.Slice(insts, func(i, j int) bool { return insts[i].FullName < insts[j].FullName })
+		owned := dicts[objfile.GenericOwner(name)]
+		sort.Slice(owned, func(i, j int) bool { return owned[i].Name < owned[j].Name })
+		grouped = append(grouped, GenericFunction{Name: name, Instantiations: insts, Dictionaries: owned})
+	}
+	sort.Slice(grouped, func(i, j int) bool { return grouped[i].Name < grouped[j].Name })
+	return grouped
+}
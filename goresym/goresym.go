@@ -0,0 +1,457 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+
+// Package goresym recovers the functions, types and build metadata of a Go binary, stripped or not, from its pclntab and moduledata.
+// It's what the GoReSym command runs, Extract returns the Report the command prints as JSON. Everything an extraction is set up with
+// is in its Options, so extractions of different settings may run side by side.
+package goresym
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+	"github.com/mandiant/GoReSym/objfile"
+	"github.com/mandiant/GoReSym/runtime/debug"
+)
+
+// ErrCanceled is what Extract fails with, wrapped with the error of the context, when the context is done before the extraction
+// finishes. The Report then has what was recovered up to that point: the signature scan, the pclntab walk and the typelinks walk
+// stop early, so for instance the functions are the ones listed before the cancellation.
+var ErrCanceled = errors.New("extraction canceled")
+
+// Options selects what Extract recovers. The zero value recovers the metadata and the user functions. The flag of the command each
+// field is set by is noted with it.
+type Options struct {
+	StdFunctions bool // -d, the standard library functions too, in StdFunctions
+	FilePaths    bool // -p, the source files in Files
+	Types        bool // -t, the types of the typelinks in Types, the interfaces of the itablinks in Interfaces
+	// -methods, with Types the methods of each type with their functions in Methods and the method table of each itab, without it
+	// Itabs is empty. Each method reads its name and both its functions, which adds up on a large binary.
+	Methods bool
+	// -m, parse only the type at this VA instead of the typelinks, ex: one located by hand. Types is ignored with it.
+	TypeAddress uint64
+	// -gcdata, with Types the pointer words of each type decoded from its gcdata in GC, its bitmap, GC program or the bitmap Go 1.24
+	// on builds on demand from its fields and elements
+	GCData      bool
+	NoFunctions bool // -nofuncs, leave the functions out
+	Timestamps  bool // -timestamps, the time.Time values of the initialized data in TimeConstants
+	// -v, the runtime version to parse the structures as instead of the detected one, ex: 1.17. Parsing fails or gives nonsense if it's wrong.
+	Version  string
+	Inlined  bool // -inlined, the inline tree of each function in Inlined and every name seen in AllFunctionNames, Go 1.12 and later
+	SPDeltas bool // -pcsp, the sp deltas of each function in SPDeltas
+	PCData   bool // -pcdata, the unsafe points and the stack map counts of each function in PCData, Go 1.2 and later
+	Strings  bool // -strings, the string literals of each function in Strings, amd64 and arm64 only
+	Hashes   bool // -hash, the code hashes of each function in Hash and their set in FunctionHashes
+	// -callsites, the calls of the extracted functions setting up cancellable contexts in ContextCallSites, the field names passed to
+	// reflect's FieldByName in ReflectFieldAccesses and the names published through expvar in ExpvarNames
+	CallSites bool
+	// -hash-min-size, with Hashes the fewest bytes a function has to be hashed
+	HashMinSize uint64
+	// -detect-hooks, the functions whose entry jumps out of the Go text and the code of the known modules in Hooks, ex: in a dump of a
+	// process whose runtime an agent patched. Every function FuncFilter keeps is checked, the std ones whatever StdFunctions is.
+	DetectHooks bool
+	// -compare-file, with DetectHooks the file the image was loaded from, the entries of its functions are compared to the image's
+	CompareFile string
+	// -include-func and -exclude-func, the functions dropped aren't looked up at all and are counted in Filtered. nil keeps every one.
+	FuncFilter *objfile.NameFilter
+	// -include-type and -exclude-type, the types dropped aren't parsed and are counted in Filtered. nil keeps every one.
+	TypeFilter *objfile.NameFilter
+	// -moduledata, the VA of the moduledata to extract from instead of scanning for one, ex: one located by hand. It's validated like
+	// a scanned one, the error names the field that looks wrong when it doesn't validate. 0 scans.
+	ModuleData uint64
+	// -moduledata-offset, ModuleData as the file offset of the moduledata, of the slice for a fat Mach-O. 0 scans.
+	ModuleDataOffset uint64
+	// -pclntab, the VA of the pclntab to parse instead of scanning for one, ex: one found by its magic in a binary whose moduledata is
+	// gone. Only the functions and source lines are recovered then, no moduledata is looked for, see Report.Unavailable. 0 scans.
+	Pclntab uint64
+	// -pclntab-offset, Pclntab as the file offset of the pclntab, of the slice for a fat Mach-O. 0 scans.
+	PclntabOffset uint64
+	// -textstart, with Pclntab the text start the functions of a 1.18 or later pclntab are relative to. 0 takes the one the header records.
+	TextStart uint64
+	// -scan-range and -scan-range-offset, the pclntab and moduledata scans only look within these ranges of the sections, the
+	// moduledata and pclntab their matches lead to can be anywhere. nil scans every section whole.
+	ScanRanges []objfile.ScanRange
+	// -tolerant, a function of the pclntab that doesn't parse is kept with the placeholder name sub_<entry> or without its line info
+	// instead of losing the functions after it, and Report.Corruption lists each. Off, a clean binary gives the same either way.
+	Tolerant bool
+	// -heuristic-funcs, when no pclntab is found the functions of an amd64 binary are found from their calls to runtime.morestack
+	// instead, see Report.Heuristic. A last resort, the rest of the Report is what's recovered without the pclntab.
+	HeuristicFunctions bool
+	// -verbose and -vv, the progress of the extraction as it runs: the sections scanned, the signature matches, the pclntab and
+	// moduledata candidates tried and the time and counts of each phase. nil logs nothing.
+	Log objfile.Logger
+	// -select-image, the Go executable embedded in the input to extract from instead of the input, ex: the payload of a dropper, by its
+	// Index in Report.EmbeddedImages. 0 is the input, whose extraction leaves the pclntabs of the embedded images alone.
+	Image int
+	// -base, the address the input was loaded at, for dumps of an image the loader relocated, see objfile.OpenOptions.LoadBase. A
+	// dump starts at it. 0 reads the input at the addresses of its headers.
+	LoadBase uint64
+	// -slide, the load bias of a position independent ELF, see objfile.OpenOptions.Slide. 0 detects it from the relocated pointers.
+	Slide uint64
+	// -mode, how the input is laid out: file or empty as on disk, dump as already mapped memory starting with its mapped headers, ex:
+	// an image carved out of a memory acquisition, raw the same without reading any headers. A raw dump is at LoadBase.
+	Mode string
+	// -arch, the GOARCH of a dump, required without its headers, and the slice of a fat Mach-O to extract, the first without one
+	Arch string
+	// -scan-overlay, the pclntab scan of a PE also covers the data appended after its last section, where droppers keep their payload
+	ScanOverlay bool
+	// -scan-resources, the pclntab scan of a PE also covers each of its resources, a pclntab in one is reported by the resource path
+	ScanResources bool
+	// -sigfile, the moduledata signatures scanned after the built-in ones, see objfile.ParseSignatures. A bad one fails the extraction.
+	Signatures []objfile.CustomSignature
+	// -outputformat ndjson, called with each type, interface and function as it's recovered instead of the Report keeping them. A
+	// StreamHeader comes first, as kind header, then the types as type, the interfaces as interface and the functions as function.
+	Stream func(kind string, value interface{})
+}
+
+// openOptions are how the input of an extraction with opts is opened
+func (opts Options) openOptions() objfile.OpenOptions {
+	dump := opts.Mode == "dump" || opts.Mode == "raw"
+	return objfile.OpenOptions{
+		LoadBase:      opts.LoadBase,
+		Slide:         opts.Slide,
+		Dump:          dump,
+		DumpHeaders:   opts.Mode == "dump",
+		DumpGoarch:    opts.Arch,
+		FatArch:       opts.Arch,
+		ScanOverlay:   opts.ScanOverlay,
+		ScanResources: opts.ScanResources,
+	}
+}
+
+// StreamHeader identifies the binary the values streamed after it belong to, one per slice of a fat Mach-O
+type StreamHeader struct {
+	SchemaVersion int
+	File          string
+	Arch          string
+	OS            string
+	Version       string
+	Compiler      string
+	BuildId       string
+	BuildMode     string
+}
+
+// Extract recovers the Report of the executable at path. It's memory mapped, or read in whole when it can't be, ex: a pipe, and
+// stays open until the Report is closed. The Report is never nil, on failure it explains the failure, see Report. Extractions may
+// run concurrently.
+func Extract(ctx context.Context, path string, opts Options) (report *Report, err error) {
+	report = &Report{SchemaVersion: SchemaVersion}
+	defer recoverPanic(report, &err)
+	clock := newPhaseClock()
+	file, err := objfile.OpenWith(path, opts.openOptions())
+	if err != nil {
+		return report, classifyOpen(fmt.Errorf("invalid file: %w", err))
+	}
+	if file, err = selectImage(file, opts.Image); err != nil {
+		return report, err
+	}
+	// a panic in the middle of the extraction closes the file too
+	report.file = file
+	extracted, err := extract(ctx, file, path, nil, clock, opts)
+	extracted.file = file
+	*report = extracted
+	finishReport(report)
+	return report, err
+}
+
+// ExtractReader is Extract of an executable laid out as on disk and read through r, ex: a sample held in memory or a member of an
+// archive. The debug file of a .gnu_debuglink is only looked up for a path.
+func ExtractReader(ctx context.Context, r io.ReaderAt, size int64, opts Options) (report *Report, err error) {
+	report = &Report{SchemaVersion: SchemaVersion}
+	defer recoverPanic(report, &err)
+	clock := newPhaseClock()
+	file, err := objfile.OpenReaderWith(r, size, opts.openOptions())
+	if err != nil {
+		return report, classifyOpen(fmt.Errorf("invalid file: %w", err))
+	}
+	if file, err = selectImage(file, opts.Image); err != nil {
+		return report, err
+	}
+	// a panic in the middle of the extraction closes the file too
+	report.file = file
+	extracted, err := extract(ctx, file, "", nil, clock, opts)
+	extracted.file = file
+	*report = extracted
+	finishReport(report)
+	return report, err
+}
+
+// ExtractImage is Extract of an image already unpacked into memory at imageBase and read through r, for pipelines that unpack or
+// emulate samples themselves. goarch is only needed when the headers of the image are gone.
+func ExtractImage(ctx context.Context, r io.ReaderAt, imageBase uint64, goarch string, opts Options) (report *Report, err error) {
+	report = &Report{SchemaVersion: SchemaVersion}
+	defer recoverPanic(report, &err)
+	clock := newPhaseClock()
+	file, err := objfile.OpenImage(r, imageBase, goarch)
+	if err != nil {
+		return report, classifyOpen(fmt.Errorf("invalid image: %w", err))
+	}
+	if file, err = selectImage(file, opts.Image); err != nil {
+		return report, err
+	}
+	// a panic in the middle of the extraction closes the file too
+	report.file = file
+	extracted, err := extract(ctx, file, "", r, clock, opts)
+	extracted.file = file
+	*report = extracted
+	finishReport(report)
+	return report, err
+}
+
+// Close releases the file Extract opened, Pclntab reads from it so it's nil afterwards. What else the Report holds stays valid.
+func (r *Report) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file, r.pclntab = nil, nil
+	return err
+}
+
+// Pclntab is the parsed pclntab the functions were recovered from, for their line tables. nil on failure and for TinyGo.
+func (r *Report) Pclntab() *gosym.Table {
+	return r.pclntab
+}
+
+// RawStructures reads the raw bytes of the moduledata, the pclntab, the typelinks and the build info blob the extraction located, ex:
+// for a bug report on a version whose structures it misreads. It reads from the file, a closed Report and a failed or TinyGo
+// extraction have nothing to read.
+func (r *Report) RawStructures() ([]objfile.RawStructure, error) {
+	if r.file == nil || r.pclntab == nil || r.pclntab.Go12line == nil {
+		return nil, fmt.Errorf("no pclntab to read the structures through")
+	}
+	return r.file.RawStructures(&r.ModuleMeta, r.TabMeta.VA, uint64(len(r.pclntab.Go12line.Data)), r.Version, r.TabMeta.PointerSize == 8, r.TabMeta.Endianess == "LittleEndian"), nil
+}
+
+// pclntab header info
+type PcLnTabMetadata struct {
+	VA            uint64
+	RVA           uint64
+	Version       string
+	Endianess     string
+	CpuQuantum    uint32
+	CpuQuantumStr string
+	PointerSize   uint32
+	// the header's function count can be tampered with, these differ when the functab had to be walked instead
+	DeclaredFuncCount  uint32
+	RecoveredFuncCount uint32
+	// the header's magic was stomped, ex: by garble, and was reconstructed to parse the table
+	ReconstructedMagic bool
+	// found in the PE overlay with -scan-overlay, it has no VA so FileOffset alone locates it
+	Overlay    bool
+	FileOffset *uint64
+	// where the scan found it: the name of its section, ex: a renamed .xyz, overlay, or resource and the path of the PE resource of
+	// -scan-resources, ex: resource RCDATA/101/1033. No moduledata points at the pclntab of a resource either.
+	Location string
+}
+
+// a pclntab candidate a failed extraction tried, Rejected is the reason it was rejected
+type CandidateAttempt struct {
+	PclntabVA uint64
+	SectionVA uint64 // the text base its function entries were taken relative to
+	Layout    string
+	Functions int
+	Location  string // the section, overlay or resource it was found in, see PcLnTabMetadata
+	Rejected  string
+}
+
+type FuncMetadata struct {
+	Start       uint64
+	End         uint64  // where the code ends, the padding before the next function isn't counted
+	Size        uint64  // End - Start, 0 for the labels sharing their entry with the next function
+	RVA         uint64  // Start relative to Report.RVABase
+	FileOffset  *uint64 // of Start in the file, null when the code isn't in it, ex: in a dump
+	PackageName string
+	FullName    string
+	GenericName string   // for generic instantiations, FullName without the type argument lists
+	TypeArgs    []string // for generic instantiations, the type arguments with GC shapes collapsed
+	Shape       bool     // generic code shared by every type argument of the same GC shape
+	Obfuscated  bool     // name was rewritten by the detected obfuscator
+	SourceFile  string   // file of the function entry, as recorded in the pclntab
+	SourceLine  int      // line of the function entry in SourceFile
+	StartLine   int      // first line of the function's own code in SourceFile, inlined code doesn't count
+	EndLine     int      // last line of the function's own code in SourceFile
+	Origin      string   // std, main, or dependency
+	Module      string   // module path for main and dependency functions, when known
+	Kind        string   // user, std, wrapper, thunk, generated or assembly-stub, see KindUser
+	Unmapped    bool     // entry is outside the dump, there's no code for it
+	Overlay     bool     // from a pclntab in the PE overlay, rather than a mapped section
+	// the size of the stack frame, the largest sp delta of the pcsp table, 0 for the functions without a frame
+	MaxFrameSize int
+	ArgsSize     int     // size of the arguments and results in bytes, -1 when not declared, ex: assembly
+	FuncID       *FuncID // special runtime function the linker marked, from Go 1.12 on
+	DeferReturn  uint32  // offset from Start of the call to runtime.deferreturn, from Go 1.12 on
+	// the functions inlined into this one from its inline tree, only with -inlined
+	Inlined []gosym.InlinedCall
+	// the sp delta wherever it changes from the pcsp table, only with -pcsp
+	SPDeltas []gosym.SPRow
+	// the unsafe points from the pcdata and the number of stack maps, only with -pcdata
+	PCData *objfile.FunctionPCData
+	// the string literals the code references, only with -strings
+	Strings []objfile.StringLiteral
+	// the hashes of the code, only with -hash
+	Hash *objfile.FunctionHash
+}
+
+// a module of the moduledata list, ex: a plugin the process loaded. The functions and types are only listed for the modules after the first.
+type ModuleMetadata struct {
+	ModuleDataVA  uint64
+	ModuleDataRVA uint64
+	// null when the moduledata isn't in the file, ex: in the bss of a dump
+	ModuleDataFileOffset *uint64
+	TextVA               uint64
+	ETextVA              uint64
+	PluginPath           string
+	UserFunctions        []FuncMetadata
+	StdFunctions         []FuncMetadata
+	Types                []objfile.Type
+	Interfaces           []objfile.Type
+	Itabs                []InterfaceItabs
+	// what the tolerant parse of the module's pclntab worked around, with Options.Tolerant
+	Corruption []gosym.Corruption
+}
+
+// companion debug file named by .gnu_debuglink
+type DebugLinkMetadata struct {
+	Name string
+	CRC  uint32
+	Path string // empty if the companion was not found
+	// text symbols from the companion that the pclntab doesn't cover, ex: C and assembly functions
+	Functions []FuncMetadata
+}
+
+// the version control stamp of the main module, from the vcs build settings of builds in a checkout
+type VCSInfo struct {
+	System   string // git, hg, svn, fossil or bzr
+	Revision string
+	Time     string // of the revision, RFC3339
+	Modified bool   // the checkout had uncommitted changes
+}
+
+// Report is what Extract recovers from a binary, the document the JSON output of the command is.
+//
+// The fields that are always set on success depend on the Compiler. For gc, the Go compiler: Version, Compiler, VersionDetection, Arch,
+// TabMeta, Composition, Packages, MetadataFingerprint, Diagnostics and Timings, and ModuleMeta unless the pclntab is one of a PE overlay,
+// TabMeta.Overlay, which no moduledata points at, or given by Options.Pclntab. For tinygo: Compiler, TinyGo, Arch, Composition, MetadataFingerprint and Timings. The
+// Report of the functions Options.HeuristicFunctions found without a pclntab has Heuristic, Diagnostics and what the headers and build
+// info tell. The functions are set unless Options.NoFunctions, StdFunctions only with Options.StdFunctions, and left empty with Options.Stream.
+//
+// The fields commented with a flag are only set with the option of that flag, see Options. The rest are optional, set when the binary
+// has them and empty otherwise, ex: BuildInfo is the zero value for a binary built without module support and Types is empty for one
+// whose typelinks and rtypes are both gone. Every field is marshaled, an empty one as null or its zero value rather than left out, and
+// the lists are sorted the same every run, see SchemaVersion.
+//
+// On failure the Report has what tells the failure apart: Diagnostics, Packer and LikelyPacked, and the version as far as the claims
+// tell it. A canceled Extract returns everything recovered up to the cancellation instead, see ErrCanceled.
+type Report struct {
+	// the JSON layout the Report follows, see SchemaVersion
+	SchemaVersion int
+	Version       string
+	// every source of the version and how they were weighed, Version is the consensus unless overridden
+	VersionDetection VersionDetection
+	// the go1.x strings of the data string headers point at, every distinct one, scanned for when there's no build info
+	VersionStrings []objfile.VersionString
+	// gc, or tinygo whose binaries keep only a symbol table. TinyGo leaves Version empty, the TinyGo version is in TinyGo
+	Compiler  string
+	TinyGo    *objfile.TinyGoInfo
+	BuildId   string
+	Arch      string
+	OS        string
+	BuildMode string // exe, pie, c-shared, plugin or c-archive. From the build info, else inferred from the file type
+	ImageBase uint64 // the base of the RVAs of a PE or a dump, the VAs are relative to it
+	RVABase   uint64 // the base the RVAs of the records are relative to, ImageBase or the start of the first ELF or Mach-O segment
+	// the embedded Go executable of the input the Report is of, nil for the input itself, see Options.Image
+	Image *objfile.EmbeddedImage
+	// the Go executables embedded in the input, each is extracted on its own by its Index, see Options.Image
+	EmbeddedImages []objfile.EmbeddedImage
+	Slide          uint64 // the load bias of a position independent ELF whose pointers were relocated, ex: in a dump
+	TabMeta        PcLnTabMetadata
+	ModuleMeta     objfile.ModuleData
+	// every module of the moduledata list, the first is ModuleMeta whose symbols are the top level ones
+	Modules    []ModuleMetadata
+	Types      []objfile.Type
+	Interfaces []objfile.Type
+	// the concrete types implementing each interface from the itabs, with -t and -methods
+	Itabs []InterfaceItabs
+	// the instantiations of each generic function and method, with their dictionaries when there's a symbol table
+	Generics      []GenericFunction
+	BuildInfo     debug.BuildInfo
+	BuildSettings map[string]string // the settings of BuildInfo by key, ex: -ldflags, CGO_ENABLED and GOEXPERIMENT
+	VCS           *VCSInfo
+	Files         []string
+	UserFunctions []FuncMetadata
+	StdFunctions  []FuncMetadata
+	// how many of the functions listed, or streamed, are of each FuncMetadata.Kind. The user ones are the program's own code, the
+	// wrappers, thunks and generated functions of its packages aren't.
+	FunctionKinds map[string]int
+	// calls to context.WithTimeout and friends, these often mark beacon intervals and request timeouts, only with -callsites
+	ContextCallSites []objfile.CallSite
+	DebugLink        *DebugLinkMetadata
+	// Go/C boundary functions and the linked in C code
+	Cgo CgoMetadata
+	// standard library defaults like http.DefaultTransport and whether the program replaced them
+	StdGlobals []objfile.StdGlobal
+	// set when the input was parsed as a memory dump with -mode dump
+	Dump *objfile.DumpInfo
+	// data appended after the last PE section, scanned with -scan-overlay
+	Overlay *objfile.PEOverlay
+	// PT_LOAD segments mapping the same VAs, reads from these ranges prefer the segment agreeing with the section headers
+	SegmentOverlaps []objfile.SegmentOverlap
+	// the go:embed file systems and their files, written out with -extract-embedded
+	EmbeddedFS []objfile.EmbeddedFS
+	// the init tasks of the packages in the order they run before main.main, from Go 1.13 on
+	Inits []objfile.InitTask
+	// time.Time values found in initialized data, only with -timestamps
+	TimeConstants []objfile.TimeConstant
+	// field offsets of runtime.g and runtime.m, for walking goroutines in memory images
+	RuntimeOffsets []objfile.RuntimeOffset
+	// the VAs of runtime.allgs, allm, sched, g0 and the likes the walk starts from, only those located confidently
+	RuntimeGlobals []objfile.RuntimeGlobal
+	// variables published through expvar, Arg is the published name, only with -callsites
+	ExpvarNames []objfile.StringArgCallSite
+	// the string headers of the initialized data no function was seen to load, only with -strings
+	UnattributedStrings []objfile.StringLiteral
+	// the sorted position independent hashes of the hashed functions, their SHA256 where there's none, only with -hash. Diffing the
+	// sets of two builds shows the code that changed.
+	FunctionHashes []string
+	// the functions whose entry looks patched, only with -detect-hooks
+	Hooks []objfile.Hook
+	// struct fields accessed by name through reflection, Arg is the field name, only with -callsites
+	ReflectFieldAccesses []objfile.StringArgCallSite
+	ObfuscatorDetected   bool
+	Obfuscator           string
+	// the signs the obfuscator was detected by
+	ObfuscationEvidence []string
+	Packer              string // executable packer detected in the headers, ex: UPX
+	// the signs of a packer or crypter, also reported when parsing fails
+	LikelyPacked *objfile.PackingInfo
+	// the pclntab candidates tried and why each was rejected, only when parsing fails
+	Attempts []CandidateAttempt
+	// package mix, flags binaries that embed the Go toolchain or an atypical amount of the standard library
+	Composition BinaryComposition
+	// the packages linked in, from the function names, the package paths of the types and the source file directories
+	Packages []PackageMetadata
+	// what was asked for but can't be recovered and why, ex: the types of a pclntab given by Options.Pclntab, which has no moduledata
+	Unavailable []string
+	// the functions of the pclntab that didn't parse and what was done instead, ex: a placeholder name, only with Options.Tolerant
+	Corruption []gosym.Corruption
+	// the file ends before what its headers lay out, what's past the end is listed in Unavailable
+	Truncated bool
+	// the functions found from the code when no pclntab is, with -heuristic-funcs. Guesses, unlike the pclntab's functions.
+	Heuristic *objfile.HeuristicRecovery
+	// SHA-256 over the sorted function and package names of the pclntab, the sorted type names of the typelinks and the Go version.
+	// Excludes all addresses, and is the same whatever the options: the typelinks are walked for it without -t too.
+	MetadataFingerprint string
+	// every name of the extracted functions and of the functions inlined into them, sorted, only with -inlined
+	AllFunctionNames []string
+	// what the -include and -exclude patterns dropped, only when one is given
+	Filtered *FilterCounts
+	Timings  *Timings // the command only emits them with -profile
+	// signature hits and scanned sections, the command only emits them with -diagnostics
+	Diagnostics *objfile.ScanDiagnostics
+	// the parsed pclntab, for the line tables of -patch-dwarf, and the file it's read from. Not serialized.
+	pclntab *gosym.Table
+	file    *objfile.File
+}
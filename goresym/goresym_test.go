@@ -0,0 +1,1423 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+	"github.com/mandiant/GoReSym/debug/gosym"
+	"github.com/mandiant/GoReSym/objfile"
+	"github.com/mandiant/GoReSym/runtime/debug"
+)
+
+func TestExtract(t *testing.T) {
+	const path = "../test/weirdbins/hello_lin"
+	report, err := Extract(context.Background(), path, Options{})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if len(report.Version) == 0 || report.Compiler != "gc" || len(report.Arch) == 0 || report.TabMeta.VA == 0 || report.ModuleMeta.VA == 0 {
+		t.Errorf("missing metadata: %+v %+v", report.TabMeta, report.ModuleMeta)
+	}
+	if len(report.UserFunctions) == 0 || len(report.StdFunctions) != 0 || len(report.Types) != 0 || len(report.Files) != 0 {
+		t.Errorf("expected only the user functions without options")
+	}
+	if report.Pclntab() == nil {
+		t.Fatalf("expected the pclntab until close")
+	}
+	if err := report.Close(); err != nil || report.Pclntab() != nil {
+		t.Errorf("expected close to release the pclntab, got %v", err)
+	}
+
+	// the header is streamed first, the functions only go to the stream
+	var kinds []string
+	streamed, err := Extract(context.Background(), path, Options{Types: true, Stream: func(kind string, value interface{}) {
+		kinds = append(kinds, kind)
+	}})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	defer streamed.Close()
+	if len(kinds) == 0 || kinds[0] != "header" || len(streamed.UserFunctions) != 0 || len(streamed.Types) != 0 {
+		t.Errorf("unexpected stream %v", kinds)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	partial, err := Extract(ctx, path, Options{Types: true})
+	if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a canceled extraction, got %v", err)
+	}
+	if partial == nil || len(partial.UserFunctions) != 0 {
+		t.Errorf("expected an empty report before any function, got %+v", partial)
+	}
+	partial.Close()
+
+	if failed, err := Extract(context.Background(), "../test/weirdbins/missing", Options{}); err == nil || failed == nil {
+		t.Errorf("expected a report and an error for a missing file")
+	}
+}
+
+func TestErrorClasses(t *testing.T) {
+	const path = "../test/weirdbins/hello_lin"
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elfFile, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the pclntab wiped, the build info still tells the version
+	stripped := append([]byte{}, data...)
+	pclntab := elfFile.Section(".gopclntab")
+	clear(stripped[pclntab.Offset : pclntab.Offset+pclntab.Size])
+
+	extractions := []struct {
+		name    string
+		extract func() (*Report, error)
+		class   error
+	}{
+		{"missing", func() (*Report, error) { return Extract(context.Background(), "../test/weirdbins/missing", Options{}) }, ErrIO},
+		{"not go", func() (*Report, error) {
+			return Extract(context.Background(), "../test/weirdbins/notgo_invalid_bss_secsize", Options{})
+		}, ErrNotGo},
+		{"no pclntab", func() (*Report, error) {
+			return ExtractReader(context.Background(), bytes.NewReader(stripped), int64(len(stripped)), Options{})
+		}, ErrNoPclntab},
+		{"bad moduledata", func() (*Report, error) { return Extract(context.Background(), path, Options{ModuleData: 0x401000}) }, ErrCorruptModuleData},
+	}
+	for _, extraction := range extractions {
+		report, err := extraction.extract()
+		if !errors.Is(err, extraction.class) {
+			t.Errorf("%s: expected %v, got %v", extraction.name, extraction.class, err)
+		}
+		report.Close()
+	}
+
+	// what was gathered before the failure is kept
+	report, err := ExtractReader(context.Background(), bytes.NewReader(stripped), int64(len(stripped)), Options{})
+	if ErrorClass(err) != "NoPclntab" || report.Version != "go1.15.5" || report.Arch != "amd64" {
+		t.Errorf("expected a NoPclntab failure with the version and arch, got %s %q %q", ErrorClass(err), report.Version, report.Arch)
+	}
+
+	panicked := func() (report *Report, err error) {
+		report = &Report{}
+		defer recoverPanic(report, &err)
+		panic("bad sample")
+	}
+	_, err = panicked()
+	var panicErr *PanicError
+	if !errors.Is(err, ErrInternal) || !errors.As(err, &panicErr) || !strings.Contains(panicErr.Stack, "TestErrorClasses") || ErrorClass(err) != "Internal" {
+		t.Errorf("expected the panic recovered as an internal error with its stack, got %v", err)
+	}
+}
+
+func TestLooksHashed(t *testing.T) {
+	for sourceFile, hashed := range map[string]bool{"f2BkFMVbEve.go": true, "main.go": false, "runtime/chan.go": false, "/home/u/src/x/Ab3dEf.go": false} {
+		if looksHashedFile(sourceFile) != hashed {
+			t.Errorf("%s: expected hashed=%v", sourceFile, hashed)
+		}
+	}
+
+	for pkg, hashed := range map[string]bool{"Dat5j9j7Fwyn": true, "t8_jCS_RX": true, "n_DnDodp": true, "main": false, "mylib": false, "github.com/spf13/cobra": false, "k8s.io/api/core/v1": false} {
+		if looksHashedPackage(pkg) != hashed {
+			t.Errorf("%s: expected hashed=%v", pkg, hashed)
+		}
+	}
+}
+
+func TestVersionDetection(t *testing.T) {
+	claim := func(source string, version string) VersionSource {
+		s, _ := versionClaim(source, version)
+		return s
+	}
+	layout := func(source string, layouts map[string][2]int, version string) VersionSource {
+		s, _ := layoutVersion(source, layouts, version)
+		return s
+	}
+
+	agreeing := decideVersion([]VersionSource{claim(versionSourceBuildInfo, "go1.21.3"), claim(versionSourceBuildVersion, "go1.21.3"),
+		layout(versionSourcePclntab, pclntabLayoutVersions, "1.20"), layout(versionSourceModuleData, moduleDataLayoutVersions, "1.21")})
+	if agreeing.Consensus != "1.21.3" || agreeing.Confidence != "high" || len(agreeing.Warning) != 0 {
+		t.Errorf("expected 1.21.3 with high confidence, got %+v", agreeing)
+	}
+
+	// a doctored buildinfo loses to the runtime's own copy of the version the layouts back up
+	lying := decideVersion([]VersionSource{claim(versionSourceBuildInfo, "go1.16.5"), claim(versionSourceBuildVersion, "go1.20.4"),
+		layout(versionSourcePclntab, pclntabLayoutVersions, "1.20")})
+	if lying.Consensus != "1.20.4" || lying.Confidence != "medium" || len(lying.Warning) == 0 || lying.Sources[0].Agrees || !lying.Sources[1].Agrees {
+		t.Errorf("expected 1.20.4 with a warning against the buildinfo, got %+v", lying)
+	}
+
+	// every claim lies, the structure sets the floor
+	structural := decideVersion([]VersionSource{claim(versionSourceString, "go1.8.7"), layout(versionSourcePclntab, pclntabLayoutVersions, "1.18"),
+		versionRange(versionSourceRuntimeFunctions, 19, -1)})
+	if structural.Consensus != "1.19" || structural.Confidence != "low" || len(structural.Warning) == 0 {
+		t.Errorf("expected 1.19 with low confidence, got %+v", structural)
+	}
+
+	if detection := decideVersion(nil); len(detection.Consensus) != 0 {
+		t.Errorf("expected no consensus without sources, got %+v", detection)
+	}
+	if _, ok := versionClaim(versionSourceBuildVersion, "unknown"); ok {
+		t.Errorf("expected garble's unknown not to be a claim")
+	}
+
+	runtimeFuncs := func(names ...string) []gosym.Func {
+		var funcs []gosym.Func
+		for _, name := range names {
+			funcs = append(funcs, gosym.Func{Sym: &gosym.Sym{Name: name}})
+		}
+		return funcs
+	}
+	if source, ok := runtimeFunctionsVersion(runtimeFuncs("runtime.main", "runtime.doInit1", "runtime.runExitHooks")); !ok || source.Version != "1.21+" {
+		t.Errorf("expected 1.21+, got %+v", source)
+	}
+	if _, ok := runtimeFunctionsVersion(runtimeFuncs("runtime.main")); ok {
+		t.Errorf("expected no version without markers")
+	}
+
+	data, err := Extract(context.Background(), "../test/weirdbins/hello_lin", Options{NoFunctions: true})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if data.VersionDetection.Consensus != data.Version || data.VersionDetection.Confidence != "high" {
+		t.Errorf("expected a high confidence consensus on %s, got %+v", data.Version, data.VersionDetection)
+	}
+}
+
+func TestVersionStringScan(t *testing.T) {
+	// a stripped Go 1.8 binary has no build info and no runtime.buildVersion symbol, the string header of the data still points at it
+	const path = "../test/weirdbins/fmtisfun_lin_stripped"
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Extract(context.Background(), path, Options{NoFunctions: true})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if len(report.VersionStrings) != 1 || report.VersionStrings[0].Version != "go1.8.7" || report.VersionDetection.Confidence != "high" {
+		t.Errorf("expected go1.8.7 the pclntab backs up, got %+v and %+v", report.VersionStrings, report.VersionDetection)
+	}
+	report.Close()
+
+	// with the pclntab wiped too only the version strings tell
+	elfFile, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pclntab := elfFile.Section(".gopclntab")
+	clear(data[pclntab.Offset : pclntab.Offset+pclntab.Size])
+	report, err = ExtractReader(context.Background(), bytes.NewReader(data), int64(len(data)), Options{})
+	if ErrorClass(err) != "NoPclntab" || report.Version != "go1.8.7" || report.VersionDetection.Consensus != "1.8.7" || report.VersionDetection.Confidence != "string-scan" {
+		t.Errorf("expected a NoPclntab failure with the version of the string scan, got %s %q %+v", ErrorClass(err), report.Version, report.VersionDetection)
+	}
+	report.Close()
+}
+
+func TestHeuristicFunctions(t *testing.T) {
+	const path = "../test/weirdbins/fmtisfun_lin_stripped"
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Extract(context.Background(), path, Options{StdFunctions: true})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	truth := make(map[uint64]string)
+	for _, fn := range append(report.UserFunctions, report.StdFunctions...) {
+		truth[fn.Start] = fn.FullName
+	}
+	report.Close()
+
+	elfFile, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pclntab := elfFile.Section(".gopclntab")
+	clear(data[pclntab.Offset : pclntab.Offset+pclntab.Size])
+	report, err = ExtractReader(context.Background(), bytes.NewReader(data), int64(len(data)), Options{HeuristicFunctions: true})
+	if err != nil {
+		t.Fatalf("expected the heuristic functions, got %s", err)
+	}
+	defer report.Close()
+	heuristic := report.Heuristic
+	if heuristic == nil || len(heuristic.Functions) < 1000 || len(heuristic.Morestack) != 2 || len(report.UserFunctions) != 0 {
+		t.Fatalf("expected the heuristic functions apart from the pclntab's, got %+v", heuristic)
+	}
+	methods := 0
+	for _, fn := range heuristic.Functions {
+		name, ok := truth[fn.Start]
+		if !ok {
+			t.Errorf("0x%x %s isn't a function start", fn.Start, fn.Name)
+		} else if fn.NamedBy != "" && fn.Name != name {
+			t.Errorf("0x%x is %s, named %s by the %s", fn.Start, name, fn.Name, fn.NamedBy)
+		}
+		if fn.NamedBy == "method" {
+			methods++
+		}
+	}
+	if methods == 0 || truth[heuristic.Morestack[0]] != "runtime.morestack_noctxt" {
+		t.Errorf("expected the methods and runtime.morestack_noctxt named, got %d methods and %s", methods, truth[heuristic.Morestack[0]])
+	}
+
+	// not asked for it's the failure it was
+	if _, err := ExtractReader(context.Background(), bytes.NewReader(data), int64(len(data)), Options{}); ErrorClass(err) != "NoPclntab" {
+		t.Errorf("expected NoPclntab, got %v", err)
+	}
+}
+
+func TestFunctionSizes(t *testing.T) {
+	const path = "../test/weirdbins/fmtisfun_lin"
+	report, err := Extract(context.Background(), path, Options{StdFunctions: true})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	defer report.Close()
+
+	// the sizes the linker wrote in the symbol table, what go tool nm -size prints
+	elfFile, err := elf.Open(path)
+	if err != nil {
+		t.Fatalf("%s doesn't open: %s", path, err)
+	}
+	defer elfFile.Close()
+	syms, err := elfFile.Symbols()
+	if err != nil {
+		t.Fatalf("no symbols: %s", err)
+	}
+	sizes := make(map[string]uint64)
+	for _, sym := range syms {
+		if elf.ST_TYPE(sym.Info) == elf.STT_FUNC {
+			sizes[sym.Name] = sym.Size
+		}
+	}
+
+	padded := 0
+	for _, fn := range append(report.UserFunctions, report.StdFunctions...) {
+		size, ok := sizes[fn.FullName]
+		if !ok {
+			continue
+		}
+		if fn.Size != size || fn.End != fn.Start+size {
+			t.Errorf("%s: expected 0x%x-0x%x, got 0x%x-0x%x", fn.FullName, fn.Start, fn.Start+size, fn.Start, fn.End)
+		}
+		if next := report.Pclntab().PCToFunc(fn.End); next == nil || next.Entry != fn.End {
+			padded++
+		}
+	}
+	if padded == 0 {
+		t.Errorf("expected the padding between functions not to be counted")
+	}
+}
+
+func TestAddressTriples(t *testing.T) {
+	for _, name := range []string{"fmtisfun_lin", "fmtisfun_win", "fmtisfun_macho"} {
+		path := "../test/weirdbins/" + name
+		report, err := Extract(context.Background(), path, Options{Types: true, Methods: true})
+		if err != nil {
+			t.Fatalf("%s: GoReSym failed: %s", name, err)
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s doesn't read: %s", path, err)
+		}
+
+		// the pclntab starts with its magic and the moduledata with the pointer to it
+		tab := report.TabMeta
+		if tab.FileOffset == nil || tab.RVA != tab.VA-report.RVABase || !bytes.Equal(raw[*tab.FileOffset+4:*tab.FileOffset+6], []byte{0, 0}) || raw[*tab.FileOffset] < 0xf0 {
+			t.Errorf("%s: expected the pclntab at its file offset, got %+v", name, tab)
+		}
+		module := report.ModuleMeta
+		if module.FileOffset == nil || module.RVA != module.VA-report.RVABase || report.Modules[0].ModuleDataFileOffset == nil || *report.Modules[0].ModuleDataFileOffset != *module.FileOffset {
+			t.Errorf("%s: expected the moduledata at a file offset, got %+v", name, module)
+		} else if pcHeader := binary.LittleEndian.Uint64(raw[*module.FileOffset:]); pcHeader != tab.VA {
+			t.Errorf("%s: expected the moduledata to point at the pclntab at 0x%x, got 0x%x", name, tab.VA, pcHeader)
+		}
+
+		for _, fn := range report.UserFunctions {
+			if fn.FileOffset == nil || fn.RVA != fn.Start-report.RVABase {
+				t.Errorf("%s: expected %s located, got 0x%x and %v", name, fn.FullName, fn.RVA, fn.FileOffset)
+			}
+		}
+		for _, typ := range report.Types {
+			if typ.FileOffset == nil || typ.RVA != typ.VA-report.RVABase {
+				t.Errorf("%s: expected %s located, got 0x%x and %v", name, typ.Str, typ.RVA, typ.FileOffset)
+			}
+		}
+		for _, itabs := range report.Itabs {
+			for _, itab := range itabs.Implementations {
+				if itab.RVA != itab.VA-report.RVABase {
+					t.Errorf("%s: expected the itab of %s located, got 0x%x", name, itab.Type, itab.RVA)
+				}
+			}
+		}
+		report.Close()
+	}
+}
+
+func TestGCLayouts(t *testing.T) {
+	// type Value struct { typ *rtype; ptr unsafe.Pointer; flag }, on a little and a big endian binary
+	valueLayout := objfile.GCLayout{Source: "bitmap", Bitmap: "03", Pointers: []uint64{0, 8}}
+	cases := []struct {
+		name     string
+		typ      string
+		size     uint64
+		ptrBytes uint64
+		layout   objfile.GCLayout
+	}{
+		{"hello_lin", "reflect.Value", 24, 16, valueLayout},
+		{"generics_lin", "reflect.Value", 24, 16, valueLayout},
+		{"bigendian", "reflect.Value", 24, 16, valueLayout},
+		// type itab struct { inter *interfacetype; _type *_type; link *itab; bad, unused int32; fun [1]uintptr }
+		{"fmtisfun_lin", "runtime.itab", 40, 24, objfile.GCLayout{Source: "bitmap", Bitmap: "07", Pointers: []uint64{0, 8, 16}}},
+		// the itab of its itablinks, too large for a bitmap in 1.8
+		{"fmtisfun_lin", "struct { ityp *reflect.rtype; typ *reflect.rtype; link unsafe.Pointer; bad int32; unused int32; fun [100000]unsafe.Pointer }", 800032, 800032, objfile.GCLayout{Source: "program"}},
+	}
+	for _, c := range cases {
+		report, err := Extract(context.Background(), "../test/weirdbins/"+c.name, Options{Types: true, GCData: true})
+		if err != nil {
+			t.Fatalf("%s: GoReSym failed: %s", c.name, err)
+		}
+		var found *objfile.Type
+		for i := range report.Types {
+			if report.Types[i].Str == c.typ {
+				found = &report.Types[i]
+				break
+			}
+		}
+		report.Close()
+		if found == nil || found.GC == nil {
+			t.Errorf("%s: expected the layout of %s, got %+v", c.name, c.typ, found)
+			continue
+		}
+		if found.Size != c.size || found.Align != 8 || found.PtrBytes != c.ptrBytes {
+			t.Errorf("%s: expected %s of %d bytes, %d of pointers, got %d aligned %d with %d", c.name, c.typ, c.size, c.ptrBytes, found.Size, found.Align, found.PtrBytes)
+		}
+		if c.layout.Source == "program" {
+			// every word is a pointer but the two int32s
+			if found.GC.Source != "program" || !strings.HasPrefix(found.GC.Bitmap, "f7ff") || found.GC.Pointers[3] != 32 {
+				t.Errorf("%s: expected the program of %s run, got %s %s", c.name, c.typ, found.GC.Source, found.GC.Bitmap[:8])
+			}
+			continue
+		}
+		if !reflect.DeepEqual(*found.GC, c.layout) {
+			t.Errorf("%s: expected the layout %+v of %s, got %+v", c.name, c.layout, c.typ, *found.GC)
+		}
+	}
+
+	// off, no type decodes its gcdata
+	report, err := Extract(context.Background(), "../test/weirdbins/hello_lin", Options{Types: true})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	defer report.Close()
+	for _, typ := range report.Types {
+		if typ.GC != nil {
+			t.Fatalf("expected no layouts without GCData, got one for %s", typ.Str)
+		}
+	}
+}
+
+func TestMethods(t *testing.T) {
+	for _, methods := range []bool{false, true} {
+		report, err := Extract(context.Background(), "../test/weirdbins/hello_lin", Options{Types: true, Methods: methods})
+		if err != nil {
+			t.Fatalf("GoReSym failed: %s", err)
+		}
+		withMethods, withPkgPath := 0, 0
+		for _, typ := range report.Types {
+			if len(typ.Methods) > 0 {
+				withMethods++
+			}
+			if len(typ.PkgPath) > 0 {
+				withPkgPath++
+			}
+		}
+		// the package paths are read either way
+		if withPkgPath == 0 {
+			t.Errorf("methods %v: expected package paths", methods)
+		}
+		if (withMethods > 0) != methods || (len(report.Itabs) > 0) != methods {
+			t.Errorf("methods %v: got %d types with methods and %d interfaces with itabs", methods, withMethods, len(report.Itabs))
+		}
+		report.Close()
+	}
+}
+
+func TestOpenOptions(t *testing.T) {
+	// each extraction opens its file with its own Options, side by side
+	cases := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{"default", Options{}, false},
+		{"raw without base", Options{Mode: "raw"}, true},
+		{"bad signature", Options{Signatures: []objfile.CustomSignature{{Name: "bad", Pattern: "{ B8 ?? ?? ?? ?? CC }", Decode: "absolute64"}}}, true},
+		{"signature", Options{Signatures: []objfile.CustomSignature{{Name: "abs", Pattern: "{ B8 ?? ?? ?? ?? CC }", Decode: "absolute32", Offset: 1}}}, false},
+	}
+	errs := make([]error, len(cases))
+	var wg sync.WaitGroup
+	for i, c := range cases {
+		wg.Add(1)
+		go func(i int, opts Options) {
+			defer wg.Done()
+			report, err := Extract(context.Background(), "../test/weirdbins/hello_lin", opts)
+			if err == nil {
+				report.Close()
+			}
+			errs[i] = err
+		}(i, c.opts)
+	}
+	wg.Wait()
+	for i, c := range cases {
+		if (errs[i] != nil) != c.wantErr {
+			t.Errorf("%s: expected failure %v, got %v", c.name, c.wantErr, errs[i])
+		}
+	}
+}
+
+func TestEmbeddedImages(t *testing.T) {
+	// a linux dropper with a windows payload in its data by go:embed
+	path := "../test/weirdbins/nested_lin"
+	hasFunction := func(report *Report, name string) bool {
+		for _, fn := range report.UserFunctions {
+			if fn.FullName == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	outer, err := Extract(context.Background(), path, Options{})
+	if err != nil {
+		t.Fatalf("GoReSym failed on the dropper: %s", err)
+	}
+	defer outer.Close()
+	if outer.Image != nil || len(outer.EmbeddedImages) != 1 || outer.EmbeddedImages[0].Index != 1 || outer.EmbeddedImages[0].Format != "pe" || outer.EmbeddedImages[0].Region != ".noptrdata" {
+		t.Fatalf("expected the payload in .noptrdata, got %+v", outer.EmbeddedImages)
+	}
+	if outer.OS != "linux" || !hasFunction(outer, "main.drop") || hasFunction(outer, "main.beacon") {
+		t.Errorf("expected the dropper's own functions, got %s with %d", outer.OS, len(outer.UserFunctions))
+	}
+
+	inner, err := Extract(context.Background(), path, Options{Image: 1})
+	if err != nil {
+		t.Fatalf("GoReSym failed on the payload: %s", err)
+	}
+	defer inner.Close()
+	if inner.Image == nil || *inner.Image != outer.EmbeddedImages[0] || inner.EmbeddedImages != nil {
+		t.Errorf("expected the report of the payload, got %+v", inner.Image)
+	}
+	if inner.OS != "windows" || !hasFunction(inner, "main.beacon") || hasFunction(inner, "main.drop") {
+		t.Errorf("expected the payload's own functions, got %s with %d", inner.OS, len(inner.UserFunctions))
+	}
+	// the addresses are of the payload, its pclntab is at its offset in the payload
+	if inner.TabMeta.FileOffset == nil || *inner.TabMeta.FileOffset >= inner.Image.Size {
+		t.Errorf("expected the pclntab located in the payload, got %v", inner.TabMeta.FileOffset)
+	}
+
+	if _, err := Extract(context.Background(), path, Options{Image: 2}); err == nil {
+		t.Errorf("expected no second image")
+	}
+}
+
+func TestBigEndian(t *testing.T) {
+	// stripped builds of a program dialing a *Config through an interface:
+	//
+	//	type Config struct { Host string; Port int; Retries []int; next *Config }
+	//	type Dialer interface { Dial(network string) error }
+	cases := []struct {
+		name    string
+		arch    string
+		ptrSize uint32
+		size    uint64
+	}{
+		{"ppc64_stripped_lin", "ppc64", 8, 56},
+		{"mips_stripped_lin", "mips", 4, 28},
+	}
+	for _, c := range cases {
+		report, err := Extract(context.Background(), "../test/weirdbins/"+c.name, Options{Types: true, Methods: true})
+		if err != nil {
+			t.Fatalf("%s: GoReSym failed: %s", c.name, err)
+		}
+		report.Close()
+		if report.Arch != c.arch || report.TabMeta.Endianess != "BigEndian" || report.TabMeta.PointerSize != c.ptrSize || report.Version != "1.22.12" {
+			t.Errorf("%s: expected a big endian %s pclntab of 1.22.12, got %s %s %d %s", c.name, c.arch, report.Arch, report.TabMeta.Endianess, report.TabMeta.PointerSize, report.Version)
+		}
+
+		functions := map[string]bool{}
+		for _, fn := range report.UserFunctions {
+			functions[fn.FullName] = true
+		}
+		for _, name := range []string{"main.main", "main.connect", "main.(*Config).Dial"} {
+			if !functions[name] {
+				t.Errorf("%s: expected the function %s", c.name, name)
+			}
+		}
+
+		var config *objfile.Type
+		for i := range report.Types {
+			if report.Types[i].Str == "main.Config" {
+				config = &report.Types[i]
+			}
+		}
+		if config == nil || config.Size != c.size || !strings.Contains(config.Reconstructed, "Retries    []int") || !strings.Contains(config.Reconstructed, "next       *main.Config") {
+			t.Errorf("%s: expected main.Config of %d bytes with its fields, got %+v", c.name, c.size, config)
+		}
+
+		dial := ""
+		for _, itabs := range report.Itabs {
+			if itabs.Interface == "main.Dialer" && len(itabs.Implementations) == 1 && itabs.Implementations[0].Type == "*main.Config" && len(itabs.Implementations[0].Methods) == 1 {
+				dial = itabs.Implementations[0].Methods[0].Function
+			}
+		}
+		if dial != "main.(*Config).Dial" {
+			t.Errorf("%s: expected *main.Config to implement main.Dialer with main.(*Config).Dial, got %q", c.name, dial)
+		}
+
+		// the moduledata given by hand is read in the order of the header too
+		known, err := Extract(context.Background(), "../test/weirdbins/"+c.name, Options{ModuleData: report.ModuleMeta.VA})
+		if err != nil {
+			t.Errorf("%s: GoReSym failed with the moduledata at 0x%x: %s", c.name, report.ModuleMeta.VA, err)
+			continue
+		}
+		known.Close()
+		if known.ModuleMeta.VA != report.ModuleMeta.VA || len(known.UserFunctions) != len(report.UserFunctions) {
+			t.Errorf("%s: expected the moduledata at 0x%x, got 0x%x with %d functions", c.name, report.ModuleMeta.VA, known.ModuleMeta.VA, len(known.UserFunctions))
+		}
+	}
+}
+
+func TestInterfaceMethods(t *testing.T) {
+	// type Transport interface {
+	// 	io.ReadWriteCloser
+	// 	Send(format string, args ...interface{}) (int, error)
+	// 	handshake(key []byte) bool
+	// }
+	report, err := Extract(context.Background(), "../test/weirdbins/interfaces_lin", Options{Types: true})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	report.Close()
+	methodSet := func(types []objfile.Type, name string) []objfile.InterfaceMethod {
+		for _, typ := range types {
+			if typ.Str == name {
+				return typ.InterfaceMethods
+			}
+		}
+		return nil
+	}
+	expected := []objfile.InterfaceMethod{
+		{Name: "Close", Type: "func() error", Signature: "Close() error", Results: []string{"error"}, From: "io.ReadWriteCloser"},
+		{Name: "Read", Type: "func([]uint8) (int, error)", Signature: "Read([]uint8) (int, error)", Params: []string{"[]uint8"}, Results: []string{"int", "error"}, From: "io.ReadWriteCloser"},
+		{Name: "Send", Type: "func(string, ...interface {}) (int, error)", Signature: "Send(string, ...interface {}) (int, error)", Params: []string{"string", "...interface {}"}, Results: []string{"int", "error"}, Variadic: true},
+		{Name: "Write", Type: "func([]uint8) (int, error)", Signature: "Write([]uint8) (int, error)", Params: []string{"[]uint8"}, Results: []string{"int", "error"}, From: "io.ReadWriteCloser"},
+		{Name: "handshake", Type: "func([]uint8) bool", PkgPath: "main", Signature: "main.handshake([]uint8) bool", Params: []string{"[]uint8"}, Results: []string{"bool"}},
+	}
+	for _, types := range [][]objfile.Type{report.Types, report.Interfaces} {
+		if methods := methodSet(types, "main.Transport"); !reflect.DeepEqual(methods, expected) {
+			t.Errorf("expected the method set of main.Transport, got %+v", methods)
+		}
+	}
+	if methods := methodSet(report.Types, "io.ReadWriteCloser"); len(methods) != 3 || methods[2].Name != "Write" || methods[2].From != "io.Writer" || methods[0].From != "" {
+		t.Errorf("expected Write of io.ReadWriteCloser from io.Writer, got %+v", methods)
+	}
+	// the types reached by the method signatures are listed once
+	for _, types := range [][]objfile.Type{report.Types, report.Interfaces} {
+		seen := make(map[uint64]bool)
+		for _, typ := range types {
+			if seen[typ.VA] {
+				t.Errorf("expected the type at 0x%x once, %s is listed again", typ.VA, typ.Str)
+			}
+			seen[typ.VA] = true
+		}
+	}
+
+	// the unexported methods of reflect.Type in 1.8, an import path before 1.17's varint names
+	report, err = Extract(context.Background(), "../test/weirdbins/fmtisfun_lin", Options{Types: true})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	report.Close()
+	found := false
+	for _, method := range methodSet(report.Types, "reflect.Type") {
+		if method.Name == "common" {
+			found = method.PkgPath == "reflect" && method.Signature == "reflect.common() *reflect.rtype"
+		}
+	}
+	if !found {
+		t.Errorf("expected reflect.common of reflect.Type, got %+v", methodSet(report.Types, "reflect.Type"))
+	}
+}
+
+func TestClassifySource(t *testing.T) {
+	buildInfo := &debug.BuildInfo{
+		Main: debug.Module{Path: "github.com/gravitational/teleport"},
+		Deps: []*debug.Module{{Path: "github.com/gravitational/teleport/api"}, {Path: "github.com/Azure/go-autorest"}, {Path: "k8s.io/api"}},
+	}
+
+	cases := []struct {
+		path      string
+		pkg       string
+		buildInfo *debug.BuildInfo
+		origin    string
+		module    string
+	}{
+		{"fmt/print.go", "fmt", buildInfo, OriginStd, ""},
+		{"/usr/local/go/src/fmt/print.go", "fmt", nil, OriginStd, ""},
+		// -trimpath
+		{"github.com/gravitational/teleport/lib/client/api.go", "github.com/gravitational/teleport/lib/client", buildInfo, OriginMain, "github.com/gravitational/teleport"},
+		{"github.com/gravitational/teleport/api@v0.0.0/client/client.go", "github.com/gravitational/teleport/api/client", buildInfo, OriginDependency, "github.com/gravitational/teleport/api"},
+		{"k8s.io/api@v0.24.0/core/v1/types.go", "k8s.io/api/core/v1", nil, OriginDependency, "k8s.io/api"},
+		// module cache, case encoded
+		{"/home/u/go/pkg/mod/github.com/!azure/go-autorest@v14.2.0/autorest.go", "github.com/Azure/go-autorest", buildInfo, OriginDependency, "github.com/Azure/go-autorest"},
+		{"/home/u/go/pkg/mod/github.com/!azure/go-autorest@v14.2.0/autorest.go", "github.com/Azure/go-autorest", nil, OriginDependency, "github.com/Azure/go-autorest"},
+		// vendored, no version in the path
+		{"/src/teleport/vendor/k8s.io/api/core/v1/types.go", "k8s.io/api/core/v1", buildInfo, OriginDependency, "k8s.io/api"},
+		{"/src/teleport/vendor/example.com/lib/lib.go", "example.com/lib", nil, OriginDependency, ""},
+		// local build without build info
+		{"/home/u/proj/main.go", "main", nil, OriginMain, ""},
+		{"/home/u/proj/util/util.go", "proj/util", nil, "", ""},
+	}
+
+	for _, c := range cases {
+		origin, module := classifySource(c.path, c.pkg, c.buildInfo)
+		if origin != c.origin || module != c.module {
+			t.Errorf("%s: expected %q %q, got %q %q", c.path, c.origin, c.module, origin, module)
+		}
+	}
+
+	if hasPathPrefix("github.com/a/bc/x.go", "github.com/a/b") {
+		t.Errorf("module path prefix matched a partial path element")
+	}
+}
+
+func TestBinaryComposition(t *testing.T) {
+	funcsFor := func(names ...string) []gosym.Func {
+		var funcs []gosym.Func
+		for _, name := range names {
+			funcs = append(funcs, gosym.Func{Sym: &gosym.Sym{Name: name}})
+		}
+		return funcs
+	}
+
+	app := analyzeComposition(funcsFor("main.main", "fmt.Println", "go/parser.ParseFile", "go/token.NewFileSet"))
+	if app.EmbedsToolchain || app.LargeStdFootprint || app.StdPackageCount != 3 {
+		t.Errorf("application flagged: %+v", app)
+	}
+	if strings.Join(app.ToolchainPackages, ",") != "go/parser,go/token" {
+		t.Errorf("unexpected toolchain packages %v", app.ToolchainPackages)
+	}
+
+	for _, name := range []string{"cmd/compile/internal/ssa.Compile", "go/types.(*Checker).Files"} {
+		if composition := analyzeComposition(funcsFor("main.main", name)); !composition.EmbedsToolchain {
+			t.Errorf("%s: toolchain not detected", name)
+		}
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, pkg := range standardPackages {
+		sym := gosym.Sym{Name: pkg + ".F"}
+		if len(names) < largeStdFootprint && !seen[pkg] && sym.PackageName() == pkg && !isToolchainPackage(pkg) {
+			names = append(names, sym.Name)
+			seen[pkg] = true
+		}
+	}
+	if composition := analyzeComposition(funcsFor(names...)); !composition.LargeStdFootprint || composition.EmbedsToolchain {
+		t.Errorf("large footprint not detected: %+v", composition)
+	}
+
+	// the real binaries are ordinary applications
+	data, err := Extract(context.Background(), "../test/weirdbins/kubectl_macho", Options{NoFunctions: true})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if data.Composition.EmbedsToolchain || data.Composition.LargeStdFootprint {
+		t.Errorf("kubectl flagged: %+v", data.Composition)
+	}
+}
+
+func TestCgoRecovery(t *testing.T) {
+	funcs := []gosym.Func{
+		{Entry: 0x1000, End: 0x1010, Sym: &gosym.Sym{Name: "main._cgoexp_0a1b2c3d4e5f_Foo"}},
+		{Entry: 0x1010, End: 0x1020, Sym: &gosym.Sym{Name: "main._Cfunc_puts"}},
+		{Entry: 0x1020, End: 0x1030, Sym: &gosym.Sym{Name: "crosscall2"}},
+		{Entry: 0x1030, End: 0x1040, Sym: &gosym.Sym{Name: "main.main"}},
+	}
+	syms := []objfile.Sym{
+		{Name: "main.main", Addr: 0x1030, Size: 0x10, Code: 'T'},
+		{Name: "_Foo", Addr: 0x2000, Size: 0x20, Code: 'T'},
+		{Name: "_x_cgo_init", Addr: 0x2020, Size: 0x20, Code: 't'},
+		{Name: "puts_helper", Addr: 0x2040, Size: 0x20, Code: 'T'},
+		{Name: "runtime.text", Addr: 0x1000, Code: 'T'},
+		{Name: "main.buf", Addr: 0x3000, Size: 0x20, Code: 'D'},
+	}
+
+	cgo := recoverCgo(nil, funcs, syms, map[string]string{"CGO_ENABLED": "1"})
+	if !cgo.Present {
+		t.Fatal("cgo not detected")
+	}
+	expectedEvidence := []string{
+		"build setting: CGO_ENABLED=1",
+		"pclntab: _Cfunc_ calls into C",
+		"pclntab: _cgoexp_ wrappers of //export functions",
+		"pclntab: crosscall2, the entry from C into Go",
+		"symbols: x_cgo_init",
+	}
+	if !reflect.DeepEqual(cgo.Evidence, expectedEvidence) {
+		t.Errorf("expected the evidence %v, got %v", expectedEvidence, cgo.Evidence)
+	}
+
+	kinds := make(map[string]string)
+	for _, fn := range cgo.Functions {
+		kinds[fn.FullName] = fn.Kind + ":" + fn.CName
+	}
+	expected := map[string]string{
+		"main._cgoexp_0a1b2c3d4e5f_Foo": "export:Foo",
+		"main._Cfunc_puts":              "call:puts",
+		"crosscall2":                    "runtime:",
+		"_Foo":                          "export:Foo",
+		"_x_cgo_init":                   "runtime:",
+		"puts_helper":                   "native:",
+	}
+	if len(kinds) != len(expected) {
+		t.Errorf("unexpected functions %v", kinds)
+	}
+	for name, kind := range expected {
+		if kinds[name] != kind {
+			t.Errorf("%s: expected %s, got %s", name, kind, kinds[name])
+		}
+	}
+
+	// a stripped cgo binary is still detected from the pclntab
+	if stripped := recoverCgo(nil, funcs, nil, nil); !stripped.Present || len(stripped.Functions) != 3 {
+		t.Errorf("stripped binary: %+v", stripped)
+	}
+
+	// text symbols of a pure Go binary aren't reported, the build allowing cgo doesn't make it a cgo binary
+	if pure := recoverCgo(nil, funcs[3:], syms[:1], map[string]string{"CGO_ENABLED": "1"}); pure.Present || len(pure.Functions) > 0 {
+		t.Errorf("pure Go binary detected as cgo: %+v", pure)
+	}
+
+	// newer toolchains name the wrapper without its package, the export table names the C side
+	unprefixed := recoverCgo(nil, []gosym.Func{{Entry: 0x1000, End: 0x1010, Sym: &gosym.Sym{Name: "_cgoexp_fe013914fe61_Add"}}}, nil, nil)
+	if len(unprefixed.Functions) != 1 || unprefixed.Functions[0].Kind != cgoExport || unprefixed.Functions[0].CName != "Add" {
+		t.Errorf("unprefixed wrapper: %+v", unprefixed.Functions)
+	}
+	exports := []objfile.Export{{Name: "Add", VA: 0x2000}, {Name: "_cgo_panic", VA: 0x2100}, {Name: "crosscall2", VA: 0x2200}}
+	entryPoints := exportedEntryPoints(unprefixed, exports, nil, nil)
+	if len(entryPoints) != 1 || entryPoints[0] != (CgoExport{Name: "Add", VA: 0x2000, GoFunction: "_cgoexp_fe013914fe61_Add"}) {
+		t.Errorf("unexpected entry points %+v", entryPoints)
+	}
+	goFuncs := []gosym.Func{{Entry: 0x1000, End: 0x1010, Sym: &gosym.Sym{Name: "_cgoexp_fe013914fe61_Add"}}, {Entry: 0x1100, End: 0x1110, Sym: &gosym.Sym{Name: "main.Add"}}}
+	entryPoints = exportedEntryPoints(unprefixed, exports, goFuncs, nil)
+	if len(entryPoints) != 1 || entryPoints[0].Function != "main.Add" || entryPoints[0].FunctionVA != 0x1100 {
+		t.Errorf("unexpected entry points %+v", entryPoints)
+	}
+
+	// a stripped c-shared library, its Add is small enough to be inlined into the wrapper
+	shared, err := Extract(context.Background(), "../test/weirdbins/cgo_shared_lin", Options{NoFunctions: true})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if !shared.Cgo.Present || shared.BuildMode != "c-shared" || len(shared.Cgo.Exports) != 2 {
+		t.Fatalf("unexpected cgo metadata %+v", shared.Cgo)
+	}
+	for _, export := range shared.Cgo.Exports {
+		if export.Function != "main."+export.Name || !strings.HasSuffix(export.GoFunction, "_"+export.Name) || export.VA == 0 {
+			t.Errorf("unexpected export %+v", export)
+		}
+		if inlined := export.Name == "Add"; export.Inlined != inlined || (export.FunctionVA == 0) != inlined {
+			t.Errorf("expected %s to be inlined %t, got %+v", export.Name, inlined, export)
+		}
+	}
+
+	data, err := Extract(context.Background(), "../test/weirdbins/hello_lin", Options{NoFunctions: true})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if data.Cgo.Present {
+		t.Errorf("pure Go binary detected as cgo")
+	}
+}
+
+func TestRecoverPackages(t *testing.T) {
+	known := map[string]bool{"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc": true}
+	for name, expected := range map[string]string{
+		"main.main":                                   "main",
+		"net/http.(*Server).Serve.func1":              "net/http",
+		"(*github.com/foo/bar.Type).Method":           "github.com/foo/bar",
+		"github.com/foo/bar.Map[go.shape.string].Get": "github.com/foo/bar",
+		"slices.Sort[go.shape.[]github.com/x/y.T]":    "slices",
+		"gopkg.in/yaml%2ev2.Marshal":                  "gopkg.in/yaml.v2",
+		"go.uber.org/zap.New":                         "go.uber.org/zap",
+		"github.com/foo/bar.v2/baz.F":                 "github.com/foo/bar.v2/baz",
+		// promoted unexported methods of embedded types of other packages
+		"github.com/alecthomas/template.(*Template).github.com/alecthomas/template/parse.add": "github.com/alecthomas/template",
+		"net.addrPortUDPAddr.net/netip.isZero":                                                "net",
+		// dotted elements before the last only resolve through the known paths
+		"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc.Extract": "go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc",
+		"type:.eq.main.T":                  "",
+		"type..eq.github.com/foo/bar.T":    "",
+		"go.itab.*os.File,io.Writer":       "",
+		"go.shape.*github.com/foo/bar.T.M": "",
+	} {
+		if pkg := functionPackage(name, known); pkg != expected {
+			t.Errorf("expected the package of %s to be %q, got %q", name, expected, pkg)
+		}
+	}
+
+	for file, expected := range map[string]string{
+		"/root/go/pkg/mod/github.com/!azure/go-autorest@v14.2.0/autorest/client.go": "github.com/Azure/go-autorest/autorest",
+		"k8s.io/api@v0.24.0/core/v1/types.go":                                       "k8s.io/api/core/v1",
+		"/usr/local/go/src/net/http/server.go":                                      "net/http",
+		"/home/u/proj/main.go":                                                      "",
+		"<autogenerated>":                                                           "",
+	} {
+		if pkg, _ := filePackage(file); pkg != expected {
+			t.Errorf("expected the package of %s to be %q, got %q", file, expected, pkg)
+		}
+	}
+
+	data, err := Extract(context.Background(), "../test/weirdbins/elf_data_rel_ro_pclntab", Options{Types: true})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	origins := make(map[string]string)
+	for _, pkg := range data.Packages {
+		origins[pkg.Path] = pkg.Origin + " " + strings.Join(pkg.Sources, ",")
+	}
+	for pkg, expected := range map[string]string{
+		"main":          "main functions,types",
+		"net":           "std functions,types,files",
+		"internal/poll": "std functions,types,files",
+		"github.com/docker/libnetwork/vendor/github.com/ishidawataru/sctp": "vendored functions,types,files",
+		"github.com/docker/libnetwork/cmd/proxy":                           "unknown files",
+	} {
+		if origins[pkg] != expected {
+			t.Errorf("expected %s to be %q, got %q", pkg, expected, origins[pkg])
+		}
+	}
+}
+
+func TestLikelyGo(t *testing.T) {
+	// the marker straddles the first two windows
+	split := make([]byte, sniffWindow+64)
+	copy(split, "\x7fELF")
+	copy(split[sniffWindow-5:], "\xff Go buildinf:")
+	pclntab := append([]byte("MZ\x90\x00"), 0xf1, 0xff, 0xff, 0xff, 0x00, 0x00, 0x01, 0x08)
+	badQuantum := append([]byte("MZ\x90\x00"), 0xf1, 0xff, 0xff, 0xff, 0x00, 0x00, 0x03, 0x08)
+
+	for name, c := range map[string]struct {
+		data     []byte
+		expected bool
+	}{
+		"split marker":   {split, true},
+		"pclntab":        {pclntab, true},
+		"bad pc quantum": {badQuantum, false},
+		"no marker":      {[]byte("\x7fELF\x02\x01\x01\x00"), false},
+		"not executable": {[]byte("#!/bin/sh\n\xff Go buildinf:"), false},
+		"tinygo":         {[]byte("\x00asm\x01\x00\x00\x00tinygo0.30.0"), true},
+		"tinygo version": {[]byte("\x7fELF\x02\x01\x01\x00tinygo version 0.30.0 linux/amd64"), true},
+		"empty":          {nil, false},
+	} {
+		if likely, err := LikelyGo(bytes.NewReader(c.data)); err != nil || likely != c.expected {
+			t.Errorf("%s: expected %v, got %v, %v", name, c.expected, likely, err)
+		}
+	}
+
+	f, err := os.Open("../test/weirdbins/GoReSym_garbled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if likely, err := LikelyGo(f); !likely || err != nil {
+		t.Errorf("expected garble's binary to look like Go, got %v, %v", likely, err)
+	}
+}
+
+func TestRawStructures(t *testing.T) {
+	cases := []struct {
+		name       string
+		moduleData uint64
+		pclntab    uint64 // the size of .gopclntab
+		typelinks  uint64
+	}{
+		{"hello_lin", 456, 0x5f940, 1844},
+		{"interfaces_lin", 592, 0x64590, 1448},
+		{"mips_stripped_lin", 296, 369752, 1432},
+		// a probed moduledata, 1.27 records the end of the pclntab and has no typelinks
+		{"go127_lin", 472, 0x9c033, 0},
+	}
+	for _, c := range cases {
+		report, err := Extract(context.Background(), "../test/weirdbins/"+c.name, Options{})
+		if err != nil {
+			t.Fatalf("%s: GoReSym failed: %s", c.name, err)
+		}
+		structures, err := report.RawStructures()
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		data, err := os.ReadFile("../test/weirdbins/" + c.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		found := make(map[string]objfile.RawStructure)
+		for _, structure := range structures {
+			found[structure.Name] = structure
+			if structure.FileOffset == nil || !bytes.Equal(structure.Data, data[*structure.FileOffset:*structure.FileOffset+structure.Size]) {
+				t.Errorf("%s: expected the %s to be the bytes of the file at its offset", c.name, structure.Name)
+			}
+		}
+		if got := found["moduledata"]; got.VA != report.ModuleMeta.VA || got.Size != c.moduleData || got.Section == "" {
+			t.Errorf("%s: expected the %d bytes of the moduledata at 0x%x, got %+v", c.name, c.moduleData, report.ModuleMeta.VA, got)
+		}
+		if got := found["pclntab"]; got.VA != report.TabMeta.VA || got.Size != c.pclntab || got.Section != ".gopclntab" {
+			t.Errorf("%s: expected the pclntab to be .gopclntab, got %+v", c.name, got)
+		}
+		if got, ok := found["typelinks"]; ok != (c.typelinks != 0) || (ok && (got.Size != c.typelinks || got.Section != ".typelink")) {
+			t.Errorf("%s: expected the typelinks to be .typelink, got %+v", c.name, got)
+		}
+		if got := found["buildinfo"]; got.Section != ".go.buildinfo" || !bytes.HasPrefix(got.Data, []byte("\xff Go buildinf:")) {
+			t.Errorf("%s: expected the build info blob, got %+v", c.name, got)
+		}
+
+		report.Close()
+		if _, err := report.RawStructures(); err == nil {
+			t.Errorf("%s: expected a closed Report to have nothing to read", c.name)
+		}
+	}
+}
+
+func TestFunctionKind(t *testing.T) {
+	wrapperID := &FuncID{Value: 22, Name: "wrapper"}
+	cases := []struct {
+		fn       FuncMetadata
+		std      bool
+		tailJump bool
+		kind     string
+	}{
+		{FuncMetadata{FullName: "main.main", PackageName: "main", SourceFile: "/src/main.go"}, false, false, KindUser},
+		{FuncMetadata{FullName: "main.(*Square).Name", PackageName: "main", SourceFile: "/src/main.go"}, false, false, KindUser},
+		{FuncMetadata{FullName: "main.main.func1", PackageName: "main", SourceFile: "/src/main.go"}, false, false, KindUser},
+		{FuncMetadata{FullName: "main.(*Stack[go.shape.int]).Push", PackageName: "main", SourceFile: "/src/main.go"}, false, false, KindUser},
+		{FuncMetadata{FullName: "main.Map[go.shape.int,go.shape.string]", PackageName: "main", SourceFile: "/src/main.go"}, false, false, KindUser},
+		{FuncMetadata{FullName: "fmt.Println", PackageName: "fmt", SourceFile: "/go/src/fmt/print.go"}, true, false, KindStd},
+		// method values, of the standard library too, and a method named like the suffix isn't one
+		{FuncMetadata{FullName: "main.Square.Area-fm", PackageName: "main", SourceFile: autogeneratedSource}, false, false, KindWrapper},
+		{FuncMetadata{FullName: "runtime.(*itabTableType).add-fm", PackageName: "runtime", SourceFile: autogeneratedSource}, true, false, KindWrapper},
+		{FuncMetadata{FullName: "main.T.fm", PackageName: "main", SourceFile: "/src/main.go"}, false, false, KindUser},
+		// the *T methods of the T ones, of generic types and of types whose package path has dots
+		{FuncMetadata{FullName: "main.(*Square).Area", PackageName: "main", SourceFile: autogeneratedSource}, false, false, KindThunk},
+		{FuncMetadata{FullName: "main.(*Pair[go.shape.int,go.shape.string]).Key", PackageName: "main", SourceFile: autogeneratedSource}, false, false, KindThunk},
+		{FuncMetadata{FullName: "github.com/x/y.(*T).String", PackageName: "github.com/x/y", SourceFile: autogeneratedSource}, false, false, KindThunk},
+		{FuncMetadata{FullName: "main.Shape.Area", PackageName: "main", SourceFile: autogeneratedSource}, false, false, KindThunk},
+		// the ABI wrappers of functions, ex: the ABI0 one of a linkname alias
+		{FuncMetadata{FullName: "runtime.osinit", PackageName: "runtime", SourceFile: autogeneratedSource}, true, false, KindWrapper},
+		{FuncMetadata{FullName: "github.com/x/y.Run", PackageName: "github.com/x/y", SourceFile: autogeneratedSource}, false, false, KindWrapper},
+		// type functions, linker symbols and the defer, go and range bodies
+		{FuncMetadata{FullName: "type:.eq.main.Square", SourceFile: autogeneratedSource}, true, false, KindGenerated},
+		{FuncMetadata{FullName: "type..eq.main.Square", SourceFile: autogeneratedSource}, true, false, KindGenerated},
+		{FuncMetadata{FullName: "type:.hash.[2]interface {}", SourceFile: autogeneratedSource}, true, false, KindGenerated},
+		{FuncMetadata{FullName: "go:buildid"}, true, false, KindGenerated},
+		{FuncMetadata{FullName: "go.buildid"}, true, false, KindGenerated},
+		{FuncMetadata{FullName: "main.main.deferwrap2", PackageName: "main", SourceFile: "/src/main.go", FuncID: wrapperID}, false, false, KindGenerated},
+		{FuncMetadata{FullName: "runtime.gcenable.gowrap1", PackageName: "runtime", SourceFile: "/go/src/runtime/mgc.go", FuncID: wrapperID}, true, false, KindGenerated},
+		{FuncMetadata{FullName: "main.main.func3", PackageName: "main", SourceFile: "/src/main.go", FuncID: wrapperID}, false, false, KindGenerated},
+		{FuncMetadata{FullName: "main.main-range1", PackageName: "main", SourceFile: "/src/main.go"}, false, false, KindGenerated},
+		{FuncMetadata{FullName: "main.walk-range2-range1", PackageName: "main", SourceFile: "/src/main.go"}, false, false, KindGenerated},
+		// a package path starting with go. is a module's
+		{FuncMetadata{FullName: "go.uber.org/zap.New", PackageName: "go.uber.org/zap", SourceFile: "/mod/zap/logger.go"}, false, false, KindUser},
+		{FuncMetadata{FullName: "main.deferwrap", PackageName: "main", SourceFile: "/src/main.go"}, false, false, KindUser},
+		// assembly, by its source or the arguments it doesn't declare, even when the linker marked it a wrapper
+		{FuncMetadata{FullName: "runtime.memmove", PackageName: "runtime", SourceFile: "/go/src/runtime/memmove_amd64.s", ArgsSize: -1}, true, false, KindAssemblyStub},
+		{FuncMetadata{FullName: "gogo", ArgsSize: -1}, true, false, KindAssemblyStub},
+		{FuncMetadata{FullName: "runtime.call16", PackageName: "runtime", SourceFile: "/go/src/runtime/asm_amd64.s", FuncID: wrapperID}, true, false, KindAssemblyStub},
+		// garbled names: the linker's mark, then the shape of the code
+		{FuncMetadata{FullName: "Ab3xQ.Zk9f", PackageName: "Ab3xQ", SourceFile: "Xy1.go", Obfuscated: true, FuncID: wrapperID}, false, false, KindWrapper},
+		{FuncMetadata{FullName: "Ab3xQ.Zk9f", PackageName: "Ab3xQ", SourceFile: "Xy1.go", Obfuscated: true}, false, true, KindWrapper},
+		{FuncMetadata{FullName: "Ab3xQ.Zk9f", PackageName: "Ab3xQ", SourceFile: "Xy1.go", Obfuscated: true, MaxFrameSize: 24}, false, true, KindUser},
+		{FuncMetadata{FullName: "Ab3xQ.Zk9f", PackageName: "Ab3xQ", SourceFile: "Xy1.go", Obfuscated: true}, false, false, KindUser},
+		// an unobfuscated tiny function tail jumping is user code
+		{FuncMetadata{FullName: "main.forward", PackageName: "main", SourceFile: "/src/main.go"}, false, true, KindUser},
+	}
+	for _, c := range cases {
+		tailJump := func() bool { return c.tailJump }
+		if got := functionKind(&c.fn, c.std, tailJump); got != c.kind {
+			t.Errorf("%s: expected %s, got %s", c.fn.FullName, c.kind, got)
+		}
+	}
+}
+
+func TestFunctionKinds(t *testing.T) {
+	// a program with methods and the method values of them, a defer, a go statement, a range over func and generic functions and
+	// methods, built with GOEXPERIMENT=rangefunc
+	report, err := Extract(context.Background(), "../test/weirdbins/kinds_lin", Options{})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	report.Close()
+
+	kinds := make(map[string]string)
+	for _, fn := range report.UserFunctions {
+		kinds[fn.FullName] = fn.Kind
+	}
+	expected := map[string]string{
+		"main.main":                              KindUser,
+		"main.Square.Area":                       KindUser,
+		"main.(*Square).Name":                    KindUser,
+		"main.(*Square).Area":                    KindThunk,
+		"main.Square.Area-fm":                    KindWrapper,
+		"main.(*Square).Name-fm":                 KindWrapper,
+		"main.main.deferwrap2":                   KindGenerated,
+		"main.main.gowrap1":                      KindGenerated,
+		"main.Count.func1":                       KindUser,
+		"main.Map[go.shape.int,go.shape.string]": KindUser,
+		"main.(*Stack[go.shape.int]).Push":       KindUser,
+	}
+	for name, kind := range expected {
+		if kinds[name] != kind {
+			t.Errorf("expected %s to be %s, got %q", name, kind, kinds[name])
+		}
+	}
+
+	// the counts are of the functions listed, the standard library isn't without StdFunctions
+	counts := make(map[string]int)
+	for _, fn := range report.UserFunctions {
+		counts[fn.Kind]++
+	}
+	if !reflect.DeepEqual(counts, report.FunctionKinds) || report.FunctionKinds[KindStd] != 0 || report.FunctionKinds[KindUser] >= len(report.UserFunctions) {
+		t.Errorf("expected the kinds of the user functions counted, got %v for %v", report.FunctionKinds, counts)
+	}
+}
+
+func TestPCData(t *testing.T) {
+	tests := []struct {
+		path    string
+		pattern string
+		std     bool
+		// the points seen in the functions kept, none before 1.14
+		points []string
+	}{
+		{"../test/weirdbins/kinds_lin", `^main\.main$`, false, []string{objfile.PointSafe, objfile.PointUnsafe}},
+		{"../test/weirdbins/hello_lin", `^main\.main$`, false, []string{objfile.PointSafe, objfile.PointUnsafe}},
+		// the atomics of arm64 are restartable sequences
+		{"../test/weirdbins/chained_fixups_macho", `^runtime\.`, true, []string{objfile.PointSafe, objfile.PointUnsafe, objfile.PointRestart1, objfile.PointRestart2}},
+		{"../test/weirdbins/fmtisfun_lin", `^main\.main$`, false, nil},
+	}
+	for _, test := range tests {
+		filter := &objfile.NameFilter{Include: []*regexp.Regexp{regexp.MustCompile(test.pattern)}}
+		report, err := Extract(context.Background(), test.path, Options{PCData: true, StdFunctions: test.std, FuncFilter: filter})
+		if err != nil {
+			t.Fatalf("%s: GoReSym failed: %s", test.path, err)
+		}
+		report.Close()
+
+		functions := append(report.UserFunctions, report.StdFunctions...)
+		if len(functions) == 0 {
+			t.Fatalf("%s: expected the functions of %s", test.path, test.pattern)
+		}
+		seen := make(map[string]bool)
+		for _, fn := range functions {
+			if fn.PCData == nil {
+				t.Fatalf("%s: expected the pcdata of %s", test.path, fn.FullName)
+			}
+			// the ranges follow each other from the entry
+			pc := fn.Start
+			for _, point := range fn.PCData.UnsafePoints {
+				if point.Start != pc || point.End <= point.Start {
+					t.Fatalf("%s: expected the unsafe points of %s to follow each other, got %+v", test.path, fn.FullName, fn.PCData.UnsafePoints)
+				}
+				pc = point.End
+				seen[point.Point] = true
+			}
+		}
+		for _, point := range test.points {
+			if !seen[point] {
+				t.Errorf("%s: expected %s points, got %v", test.path, point, seen)
+			}
+		}
+		if test.points == nil && len(seen) != 0 {
+			t.Errorf("%s: expected no unsafe points before 1.14, got %v", test.path, seen)
+		}
+		// main.main calls, there are stack maps for those
+		if !test.std && (functions[0].PCData.ArgsStackMaps == 0 || functions[0].PCData.LocalsStackMaps == 0) {
+			t.Errorf("%s: expected the stack maps of main.main, got %+v", test.path, functions[0].PCData)
+		}
+	}
+
+	report, err := Extract(context.Background(), "../test/weirdbins/kinds_lin", Options{})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	report.Close()
+	for _, fn := range report.UserFunctions {
+		if fn.PCData != nil {
+			t.Fatalf("expected no pcdata without the option, got %+v for %s", fn.PCData, fn.FullName)
+		}
+	}
+}
+
+func TestDetectHooks(t *testing.T) {
+	original := "../test/weirdbins/hello_lin"
+	data, err := os.ReadFile(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clean, err := Extract(context.Background(), original, Options{NoFunctions: true, DetectHooks: true})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	entries := make(map[string]uint64)
+	for _, fn := range clean.pclntab.Funcs {
+		entries[fn.Name] = fn.Entry
+	}
+	clean.Close()
+	if len(clean.Hooks) != 0 {
+		t.Fatalf("expected no hooks in the original, got %+v", clean.Hooks)
+	}
+
+	// the first segment maps the file from its start, the Go text is in it
+	patch := func(name string, code []byte) {
+		copy(data[entries[name]-0x400000:], code)
+	}
+	jump := func(name string, target uint64) []byte {
+		return binary.LittleEndian.AppendUint32([]byte{0xe9}, uint32(target-(entries[name]+5)))
+	}
+	// mov rax, 0x7f0000001000; jmp rax
+	patch("main.main", []byte{0x48, 0xb8, 0, 0x10, 0, 0, 0, 0x7f, 0, 0, 0xff, 0xe0})
+	patch("runtime.newproc", jump("runtime.newproc", 0x10000000))
+	// into the executable segment before the Go text, ex: a PLT
+	patch("runtime.gopanic", jump("runtime.gopanic", 0x400100))
+	patch("runtime.morestack", []byte{0xcc})
+	hooked := t.TempDir() + "/hooked_lin"
+	if err := os.WriteFile(hooked, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, compare := range []string{"", original} {
+		report, err := Extract(context.Background(), hooked, Options{NoFunctions: true, DetectHooks: true, CompareFile: compare})
+		if err != nil {
+			t.Fatalf("GoReSym failed: %s", err)
+		}
+		report.Close()
+		hooks := make(map[string]objfile.Hook)
+		for _, hook := range report.Hooks {
+			hooks[hook.Function] = hook
+		}
+		expected := map[string]string{"main.main": objfile.HookIndirectJump, "runtime.newproc": objfile.HookJump}
+		if len(compare) > 0 {
+			// the original tells the jump to a known module and the breakpoint apart from the code
+			expected["runtime.gopanic"] = objfile.HookModified
+			expected["runtime.morestack"] = objfile.HookModified
+		}
+		if len(hooks) != len(expected) {
+			t.Fatalf("compare %q: expected the hooks %v, got %+v", compare, expected, report.Hooks)
+		}
+		for name, kind := range expected {
+			if hooks[name].Kind != kind || (len(compare) > 0) != (len(hooks[name].Original) > 0) {
+				t.Errorf("compare %q: expected %s to be a %s hook, got %+v", compare, name, kind, hooks[name])
+			}
+		}
+		if hook := hooks["main.main"]; hook.Target != 0x7f0000001000 || !strings.HasPrefix(hook.Prologue, "48b800100000007f0000ffe0") {
+			t.Errorf("compare %q: expected the target and the bytes of the hook, got %+v", compare, hook)
+		}
+		if hook := hooks["runtime.gopanic"]; len(compare) > 0 && hook.Target != 0x400100 {
+			t.Errorf("expected the jump into the segment reported with the difference, got %+v", hook)
+		}
+	}
+
+	if _, err := Extract(context.Background(), hooked, Options{NoFunctions: true, DetectHooks: true, CompareFile: "../test/weirdbins/missing"}); err == nil {
+		t.Errorf("expected a missing compare file to fail")
+	}
+}
+
+func TestRuntimeGlobals(t *testing.T) {
+	globals := func(path string) map[string]objfile.RuntimeGlobal {
+		report, err := Extract(context.Background(), path, Options{NoFunctions: true})
+		if err != nil {
+			t.Fatalf("%s: GoReSym failed: %s", path, err)
+		}
+		report.Close()
+		byName := make(map[string]objfile.RuntimeGlobal)
+		for _, global := range report.RuntimeGlobals {
+			byName[global.Name] = global
+		}
+		return byName
+	}
+
+	// the references of the stripped build find what the symbols of the other give
+	symbols, references := globals("../test/weirdbins/fmtisfun_lin"), globals("../test/weirdbins/fmtisfun_lin_stripped")
+	for _, name := range []string{"runtime.allgs", "runtime.allglen", "runtime.allm", "runtime.sched", "runtime.g0", "runtime.m0"} {
+		if symbols[name].Method != "symtab" || references[name].Method != "references" || symbols[name].VA != references[name].VA {
+			t.Errorf("expected %s at the same VA by the symtab and the references, got %+v and %+v", name, symbols[name], references[name])
+		}
+	}
+	if _, ok := symbols["runtime.allgptr"]; ok {
+		t.Errorf("expected no allgptr in 1.8")
+	}
+
+	kinds := globals("../test/weirdbins/kinds_lin")
+	if global := kinds["runtime.allgptr"]; global.VA == 0 || global.Method != "references" || len(global.Evidence) != 2 || global.Evidence[0] != "runtime.forEachGRace" {
+		t.Errorf("expected allgptr from forEachGRace, got %+v", global)
+	}
+	if len(kinds) != 7 {
+		t.Errorf("expected every global of 1.22, got %+v", kinds)
+	}
+}
+
+func TestRecordOrder(t *testing.T) {
+	report, err := Extract(context.Background(), "../test/weirdbins/kinds_lin", Options{StdFunctions: true, Types: true, FilePaths: true})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	report.Close()
+	if report.SchemaVersion != SchemaVersion {
+		t.Errorf("expected schema version %d, got %d", SchemaVersion, report.SchemaVersion)
+	}
+	for _, funcs := range [][]FuncMetadata{report.UserFunctions, report.StdFunctions} {
+		for i := 1; i < len(funcs); i++ {
+			if funcs[i-1].Start > funcs[i].Start {
+				t.Fatalf("expected the functions by entry, got %s at 0x%x before %s at 0x%x", funcs[i-1].FullName, funcs[i-1].Start, funcs[i].FullName, funcs[i].Start)
+			}
+		}
+	}
+	for _, types := range [][]objfile.Type{report.Types, report.Interfaces} {
+		for i := 1; i < len(types); i++ {
+			if previous := types[i-1]; previous.VA > types[i].VA || (previous.VA == types[i].VA && previous.Str > types[i].Str) {
+				t.Fatalf("expected the types by VA then name, got %s at 0x%x before %s at 0x%x", previous.Str, previous.VA, types[i].Str, types[i].VA)
+			}
+		}
+	}
+	if len(report.Files) == 0 || !sort.StringsAreSorted(report.Files) {
+		t.Errorf("expected the files sorted, got %d", len(report.Files))
+	}
+
+	// a failed extraction is versioned too
+	if failed, err := Extract(context.Background(), "../test/weirdbins/missing", Options{}); err == nil || failed.SchemaVersion != SchemaVersion {
+		t.Errorf("expected a versioned failure, got %d %v", failed.SchemaVersion, err)
+	}
+}
+
+// the report of TestFinishReport is compared to test/golden/order.json, rewrite it with 'go test -run TestFinishReport -update'
+var updateGolden = flag.Bool("update", false, "rewrite the golden files from the current output")
+
+func TestFinishReport(t *testing.T) {
+	// every sorted list out of order, with ties broken by a second key where it has one
+	unordered := func() Report {
+		call := func(va uint64, arg string) objfile.StringArgCallSite {
+			return objfile.StringArgCallSite{CallSite: objfile.CallSite{VA: va, Caller: "main.main"}, Arg: arg}
+		}
+		return Report{
+			UserFunctions: []FuncMetadata{{Start: 0x402000, FullName: "main.b"}, {Start: 0x401000, FullName: "main.a"}},
+			Types:         []objfile.Type{{VA: 0x4a0100, Str: "main.T"}, {VA: 0x4a0000, Str: "string"}, {VA: 0x4a0000, Str: "*main.T"}},
+			Interfaces:    []objfile.Type{{VA: 0x4b0100, Str: "io.Writer"}, {VA: 0x4b0000, Str: "error"}},
+			Itabs: []InterfaceItabs{
+				{Interface: "io.Writer", Implementations: []ItabMetadata{{Type: "*os.File", VA: 0x4c0200}, {Type: "*main.T", VA: 0x4c0100}}},
+				{Interface: "error", Implementations: []ItabMetadata{{Type: "*errors.errorString", VA: 0x4c0000}}},
+			},
+			Generics: []GenericFunction{
+				{Name: "main.Map", Instantiations: []GenericInstantiation{{FullName: "main.Map[string]", Start: 0x401100}, {FullName: "main.Map[int]", Start: 0x401200}},
+					Dictionaries: []GenericDictionary{{Name: "main..dict.Map[string]", VA: 0x4d0000}, {Name: "main..dict.Map[int]", VA: 0x4d0100}}},
+				{Name: "main.Filter"},
+			},
+			Files:      []string{"/src/main.go", "/src/a.go"},
+			Packages:   []PackageMetadata{{Path: "main", Origin: OriginMain}, {Path: "fmt", Origin: OriginStd}},
+			Hooks:      []objfile.Hook{{Function: "main.b", Entry: 0x402000}, {Function: "main.a", Entry: 0x401000}},
+			StdGlobals: []objfile.StdGlobal{{Name: "net/http.DefaultTransport", VA: 0x5a0100}, {Name: "net/http.DefaultClient", VA: 0x5a0000}},
+			EmbeddedFS: []objfile.EmbeddedFS{
+				{VA: 0x5b0100, Name: "main.web", Files: []objfile.EmbeddedFile{{Name: "web/"}, {Name: "web/index.html"}}},
+				{VA: 0x5b0000, Name: "main.assets"},
+			},
+			VersionStrings:       []objfile.VersionString{{Version: "go1.21.0", VA: 0x4e0100}, {Version: "go1.20", VA: 0x4e0000}},
+			SegmentOverlaps:      []objfile.SegmentOverlap{{Start: 0x600000, End: 0x601000}, {Start: 0x500000, End: 0x501000}},
+			ContextCallSites:     []objfile.CallSite{{VA: 0x401020, Callee: "context.WithTimeout"}, {VA: 0x401010, Callee: "context.WithCancel"}},
+			ExpvarNames:          []objfile.StringArgCallSite{call(0x401040, "requests"), call(0x401030, "errors")},
+			ReflectFieldAccesses: []objfile.StringArgCallSite{call(0x401060, "Token"), call(0x401050, "Name")},
+			TimeConstants:        []objfile.TimeConstant{{VA: 0x5c0100, Unix: 2}, {VA: 0x5c0000, Unix: 1}},
+			UnattributedStrings:  []objfile.StringLiteral{{VA: 0x4f0100, Value: "b"}, {VA: 0x4f0000, Value: "a"}},
+			// kept in the order they run
+			Inits: []objfile.InitTask{{VA: 0x5d0100, Package: "main"}, {VA: 0x5d0000, Package: "fmt"}},
+		}
+	}
+
+	report := unordered()
+	finishReport(&report)
+	document, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		t.Fatalf("failed to marshal the report: %s", err)
+	}
+	const golden = "../test/golden/order.json"
+	if *updateGolden {
+		if err := os.WriteFile(golden, document, 0644); err != nil {
+			t.Fatalf("failed to update %s: %s", golden, err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", golden, err)
+	}
+	if !bytes.Equal(document, expected) {
+		t.Errorf("output differs from %s, rerun with -update if the change is intended:\n%s", golden, document)
+	}
+
+	// sorting is idempotent
+	again := report
+	finishReport(&again)
+	if !reflect.DeepEqual(again, report) {
+		t.Errorf("expected a sorted report to stay as it is")
+	}
+}
+
+func TestCallSites(t *testing.T) {
+	const path = "../test/weirdbins/callsites_lin"
+	report, err := Extract(context.Background(), path, Options{})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	report.Close()
+	if report.ContextCallSites != nil || report.ReflectFieldAccesses != nil || report.ExpvarNames != nil {
+		t.Errorf("expected no call sites without the option, got %+v %+v %+v", report.ContextCallSites, report.ReflectFieldAccesses, report.ExpvarNames)
+	}
+
+	report, err = Extract(context.Background(), path, Options{CallSites: true})
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	report.Close()
+	if sites := report.ContextCallSites; len(sites) != 1 || sites[0].Caller != "main.beacon" || sites[0].Callee != "context.WithTimeout" {
+		t.Errorf("expected the context.WithTimeout of main.beacon, got %+v", sites)
+	}
+	if sites := report.ReflectFieldAccesses; len(sites) != 1 || sites[0].Caller != "main.main" || sites[0].Arg != "Server" {
+		t.Errorf("expected the FieldByName of main.main, got %+v", sites)
+	}
+}
+
+func TestFingerprintTypes(t *testing.T) {
+	funcs := []gosym.Func{{Sym: &gosym.Sym{Name: "main.main"}}, {Sym: &gosym.Sym{Name: "fmt.Println"}}}
+	// the same functions, the types tell the programs apart
+	if metadataFingerprint("1.22", funcs, []string{"main.a"}) == metadataFingerprint("1.22", funcs, []string{"main.b"}) {
+		t.Errorf("expected programs with other types to have other fingerprints")
+	}
+	if metadataFingerprint("1.22", funcs, []string{"main.b", "main.a", "main.a"}) != metadataFingerprint("1.22", funcs, []string{"main.a", "main.b"}) {
+		t.Errorf("expected the fingerprint of the sorted type names")
+	}
+}
@@ -0,0 +1,58 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// originalEntries opens the file at path the image was loaded from, as on disk, and maps the name of each of its functions to its
+// entry. The file is the caller's to close.
+func originalEntries(ctx context.Context, path string, opts Options) (*objfile.File, map[string]uint64, error) {
+	file, err := objfile.OpenWith(path, objfile.OpenOptions{FatArch: opts.Arch})
+	if err != nil {
+		return nil, nil, classifyOpen(fmt.Errorf("invalid original file: %w", err))
+	}
+	original, err := extract(ctx, file, path, nil, newPhaseClock(), Options{NoFunctions: true, Arch: opts.Arch, Signatures: opts.Signatures})
+	if err != nil || original.pclntab == nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("the functions of the original file %s aren't recovered: %w", path, err)
+	}
+	entries := make(map[string]uint64, len(original.pclntab.Funcs))
+	for _, fn := range original.pclntab.Funcs {
+		entries[fn.Name] = fn.Entry
+	}
+	return file, entries, nil
+}
+
+// detectHooks checks the entries of the functions of table the filter keeps for hooks, see objfile.File.DetectHooks. The Go text is
+// that of every module, or the span of the functions without a moduledata. With opts.CompareFile they're compared to the file too.
+func detectHooks(ctx context.Context, file *objfile.File, table *gosym.Table, modules []ModuleMetadata, opts Options) ([]objfile.Hook, error) {
+	var funcs []gosym.Func
+	for _, fn := range table.Funcs {
+		if opts.FuncFilter.Keep(fn.Name) {
+			funcs = append(funcs, fn)
+		}
+	}
+	var texts []gosym.PCRange
+	for _, module := range modules {
+		texts = append(texts, gosym.PCRange{Start: module.TextVA, End: module.ETextVA})
+	}
+	if len(texts) == 0 && len(table.Funcs) > 0 {
+		texts = append(texts, gosym.PCRange{Start: table.Funcs[0].Entry, End: table.Funcs[len(table.Funcs)-1].End})
+	}
+
+	var original *objfile.File
+	var entries map[string]uint64
+	if len(opts.CompareFile) > 0 {
+		var err error
+		if original, entries, err = originalEntries(ctx, opts.CompareFile, opts); err != nil {
+			return nil, err
+		}
+		defer original.Close()
+	}
+	return file.DetectHooks(funcs, texts, original, entries), nil
+}
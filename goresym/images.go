@@ -0,0 +1,43 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"fmt"
+
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// selectImage is the embedded image of file that Options.Image picks, file itself for 0. file is closed when the image doesn't open,
+// else closing the image closes it.
+func selectImage(file *objfile.File, index int) (*objfile.File, error) {
+	if index == 0 {
+		return file, nil
+	}
+	image, err := file.OpenEmbeddedImage(index)
+	if err != nil {
+		file.Close()
+		return nil, classify(ErrNotGo, fmt.Errorf("invalid image: %w", err))
+	}
+	return image, nil
+}
+
+// embeddedImageOf is the embedded image the pclntab candidate tab is in, nil when it's the input's own. Its pclntab is the image's,
+// see Options.Image, a moduledata of the input may even point at it when the image is laid out like the input.
+func embeddedImageOf(file *objfile.File, embedded []objfile.EmbeddedImage, tab *objfile.PclntabCandidate) *objfile.EmbeddedImage {
+	if len(embedded) == 0 {
+		return nil
+	}
+	offset := tab.FileOffset
+	if !tab.Overlay {
+		var err error
+		if offset, err = file.VAToFileOffset(tab.PclntabVA); err != nil {
+			return nil
+		}
+	}
+	for i := range embedded {
+		if embedded[i].FileOffset <= offset && offset < embedded[i].FileOffset+embedded[i].Size {
+			return &embedded[i]
+		}
+	}
+	return nil
+}
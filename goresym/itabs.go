@@ -0,0 +1,64 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"sort"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// InterfaceItabs is an interface with the itabs of the types implementing it
+type InterfaceItabs struct {
+	Interface       string
+	Implementations []ItabMetadata
+}
+
+// ItabMetadata is the itab at VA of a type implementing an interface
+type ItabMetadata struct {
+	Type       string
+	VA         uint64
+	RVA        uint64
+	FileOffset *uint64
+	Methods    []ItabMethod
+}
+
+// ItabMethod is a method of the interface with the function of the type implementing it, Function is empty when VA is 0, never
+// called so left out by the linker, or isn't in a recovered function
+type ItabMethod struct {
+	Name     string
+	VA       uint64
+	Function string
+}
+
+// groupItabs lists the itabs by interface, sorted by the interface then the type names, with the methods resolved to the functions
+// of table
+func groupItabs(itabs []objfile.Itab, table *gosym.Table) []InterfaceItabs {
+	byInterface := make(map[string][]ItabMetadata)
+	for _, itab := range itabs {
+		impl := ItabMetadata{Type: itab.Type, VA: itab.VA}
+		for _, method := range itab.Methods {
+			resolved := ItabMethod{Name: method.Name, VA: method.VA}
+			if method.VA != 0 && table != nil {
+				if fn := table.PCToFunc(method.VA); fn != nil {
+					resolved.Function = fn.Name
+				}
+			}
+			impl.Methods = append(impl.Methods, resolved)
+		}
+		byInterface[itab.Interface] = append(byInterface[itab.Interface], impl)
+	}
+
+	var grouped []InterfaceItabs
+	for iface, impls := range byInterface {
+		sort.Slice(impls, func(i, j int) bool {
+			if impls[i].Type != impls[j].Type {
+				return impls[i].Type < impls[j].Type
+			}
+			return impls[i].VA < impls[j].VA
+		})
+		grouped = append(grouped, InterfaceItabs{Interface: iface, Implementations: impls})
+	}
+	sort.Slice(grouped, func(i, j int) bool { return grouped[i].Interface < grouped[j].Interface })
+	return grouped
+}
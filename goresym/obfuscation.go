@@ -0,0 +1,188 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// looksHashedPackage reports whether a package path looks like one rewritten by garble.
+// garble replaces the whole import path with a short base64-ish hash, ex: 'Dat5j9j7Fwyn'. Real paths have a '/' or '.' if they aren't a single lowercase word.
+func looksHashedPackage(pkg string) bool {
+	if len(pkg) < 6 || strings.ContainsAny(pkg, "/.") {
+		return false
+	}
+
+	hasUpper := false
+	hasDigitOrUnderscore := false
+	for _, c := range pkg {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			hasUpper = true
+		case c >= '0' && c <= '9', c == '_':
+			hasDigitOrUnderscore = true
+		case c >= 'a' && c <= 'z':
+		default:
+			return false
+		}
+	}
+
+	// by convention packages are lowercase, a hash almost always mixes case or digits in
+	return hasUpper || hasDigitOrUnderscore
+}
+
+// detectObfuscator guesses if the binary was obfuscated, and by what. Returns an empty string if it appears unobfuscated, along with the signs found.
+// The runtime and a handful of core packages are never renamed by garble, so a mix of normal standard library names and hashed packages is the tell.
+// Without that, hashed file names, runtime functions linked into hashed packages and literal decoding stubs must agree.
+func detectObfuscator(file *objfile.File, table *gosym.Table, hasBuildInfo bool) (obfuscator string, evidence []string) {
+	packages := make(map[string]bool)
+	linkedRuntime := 0
+	for _, fn := range table.Funcs {
+		pkg := fn.PackageName()
+		if pkg == "main" || isStdPackage(pkg) {
+			continue
+		}
+		packages[pkg] = true
+		if sourceFile, _, _ := table.PCToLine(fn.Entry); looksHashedPackage(pkg) && isRuntimeSource(sourceFile) {
+			linkedRuntime++
+		}
+	}
+
+	hashed := 0
+	for pkg := range packages {
+		if looksHashedPackage(pkg) {
+			hashed++
+		}
+	}
+
+	// a couple of oddly named local packages is not enough, majority must look hashed
+	hashedPackages := hashed >= 2 && hashed*2 >= len(packages)
+	if hashedPackages {
+		evidence = append(evidence, fmt.Sprintf("%d of %d non standard packages have hashed names", hashed, len(packages)))
+	}
+
+	signs := 0
+	hashedFiles := 0
+	for sourceFile := range table.Files {
+		if looksHashedFile(sourceFile) {
+			hashedFiles++
+		}
+	}
+	if hashedFiles >= 10 && hashedFiles*2 >= len(table.Files) {
+		signs++
+		evidence = append(evidence, fmt.Sprintf("%d of %d source files have hashed names", hashedFiles, len(table.Files)))
+	}
+	if linkedRuntime > 0 {
+		signs++
+		evidence = append(evidence, fmt.Sprintf("%d runtime functions are linked into packages with hashed names, the standard library packages they belong to are missing", linkedRuntime))
+	}
+	if stubs := countLiteralStubs(file, table.Funcs); stubs >= 8 {
+		signs++
+		evidence = append(evidence, fmt.Sprintf("%d closures look like literal decoding stubs", stubs))
+	}
+
+	if !hashedPackages && signs < 2 {
+		return "", nil
+	}
+	if !hasBuildInfo {
+		evidence = append(evidence, "the build info is stripped")
+	}
+	return "garble", evidence
+}
+
+// isRuntimeSource reports whether a source path is a file of the runtime, the GOROOT relative 'runtime/x.go' of a trimmed build or a full path
+func isRuntimeSource(sourceFile string) bool {
+	return strings.HasPrefix(sourceFile, "runtime/") || strings.Contains(sourceFile, "/src/runtime/")
+}
+
+// looksHashedFile reports whether a file table entry is a bare hashed name like 'f2BkFMVbEve.go'. garble renames the files of every package
+// it obfuscates, real entries are paths.
+func looksHashedFile(sourceFile string) bool {
+	base, found := strings.CutSuffix(sourceFile, ".go")
+	return found && looksHashedPackage(base)
+}
+
+// literal decoders are tiny, a closure much bigger than this is real code
+const maxLiteralStubSize = 0x400
+
+// countLiteralStubs counts the closures of hashed packages that look like garble's -literals decoders: a small func1 style closure that
+// rebuilds a string from obfuscated bytes, so makes a direct call to runtime.slicebytetostring.
+func countLiteralStubs(file *objfile.File, funcs []gosym.Func) int {
+	var closures []gosym.Func
+	for _, fn := range funcs {
+		if looksHashedPackage(fn.PackageName()) && strings.Contains(fn.Name, ".func") && fn.End > fn.Entry && fn.End-fn.Entry <= maxLiteralStubSize {
+			closures = append(closures, fn)
+		}
+	}
+	if len(closures) == 0 {
+		return 0
+	}
+
+	sites, err := file.FindCallSites(closures, funcs, map[string]bool{"runtime.slicebytetostring": true})
+	if err != nil {
+		return 0
+	}
+	stubs := make(map[uint64]bool)
+	for _, site := range sites {
+		stubs[site.CallerVA] = true
+	}
+	return len(stubs)
+}
+
+// recoverHashedStd works out which hashed packages are really the standard library. Two things give them away:
+// runtime functions pushed into them with linkname keep their runtime source files, only std packages receive them,
+// and anything the standard library calls directly is also standard library, std never imports user code.
+// Autogenerated functions and generic instantiations are left out as callers, they can call into the package of their type.
+func recoverHashedStd(file *objfile.File, table *gosym.Table) map[string]bool {
+	std := make(map[string]bool)
+	hashed := make(map[string][]gosym.Func)
+	packageAt := make(map[uint64]string)
+	var callers []gosym.Func
+	for _, fn := range table.Funcs {
+		pkg := fn.PackageName()
+		switch {
+		case looksHashedPackage(pkg):
+			hashed[pkg] = append(hashed[pkg], fn)
+			packageAt[fn.Entry] = pkg
+			if sourceFile, _, _ := table.PCToLine(fn.Entry); isRuntimeSource(sourceFile) {
+				std[pkg] = true
+			}
+		case len(pkg) > 0 && isStdPackage(pkg) && !strings.Contains(fn.Name, "["):
+			callers = append(callers, fn)
+		}
+	}
+	for pkg := range std {
+		callers = append(callers, hashed[pkg]...)
+	}
+
+	for len(callers) > 0 {
+		targets := make(map[string]bool)
+		for pkg, funcs := range hashed {
+			if !std[pkg] {
+				for _, fn := range funcs {
+					targets[fn.Name] = true
+				}
+			}
+		}
+		if len(targets) == 0 {
+			break
+		}
+
+		sites, err := file.FindCallSites(callers, table.Funcs, targets)
+		if err != nil {
+			break
+		}
+		callers = nil
+		for _, site := range sites {
+			pkg := packageAt[site.CalleeVA]
+			if !std[pkg] {
+				std[pkg] = true
+				callers = append(callers, hashed[pkg]...)
+			}
+		}
+	}
+	return std
+}
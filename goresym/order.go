@@ -0,0 +1,117 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"sort"
+
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// SchemaVersion is the version of the JSON layout of the Report, bumped on every change breaking its readers: a field renamed,
+// removed or changing its type or meaning. A field added isn't one.
+const SchemaVersion = 1
+
+// sortFunctions orders funcs by their entry
+func sortFunctions(funcs []FuncMetadata) {
+	sort.SliceStable(funcs, func(i, j int) bool {
+		return funcs[i].Start < funcs[j].Start
+	})
+}
+
+// sortTypes orders types by their VA, then their name
+func sortTypes(types []objfile.Type) {
+	sort.SliceStable(types, func(i, j int) bool {
+		if types[i].VA != types[j].VA {
+			return types[i].VA < types[j].VA
+		}
+		return types[i].Str < types[j].Str
+	})
+}
+
+// sortItabs orders the interfaces by name and the itabs of each by VA
+func sortItabs(itabs []InterfaceItabs) {
+	sort.SliceStable(itabs, func(i, j int) bool {
+		return itabs[i].Interface < itabs[j].Interface
+	})
+	for _, iface := range itabs {
+		impls := iface.Implementations
+		sort.SliceStable(impls, func(i, j int) bool {
+			return impls[i].VA < impls[j].VA
+		})
+	}
+}
+
+// sortCallSites orders calls by the VA of the call
+func sortCallSites(calls []objfile.StringArgCallSite) {
+	sort.SliceStable(calls, func(i, j int) bool {
+		return calls[i].VA < calls[j].VA
+	})
+}
+
+// finishReport stamps the SchemaVersion on r and orders its lists the same every run whatever order they were recovered in: the
+// functions by entry, the types, interfaces and the records of the data by VA, and the named ones by name. A few lists keep an order
+// of their own, the same every run too: the dependencies of the build info, the files of each embed.FS as embed sorts them, the Inits
+// in the order they run, RuntimeOffsets and RuntimeGlobals in the order of their tables, EmbeddedImages by Index, the Modules in the
+// order of the moduledata list, the Attempts in the order they were tried, Corruption by functab index, and Unavailable and
+// ObfuscationEvidence in the order they were checked.
+func finishReport(r *Report) {
+	r.SchemaVersion = SchemaVersion
+	sortFunctions(r.UserFunctions)
+	sortFunctions(r.StdFunctions)
+	sortTypes(r.Types)
+	sortTypes(r.Interfaces)
+	sortItabs(r.Itabs)
+	sort.Strings(r.Files)
+	for i := range r.Modules {
+		sortFunctions(r.Modules[i].UserFunctions)
+		sortFunctions(r.Modules[i].StdFunctions)
+		sortTypes(r.Modules[i].Types)
+		sortTypes(r.Modules[i].Interfaces)
+		sortItabs(r.Modules[i].Itabs)
+	}
+
+	sort.SliceStable(r.Packages, func(i, j int) bool {
+		return r.Packages[i].Path < r.Packages[j].Path
+	})
+	sort.SliceStable(r.Generics, func(i, j int) bool {
+		return r.Generics[i].Name < r.Generics[j].Name
+	})
+	for _, generic := range r.Generics {
+		insts, dicts := generic.Instantiations, generic.Dictionaries
+		sort.SliceStable(insts, func(i, j int) bool {
+			return insts[i].FullName < insts[j].FullName
+		})
+		sort.SliceStable(dicts, func(i, j int) bool {
+			return dicts[i].Name < dicts[j].Name
+		})
+	}
+	sort.SliceStable(r.Hooks, func(i, j int) bool {
+		return r.Hooks[i].Entry < r.Hooks[j].Entry
+	})
+	sort.SliceStable(r.StdGlobals, func(i, j int) bool {
+		if r.StdGlobals[i].VA != r.StdGlobals[j].VA {
+			return r.StdGlobals[i].VA < r.StdGlobals[j].VA
+		}
+		return r.StdGlobals[i].Name < r.StdGlobals[j].Name
+	})
+	sort.SliceStable(r.EmbeddedFS, func(i, j int) bool {
+		return r.EmbeddedFS[i].VA < r.EmbeddedFS[j].VA
+	})
+	sort.SliceStable(r.VersionStrings, func(i, j int) bool {
+		return r.VersionStrings[i].VA < r.VersionStrings[j].VA
+	})
+	sort.SliceStable(r.SegmentOverlaps, func(i, j int) bool {
+		return r.SegmentOverlaps[i].Start < r.SegmentOverlaps[j].Start
+	})
+	sort.SliceStable(r.ContextCallSites, func(i, j int) bool {
+		return r.ContextCallSites[i].VA < r.ContextCallSites[j].VA
+	})
+	sortCallSites(r.ExpvarNames)
+	sortCallSites(r.ReflectFieldAccesses)
+	sort.SliceStable(r.TimeConstants, func(i, j int) bool {
+		return r.TimeConstants[i].VA < r.TimeConstants[j].VA
+	})
+	sort.SliceStable(r.UnattributedStrings, func(i, j int) bool {
+		return r.UnattributedStrings[i].VA < r.UnattributedStrings[j].VA
+	})
+}
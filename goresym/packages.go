@@ -0,0 +1,209 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"net/url"
+	pathpkg "path"
+	"sort"
+	"strings"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+	"github.com/mandiant/GoReSym/objfile"
+	"github.com/mandiant/GoReSym/runtime/debug"
+)
+
+// the buckets of a package on top of those of classifySource, its PackageMetadata.Origin
+const (
+	OriginVendored = "vendored"
+	OriginUnknown  = "unknown"
+)
+
+// where a package was seen
+const (
+	packageSourceFunctions = "functions"
+	packageSourceTypes     = "types"
+	packageSourceFiles     = "files"
+)
+
+// a package linked into the binary, from the names of its functions, the package paths of its types with -t and the directories of
+// its source files
+type PackageMetadata struct {
+	Path       string
+	Origin     string   // std, main, dependency, vendored or unknown
+	Module     string   // module path for main and dependency packages, when known
+	Obfuscated bool     // path was rewritten by the detected obfuscator
+	Sources    []string // functions, types and files, where the package was seen
+}
+
+// isModulePathElem reports whether elem can be the first element of a module path, a domain like go.uber.org. Compiler generated
+// names aren't, ex: go.shape.*github.com/foo/bar.T or type..eq.github.com/foo/bar.T
+func isModulePathElem(elem string) bool {
+	if strings.Contains(elem, "..") {
+		return false
+	}
+	for _, c := range elem {
+		if !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') && c != '.' && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// functionPackage is the import path of the package of a function name, ex: github.com/foo/bar for github.com/foo/bar.(*Type).Method,
+// (*github.com/foo/bar.Type).Method, github.com/foo/bar.Map[...].Get and github.com/foo/bar.init.func1, and gopkg.in/yaml.v2 for
+// gopkg.in/yaml%2ev2.Marshal. The longest of the known packages the name starts with wins, else the path ends at the first '.' of
+// the last element. Compiler generated names have none.
+func functionPackage(name string, known map[string]bool) string {
+	name = strings.TrimLeft(name, "(*")
+	// the type arguments have paths of their own, ex: slices.Sort[go.shape.[]github.com/foo/bar.T]
+	if bracket := strings.IndexByte(name, '['); bracket >= 0 {
+		name = name[:bracket]
+	}
+	if strings.HasPrefix(name, "go:") || strings.HasPrefix(name, "type:") || strings.HasPrefix(name, "type.") {
+		return ""
+	}
+	unescape := func(pkg string) string {
+		if unescaped, err := url.PathUnescape(pkg); err == nil {
+			return unescaped
+		}
+		return pkg
+	}
+
+	// a path before the name of a promoted method is the package of the unexported method, ex:
+	// github.com/foo/bar.T.github.com/foo/baz.m, and an element before the last can have dots, ex: google.golang.org/grpc
+	for i := len(name) - 1; i > 0; i-- {
+		if name[i] == '.' && known[unescape(name[:i])] {
+			return unescape(name[:i])
+		}
+	}
+
+	slash := strings.IndexByte(name, '/')
+	if slash < 0 || !isModulePathElem(name[:slash]) {
+		// go.itab.*os.File,io.Writer and the like
+		if strings.HasPrefix(name, "go.") {
+			return ""
+		}
+		if dot := strings.IndexByte(name, '.'); dot > 0 {
+			return name[:dot]
+		}
+		return ""
+	}
+	// the linker escapes the dots of the last element, one before it with a dot reaches its '/' without another, ex:
+	// github.com/foo/bar.v2/baz.F
+	for i := slash + 1; i < len(name); i++ {
+		if name[i] != '.' {
+			continue
+		}
+		next := strings.IndexByte(name[i+1:], '/')
+		if next < 0 || strings.ContainsAny(name[i+1:i+1+next], ".()*") {
+			return unescape(name[:i])
+		}
+	}
+	return ""
+}
+
+// filePackage is the import path of the directory of a source file, from its module cache, vendor or GOPATH relative form, ex:
+// github.com/foo/bar for /root/go/pkg/mod/github.com/foo/bar@v1.0.0/bar.go. An absolute path outside of them doesn't tell.
+func filePackage(path string) (pkg string, vendored bool) {
+	candidates, vendored := relativeSourcePaths(path)
+	relative := candidates[len(candidates)-1]
+	if strings.HasPrefix(relative, "/") || strings.Contains(relative, ":") {
+		return "", false
+	}
+	dir := pathpkg.Dir(relative)
+	if dir == "." {
+		return "", false
+	}
+	// the directory of a module in the cache is module@version
+	if at := strings.IndexByte(dir, '@'); at > 0 {
+		rest := ""
+		if slash := strings.IndexByte(dir[at:], '/'); slash >= 0 {
+			rest = dir[at+slash:]
+		}
+		dir = dir[:at] + rest
+	}
+	return unescapeModulePath(dir), vendored
+}
+
+// recoverPackages lists the packages of funcs, types and files sorted by path, the functions funcFilter drops don't count. Packages
+// under a vendor directory are vendored, the others are bucketed like their functions, see classifySource, and unknown when nothing
+// tells.
+func recoverPackages(funcs []gosym.Func, types []objfile.Type, files map[string]*gosym.Obj, buildInfo *debug.BuildInfo, isStd func(string) bool, obfuscated bool, funcFilter *objfile.NameFilter) []PackageMetadata {
+	type evidence struct {
+		sources  map[string]bool
+		file     string
+		vendored bool
+	}
+	packages := make(map[string]*evidence)
+	add := func(pkg string, source string) *evidence {
+		found := packages[pkg]
+		if found == nil {
+			found = &evidence{sources: make(map[string]bool)}
+			packages[pkg] = found
+		}
+		found.sources[source] = true
+		return found
+	}
+
+	for _, typ := range types {
+		if len(typ.PkgPath) > 0 && !strings.HasPrefix(typ.PkgPath, "go.shape") {
+			add(typ.PkgPath, packageSourceTypes)
+		}
+	}
+	for file := range files {
+		if pkg, vendored := filePackage(file); len(pkg) > 0 {
+			found := add(pkg, packageSourceFiles)
+			found.vendored = found.vendored || vendored
+			// the map order is random, keep the same file whatever it is
+			if len(found.file) == 0 || file < found.file {
+				found.file = file
+			}
+		}
+	}
+
+	// the paths the types and files give resolve the ambiguous function names
+	known := make(map[string]bool)
+	for pkg := range packages {
+		known[pkg] = true
+	}
+	if buildInfo != nil {
+		known[buildInfo.Main.Path] = len(buildInfo.Main.Path) > 0
+		for _, dep := range buildInfo.Deps {
+			known[dep.Path] = true
+		}
+	}
+	for _, fn := range funcs {
+		if pkg := functionPackage(fn.Name, known); len(pkg) > 0 && funcFilter.Keep(fn.Name) {
+			add(pkg, packageSourceFunctions)
+		}
+	}
+
+	var result []PackageMetadata
+	for pkg, found := range packages {
+		meta := PackageMetadata{Path: pkg, Obfuscated: obfuscated && looksHashedPackage(pkg)}
+		switch {
+		// the standard library vendors its dependencies as vendor/golang.org/x/..., golang_org/x/... before 1.13
+		case isStd(pkg) || strings.HasPrefix(pkg, "vendor/") || strings.HasPrefix(pkg, "golang_org/"):
+			meta.Origin = OriginStd
+		case found.vendored || strings.Contains(pkg, "/vendor/"):
+			meta.Origin = OriginVendored
+		default:
+			meta.Origin, meta.Module = classifySource(found.file, pkg, buildInfo)
+			first, _, _ := strings.Cut(pkg, "/")
+			// the paths without a dot in the first element are reserved for the standard library, its newer packages aren't listed
+			if len(meta.Origin) == 0 && !strings.Contains(first, ".") && pkg != "main" && !meta.Obfuscated {
+				meta.Origin = OriginStd
+			} else if len(meta.Origin) == 0 {
+				meta.Origin = OriginUnknown
+			}
+		}
+		for _, source := range []string{packageSourceFunctions, packageSourceTypes, packageSourceFiles} {
+			if found.sources[source] {
+				meta.Sources = append(meta.Sources, source)
+			}
+		}
+		result = append(result, meta)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
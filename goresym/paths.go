@@ -0,0 +1,133 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"strings"
+
+	"github.com/mandiant/GoReSym/runtime/debug"
+)
+
+// the buckets a function's source can fall into, its FuncMetadata.Origin
+const (
+	OriginStd        = "std"
+	OriginMain       = "main"
+	OriginDependency = "dependency"
+)
+
+// unescapeModulePath reverses the module cache case encoding, ex: 'github.com/!azure/sdk' -> 'github.com/Azure/sdk'
+func unescapeModulePath(path string) string {
+	if !strings.Contains(path, "!") {
+		return path
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '!' && i+1 < len(path) && path[i+1] >= 'a' && path[i+1] <= 'z' {
+			b.WriteByte(path[i+1] - 'a' + 'A')
+			i++
+			continue
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
+
+// relativeSourcePaths strips the GOROOT, GOPATH, module cache, and vendor prefixes a path may carry, returning every import-path-relative form to try.
+// -trimpath paths are already relative and come back unchanged. The bool reports if the path was under a vendor directory.
+func relativeSourcePaths(path string) ([]string, bool) {
+	path = strings.ReplaceAll(path, "\\", "/")
+	candidates := []string{path}
+
+	if idx := strings.LastIndex(path, "/pkg/mod/"); idx >= 0 {
+		candidates = append(candidates, path[idx+len("/pkg/mod/"):])
+	}
+
+	vendored := false
+	if idx := strings.LastIndex(path, "/vendor/"); idx >= 0 {
+		candidates = append(candidates, path[idx+len("/vendor/"):])
+		vendored = true
+	} else if strings.HasPrefix(path, "vendor/") {
+		candidates = append(candidates, path[len("vendor/"):])
+		vendored = true
+	}
+
+	// GOROOT/src and GOPATH/src, any of them could be the root so try each
+	for idx := strings.Index(path, "/src/"); idx >= 0; {
+		candidates = append(candidates, path[idx+len("/src/"):])
+		next := strings.Index(path[idx+1:], "/src/")
+		if next < 0 {
+			break
+		}
+		idx += next + 1
+	}
+	return candidates, vendored
+}
+
+// hasPathPrefix reports whether path is prefix itself or is inside it, module 'a/b' must not match 'a/bc'
+func hasPathPrefix(path string, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// classifySource buckets a function as standard library, main module, or dependency. Returns the module path for the latter two when known.
+// With build info the module list is ground truth, the longest module path that prefixes the source path or package wins, so nested modules resolve to the innermost.
+// Without it, module cache style 'module@version/' paths are still recognized, and package main is assumed to be the main module.
+func classifySource(sourcePath string, pkg string, buildInfo *debug.BuildInfo) (origin string, module string) {
+	if len(pkg) > 0 && isStdPackage(pkg) {
+		return OriginStd, ""
+	}
+
+	candidates, vendored := relativeSourcePaths(sourcePath)
+	if len(pkg) > 0 {
+		candidates = append(candidates, pkg)
+	}
+
+	if buildInfo != nil {
+		best := ""
+		bestOrigin := ""
+		consider := func(modPath string, modOrigin string) {
+			if len(modPath) <= len(best) {
+				return
+			}
+			for _, candidate := range candidates {
+				if hasPathPrefix(unescapeModulePath(candidate), modPath) {
+					best = modPath
+					bestOrigin = modOrigin
+					return
+				}
+			}
+		}
+
+		if len(buildInfo.Main.Path) > 0 {
+			consider(buildInfo.Main.Path, OriginMain)
+		}
+		for _, dep := range buildInfo.Deps {
+			consider(dep.Path, OriginDependency)
+		}
+
+		if len(best) > 0 {
+			return bestOrigin, best
+		}
+	}
+
+	// module cache and -trimpath module files are 'module@version/file.go', absolute forms still carry the cache directory
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, "/") || strings.Contains(candidate, ":") {
+			continue
+		}
+		if at := strings.Index(candidate, "@"); at > 0 && strings.Contains(candidate[at:], "/") {
+			return OriginDependency, unescapeModulePath(candidate[:at])
+		}
+	}
+
+	if vendored {
+		return OriginDependency, ""
+	}
+
+	if pkg == "main" {
+		if buildInfo != nil {
+			return OriginMain, buildInfo.Main.Path
+		}
+		return OriginMain, ""
+	}
+	return "", ""
+}
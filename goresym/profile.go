@@ -0,0 +1,49 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import "time"
+
+// Timings is the wall clock time spent in each extraction phase, in milliseconds. The command only emits them with -profile.
+type Timings struct {
+	Open          float64 // open, build id, build info, version detection
+	PclntabScan   float64 // pclntab candidate search and parsing, excluding ModuleData
+	ModuleData    float64 // moduledata signature scan and parsing, summed over every pclntab candidate
+	Types         float64
+	Analysis      float64 // everything between type reconstruction and the function walk, ex: -timestamps
+	Functions     float64 // function walk, call site scans, fingerprint
+	Serialization float64 // json encoding of the results
+	Total         float64
+}
+
+// AddSerialization counts d spent encoding the Report into Serialization and Total, the extraction can't time what comes after it
+func (t *Timings) AddSerialization(d time.Duration) {
+	t.Serialization = milliseconds(d)
+	t.Total = milliseconds(time.Duration(t.Total*float64(time.Millisecond)) + d)
+}
+
+// phaseClock measures consecutive phases
+type phaseClock struct {
+	start time.Time
+	last  time.Time
+}
+
+func newPhaseClock() *phaseClock {
+	now := time.Now()
+	return &phaseClock{start: now, last: now}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// lap returns the time since the previous lap, or since the clock started
+func (c *phaseClock) lap() time.Duration {
+	now := time.Now()
+	elapsed := now.Sub(c.last)
+	c.last = now
+	return elapsed
+}
+
+func (c *phaseClock) total() time.Duration {
+	return time.Since(c.start)
+}
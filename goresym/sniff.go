@@ -0,0 +1,71 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"bytes"
+	"io"
+
+	"rsc.io/binaryregexp"
+)
+
+// the magics of the executable formats Extract opens: ELF, PE, Mach-O in both byte orders and widths, fat Mach-O and WebAssembly
+var executableMagics = [][]byte{
+	[]byte("\x7fELF"), []byte("MZ"),
+	{0xfe, 0xed, 0xfa, 0xce}, {0xce, 0xfa, 0xed, 0xfe}, {0xfe, 0xed, 0xfa, 0xcf}, {0xcf, 0xfa, 0xed, 0xfe},
+	{0xca, 0xfe, 0xba, 0xbe}, []byte("\x00asm"),
+}
+
+// a Go marker: the build info magic, the build id of the non ELF formats, a pclntab header of any version in either byte order,
+// magic, two zeros, pc quantum and pointer size, or the version string of TinyGo, which has none of those, ex: 'tinygo0.30.0' or
+// 'tinygo version 0.30.0', what objfile's TinyGo detection looks for
+var goMarker = binaryregexp.MustCompile(`\xff Go buildinf:|\xff Go build ID: "|[\xf0\xf1\xfa\xfb]\xff\xff\xff\x00\x00[\x01\x02\x04][\x04\x08]|\xff\xff\xff[\xf0\xf1\xfa\xfb]\x00\x00[\x01\x02\x04][\x04\x08]|(?i)tinygo(?: version)? ?v?[0-9]+\.[0-9]+`)
+
+// the marker is looked for this many bytes at a time, the windows overlap by the longest marker
+const (
+	sniffWindow  = 1 << 20
+	sniffOverlap = 32
+)
+
+// LikelyGo reports whether the file read from r looks like a Go binary without parsing it: it starts with an executable magic and
+// has a Go marker somewhere, a build info, a build id, a pclntab header or a TinyGo version string. It stops reading at the first
+// marker. It's the check batch runs do before extracting each file. It misses a binary stripped of its build info whose pclntab magic
+// was stomped too, and a TinyGo binary without its version string.
+func LikelyGo(r io.Reader) (bool, error) {
+	buf := make([]byte, sniffWindow+sniffOverlap)
+	n, err := io.ReadFull(r, buf[:sniffWindow])
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	executable := false
+	for _, magic := range executableMagics {
+		if bytes.HasPrefix(buf[:n], magic) {
+			executable = true
+			break
+		}
+	}
+	if !executable {
+		return false, nil
+	}
+
+	carried := 0
+	for n > 0 {
+		window := buf[:carried+n]
+		if goMarker.Match(window) {
+			return true, nil
+		}
+		// the overlap catches a marker split between two windows
+		carried = copy(buf, window[len(window)-min(len(window), sniffOverlap):])
+		n, err = io.ReadFull(r, buf[carried:carried+sniffWindow])
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
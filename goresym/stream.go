@@ -0,0 +1,28 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import "github.com/mandiant/GoReSym/objfile"
+
+// streamHeader streams the StreamHeader of the binary, ahead of its types and functions
+func streamHeader(opts Options, fileName string, metadata Report) {
+	opts.Stream("header", StreamHeader{SchemaVersion, fileName, metadata.Arch, metadata.OS, metadata.Version, metadata.Compiler, metadata.BuildId, metadata.BuildMode})
+}
+
+// streamTypes streams the types by VA, located in space, the report's records are only located once it's done
+func streamTypes(opts Options, space addressSpace, kind string, types []objfile.Type) {
+	sortTypes(types)
+	space.locateTypes(types)
+	for _, typ := range types {
+		opts.Stream(kind, typ)
+	}
+}
+
+// appendFunction adds fn to funcs, or locates it in space and streams it and leaves funcs as they are
+func appendFunction(opts Options, space addressSpace, funcs []FuncMetadata, fn FuncMetadata) []FuncMetadata {
+	if opts.Stream != nil {
+		space.locateFunction(&fn)
+		opts.Stream("function", fn)
+		return funcs
+	}
+	return append(funcs, fn)
+}
@@ -0,0 +1,236 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package goresym
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// the artifacts the Go version is read from. The first four claim an exact version and can be tampered with,
+// the structural ones only narrow it down but can't lie without breaking the parse.
+const (
+	versionSourceBuildInfo        = "buildinfo"
+	versionSourceBuildVersion     = "runtime.buildVersion"
+	versionSourceStringScan       = "string-scan"    // the go1.x string of the data the most string headers point at
+	versionSourceString           = "version string" // the first go1.x string in the file
+	versionSourcePclntab          = "pclntab magic"
+	versionSourceModuleData       = "moduledata layout"
+	versionSourceRuntimeFunctions = "runtime functions"
+)
+
+// claim sources in order of trust, the first consistent one is the consensus
+var versionClaimOrder = []string{versionSourceBuildInfo, versionSourceBuildVersion, versionSourceStringScan, versionSourceString}
+
+// runtime functions every binary built by the version or later links in, newest first
+var runtimeVersionMarkers = []struct {
+	version  string
+	function string
+}{
+	{"1.22", "runtime.(*mspan).typePointersOf"},
+	{"1.22", "runtime.(*traceMap).put"},
+	{"1.21", "runtime.doInit1"},
+	{"1.21", "runtime.(*unwinder).next"},
+	{"1.20", "runtime.runExitHooks"},
+	{"1.19", "runtime.(*gcCPULimiterState).update"},
+}
+
+// the minor versions each pclntab layout was used by, -1 is open ended
+var pclntabLayoutVersions = map[string][2]int{
+	"1.2":  {2, 15},
+	"1.16": {16, 17},
+	"1.18": {18, 19},
+	"1.20": {20, -1},
+}
+
+// the minor versions each moduledata layout was used by, 1.21 added the inittasks to the 1.20 pclntab
+var moduleDataLayoutVersions = map[string][2]int{
+	"1.2":  {2, 15},
+	"1.16": {16, 17},
+	"1.18": {18, 19},
+	"1.20": {20, 20},
+	"1.21": {21, -1},
+}
+
+// VersionSource is the Go version one artifact of the binary gives. A claim is exact, ex: '1.21.3', a structural source is the range of
+// versions its layout was used by, ex: '1.16-1.17', or '1.20+' when it's still in use.
+type VersionSource struct {
+	Source  string
+	Version string
+	Agrees  bool // consistent with the consensus
+	min     int  // minor version range, max is -1 when open ended
+	max     int
+}
+
+// VersionDetection is every source's answer and the version settled on. When sources conflict the ones the structures corroborate win.
+type VersionDetection struct {
+	Sources    []VersionSource
+	Consensus  string
+	Confidence string // high when all sources agree, medium when a conflict was settled, low when only the structure or a single source tells, string-scan when it's the version strings of the data and nothing parsed backs them up
+	Warning    string
+}
+
+// minorVersion parses the minor version out of '1.N', '1.N.P' or '1.NrcX', false if it isn't a version
+func minorVersion(version string) (int, bool) {
+	rest, found := strings.CutPrefix(version, "1.")
+	end := 0
+	for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+		end++
+	}
+	minor, err := strconv.Atoi(rest[:end])
+	return minor, found && err == nil
+}
+
+// normalizeGoVersion strips a version string down to the number, 'go1.17' -> '1.17', 'devel go1.18-2d1d548 Tue Dec 21 ...' -> '1.18'
+func normalizeGoVersion(version string) string {
+	if idx := strings.Index(version, "go"); idx != -1 {
+		version = strings.Split(version[idx+2:]+" ", " ")[0]
+		version = strings.Split(version+"-", "-")[0]
+	}
+	return version
+}
+
+// versionClaim is the exact version source claims, false when it isn't a version, ex: garble's 'unknown'
+func versionClaim(source string, version string) (VersionSource, bool) {
+	version = normalizeGoVersion(version)
+	minor, ok := minorVersion(version)
+	return VersionSource{Source: source, Version: version, min: minor, max: minor}, ok
+}
+
+// versionRange is a structural source consistent with the minor versions oldest to newest
+func versionRange(source string, oldest int, newest int) VersionSource {
+	version := fmt.Sprintf("1.%d-1.%d", oldest, newest)
+	switch {
+	case newest < 0:
+		version = fmt.Sprintf("1.%d+", oldest)
+	case oldest == newest:
+		version = fmt.Sprintf("1.%d", oldest)
+	}
+	return VersionSource{Source: source, Version: version, min: oldest, max: newest}
+}
+
+// layoutVersion is the structural source for a pclntab or moduledata layout, false for an unknown layout
+func layoutVersion(source string, layouts map[string][2]int, layout string) (VersionSource, bool) {
+	versions, ok := layouts[layout]
+	return versionRange(source, versions[0], versions[1]), ok
+}
+
+// runtimeFunctionsVersion is the structural source for the newest runtime functions linked in, false when none of the markers are
+func runtimeFunctionsVersion(funcs []gosym.Func) (VersionSource, bool) {
+	names := make(map[string]bool, len(funcs))
+	for _, fn := range funcs {
+		names[fn.Name] = true
+	}
+	for _, marker := range runtimeVersionMarkers {
+		if names[marker.function] {
+			minor, _ := minorVersion(marker.version)
+			return versionRange(versionSourceRuntimeFunctions, minor, -1), true
+		}
+	}
+	return VersionSource{}, false
+}
+
+// contains reports whether the minor version is in the range of the source
+func (s VersionSource) contains(minor int) bool {
+	return minor >= s.min && (s.max < 0 || minor <= s.max)
+}
+
+// isClaim reports whether the source claims an exact version rather than a range
+func (s VersionSource) isClaim() bool {
+	return s.Source == versionSourceBuildInfo || s.Source == versionSourceBuildVersion || s.Source == versionSourceStringScan || s.Source == versionSourceString
+}
+
+// decideVersion settles on a version from the sources. The first trusted claim every structural source is consistent with wins,
+// without one it's the oldest version the structural sources allow. Returns an empty consensus without sources.
+func decideVersion(sources []VersionSource) VersionDetection {
+	detection := VersionDetection{Sources: sources}
+	consistent := func(minor int) bool {
+		for _, source := range sources {
+			if !source.isClaim() && !source.contains(minor) {
+				return false
+			}
+		}
+		return true
+	}
+
+	claims := 0
+	var consensusSource string
+	for _, name := range versionClaimOrder {
+		for _, source := range sources {
+			if source.Source != name {
+				continue
+			}
+			claims++
+			if len(detection.Consensus) == 0 && consistent(source.min) {
+				detection.Consensus = source.Version
+				consensusSource = name
+			}
+		}
+	}
+
+	structural := len(detection.Consensus) == 0
+	if structural {
+		oldest := -1
+		for _, source := range sources {
+			if !source.isClaim() && source.min > oldest {
+				oldest = source.min
+			}
+		}
+		if oldest < 0 {
+			return detection
+		}
+		detection.Consensus = fmt.Sprintf("1.%d", oldest)
+	}
+
+	consensusMinor, _ := minorVersion(detection.Consensus)
+	var disagreeing []string
+	for i, source := range detection.Sources {
+		if source.isClaim() {
+			detection.Sources[i].Agrees = source.Version == detection.Consensus
+		} else {
+			detection.Sources[i].Agrees = source.contains(consensusMinor)
+		}
+		if !detection.Sources[i].Agrees {
+			disagreeing = append(disagreeing, fmt.Sprintf("%s says %s", source.Source, source.Version))
+		}
+	}
+
+	switch {
+	case len(disagreeing) > 0:
+		detection.Warning = fmt.Sprintf("the sources disagree, %s while the consensus is %s", strings.Join(disagreeing, ", "), detection.Consensus)
+		detection.Confidence = "medium"
+		if structural {
+			detection.Confidence = "low"
+		}
+	case structural || len(sources) == 1:
+		detection.Confidence = "low"
+	case claims == len(sources):
+		// only claims that agree, nothing parsed backs them up
+		detection.Confidence = "medium"
+	default:
+		detection.Confidence = "high"
+	}
+	if consensusSource == versionSourceStringScan && detection.Confidence != "high" {
+		detection.Confidence = versionSourceStringScan
+	}
+	return detection
+}
+
+// tabVersionSources adds what a pclntab candidate tells to the claims: the versions of its layout, unless the magic was reconstructed
+// as a guess, and of the runtime functions it lists
+func tabVersionSources(claims []VersionSource, tab *objfile.PclntabCandidate) []VersionSource {
+	sources := slices.Clip(claims)
+	if !tab.ReconstructedMagic {
+		if source, ok := layoutVersion(versionSourcePclntab, pclntabLayoutVersions, tab.ParsedPclntab.Go12line.Version.String()); ok {
+			sources = append(sources, source)
+		}
+	}
+	if source, ok := runtimeFunctionsVersion(tab.ParsedPclntab.Funcs); ok {
+		sources = append(sources, source)
+	}
+	return sources
+}
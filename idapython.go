@@ -0,0 +1,143 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mandiant/GoReSym/goresym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// idaPythonHeader is the start of the generated script, the recovered names follow it and idaPythonBody applies them
+const idaPythonHeader = `# Generated by GoReSym, run it with File > Script file in the database of %s
+# The addresses are the virtual addresses of the file, rebase the database with Edit > Segments > Rebase program rather than the script.
+# Running it again is harmless: what's already applied is left as is, and names and comments set by hand are only replaced with FORCE.
+import ida_bytes
+import ida_funcs
+import ida_name
+
+FORCE = False
+
+`
+
+const idaPythonBody = `
+
+def apply_name(ea, name):
+    current = ida_name.get_name(ea)
+    if current == name:
+        return
+    if current and ida_bytes.has_user_name(ida_bytes.get_flags(ea)) and not FORCE:
+        print("GoReSym: keeping %s at 0x%x, set FORCE to rename it %s" % (current, ea, name))
+        return
+    ida_name.set_name(ea, name, ida_name.SN_NOWARN | ida_name.SN_FORCE)
+
+
+def apply_function(start, end, comment):
+    func = ida_funcs.get_func(start)
+    if func is None:
+        if not ida_funcs.add_func(start, end) and FORCE:
+            ida_bytes.del_items(start, ida_bytes.DELIT_SIMPLE, end - start)
+            ida_funcs.add_func(start, end)
+        func = ida_funcs.get_func(start)
+    elif func.start_ea == start and func.end_ea != end:
+        ida_funcs.set_func_end(start, end)
+    if func is None or func.start_ea != start:
+        print("GoReSym: no function at 0x%x" % start)
+        return
+    current = ida_funcs.get_func_cmt(func, False)
+    if current != comment and (not current or FORCE):
+        ida_funcs.set_func_cmt(func, comment, False)
+
+
+def apply_comment(ea, comment):
+    current = ida_bytes.get_cmt(ea, True)
+    if current != comment and (not current or FORCE):
+        ida_bytes.set_cmt(ea, comment, True)
+
+
+for start, end, name, comment in FUNCTIONS:
+    apply_function(start, end, comment)
+    apply_name(start, name)
+
+for va, name, comment in TYPES:
+    apply_name(va, name)
+    apply_comment(va, comment)
+
+print("GoReSym: applied %d functions and %d types" % (len(FUNCTIONS), len(TYPES)))
+`
+
+// idaName replaces the characters IDA rejects in a name, everything but letters, digits and _$?@, ex: 'main.(*T).M' -> 'main___T__M'
+func idaName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || strings.ContainsRune("_$?@", r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// uniqueNames hands out the sanitized names of a namespace, a name that collides with an earlier one gets the address appended
+type uniqueNames map[[2]string]bool
+
+func (u uniqueNames) name(namespace string, name string, va uint64) string {
+	if u[[2]string{namespace, name}] {
+		name = fmt.Sprintf("%s_%x", name, va)
+	}
+	u[[2]string{namespace, name}] = true
+	return name
+}
+
+// headerName is the base name of fileName for the comment of a script header. Its control and non ASCII characters are escaped as
+// in a Go string, so a newline in the name can't end the comment and run the rest of it as code.
+func headerName(fileName string) string {
+	quoted := strconv.QuoteToASCII(filepath.Base(fileName))
+	return quoted[1 : len(quoted)-1]
+}
+
+// functionComment is the full Go name of a function and the source line of its entry, when known
+func functionComment(fn goresym.FuncMetadata) string {
+	if len(fn.SourceFile) == 0 {
+		return fn.FullName
+	}
+	return fmt.Sprintf("%s\n%s:%d", fn.FullName, fn.SourceFile, fn.SourceLine)
+}
+
+// printIdaPython writes an IDAPython script naming the functions and types of metadata, marking the function boundaries and commenting
+// them with their source lines. fileName goes in the header. Std functions and types are included when they were extracted, with -d and -t.
+func printIdaPython(w io.Writer, fileName string, metadata goresym.Report) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, idaPythonHeader, headerName(fileName))
+
+	// an address is named once, naming it twice would flip between the names on every run
+	names := make(uniqueNames)
+	named := make(map[uint64]bool)
+	b.WriteString("# start, end, name and the comment of each function\nFUNCTIONS = [\n")
+	for _, funcs := range [][]goresym.FuncMetadata{metadata.UserFunctions, metadata.StdFunctions} {
+		for _, fn := range funcs {
+			if fn.Unmapped || named[fn.Start] {
+				continue
+			}
+			named[fn.Start] = true
+			fmt.Fprintf(&b, "    (0x%x, 0x%x, %s, %s),\n", fn.Start, fn.End, strconv.QuoteToASCII(names.name("", idaName(fn.FullName), fn.Start)), strconv.QuoteToASCII(functionComment(fn)))
+		}
+	}
+	b.WriteString("]\n\n# va, name and the Go type of each type structure\nTYPES = [\n")
+	for _, types := range [][]objfile.Type{metadata.Types, metadata.Interfaces} {
+		for _, typ := range types {
+			if named[typ.VA] {
+				continue
+			}
+			named[typ.VA] = true
+			fmt.Fprintf(&b, "    (0x%x, %s, %s),\n", typ.VA, strconv.QuoteToASCII(names.name("", idaName("type_"+typ.Str), typ.VA)), strconv.QuoteToASCII(typ.Str))
+		}
+	}
+	b.WriteString("]\n")
+	b.WriteString(idaPythonBody)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,121 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/mandiant/GoReSym/goresym"
+)
+
+// writeJson writes DataToJson of data and a newline to w without holding the document: the functions, types and interfaces of a
+// Report, most of it for a large binary with -t, are encoded one at a time. The document is the same as DataToJson's.
+func writeJson(w io.Writer, data interface{}) {
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+
+	var err error
+	if report, ok := data.(goresym.Report); ok {
+		err = writeReport(out, report)
+	} else {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "    ")
+		err = encoder.Encode(data)
+	}
+	if err != nil {
+		fmt.Fprintln(out, "{\"error\": \"failed to format output\"}")
+	}
+}
+
+// streamedList is a list of the Report writeReport encodes an element at a time, elem is the i'th
+type streamedList struct {
+	key   string
+	len   int
+	isNil bool
+	elem  func(i int) interface{}
+	at    int // of its null in the document without it
+}
+
+// writeReport writes the indented JSON of report and a newline to w, the document of report without its large lists with each
+// list written in place of its null
+func writeReport(w io.Writer, report goresym.Report) error {
+	lists := []*streamedList{
+		{key: "UserFunctions", len: len(report.UserFunctions), isNil: report.UserFunctions == nil, elem: func(i int) interface{} { return &report.UserFunctions[i] }},
+		{key: "StdFunctions", len: len(report.StdFunctions), isNil: report.StdFunctions == nil, elem: func(i int) interface{} { return &report.StdFunctions[i] }},
+		{key: "Types", len: len(report.Types), isNil: report.Types == nil, elem: func(i int) interface{} { return &report.Types[i] }},
+		{key: "Interfaces", len: len(report.Interfaces), isNil: report.Interfaces == nil, elem: func(i int) interface{} { return &report.Interfaces[i] }},
+	}
+	rest := report
+	rest.UserFunctions, rest.StdFunctions, rest.Types, rest.Interfaces = nil, nil, nil, nil
+	document, err := json.MarshalIndent(rest, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	// the fields of the report are indented once, those of the modules further
+	for _, list := range lists {
+		marker := []byte("\n    \"" + list.key + "\": ")
+		list.at = bytes.Index(document, append(marker, "null"...))
+		if list.at == -1 {
+			return fmt.Errorf("no %s in the document", list.key)
+		}
+		list.at += len(marker)
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].at < lists[j].at
+	})
+
+	written := 0
+	for _, list := range lists {
+		if _, err := w.Write(document[written:list.at]); err != nil {
+			return err
+		}
+		written = list.at + len("null")
+		if err := writeList(w, list); err != nil {
+			return err
+		}
+	}
+	if _, err := w.Write(document[written:]); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// writeList writes list as MarshalIndent would nested once in an object
+func writeList(w io.Writer, list *streamedList) error {
+	switch {
+	case list.isNil:
+		_, err := io.WriteString(w, "null")
+		return err
+	case list.len == 0:
+		_, err := io.WriteString(w, "[]")
+		return err
+	}
+
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i := 0; i < list.len; i++ {
+		elem, err := json.MarshalIndent(list.elem(i), "        ", "    ")
+		if err != nil {
+			return err
+		}
+		separator := "\n        "
+		if i > 0 {
+			separator = "," + separator
+		}
+		if _, err := io.WriteString(w, separator); err != nil {
+			return err
+		}
+		if _, err := w.Write(elem); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\n    ]")
+	return err
+}
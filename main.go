@@ -4,320 +4,157 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"time"
 
-	// we copy the go src directly, then change every include to github.com/mandiant/GoReSym/<whatever>
-	// this is required since we're using internal files. Our modifications are directly inside the copied source
-	"github.com/mandiant/GoReSym/buildid"
-	"github.com/mandiant/GoReSym/buildinfo"
+	"github.com/mandiant/GoReSym/goresym"
 	"github.com/mandiant/GoReSym/objfile"
-	"github.com/mandiant/GoReSym/runtime/debug"
 )
 
-func isStdPackage(pkg string) bool {
-	// Empty name is common for reflect/type functions and some runtime symbols
-	if len(strings.TrimSpace(pkg)) <= 0 {
-		return true
-	}
-
-	for _, v := range standardPackages {
-		if v == pkg {
-			return true
-		}
-	}
-
-	return false
+// the results of a fat Mach-O, each slice labeled by its Arch
+type FatMetadata struct {
+	Slices []goresym.Report
+	Failed map[string]string // GOARCH of the slices that didn't parse, ex: not Go, to the error
 }
 
-// pclntab header info
-type PcLnTabMetadata struct {
-	VA            uint64
-	Version       string
-	Endianess     string
-	CpuQuantum    uint32
-	CpuQuantumStr string
-	PointerSize   uint32
+// the results of an input embedding Go executables, the input's first, each image labeled by its Image
+type ImagesMetadata struct {
+	Images []goresym.Report
+	Failed map[string]string // the indexes of the images that didn't parse, ex: the input isn't Go itself, to the error
 }
 
-type FuncMetadata struct {
-	Start       uint64
-	End         uint64
-	PackageName string
-	FullName    string
+// imageRegion names the part of the input an embedded image is in for the human view
+func imageRegion(image objfile.EmbeddedImage) string {
+	if len(image.Region) == 0 {
+		return "the input"
+	}
+	return image.Region
 }
 
-type ExtractMetadata struct {
-	Version       string
-	BuildId       string
-	Arch          string
-	OS            string
-	TabMeta       PcLnTabMetadata
-	ModuleMeta    objfile.ModuleData
-	Types         []objfile.Type
-	Interfaces    []objfile.Type
-	BuildInfo     debug.BuildInfo
-	Files         []string
-	UserFunctions []FuncMetadata
-	StdFunctions  []FuncMetadata
+// options are the Options of the flags main_impl takes, main sets the others from their flags on what it returns
+func options(printStdPkgs bool, printFilePaths bool, printTypes bool, noPrintFunctions bool, manualTypeAddress int, versionOverride string, printTimestamps bool) goresym.Options {
+	return goresym.Options{
+		StdFunctions: printStdPkgs,
+		FilePaths:    printFilePaths,
+		Types:        printTypes,
+		TypeAddress:  uint64(manualTypeAddress),
+		NoFunctions:  noPrintFunctions,
+		Timestamps:   printTimestamps,
+		Version:      versionOverride,
+	}
 }
 
-func main_impl_tmpfile(fileBytes []byte, printStdPkgs bool, printFilePaths bool, printTypes bool, noPrintFunctions bool, manualTypeAddress int, versionOverride string) (metadata ExtractMetadata, err error) {
-	tmpFile, err := os.CreateTemp(os.TempDir(), "goresym_tmp-")
-	if err != nil {
-		return ExtractMetadata{}, fmt.Errorf("failed to create temporary file: %s", err)
-	}
-	defer os.Remove(tmpFile.Name())
+// main_impl_bytes extracts from a file held in memory, with the same extraction main_impl runs over a file on disk
+func main_impl_bytes(fileBytes []byte, printStdPkgs bool, printFilePaths bool, printTypes bool, noPrintFunctions bool, manualTypeAddress int, versionOverride string, printTimestamps bool) (metadata goresym.Report, err error) {
+	return extractBytes(fileBytes, options(printStdPkgs, printFilePaths, printTypes, noPrintFunctions, manualTypeAddress, versionOverride, printTimestamps))
+}
 
-	if _, err = tmpFile.Write(fileBytes); err != nil {
-		return ExtractMetadata{}, fmt.Errorf("failed to write bytes to temporary file: %s", err)
-	}
+// extractBytes is extractFile over a file held in memory
+func extractBytes(fileBytes []byte, opts goresym.Options) (metadata goresym.Report, err error) {
+	report, err := goresym.ExtractReader(context.Background(), bytes.NewReader(fileBytes), int64(len(fileBytes)), opts)
+	return *report, err
+}
 
-	if err := tmpFile.Close(); err != nil {
-		return ExtractMetadata{}, fmt.Errorf("failed to close temporary file: %s", err)
-	}
+func main_impl(fileName string, printStdPkgs bool, printFilePaths bool, printTypes bool, noPrintFunctions bool, manualTypeAddress int, versionOverride string, printTimestamps bool) (metadata goresym.Report, err error) {
+	return extractFile(fileName, options(printStdPkgs, printFilePaths, printTypes, noPrintFunctions, manualTypeAddress, versionOverride, printTimestamps))
+}
 
-	return main_impl(tmpFile.Name(), printStdPkgs, printFilePaths, printTypes, noPrintFunctions, manualTypeAddress, versionOverride)
+// extractFile extracts from the file at fileName with opts, every flag of the command rather than the ones main_impl takes
+func extractFile(fileName string, opts goresym.Options) (metadata goresym.Report, err error) {
+	report, err := goresym.Extract(context.Background(), fileName, opts)
+	return *report, err
 }
 
-func main_impl(fileName string, printStdPkgs bool, printFilePaths bool, printTypes bool, noPrintFunctions bool, manualTypeAddress int, versionOverride string) (metadata ExtractMetadata, err error) {
-	extractMetadata := ExtractMetadata{}
+// main_impl_image extracts from an image already unpacked into memory at imageBase, read through r, with the same extraction main_impl runs
+// over a file, see goresym.ExtractImage
+func main_impl_image(r io.ReaderAt, imageBase uint64, goarch string, printStdPkgs bool, printFilePaths bool, printTypes bool, noPrintFunctions bool, manualTypeAddress int, versionOverride string, printTimestamps bool) (metadata goresym.Report, err error) {
+	report, err := goresym.ExtractImage(context.Background(), r, imageBase, goarch, options(printStdPkgs, printFilePaths, printTypes, noPrintFunctions, manualTypeAddress, versionOverride, printTimestamps))
+	return *report, err
+}
 
-	file, err := objfile.Open(fileName)
-	if err != nil {
-		return ExtractMetadata{}, fmt.Errorf("invalid file: %w", err)
+func printForHuman(metadata goresym.Report) {
+	fmt.Println("----GoReSym----")
+	fmt.Println("Some information is omitted, for a full listing do not use human view")
+	fmt.Printf("%-20s %s\n", "Version:", metadata.Version)
+	if len(metadata.VersionDetection.Confidence) > 0 {
+		fmt.Printf("%-20s %s\n", "Confidence:", metadata.VersionDetection.Confidence)
 	}
-
-	buildId, err := buildid.ReadFile(fileName)
-	if err == nil {
-		extractMetadata.BuildId = buildId
-	} else {
-		extractMetadata.BuildId = ""
+	if len(metadata.VersionDetection.Warning) > 0 {
+		fmt.Printf("%-20s %s\n", "Version warning:", metadata.VersionDetection.Warning)
 	}
-
-	// try to get version the 'correct' way, also fill out buildSettings if parsing was ok
-	bi, err := buildinfo.ReadFile(fileName)
-	if err == nil {
-		extractMetadata.Version = bi.GoVersion
-
-		for _, setting := range bi.Settings {
-			if setting.Key == "GOOS" {
-				extractMetadata.OS = setting.Value
-			} else if setting.Key == "GOARCH" {
-				extractMetadata.Arch = setting.Value
-			}
+	if metadata.TinyGo != nil {
+		fmt.Printf("%-20s %s\n", "Compiler:", "TinyGo "+metadata.TinyGo.Version)
+		for _, sign := range metadata.TinyGo.Evidence {
+			fmt.Printf("%-20s %s\n", "", sign)
 		}
-
-		extractMetadata.BuildInfo = *bi
-	}
-
-	// Optional bruteforce any one of these, but only if they weren't previous found in the buildinfo
-	if extractMetadata.OS == "" || extractMetadata.Arch == "" || extractMetadata.Version == "" {
-		// GOARCH
-		if extractMetadata.Arch == "" {
-			extractMetadata.Arch = file.GOARCH()
+		if metadata.TinyGo.Stripped {
+			fmt.Printf("%-20s %s\n", "", "TinyGo, limited metadata: the symbol table is stripped, no functions can be recovered")
 		}
-
-		fileData, fileDataErr := os.ReadFile(fileName)
-		if fileDataErr == nil {
-
-			// GOVERSION
-			if extractMetadata.Version == "" {
-				// go1.<varies><garbage data>
-				idx := bytes.Index(fileData, []byte{0x67, 0x6F, 0x31, 0x2E})
-				if idx != -1 && len(fileData[idx:]) > 10 {
-					extractMetadata.Version = "go1."
-					ver := fileData[idx+4 : idx+10]
-					for i, c := range ver {
-						// the string is _not_ null terminated, nor length delimited. So, filter till first non-numeric ascii
-						nextIsNumeric := (i+1) < len(ver) && ver[i+1] >= 0x30 && ver[i+1] <= 0x39
-
-						// careful not to end with a . at the end
-						if (c >= 0x30 && c <= 0x39 && c != ' ') || (c == '.' && nextIsNumeric) {
-							extractMetadata.Version += string([]byte{c})
-						} else {
-							break
-						}
-					}
-				}
-			}
-
-			// GOOS
-			if extractMetadata.OS == "" {
-				// try to find the OS by locating the source file name from https://github.com/golang/go/tree/master/src/runtime/os_<os name>.go or the asm file name rt0_<os name>_<arch>.s
-				// if this is bad, we can end up signaturing the asm file manually (todo)
-				// /src/runtime/os_
-				needleSrcFile := []byte{0x2F, 0x73, 0x72, 0x63, 0x2F, 0x72, 0x75, 0x6E, 0x74, 0x69, 0x6D, 0x65, 0x2F, 0x6F, 0x73, 0x5F}
-				needleSrcFileLen := len(needleSrcFile)
-				idx := bytes.Index(fileData, needleSrcFile)
-				if idx != -1 && len(fileData[idx:]) > needleSrcFileLen+20 {
-					os_str := fileData[idx+needleSrcFileLen : idx+needleSrcFileLen+20]
-					for _, c := range os_str {
-						// end our search at the first '.', which should be the .go soure file extension, or a space as fallback
-						if (c >= 0x30 && c <= 0x5a) || (c >= 0x61 && c <= 0x7a) && c != '.' && c != ' ' {
-							extractMetadata.OS += string([]byte{c})
-						} else {
-							break
-						}
-					}
-				} else {
-					// /src/runtime/rt0_
-					needleAsmFile := []byte{0x2F, 0x73, 0x72, 0x63, 0x2F, 0x72, 0x75, 0x6E, 0x74, 0x69, 0x6D, 0x65, 0x2F, 0x72, 0x74, 0x30, 0x5F}
-					needleAsmFileLen := len(needleAsmFile)
-					idx := bytes.Index(fileData, needleAsmFile)
-					if idx != -1 && len(fileData[idx:]) > needleAsmFileLen+20 {
-						os_str := fileData[idx+needleAsmFileLen : idx+needleAsmFileLen+20]
-						for _, c := range os_str {
-							// end our search at the first '_', which should be the _arch, space as fallback
-							if (c >= 0x30 && c <= 0x5a) || (c >= 0x61 && c <= 0x7a) && c != '_' && c != '.' && c != ' ' {
-								extractMetadata.OS += string([]byte{c})
-							} else {
-								break
-							}
-						}
-					}
-				}
-			}
+	}
+	fmt.Printf("%-20s %s\n", "Arch:", metadata.Arch)
+	fmt.Printf("%-20s %s\n", "OS:", metadata.OS)
+	fmt.Printf("%-20s %s\n", "BuildMode:", metadata.BuildMode)
+	fmt.Printf("%-20s %s\n", "Fingerprint:", metadata.MetadataFingerprint)
+	if metadata.ObfuscatorDetected {
+		fmt.Printf("%-20s %s\n", "Obfuscator:", metadata.Obfuscator)
+		for _, sign := range metadata.ObfuscationEvidence {
+			fmt.Printf("%-20s %s\n", "", sign)
 		}
 	}
-
-	var knownPclntabVA = uint64(0)
-	var knownGoTextBase = uint64(0)
-
-restartParseWithRealTextBase:
-	ch_tabs, err := file.PCLineTable(versionOverride, knownPclntabVA, knownGoTextBase)
-	if err != nil {
-		return ExtractMetadata{}, fmt.Errorf("failed to read pclntab: %w", err)
+	if len(metadata.Packer) > 0 {
+		fmt.Printf("%-20s %s\n", "Packer:", metadata.Packer)
 	}
-
-	var moduleData *objfile.ModuleData = nil
-	var finalTab *objfile.PclntabCandidate = nil
-	for tab := range ch_tabs {
-		if len(versionOverride) > 0 {
-			extractMetadata.Version = versionOverride
+	if metadata.LikelyPacked != nil {
+		for _, sign := range metadata.LikelyPacked.Evidence {
+			fmt.Printf("%-20s %s\n", "Likely packed:", sign)
 		}
-
-		// numeric only, go1.17 -> 1.17
-		goVersionIdx := strings.Index(extractMetadata.Version, "go")
-		if goVersionIdx != -1 {
-			// "devel go1.18-2d1d548 Tue Dec 21 03:55:43 2021 +0000"
-			extractMetadata.Version = strings.Split(extractMetadata.Version[goVersionIdx+2:]+" ", " ")[0]
-
-			// go1.18-2d1d548
-			extractMetadata.Version = strings.Split(extractMetadata.Version+"-", "-")[0]
+	}
+	if metadata.Dump != nil {
+		fmt.Printf("%-20s %s at 0x%x, 0x%x bytes\n", "Dump:", metadata.Dump.Format, metadata.Dump.Base, metadata.Dump.Size)
+		if len(metadata.Dump.Region) > 0 {
+			fmt.Printf("%-20s %s\n", "Region:", metadata.Dump.Region)
 		}
-
-		extractMetadata.TabMeta.CpuQuantum = tab.ParsedPclntab.Go12line.Quantum
-
-		// quantum is the minimal unit for a program counter (1 on x86, 4 on most other systems).
-		// 386: 1, amd64: 1, arm: 4, arm64: 4, mips: 4, mips/64/64le/64be: 4, ppc64/64le: 4, riscv64: 4, s390x: 2, wasm: 1
-		extractMetadata.TabMeta.CpuQuantumStr = "x86/x64/wasm"
-		if extractMetadata.TabMeta.CpuQuantum == 2 {
-			extractMetadata.TabMeta.CpuQuantumStr = "s390x"
-		} else if extractMetadata.TabMeta.CpuQuantum == 4 {
-			extractMetadata.TabMeta.CpuQuantumStr = "arm/mips/ppc/riscv"
+		for _, gap := range metadata.Dump.Gaps {
+			fmt.Printf("%-20s 0x%x-0x%x\n", "Not dumped:", gap.Start, gap.End)
 		}
-
-		extractMetadata.TabMeta.VA = tab.PclntabVA
-		extractMetadata.TabMeta.Version = tab.ParsedPclntab.Go12line.Version.String()
-		extractMetadata.TabMeta.Endianess = tab.ParsedPclntab.Go12line.Binary.String()
-		extractMetadata.TabMeta.PointerSize = tab.ParsedPclntab.Go12line.Ptrsize
-
-		// this can be a little tricky to locate and parse properly across all go versions
-		// since moduledata holds a pointer to the pclntab, we can (hopefully) find the right candidate by using it to find the moduledata.
-		// if that location works, then we must have given it the correct pclntab VA. At least in theory...
-		// The resolved offsets within the pclntab might have used the wrong base though! We'll fix that later.
-		_, tmpModData, err := file.ModuleDataTable(tab.PclntabVA, extractMetadata.Version, extractMetadata.TabMeta.Version, extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian")
-		if err == nil && tmpModData != nil {
-			// if the search candidate relied on a moduledata va, make sure it lines up with ours now
-			stomppedMagicMetaConstraintsValid := true
-			if tab.StompMagicCandidateMeta != nil {
-				stomppedMagicMetaConstraintsValid = tab.StompMagicCandidateMeta.SuspectedModuleDataVa == tmpModData.VA
-			}
-
-			if knownGoTextBase == 0 && knownPclntabVA == 0 && stomppedMagicMetaConstraintsValid {
-				// assign real base and restart pclntab parsing with correct VAs!
-				knownGoTextBase = tmpModData.TextVA
-				knownPclntabVA = tab.PclntabVA
-				goto restartParseWithRealTextBase
-			}
-
-			// we already have pclntab candidates with the right VA, but which candidate?? The one that finds a valid moduledata!
-			finalTab = &tab
-			moduleData = tmpModData
-			break
+		for _, module := range metadata.Dump.Modules {
+			fmt.Printf("%-20s moduledata 0x%x in %s\n", "Module:", module.ModuleDataVA, module.Region)
+		}
+		if len(metadata.Dump.Unresolved) > 0 {
+			fmt.Printf("%-20s %s\n", "Outside the dump:", strings.Join(metadata.Dump.Unresolved, ", "))
 		}
 	}
-
-	if finalTab == nil {
-		return ExtractMetadata{}, fmt.Errorf("no valid pclntab found")
+	for _, module := range metadata.Modules[min(1, len(metadata.Modules)):] {
+		fmt.Printf("%-20s %s text 0x%x-0x%x, %d user and %d std functions\n", "Loaded module:", module.PluginPath, module.TextVA, module.ETextVA, len(module.UserFunctions), len(module.StdFunctions))
 	}
-
-	// to be sure we got the right pclntab we had to have found a moduledat as well. If we didn't, then we failed to find the pclntab (correctly) as well
-	if moduleData == nil {
-		return ExtractMetadata{}, fmt.Errorf("no valid moduledata found")
+	if metadata.Overlay != nil {
+		fmt.Printf("%-20s 0x%x bytes at file offset 0x%x\n", "Overlay:", metadata.Overlay.Size, metadata.Overlay.Offset)
 	}
-
-	extractMetadata.ModuleMeta = *moduleData
-	if printTypes && manualTypeAddress == 0 {
-		types, err := file.ParseTypeLinks(extractMetadata.Version, moduleData, extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian")
-		if err == nil {
-			extractMetadata.Types = types
-		}
-
-		// the ITabLinks did not always exist, older versions it will be NULL
-		interfaces, err := file.ParseITabLinks(extractMetadata.Version, moduleData, extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian")
-		if err == nil {
-			extractMetadata.Interfaces = interfaces
-		}
-	} else if manualTypeAddress != 0 {
-		types, err := file.ParseType(extractMetadata.Version, moduleData, uint64(manualTypeAddress), extractMetadata.TabMeta.PointerSize == 8, extractMetadata.TabMeta.Endianess == "LittleEndian")
-		if err == nil {
-			extractMetadata.Types = types
-		}
+	if metadata.TabMeta.Overlay {
+		fmt.Printf("%-20s the pclntab is in the overlay at file offset 0x%x, it's not mapped\n", "Warning:", *metadata.TabMeta.FileOffset)
 	}
-
-	if printFilePaths {
-		for k := range finalTab.ParsedPclntab.Files {
-			extractMetadata.Files = append(extractMetadata.Files, k)
-		}
+	if metadata.TabMeta.Location != "" && metadata.TabMeta.Location != ".gopclntab" && metadata.TabMeta.Location != "__gopclntab" {
+		fmt.Printf("%-20s the pclntab was found in %s\n", "Pclntab:", metadata.TabMeta.Location)
 	}
-
-	if !noPrintFunctions {
-		for _, elem := range finalTab.ParsedPclntab.Funcs {
-			if isStdPackage(elem.PackageName()) {
-				if printStdPkgs {
-					extractMetadata.StdFunctions = append(extractMetadata.StdFunctions, FuncMetadata{
-						Start:       elem.Entry,
-						End:         elem.End,
-						PackageName: elem.PackageName(),
-						FullName:    elem.Name,
-					})
-				}
-			} else {
-				extractMetadata.UserFunctions = append(extractMetadata.UserFunctions, FuncMetadata{
-					Start:       elem.Entry,
-					End:         elem.End,
-					PackageName: elem.PackageName(),
-					FullName:    elem.Name,
-				})
-			}
-		}
+	if metadata.TabMeta.ReconstructedMagic {
+		fmt.Printf("%-20s the pclntab magic was stomped, the header was reconstructed as the %s layout\n", "Warning:", metadata.TabMeta.Version)
+	}
+	if metadata.Composition.EmbedsToolchain {
+		fmt.Printf("%-20s %s\n", "EmbedsToolchain:", strings.Join(metadata.Composition.ToolchainPackages, ", "))
+	}
+	if metadata.Composition.LargeStdFootprint {
+		fmt.Printf("%-20s %d std packages\n", "LargeStdFootprint:", metadata.Composition.StdPackageCount)
 	}
-
-	return extractMetadata, nil
-}
-
-func printForHuman(metadata ExtractMetadata) {
-	fmt.Println("----GoReSym----")
-	fmt.Println("Some information is omitted, for a full listing do not use human view")
-	fmt.Printf("%-20s %s\n", "Version:", metadata.Version)
-	fmt.Printf("%-20s %s\n", "Arch:", metadata.Arch)
-	fmt.Printf("%-20s %s\n", "OS:", metadata.OS)
 	fmt.Println("\n-BUILD INFO-")
 	fmt.Printf("%-20s %s\n", "GoVersion", metadata.BuildInfo.GoVersion)
 	fmt.Printf("%-20s %s\n", "Path", metadata.BuildInfo.Path)
@@ -340,6 +177,13 @@ func printForHuman(metadata ExtractMetadata) {
 	} else {
 		fmt.Println("  <NO SETTINGS PRESENT>")
 	}
+	if metadata.VCS != nil {
+		fmt.Println("\n  -VCS-")
+		fmt.Printf("  %-20s %s\n", "System", metadata.VCS.System)
+		fmt.Printf("  %-20s %s\n", "Revision", metadata.VCS.Revision)
+		fmt.Printf("  %-20s %s\n", "Time", metadata.VCS.Time)
+		fmt.Printf("  %-20s %t\n", "Modified", metadata.VCS.Modified)
+	}
 
 	fmt.Println("\n-TYPE STRUCTURES-")
 	printedStruct := false
@@ -367,6 +211,88 @@ func printForHuman(metadata ExtractMetadata) {
 		fmt.Println("<NO INTERFACES EXTRACTED>")
 	}
 
+	if len(metadata.SegmentOverlaps) > 0 {
+		fmt.Println("\n-SEGMENT OVERLAPS-")
+		for _, overlap := range metadata.SegmentOverlaps {
+			fmt.Printf("0x%x-0x%x segments %v\n", overlap.Start, overlap.End, overlap.Segments)
+		}
+	}
+
+	fmt.Println("\n-RUNTIME OFFSETS-")
+	if len(metadata.RuntimeOffsets) > 0 {
+		for _, offset := range metadata.RuntimeOffsets {
+			fmt.Printf("%-20s 0x%x (%s)\n", offset.Field, offset.Offset, offset.Source)
+		}
+	} else {
+		fmt.Println("<NO RUNTIME OFFSETS KNOWN>")
+	}
+
+	if len(metadata.RuntimeGlobals) > 0 {
+		fmt.Println("\n-RUNTIME GLOBALS-")
+		for _, global := range metadata.RuntimeGlobals {
+			fmt.Printf("0x%-18x %s (%s)\n", global.VA, global.Name, global.Method)
+		}
+	}
+
+	if len(metadata.EmbeddedFS) > 0 {
+		fmt.Println("\n-EMBEDDED FILES-")
+		for _, embedded := range metadata.EmbeddedFS {
+			fmt.Printf("0x%-18x %s\n", embedded.VA, embedded.Name)
+			for _, file := range embedded.Files {
+				fmt.Printf("    %-40s %d bytes %s\n", file.Name, file.Size, file.SHA256)
+			}
+			for _, warning := range embedded.Warnings {
+				fmt.Printf("    Warning: %s\n", warning)
+			}
+		}
+	}
+
+	if len(metadata.Inits) > 0 {
+		fmt.Println("\n-INIT TASKS-")
+		for _, task := range metadata.Inits {
+			fmt.Printf("0x%-18x %s\n", task.VA, task.Package)
+			for _, fn := range task.Functions {
+				if fn.Outside {
+					fmt.Printf("    0x%-14x %s (outside the text)\n", fn.VA, fn.Name)
+				} else {
+					fmt.Printf("    0x%-14x %s\n", fn.VA, fn.Name)
+				}
+			}
+		}
+	}
+
+	if len(metadata.Hooks) > 0 {
+		fmt.Println("\n-HOOKS-")
+		for _, hook := range metadata.Hooks {
+			fmt.Printf("0x%-18x %s %s", hook.Entry, hook.Function, hook.Kind)
+			if hook.Target != 0 {
+				fmt.Printf(" to 0x%x", hook.Target)
+			}
+			if len(hook.TargetRegion) > 0 {
+				fmt.Printf(" in %s", hook.TargetRegion)
+			}
+			fmt.Println()
+			fmt.Printf("    %-14s %s\n", "bytes", hook.Prologue)
+			if len(hook.Original) > 0 {
+				fmt.Printf("    %-14s %s\n", "original", hook.Original)
+			}
+		}
+	}
+
+	if len(metadata.TimeConstants) > 0 {
+		fmt.Println("\n-TIME CONSTANTS-")
+		for _, tc := range metadata.TimeConstants {
+			fmt.Printf("0x%-18x %s\n", tc.VA, tc.Time)
+		}
+	}
+
+	if len(metadata.Packages) > 0 {
+		fmt.Println("\n-PACKAGES-")
+		for _, pkg := range metadata.Packages {
+			fmt.Printf("%-12s %s\n", pkg.Origin, pkg.Path)
+		}
+	}
+
 	fmt.Println("\n-Files-")
 	if len(metadata.Files) > 0 {
 		for _, file := range metadata.Files {
@@ -376,6 +302,16 @@ func printForHuman(metadata ExtractMetadata) {
 		fmt.Println("<NO FILES EXTRACTED>")
 	}
 
+	if len(metadata.FunctionKinds) > 0 {
+		var kinds []string
+		for _, kind := range goresym.FunctionKindNames {
+			if count, ok := metadata.FunctionKinds[kind]; ok {
+				kinds = append(kinds, fmt.Sprintf("%d %s", count, kind))
+			}
+		}
+		fmt.Printf("\n%-20s %s\n", "Function kinds:", strings.Join(kinds, ", "))
+	}
+
 	fmt.Println("\n-User Functions-")
 	if len(metadata.UserFunctions) > 0 {
 		for i, fn := range metadata.UserFunctions {
@@ -384,11 +320,79 @@ func printForHuman(metadata ExtractMetadata) {
 			fmt.Printf("%-20s 0x%x\n", fnPrefix+"EndVA:", fn.End)
 			fmt.Printf("%-20s %s\n", fnPrefix+"Package:", fn.PackageName)
 			fmt.Printf("%-20s %s\n", fnPrefix+"Name:", strings.TrimLeft(strings.TrimLeft(fn.FullName, fn.PackageName), "."))
+			if len(fn.Origin) > 0 {
+				fmt.Printf("%-20s %s %s\n", fnPrefix+"Origin:", fn.Origin, fn.Module)
+			}
+			if fn.Kind != goresym.KindUser {
+				fmt.Printf("%-20s %s\n", fnPrefix+"Kind:", fn.Kind)
+			}
+			if fn.Unmapped {
+				fmt.Printf("%-20s outside the dump\n", fnPrefix+"Unmapped:")
+			}
 		}
 	} else {
 		fmt.Println("<NO USER FUNCTIONS EXTRACTED>")
 	}
 
+	if metadata.Cgo.Present {
+		fmt.Println("\n-Cgo Functions-")
+		for _, evidence := range metadata.Cgo.Evidence {
+			fmt.Printf("%-20s %s\n", "evidence", evidence)
+		}
+		for _, fn := range metadata.Cgo.Functions {
+			fmt.Printf("0x%-18x %-8s %s\n", fn.Start, fn.Kind, fn.FullName)
+		}
+		for _, export := range metadata.Cgo.Exports {
+			fmt.Printf("0x%-18x %-8s %s calls %s", export.VA, "exported", export.Name, export.GoFunction)
+			if export.Inlined {
+				fmt.Printf(", %s inlined", export.Function)
+			} else if len(export.Function) > 0 {
+				fmt.Printf(", %s at 0x%x", export.Function, export.FunctionVA)
+			}
+			fmt.Println()
+		}
+	}
+
+	if len(metadata.StdGlobals) > 0 {
+		fmt.Println("\n-Std Globals-")
+		for _, global := range metadata.StdGlobals {
+			fmt.Printf("0x%-18x %s Customized:%t %s\n", global.VA, global.Name, global.Customized, global.ConcreteType)
+			for _, writer := range global.Writers {
+				fmt.Printf("\tassigned by %s\n", writer)
+			}
+			for _, reader := range global.Readers {
+				fmt.Printf("\tread by %s\n", reader)
+			}
+		}
+	}
+
+	fmt.Println("\n-Context Call Sites-")
+	if len(metadata.ContextCallSites) > 0 {
+		for _, site := range metadata.ContextCallSites {
+			fmt.Printf("0x%-18x %s -> %s\n", site.VA, site.Caller, site.Callee)
+		}
+	} else {
+		fmt.Println("<NO CONTEXT CALL SITES FOUND>")
+	}
+
+	fmt.Println("\n-Reflect Field Accesses-")
+	if len(metadata.ReflectFieldAccesses) > 0 {
+		for _, site := range metadata.ReflectFieldAccesses {
+			fmt.Printf("0x%-18x %s -> %s(%q)\n", site.VA, site.Caller, site.Callee, site.Arg)
+		}
+	} else {
+		fmt.Println("<NO REFLECT FIELD ACCESSES FOUND>")
+	}
+
+	fmt.Println("\n-Expvar Names-")
+	if len(metadata.ExpvarNames) > 0 {
+		for _, site := range metadata.ExpvarNames {
+			fmt.Printf("0x%-18x %s -> %s(%q)\n", site.VA, site.Caller, site.Callee, site.Arg)
+		}
+	} else {
+		fmt.Println("<NO EXPVAR NAMES FOUND>")
+	}
+
 	fmt.Println("\n-Standard Functions-")
 	if len(metadata.StdFunctions) > 0 {
 		for i, fn := range metadata.StdFunctions {
@@ -400,6 +404,31 @@ func printForHuman(metadata ExtractMetadata) {
 	} else {
 		fmt.Println("<NO STANDARD FUNCTIONS EXTRACTED>")
 	}
+
+	if metadata.Diagnostics != nil {
+		fmt.Println("\n-Diagnostics-")
+		for _, sig := range metadata.Diagnostics.Signatures {
+			fmt.Printf("%-20s hits %d validated %d\n", sig.Signature+":", sig.Hits, sig.Validated)
+		}
+		for _, sec := range metadata.Diagnostics.Sections {
+			fmt.Printf("%-20s 0x%x size 0x%x executable %t\n", sec.Name, sec.VA, sec.Size, sec.Executable)
+		}
+		for _, match := range metadata.Diagnostics.Matches {
+			fmt.Printf("%s signature matched at %s+0x%x (0x%x) -> candidate moduledata 0x%x validated %t score %d\n", match.Signature, match.Section, match.SectionOffset, match.VA, match.ModuleDataVA, match.Validated, match.Score)
+		}
+	}
+
+	if metadata.Timings != nil {
+		fmt.Println("\n-TIMINGS (ms)-")
+		fmt.Printf("%-20s %.3f\n", "Open:", metadata.Timings.Open)
+		fmt.Printf("%-20s %.3f\n", "PclntabScan:", metadata.Timings.PclntabScan)
+		fmt.Printf("%-20s %.3f\n", "ModuleData:", metadata.Timings.ModuleData)
+		fmt.Printf("%-20s %.3f\n", "Types:", metadata.Timings.Types)
+		fmt.Printf("%-20s %.3f\n", "Analysis:", metadata.Timings.Analysis)
+		fmt.Printf("%-20s %.3f\n", "Functions:", metadata.Timings.Functions)
+		fmt.Printf("%-20s %.3f\n", "Serialization:", metadata.Timings.Serialization)
+		fmt.Printf("%-20s %.3f\n", "Total:", metadata.Timings.Total)
+	}
 }
 
 func DataToJson(data interface{}) string {
@@ -411,7 +440,24 @@ func DataToJson(data interface{}) string {
 }
 
 func TextToJson(key string, text string) string {
-	return fmt.Sprintf("{\"%s\": \"%s\"}", key, text)
+	// errors quote user input, like a signature file's pattern, so escape rather than print as is
+	encodedKey, _ := json.Marshal(key)
+	encodedText, _ := json.Marshal(text)
+	return fmt.Sprintf("{%s: %s}", encodedKey, encodedText)
+}
+
+// loadSignatureFile reads the signatures of a -sigfile, scanned after the built-in ones
+func loadSignatureFile(path string) ([]objfile.CustomSignature, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature file: %w", err)
+	}
+
+	sigs, err := objfile.ParseSignatures(data)
+	if err != nil {
+		return nil, fmt.Errorf("bad signature file %s: %w", path, err)
+	}
+	return sigs, nil
 }
 
 func main() {
@@ -429,7 +475,65 @@ func main() {
 	typeAddress := flag.Int("m", 0, "Manually parse the RTYPE at the provided virtual address, disables automated enumeration of moduledata typelinks itablinks")
 	versionOverride := flag.String("v", "", "Override the automated version detection, ex: 1.17. If this is wrong, parsing may fail or produce nonsense")
 	humanView := flag.Bool("human", false, "Human view, print information flat rather than json, some information is omitted for clarity")
+	printTimestamps := flag.Bool("timestamps", false, "Scan initialized data for time.Time values, such as hardcoded expiry dates")
+	outputFormat := flag.String("outputformat", "json", "Output format, one of: json, ndjson, csv, idapy, ghidra, r2, x64dbg, map, sqlite, yara. ndjson streams one JSON object per line as the results are recovered, a header, then the types, interfaces and functions, then the rest of the metadata. csv emits one row per function, or per type with -csv-table types, other information is omitted. idapy, ghidra and r2 emit an IDAPython, Ghidra or radare2/rizin script applying the function names, boundaries and source lines and the type names. x64dbg and map emit the names at their RVAs in a PE, as an x64dbg script and as 'RVA name' lines. sqlite appends the binary, its functions and types to the sqlite database -out names with the sqlite3 shell, or emits the SQL script without -out. yara emits a YARA rule of the module paths, user function names and source files of each input")
+	outFile := flag.String("out", "", "Write the output to this file rather than stdout, ex: -outputformat idapy -out apply.py. For -outputformat sqlite it is the database appended to, also by a batch run, with the sqlite3 shell which must be on PATH")
+	csvTable := flag.String("csv-table", "", "Table of -outputformat csv, one of: functions, types. By default the functions are written and with -out the types too, to the -out file name with _types appended")
+	noHeader := flag.Bool("no-header", false, "Leave the header row out of -outputformat csv")
+	reconstruct := flag.String("reconstruct", "", "Print declarations of the recovered types instead, one of: go, c. go emits Go source of every named type, structs with their fields, offsets and tags, c a C header of them for IDA or Ghidra with the layout of the binary's architecture. Implies -t")
+	yaraStrings := flag.Int("yara-strings", 10, "Most user function names and source files each YARA rule keeps, the most distinctive first")
+	yaraFamily := flag.String("yara-family", "", "Merge the inputs of -outputformat yara into one rule of this name, of the strings every input has")
+	patchOut := flag.String("patch-out", "", "Write a copy of the ELF with a .symtab of every recovered function to this file, for gdb, objdump and perf. The std functions are then always recovered, as with -d")
+	patchDwarf := flag.Bool("patch-dwarf", false, "With -patch-out, also add DWARF describing every function and its source lines from the pclntab")
+	extractEmbedded := flag.String("extract-embedded", "", "Write the files of every go:embed embed.FS to this directory, one directory per embed.FS named after its variable")
+	dumpRaw := flag.String("dump-raw", "", "Write the raw bytes of the moduledata, pclntab, typelinks and build info found to this directory, each named with its VA, with a manifest.json of where they came from")
+	timings := flag.Bool("timings", false, "Emit the time spent in each extraction phase as a Timings object")
+	profile := flag.Bool("profile", false, "Same as -timings, its older name")
+	verbose := flag.Bool("verbose", false, "Log the progress of the extraction to stderr: the sections scanned, the signatures that matched, the pclntab and moduledata candidates picked and rejected, and the counts and time of each phase")
+	veryVerbose := flag.Bool("vv", false, "-verbose with every signature, signature match and candidate tried")
+	diagnostics := flag.Bool("diagnostics", false, "Emit the moduledata signature hits and the scanned sections as a Diagnostics object, also when parsing fails")
+	var includeFuncs, excludeFuncs, includeTypes, excludeTypes objfile.Patterns
+	flag.Var(&includeFuncs, "include-func", "Only extract the functions whose full names match this RE2 `pattern`, repeatable, ex: -include-func '^main\\.'")
+	flag.Var(&excludeFuncs, "exclude-func", "Don't extract the functions whose full names match this RE2 `pattern`, repeatable. Excludes win over includes")
+	flag.Var(&includeTypes, "include-type", "Only parse the types whose names match this RE2 `pattern`, repeatable. The others aren't recursed into")
+	flag.Var(&excludeTypes, "exclude-type", "Don't parse the types whose names match this RE2 `pattern`, repeatable. Excludes win over includes")
+	pcsp := flag.Bool("pcsp", false, "List the sp delta of each function wherever it changes, from its pcsp table")
+	pcdata := flag.Bool("pcdata", false, "List the safe, unsafe and restartable pc ranges of each function from its unsafe point pcdata, and count its stack maps. Go 1.2 and later, the unsafe points from 1.14")
+	stringLiterals := flag.Bool("strings", false, "List the string literals each function references, amd64 and arm64 only. The strings of the data no code was seen to load are in UnattributedStrings")
+	hashFuncs := flag.Bool("hash", false, "Hash the code of each function, a SHA256 of its bytes and on amd64 a position independent one with the pc relative references out of the function zeroed, so the same code in two builds hashes the same. The set of them is in FunctionHashes, for diffing two runs")
+	callSites := flag.Bool("callsites", false, "Disassemble each function for its calls to context.WithTimeout, WithCancel and WithDeadline, to reflect's FieldByName and to expvar's publishing functions, listed in ContextCallSites, ReflectFieldAccesses and ExpvarNames with the constant field and variable names")
+	hashMin := flag.Int("hash-min-size", 32, "With -hash, the fewest bytes a function has to be hashed, the smaller stubs and wrappers are alike everywhere")
+	inlined := flag.Bool("inlined", false, "Decode the inline tree of each function, listing the functions inlined into it with their call sites and code, and list every name seen in AllFunctionNames. Go 1.12 and later")
+	sigFile := flag.String("sigfile", "", "JSON file of additional moduledata signatures, scanned after the built-in ones")
+	loadBase := flag.Uint64("base", 0, "Address the image was loaded at, for dumps of a relocated image or with -mode dump, ex: 0x10000000")
+	slide := flag.Uint64("slide", 0, "Load bias of a position independent ELF, the address it was loaded at minus the one in its headers, for dumps and prelinked images whose pointers were relocated. Detected from the relocations by default, ex: 0x7f0000000000")
+	mode := flag.String("mode", "file", "Input kind, one of: file, dump, raw. dump parses already mapped memory, such as an image carved out of a memory acquisition, raw the same without reading any headers")
+	scanOverlay := flag.Bool("scan-overlay", false, "Also scan the data appended after the last PE section for a pclntab, droppers keep their payload there")
+	scanResources := flag.Bool("scan-resources", false, "Also scan each resource of a PE file for a pclntab, droppers keep their payload there too")
+	moduleData := flag.Uint64("moduledata", 0, "Virtual address of the moduledata to extract from instead of scanning for it, ex: one located by hand in a binary the scan misses. It's validated like a scanned one, ex: 0x71c080")
+	moduleDataOffset := flag.Uint64("moduledata-offset", 0, "Same as -moduledata with the file offset of the moduledata, from the start of the slice of a fat Mach-O")
+	pclntab := flag.Uint64("pclntab", 0, "Virtual address of a pclntab to parse without a moduledata, when the moduledata is gone. Its layout is told by its magic, only the functions and source lines are recovered, ex: 0x6042c0")
+	pclntabOffset := flag.Uint64("pclntab-offset", 0, "Same as -pclntab with the file offset of the pclntab, from the start of the slice of a fat Mach-O")
+	textStart := flag.Uint64("textstart", 0, "With -pclntab, the text start the functions of a Go 1.18 or later pclntab are relative to, instead of the one its header records")
+	var scanRanges []objfile.ScanRange
+	flag.Var(objfile.ScanRanges{Ranges: &scanRanges}, "scan-range", "Only scan this `start:end` range of VAs for the pclntab and the moduledata, repeatable, ex: -scan-range 0x401000:0x480000 to target the runtime init code or one of two embedded Go binaries. The moduledata and pclntab the matches lead to may lie outside it")
+	flag.Var(objfile.ScanRanges{Ranges: &scanRanges, Offset: true}, "scan-range-offset", "Same as -scan-range with a `start:end` range of file offsets, repeatable")
+	tolerantPclntab := flag.Bool("tolerant", false, "Parse a partially corrupted pclntab function by function: a function whose name is out of bounds is named sub_<entry>, one whose line table doesn't decode loses its source lines, and entries out of order don't end the table. Each is listed in Corruption")
+	heuristicFunctions := flag.Bool("heuristic-funcs", false, "When no pclntab is found, find the functions of an amd64 binary from the calls of their stack checks to runtime.morestack instead, a last resort. They're listed in Heuristic, named sub_<start> unless the symbols, exports or type methods name them, and end where the next starts")
+	hooks := flag.Bool("detect-hooks", false, "Check the entry of every function for a hook, a jump out of the Go text to code of no known module, ex: in a dump of a process whose runtime an agent patched. The functions found are listed in Hooks with the jump target and their first bytes")
+	originalFile := flag.String("compare-file", "", "With -detect-hooks, the file the dumped image was loaded from, a function whose first bytes differ from its entry there is a hook too")
+	methods := flag.Bool("methods", false, "With -t, list the methods of each type in Methods with the functions implementing them, and the types implementing each interface with their method tables in Itabs. Each method reads its name and both its functions, which adds time and memory on a large binary")
+	gcLayouts := flag.Bool("gcdata", false, "With -t, decode the pointer layout of each type from its gcdata into GC: the offsets of the words of a value that hold pointers and their bitmap, for carving structures out of heap dumps. Large types have a GC program up to Go 1.23 and a bitmap built from their fields from Go 1.24 on")
+	selectImage := flag.Int("select-image", -1, "Extract only this Go executable embedded in the input, by its Index in EmbeddedImages, or 0 for the input itself. By default an input embedding Go executables, ex: a dropper and its payload, gets a result for itself and for each one")
+	dumpArch := flag.String("arch", "", "GOARCH of a -mode dump or raw input, required when the dump doesn't start with PE or ELF headers, or of the slice of a fat Mach-O to parse, ex: amd64")
+	workers := flag.Int("workers", runtime.GOMAXPROCS(0), "Files a batch run extracts at once, see -out-dir")
+	outDir := flag.String("out-dir", "", "Write the result of each file of a batch run to <sha256>.json in this directory, the records on stdout then name it. A batch run is started by a directory argument, walked for the files that look like Go binaries, or by - to read the list of files from stdin")
+	timeout := flag.Duration("timeout", 0, "Fail a file of a batch run whose extraction takes longer than this, ex: 2m. 0 for no limit")
+	jsonErrors := flag.Bool("json-errors", false, "On a failure print a JSON object with the class of the error, its message and what was gathered before it failed: the architecture, the Go version guess and the pclntab candidates tried. The exit code tells the class either way, see the exit codes below")
+	flag.Usage = usage
 	flag.Parse()
+	defer exitOnPanic(jsonErrors)
+	batch := flag.NArg() == 1 && isBatchInput(flag.Arg(0))
 
 	if *about {
 		fmt.Println("GoReSym is a Golang symbol recovery tool by Google's Mandiant FLARE team. Maintained by Stephen Eckels.")
@@ -441,20 +545,459 @@ func main() {
 		os.Exit(0)
 	}
 
-	if flag.NArg() != 1 {
+	if *outputFormat != "json" && *outputFormat != "ndjson" && *outputFormat != "csv" && *outputFormat != "idapy" && *outputFormat != "ghidra" && *outputFormat != "r2" && *outputFormat != "x64dbg" && *outputFormat != "map" && *outputFormat != "sqlite" && *outputFormat != "yara" {
+		fmt.Println(TextToJson("error", fmt.Sprintf("unknown output format %s", *outputFormat)))
+		os.Exit(1)
+	}
+	// the database is written by the sqlite3 shell, a missing one fails before anything is extracted
+	var sqliteDB string
+	if *outputFormat == "sqlite" && len(*outFile) > 0 {
+		if _, err := sqliteShell(); err != nil {
+			fmt.Println(TextToJson("error", err.Error()))
+			os.Exit(1)
+		}
+		sqliteDB = *outFile
+	}
+
+	if *reconstruct != "" && *reconstruct != "go" && *reconstruct != "c" {
+		fmt.Println(TextToJson("error", fmt.Sprintf("unknown reconstruct language %s", *reconstruct)))
+		os.Exit(1)
+	}
+	if *reconstruct != "" && *outputFormat != "json" {
+		fmt.Println(TextToJson("error", "-reconstruct prints the types instead of the -outputformat, use one of them"))
+		os.Exit(1)
+	}
+	if *reconstruct != "" {
+		*printTypes = true
+	}
+
+	if *csvTable != "" && *csvTable != "functions" && *csvTable != "types" {
+		fmt.Println(TextToJson("error", fmt.Sprintf("unknown csv table %s", *csvTable)))
+		os.Exit(1)
+	}
+
+	var customSignatures []objfile.CustomSignature
+	if len(*sigFile) > 0 {
+		sigs, err := loadSignatureFile(*sigFile)
+		if err != nil {
+			fmt.Println(TextToJson("error", err.Error()))
+			os.Exit(1)
+		}
+		customSignatures = sigs
+	}
+
+	if *mode != "file" && *mode != "dump" && *mode != "raw" {
+		fmt.Println(TextToJson("error", fmt.Sprintf("unknown mode %s", *mode)))
+		os.Exit(1)
+	}
+
+	// errors are still printed, the file only gets a result. The sqlite results are appended to the database -out names instead.
+	var output io.Writer = os.Stdout
+	if len(*outFile) > 0 && *outputFormat != "sqlite" {
+		out, err := os.Create(*outFile)
+		if err != nil {
+			fmt.Println(TextToJson("error", fmt.Sprintf("Failed to create %s: %s", *outFile, err)))
+			os.Exit(1)
+		}
+		defer out.Close()
+		output = out
+	}
+
+	// the patch needs every function, the stream doesn't keep them
+	if *outputFormat == "ndjson" && len(*patchOut) > 0 {
+		fmt.Println(TextToJson("error", "-patch-out needs the functions in memory, use another output format with it"))
+		os.Exit(1)
+	}
+	if *outputFormat == "ndjson" && !*humanView && !batch {
+		ndjsonOut = newNdjsonStream(output)
+	}
+
+	*profile = *profile || *timings
+	if *verbose {
+		verbosity = 1
+	}
+	if *veryVerbose {
+		verbosity = 2
+	}
+	if *moduleData != 0 && *moduleDataOffset != 0 {
+		fmt.Println(TextToJson("error", "-moduledata and -moduledata-offset locate the same moduledata, use one of them"))
+		os.Exit(1)
+	}
+	if *pclntab != 0 && *pclntabOffset != 0 {
+		fmt.Println(TextToJson("error", "-pclntab and -pclntab-offset locate the same pclntab, use one of them"))
+		os.Exit(1)
+	}
+	if (*moduleData != 0 || *moduleDataOffset != 0) && (*pclntab != 0 || *pclntabOffset != 0) {
+		fmt.Println(TextToJson("error", "-moduledata leads to its pclntab, -pclntab is for when there's no moduledata, use one of them"))
+		os.Exit(1)
+	}
+	if *loadBase != 0 && *slide != 0 {
+		fmt.Println(TextToJson("error", "-base and -slide both place the image, use one of them"))
+		os.Exit(1)
+	}
+	if *textStart != 0 && *pclntab == 0 && *pclntabOffset == 0 {
+		fmt.Println(TextToJson("error", "-textstart is the text start of a -pclntab, use it with -pclntab or -pclntab-offset"))
+		os.Exit(1)
+	}
+	if len(*originalFile) > 0 && !*hooks {
+		fmt.Println(TextToJson("error", "-compare-file is the original of -detect-hooks, use it with -detect-hooks"))
+		os.Exit(1)
+	}
+
+	// every extraction of the run starts from these, a slice of a fat Mach-O or an embedded image sets its Arch or Image on a copy
+	opts := options(*printStdPkgs, *printFilePaths, *printTypes, *noPrintFunctions, *typeAddress, *versionOverride, *printTimestamps)
+	opts.Inlined = *inlined
+	opts.SPDeltas = *pcsp
+	opts.PCData = *pcdata
+	opts.Strings = *stringLiterals
+	opts.Hashes = *hashFuncs
+	opts.CallSites = *callSites
+	opts.HashMinSize = uint64(*hashMin)
+	opts.FuncFilter = &objfile.NameFilter{Include: includeFuncs, Exclude: excludeFuncs}
+	opts.TypeFilter = &objfile.NameFilter{Include: includeTypes, Exclude: excludeTypes}
+	// the offsets are only resolved once the file is open
+	opts.ModuleData, opts.ModuleDataOffset = *moduleData, *moduleDataOffset
+	opts.Pclntab, opts.PclntabOffset, opts.TextStart = *pclntab, *pclntabOffset, *textStart
+	opts.ScanRanges = scanRanges
+	opts.Tolerant = *tolerantPclntab
+	// only when no pclntab is found
+	opts.HeuristicFunctions = *heuristicFunctions
+	opts.Image = max(*selectImage, 0)
+	opts.GCData = *gcLayouts
+	opts.Methods = *methods
+	opts.DetectHooks, opts.CompareFile = *hooks, *originalFile
+	opts.LoadBase, opts.Slide = *loadBase, *slide
+	opts.Mode, opts.Arch = *mode, *dumpArch
+	opts.ScanOverlay, opts.ScanResources = *scanOverlay, *scanResources
+	opts.Signatures = customSignatures
+	if ndjsonOut != nil {
+		opts.Stream = ndjsonOut.record
+	}
+	if verbosity > 0 {
+		opts.Log = logProgress
+	}
+
+	if batch {
+		// every file gets its record of the stream, its own little document
+		if (*outputFormat != "json" && *outputFormat != "ndjson" && *outputFormat != "sqlite") || *humanView || *reconstruct != "" || len(*patchOut) > 0 || len(*extractEmbedded) > 0 || len(*dumpRaw) > 0 || len(*originalFile) > 0 || *mode != "file" || *moduleData != 0 || *moduleDataOffset != 0 || *pclntab != 0 || *pclntabOffset != 0 || *selectImage >= 0 {
+			fmt.Println(TextToJson("error", "a batch run writes NDJSON records of the files, -outputformat other than json, ndjson and sqlite, -human, -reconstruct, -patch-out, -extract-embedded, -dump-raw, -compare-file, -mode, -moduledata, -pclntab and -select-image don't apply to it"))
+			os.Exit(1)
+		}
+		// the records of the files go to stdout, the database gets their rows
+		if *outputFormat == "sqlite" && (len(*outFile) == 0 || len(*outDir) > 0) {
+			fmt.Println(TextToJson("error", "a batch run with -outputformat sqlite appends every file to the database -out names, -out is required and -out-dir doesn't apply"))
+			os.Exit(1)
+		}
+		config := batchConfig{
+			workers:     *workers,
+			outDir:      *outDir,
+			sqliteDB:    sqliteDB,
+			timeout:     *timeout,
+			opts:        opts,
+			diagnostics: *diagnostics,
+			timings:     *profile,
+		}
+		if err := runBatch(flag.Arg(0), config, output); err != nil {
+			fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write the batch results: %s", err)))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// a rule can cover many samples, the other formats take one file
+	if flag.NArg() != 1 && (*outputFormat != "yara" || flag.NArg() == 0) {
 		fmt.Println(TextToJson("error", "filepath must be provided as first argument"))
 		os.Exit(1)
 	}
 
-	metadata, err := main_impl(flag.Arg(0), *printStdPkgs, *printFilePaths, *printTypes, *noPrintFunctions, *typeAddress, *versionOverride)
-	if err != nil {
-		fmt.Println(TextToJson("error", fmt.Sprintf("Failed to parse file: %s", err)))
+	if *outputFormat == "yara" {
+		var samples []yaraSample
+		for _, path := range flag.Args() {
+			sample, err := extractYaraSample(path, opts)
+			if err != nil {
+				message := fmt.Sprintf("Failed to parse file %s: %s", path, err)
+				if *jsonErrors {
+					fmt.Println(DataToJson(newJsonError(message, err, goresym.Report{})))
+				} else {
+					fmt.Println(TextToJson("error", message))
+				}
+				os.Exit(exitCode(err))
+			}
+			samples = append(samples, sample)
+		}
+		if err := printYara(output, samples, *yaraFamily, *yaraStrings); err != nil {
+			fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write rule: %s", err)))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// a fat Mach-O gets a result per slice, unless -arch picks one
+	fatArchs, _ := objfile.FatArchs(flag.Arg(0))
+	if len(fatArchs) > 0 && len(*dumpArch) > 0 && !slices.Contains(fatArchs, *dumpArch) {
+		fmt.Println(TextToJson("error", fmt.Sprintf("no %s slice, the slices are %s", *dumpArch, strings.Join(fatArchs, ", "))))
 		os.Exit(1)
+	}
+	// an input embedding Go executables gets a result per image, the input first, unless -select-image picks one
+	var images []objfile.EmbeddedImage
+	if len(fatArchs) <= 1 && *selectImage < 0 && *mode == "file" {
+		images, _ = objfile.EmbeddedImages(flag.Arg(0))
+	}
+	if *selectImage > 0 && len(*patchOut) > 0 {
+		fmt.Println(TextToJson("error", "-patch-out patches the input, an embedded image can't be patched in place"))
+		os.Exit(1)
+	}
+
+	// extractEach extracts the count slices or images, pick selects the i-th in its copy of opts and label names it in Failed and in
+	// the directories of -extract-embedded and -dump-raw, failure is its record of the stream when it doesn't parse
+	extractEach := func(count int, pick func(opts *goresym.Options, i int), label func(i int) string, failure func(i int, err error) interface{}) (results []goresym.Report, failed map[string]string, firstErr error) {
+		for i := 0; i < count; i++ {
+			picked := opts
+			pick(&picked, i)
+			metadata, err := extractFile(flag.Arg(0), picked)
+			if err != nil {
+				if failed == nil {
+					failed = make(map[string]string)
+				}
+				failed[label(i)] = err.Error()
+				if firstErr == nil {
+					firstErr = err
+				}
+				if ndjsonOut != nil {
+					ndjsonOut.record("error", failure(i, err))
+				}
+				continue
+			}
+			if !*diagnostics {
+				metadata.Diagnostics = nil
+			}
+			if !*profile {
+				metadata.Timings = nil
+			}
+			if len(*extractEmbedded) > 0 {
+				if err := writeEmbeddedFiles(filepath.Join(*extractEmbedded, label(i)), metadata); err != nil {
+					fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write the embedded files: %s", err)))
+					os.Exit(1)
+				}
+			}
+			if len(*dumpRaw) > 0 {
+				if err := writeRawStructures(filepath.Join(*dumpRaw, label(i)), metadata); err != nil {
+					fmt.Println(TextToJson("error", fmt.Sprintf("Failed to dump the raw structures: %s", err)))
+					os.Exit(1)
+				}
+			}
+			if ndjsonOut != nil {
+				// the records of a slice are done once its metadata is out, nothing is kept for later
+				ndjsonOut.record("metadata", metadata)
+				results = append(results, goresym.Report{})
+				continue
+			}
+			results = append(results, metadata)
+		}
+		return results, failed, firstErr
+	}
+	// printEach writes the results of extractEach as document, heading introduces each in the human view. none is the error when
+	// nothing parsed.
+	printEach := func(results []goresym.Report, failed map[string]string, firstErr error, document interface{}, heading func(metadata goresym.Report) string, none string) {
+		if ndjsonOut != nil {
+			if err := ndjsonOut.flush(); err != nil {
+				fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write ndjson: %s", err)))
+				os.Exit(1)
+			}
+			if len(results) == 0 {
+				fmt.Println(TextToJson("error", "Failed to parse file: "+none))
+				os.Exit(exitCode(firstErr))
+			}
+			return
+		}
+		if len(results) == 0 {
+			if *jsonErrors {
+				failure := newJsonError("Failed to parse file: "+none, firstErr, goresym.Report{})
+				failure.Failed = failed
+				fmt.Println(DataToJson(failure))
+			} else {
+				fmt.Println(DataToJson(struct {
+					Error  string `json:"error"`
+					Failed map[string]string
+				}{"Failed to parse file: " + none, failed}))
+			}
+			os.Exit(exitCode(firstErr))
+		}
+
+		if *humanView {
+			for _, metadata := range results {
+				fmt.Println(heading(metadata))
+				printForHuman(metadata)
+			}
+		} else if *outputFormat == "csv" {
+			writeCsv(output, *outFile, *csvTable, !*noHeader, results...)
+		} else if *outputFormat == "sqlite" {
+			writeSqlite(output, *outFile, flag.Arg(0), results...)
+		} else {
+			writeJson(output, document)
+		}
+	}
+	// a database holds one slice or image, the addresses of the others would be wrong in it
+	script := *outputFormat == "idapy" || *outputFormat == "ghidra" || *outputFormat == "r2" || *outputFormat == "x64dbg" || *outputFormat == "map" || *reconstruct != ""
+
+	if len(fatArchs) > 1 && len(*dumpArch) == 0 {
+		if script {
+			fmt.Println(TextToJson("error", fmt.Sprintf("a script applies to one slice, pick it with -arch, the slices are %s", strings.Join(fatArchs, ", "))))
+			os.Exit(1)
+		}
+
+		var fat FatMetadata
+		var firstErr error
+		fat.Slices, fat.Failed, firstErr = extractEach(len(fatArchs), func(opts *goresym.Options, i int) {
+			opts.Arch = fatArchs[i]
+		}, func(i int) string {
+			return fatArchs[i]
+		}, func(i int, err error) interface{} {
+			return struct{ Arch, Error string }{fatArchs[i], err.Error()}
+		})
+		printEach(fat.Slices, fat.Failed, firstErr, fat, func(metadata goresym.Report) string {
+			return fmt.Sprintf("-SLICE %s-", metadata.Arch)
+		}, "no Go slice")
+		return
+	}
+
+	if len(images) > 0 {
+		if script || len(*patchOut) > 0 {
+			fmt.Println(TextToJson("error", fmt.Sprintf("a script or patch applies to one image, pick it with -select-image, 0 is the input and it embeds %d", len(images))))
+			os.Exit(1)
+		}
+
+		var embedded ImagesMetadata
+		var firstErr error
+		embedded.Images, embedded.Failed, firstErr = extractEach(len(images)+1, func(opts *goresym.Options, i int) {
+			opts.Image = i
+		}, func(i int) string {
+			return fmt.Sprint(i)
+		}, func(i int, err error) interface{} {
+			return struct {
+				Image int
+				Error string
+			}{i, err.Error()}
+		})
+		printEach(embedded.Images, embedded.Failed, firstErr, embedded, func(metadata goresym.Report) string {
+			if metadata.Image == nil {
+				return "-IMAGE 0 (the input)-"
+			}
+			return fmt.Sprintf("-IMAGE %d (%s at 0x%x in %s)-", metadata.Image.Index, metadata.Image.Format, metadata.Image.FileOffset, imageRegion(*metadata.Image))
+		}, "no Go image")
+		return
+	}
+
+	// the patched symbol table should have every function
+	if len(*patchOut) > 0 {
+		opts.StdFunctions, opts.NoFunctions = true, false
+	}
+	metadata, err := extractFile(flag.Arg(0), opts)
+	if err != nil {
+		if !*diagnostics {
+			metadata.Diagnostics = nil
+		}
+		if ndjsonOut != nil {
+			// what was streamed before the failure goes out ahead of the error
+			ndjsonOut.flush()
+		}
+		if *jsonErrors {
+			fmt.Println(DataToJson(newJsonError(fmt.Sprintf("Failed to parse file: %s", err), err, metadata)))
+		} else if metadata.Diagnostics != nil || metadata.LikelyPacked != nil {
+			fmt.Println(DataToJson(struct {
+				Error        string                   `json:"error"`
+				LikelyPacked *objfile.PackingInfo     `json:",omitempty"`
+				Diagnostics  *objfile.ScanDiagnostics `json:",omitempty"`
+			}{fmt.Sprintf("Failed to parse file: %s", err), metadata.LikelyPacked, metadata.Diagnostics}))
+		} else {
+			fmt.Println(TextToJson("error", fmt.Sprintf("Failed to parse file: %s", err)))
+		}
+		os.Exit(exitCode(err))
 	} else {
+		if !*diagnostics {
+			metadata.Diagnostics = nil
+		}
+
+		if len(*patchOut) > 0 {
+			if err := writePatchedElf(flag.Arg(0), *patchOut, metadata, *patchDwarf); err != nil {
+				fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write %s: %s", *patchOut, err)))
+				os.Exit(1)
+			}
+		}
+
+		if len(*extractEmbedded) > 0 {
+			if err := writeEmbeddedFiles(*extractEmbedded, metadata); err != nil {
+				fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write the embedded files: %s", err)))
+				os.Exit(1)
+			}
+		}
+
+		if len(*dumpRaw) > 0 {
+			if err := writeRawStructures(*dumpRaw, metadata); err != nil {
+				fmt.Println(TextToJson("error", fmt.Sprintf("Failed to dump the raw structures: %s", err)))
+				os.Exit(1)
+			}
+		}
+
+		if *profile {
+			// serialization can't time itself, encode once to measure and again with the measurement included
+			serializationStart := time.Now()
+			writeJson(io.Discard, metadata)
+			serializationTime := time.Since(serializationStart)
+			metadata.Timings.AddSerialization(serializationTime)
+		} else {
+			metadata.Timings = nil
+		}
+
 		if *humanView {
 			printForHuman(metadata)
+		} else if *reconstruct == "go" {
+			if err := printReconstructedGo(output, flag.Arg(0), metadata); err != nil {
+				fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write types: %s", err)))
+				os.Exit(1)
+			}
+		} else if *reconstruct == "c" {
+			if err := printCHeader(output, flag.Arg(0), metadata); err != nil {
+				fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write types: %s", err)))
+				os.Exit(1)
+			}
+		} else if *outputFormat == "csv" {
+			writeCsv(output, *outFile, *csvTable, !*noHeader, metadata)
+		} else if *outputFormat == "idapy" {
+			if err := printIdaPython(output, flag.Arg(0), metadata); err != nil {
+				fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write script: %s", err)))
+				os.Exit(1)
+			}
+		} else if *outputFormat == "ghidra" {
+			if err := printGhidraScript(output, flag.Arg(0), *loadBase == 0 && metadata.Slide == 0 && linkedAtZero(flag.Arg(0)), metadata); err != nil {
+				fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write script: %s", err)))
+				os.Exit(1)
+			}
+		} else if *outputFormat == "r2" {
+			if err := printR2Script(output, flag.Arg(0), metadata); err != nil {
+				fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write script: %s", err)))
+				os.Exit(1)
+			}
+		} else if *outputFormat == "x64dbg" {
+			if err := printX64dbgScript(output, flag.Arg(0), metadata); err != nil {
+				fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write script: %s", err)))
+				os.Exit(1)
+			}
+		} else if *outputFormat == "map" {
+			if err := printMapFile(output, metadata); err != nil {
+				fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write map: %s", err)))
+				os.Exit(1)
+			}
+		} else if *outputFormat == "sqlite" {
+			writeSqlite(output, *outFile, flag.Arg(0), metadata)
+		} else if *outputFormat == "ndjson" {
+			ndjsonOut.record("metadata", metadata)
+			if err := ndjsonOut.flush(); err != nil {
+				fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write ndjson: %s", err)))
+				os.Exit(1)
+			}
 		} else {
-			fmt.Println(DataToJson((metadata)))
+			writeJson(output, metadata)
 		}
 	}
 }
@@ -2,12 +2,32 @@
 package main
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log"
+	"math/rand"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"testing"
 
+	"github.com/mandiant/GoReSym/debug/dwarf"
+	"github.com/mandiant/GoReSym/debug/elf"
+	"github.com/mandiant/GoReSym/goresym"
+	"github.com/mandiant/GoReSym/objfile"
+	"github.com/mandiant/GoReSym/runtime/debug"
+
 	_ "net/http/pprof"
 )
 
@@ -30,7 +50,7 @@ func TestAllVersions(t *testing.T) {
 			}
 
 			t.Run(versionPath, func(t *testing.T) {
-				data, err := main_impl(filePath, true, true, true, true, 0, "")
+				data, err := main_impl(filePath, true, true, true, true, 0, "", false)
 				if err != nil {
 					t.Errorf("Go %s failed on %s: %s", v, file, err)
 				}
@@ -119,7 +139,7 @@ func testSymbolRecovery(t *testing.T, workingDirectory string, binaryName string
 		return
 	}
 
-	data, err := main_impl(filePath, true, true, true, true, 0, "")
+	data, err := main_impl(filePath, true, true, true, true, 0, "", false)
 	if err != nil {
 		t.Errorf("GoReSym failed: %s", err)
 	}
@@ -132,19 +152,26 @@ func testSymbolRecovery(t *testing.T, workingDirectory string, binaryName string
 		t.Errorf("incorrect moduledata VA: %016x", data.ModuleMeta.VA)
 	}
 
-	foundMain := false
-	for _, fn := range data.UserFunctions {
-		if fn.FullName == "main.main" {
-			if fn.Start != mainVA {
-				t.Errorf("main.main has wrong VA: %016x", fn.Start)
-			}
-			foundMain = true
-			break
-		}
+	// the functions aren't printed, main.main is looked up in the parsed pclntab
+	table := data.Pclntab()
+	if table == nil {
+		t.Errorf("main.main symbol not recovered")
+		return
 	}
-
-	if !foundMain {
+	fn := table.LookupFunc("main.main")
+	if fn == nil {
 		t.Errorf("main.main symbol not recovered")
+		return
+	}
+	if fn.Entry != mainVA {
+		t.Errorf("main.main has wrong VA: %016x", fn.Entry)
+	}
+	if sourceFile, startLine, endLine := table.LineRange(fn); startLine == 0 || endLine < startLine || !strings.HasSuffix(sourceFile, ".go") {
+		t.Errorf("main.main has no line range: %s %d to %d", sourceFile, startLine, endLine)
+	}
+	// it calls, there's a frame
+	if table.MaxFrameSize(fn) <= 0 {
+		t.Errorf("main.main has no frame size")
 	}
 }
 
@@ -206,6 +233,51 @@ func TestWeirdBins(t *testing.T) {
 		testSymbolRecovery(t, workingDirectory, "GoReSym_garbled", 0x6042c0, 0x71c080, 0x55b800)
 	})
 
+	// stripped go1.22.12 linux builds, the moduledata found through the signature of their GOARCH
+	t.Run("arm64_stripped_lin", func(t *testing.T) {
+		testSymbolRecovery(t, workingDirectory, "arm64_stripped_lin", 0xdde60, 0x140f00, 0x910b0)
+	})
+
+	t.Run("arm_stripped_lin", func(t *testing.T) {
+		testSymbolRecovery(t, workingDirectory, "arm_stripped_lin", 0xd24a0, 0x130cb8, 0x9ecfc)
+	})
+
+	t.Run("mips_hello_stripped_lin", func(t *testing.T) {
+		testSymbolRecovery(t, workingDirectory, "mips_hello_stripped_lin", 0xf6ac0, 0x150da0, 0xbcee4)
+	})
+
+	t.Run("mipsle_stripped_lin", func(t *testing.T) {
+		testSymbolRecovery(t, workingDirectory, "mipsle_stripped_lin", 0xf6a60, 0x150da0, 0xbce70)
+	})
+
+	t.Run("mips64_stripped_lin", func(t *testing.T) {
+		testSymbolRecovery(t, workingDirectory, "mips64_stripped_lin", 0xfdc80, 0x160f60, 0xbf758)
+	})
+
+	t.Run("mips64le_stripped_lin", func(t *testing.T) {
+		testSymbolRecovery(t, workingDirectory, "mips64le_stripped_lin", 0xfdcc0, 0x160f60, 0xbf378)
+	})
+
+	t.Run("riscv64_stripped_lin", func(t *testing.T) {
+		testSymbolRecovery(t, workingDirectory, "riscv64_stripped_lin", 0xccc20, 0x130f80, 0x8c168)
+	})
+
+	t.Run("loong64_stripped_lin", func(t *testing.T) {
+		testSymbolRecovery(t, workingDirectory, "loong64_stripped_lin", 0xdce20, 0x140f80, 0x9ba90)
+	})
+
+	t.Run("s390x_stripped_lin", func(t *testing.T) {
+		testSymbolRecovery(t, workingDirectory, "s390x_stripped_lin", 0xed840, 0x150ee0, 0xacf50)
+	})
+
+	t.Run("ppc64le_stripped_lin", func(t *testing.T) {
+		testSymbolRecovery(t, workingDirectory, "ppc64le_stripped_lin", 0xddd00, 0x140f20, 0x99bd0)
+	})
+
+	t.Run("ppc64_hello_stripped_lin", func(t *testing.T) {
+		testSymbolRecovery(t, workingDirectory, "ppc64_hello_stripped_lin", 0xdd660, 0x140f20, 0x98c80)
+	})
+
 	// We previosly threw on this binary. It has invalid section size for .bss section
 	t.Run("notgo_invalid_bss_secsize", func(t *testing.T) {
 		filePath := fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, "notgo_invalid_bss_secsize")
@@ -214,7 +286,7 @@ func TestWeirdBins(t *testing.T) {
 			return
 		}
 
-		_, err := main_impl(filePath, true, true, true, true, 0, "")
+		_, err := main_impl(filePath, true, true, true, true, 0, "", false)
 		if err == nil {
 			t.Errorf("GoReSym found pclntab in a non-go binary, this is not possible.")
 		}
@@ -228,7 +300,7 @@ func TestWeirdBins(t *testing.T) {
 			return
 		}
 
-		_, err := main_impl(filePath, true, true, true, true, 0, "")
+		_, err := main_impl(filePath, true, true, true, true, 0, "", false)
 		if err == nil {
 			t.Errorf("GoReSym found pclntab in a non-go binary, this is not possible.")
 		}
@@ -244,4 +316,2389 @@ func TestBig(t *testing.T) {
 	t.Run("kubectl_macho", func(t *testing.T) {
 		testSymbolRecovery(t, workingDirectory, "kubectl_macho", 0x6C6CB20, 0x7F8CB20, 0x5CD9E40)
 	})
+
+	// -t with the functions, written out like a run does. Sys is the most the runtime held at once, it isn't given back.
+	t.Run("kubectl_macho_memory", func(t *testing.T) {
+		data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/kubectl_macho", workingDirectory), true, true, true, false, 0, "", false)
+		if err != nil {
+			t.Fatalf("GoReSym failed: %s", err)
+		}
+		writeJson(io.Discard, data)
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		if stats.Sys > maxKubectlMemory {
+			t.Errorf("expected at most %d MB held, got %d MB", maxKubectlMemory>>20, stats.Sys>>20)
+		}
+	})
+}
+
+// the memory TestBig's -t run of kubectl may take, about 2GB since the methods and itabs take -methods
+const maxKubectlMemory = 3 << 30
+
+func TestMetadataFingerprint(t *testing.T) {
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		t.Errorf("Failed to get working directory")
+	}
+
+	// stripping changes the layout but not the recovered metadata, so the fingerprint must match
+	fingerprintOf := func(binaryName string) string {
+		filePath := fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, binaryName)
+		data, err := main_impl(filePath, true, true, true, false, 0, "", false)
+		if err != nil {
+			t.Errorf("GoReSym failed on %s: %s", binaryName, err)
+		}
+		if len(data.MetadataFingerprint) != 64 {
+			t.Errorf("invalid fingerprint for %s: %s", binaryName, data.MetadataFingerprint)
+		}
+		return data.MetadataFingerprint
+	}
+
+	if fingerprintOf("fmtisfun_lin") != fingerprintOf("fmtisfun_lin_stripped") {
+		t.Errorf("stripped and unstripped fingerprints differ")
+	}
+
+	if fingerprintOf("fmtisfun_lin") == fingerprintOf("hello_lin") {
+		t.Errorf("different programs have the same fingerprint")
+	}
+
+	// the flags select what's output, not the fingerprint
+	data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/fmtisfun_lin", workingDirectory), false, false, false, true, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if data.MetadataFingerprint != fingerprintOf("fmtisfun_lin") {
+		t.Errorf("expected the fingerprint without -t and -d to be the same")
+	}
+	var excludeTypes objfile.Patterns
+	if err := excludeTypes.Set(`^\*?main\.`); err != nil {
+		t.Fatal(err)
+	}
+	opts := options(true, true, true, false, 0, "", false)
+	opts.TypeFilter = &objfile.NameFilter{Exclude: excludeTypes}
+	filtered, err := extractFile(fmt.Sprintf("%s/test/weirdbins/fmtisfun_lin", workingDirectory), opts)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if filtered.MetadataFingerprint != fingerprintOf("fmtisfun_lin") {
+		t.Errorf("expected the fingerprint with the types filtered to be the same")
+	}
+}
+
+func TestObfuscationDetection(t *testing.T) {
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		t.Errorf("Failed to get working directory")
+	}
+
+	for binaryName, obfuscator := range map[string]string{"GoReSym_garbled": "garble", "fmtisfun_lin": "", "hello_lin": ""} {
+		t.Run(binaryName, func(t *testing.T) {
+			filePath := fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, binaryName)
+			data, err := main_impl(filePath, true, false, false, false, 0, "", false)
+			if err != nil {
+				t.Fatalf("GoReSym failed: %s", err)
+			}
+
+			if data.Obfuscator != obfuscator || data.ObfuscatorDetected != (len(obfuscator) > 0) {
+				t.Errorf("expected obfuscator %q, got %q", obfuscator, data.Obfuscator)
+			}
+			if len(obfuscator) == 0 {
+				if len(data.ObfuscationEvidence) > 0 {
+					t.Errorf("unexpected evidence %v", data.ObfuscationEvidence)
+				}
+				return
+			}
+
+			// the build info is gone, the runtime functions give the version away
+			if data.Version != "1.20" || len(data.ObfuscationEvidence) == 0 {
+				t.Errorf("expected version 1.20 and evidence, got %q and %v", data.Version, data.ObfuscationEvidence)
+			}
+
+			// the hashed os/syscall style packages runtime links into are standard library, main stays user code
+			std := make(map[string]bool)
+			for _, fn := range data.StdFunctions {
+				std[fn.PackageName] = true
+			}
+			for _, fn := range data.UserFunctions {
+				if std[fn.PackageName] {
+					t.Errorf("%s is both user and standard library", fn.PackageName)
+				}
+			}
+			if !std["Dat5j9j7Fwyn"] || !std["t8_jCS_RX"] {
+				t.Errorf("hashed standard library packages weren't recovered")
+			}
+		})
+	}
+
+}
+
+func TestCsvOutput(t *testing.T) {
+	metadata := goresym.Report{
+		UserFunctions: []goresym.FuncMetadata{
+			{Start: 0x401000, End: 0x401040, PackageName: "main", FullName: "main.Map[int,string]", SourceFile: "/build/util.go", SourceLine: 7},
+			{Start: 0x401040, End: 0x401080, PackageName: "main", FullName: "main.Apply[go.shape.map[string]func(int, int)]"},
+		},
+		StdFunctions: []goresym.FuncMetadata{{Start: 0x402000, End: 0x402010, PackageName: "fmt", FullName: `fmt."quoted"`}},
+		Types:        []objfile.Type{{VA: 0x4a0000, Size: 8, Str: "map[string]func(int, int)", Kind: "Map"}},
+		Interfaces:   []objfile.Type{{VA: 0x4a0100, Size: 16, Str: "interface { Close() error; \"Read\"([]uint8) (int, error) }", Kind: "Interface"}},
+	}
+
+	var out bytes.Buffer
+	if err := printCsv(&out, true, metadata); err != nil {
+		t.Fatalf("printCsv failed: %s", err)
+	}
+	expected := "StartVA,EndVA,FullName,PackageName,Kind,Size,SourceFile,StartLine,EndLine\n" +
+		"0x401000,0x401040,\"main.Map[int,string]\",main,user,64,/build/util.go,7,\n" +
+		"0x401040,0x401080,\"main.Apply[go.shape.map[string]func(int, int)]\",main,user,64,,,\n" +
+		"0x402000,0x402010,\"fmt.\"\"quoted\"\"\",fmt,std,16,,,\n"
+	if out.String() != expected {
+		t.Errorf("unexpected csv:\n%s", out.String())
+	}
+
+	var types bytes.Buffer
+	if err := printCsvTypes(&types, false, metadata); err != nil {
+		t.Fatalf("printCsvTypes failed: %s", err)
+	}
+	expected = "0x4a0000,\"map[string]func(int, int)\",Map,8,false\n" +
+		"0x4a0100,\"interface { Close() error; \"\"Read\"\"([]uint8) (int, error) }\",Interface,16,true\n"
+	if types.String() != expected {
+		t.Errorf("unexpected types csv:\n%s", types.String())
+	}
+
+	// every row reads back as the fields it was written from
+	rows, err := csv.NewReader(&types).ReadAll()
+	if err != nil {
+		t.Fatalf("csv doesn't read back: %s", err)
+	}
+	if rows[0][1] != metadata.Types[0].Str || rows[1][1] != metadata.Interfaces[0].Str {
+		t.Errorf("type names read back as %s and %s", rows[0][1], rows[1][1])
+	}
+
+	workingDirectory, _ := os.Getwd()
+	data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/fmtisfun_lin", workingDirectory), false, false, false, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	for _, fn := range data.UserFunctions {
+		if fn.FullName == "main.main" && (fn.StartLine != fn.SourceLine || fn.EndLine <= fn.StartLine) {
+			t.Errorf("expected main.main to start at its entry line %d and end after it, got %d to %d", fn.SourceLine, fn.StartLine, fn.EndLine)
+		}
+	}
+}
+
+// the generated scripts are compared to the files in test/golden, rewrite them with 'go test -run TestScriptOutput -update'
+var updateGolden = flag.Bool("update", false, "rewrite the golden files from the current output")
+
+// compareGolden compares output to the golden file name, or rewrites it with -update
+func compareGolden(t *testing.T, name string, output []byte) {
+	golden := fmt.Sprintf("test/golden/%s", name)
+	if *updateGolden {
+		if err := os.WriteFile(golden, output, 0644); err != nil {
+			t.Fatalf("failed to update %s: %s", golden, err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", golden, err)
+	}
+	if !bytes.Equal(output, expected) {
+		t.Errorf("output differs from %s, rerun with -update if the change is intended:\n%s", golden, output)
+	}
+}
+
+func TestScriptOutput(t *testing.T) {
+	// a method, a generic whose sanitized name collides with another function's for IDA, a name seen twice, a std function and one outside the dump
+	metadata := goresym.Report{
+		UserFunctions: []goresym.FuncMetadata{
+			{Start: 0x401000, End: 0x401040, PackageName: "main", FullName: "main.handleC2", SourceFile: "/build/c2.go", SourceLine: 12},
+			{Start: 0x401040, End: 0x401080, PackageName: "main", FullName: "main.(*Beacon).Send", SourceFile: "/build/beacon.go", SourceLine: 40},
+			{Start: 0x401080, End: 0x4010c0, PackageName: "main", FullName: "main.Map[int]", SourceFile: "/build/util.go", SourceLine: 7},
+			{Start: 0x4010c0, End: 0x401100, PackageName: "main", FullName: "main.Map(int)"},
+			{Start: 0x401100, End: 0x401140, PackageName: "main", FullName: "main.handleC2"},
+			{Start: 0x500000, End: 0x500010, PackageName: "main", FullName: "main.gone", Unmapped: true},
+		},
+		StdFunctions: []goresym.FuncMetadata{{Start: 0x402000, End: 0x402010, PackageName: "fmt", FullName: "fmt.Println", SourceFile: "/usr/local/go/src/fmt/print.go", SourceLine: 313}},
+		Types:        []objfile.Type{{VA: 0x4a0000, Str: "*main.Beacon"}, {VA: 0x4a0100, Str: "map[string]\"quoted\""}},
+		Interfaces:   []objfile.Type{{VA: 0x4a0000, Str: "*main.Beacon"}},
+	}
+
+	var ida bytes.Buffer
+	if err := printIdaPython(&ida, "/samples/implant.exe", metadata); err != nil {
+		t.Fatalf("printIdaPython failed: %s", err)
+	}
+	compareGolden(t, "idapython.py", ida.Bytes())
+
+	var ghidra bytes.Buffer
+	if err := printGhidraScript(&ghidra, "/samples/implant.exe", false, metadata); err != nil {
+		t.Fatalf("printGhidraScript failed: %s", err)
+	}
+	compareGolden(t, "ghidra.py", ghidra.Bytes())
+
+	var r2 bytes.Buffer
+	if err := printR2Script(&r2, "/samples/implant.exe", metadata); err != nil {
+		t.Fatalf("printR2Script failed: %s", err)
+	}
+	compareGolden(t, "r2.txt", r2.Bytes())
+
+	if err := printMapFile(io.Discard, metadata); err == nil {
+		t.Errorf("expected RVAs to need an image base")
+	}
+	metadata.ImageBase = 0x400000
+	var x64dbg bytes.Buffer
+	if err := printX64dbgScript(&x64dbg, "/samples/implant.exe", metadata); err != nil {
+		t.Fatalf("printX64dbgScript failed: %s", err)
+	}
+	compareGolden(t, "x64dbg.txt", x64dbg.Bytes())
+	var mapFile bytes.Buffer
+	if err := printMapFile(&mapFile, metadata); err != nil {
+		t.Fatalf("printMapFile failed: %s", err)
+	}
+	compareGolden(t, "implant.map", mapFile.Bytes())
+
+	names := make(uniqueNames)
+	long := strings.Repeat("a", x64dbgNameLimit+10)
+	if first, second := names.limitedName("", long, x64dbgNameLimit, 0x1000), names.limitedName("", long, x64dbgNameLimit, 0x2000); len(first) != x64dbgNameLimit || len(second) != x64dbgNameLimit || !strings.HasSuffix(second, "_2000") {
+		t.Errorf("expected colliding names cut to the limit and made unique, got %s and %s", first, second)
+	}
+
+	metadata.Arch = "amd64"
+	metadata.Types[0].Kind, metadata.Types[0].Size = "Pointer", 8
+	var sqlite bytes.Buffer
+	if err := printSqlite(&sqlite, "/samples/it's.exe", "5e1f", metadata); err != nil {
+		t.Fatalf("printSqlite failed: %s", err)
+	}
+	compareGolden(t, "sqlite.sql", sqlite.Bytes())
+
+	if name := r2Name("github.com/x/y.(*T).M"); name != "github.com.x.y.__T_.M" {
+		t.Errorf("expected the package path as flag name separators, got %s", name)
+	}
+	if path, name := ghidraNamespace(goresym.FuncMetadata{PackageName: "github.com/x/y", FullName: "github.com/x/y.(*T).M"}); fmt.Sprint(path) != "[github.com x y]" || name != "(*T).M" {
+		t.Errorf("expected the package path as namespaces, got %v and %s", path, name)
+	}
+}
+
+func TestScriptHeaderName(t *testing.T) {
+	// a file name ending the header comment would run the rest of it
+	const fileName = "/samples/x\nimport os; os.system('id')\r.exe"
+	scripts := map[string]func(w io.Writer) error{
+		"idapy":  func(w io.Writer) error { return printIdaPython(w, fileName, goresym.Report{}) },
+		"ghidra": func(w io.Writer) error { return printGhidraScript(w, fileName, false, goresym.Report{}) },
+		"r2":     func(w io.Writer) error { return printR2Script(w, fileName, goresym.Report{}) },
+		"x64dbg": func(w io.Writer) error { return printX64dbgScript(w, fileName, goresym.Report{ImageBase: 0x400000}) },
+		"sqlite": func(w io.Writer) error { return printSqlite(w, fileName, "5e1f", goresym.Report{}) },
+	}
+	for format, print := range scripts {
+		var b bytes.Buffer
+		if err := print(&b); err != nil {
+			t.Fatalf("%s failed: %s", format, err)
+		}
+		// python ends a line at a carriage return too
+		header, _, _ := strings.Cut(strings.ReplaceAll(b.String(), "\r", "\n"), "\n")
+		if !strings.Contains(header, "os.system") {
+			t.Errorf("expected the whole name in the header comment of %s, got %q", format, header)
+		}
+	}
+}
+
+func TestGhidraHeadless(t *testing.T) {
+	analyzeHeadless, err := exec.LookPath("analyzeHeadless")
+	if err != nil {
+		t.Skip("no analyzeHeadless on PATH")
+	}
+	path := "test/weirdbins/hello_lin"
+	data, err := main_impl(path, true, false, false, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	defer data.Close()
+
+	dir := t.TempDir()
+	var script bytes.Buffer
+	if err := printGhidraScript(&script, path, linkedAtZero(path), data); err != nil {
+		t.Fatalf("printGhidraScript failed: %s", err)
+	}
+	// after the script, print the namespace path and name of every function it left behind
+	check := `for func in currentProgram.getFunctionManager().getFunctions(True):
+    print("GoReSym-check: 0x%x %s" % (func.getEntryPoint().getOffset(), func.getName(True)))
+`
+	for name, content := range map[string][]byte{"goresym.py": script.Bytes(), "check.py": []byte(check)} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", name, err)
+		}
+	}
+	out, err := exec.Command(analyzeHeadless, dir, "goresym", "-import", path, "-noanalysis", "-scriptPath", dir,
+		"-postScript", "goresym.py", "-postScript", "check.py", "-deleteProject").CombinedOutput()
+	if err != nil {
+		t.Fatalf("analyzeHeadless failed: %s\n%s", err, out)
+	}
+
+	applied := make(map[uint64]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		_, found, ok := strings.Cut(line, "GoReSym-check: ")
+		if !ok {
+			continue
+		}
+		var va uint64
+		var name string
+		if _, err := fmt.Sscanf(found, "0x%x %s", &va, &name); err == nil {
+			applied[va] = name
+		}
+	}
+	// the script names an address once, the functions outside the image aren't in it
+	functions := 0
+	named := make(map[uint64]bool)
+	for _, fn := range append(data.UserFunctions, data.StdFunctions...) {
+		if fn.Unmapped || named[fn.Start] {
+			continue
+		}
+		named[fn.Start] = true
+		functions++
+		namespace, name := ghidraNamespace(fn)
+		expected := strings.Join(append(namespace, ghidraName(name)), "::")
+		if applied[fn.Start] != expected && !strings.HasPrefix(applied[fn.Start], expected+"_") {
+			t.Errorf("expected %s at 0x%x, got %q", expected, fn.Start, applied[fn.Start])
+		}
+	}
+	if !strings.Contains(string(out), fmt.Sprintf("GoReSym: applied %d functions", functions)) {
+		t.Errorf("expected the script to apply the %d functions:\n%s", functions, out)
+	}
+}
+
+func TestSqliteDatabase(t *testing.T) {
+	shell, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("no sqlite3 shell on PATH")
+	}
+	query := func(db string, sql string) string {
+		out, err := exec.Command(shell, db, sql).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s failed: %s %s", sql, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	data, err := main_impl("test/weirdbins/hello_lin", false, false, true, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	defer data.Close()
+	db := filepath.Join(t.TempDir(), "results.db")
+	if err := loadSqlite(db, "test/weirdbins/hello_lin", "5e1f", data); err != nil {
+		t.Fatalf("loadSqlite failed: %s", err)
+	}
+	functions := fmt.Sprint(len(data.UserFunctions) + len(data.StdFunctions))
+	if count := query(db, "SELECT COUNT(*) FROM functions f JOIN binaries b ON b.id = f.binary_id WHERE b.sha256 = '5e1f'"); count != functions {
+		t.Fatalf("expected the %s functions in the database, got %s", functions, count)
+	}
+	// a second run on the same file replaces its rows
+	if err := loadSqlite(db, "test/weirdbins/hello_lin", "5e1f", data); err != nil {
+		t.Fatalf("loadSqlite failed again: %s", err)
+	}
+	if count := query(db, "SELECT COUNT(*) FROM functions"); count != functions {
+		t.Errorf("expected the rows of the first run replaced, got %s functions", count)
+	}
+
+	// the second of three binaries fails midway, after its binary row and functions
+	query(db, "CREATE TRIGGER fail BEFORE INSERT ON types WHEN NEW.name = 'boom' BEGIN SELECT RAISE(ABORT, 'boom'); END")
+	other, failing := data, data
+	other.Arch, failing.Arch = "arm64", "386"
+	failing.Types = append([]objfile.Type{{Str: "boom"}}, data.Types...)
+	if err := loadSqlite(db, "test/weirdbins/fat", "fa7", other, failing, other); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected the failing binary to fail the load, got %v", err)
+	}
+	if binaries := query(db, "SELECT sha256 || '/' || arch FROM binaries ORDER BY id"); binaries != "5e1f/amd64\nfa7/arm64" {
+		t.Errorf("expected the binaries before the failing one, got %q", binaries)
+	}
+	if count := query(db, "SELECT COUNT(*) FROM functions f JOIN binaries b ON b.id = f.binary_id WHERE b.arch = 'arm64'"); count != functions {
+		t.Errorf("expected the %s functions of the binary before the failing one, got %s", functions, count)
+	}
+	if count := query(db, "SELECT COUNT(*) FROM functions"); count != fmt.Sprint(2*len(data.UserFunctions)+2*len(data.StdFunctions)) {
+		t.Errorf("expected no rows of the failing binary, got %s functions", count)
+	}
+	if lines := query(db, "SELECT COUNT(*) FROM functions WHERE start_line > 0 AND end_line >= start_line"); lines == "0" {
+		t.Errorf("expected the line range of the functions")
+	}
+
+	// an address past the range of a signed INTEGER keeps its bits
+	kernel := data
+	kernel.Arch = "s390x"
+	kernel.Types = []objfile.Type{{Str: "high", VA: 0xffff800000001000}}
+	if err := loadSqlite(db, "test/weirdbins/kernel", "ffff", kernel); err != nil {
+		t.Fatalf("loadSqlite failed: %s", err)
+	}
+	if va := query(db, "SELECT typeof(va) || ' ' || printf('%x', va) FROM types WHERE name = 'high'"); va != "integer ffff800000001000" {
+		t.Errorf("expected the VA as an INTEGER, got %s", va)
+	}
+
+	// a batch run appends every file, a transaction each
+	in := t.TempDir()
+	for _, file := range []string{"hello_lin", "notgo_invalid_bss_secsize"} {
+		content, err := os.ReadFile("test/weirdbins/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(in, file), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	batchDB := filepath.Join(t.TempDir(), "batch.db")
+	var out bytes.Buffer
+	config := batchConfig{workers: 2, sqliteDB: batchDB, opts: options(false, false, false, false, 0, "", false)}
+	if err := runBatch(in, config, &out); err != nil {
+		t.Fatalf("batch failed: %s", err)
+	}
+	if !strings.Contains(out.String(), `"Output":"`+batchDB+`"`) || !strings.Contains(out.String(), `"Processed":1,"Skipped":1,"Failed":0`) {
+		t.Errorf("expected a record naming the database and the skipped file, got %s", out.String())
+	}
+	if binaries := query(batchDB, "SELECT name FROM binaries"); binaries != "hello_lin" {
+		t.Errorf("expected the binary that extracted, got %q", binaries)
+	}
+	if count := query(batchDB, "SELECT COUNT(*) FROM functions"); count != fmt.Sprint(len(data.UserFunctions)) {
+		t.Errorf("expected the %d user functions of the batch, got %s", len(data.UserFunctions), count)
+	}
+}
+
+func TestYara(t *testing.T) {
+	sample := func(name string, funcs ...string) yaraSample {
+		metadata := goresym.Report{Version: "1.21.0"}
+		metadata.BuildInfo.Main.Path = "github.com/evil/implant"
+		metadata.BuildInfo.Deps = []*debug.Module{{Path: "github.com/spf13/cobra"}, {Path: "x.io/y"}}
+		for _, fn := range funcs {
+			metadata.UserFunctions = append(metadata.UserFunctions, goresym.FuncMetadata{FullName: fn, Origin: goresym.OriginMain, SourceFile: "C:\\build\\" + name + ".go"})
+		}
+		return yaraSample{fileName: "/samples/" + name, sha256: name + "sha", results: []goresym.Report{metadata}}
+	}
+	first := sample("a.exe", "main.main", "main.beaconLoop", "main.decryptConfig", "main.(*Client).Send")
+	second := sample("b.exe", "main.beaconLoop", "main.(*Client).Send", "main.newFeature")
+
+	var b bytes.Buffer
+	if err := printYara(&b, []yaraSample{first, second}, "", 10); err != nil {
+		t.Fatalf("printYara failed: %s", err)
+	}
+	rules := b.String()
+	for _, expected := range []string{"rule GoReSym_a_exe", "rule GoReSym_b_exe", `$module0 = "github.com/evil/implant" ascii`, `"C:\\build\\a.exe.go"`, `"main.decryptConfig"`} {
+		if !strings.Contains(rules, expected) {
+			t.Errorf("expected %s in the rules:\n%s", expected, rules)
+		}
+	}
+	// too short, or in every Go program
+	if strings.Contains(rules, `"x.io/y"`) || strings.Contains(rules, `"main.main"`) {
+		t.Errorf("expected short and generic strings to be dropped:\n%s", rules)
+	}
+
+	b.Reset()
+	if err := printYara(&b, []yaraSample{first, second}, "implant family", 1); err != nil {
+		t.Fatalf("printYara failed: %s", err)
+	}
+	family := b.String()
+	if !strings.Contains(family, "rule implant_family") || !strings.Contains(family, `$func0 = "main.(*Client).Send"`) || strings.Contains(family, "$func1") ||
+		strings.Contains(family, "decryptConfig") || strings.Contains(family, "$file") || !strings.Contains(family, "1 of them") {
+		t.Errorf("expected one rule of the shared strings, cut to the limit:\n%s", family)
+	}
+}
+
+func TestRuntimeOffsets(t *testing.T) {
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		t.Errorf("Failed to get working directory")
+	}
+
+	// both carry DWARF, so every tabled offset gets cross checked
+	for _, binaryName := range []string{"fmtisfun_lin", "hello_lin"} {
+		t.Run(binaryName, func(t *testing.T) {
+			filePath := fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, binaryName)
+			data, err := main_impl(filePath, false, false, false, true, 0, "", false)
+			if err != nil {
+				t.Fatalf("GoReSym failed: %s", err)
+			}
+
+			if len(data.RuntimeOffsets) == 0 {
+				t.Fatalf("no runtime offsets recovered")
+			}
+
+			for _, offset := range data.RuntimeOffsets {
+				if offset.Mismatch {
+					t.Errorf("%s: table disagrees with DWARF offset 0x%x", offset.Field, offset.Offset)
+				}
+			}
+		})
+	}
+}
+
+func TestTimings(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/fmtisfun_lin", workingDirectory), false, false, true, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+
+	timings := data.Timings
+	if timings == nil || timings.Total <= 0 {
+		t.Fatalf("timings not recorded: %+v", timings)
+	}
+
+	phases := timings.Open + timings.PclntabScan + timings.ModuleData + timings.Types + timings.Analysis + timings.Functions
+	if timings.PclntabScan < 0 || phases > timings.Total+0.01 {
+		t.Errorf("phases don't add up to the total: %+v", timings)
+	}
+}
+
+func TestVerbose(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		verbosity = 0
+	}()
+
+	workingDirectory, _ := os.Getwd()
+	path := fmt.Sprintf("%s/test/weirdbins/GoReSym_garbled", workingDirectory)
+	for _, level := range []int{1, 2} {
+		logged.Reset()
+		verbosity = level
+		opts := options(false, false, true, false, 0, "", false)
+		opts.Log = logProgress
+		if _, err := extractFile(path, opts); err != nil {
+			t.Fatalf("GoReSym failed: %s", err)
+		}
+
+		for _, expected := range []string{"scanning .text at 0x401000", "signature x64: 3 matches in .text", "picked the pclntab at 0x6042c0 and the 1.20 moduledata at 0x71c080", "pclntab: 1.20 layout", "types: ", "functions: 1252 user", "total "} {
+			if !strings.Contains(logged.String(), expected) {
+				t.Errorf("level %d: expected %q in the log:\n%s", level, expected, logged.String())
+			}
+		}
+		// the detail is only logged with -vv
+		if detail := strings.Contains(logged.String(), "x64 match at .text+"); detail != (level == 2) {
+			t.Errorf("level %d: unexpected signature matches logged: %v", level, detail)
+		}
+	}
+}
+
+func TestNdjsonOutput(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	path := fmt.Sprintf("%s/test/weirdbins/fmtisfun_lin", workingDirectory)
+	whole, err := main_impl(path, true, false, true, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+
+	var out bytes.Buffer
+	stream := newNdjsonStream(&out)
+	opts := options(true, false, true, false, 0, "", false)
+	opts.Stream = stream.record
+	streamed, err := extractFile(path, opts)
+	if err != nil {
+		t.Fatalf("GoReSym failed streaming: %s", err)
+	}
+	stream.record("metadata", streamed)
+	if err := stream.flush(); err != nil {
+		t.Fatalf("flush failed: %s", err)
+	}
+	if len(streamed.UserFunctions) != 0 || len(streamed.StdFunctions) != 0 || len(streamed.Types) != 0 {
+		t.Errorf("streamed records were kept in the metadata")
+	}
+
+	counts := make(map[string]int)
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	for i, line := range lines {
+		var record map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("line %d isn't a JSON object: %s", i, err)
+		}
+		var kind string
+		json.Unmarshal(record["kind"], &kind)
+		if _, ok := record[kind]; !ok || len(record) != 2 {
+			t.Errorf("line %d isn't a %s record: %s", i, kind, line)
+		}
+		if (i == 0) != (kind == "header") || (i == len(lines)-1) != (kind == "metadata") {
+			t.Errorf("%s record out of order at line %d", kind, i)
+		}
+		counts[kind]++
+	}
+	if counts["function"] != len(whole.UserFunctions)+len(whole.StdFunctions) || counts["type"] != len(whole.Types) || counts["interface"] != len(whole.Interfaces) {
+		t.Errorf("expected the records of every function, type and interface, got %v", counts)
+	}
+	if streamed.MetadataFingerprint != whole.MetadataFingerprint {
+		t.Errorf("streaming changed the fingerprint")
+	}
+}
+
+func TestFilters(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	path := fmt.Sprintf("%s/test/weirdbins/fmtisfun_lin", workingDirectory)
+	whole, err := main_impl(path, true, false, true, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if whole.Filtered != nil {
+		t.Errorf("expected no filter counts without a filter, got %+v", whole.Filtered)
+	}
+
+	var includeFuncs, excludeFuncs, excludeTypes objfile.Patterns
+	for _, pattern := range []struct {
+		patterns *objfile.Patterns
+		pattern  string
+	}{{&includeFuncs, `^main\.`}, {&includeFuncs, `^fmt\.`}, {&excludeFuncs, `^fmt\.Sprint`}, {&excludeTypes, `runtime\.`}} {
+		if err := pattern.patterns.Set(pattern.pattern); err != nil {
+			t.Fatalf("bad pattern %s: %s", pattern.pattern, err)
+		}
+	}
+	opts := options(true, false, true, false, 0, "", false)
+	opts.FuncFilter = &objfile.NameFilter{Include: includeFuncs, Exclude: excludeFuncs}
+	opts.TypeFilter = &objfile.NameFilter{Exclude: excludeTypes}
+
+	data, err := extractFile(path, opts)
+	if err != nil {
+		t.Fatalf("GoReSym failed filtering: %s", err)
+	}
+	kept := len(data.UserFunctions) + len(data.StdFunctions)
+	if kept == 0 || data.Filtered == nil || kept+data.Filtered.Functions != len(whole.UserFunctions)+len(whole.StdFunctions) {
+		t.Fatalf("expected every function kept or counted as filtered, kept %d of %d, %+v", kept, len(whole.UserFunctions)+len(whole.StdFunctions), data.Filtered)
+	}
+	for _, fn := range append(data.UserFunctions, data.StdFunctions...) {
+		if !(strings.HasPrefix(fn.FullName, "main.") || strings.HasPrefix(fn.FullName, "fmt.")) || strings.HasPrefix(fn.FullName, "fmt.Sprint") {
+			t.Errorf("%s should have been filtered", fn.FullName)
+		}
+	}
+	if len(data.Types) == 0 || data.Filtered.Types == 0 || len(data.Types) >= len(whole.Types) {
+		t.Errorf("expected the runtime types filtered, %d of %d types kept, %+v", len(data.Types), len(whole.Types), data.Filtered)
+	}
+	for _, typ := range data.Types {
+		if strings.Contains(typ.Str, "runtime.") {
+			t.Errorf("type %s should have been filtered", typ.Str)
+		}
+	}
+}
+
+func TestInlinedCalls(t *testing.T) {
+	opts := options(true, false, false, false, 0, "", false)
+	opts.Inlined = true
+
+	workingDirectory, _ := os.Getwd()
+	// the layouts of Go 1.12 to 1.15 and of 1.20 on
+	for _, file := range []string{"hello_lin", "GoReSym_garbled"} {
+		t.Run(file, func(t *testing.T) {
+			data, err := extractFile(fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, file), opts)
+			if err != nil {
+				t.Fatalf("GoReSym failed: %s", err)
+			}
+			if !sort.StringsAreSorted(data.AllFunctionNames) {
+				t.Errorf("expected the function names sorted")
+			}
+			names := make(map[string]bool)
+			for _, name := range data.AllFunctionNames {
+				names[name] = true
+			}
+
+			found := false
+			for _, fn := range append(data.UserFunctions, data.StdFunctions...) {
+				for i, call := range fn.Inlined {
+					if !names[call.Name] || call.Parent >= i || call.CallLine <= 0 {
+						t.Errorf("bad inlined call %d of %s: %+v", i, fn.FullName, call)
+					}
+					for _, r := range call.Ranges {
+						if r.Start >= r.End || r.Start < fn.Start || r.End > fn.End {
+							t.Errorf("inlined call %d of %s has code outside it: %+v", i, fn.FullName, call)
+						}
+					}
+				}
+				if fn.FullName == "runtime.main" {
+					found = len(fn.Inlined) > 0 && fn.Inlined[0].Name == "runtime.lockOSThread" && fn.Inlined[0].Parent == -1
+				}
+			}
+			if !found {
+				t.Errorf("expected runtime.lockOSThread inlined into runtime.main")
+			}
+		})
+	}
+}
+
+func TestFuncFields(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	// the _func of Go 1.12 to 1.15 and of 1.20 on, their funcIDs are numbered differently
+	for _, file := range []string{"hello_lin", "GoReSym_garbled"} {
+		t.Run(file, func(t *testing.T) {
+			data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, file), true, false, false, false, 0, "", false)
+			if err != nil {
+				t.Fatalf("GoReSym failed: %s", err)
+			}
+			ids := make(map[string]string)
+			deferReturns := 0
+			for _, fn := range append(data.UserFunctions, data.StdFunctions...) {
+				if fn.FuncID != nil {
+					ids[fn.FullName] = fn.FuncID.Name
+				}
+				if fn.DeferReturn > 0 {
+					deferReturns++
+					if fn.Start+uint64(fn.DeferReturn) >= fn.End {
+						t.Errorf("%s has its deferreturn call outside it, at %x", fn.FullName, fn.DeferReturn)
+					}
+				}
+				if fn.FullName == "runtime.memmove" && fn.ArgsSize != 24 {
+					t.Errorf("expected 24 bytes of arguments to runtime.memmove, got %d", fn.ArgsSize)
+				}
+			}
+			for name, id := range map[string]string{"runtime.goexit": "goexit", "runtime.main": "runtime_main", "runtime.mcall": "mcall"} {
+				if ids[name] != id {
+					t.Errorf("expected %s to be the funcID %s, got %q", name, id, ids[name])
+				}
+			}
+			if deferReturns == 0 {
+				t.Errorf("expected some functions to call runtime.deferreturn")
+			}
+		})
+	}
+}
+
+func TestItabs(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	opts := options(true, false, true, false, 0, "", false)
+	opts.Methods = true
+	for _, test := range []struct {
+		file   string
+		filled bool // before Go 1.10 the runtime fills the method tables in at start
+	}{{"fmtisfun_lin", false}, {"hello_lin", true}, {"GoReSym_garbled", true}} {
+		t.Run(test.file, func(t *testing.T) {
+			data, err := extractFile(fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, test.file), opts)
+			if err != nil {
+				t.Fatalf("GoReSym failed: %s", err)
+			}
+			entries := make(map[uint64]string)
+			for _, fn := range append(data.UserFunctions, data.StdFunctions...) {
+				entries[fn.Start] = fn.FullName
+			}
+
+			found := false
+			for _, iface := range data.Itabs {
+				for _, impl := range iface.Implementations {
+					for _, method := range impl.Methods {
+						if method.VA != 0 && entries[method.VA] != method.Function {
+							t.Errorf("%s of %s for %s is at %x, the entry of %q, not %q", method.Name, impl.Type, iface.Interface, method.VA, entries[method.VA], method.Function)
+						}
+					}
+					// the names of an obfuscated binary are hashed, but not those of error
+					if iface.Interface == "error" {
+						found = true
+						if len(impl.Methods) != 1 || impl.Methods[0].Name != "Error" {
+							t.Fatalf("expected the Error method of %s, got %+v", impl.Type, impl.Methods)
+						}
+						if filled := strings.HasSuffix(impl.Methods[0].Function, ".Error"); filled != test.filled {
+							t.Errorf("expected the method table of %s filled %v, got %+v", impl.Type, test.filled, impl.Methods)
+						}
+					}
+				}
+			}
+			if !found {
+				t.Errorf("expected types implementing error")
+			}
+		})
+	}
+}
+
+func TestTypeMethods(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	opts := options(true, false, true, false, 0, "", false)
+	opts.Methods = true
+	// eliminated methods are -1 from 1.16 on and 0 before, bigendian is ppc64
+	for _, file := range []string{"fmtisfun_lin", "elf_data_rel_ro_pclntab", "hello_lin", "bigendian", "GoReSym_garbled"} {
+		t.Run(file, func(t *testing.T) {
+			data, err := extractFile(fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, file), opts)
+			if err != nil {
+				t.Fatalf("GoReSym failed: %s", err)
+			}
+			entries := make(map[uint64]string)
+			for _, fn := range append(data.UserFunctions, data.StdFunctions...) {
+				entries[fn.Start] = fn.FullName
+			}
+
+			resolved := 0
+			for _, typ := range data.Types {
+				for _, method := range typ.Methods {
+					for _, va := range []*uint64{method.VA, method.InterfaceVA} {
+						if va == nil {
+							continue
+						}
+						resolved++
+						if !strings.HasSuffix(entries[*va], "."+method.Name) {
+							t.Fatalf("%s of %s is at %x, the entry of %q", method.Name, typ.Str, *va, entries[*va])
+						}
+					}
+				}
+			}
+			if resolved == 0 {
+				t.Errorf("expected methods resolved to functions")
+			}
+		})
+	}
+}
+
+func TestGenerics(t *testing.T) {
+	// Go 1.22 with symbols: Sum[T Number] and Keys[K comparable, V any] and the methods of Stack[T any] instantiated for two shapes
+	workingDirectory, _ := os.Getwd()
+	data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/generics_lin", workingDirectory), false, false, true, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+
+	generics := make(map[string]goresym.GenericFunction)
+	for _, generic := range data.Generics {
+		generics[generic.Name] = generic
+	}
+	for name, dicts := range map[string][]string{
+		"main.Sum":           {"main..dict.Sum[float64]", "main..dict.Sum[int]"},
+		"main.Keys":          {"main..dict.Keys[int,*main.Stack[int]]", "main..dict.Keys[string,int]"},
+		"main.(*Stack).Push": {"main..dict.Stack[int]", "main..dict.Stack[string]"},
+		"main.(*Stack).Pop":  {"main..dict.Stack[int]", "main..dict.Stack[string]"},
+	} {
+		generic, ok := generics[name]
+		if !ok {
+			t.Errorf("expected the instantiations of %s grouped", name)
+			continue
+		}
+		if len(generic.Instantiations) != 2 {
+			t.Errorf("expected 2 instantiations of %s, got %+v", name, generic.Instantiations)
+		}
+		for _, inst := range generic.Instantiations {
+			if !inst.Shape || !strings.Contains(inst.FullName, "go.shape.") || len(inst.TypeArgs) == 0 {
+				t.Errorf("expected a shaped instantiation of %s, got %+v", name, inst)
+			}
+		}
+		var names []string
+		for _, dict := range generic.Dictionaries {
+			names = append(names, dict.Name)
+		}
+		if !reflect.DeepEqual(names, dicts) {
+			t.Errorf("expected the dictionaries %v of %s, got %v", dicts, name, names)
+		}
+	}
+
+	found := false
+	for _, fn := range data.UserFunctions {
+		if fn.FullName == "main.Keys[go.shape.string,go.shape.int]" {
+			found = true
+			if fn.GenericName != "main.Keys" || !reflect.DeepEqual(fn.TypeArgs, []string{"string", "int"}) || !fn.Shape {
+				t.Errorf("unexpected generic fields %+v", fn)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected main.Keys[go.shape.string,go.shape.int]")
+	}
+	for _, typ := range data.Types {
+		if typ.Str == "main.Stack[int]" && (typ.Shape || !reflect.DeepEqual(typ.TypeArgs, []string{"int"})) {
+			t.Errorf("expected an instantiated type, not a shape: %+v", typ)
+		}
+	}
+}
+
+func TestPackerDetection(t *testing.T) {
+	// the shape of a UPX packed ELF: one PT_LOAD over the whole file, no sections, l_info right after the program headers
+	var hdr elf.Header64
+	copy(hdr.Ident[:], elf.ELFMAG)
+	hdr.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	hdr.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	hdr.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	hdr.Type = uint16(elf.ET_EXEC)
+	hdr.Machine = uint16(elf.EM_X86_64)
+	hdr.Version = uint32(elf.EV_CURRENT)
+	hdr.Phoff = 64
+	hdr.Ehsize = 64
+	hdr.Phentsize = 56
+	hdr.Phnum = 1
+	hdr.Shentsize = 64
+	load := elf.Prog64{Type: uint32(elf.PT_LOAD), Flags: uint32(elf.PF_R | elf.PF_X), Vaddr: 0x400000, Paddr: 0x400000, Filesz: 0x200, Memsz: 0x200, Align: 0x1000}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, hdr)
+	binary.Write(&buf, binary.LittleEndian, load)
+	buf.Write([]byte{0, 0, 0, 0})
+	buf.WriteString("UPX!")
+	buf.Write(make([]byte, 0x200-buf.Len()))
+
+	data, err := main_impl_bytes(buf.Bytes(), false, false, false, false, 0, "", false)
+	if err == nil || !strings.Contains(err.Error(), "packed with UPX") {
+		t.Errorf("expected a packed file error, got %v", err)
+	}
+	if data.LikelyPacked == nil || data.LikelyPacked.Packer != "UPX" {
+		t.Errorf("expected the UPX evidence, got %+v", data.LikelyPacked)
+	}
+
+	// a crypter leaves no marker, just one executable segment of random looking bytes
+	const encryptedSize = 0x10000
+	load.Filesz, load.Memsz = encryptedSize, encryptedSize
+	buf.Reset()
+	binary.Write(&buf, binary.LittleEndian, hdr)
+	binary.Write(&buf, binary.LittleEndian, load)
+	random := rand.New(rand.NewSource(1))
+	for buf.Len() < encryptedSize {
+		buf.WriteByte(byte(random.Intn(256)))
+	}
+
+	data, err = main_impl_bytes(buf.Bytes(), false, false, false, false, 0, "", false)
+	if err == nil || !strings.Contains(err.Error(), "looks packed") {
+		t.Errorf("expected a packed file error, got %v", err)
+	}
+	if data.LikelyPacked == nil || len(data.LikelyPacked.Packer) > 0 || !strings.Contains(strings.Join(data.LikelyPacked.Evidence, " "), "entropy") {
+		t.Errorf("expected the entropy evidence, got %+v", data.LikelyPacked)
+	}
+
+	workingDirectory, _ := os.Getwd()
+	for _, binaryName := range []string{"hello_lin", "fmtisfun_win", "fmtisfun_macho"} {
+		data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, binaryName), false, false, false, true, 0, "", false)
+		if err != nil {
+			t.Fatalf("GoReSym failed: %s", err)
+		}
+		if len(data.Packer) > 0 || data.LikelyPacked != nil {
+			t.Errorf("unpacked %s detected as packed: %s %+v", binaryName, data.Packer, data.LikelyPacked)
+		}
+	}
+}
+
+func TestImageInput(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	filePath := fmt.Sprintf("%s/test/weirdbins/hello_lin", workingDirectory)
+	fileData, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", filePath, err)
+	}
+
+	// lay the file out like the loader maps it, what an unpacker hands over
+	ef, err := elf.NewFile(bytes.NewReader(fileData))
+	if err != nil {
+		t.Fatalf("failed to parse %s: %s", filePath, err)
+	}
+	var base, end uint64
+	for _, prog := range ef.Progs {
+		if prog.Type == elf.PT_LOAD {
+			if base == 0 {
+				base = prog.Vaddr
+			}
+			end = max(end, prog.Vaddr+prog.Memsz)
+		}
+	}
+	image := make([]byte, end-base)
+	for _, prog := range ef.Progs {
+		if prog.Type == elf.PT_LOAD {
+			copy(image[prog.Vaddr-base:], fileData[prog.Off:prog.Off+prog.Filesz])
+		}
+	}
+
+	fromFile, err := main_impl(filePath, true, false, true, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed on the file: %s", err)
+	}
+	fromImage, err := main_impl_image(bytes.NewReader(image), base, "", true, false, true, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed on the image: %s", err)
+	}
+
+	if fromImage.Version != fromFile.Version || fromImage.TabMeta.VA != fromFile.TabMeta.VA || fromImage.ModuleMeta.VA != fromFile.ModuleMeta.VA {
+		t.Errorf("the image parsed differently: %s 0x%x 0x%x, the file %s 0x%x 0x%x", fromImage.Version, fromImage.TabMeta.VA, fromImage.ModuleMeta.VA, fromFile.Version, fromFile.TabMeta.VA, fromFile.ModuleMeta.VA)
+	}
+	if len(fromImage.UserFunctions) != len(fromFile.UserFunctions) || len(fromImage.StdFunctions) != len(fromFile.StdFunctions) || len(fromImage.Types) != len(fromFile.Types) {
+		t.Errorf("expected %d user functions, %d std functions and %d types, got %d, %d and %d", len(fromFile.UserFunctions), len(fromFile.StdFunctions), len(fromFile.Types),
+			len(fromImage.UserFunctions), len(fromImage.StdFunctions), len(fromImage.Types))
+	}
+}
+
+func TestBuildMode(t *testing.T) {
+	// the older binaries have no build info, the mode comes from the file type alone
+	cases := map[string]string{
+		"fmtisfun_lin":            "exe",
+		"fmtisfun_lin_stripped":   "exe",
+		"fmtisfun_macho":          "exe",
+		"elf_data_rel_ro_pclntab": "pie",
+		"windows_rdata_pclntab":   "pie",
+	}
+
+	workingDirectory, _ := os.Getwd()
+	for file, expected := range cases {
+		data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, file), false, false, false, true, 0, "", false)
+		if err != nil {
+			t.Fatalf("GoReSym failed on %s: %s", file, err)
+		}
+		if data.BuildMode != expected {
+			t.Errorf("%s: expected build mode %s, got %s", file, expected, data.BuildMode)
+		}
+	}
+}
+
+func TestStrippedSectionHeaders(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	filePath := fmt.Sprintf("%s/test/weirdbins/hello_lin", workingDirectory)
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("Test file %s doesn't exist", filePath)
+	}
+	expected, err := main_impl(filePath, true, false, false, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+
+	for name, patch := range map[string]func(data []byte){
+		// e_shoff, e_shnum and e_shstrndx
+		"zeroed": func(data []byte) {
+			clear(data[0x28:0x30])
+			clear(data[0x3c:0x40])
+		},
+		"past the end": func(data []byte) {
+			binary.LittleEndian.PutUint64(data[0x28:], 0x7fffffff0000)
+		},
+	} {
+		data := append([]byte{}, original...)
+		patch(data)
+		stripped, err := main_impl_bytes(data, true, false, false, false, 0, "", false)
+		if err != nil {
+			t.Errorf("%s: GoReSym failed: %s", name, err)
+			continue
+		}
+
+		if stripped.TabMeta.VA != expected.TabMeta.VA || stripped.ModuleMeta.VA != expected.ModuleMeta.VA || stripped.Version != expected.Version {
+			t.Errorf("%s: expected pclntab 0x%x and moduledata 0x%x, got 0x%x and 0x%x", name, expected.TabMeta.VA, expected.ModuleMeta.VA, stripped.TabMeta.VA, stripped.ModuleMeta.VA)
+		}
+		if len(stripped.UserFunctions) != len(expected.UserFunctions) || len(stripped.StdFunctions) != len(expected.StdFunctions) {
+			t.Errorf("%s: expected %d user and %d std functions, got %d and %d", name, len(expected.UserFunctions), len(expected.StdFunctions), len(stripped.UserFunctions), len(stripped.StdFunctions))
+			continue
+		}
+		for i, fn := range expected.UserFunctions {
+			if !reflect.DeepEqual(stripped.UserFunctions[i], fn) {
+				t.Errorf("%s: expected %+v, got %+v", name, fn, stripped.UserFunctions[i])
+			}
+		}
+	}
+}
+
+// legacyTwins are the unstripped and stripped builds of the same program with a Go 1.2-1.15 pclntab, under test. The ones of
+// test/build are built by build_test_files.sh: Go 1.12 for amd64 and 386, and Go 1.5 to 1.7, the oldest layout of the _func.
+var legacyTwins = []struct{ unstripped, stripped string }{
+	{"weirdbins/fmtisfun_lin", "weirdbins/fmtisfun_lin_stripped"},
+	{"weirdbins/hello_lin", "weirdbins/hello_stripped_lin"},
+	{"build/112/testproject_lin", "build/112/testproject_lin_stripped"},
+	{"build/112/testproject_lin_32", "build/112/testproject_lin_stripped_32"},
+	{"build/17/testproject_lin", "build/17/testproject_lin_stripped"},
+	{"build/16/testproject_lin", "build/16/testproject_lin_stripped"},
+	{"build/15/testproject_lin", "build/15/testproject_lin_stripped"},
+}
+
+// legacyTwinPaths is the paths of twins, false when one of them wasn't built
+func legacyTwinPaths(workingDirectory string, unstripped string, stripped string) (string, string, bool) {
+	unstrippedPath, strippedPath := fmt.Sprintf("%s/test/%s", workingDirectory, unstripped), fmt.Sprintf("%s/test/%s", workingDirectory, stripped)
+	for _, path := range []string{unstrippedPath, strippedPath} {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("Test file %s doesn't exist\n", path)
+			return "", "", false
+		}
+	}
+	return unstrippedPath, strippedPath, true
+}
+
+// The Go 1.2-1.15 pclntab has no header of offsets: nfunc is the word after the magic and the names are offsets from the start of
+// the table. Every function of the stripped binaries of both _func layouts, before and from Go 1.12, and of both pointer sizes, is
+// read with its name at its entry, the same as in the symbols of the unstripped twins.
+func TestLegacyStrippedFuncs(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	for _, twins := range legacyTwins {
+		unstrippedPath, strippedPath, ok := legacyTwinPaths(workingDirectory, twins.unstripped, twins.stripped)
+		if !ok {
+			continue
+		}
+		unstripped, stripped := twins.unstripped, twins.stripped
+		file, err := elf.Open(unstrippedPath)
+		if err != nil {
+			t.Fatalf("%s: %s", unstripped, err)
+		}
+		symbols, err := file.Symbols()
+		file.Close()
+		if err != nil {
+			t.Fatalf("%s: %s", unstripped, err)
+		}
+		expected := map[string]uint64{}
+		for _, symbol := range symbols {
+			// the text markers aren't functions of the pclntab
+			if elf.ST_TYPE(symbol.Info) == elf.STT_FUNC && symbol.Name != "runtime.text" && symbol.Name != "runtime.etext" {
+				expected[symbol.Name] = symbol.Value
+			}
+		}
+
+		data, err := main_impl(strippedPath, true, false, false, false, 0, "", false)
+		if err != nil {
+			t.Fatalf("%s: GoReSym failed: %s", stripped, err)
+		}
+		if data.TabMeta.Version != "1.2" {
+			t.Errorf("%s: expected the 1.2 pclntab, got %s", stripped, data.TabMeta.Version)
+		}
+		funcs := append(data.UserFunctions, data.StdFunctions...)
+		if len(funcs) != len(expected) {
+			t.Errorf("%s: expected %d functions, got %d", stripped, len(expected), len(funcs))
+		}
+		// the functab has nfunc entries and the end PC, one short loses the last function and one over reads the end as one
+		last := ""
+		for name, entry := range expected {
+			if len(last) == 0 || entry > expected[last] {
+				last = name
+			}
+		}
+		if !slices.ContainsFunc(funcs, func(fn goresym.FuncMetadata) bool { return fn.FullName == last }) {
+			t.Errorf("%s: expected the last function %s at 0x%x", stripped, last, expected[last])
+		}
+		// a name read from another base than the start of the table isn't a symbol
+		for _, fn := range funcs {
+			if entry, ok := expected[fn.FullName]; !ok || entry != fn.Start {
+				t.Errorf("%s: %s at 0x%x isn't a function of %s", stripped, fn.FullName, fn.Start, unstripped)
+			}
+		}
+	}
+}
+
+// dwarfLines maps each address of the line programs of the DWARF of the ELF at path to its files and lines, more than one at the
+// entries of inlined code
+func dwarfLines(t *testing.T, path string) map[uint64][]string {
+	file, err := elf.Open(path)
+	if err != nil {
+		t.Fatalf("%s: %s", path, err)
+	}
+	defer file.Close()
+	debugInfo, err := file.DWARF()
+	if err != nil {
+		t.Fatalf("%s: %s", path, err)
+	}
+
+	lines := map[uint64][]string{}
+	reader := debugInfo.Reader()
+	for unit, err := reader.Next(); unit != nil && err == nil; unit, err = reader.Next() {
+		reader.SkipChildren()
+		program, err := debugInfo.LineReader(unit)
+		if err != nil || program == nil {
+			continue
+		}
+		var entry dwarf.LineEntry
+		for program.Next(&entry) == nil {
+			if !entry.EndSequence {
+				lines[entry.Address] = append(lines[entry.Address], fmt.Sprintf("%s:%d", entry.File.Name, entry.Line))
+			}
+		}
+	}
+	return lines
+}
+
+// The file and line of each function of the stripped Go 1.2-1.15 binaries, read through the filetab of name offsets of the
+// legacy pclntab, are the ones of the DWARF of their unstripped twins
+func TestLegacyStrippedLines(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	for _, twins := range legacyTwins {
+		unstrippedPath, strippedPath, ok := legacyTwinPaths(workingDirectory, twins.unstripped, twins.stripped)
+		if !ok {
+			continue
+		}
+		unstripped, stripped := twins.unstripped, twins.stripped
+		lines := dwarfLines(t, unstrippedPath)
+		data, err := main_impl(strippedPath, true, false, false, false, 0, "", false)
+		if err != nil {
+			t.Fatalf("%s: GoReSym failed: %s", stripped, err)
+		}
+
+		for _, fn := range append(data.UserFunctions, data.StdFunctions...) {
+			expected, ok := lines[fn.Start]
+			if !ok {
+				t.Errorf("%s: %s at 0x%x has no line in the DWARF of %s", stripped, fn.FullName, fn.Start, unstripped)
+				continue
+			}
+			// the DWARF of Go 1.8 roots the generated code in the directory of the build
+			got := fmt.Sprintf("%s:%d", fn.SourceFile, fn.SourceLine)
+			if !slices.ContainsFunc(expected, func(line string) bool { return line == got || strings.HasSuffix(line, "/"+got) }) {
+				t.Errorf("%s: expected one of %v at %s, got %s", stripped, expected, fn.FullName, got)
+			}
+		}
+	}
+}
+
+func TestPatchElf(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	filePath := fmt.Sprintf("%s/test/weirdbins/hello_stripped_lin", workingDirectory)
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("Test file %s doesn't exist", filePath)
+	}
+	metadata, err := main_impl(filePath, true, false, false, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	symbols := elfSymbols(metadata)
+
+	withoutHeaders := append([]byte{}, original...)
+	clear(withoutHeaders[0x28:0x30])
+	clear(withoutHeaders[0x3c:0x40])
+	for name, data := range map[string][]byte{"stripped": original, "no section headers": withoutHeaders} {
+		patched, err := objfile.PatchElf(data, symbols, nil)
+		if err != nil {
+			t.Fatalf("%s: PatchElf failed: %s", name, err)
+		}
+		if !bytes.Equal(patched[0x40:len(original)], original[0x40:]) {
+			t.Errorf("%s: expected the original bytes past the ELF header to be left alone", name)
+		}
+		file, err := elf.NewFile(bytes.NewReader(patched))
+		if err != nil {
+			t.Fatalf("%s: the patched file doesn't parse: %s", name, err)
+		}
+		syms, err := file.Symbols()
+		if err != nil || len(syms) != len(symbols) {
+			t.Fatalf("%s: expected %d symbols, got %d: %v", name, len(symbols), len(syms), err)
+		}
+		found := false
+		for _, sym := range syms {
+			if sym.Name == "main.main" {
+				found = true
+				text := file.Sections[sym.Section]
+				if sym.Value != metadata.UserFunctions[0].Start || sym.Size != metadata.UserFunctions[0].End-metadata.UserFunctions[0].Start ||
+					elf.ST_TYPE(sym.Info) != elf.STT_FUNC || text.Flags&elf.SHF_EXECINSTR == 0 {
+					t.Errorf("%s: expected main.main at 0x%x in the text, got %+v in %s", name, metadata.UserFunctions[0].Start, sym, text.Name)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%s: expected a main.main symbol", name)
+		}
+	}
+
+	// the DWARF lines agree with the pclntab at every row
+	funcs := dwarfFuncs(symbols, metadata.Pclntab())
+	for _, fn := range funcs {
+		for _, row := range fn.Rows {
+			if file, line, _ := metadata.Pclntab().PCToLine(row.PC); file != row.File || line != row.Line {
+				t.Fatalf("expected %s %s:%d at 0x%x, the pclntab has %s:%d", fn.Name, row.File, row.Line, row.PC, file, line)
+			}
+		}
+	}
+	debugSections, err := objfile.ElfDwarf(original, funcs)
+	if err != nil {
+		t.Fatalf("ElfDwarf failed: %s", err)
+	}
+	patched, err := objfile.PatchElf(original, symbols, debugSections)
+	if err != nil {
+		t.Fatalf("PatchElf failed: %s", err)
+	}
+	file, err := elf.NewFile(bytes.NewReader(patched))
+	if err != nil {
+		t.Fatalf("the patched file doesn't parse: %s", err)
+	}
+	debugInfo, err := file.DWARF()
+	if err != nil {
+		t.Fatalf("the generated DWARF doesn't parse: %s", err)
+	}
+	reader := debugInfo.Reader()
+	unit, err := reader.Next()
+	if err != nil || unit == nil {
+		t.Fatalf("expected a compile unit: %v", err)
+	}
+	lines, err := debugInfo.LineReader(unit)
+	if err != nil || lines == nil {
+		t.Fatalf("expected a line program: %v", err)
+	}
+	mainFunc := metadata.UserFunctions[0]
+	var entry dwarf.LineEntry
+	if err := lines.SeekPC(mainFunc.Start, &entry); err != nil || entry.File.Name != mainFunc.SourceFile || entry.Line != mainFunc.SourceLine {
+		t.Errorf("expected %s:%d at 0x%x, got %+v: %v", mainFunc.SourceFile, mainFunc.SourceLine, mainFunc.Start, entry, err)
+	}
+	subprograms := 0
+	for die, err := reader.Next(); die != nil && err == nil; die, err = reader.Next() {
+		if die.Tag == dwarf.TagSubprogram {
+			subprograms++
+		}
+	}
+	if subprograms != len(symbols) {
+		t.Errorf("expected %d subprograms, got %d", len(symbols), subprograms)
+	}
+
+	symbolized, _ := os.ReadFile(fmt.Sprintf("%s/test/weirdbins/hello_lin", workingDirectory))
+	if _, err := objfile.PatchElf(symbolized, symbols, nil); err == nil {
+		t.Errorf("expected a file with a symbol table to be refused")
+	}
+}
+
+func TestPatchDwarfVerify(t *testing.T) {
+	verifier, err := exec.LookPath("llvm-dwarfdump")
+	if err != nil {
+		t.Skip("no llvm-dwarfdump on PATH")
+	}
+	workingDirectory, _ := os.Getwd()
+	filePath := fmt.Sprintf("%s/test/weirdbins/hello_stripped_lin", workingDirectory)
+	metadata, err := main_impl(filePath, true, false, false, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	// what -patch-out with -patch-dwarf writes
+	patched := filepath.Join(t.TempDir(), "hello_patched")
+	if err := writePatchedElf(filePath, patched, metadata, true); err != nil {
+		t.Fatalf("writePatchedElf failed: %s", err)
+	}
+	if out, err := exec.Command(verifier, "--verify", patched).CombinedOutput(); err != nil {
+		t.Fatalf("the generated DWARF doesn't verify: %s\n%s", err, out)
+	}
+}
+
+func TestPEOverlay(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	carrier, err := os.ReadFile(fmt.Sprintf("%s/test/weirdbins/fmtisfun_win", workingDirectory))
+	if err != nil {
+		t.Fatalf("Test file fmtisfun_win doesn't exist")
+	}
+	payloadPath := fmt.Sprintf("%s/test/weirdbins/hello_lin", workingDirectory)
+	payload, err := os.ReadFile(payloadPath)
+	if err != nil {
+		t.Fatalf("Test file %s doesn't exist", payloadPath)
+	}
+	expected, err := main_impl(payloadPath, true, false, false, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+
+	// a PE without Go in it: the section data and the COFF symbols are dropped, the long section names point into the string table
+	peOffset := binary.LittleEndian.Uint32(carrier[0x3c:])
+	clear(carrier[peOffset+12 : peOffset+20])
+	numSections := binary.LittleEndian.Uint16(carrier[peOffset+6:])
+	sections := peOffset + 24 + uint32(binary.LittleEndian.Uint16(carrier[peOffset+20:]))
+	for i := uint32(0); i < uint32(numSections); i++ {
+		copy(carrier[sections+40*i:sections+40*i+8], fmt.Sprintf(".s%d\x00\x00\x00\x00\x00", i))
+	}
+	clear(carrier[0x600:])
+	dropper := append(carrier, payload...)
+
+	if _, err := main_impl_bytes(dropper, true, false, false, false, 0, "", false); err == nil {
+		t.Errorf("the overlay was scanned without -scan-overlay")
+	}
+
+	opts := options(true, false, false, false, 0, "", false)
+	opts.ScanOverlay = true
+	data, err := extractBytes(dropper, opts)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+
+	if data.Overlay == nil || data.Overlay.Offset+data.Overlay.Size != uint64(len(dropper)) || !data.Overlay.Scanned {
+		t.Errorf("expected a scanned overlay ending at 0x%x, got %+v", len(dropper), data.Overlay)
+	}
+	if !data.TabMeta.Overlay || data.TabMeta.VA != 0 || data.TabMeta.FileOffset == nil || *data.TabMeta.FileOffset != uint64(len(carrier))+expected.TabMeta.VA-0x400000 {
+		t.Errorf("expected the pclntab at file offset 0x%x, got %+v", uint64(len(carrier))+expected.TabMeta.VA-0x400000, data.TabMeta)
+	}
+	if expected.TabMeta.Location != ".gopclntab" || data.TabMeta.Location != "overlay" {
+		t.Errorf("expected the pclntabs found in .gopclntab and the overlay, got %q and %q", expected.TabMeta.Location, data.TabMeta.Location)
+	}
+	if len(data.UserFunctions) != len(expected.UserFunctions) || len(data.StdFunctions) != len(expected.StdFunctions) {
+		t.Fatalf("expected %d user and %d std functions, got %d and %d", len(expected.UserFunctions), len(expected.StdFunctions), len(data.UserFunctions), len(data.StdFunctions))
+	}
+	for i, fn := range expected.UserFunctions {
+		// the build info is only read from the mapped sections, so the modules are unknown. The VAs are the payload's, not the PE's.
+		fn.Overlay, fn.Module = true, ""
+		fn.RVA, fn.FileOffset = 0, nil
+		if !reflect.DeepEqual(data.UserFunctions[i], fn) {
+			t.Errorf("expected %+v, got %+v", fn, data.UserFunctions[i])
+		}
+	}
+}
+
+// wasmSection is a section of a WebAssembly module, its id and the length of contents before them
+func wasmSection(id byte, contents []byte) []byte {
+	return append(append([]byte{id}, binary.AppendUvarint(nil, uint64(len(contents)))...), contents...)
+}
+
+// tinygoModule is a stripped TinyGo wasm build: its version string in a data segment at 0x10000
+func tinygoModule() []byte {
+	version := []byte("tinygo0.30.0")
+	data := []byte{1, 0, 0x41, 0x80, 0x80, 0x04, 0x0b} // one active segment, i32.const 0x10000
+	data = append(append(data, binary.AppendUvarint(nil, uint64(len(version)))...), version...)
+	return append([]byte("\x00asm\x01\x00\x00\x00"), wasmSection(11, data)...)
+}
+
+func TestTinyGo(t *testing.T) {
+	name := func(s string) []byte {
+		return append(binary.AppendUvarint(nil, uint64(len(s))), s...)
+	}
+
+	module := tinygoModule()
+	stripped, err := main_impl_bytes(module, true, false, false, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed on a stripped TinyGo module: %s", err)
+	}
+	if stripped.Compiler != "tinygo" || stripped.TinyGo == nil || stripped.TinyGo.Version != "0.30.0" || !stripped.TinyGo.Stripped {
+		t.Errorf("expected a stripped TinyGo 0.30.0 module, got %q %+v", stripped.Compiler, stripped.TinyGo)
+	}
+
+	// the function names subsection of the name section, index 0 is a C function of the bundled libc
+	var names []byte
+	funcs := []string{"memcpy", "runtime.alloc", "runtime.nilPanic", "main.main", "main.handleC2", "machine.init"}
+	names = append(names, binary.AppendUvarint(nil, uint64(len(funcs)))...)
+	for i, fn := range funcs {
+		names = append(append(names, byte(i)), name(fn)...)
+	}
+	module = append(module, wasmSection(0, append(name("name"), wasmSection(1, names)...))...)
+
+	symbols, err := main_impl_bytes(module, true, false, false, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed on a TinyGo module: %s", err)
+	}
+	if symbols.TinyGo == nil || symbols.TinyGo.Stripped || len(symbols.TinyGo.Evidence) != 2 || len(symbols.Version) != 0 {
+		t.Errorf("expected the version string and runtime functions, got %+v and version %q", symbols.TinyGo, symbols.Version)
+	}
+	var user []string
+	for _, fn := range symbols.UserFunctions {
+		user = append(user, fmt.Sprintf("%s@%d", fn.FullName, fn.Start))
+	}
+	if strings.Join(user, " ") != "main.main@3 main.handleC2@4" || len(symbols.StdFunctions) != 3 {
+		t.Errorf("expected the main functions by index and 3 std functions, got %v and %v", user, symbols.StdFunctions)
+	}
+
+	// gc binaries are left alone
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory")
+	}
+	gc, err := main_impl(fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, "hello_lin"), false, false, false, true, 0, "", false)
+	if err != nil || gc.Compiler != "gc" || gc.TinyGo != nil {
+		t.Errorf("expected a gc binary, got %q %+v %v", gc.Compiler, gc.TinyGo, err)
+	}
+}
+
+func TestReconstructGo(t *testing.T) {
+	// Go 1.22 -trimpath: nested, embedded and tagged fields, maps, a variadic func, a send only chan, an interface and Node and
+	// Tree pointing at each other
+	workingDirectory, _ := os.Getwd()
+	data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/reconstruct_lin", workingDirectory), true, false, true, true, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	var types []objfile.Type
+	for _, typ := range append(data.Types, data.Interfaces...) {
+		if strings.HasPrefix(typ.Str, "main.") {
+			types = append(types, typ)
+		}
+	}
+	// a field whose type wasn't recovered
+	types = append(types, objfile.Type{VA: 0x1000, Str: "main.partial", Kind: "Struct", Size: 16, Fields: []objfile.StructField{
+		{Name: "ok", Type: "int", Offset: 0}, {Name: "lost", TypeVA: 0x2000, Offset: 8}}})
+	data.Types, data.Interfaces = types, nil
+
+	var out bytes.Buffer
+	if err := printReconstructedGo(&out, "/samples/reconstruct_lin", data); err != nil {
+		t.Fatalf("failed to reconstruct: %s", err)
+	}
+	compareGolden(t, "reconstruct.go.txt", out.Bytes())
+}
+
+func TestReconstructC(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/reconstruct_lin", workingDirectory), true, false, true, true, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	// the main types and the literals they're made of, the fields of other named types become bytes
+	var types []objfile.Type
+	for _, typ := range append(data.Types, data.Interfaces...) {
+		if _, _, named := splitTypeName(typ.Str); !named || strings.HasPrefix(typ.Str, "main.") {
+			types = append(types, typ)
+		}
+	}
+	// fields overlapping and clashing with a C keyword
+	types = append(types, objfile.Type{VA: 0x1000, Str: "main.overlap", Kind: "Struct", Size: 8, Fields: []objfile.StructField{
+		{Name: "a", Type: "int", TypeVA: 0x4a8ae0, Offset: 0}, {Name: "b", Type: "int", TypeVA: 0x4a8ae0, Offset: 4}}})
+	types = append(types, objfile.Type{VA: 0x2000, Str: "main.keywords", Kind: "Struct", Size: 16, Fields: []objfile.StructField{
+		{Name: "int", Type: "int", TypeVA: 0x4a8ae0, Offset: 0}, {Name: "_", Type: "int", TypeVA: 0x4a8ae0, Offset: 8}}})
+	data.Types, data.Interfaces = types, nil
+
+	var out bytes.Buffer
+	if err := printCHeader(&out, "/samples/reconstruct_lin", data); err != nil {
+		t.Fatalf("failed to reconstruct: %s", err)
+	}
+	compareGolden(t, "reconstruct.h", out.Bytes())
+}
+
+func TestEmbeddedFS(t *testing.T) {
+	// Go 1.22: configs embeds config.yaml and payload.bin, assets the static directory with an empty file
+	workingDirectory, _ := os.Getwd()
+	data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/embed_lin", workingDirectory), false, false, false, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	listed := make(map[string]string)
+	for _, embedded := range data.EmbeddedFS {
+		if len(embedded.Warnings) > 0 {
+			t.Errorf("unexpected warnings for %s: %v", embedded.Name, embedded.Warnings)
+		}
+		for _, file := range embedded.Files {
+			listed[embedded.Name+":"+file.Name] = fmt.Sprintf("%d %s", file.Size, file.SHA256)
+		}
+	}
+	for name, expected := range map[string]string{
+		"main.configs:config.yaml":     "33 5b7bc5905e212483c6e4b57fecc3fffcb9556a61eac0f8e1c8fc23452c25b0b2",
+		"main.configs:payload.bin":     "5000 49ee74afd516277f85d8e65a80f992acabf2f811054017426bd8ad2dd312516f",
+		"main.assets:static/":          "0 ",
+		"main.assets:static/empty.txt": "0 e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		"main.assets:static/js/app.js": "18 8e8ee1febc14d8614e1f8bf6384a3641de3017fa43ad0600d643d291b14a42b1",
+	} {
+		if listed[name] != expected {
+			t.Errorf("expected %s to be %q, got %q", name, expected, listed[name])
+		}
+	}
+	if len(listed) != 9 {
+		t.Errorf("expected 9 files, got %v", listed)
+	}
+
+	dir := t.TempDir()
+	if err := writeEmbeddedFiles(dir, data); err != nil {
+		t.Fatalf("failed to write the embedded files: %s", err)
+	}
+	if config, err := os.ReadFile(filepath.Join(dir, "main.configs", "config.yaml")); err != nil || !strings.HasPrefix(string(config), "server: c2.example.com") {
+		t.Errorf("expected config.yaml written out, got %q %v", config, err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "main.assets", "static", "css")); err != nil || !info.IsDir() {
+		t.Errorf("expected the css directory, got %v", err)
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	opts := options(false, false, false, false, 0, "", false)
+	opts.Strings = true
+
+	workingDirectory, _ := os.Getwd()
+	for file, expected := range map[string]map[string]string{
+		// Go 1.8, the stack ABI and a literal converted to an interface, through its static header
+		"fmtisfun_lin": {"main.main": "Hello World", "main.generateBase64String": "This is a byte array"},
+		"hello_lin":    {"main.main": "hello world"},
+		// Go 1.22, the register ABI
+		"embed_lin": {"main.main": "config.yaml"},
+	} {
+		data, err := extractFile(fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, file), opts)
+		if err != nil {
+			t.Fatalf("GoReSym failed on %s: %s", file, err)
+		}
+		found := make(map[string]bool)
+		for _, fn := range data.UserFunctions {
+			for _, literal := range fn.Strings {
+				found[fn.FullName+":"+literal.Value] = true
+			}
+		}
+		for fn, literal := range expected {
+			if !found[fn+":"+literal] {
+				t.Errorf("%s: expected %s to reference %q, got %v", file, fn, literal, found)
+			}
+		}
+		if len(data.UnattributedStrings) == 0 {
+			t.Errorf("%s: expected the strings of the runtime tables in UnattributedStrings", file)
+		}
+	}
+}
+
+func TestInitTasks(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	for file, expected := range map[string][]string{
+		// Go 1.15, the task graph from the symbols
+		"hello_lin": {"internal/bytealg", "runtime", "errors", "math", "strconv", "sync", "unicode", "reflect", "io", "internal/oserror", "syscall", "time", "internal/poll", "os", "fmt"},
+		// Go 1.20 and stripped, the roots from the calls to runtime.doInit
+		"GoReSym_garbled": nil,
+		// Go 1.22, the tasks the linker sorted in the moduledata
+		"generics_lin": {"internal/bytealg", "math", "runtime", "errors", "sync", "syscall", "time", "io/fs", "os", "unicode", "reflect"},
+	} {
+		data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, file), false, false, false, true, 0, "", false)
+		if err != nil {
+			t.Fatalf("GoReSym failed on %s: %s", file, err)
+		}
+		var packages []string
+		for _, task := range data.Inits {
+			packages = append(packages, task.Package)
+			for _, fn := range task.Functions {
+				if fn.Outside || !strings.HasPrefix(fn.Name, task.Package+".init") {
+					t.Errorf("%s: unexpected init function %+v of %s", file, fn, task.Package)
+				}
+			}
+		}
+		if expected == nil && (len(packages) < 2 || packages[1] != "runtime") {
+			t.Errorf("%s: expected the runtime tasks first, got %v", file, packages)
+		}
+		if expected != nil && !reflect.DeepEqual(packages, expected) {
+			t.Errorf("%s: expected %v, got %v", file, expected, packages)
+		}
+	}
+}
+
+func TestBuildSettings(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/vcs_lin", workingDirectory), false, false, false, true, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	expected := goresym.VCSInfo{System: "git", Revision: "8527b53acf5143d1bcd90a05b955e864bdb2d6b2", Time: "2024-01-02T03:04:05Z", Modified: true}
+	if data.VCS == nil || *data.VCS != expected {
+		t.Errorf("expected the VCS stamp %+v, got %+v", expected, data.VCS)
+	}
+	for key, value := range map[string]string{"-ldflags": "-X main.version=1.2.3", "CGO_ENABLED": "0", "GOEXPERIMENT": "loopvar", "-buildmode": "exe"} {
+		if data.BuildSettings[key] != value {
+			t.Errorf("expected the build setting %s=%q, got %q", key, value, data.BuildSettings[key])
+		}
+	}
+	if data.BuildInfo.Main.Path != "example.com/stamped" {
+		t.Errorf("expected the main module example.com/stamped, got %q", data.BuildInfo.Main.Path)
+	}
+
+	// a build in GOPATH mode has no module and no stamp
+	data, err = main_impl(fmt.Sprintf("%s/test/weirdbins/gopath_lin", workingDirectory), false, false, false, true, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if data.VCS != nil || data.BuildInfo.Path != "legacy" || data.BuildInfo.Main.Path != "" || data.BuildSettings["GOOS"] != "linux" {
+		t.Errorf("unexpected GOPATH build info %+v, VCS %+v", data.BuildInfo, data.VCS)
+	}
+
+	// a line that doesn't parse loses only itself
+	contents, err := os.ReadFile(fmt.Sprintf("%s/test/weirdbins/vcs_lin", workingDirectory))
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupted := bytes.Replace(contents, []byte("build\t-compiler=gc\n"), []byte("build\t=compiler_gc\n"), -1)
+	if bytes.Equal(corrupted, contents) {
+		t.Fatal("the -compiler setting wasn't found")
+	}
+	path := filepath.Join(t.TempDir(), "vcs_lin")
+	if err := os.WriteFile(path, corrupted, 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, err = main_impl(path, false, false, false, true, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if _, ok := data.BuildSettings["-compiler"]; ok || data.VCS == nil || data.VCS.Revision != expected.Revision || data.BuildInfo.Main.Path != "example.com/stamped" {
+		t.Errorf("expected the rest of the corrupted build info, got %+v, VCS %+v", data.BuildInfo, data.VCS)
+	}
+}
+
+func TestScanTypes(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	path := fmt.Sprintf("%s/test/weirdbins/reconstruct_lin", workingDirectory)
+	healthy, err := main_impl(path, false, false, true, true, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	expected := make(map[uint64]string)
+	for _, typ := range healthy.Types {
+		if len(typ.Recovery) > 0 {
+			t.Fatalf("the typelinks of a healthy moduledata were scanned past: %+v", typ)
+		}
+		expected[typ.VA] = typ.Str
+	}
+
+	// zero the typelinks and point the types base at nothing, the pclntab still finds the moduledata
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elfFile, err := elf.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer elfFile.Close()
+	var moduleDataOffset uint64
+	for _, prog := range elfFile.Progs {
+		if prog.Type == elf.PT_LOAD && healthy.ModuleMeta.VA >= prog.Vaddr && healthy.ModuleMeta.VA < prog.Vaddr+prog.Filesz {
+			moduleDataOffset = healthy.ModuleMeta.VA - prog.Vaddr + prog.Off
+		}
+	}
+	moduleData := contents[moduleDataOffset : moduleDataOffset+0x300]
+	typelinks := binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(nil,
+		uint64(healthy.ModuleMeta.Typelinks.Data)), healthy.ModuleMeta.Typelinks.Len), healthy.ModuleMeta.Typelinks.Capacity)
+	idx := bytes.Index(moduleData, typelinks)
+	if idx < 0 {
+		t.Fatal("the typelinks weren't found in the moduledata")
+	}
+	copy(moduleData[idx:], make([]byte, len(typelinks)))
+	idx = bytes.Index(moduleData, binary.LittleEndian.AppendUint64(nil, healthy.ModuleMeta.Types))
+	if idx < 0 {
+		t.Fatal("the types base wasn't found in the moduledata")
+	}
+	binary.LittleEndian.PutUint64(moduleData[idx:], 0xdead0000)
+	corruptedPath := filepath.Join(t.TempDir(), "reconstruct_lin")
+	if err := os.WriteFile(corruptedPath, contents, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered, err := main_impl(corruptedPath, false, false, true, true, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	found := 0
+	for _, typ := range recovered.Types {
+		if typ.Recovery != "recovered without typelinks" {
+			t.Errorf("expected %s to be marked as recovered without typelinks", typ.Str)
+		}
+		if name, ok := expected[typ.VA]; ok {
+			found++
+			if name != typ.Str {
+				t.Errorf("expected the type at 0x%x to be %s, got %s", typ.VA, name, typ.Str)
+			}
+		}
+	}
+	if found != len(expected) {
+		t.Errorf("expected the %d types of the typelinks, %d were recovered", len(expected), found)
+	}
+	if len(recovered.Interfaces) != len(healthy.Interfaces) {
+		t.Errorf("expected the %d itab types from the recovered types base, got %d", len(healthy.Interfaces), len(recovered.Interfaces))
+	}
+}
+
+func TestFunctionHashes(t *testing.T) {
+	opts := options(false, false, false, false, 0, "", false)
+	opts.Hashes, opts.HashMinSize = true, 32
+
+	// the same main.checksum, linked at other addresses with other code before it and the globals and callees it references moved
+	workingDirectory, _ := os.Getwd()
+	hashes := make(map[string]*objfile.FunctionHash)
+	for _, file := range []string{"hash_v1_lin", "hash_v2_lin"} {
+		data, err := extractFile(fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, file), opts)
+		if err != nil {
+			t.Fatalf("GoReSym failed on %s: %s", file, err)
+		}
+		for _, fn := range data.UserFunctions {
+			if fn.FullName == "main.checksum" {
+				hashes[file] = fn.Hash
+			}
+			if fn.Hash != nil && fn.End-fn.Start < 32 {
+				t.Errorf("%s: %s of %d bytes is shorter than the threshold but hashed", file, fn.FullName, fn.End-fn.Start)
+			}
+		}
+		if hashes[file] == nil || len(hashes[file].PositionIndependent) == 0 {
+			t.Fatalf("%s: expected main.checksum to be hashed, got %v", file, hashes[file])
+		}
+		if !slices.Contains(data.FunctionHashes, hashes[file].PositionIndependent) || !slices.IsSorted(data.FunctionHashes) {
+			t.Errorf("%s: expected the hash of main.checksum in the sorted FunctionHashes, got %v", file, data.FunctionHashes)
+		}
+	}
+
+	v1, v2 := hashes["hash_v1_lin"], hashes["hash_v2_lin"]
+	if v1.SHA256 == v2.SHA256 {
+		t.Errorf("expected the raw hashes of main.checksum to differ where it's linked elsewhere, got %s for both", v1.SHA256)
+	}
+	if v1.PositionIndependent != v2.PositionIndependent {
+		t.Errorf("expected the position independent hashes of main.checksum to be equal, got %s and %s", v1.PositionIndependent, v2.PositionIndependent)
+	}
+}
+
+func TestExtractFromBytes(t *testing.T) {
+	// a sample held in memory extracts the same as the file, without being written out
+	workingDirectory, _ := os.Getwd()
+	for _, file := range []string{"hello_lin", "fmtisfun_win", "fmtisfun_macho"} {
+		path := fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, file)
+		fileBytes, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", file, err)
+		}
+		fromFile, err := main_impl(path, true, true, false, false, 0, "", false)
+		if err != nil {
+			t.Fatalf("GoReSym failed on %s: %s", file, err)
+		}
+		fromBytes, err := main_impl_bytes(fileBytes, true, true, false, false, 0, "", false)
+		if err != nil {
+			t.Fatalf("GoReSym failed on the bytes of %s: %s", file, err)
+		}
+		if fromBytes.Version != fromFile.Version || fromBytes.BuildId != fromFile.BuildId || !reflect.DeepEqual(fromBytes.BuildInfo, fromFile.BuildInfo) {
+			t.Errorf("%s: expected version %q and build id %q from the bytes, got %q and %q", file, fromFile.Version, fromFile.BuildId, fromBytes.Version, fromBytes.BuildId)
+		}
+		if len(fromBytes.UserFunctions) != len(fromFile.UserFunctions) || len(fromBytes.StdFunctions) != len(fromFile.StdFunctions) || len(fromBytes.Files) != len(fromFile.Files) {
+			t.Errorf("%s: expected %d user and %d std functions from the bytes, got %d and %d", file, len(fromFile.UserFunctions), len(fromFile.StdFunctions), len(fromBytes.UserFunctions), len(fromBytes.StdFunctions))
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	in := t.TempDir()
+	for _, file := range []string{"hello_lin", "fmtisfun_win", "notgo_invalid_bss_secsize"} {
+		data, err := os.ReadFile(fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(in, file), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// passes the sniff by its build info magic, then fails to open
+	if err := os.MkdirAll(filepath.Join(in, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(in, "sub", "corrupt"), []byte("\x7fELF\x02\x01\x01\x00\xff Go buildinf:\x08\x02"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// no build info or pclntab, passes the sniff by its version string
+	if err := os.WriteFile(filepath.Join(in, "tinygo.wasm"), tinygoModule(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	outDir := filepath.Join(t.TempDir(), "results")
+	for _, dir := range []string{"", outDir} {
+		var out bytes.Buffer
+		config := batchConfig{workers: 2, outDir: dir, opts: options(false, false, false, false, 0, "", false)}
+		if err := runBatch(in, config, &out); err != nil {
+			t.Fatalf("batch failed: %s", err)
+		}
+
+		records := make(map[string]batchRecord)
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		for _, line := range lines[:len(lines)-1] {
+			var record struct {
+				Kind  string
+				File  *batchRecord
+				Error *batchRecord
+			}
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("bad record %s: %s", line, err)
+			}
+			if record.File != nil {
+				records[filepath.Base(record.File.Path)] = *record.File
+			} else if record.Error != nil {
+				records[filepath.Base(record.Error.Path)] = *record.Error
+			}
+		}
+		if last := lines[len(lines)-1]; last != `{"kind":"summary","summary":{"Processed":3,"Skipped":1,"Failed":1}}` {
+			t.Errorf("unexpected summary %s", last)
+		}
+
+		if corrupt := records["corrupt"]; len(corrupt.Error) == 0 || len(corrupt.SHA256) != 64 {
+			t.Errorf("expected the corrupt file to fail with its sha256, got %+v", corrupt)
+		}
+		hello := records["hello_lin"]
+		if len(hello.Error) > 0 || len(hello.SHA256) != 64 {
+			t.Fatalf("expected hello_lin to extract, got %+v", hello)
+		}
+		metadata := hello.Metadata
+		if len(dir) > 0 {
+			if hello.Output != filepath.Join(dir, hello.SHA256+".json") || len(metadata) > 0 {
+				t.Errorf("expected the result in the output directory, got %+v", hello)
+			}
+			var err error
+			if metadata, err = os.ReadFile(hello.Output); err != nil {
+				t.Fatalf("failed to read the result: %s", err)
+			}
+		}
+		var report goresym.Report
+		if err := json.Unmarshal(metadata, &report); err != nil || report.Version != "1.15.5" || len(report.UserFunctions) == 0 || report.Timings != nil {
+			t.Errorf("unexpected result of hello_lin: version %q, %d user functions, %v", report.Version, len(report.UserFunctions), err)
+		}
+
+		tinygo := records["tinygo.wasm"]
+		if metadata = tinygo.Metadata; len(dir) > 0 {
+			metadata, _ = os.ReadFile(tinygo.Output)
+		}
+		var tinygoReport goresym.Report
+		if err := json.Unmarshal(metadata, &tinygoReport); err != nil || len(tinygo.Error) > 0 || tinygoReport.Compiler != "tinygo" {
+			t.Errorf("expected tinygo.wasm to extract as TinyGo, got %+v: %v", tinygo, err)
+		}
+	}
+}
+
+func TestKnownModuleData(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	file := fmt.Sprintf("%s/test/weirdbins/GoReSym_garbled", workingDirectory)
+	scanned, err := main_impl(file, true, false, false, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+
+	// the VA and its file offset, the moduledata is the third segment's at 0x8080
+	for _, c := range []struct {
+		va, offset uint64
+	}{{0x71c080, 0}, {0, 0x31c080}} {
+		opts := options(true, false, false, false, 0, "", false)
+		opts.ModuleData, opts.ModuleDataOffset = c.va, c.offset
+		data, err := extractFile(file, opts)
+		if err != nil {
+			t.Errorf("0x%x 0x%x: GoReSym failed: %s", c.va, c.offset, err)
+			continue
+		}
+		if data.ModuleMeta.VA != 0x71c080 || data.TabMeta.VA != scanned.TabMeta.VA || len(data.StdFunctions) != len(scanned.StdFunctions) || len(data.UserFunctions) != len(scanned.UserFunctions) {
+			t.Errorf("0x%x 0x%x: expected the scanned result, got the moduledata at 0x%x and %d functions", c.va, c.offset, data.ModuleMeta.VA, len(data.StdFunctions)+len(data.UserFunctions))
+		}
+	}
+
+	opts := options(true, false, false, false, 0, "", false)
+	opts.ModuleData = 0x71c088
+	if _, err := extractFile(file, opts); err == nil || !strings.Contains(err.Error(), "pcHeader magic mismatch") {
+		t.Errorf("expected the wrong moduledata to fail on its pcHeader, got %v", err)
+	}
+}
+
+func TestKnownPclntab(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	file := fmt.Sprintf("%s/test/weirdbins/GoReSym_garbled", workingDirectory)
+	scanned, err := main_impl(file, true, true, false, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+
+	// the VA and its file offset in the second segment, the magic is stomped
+	for _, c := range []struct {
+		va, offset uint64
+	}{{0x6042c0, 0}, {0, 0x2042c0}} {
+		opts := options(true, true, true, false, 0, "", false)
+		opts.Pclntab, opts.PclntabOffset = c.va, c.offset
+		data, err := extractFile(file, opts)
+		if err != nil {
+			t.Errorf("0x%x 0x%x: GoReSym failed: %s", c.va, c.offset, err)
+			continue
+		}
+		if data.TabMeta.VA != 0x6042c0 || data.TabMeta.Version != scanned.TabMeta.Version || data.ModuleMeta.VA != 0 || len(data.Types) != 0 {
+			t.Errorf("0x%x 0x%x: expected the %s pclntab alone, got %+v and the moduledata at 0x%x", c.va, c.offset, scanned.TabMeta.Version, data.TabMeta, data.ModuleMeta.VA)
+		}
+		if len(data.StdFunctions) != len(scanned.StdFunctions) || len(data.UserFunctions) != len(scanned.UserFunctions) || data.UserFunctions[0].FullName != scanned.UserFunctions[0].FullName || data.UserFunctions[0].Start != scanned.UserFunctions[0].Start || len(data.Files) != len(scanned.Files) {
+			t.Errorf("0x%x 0x%x: expected the functions and files of the scan", c.va, c.offset)
+		}
+		if len(data.Unavailable) != 1 || !strings.HasPrefix(data.Unavailable[0], "types") {
+			t.Errorf("0x%x 0x%x: expected the types reported unavailable, got %v", c.va, c.offset, data.Unavailable)
+		}
+	}
+
+	// the functions of a 1.20 table move with the text start
+	opts := options(true, false, false, false, 0, "", false)
+	opts.Pclntab, opts.TextStart = 0x6042c0, 0x501000
+	data, err := extractFile(file, opts)
+	if err != nil {
+		t.Fatalf("GoReSym failed with -textstart: %s", err)
+	}
+	if data.UserFunctions[0].Start != scanned.UserFunctions[0].Start+0x100000 {
+		t.Errorf("expected the functions 0x100000 further, got 0x%x for 0x%x", data.UserFunctions[0].Start, scanned.UserFunctions[0].Start)
+	}
+
+	opts.Pclntab, opts.TextStart = 0x6042c8, 0
+	if _, err := extractFile(file, opts); err == nil || !strings.Contains(err.Error(), "no pcHeader") {
+		t.Errorf("expected a pclntab off its header to fail, got %v", err)
+	}
+}
+
+func TestScanRange(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+
+	for _, c := range []struct {
+		file   string
+		ranges []objfile.ScanRange
+		found  bool
+	}{
+		// the stomped magic is only found through the init code, at 0x44c142 and in the file at 0x4c142
+		{"GoReSym_garbled", []objfile.ScanRange{{Start: 0x44c000, End: 0x44d000}}, true},
+		{"GoReSym_garbled", []objfile.ScanRange{{Start: 0x4c000, End: 0x4d000, Offset: true}}, true},
+		{"GoReSym_garbled", []objfile.ScanRange{{Start: 0x401000, End: 0x402000}}, false},
+		// the magic of the pclntab, its moduledata is found through the pointer to it as usual
+		{"hello_lin", []objfile.ScanRange{{Start: 0x4de6e0, End: 0x4df6e0}}, true},
+	} {
+		opts := options(false, false, false, false, 0, "", false)
+		opts.ScanRanges = c.ranges
+		data, err := extractFile(fmt.Sprintf("%s/test/weirdbins/%s", workingDirectory, c.file), opts)
+		if !c.found {
+			if err == nil {
+				t.Errorf("%s %v: expected nothing found outside the init code", c.file, c.ranges)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %v: GoReSym failed: %s", c.file, c.ranges, err)
+			continue
+		}
+		if len(data.Diagnostics.Sections) != 1 || data.Diagnostics.Sections[0].Scanned != 0x1000 {
+			t.Errorf("%s %v: expected 0x1000 bytes of one section scanned, got %+v", c.file, c.ranges, data.Diagnostics.Sections)
+		}
+	}
+}
+
+func TestTolerant(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	clean := fmt.Sprintf("%s/test/weirdbins/hello_lin", workingDirectory)
+	data, err := os.ReadFile(clean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elfFile, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a Go 1.2 pclntab, the functab of entry and _func offset pairs follows the 16 byte header. The name offset of the 100th
+	// function comes after its entry in its _func.
+	const corrupt = 100
+	pclntab := data[elfFile.Section(".gopclntab").Offset:]
+	entry := binary.LittleEndian.Uint64(pclntab[16+corrupt*16:])
+	funcOff := binary.LittleEndian.Uint64(pclntab[16+corrupt*16+8:])
+	binary.LittleEndian.PutUint32(pclntab[funcOff+8:], 0xfffffff0)
+	file := filepath.Join(t.TempDir(), "hello_lin")
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := main_impl(clean, true, false, false, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	functions := len(expected.StdFunctions) + len(expected.UserFunctions)
+
+	// strict, the functions from the corrupt one on are lost
+	if strict, err := main_impl(file, true, false, false, false, 0, "", false); err == nil && len(strict.StdFunctions)+len(strict.UserFunctions) >= functions {
+		t.Errorf("expected the strict parse to lose functions, got %d of %d", len(strict.StdFunctions)+len(strict.UserFunctions), functions)
+	}
+
+	opts := options(true, false, false, false, 0, "", false)
+	opts.Tolerant = true
+	report, err := extractFile(file, opts)
+	if err != nil {
+		t.Fatalf("GoReSym failed with -tolerant: %s", err)
+	}
+	if got := len(report.StdFunctions) + len(report.UserFunctions); got != functions {
+		t.Errorf("expected %d functions, got %d", functions, got)
+	}
+	// the walk of the functab stops at the corrupt entry too, the ones after it are kept
+	if len(report.Corruption) != 2 || report.Corruption[0].Kind != "count" || report.Corruption[1].Index != corrupt || report.Corruption[1].Entry != entry || report.Corruption[1].Kind != "name" {
+		t.Fatalf("expected the name of function %d at 0x%x reported, got %+v", corrupt, entry, report.Corruption)
+	}
+	placeholder := fmt.Sprintf("sub_%x", entry)
+	found := false
+	for _, fn := range report.StdFunctions {
+		found = found || (fn.FullName == placeholder && fn.Start == entry)
+	}
+	if !found {
+		t.Errorf("expected the placeholder %s in the functions", placeholder)
+	}
+
+	// a clean binary reports nothing
+	if report, err := main_impl(clean, true, false, false, false, 0, "", false); err != nil || report.Corruption != nil {
+		t.Errorf("expected a clean binary to have no corruption, got %v and %+v", err, report)
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	workingDirectory, _ := os.Getwd()
+	clean := fmt.Sprintf("%s/test/weirdbins/hello_lin", workingDirectory)
+	data, err := os.ReadFile(clean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := main_impl(clean, true, false, false, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if expected.Truncated {
+		t.Errorf("expected the whole file not to be truncated")
+	}
+
+	// the less of the file there is the less is recovered, and none of it panics
+	const cuts = 24
+	file := filepath.Join(t.TempDir(), "hello_lin")
+	previous := 0
+	for i := 1; i <= cuts; i++ {
+		size := len(data) * i / (cuts + 1)
+		if err := os.WriteFile(file, data[:size], 0o644); err != nil {
+			t.Fatal(err)
+		}
+		var report goresym.Report
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("panic truncated to 0x%x bytes: %v", size, r)
+				}
+			}()
+			report, err = main_impl(file, true, false, true, false, 0, "", false)
+		}()
+
+		functions := len(report.StdFunctions) + len(report.UserFunctions)
+		if functions < previous {
+			t.Errorf("truncated to 0x%x bytes, expected at least the %d functions of a shorter cut, got %d", size, previous, functions)
+		}
+		previous = functions
+		if err == nil && !report.Truncated {
+			t.Errorf("truncated to 0x%x bytes, expected the report to be flagged truncated", size)
+		}
+		// without the moduledata, what it leads to is reported missing
+		if err == nil && report.ModuleMeta.VA == 0 && len(report.Unavailable) == 0 {
+			t.Errorf("truncated to 0x%x bytes, expected the components past the end listed", size)
+		}
+	}
+	if whole := len(expected.StdFunctions) + len(expected.UserFunctions); previous != whole {
+		t.Errorf("expected the longest cut to keep all %d functions, got %d", whole, previous)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	// each class exits with its own code, none of which is taken by success, the other failures or a bad flag
+	seen := map[int]bool{0: true, 1: true, 2: true}
+	for _, exit := range exitCodes {
+		if seen[exit.code] {
+			t.Errorf("the exit code %d of %s is taken", exit.code, exit.class)
+		}
+		seen[exit.code] = true
+	}
+
+	_, err := main_impl("test/weirdbins/notgo_invalid_bss_secsize", false, false, false, false, 0, "", false)
+	if code := exitCode(err); code != 3 {
+		t.Errorf("expected a file that isn't Go to exit with 3, got %d for %v", code, err)
+	}
+	if code := exitCode(fmt.Errorf("failed to write the output")); code != 9 {
+		t.Errorf("expected an unclassified error to be internal, got %d", code)
+	}
+}
+
+func TestChainedFixups(t *testing.T) {
+	// a darwin/arm64 build whose pointers are chain entries of LC_DYLD_CHAINED_FIXUPS rather than VAs with rebase opcodes
+	workingDirectory, _ := os.Getwd()
+	data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/chained_fixups_macho", workingDirectory), false, false, true, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if data.Version != "1.22.12" || data.ModuleMeta.VA == 0 || data.ModuleMeta.Types < 0x100000000 {
+		t.Errorf("expected the moduledata of a Go 1.22.12 binary, got %s at 0x%x", data.Version, data.ModuleMeta.VA)
+	}
+	if !slices.ContainsFunc(data.Types, func(typ objfile.Type) bool { return typ.Str == "main.structurea" }) || len(data.Interfaces) == 0 {
+		t.Errorf("expected the types and interfaces, got %d types without main.structurea and %d interfaces", len(data.Types), len(data.Interfaces))
+	}
+	for _, typ := range data.Types {
+		if typ.VA < 0x100000000 || typ.VA >= 0x100200000 {
+			t.Fatalf("expected the types within the image, got %s at 0x%x", typ.Str, typ.VA)
+		}
+	}
+}
+
+func TestProbedModuleData(t *testing.T) {
+	// a Go 1.27 build, whose moduledata dropped the typelinks and moved the fields after types, is newer than every table
+	workingDirectory, _ := os.Getwd()
+	data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/go127_lin", workingDirectory), false, false, true, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if data.ModuleMeta.LayoutSource != objfile.LayoutProbed || data.ModuleMeta.ProbedOffsets["etypes"] != 312 || data.ModuleMeta.Typelinks.Len != 0 {
+		t.Errorf("expected the probed layout of 1.27, got %s with etypes at %d", data.ModuleMeta.LayoutSource, data.ModuleMeta.ProbedOffsets["etypes"])
+	}
+	if !slices.ContainsFunc(data.UserFunctions, func(fn goresym.FuncMetadata) bool { return fn.FullName == "main.main" }) {
+		t.Errorf("expected main.main, got %d user functions", len(data.UserFunctions))
+	}
+
+	// the table still parses the layouts it knows
+	data, err = main_impl(fmt.Sprintf("%s/test/weirdbins/hash_v2_lin", workingDirectory), false, false, false, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	if data.ModuleMeta.LayoutSource != objfile.LayoutTable || data.ModuleMeta.ProbedOffsets != nil {
+		t.Errorf("expected the table layout, got %s", data.ModuleMeta.LayoutSource)
+	}
+}
+
+// slidImages relocates a position independent ELF by slide as the loader would, written back out in the layout of the file and as the
+// mapped memory of its PT_LOAD segments, whose dynamic section's pointers are relocated too
+func slidImages(t *testing.T, path string, slide uint64) (prelinked []byte, dump []byte) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileOffset := func(VA uint64) uint64 {
+		for _, p := range f.Progs {
+			if p.Type == elf.PT_LOAD && VA >= p.Vaddr && VA < p.Vaddr+p.Filesz {
+				return p.Off + VA - p.Vaddr
+			}
+		}
+		t.Fatalf("0x%x isn't in the file", VA)
+		return 0
+	}
+
+	var dynamic *elf.Prog
+	var rela, relasz, end uint64
+	for _, p := range f.Progs {
+		end = max(end, p.Vaddr+p.Memsz)
+		if p.Type == elf.PT_DYNAMIC {
+			dynamic = p
+		}
+	}
+	for off := dynamic.Off; off+16 <= dynamic.Off+dynamic.Filesz; off += 16 {
+		switch elf.DynTag(binary.LittleEndian.Uint64(data[off:])) {
+		case elf.DT_RELA:
+			rela = binary.LittleEndian.Uint64(data[off+8:])
+		case elf.DT_RELASZ:
+			relasz = binary.LittleEndian.Uint64(data[off+8:])
+		}
+	}
+
+	prelinked = slices.Clone(data)
+	for off := fileOffset(rela); off < fileOffset(rela)+relasz; off += 24 {
+		if elf.R_X86_64(binary.LittleEndian.Uint64(data[off+8:])) == elf.R_X86_64_RELATIVE {
+			binary.LittleEndian.PutUint64(prelinked[fileOffset(binary.LittleEndian.Uint64(data[off:])):], binary.LittleEndian.Uint64(data[off+16:])+slide)
+		}
+	}
+
+	// the image is linked at 0, its memory starts with the first segment
+	dump = make([]byte, end)
+	for _, p := range f.Progs {
+		if p.Type == elf.PT_LOAD {
+			copy(dump[p.Vaddr:], prelinked[p.Off:p.Off+p.Filesz])
+		}
+	}
+	for VA := dynamic.Vaddr; VA+16 <= dynamic.Vaddr+dynamic.Filesz; VA += 16 {
+		switch elf.DynTag(binary.LittleEndian.Uint64(dump[VA:])) {
+		case elf.DT_PLTGOT, elf.DT_HASH, elf.DT_STRTAB, elf.DT_SYMTAB, elf.DT_RELA, elf.DT_JMPREL, elf.DT_GNU_HASH, elf.DT_VERSYM, elf.DT_VERNEED:
+			binary.LittleEndian.PutUint64(dump[VA+8:], binary.LittleEndian.Uint64(dump[VA+8:])+slide)
+		}
+	}
+	return prelinked, dump
+}
+
+func TestSlide(t *testing.T) {
+	// a PIE linked at 0, on disk and relocated to where a loader put it: written back out like a prelinked image and dumped from memory
+	const slide = 0x7f1234560000
+	workingDirectory, _ := os.Getwd()
+	path := fmt.Sprintf("%s/test/weirdbins/elf_data_rel_ro_pclntab", workingDirectory)
+	prelinked, dump := slidImages(t, path, slide)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "prelinked"), prelinked, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "dump"), dump, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	onDisk, err := main_impl(path, false, false, true, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed on the file: %s", err)
+	}
+	if onDisk.Slide != 0 || len(onDisk.UserFunctions) == 0 || len(onDisk.Types) == 0 {
+		t.Fatalf("expected the functions and types of the file without a slide, got 0x%x with %d functions and %d types", onDisk.Slide, len(onDisk.UserFunctions), len(onDisk.Types))
+	}
+
+	for _, c := range []struct {
+		name  string
+		file  string
+		dump  bool
+		slide uint64
+	}{
+		{"prelinked", "prelinked", false, 0},
+		{"dump", "dump", true, 0},
+		{"dump with -slide", "dump", true, slide},
+	} {
+		opts := options(false, false, true, false, 0, "", false)
+		opts.Mode, opts.Slide = "file", c.slide
+		if c.dump {
+			opts.Mode = "dump"
+		}
+		data, err := extractFile(filepath.Join(dir, c.file), opts)
+		if err != nil {
+			t.Errorf("%s: GoReSym failed: %s", c.name, err)
+			continue
+		}
+		if data.Slide != slide || data.ModuleMeta.VA != onDisk.ModuleMeta.VA+slide || data.ModuleMeta.TextVA != onDisk.ModuleMeta.TextVA+slide {
+			t.Errorf("%s: expected a slide of 0x%x and the moduledata slid by it, got 0x%x with the moduledata at 0x%x", c.name, uint64(slide), data.Slide, data.ModuleMeta.VA)
+		}
+		if len(data.UserFunctions) != len(onDisk.UserFunctions) || len(data.Types) != len(onDisk.Types) {
+			t.Errorf("%s: expected %d functions and %d types, got %d and %d", c.name, len(onDisk.UserFunctions), len(onDisk.Types), len(data.UserFunctions), len(data.Types))
+			continue
+		}
+		for i, fn := range data.UserFunctions {
+			if fn.Start != onDisk.UserFunctions[i].Start+slide || fn.FullName != onDisk.UserFunctions[i].FullName {
+				t.Errorf("%s: expected %s at 0x%x, got %s at 0x%x", c.name, onDisk.UserFunctions[i].FullName, onDisk.UserFunctions[i].Start+slide, fn.FullName, fn.Start)
+				break
+			}
+		}
+		for i, typ := range data.Types {
+			if typ.VA != onDisk.Types[i].VA+slide || typ.Str != onDisk.Types[i].Str {
+				t.Errorf("%s: expected %s at 0x%x, got %s at 0x%x", c.name, onDisk.Types[i].Str, onDisk.Types[i].VA+slide, typ.Str, typ.VA)
+				break
+			}
+		}
+	}
+}
+
+func TestJsonSchema(t *testing.T) {
+	// the output of a run is the same every time, and what a reader of SchemaVersion unmarshals marshals back to the same bytes
+	workingDirectory, _ := os.Getwd()
+	output := func(printTypes bool) []byte {
+		data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/hello_lin", workingDirectory), false, true, printTypes, false, 0, "", false)
+		if err != nil {
+			t.Fatalf("GoReSym failed: %s", err)
+		}
+		data.Timings, data.Diagnostics = nil, nil
+		return []byte(DataToJson(data))
+	}
+	first := output(false)
+	compareGolden(t, "hello_lin.json", first)
+	if second := output(false); !bytes.Equal(first, second) {
+		t.Errorf("expected the same output every run")
+	}
+
+	golden, err := os.ReadFile("test/golden/hello_lin.json")
+	if err != nil {
+		t.Fatalf("failed to read the golden output: %s", err)
+	}
+	withTypes := output(true)
+	for _, document := range [][]byte{golden, withTypes} {
+		var report goresym.Report
+		if err := json.Unmarshal(document, &report); err != nil {
+			t.Fatalf("failed to unmarshal the output: %s", err)
+		}
+		if report.SchemaVersion != goresym.SchemaVersion {
+			t.Errorf("expected schema version %d, got %d", goresym.SchemaVersion, report.SchemaVersion)
+		}
+		if remarshaled := []byte(DataToJson(report)); !bytes.Equal(remarshaled, document) {
+			t.Errorf("expected the output to marshal back to the same bytes")
+		}
+	}
+
+	// absent data is an explicit null rather than a missing key
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(golden, &keys); err != nil {
+		t.Fatalf("failed to unmarshal the output: %s", err)
+	}
+	for _, key := range []string{"Types", "TinyGo", "Hooks", "Timings"} {
+		if value, ok := keys[key]; !ok || string(value) != "null" {
+			t.Errorf("expected %s to be null, got %q", key, value)
+		}
+	}
+
+	// and so is the failure record of -json-errors
+	path := fmt.Sprintf("%s/test/weirdbins/notgo_invalid_bss_secsize", workingDirectory)
+	metadata, err := main_impl(path, false, true, false, false, 0, "", false)
+	if err == nil {
+		t.Fatalf("expected GoReSym to fail on %s", path)
+	}
+	metadata.Timings, metadata.Diagnostics = nil, nil
+	document := []byte(DataToJson(newJsonError(fmt.Sprintf("Failed to parse file: %s", err), err, metadata)))
+	compareGolden(t, "notgo_invalid_bss_secsize.json", document)
+	var failure jsonError
+	if err := json.Unmarshal(document, &failure); err != nil {
+		t.Fatalf("failed to unmarshal the failure: %s", err)
+	}
+	if failure.SchemaVersion != goresym.SchemaVersion || failure.Class != "NotGo" {
+		t.Errorf("expected a NotGo failure of schema version %d, got %s of %d", goresym.SchemaVersion, failure.Class, failure.SchemaVersion)
+	}
+	if remarshaled := []byte(DataToJson(failure)); !bytes.Equal(remarshaled, document) {
+		t.Errorf("expected the failure to marshal back to the same bytes")
+	}
+	keys = nil
+	if err := json.Unmarshal(document, &keys); err != nil {
+		t.Fatalf("failed to unmarshal the failure: %s", err)
+	}
+	for _, key := range []string{"stack", "Version", "Attempts", "Diagnostics", "Failed"} {
+		if _, ok := keys[key]; !ok {
+			t.Errorf("expected the failure to have %s", key)
+		}
+	}
+}
+
+func TestWriteJson(t *testing.T) {
+	// the lists are written an element at a time, the document is DataToJson's whether they're full, empty or null
+	workingDirectory, _ := os.Getwd()
+	data, err := main_impl(fmt.Sprintf("%s/test/weirdbins/hello_lin", workingDirectory), true, true, true, false, 0, "", false)
+	if err != nil {
+		t.Fatalf("GoReSym failed: %s", err)
+	}
+	empty := data
+	empty.UserFunctions, empty.Interfaces = []goresym.FuncMetadata{}, nil
+	for _, document := range []interface{}{data, empty, goresym.Report{}, newJsonError("failed", nil, goresym.Report{})} {
+		var written bytes.Buffer
+		writeJson(&written, document)
+		if expected := DataToJson(document) + "\n"; written.String() != expected {
+			t.Errorf("expected the document of DataToJson, got %d bytes instead of %d", written.Len(), len(expected))
+		}
+	}
 }
@@ -0,0 +1,62 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+// the records are written out in batches of this many, a consumer sees them well before extraction finishes
+const ndjsonFlushRecords = 256
+
+// ndjsonOut is set for -outputformat ndjson, the extraction then streams the types, interfaces and functions to it as records as
+// it recovers them rather than keeping them in the metadata, see goresym.Options
+var ndjsonOut *ndjsonStream
+
+// ndjsonStream writes NDJSON records, one object per line of the form {"kind": kind, kind: value}. The header of a file comes
+// first, then its types, interfaces and functions as they are recovered and last a metadata record of everything else. record is
+// the goresym.Options Stream.
+type ndjsonStream struct {
+	w       *bufio.Writer
+	records int
+	err     error
+}
+
+func newNdjsonStream(w io.Writer) *ndjsonStream {
+	return &ndjsonStream{w: bufio.NewWriter(w)}
+}
+
+// record writes one line, the first error stops the stream and is returned by flush
+func (s *ndjsonStream) record(kind string, value interface{}) {
+	if s.err != nil {
+		return
+	}
+	encodedKind, _ := json.Marshal(kind)
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.w.WriteString("{\"kind\":")
+	s.w.Write(encodedKind)
+	s.w.WriteString(",")
+	s.w.Write(encodedKind)
+	s.w.WriteString(":")
+	s.w.Write(encodedValue)
+	s.w.WriteString("}\n")
+
+	s.records++
+	if s.records%ndjsonFlushRecords == 0 {
+		s.err = s.w.Flush()
+	}
+}
+
+// flush writes the buffered records out, a consumer has everything so far once it returns
+func (s *ndjsonStream) flush() error {
+	if s.err != nil {
+		return s.err
+	}
+	s.err = s.w.Flush()
+	return s.err
+}
@@ -0,0 +1,96 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"strings"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+	"github.com/mandiant/GoReSym/debug/macho"
+	"github.com/mandiant/GoReSym/debug/pe"
+)
+
+// isPluginSymbol reports whether name is a symbol only the linker of a Go plugin emits. Plugins export Go symbols dynamically,
+// the package hashes among them, so they survive stripping. Names are go.* before 1.20 and go:* since, Mach-O adds a leading underscore.
+func isPluginSymbol(name string) bool {
+	name = strings.TrimPrefix(name, "_")
+	for _, prefix := range []string{"go:plugin.", "go.plugin.", "go:link.pkghash", "go.link.pkghash"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPluginSymbol(syms []Sym) bool {
+	for _, sym := range syms {
+		if isPluginSymbol(sym.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildMode infers the -buildmode the file was linked with from its file type: exe, pie, c-shared, plugin or c-archive.
+// Returns an empty string when the layout doesn't tell. The build info records the mode on 1.18+, prefer it when present.
+func (e *Entry) BuildMode() string {
+	syms, _ := e.raw.symbols()
+
+	switch f := e.raw.(type) {
+	case *elfFile:
+		switch f.elf.Type {
+		case elf.ET_EXEC:
+			return "exe"
+		case elf.ET_REL:
+			// the go.o object a c-archive wraps
+			return "c-archive"
+		case elf.ET_DYN:
+			for _, prog := range f.elf.Progs {
+				if prog.Type == elf.PT_INTERP {
+					return "pie"
+				}
+			}
+
+			dynSyms, _ := f.elf.DynamicSymbols()
+			for _, sym := range dynSyms {
+				if isPluginSymbol(sym.Name) {
+					return "plugin"
+				}
+			}
+			if hasPluginSymbol(syms) {
+				return "plugin"
+			}
+			return "c-shared"
+		}
+	case *machoFile:
+		switch f.macho.Type {
+		case macho.TypeExec:
+			if f.macho.Flags&macho.FlagPIE != 0 {
+				return "pie"
+			}
+			return "exe"
+		case macho.TypeDylib, macho.TypeBundle:
+			if hasPluginSymbol(syms) {
+				return "plugin"
+			}
+			return "c-shared"
+		}
+	case *peFile:
+		if f.pe.Characteristics&pe.IMAGE_FILE_DLL != 0 {
+			return "c-shared"
+		}
+
+		// an ASLR image keeps its base relocations, windows builds are pie by default since 1.15
+		var dllCharacteristics uint16
+		switch oh := f.pe.OptionalHeader.(type) {
+		case *pe.OptionalHeader32:
+			dllCharacteristics = oh.DllCharacteristics
+		case *pe.OptionalHeader64:
+			dllCharacteristics = oh.DllCharacteristics
+		}
+		if dllCharacteristics&pe.IMAGE_DLLCHARACTERISTICS_DYNAMIC_BASE != 0 && f.pe.Section(".reloc") != nil {
+			return "pie"
+		}
+		return "exe"
+	}
+	return ""
+}
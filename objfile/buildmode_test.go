@@ -0,0 +1,22 @@
+package objfile
+
+import "testing"
+
+func TestIsPluginSymbol(t *testing.T) {
+	cases := map[string]bool{
+		"go:plugin.tabs":                      true,
+		"go.plugin.tabs":                      true,
+		"_go:plugin.tabs":                     true, // Mach-O
+		"go:link.pkghash.internal/cmp":        true,
+		"go.link.pkghashbytes.syscall":        true,
+		"go:itab.*io.LimitedReader,io.Reader": false,
+		"plugin.Open":                         false,
+		"crosscall2":                          false,
+	}
+
+	for name, expected := range cases {
+		if isPluginSymbol(name) != expected {
+			t.Errorf("%s: expected %t", name, expected)
+		}
+	}
+}
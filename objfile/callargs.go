@@ -0,0 +1,187 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// StringArgCallSite is a call site whose constant string argument could be resolved
+type StringArgCallSite struct {
+	CallSite
+	Arg string
+}
+
+// register ABI (>= 1.17) slots of the string argument (pointer, length) for well known callees.
+// Callees not listed here, and binaries using the older stack ABI, fall back to pairing the most recent constant pointer and length.
+var stringArgRegs = map[string][2]x86asm.Reg{
+	"reflect.Value.FieldByName":         {x86asm.RDI, x86asm.RSI}, // receiver is 3 words, RAX:RBX:RCX
+	"reflect.(*rtype).FieldByName":      {x86asm.RBX, x86asm.RCX},
+	"reflect.(*structType).FieldByName": {x86asm.RBX, x86asm.RCX},
+	"expvar.Publish":                    {x86asm.RAX, x86asm.RBX},
+	"expvar.NewInt":                     {x86asm.RAX, x86asm.RBX},
+	"expvar.NewFloat":                   {x86asm.RAX, x86asm.RBX},
+	"expvar.NewMap":                     {x86asm.RAX, x86asm.RBX},
+	"expvar.NewString":                  {x86asm.RAX, x86asm.RBX},
+}
+
+// maxStringArgLen bounds the length immediates considered as string lengths
+const maxStringArgLen = 256
+
+// canonicalReg maps the 32bit view of a register to the 64bit register, writes to the low dword zero extend
+func canonicalReg(r x86asm.Reg) x86asm.Reg {
+	if r >= x86asm.EAX && r <= x86asm.R15L {
+		return r - x86asm.EAX + x86asm.RAX
+	}
+	return r
+}
+
+// isPrintableString reports whether data is non-empty printable ASCII, our proxy for a real string constant
+func isPrintableString(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	for _, c := range data {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+type constTracker struct {
+	leas     map[x86asm.Reg]uint64 // register -> rip relative address loaded into it
+	imms     map[x86asm.Reg]uint64 // register -> immediate moved into it
+	leaOrder []uint64              // every constant address in program order
+	immOrder []uint64              // every small immediate in program order, register or stack stores
+}
+
+func newConstTracker() *constTracker {
+	return &constTracker{leas: make(map[x86asm.Reg]uint64), imms: make(map[x86asm.Reg]uint64)}
+}
+
+// observe updates the tracked constants with the effects of inst, located at pc
+func (c *constTracker) observe(inst x86asm.Inst, pc uint64) {
+	dst, isReg := inst.Args[0].(x86asm.Reg)
+	if isReg {
+		dst = canonicalReg(dst)
+		delete(c.leas, dst)
+		delete(c.imms, dst)
+	}
+
+	switch inst.Op {
+	case x86asm.LEA:
+		if mem, ok := inst.Args[1].(x86asm.Mem); ok && isReg && mem.Base == x86asm.RIP && mem.Index == 0 {
+			addr := uint64(int64(pc) + int64(inst.Len) + mem.Disp)
+			c.leas[dst] = addr
+			c.leaOrder = append(c.leaOrder, addr)
+		}
+	case x86asm.MOV:
+		if imm, ok := inst.Args[1].(x86asm.Imm); ok && imm > 0 && imm <= maxStringArgLen {
+			if isReg {
+				c.imms[dst] = uint64(imm)
+			}
+			c.immOrder = append(c.immOrder, uint64(imm))
+		}
+	}
+}
+
+// FindStringArgCallSites is like FindCallSites, but also resolves the constant string passed to each call.
+// Call sites whose argument is not a constant (or not resolvable) are omitted. Only amd64 is supported.
+func (e *Entry) FindStringArgCallSites(funcs []gosym.Func, allFuncs []gosym.Func, targets map[string]bool) ([]StringArgCallSite, error) {
+	goarch := e.GOARCH()
+	if goarch != "amd64" {
+		return nil, fmt.Errorf("string argument resolution unsupported for architecture %q", goarch)
+	}
+
+	targetsByVA := make(map[uint64]string)
+	for _, fn := range allFuncs {
+		if targets[fn.Name] {
+			targetsByVA[fn.Entry] = fn.Name
+		}
+	}
+
+	var sites []StringArgCallSite
+	if len(targetsByVA) == 0 {
+		return sites, nil
+	}
+
+	readString := func(ptr uint64, length uint64) (string, bool) {
+		data, err := e.raw.read_memory(ptr, length)
+		if err != nil || !isPrintableString(data) {
+			return "", false
+		}
+		return string(data), true
+	}
+
+	for _, fn := range funcs {
+		if fn.End <= fn.Entry {
+			continue
+		}
+
+		code, err := e.raw.read_memory(fn.Entry, fn.End-fn.Entry)
+		if err != nil {
+			continue
+		}
+
+		tracker := newConstTracker()
+		for off := 0; off < len(code); {
+			pc := fn.Entry + uint64(off)
+			inst, err := x86asm.Decode(code[off:], 64)
+			if err != nil || inst.Len == 0 {
+				off++
+				continue
+			}
+			off += inst.Len
+
+			if inst.Op != x86asm.CALL {
+				tracker.observe(inst, pc)
+				continue
+			}
+
+			rel, ok := inst.Args[0].(x86asm.Rel)
+			if ok {
+				target := uint64(int64(pc) + int64(inst.Len) + int64(rel))
+				if callee, isTarget := targetsByVA[target]; isTarget {
+					site := CallSite{VA: pc, Caller: fn.Name, CallerVA: fn.Entry, Callee: callee, CalleeVA: target}
+					if arg, resolved := tracker.resolveString(callee, readString); resolved {
+						sites = append(sites, StringArgCallSite{CallSite: site, Arg: arg})
+					}
+				}
+			}
+
+			// arguments are set up after the previous call returns, and calls clobber the argument registers
+			tracker = newConstTracker()
+		}
+	}
+
+	sort.Slice(sites, func(i, j int) bool { return sites[i].VA < sites[j].VA })
+	return sites, nil
+}
+
+// resolveString finds the string argument of a call to callee from the tracked constants
+func (c *constTracker) resolveString(callee string, readString func(ptr uint64, length uint64) (string, bool)) (string, bool) {
+	if regs, ok := stringArgRegs[callee]; ok {
+		ptr, hasPtr := c.leas[regs[0]]
+		length, hasLen := c.imms[regs[1]]
+		if hasPtr && hasLen {
+			if s, ok := readString(ptr, length); ok {
+				return s, true
+			}
+		}
+	}
+
+	// stack ABI or unknown callee, most recent pairing that reads as a string wins
+	for i := len(c.leaOrder) - 1; i >= 0; i-- {
+		for j := len(c.immOrder) - 1; j >= 0; j-- {
+			if s, ok := readString(c.leaOrder[i], c.immOrder[j]); ok {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,82 @@
+package objfile
+
+import (
+	"testing"
+
+	"golang.org/x/arch/x86/x86asm"
+)
+
+func TestConstTrackerResolveString(t *testing.T) {
+	const pc = 0x401000
+	code := []byte{
+		0x48, 0x8D, 0x3D, 0xF9, 0x0F, 0x00, 0x00, // lea rdi, [rip+0xff9]   -> 0x402000
+		0xBE, 0x04, 0x00, 0x00, 0x00, // mov esi, 4
+	}
+
+	memory := map[uint64]string{0x402000: "Nameother"}
+	readString := func(ptr uint64, length uint64) (string, bool) {
+		s, ok := memory[ptr]
+		if !ok || uint64(len(s)) < length || !isPrintableString([]byte(s[:length])) {
+			return "", false
+		}
+		return s[:length], true
+	}
+
+	tracker := newConstTracker()
+	for off := 0; off < len(code); {
+		inst, err := x86asm.Decode(code[off:], 64)
+		if err != nil {
+			t.Fatalf("decode failed at %d: %s", off, err)
+		}
+		tracker.observe(inst, pc+uint64(off))
+		off += inst.Len
+	}
+
+	if arg, ok := tracker.resolveString("reflect.Value.FieldByName", readString); !ok || arg != "Name" {
+		t.Errorf("register ABI: expected Name, got %q", arg)
+	}
+
+	if arg, ok := tracker.resolveString("main.unknown", readString); !ok || arg != "Name" {
+		t.Errorf("fallback: expected Name, got %q", arg)
+	}
+
+	// clobbering the length register must drop it
+	tracker.observe(x86asm.Inst{Op: x86asm.XOR, Args: x86asm.Args{x86asm.ESI, x86asm.ESI}}, pc)
+	if _, ok := tracker.imms[x86asm.RSI]; ok {
+		t.Errorf("clobbered register still tracked")
+	}
+}
+
+func TestExpvarArgRegs(t *testing.T) {
+	const pc = 0x401000
+	code := []byte{
+		0x48, 0x8D, 0x05, 0xF9, 0x0F, 0x00, 0x00, // lea rax, [rip+0xff9]   -> 0x402000
+		0xBB, 0x0B, 0x00, 0x00, 0x00, // mov ebx, 11
+		0x48, 0x8D, 0x0D, 0xEE, 0x1F, 0x00, 0x00, // lea rcx, [rip+0x1fee]  -> 0x403000
+		0xBF, 0x03, 0x00, 0x00, 0x00, // mov edi, 3
+	}
+
+	memory := map[uint64]string{0x402000: "beacon_hits", 0x403000: "zzzzzzzzzzzz"}
+	readString := func(ptr uint64, length uint64) (string, bool) {
+		s, ok := memory[ptr]
+		if !ok || uint64(len(s)) < length {
+			return "", false
+		}
+		return s[:length], true
+	}
+
+	tracker := newConstTracker()
+	for off := 0; off < len(code); {
+		inst, err := x86asm.Decode(code[off:], 64)
+		if err != nil {
+			t.Fatalf("decode failed at %d: %s", off, err)
+		}
+		tracker.observe(inst, pc+uint64(off))
+		off += inst.Len
+	}
+
+	// the later constants would win the fallback pairing, the register ABI slots must take priority
+	if arg, ok := tracker.resolveString("expvar.NewInt", readString); !ok || arg != "beacon_hits" {
+		t.Errorf("expected beacon_hits, got %q", arg)
+	}
+}
@@ -0,0 +1,133 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+
+	"golang.org/x/arch/arm/armasm"
+	"golang.org/x/arch/arm64/arm64asm"
+	"golang.org/x/arch/ppc64/ppc64asm"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// CallSite is a direct call from one recovered function to another
+type CallSite struct {
+	VA       uint64 // address of the call instruction
+	Caller   string
+	CallerVA uint64
+	Callee   string
+	CalleeVA uint64
+}
+
+// callDecoder decodes the instruction at the start of code, returning its size and, if it is a direct call, the call target
+type callDecoder func(code []byte, pc uint64, byteOrder binary.ByteOrder) (size int, target uint64, isCall bool)
+
+func decodeCall_x86(code []byte, pc uint64, mode int) (int, uint64, bool) {
+	inst, err := x86asm.Decode(code, mode)
+	if err != nil || inst.Len == 0 {
+		return 1, 0, false
+	}
+
+	if inst.Op == x86asm.CALL {
+		if rel, ok := inst.Args[0].(x86asm.Rel); ok {
+			return inst.Len, uint64(int64(pc) + int64(inst.Len) + int64(rel)), true
+		}
+	}
+	return inst.Len, 0, false
+}
+
+var callDecoders = map[string]callDecoder{
+	"386": func(code []byte, pc uint64, _ binary.ByteOrder) (int, uint64, bool) {
+		return decodeCall_x86(code, pc, 32)
+	},
+	"amd64": func(code []byte, pc uint64, _ binary.ByteOrder) (int, uint64, bool) {
+		return decodeCall_x86(code, pc, 64)
+	},
+	"arm": func(code []byte, pc uint64, _ binary.ByteOrder) (int, uint64, bool) {
+		inst, err := armasm.Decode(code, armasm.ModeARM)
+		if err == nil && inst.Op == armasm.BL {
+			if rel, ok := inst.Args[0].(armasm.PCRel); ok {
+				// arm reads pc as the current instruction + 8
+				return 4, uint64(uint32(pc) + 8 + uint32(rel)), true
+			}
+		}
+		return 4, 0, false
+	},
+	"arm64": func(code []byte, pc uint64, _ binary.ByteOrder) (int, uint64, bool) {
+		inst, err := arm64asm.Decode(code)
+		if err == nil && inst.Op == arm64asm.BL {
+			if rel, ok := inst.Args[0].(arm64asm.PCRel); ok {
+				return 4, uint64(int64(pc) + int64(rel)), true
+			}
+		}
+		return 4, 0, false
+	},
+	"ppc64":   decodeCall_ppc64,
+	"ppc64le": decodeCall_ppc64,
+}
+
+func decodeCall_ppc64(code []byte, pc uint64, byteOrder binary.ByteOrder) (int, uint64, bool) {
+	inst, err := ppc64asm.Decode(code, byteOrder)
+	if err == nil && inst.Op == ppc64asm.BL {
+		if rel, ok := inst.Args[0].(ppc64asm.PCRel); ok {
+			return 4, uint64(int64(pc) + int64(rel)), true
+		}
+	}
+	return 4, 0, false
+}
+
+// FindCallSites disassembles each function in funcs and returns the direct calls into any function named in targets.
+// allFuncs is used to resolve call targets, it should be every function in the pclntab, not just the ones being scanned.
+func (e *Entry) FindCallSites(funcs []gosym.Func, allFuncs []gosym.Func, targets map[string]bool) ([]CallSite, error) {
+	goarch := e.GOARCH()
+	decode := callDecoders[goarch]
+	byteOrder := byteOrders[goarch]
+	if decode == nil || byteOrder == nil {
+		return nil, fmt.Errorf("call resolution unsupported for architecture %q", goarch)
+	}
+
+	targetsByVA := make(map[uint64]string)
+	for _, fn := range allFuncs {
+		if targets[fn.Name] {
+			targetsByVA[fn.Entry] = fn.Name
+		}
+	}
+
+	var sites []CallSite
+	if len(targetsByVA) == 0 {
+		return sites, nil
+	}
+
+	for _, fn := range funcs {
+		if fn.End <= fn.Entry {
+			continue
+		}
+
+		code, err := e.raw.read_memory(fn.Entry, fn.End-fn.Entry)
+		if err != nil {
+			continue
+		}
+
+		for off := 0; off < len(code); {
+			pc := fn.Entry + uint64(off)
+			size, target, isCall := decode(code[off:], pc, byteOrder)
+			if isCall {
+				if callee, ok := targetsByVA[target]; ok {
+					sites = append(sites, CallSite{VA: pc, Caller: fn.Name, CallerVA: fn.Entry, Callee: callee, CalleeVA: target})
+				}
+			}
+
+			if size <= 0 {
+				size = 1
+			}
+			off += size
+		}
+	}
+
+	sort.Slice(sites, func(i, j int) bool { return sites[i].VA < sites[j].VA })
+	return sites, nil
+}
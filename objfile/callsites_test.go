@@ -0,0 +1,48 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestCallDecoders(t *testing.T) {
+	cases := []struct {
+		goarch string
+		code   []byte
+		pc     uint64
+		size   int
+		target uint64
+	}{
+		// call rel32 -0x1005
+		{"amd64", []byte{0xE8, 0xFB, 0xEF, 0xFF, 0xFF}, 0x401000, 5, 0x400000},
+		{"386", []byte{0xE8, 0x10, 0x00, 0x00, 0x00}, 0x8049000, 5, 0x8049015},
+		// bl #0x100
+		{"arm64", []byte{0x40, 0x00, 0x00, 0x94}, 0x10000, 4, 0x10100},
+		// bl #0x100, relative to pc+8
+		{"arm", []byte{0x3E, 0x00, 0x00, 0xEB}, 0x10000, 4, 0x10100},
+		// bl 0x100
+		{"ppc64", []byte{0x48, 0x00, 0x01, 0x01}, 0x10000, 4, 0x10100},
+		{"ppc64le", []byte{0x01, 0x01, 0x00, 0x48}, 0x10000, 4, 0x10100},
+	}
+
+	for _, c := range cases {
+		t.Run(c.goarch, func(t *testing.T) {
+			size, target, isCall := callDecoders[c.goarch](c.code, c.pc, byteOrders[c.goarch])
+			if !isCall {
+				t.Fatalf("call not decoded")
+			}
+
+			if size != c.size || target != c.target {
+				t.Errorf("expected size %d target %x, got size %d target %x", c.size, c.target, size, target)
+			}
+		})
+	}
+
+	// indirect calls have no static target
+	t.Run("amd64 indirect", func(t *testing.T) {
+		// call rax
+		if _, _, isCall := callDecoders["amd64"]([]byte{0xFF, 0xD0}, 0x401000, binary.LittleEndian); isCall {
+			t.Errorf("indirect call resolved")
+		}
+	})
+}
@@ -0,0 +1,32 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import "context"
+
+// contextErr is ctx.Err(), a nil ctx is never done
+func contextErr(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// SetContext makes the long scans and walks of the file stop once ctx is done: the moduledata signature scan and the pclntab candidates
+// of PCLineTable, and the typelinks and itablinks walks of ParseTypeLinks, ParseITabLinks and ScanTypes. Those then return what they
+// had so far with ctx.Err(). nil, the default, never stops them.
+func (e *Entry) SetContext(ctx context.Context) {
+	e.ctx = ctx
+
+	switch f := e.raw.(type) {
+	case *elfFile:
+		f.ctx = ctx
+	case *machoFile:
+		f.ctx = ctx
+	case *peFile:
+		f.ctx = ctx
+	case *dumpFile:
+		f.ctx = ctx
+	case *wasmFile:
+		f.ctx = ctx
+	}
+}
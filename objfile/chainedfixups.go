@@ -0,0 +1,179 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/mandiant/GoReSym/debug/macho"
+)
+
+// the load command of the chained fixups, a linkedit_data_command of the payload in __LINKEDIT
+const loadCmdDyldChainedFixups = 0x80000034
+
+// the pointer formats of a segment's chains, dyld_chained_ptr_* in mach-o/fixup-chains.h
+const (
+	chainedPtrArm64e           = 1
+	chainedPtr64               = 2
+	chainedPtr64Offset         = 6
+	chainedPtrArm64eUserland   = 9
+	chainedPtrArm64eUserland24 = 12
+)
+
+// a page of a segment without a chain
+const chainedPtrStartNone = 0xFFFF
+
+// a chainedFixup is the pointer a chain entry at VA encodes, rebased on the preferred base. Binds are to other images and hold 0.
+type chainedFixup struct {
+	VA    uint64
+	value uint64
+}
+
+// chainedFixups decodes the LC_DYLD_CHAINED_FIXUPS of the file, sorted by VA. Files linked by a recent ld store the pointers of their
+// data this way rather than with rebase opcodes, a pointer in the file is then an entry of the chain of its page and not a VA. A payload
+// that doesn't parse, or a file without one, has none and its pointers are read raw.
+func (f *machoFile) chainedFixups() []chainedFixup {
+	payload := f.chainedFixupsPayload()
+	if len(payload) < 28 {
+		return nil
+	}
+	bo := f.macho.ByteOrder
+	base, err := f.loadAddress()
+	if err != nil {
+		return nil
+	}
+
+	// dyld_chained_fixups_header then dyld_chained_starts_in_image: the offset of each segment's starts, 0 for a segment without pointers
+	startsOffset := uint64(bo.Uint32(payload[4:]))
+	if startsOffset+4 > uint64(len(payload)) {
+		return nil
+	}
+	segCount := uint64(bo.Uint32(payload[startsOffset:]))
+	if startsOffset+4+segCount*4 > uint64(len(payload)) {
+		return nil
+	}
+
+	var fixups []chainedFixup
+	for seg := uint64(0); seg < segCount; seg++ {
+		segInfo := uint64(bo.Uint32(payload[startsOffset+4+seg*4:]))
+		if segInfo == 0 {
+			continue
+		}
+		// dyld_chained_starts_in_segment
+		starts := startsOffset + segInfo
+		if starts+22 > uint64(len(payload)) {
+			continue
+		}
+		pageSize := uint64(bo.Uint16(payload[starts+4:]))
+		pointerFormat := bo.Uint16(payload[starts+6:])
+		segmentOffset := bo.Uint64(payload[starts+8:])
+		pageCount := uint64(bo.Uint16(payload[starts+20:]))
+		if starts+22+pageCount*2 > uint64(len(payload)) || pageSize == 0 {
+			continue
+		}
+		for page := uint64(0); page < pageCount; page++ {
+			start := uint64(bo.Uint16(payload[starts+22+page*2:]))
+			if start == chainedPtrStartNone {
+				continue
+			}
+			pageVA := base + segmentOffset + page*pageSize
+			data, err := f.readSegment(pageVA, pageSize)
+			if err != nil {
+				continue
+			}
+			fixups = append(fixups, walkChain(data, pageVA, start, pointerFormat, base, bo)...)
+		}
+	}
+	sort.Slice(fixups, func(i, j int) bool { return fixups[i].VA < fixups[j].VA })
+	return fixups
+}
+
+// chainedFixupsPayload is the payload of the LC_DYLD_CHAINED_FIXUPS, read through the segment laying out its file offset
+func (f *machoFile) chainedFixupsPayload() []byte {
+	for _, load := range f.macho.Loads {
+		raw, ok := load.(macho.LoadBytes)
+		if !ok || len(raw) < 16 || f.macho.ByteOrder.Uint32(raw) != loadCmdDyldChainedFixups {
+			continue
+		}
+		dataoff := uint64(f.macho.ByteOrder.Uint32(raw[8:]))
+		datasize := uint64(f.macho.ByteOrder.Uint32(raw[12:]))
+		for _, load := range f.macho.Loads {
+			seg, ok := load.(*macho.Segment)
+			if !ok || dataoff < seg.Offset || dataoff+datasize > seg.Offset+seg.Filesz {
+				continue
+			}
+			payload := make([]byte, datasize)
+			if _, err := seg.ReadAt(payload, int64(dataoff-seg.Offset)); err != nil {
+				return nil
+			}
+			return payload
+		}
+	}
+	return nil
+}
+
+// walkChain decodes the chain starting start bytes into the page at pageVA. Each entry holds the distance to the next one in strides
+// of the format, the last holds 0. Formats other than the 64 bit ones of user space executables are skipped.
+func walkChain(page []byte, pageVA uint64, start uint64, pointerFormat uint16, base uint64, bo binary.ByteOrder) []chainedFixup {
+	var fixups []chainedFixup
+	for offset := start; offset+8 <= uint64(len(page)); {
+		raw := bo.Uint64(page[offset:])
+		var value, next uint64
+		switch pointerFormat {
+		case chainedPtr64, chainedPtr64Offset:
+			// dyld_chained_ptr_64_rebase: target:36 high8:8 reserved:7 next:12 bind:1
+			next = (raw >> 51 & 0xFFF) * 4
+			if raw>>63 == 0 {
+				value = (raw>>36&0xFF)<<56 | raw&(1<<36-1)
+				if pointerFormat == chainedPtr64Offset {
+					value += base
+				}
+			}
+		case chainedPtrArm64e, chainedPtrArm64eUserland, chainedPtrArm64eUserland24:
+			// dyld_chained_ptr_arm64e_rebase: target:43 high8:8 next:11 bind:1 auth:1, an authenticated rebase has a 32 bit
+			// target that is always an offset
+			next = (raw >> 51 & 0x7FF) * 8
+			auth, bind := raw>>63 == 1, raw>>62&1 == 1
+			switch {
+			case bind:
+			case auth:
+				value = base + raw&(1<<32-1)
+			default:
+				value = (raw>>43&0xFF)<<56 | raw&(1<<43-1)
+				if pointerFormat != chainedPtrArm64e {
+					value += base
+				}
+			}
+		default:
+			return fixups
+		}
+		fixups = append(fixups, chainedFixup{VA: pageVA + offset, value: value})
+		if next == 0 {
+			break
+		}
+		offset += next
+	}
+	return fixups
+}
+
+// applyFixups is data read at VA with the chain entries in it replaced by the pointers they encode, data itself when it holds none
+func (f *machoFile) applyFixups(data []byte, VA uint64) []byte {
+	fixups := f.fixups
+	i := sort.Search(len(fixups), func(i int) bool { return fixups[i].VA+8 > VA })
+	if i == len(fixups) || fixups[i].VA >= VA+uint64(len(data)) {
+		return data
+	}
+
+	fixed := make([]byte, len(data))
+	copy(fixed, data)
+	pointer := make([]byte, 8)
+	for ; i < len(fixups) && fixups[i].VA < VA+uint64(len(data)); i++ {
+		f.macho.ByteOrder.PutUint64(pointer, fixups[i].value)
+		if fixups[i].VA < VA {
+			copy(fixed, pointer[VA-fixups[i].VA:])
+		} else {
+			copy(fixed[fixups[i].VA-VA:], pointer)
+		}
+	}
+	return fixed
+}
@@ -0,0 +1,77 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// FunctionHash identifies the code of a function across builds. SHA256 is of its bytes as linked, PositionIndependent of them with
+// the pc relative references out of the function zeroed, so the same code linked at other addresses hashes the same.
+type FunctionHash struct {
+	SHA256              string
+	PositionIndependent string // amd64 only
+}
+
+// maskPCRelative_amd64 zeroes the displacement of every instruction of code addressing outside of it relative to the pc, ex: CALL
+// fmt.Sprint(SB) or MOVQ main.table(SB), AX, the branches within the function are the same wherever it's linked and are kept
+func maskPCRelative_amd64(code []byte, entry uint64) []byte {
+	masked := append([]byte(nil), code...)
+	for off := 0; off < len(code); {
+		inst, err := x86asm.Decode(code[off:], 64)
+		if err != nil || inst.Len == 0 {
+			off++
+			continue
+		}
+		if inst.PCRel > 0 && inst.PCRelOff+inst.PCRel <= inst.Len {
+			field := code[off+inst.PCRelOff : off+inst.PCRelOff+inst.PCRel]
+			var disp int64
+			for i := len(field) - 1; i >= 0; i-- {
+				disp = disp<<8 | int64(field[i])
+			}
+			// sign extend the field
+			shift := 64 - 8*uint(len(field))
+			disp = disp << shift >> shift
+			target := int64(entry) + int64(off+inst.Len) + disp
+			if target < int64(entry) || target >= int64(entry)+int64(len(code)) {
+				for i := range field {
+					masked[off+inst.PCRelOff+i] = 0
+				}
+			}
+		}
+		off += inst.Len
+	}
+	return masked
+}
+
+var pcRelativeMaskers = map[string]func(code []byte, entry uint64) []byte{
+	"amd64": maskPCRelative_amd64,
+}
+
+// HashFunctions hashes the code of funcs, by entry. Functions shorter than minSize bytes are left out, the wrappers and stubs that
+// small are the same in every binary and would only collide. The position independent hash is only computed on amd64.
+func (e *Entry) HashFunctions(funcs []gosym.Func, minSize uint64) map[uint64]FunctionHash {
+	mask := pcRelativeMaskers[e.GOARCH()]
+	hashes := make(map[uint64]FunctionHash)
+	for _, fn := range funcs {
+		if fn.End <= fn.Entry || fn.End-fn.Entry < minSize {
+			continue
+		}
+		code, err := e.raw.read_memory(fn.Entry, fn.End-fn.Entry)
+		if err != nil || uint64(len(code)) != fn.End-fn.Entry {
+			continue
+		}
+		raw := sha256.Sum256(code)
+		hash := FunctionHash{SHA256: hex.EncodeToString(raw[:])}
+		if mask != nil {
+			independent := sha256.Sum256(mask(code, fn.Entry))
+			hash.PositionIndependent = hex.EncodeToString(independent[:])
+		}
+		hashes[fn.Entry] = hash
+	}
+	return hashes
+}
@@ -0,0 +1,179 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+)
+
+// the note of a core listing the files mapped into the process
+const ntFile = 0x46494c45
+
+// fileMapping is a file mapped into the dumped process, from the NT_FILE note of a core or the module list of a minidump
+type fileMapping struct {
+	start  uint64
+	end    uint64
+	offset uint64 // in the file, in bytes
+	path   string
+}
+
+// openCore opens an ELF core as a dump of the crashed process: its PT_LOAD segments at their VAs, named after the files they map.
+// The kernel doesn't dump most of a read only file mapping, ex: the text and rodata of the executable, those pages are read from the mapped
+// file when it's still at its path. Segments left without data are skipped, reads of them fail.
+func openCore(ef *elf.File) (rawFile, error) {
+	mappings := coreMappings(ef)
+	f := &dumpFile{format: "core", arch: (&elfFile{elf: ef}).goarch(), byteOrder: ef.ByteOrder, mappings: mappings}
+
+	found := false
+	for i, p := range ef.Progs {
+		if p.Type != elf.PT_LOAD {
+			continue
+		}
+		data := make([]byte, p.Filesz)
+		if _, err := p.ReadAt(data, 0); err != nil && p.Filesz > 0 {
+			continue
+		}
+
+		name := fmt.Sprintf("load%d", i)
+		for _, mapping := range mappings {
+			if p.Vaddr >= mapping.start && p.Vaddr < mapping.end {
+				name = mapping.path
+				if p.Filesz < p.Memsz {
+					data = mappedFileData(mapping, p.Vaddr, data, p.Memsz)
+				}
+				break
+			}
+		}
+		if len(data) == 0 {
+			continue
+		}
+		f.regions = append(f.regions, dumpRegion{name: name, addr: p.Vaddr, data: data, executable: p.Flags&elf.PF_X != 0})
+
+		if !found || p.Vaddr < f.base {
+			f.base = p.Vaddr
+		}
+		f.size += uint64(len(data))
+		found = true
+	}
+	if !found {
+		return nil, errors.New("the core has no dumped segments")
+	}
+	return f, nil
+}
+
+// mappedFileData fills a partly dumped segment at VA up to size bytes from the file the mapping maps, the dumped bytes win.
+// Without the file the dumped bytes are all there is.
+func mappedFileData(mapping fileMapping, VA uint64, dumped []byte, size uint64) []byte {
+	file, err := os.Open(mapping.path)
+	if err != nil {
+		return dumped
+	}
+	defer file.Close()
+
+	size = min(size, mapping.end-VA)
+	data := make([]byte, size)
+	n, err := file.ReadAt(data, int64(mapping.offset+VA-mapping.start))
+	if n == 0 && err != nil {
+		return dumped
+	}
+	data = data[:max(uint64(n), uint64(len(dumped)))]
+	copy(data, dumped)
+	return data
+}
+
+// coreMappings parses the NT_FILE note: a count and the page size, count start, end and file offset triples, then count NUL terminated paths.
+// Every field is a word of the core's class.
+func coreMappings(ef *elf.File) []fileMapping {
+	word := 8
+	if ef.Class == elf.ELFCLASS32 {
+		word = 4
+	}
+	readWord := func(b []byte) uint64 {
+		if word == 4 {
+			return uint64(ef.ByteOrder.Uint32(b))
+		}
+		return ef.ByteOrder.Uint64(b)
+	}
+
+	for _, p := range ef.Progs {
+		if p.Type != elf.PT_NOTE {
+			continue
+		}
+		notes := make([]byte, p.Filesz)
+		if _, err := p.ReadAt(notes, 0); err != nil {
+			continue
+		}
+
+		for len(notes) >= 12 {
+			namesz := uint64(ef.ByteOrder.Uint32(notes))
+			descsz := uint64(ef.ByteOrder.Uint32(notes[4:]))
+			noteType := ef.ByteOrder.Uint32(notes[8:])
+			descStart := 12 + (namesz+3)&^3
+			descEnd := descStart + descsz
+			if descEnd > uint64(len(notes)) {
+				break
+			}
+			desc := notes[descStart:descEnd]
+			notes = notes[min(uint64(len(notes)), (descEnd+3)&^3):]
+			if noteType != ntFile || len(desc) < 2*word {
+				continue
+			}
+
+			count := readWord(desc)
+			pageSize := readWord(desc[word:])
+			entries := desc[2*word:]
+			if count > uint64(len(entries))/uint64(3*word) {
+				continue
+			}
+			paths := bytes.Split(entries[count*uint64(3*word):], []byte{0})
+
+			var mappings []fileMapping
+			for i := uint64(0); i < count && i < uint64(len(paths)); i++ {
+				entry := entries[i*uint64(3*word):]
+				mappings = append(mappings, fileMapping{start: readWord(entry), end: readWord(entry[word:]), offset: readWord(entry[2*word:]) * pageSize, path: string(paths[i])})
+			}
+			return mappings
+		}
+	}
+	return nil
+}
+
+// DumpModule is one Go runtime found in a dump, a core holds one per Go executable or plugin
+type DumpModule struct {
+	Region       string // section, segment or mapped file of the moduledata, ex: /usr/bin/app
+	ModuleDataVA uint64
+}
+
+// dumpModules lists every moduledata the signature scan finds and validates, the parse only continues with the first
+func (f *dumpFile) dumpModules() []DumpModule {
+	validate := pcHeaderValidator(true, f.read_memory)
+	seen := make(map[uint64]bool)
+
+	var modules []DumpModule
+	for _, region := range f.regions {
+		if !region.executable {
+			continue
+		}
+		for _, match := range findModuleInitPCHeaderWorkers(f.ctx, region.data, region.addr, f.byteOrder, f.arch, f.signatures, 0, f.scanWorkers, nil, nil) {
+			if seen[match.moduleDataVA] || !validate(match) {
+				continue
+			}
+			seen[match.moduleDataVA] = true
+			modules = append(modules, DumpModule{Region: f.regionName(match.moduleDataVA), ModuleDataVA: match.moduleDataVA})
+		}
+	}
+	return modules
+}
+
+func (f *dumpFile) regionName(VA uint64) string {
+	for _, region := range f.regions {
+		if VA >= region.addr && VA-region.addr < uint64(len(region.data)) {
+			return region.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+)
+
+// fakeCore is a 64 bit core with a NT_FILE note mapping path at 0x400000, and a text segment there of memsz bytes of which only dumped is in the core
+func fakeCore(path string, dumped []byte, memsz uint64) []byte {
+	le := binary.LittleEndian
+	var desc bytes.Buffer
+	for _, word := range []uint64{1, 0x1000, 0x400000, 0x400000 + memsz, 1} {
+		binary.Write(&desc, le, word)
+	}
+	desc.WriteString(path + "\x00")
+	for desc.Len()%4 != 0 {
+		desc.WriteByte(0)
+	}
+
+	var note bytes.Buffer
+	binary.Write(&note, le, []uint32{5, uint32(desc.Len()), ntFile})
+	note.WriteString("CORE\x00\x00\x00\x00")
+	note.Write(desc.Bytes())
+
+	const phoff = 64
+	notesOff := uint64(phoff + 2*56)
+	dataOff := notesOff + uint64(note.Len())
+
+	var core bytes.Buffer
+	core.Write([]byte{0x7f, 'E', 'L', 'F', byte(elf.ELFCLASS64), byte(elf.ELFDATA2LSB), 1, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	binary.Write(&core, le, []uint16{uint16(elf.ET_CORE), uint16(elf.EM_X86_64)})
+	binary.Write(&core, le, uint32(1))
+	binary.Write(&core, le, []uint64{0, phoff, 0})
+	binary.Write(&core, le, uint32(0))
+	binary.Write(&core, le, []uint16{64, 56, 2, 64, 0, 0})
+	binary.Write(&core, le, []uint32{uint32(elf.PT_NOTE), 0})
+	binary.Write(&core, le, []uint64{notesOff, 0, 0, uint64(note.Len()), 0, 4})
+	binary.Write(&core, le, []uint32{uint32(elf.PT_LOAD), uint32(elf.PF_R | elf.PF_X)})
+	binary.Write(&core, le, []uint64{dataOff, 0x400000, 0, uint64(len(dumped)), memsz, 0x1000})
+	core.Write(note.Bytes())
+	core.Write(dumped)
+	return core.Bytes()
+}
+
+func TestOpenCore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app")
+	// the mapping starts a page into the file
+	mapped := bytes.Repeat([]byte{0xcc}, 0x1000+0x2000)
+	if err := os.WriteFile(path, mapped, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{"mapped file", path, 0x2000},
+		{"mapped file gone", path + ".gone", 0x10},
+	} {
+		ef, err := elf.NewFile(bytes.NewReader(fakeCore(c.path, bytes.Repeat([]byte{0x90}, 0x10), 0x2000)))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if mappings := coreMappings(ef); len(mappings) != 1 || mappings[0].path != c.path || mappings[0].offset != 0x1000 {
+			t.Fatalf("%s: unexpected mappings %+v", c.name, mappings)
+		}
+
+		raw, err := openCore(ef)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		f := raw.(*dumpFile)
+		if f.format != "core" || f.base != 0x400000 || f.size != uint64(c.expected) || f.goarch() != "amd64" {
+			t.Errorf("%s: unexpected dump %s 0x%x 0x%x %s", c.name, f.format, f.base, f.size, f.goarch())
+		}
+		if name := f.regionName(0x400008); name != c.path {
+			t.Errorf("%s: expected the region to be named %s, got %s", c.name, c.path, name)
+		}
+		// the dumped bytes win over the file
+		if data, err := f.read_memory(0x40000f, 2); c.expected > 0x10 && (err != nil || !bytes.Equal(data, []byte{0x90, 0xcc})) {
+			t.Errorf("%s: expected the dumped bytes then the file's, got %x %v", c.name, data, err)
+		}
+		if _, err := f.read_memory(0x400000+uint64(c.expected), 1); err == nil {
+			t.Errorf("%s: expected a read past the segment to fail", c.name)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// how a CustomSignature derives the moduledata VA from its match
+const (
+	decodeRelative   = "relative"   // signed 32 bit displacement at Offset, from the end of the instruction at InstructionLength, ex: x64 lea rip+disp
+	decodeAbsolute32 = "absolute32" // 32 bit pointer at Offset, ex: x86 lea disp32
+	decodeHiLo       = "hilo"       // 16 bit halves at Hi and Lo, ex: ppc64 lis/addi or mips lui/addiu
+)
+
+// CustomSignature is a moduledata signature added at runtime, for init sequences the built-in signatures miss
+type CustomSignature struct {
+	Name              string
+	Pattern           string // in the yara syntax of the built-in signatures
+	Goarch            string `json:",omitempty"` // only run on this GOARCH, empty for every file
+	ByteOrder         string `json:",omitempty"` // "little" (the default) or "big", files of the other byte order are skipped
+	Decode            string // relative, absolute32 or hilo
+	Offset            uint64 `json:",omitempty"` // relative and absolute32: offset of the 32 bit field in the match
+	InstructionLength uint64 `json:",omitempty"` // relative: offset of the end of the instruction in the match
+	Hi                uint64 `json:",omitempty"` // hilo: offset of the high half in the match
+	Lo                uint64 `json:",omitempty"` // hilo: offset of the low half in the match
+	LoSigned          bool   `json:",omitempty"` // hilo: the low half is sign extended, as addi and addiu do
+}
+
+type compiledCustomSignature struct {
+	CustomSignature
+	compiledRegex *RegexAndNeedle
+	byteOrder     binary.ByteOrder
+}
+
+func compileCustomSignature(sig CustomSignature) (compiledCustomSignature, error) {
+	compiled := compiledCustomSignature{CustomSignature: sig}
+	if len(sig.Name) == 0 {
+		return compiled, errors.New("missing name")
+	}
+
+	switch sig.ByteOrder {
+	case "", "little":
+		compiled.byteOrder = binary.LittleEndian
+	case "big":
+		compiled.byteOrder = binary.BigEndian
+	default:
+		return compiled, fmt.Errorf("unknown byte order %q, expected little or big", sig.ByteOrder)
+	}
+
+	switch sig.Decode {
+	case decodeRelative:
+		if sig.InstructionLength < sig.Offset+4 {
+			return compiled, fmt.Errorf("instruction length %d ends before the displacement at %d", sig.InstructionLength, sig.Offset)
+		}
+	case decodeAbsolute32, decodeHiLo:
+	default:
+		return compiled, fmt.Errorf("unknown decode %q, expected %s, %s or %s", sig.Decode, decodeRelative, decodeAbsolute32, decodeHiLo)
+	}
+
+	regexInfo, err := RegexpPatternFromYaraPattern(sig.Pattern)
+	if err != nil {
+		return compiled, fmt.Errorf("bad pattern %q: %w", sig.Pattern, err)
+	}
+	compiled.compiledRegex = regexInfo
+	return compiled, nil
+}
+
+// ParseSignatures reads a JSON array of CustomSignature and checks every entry
+func ParseSignatures(data []byte) ([]CustomSignature, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var sigs []CustomSignature
+	if err := decoder.Decode(&sigs); err != nil {
+		return nil, fmt.Errorf("failed to parse signatures: %w", err)
+	}
+
+	for i, sig := range sigs {
+		if _, err := compileCustomSignature(sig); err != nil {
+			return nil, fmt.Errorf("signature %d (%s): %w", i, sig.Name, err)
+		}
+	}
+	return sigs, nil
+}
+
+// SetSignatures sets the signatures scanned after the built-in ones, nil for none. Go objects have no signature scan and ignore them.
+func (e *Entry) SetSignatures(sigs []CustomSignature) error {
+	var compiled []compiledCustomSignature
+	for i, sig := range sigs {
+		c, err := compileCustomSignature(sig)
+		if err != nil {
+			return fmt.Errorf("signature %d (%s): %w", i, sig.Name, err)
+		}
+		compiled = append(compiled, c)
+	}
+	switch f := e.raw.(type) {
+	case *elfFile:
+		f.signatures = compiled
+	case *machoFile:
+		f.signatures = compiled
+	case *peFile:
+		f.signatures = compiled
+	case *dumpFile:
+		f.signatures = compiled
+	}
+	return nil
+}
+
+// decode resolves the moduledata VA of a match at sigPtr
+func (sig *compiledCustomSignature) decode(read sectionReader, sectionBase uint64, sigPtr uint64) (uint64, bool) {
+	switch sig.Decode {
+	case decodeRelative:
+		field, ok := read(sigPtr+sig.Offset, 4)
+		if !ok {
+			return 0, false
+		}
+		disp := int64(int32(sig.byteOrder.Uint32(field)))
+		return uint64(int64(sigPtr+sectionBase+sig.InstructionLength) + disp), true
+	case decodeAbsolute32:
+		field, ok := read(sigPtr+sig.Offset, 4)
+		if !ok {
+			return 0, false
+		}
+		return uint64(sig.byteOrder.Uint32(field)), true
+	case decodeHiLo:
+		hiBytes, hiOk := read(sigPtr+sig.Hi, 2)
+		loBytes, loOk := read(sigPtr+sig.Lo, 2)
+		if !hiOk || !loOk {
+			return 0, false
+		}
+		hi := uint32(sig.byteOrder.Uint16(hiBytes))
+		lo := uint32(sig.byteOrder.Uint16(loBytes))
+		if sig.LoSigned {
+			lo = uint32(int32(int16(lo)))
+		}
+		return uint64((hi << 16) + lo), true
+	}
+	return 0, false
+}
@@ -0,0 +1,87 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestParseSignatures(t *testing.T) {
+	good := `[
+		{"Name": "x64lea", "Pattern": "{ 48 8D 0? ?? ?? ?? ?? EB }", "Goarch": "amd64", "Decode": "relative", "Offset": 3, "InstructionLength": 7},
+		{"Name": "ppclis", "Pattern": "{ 3C ?? ?? ?? 38 }", "ByteOrder": "big", "Decode": "hilo", "Hi": 2, "Lo": 6, "LoSigned": true}
+	]`
+	sigs, err := ParseSignatures([]byte(good))
+	if err != nil {
+		t.Fatalf("good signatures errored: %s", err)
+	}
+	if len(sigs) != 2 || sigs[1].Name != "ppclis" || !sigs[1].LoSigned {
+		t.Errorf("unexpected signatures %+v", sigs)
+	}
+
+	cases := []struct {
+		name     string
+		json     string
+		expected string
+	}{
+		{"not json", `{`, "failed to parse signatures"},
+		{"unknown field", `[{"Name": "a", "Pattern": "{ 48 }", "Decode": "absolute32", "Ofset": 1}]`, "unknown field"},
+		{"no name", `[{"Pattern": "{ 48 }", "Decode": "absolute32"}]`, "signature 0 (): missing name"},
+		{"bad pattern", `[{"Name": "ok", "Pattern": "{ 48 }", "Decode": "absolute32"}, {"Name": "typo", "Pattern": "{ 48 ?8 }", "Decode": "absolute32"}]`, "signature 1 (typo): bad pattern"},
+		{"bad decode", `[{"Name": "a", "Pattern": "{ 48 }", "Decode": "rip"}]`, "unknown decode"},
+		{"bad byte order", `[{"Name": "a", "Pattern": "{ 48 }", "Decode": "absolute32", "ByteOrder": "middle"}]`, "unknown byte order"},
+		{"short instruction", `[{"Name": "a", "Pattern": "{ 48 }", "Decode": "relative", "Offset": 3, "InstructionLength": 4}]`, "ends before the displacement"},
+	}
+	for _, c := range cases {
+		if _, err := ParseSignatures([]byte(c.json)); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", c.name, c.expected, err)
+		}
+	}
+}
+
+func TestCustomSignatures(t *testing.T) {
+	sigs := []CustomSignature{
+		// the built-in x64 signature without the trailing mov, so it's only found by the custom one
+		{Name: "x64lea", Pattern: "{ (48|4C) 8D 0? ?? ?? ?? ?? EB ?? C3 }", Goarch: "amd64", Decode: decodeRelative, Offset: 3, InstructionLength: 7},
+		{Name: "ppclis", Pattern: "{ 3C ?? ?? ?? 38 ?? ?? ?? 60 }", ByteOrder: "big", Decode: decodeHiLo, Hi: 2, Lo: 6, LoSigned: true},
+		{Name: "abs", Pattern: "{ B8 ?? ?? ?? ?? CC }", Decode: decodeAbsolute32, Offset: 1},
+	}
+	var compiled []compiledCustomSignature
+	for _, sig := range sigs {
+		c, err := compileCustomSignature(sig)
+		if err != nil {
+			t.Fatalf("signature %s errored: %s", sig.Name, err)
+		}
+		compiled = append(compiled, c)
+	}
+
+	x64 := []byte{0x48, 0x8D, 0x0D, 0x00, 0x01, 0x00, 0x00, 0xEB, 0x0D, 0xC3}
+	ppc := []byte{0x3C, 0x80, 0x00, 0x2C, 0x38, 0x84, 0x80, 0x00, 0x60}
+	abs := []byte{0xB8, 0x78, 0x56, 0x34, 0x12, 0xCC}
+
+	cases := []struct {
+		name       string
+		data       []byte
+		imageOrder binary.ByteOrder
+		goarch     string
+		expected   []uint64
+	}{
+		{"relative", x64, binary.LittleEndian, "amd64", []uint64{0x401107}},
+		{"relative on another arch", x64, binary.LittleEndian, "arm64", nil},
+		{"hilo", ppc, binary.BigEndian, "", []uint64{0x2B8000}},
+		{"hilo on LE", ppc, binary.LittleEndian, "", nil},
+		{"absolute32", abs, binary.LittleEndian, "386", []uint64{0x12345678}},
+	}
+	for _, c := range cases {
+		var found []uint64
+		for _, match := range findModuleInitPCHeaderWorkers(nil, c.data, 0x401000, c.imageOrder, c.goarch, compiled, 0, 1, nil, nil) {
+			if match.signature != sigs[0].Name && match.signature != sigs[1].Name && match.signature != sigs[2].Name {
+				t.Errorf("%s: matched by %s", c.name, match.signature)
+			}
+			found = append(found, match.moduleDataVA)
+		}
+		if len(found) != len(c.expected) || (len(found) > 0 && found[0] != c.expected[0]) {
+			t.Errorf("%s: expected %x, got %x", c.name, c.expected, found)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+)
+
+// DebugLink describes the companion debug file named by .gnu_debuglink
+type DebugLink struct {
+	Name string // file name stored in the section
+	CRC  uint32 // crc32 of the companion file stored in the section
+	Path string // where the companion was found, empty if missing
+}
+
+// global debug directory, see the gdb documentation 'Debugging Information in Separate Files'
+var debugLinkGlobalDir = "/usr/lib/debug"
+
+// parseDebugLink decodes a .gnu_debuglink section: a NUL terminated file name, padding to a 4 byte boundary, then the crc32
+func parseDebugLink(data []byte, byteOrder binary.ByteOrder) (name string, crc uint32, err error) {
+	nul := bytes.IndexByte(data, 0)
+	if nul <= 0 {
+		return "", 0, fmt.Errorf("malformed .gnu_debuglink name")
+	}
+
+	crcOff := (nul + 4) &^ 3
+	if crcOff+4 > len(data) {
+		return "", 0, fmt.Errorf("malformed .gnu_debuglink crc")
+	}
+	return string(data[:nul]), byteOrder.Uint32(data[crcOff:]), nil
+}
+
+// debugLinkCandidates lists the locations gdb searches for the companion, in order
+func debugLinkCandidates(binaryPath string, name string) []string {
+	absPath, err := filepath.Abs(binaryPath)
+	if err != nil {
+		absPath = binaryPath
+	}
+	dir := filepath.Dir(absPath)
+
+	return []string{
+		filepath.Join(dir, name),
+		filepath.Join(dir, ".debug", name),
+		filepath.Join(debugLinkGlobalDir, dir, name),
+	}
+}
+
+// findDebugLinkFile returns the first candidate whose crc32 matches, a companion with the right name but wrong crc is for a different build
+func findDebugLinkFile(binaryPath string, name string, crc uint32) (string, error) {
+	absBinary, _ := filepath.Abs(binaryPath)
+	for _, candidate := range debugLinkCandidates(binaryPath, name) {
+		// the link can name the stripped binary itself when it lives in the same directory
+		if candidate == absBinary {
+			continue
+		}
+
+		data, err := os.ReadFile(candidate)
+		if err != nil {
+			continue
+		}
+
+		if crc32.ChecksumIEEE(data) == crc {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("companion debug file %s not found", name)
+}
+
+// DebugLink reads .gnu_debuglink and locates the companion debug file. Only ELF files carry this section.
+func (e *Entry) DebugLink(binaryPath string) (*DebugLink, error) {
+	f, ok := e.raw.(*elfFile)
+	if !ok {
+		return nil, fmt.Errorf("debug links are only supported for ELF")
+	}
+
+	sect := f.elf.Section(".gnu_debuglink")
+	if sect == nil {
+		return nil, fmt.Errorf("no .gnu_debuglink section")
+	}
+
+	data, err := sect.Data()
+	if err != nil {
+		return nil, err
+	}
+
+	name, crc, err := parseDebugLink(data, f.elf.ByteOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	link := &DebugLink{Name: name, CRC: crc}
+
+	// missing companion is normal, caller falls back to the pclntab only results
+	link.Path, _ = findDebugLinkFile(binaryPath, name, crc)
+	return link, nil
+}
+
+// Symbols opens the companion debug file and returns its symbol table
+func (link *DebugLink) Symbols() ([]Sym, error) {
+	if len(link.Path) == 0 {
+		return nil, fmt.Errorf("companion debug file %s not found", link.Name)
+	}
+
+	companion, err := Open(link.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer companion.Close()
+	return companion.Symbols()
+}
@@ -0,0 +1,56 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDebugLink(t *testing.T) {
+	// name is padded to a 4 byte boundary before the crc
+	data := append([]byte("hello.debug\x00"), 0x78, 0x56, 0x34, 0x12)
+	name, crc, err := parseDebugLink(data, binary.LittleEndian)
+	if err != nil || name != "hello.debug" || crc != 0x12345678 {
+		t.Errorf("got %q %x %v", name, crc, err)
+	}
+
+	data = append([]byte("a.dbg\x00\x00\x00"), 0x12, 0x34, 0x56, 0x78)
+	name, crc, err = parseDebugLink(data, binary.BigEndian)
+	if err != nil || name != "a.dbg" || crc != 0x12345678 {
+		t.Errorf("got %q %x %v", name, crc, err)
+	}
+
+	if _, _, err := parseDebugLink([]byte("truncated\x00"), binary.LittleEndian); err == nil {
+		t.Errorf("truncated section accepted")
+	}
+}
+
+func TestFindDebugLinkFile(t *testing.T) {
+	dir := t.TempDir()
+	binaryPath := filepath.Join(dir, "hello")
+	companion := []byte("companion contents")
+	crc := crc32.ChecksumIEEE(companion)
+
+	if err := os.Mkdir(filepath.Join(dir, ".debug"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// same name, wrong build, must be skipped in favor of the .debug subdirectory
+	if err := os.WriteFile(filepath.Join(dir, "hello.debug"), []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".debug", "hello.debug"), companion, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := findDebugLinkFile(binaryPath, "hello.debug", crc)
+	if err != nil || path != filepath.Join(dir, ".debug", "hello.debug") {
+		t.Errorf("got %q %v", path, err)
+	}
+
+	if _, err := findDebugLinkFile(binaryPath, "missing.debug", crc); err == nil {
+		t.Errorf("missing companion found")
+	}
+}
@@ -0,0 +1,243 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"sync"
+	"time"
+)
+
+// SectionDiagnostic is a section the pclntab and moduledata scans ran over
+type SectionDiagnostic struct {
+	Name       string
+	VA         uint64
+	Size       uint64
+	Executable bool
+	Scanned    uint64 // the bytes of it within the scan ranges, only with SetScanRanges
+}
+
+// SignatureDiagnostic counts the matches of one moduledata signature across every scanned section
+type SignatureDiagnostic struct {
+	Signature string
+	Hits      int // raw pattern matches, before decoding
+	Validated int // decoded matches whose moduledata points at a pcHeader
+}
+
+// MatchDiagnostic is one decoded signature match, ex: x64 at .text+0x4d80a resolving to the candidate moduledata 0x69d0c0
+type MatchDiagnostic struct {
+	Signature     string
+	Section       string // empty when the match came from a scan without a section
+	SectionOffset uint64
+	VA            uint64
+	ModuleDataVA  uint64
+	Validated     bool
+	Score         int // from rankSignatureMatches, the candidates are tried best first
+}
+
+// ScanDiagnostics explains a failed recovery. No hits at all points to an unsupported architecture,
+// hits that all fail validation to a packed or corrupted image.
+type ScanDiagnostics struct {
+	Sections   []SectionDiagnostic
+	Signatures []SignatureDiagnostic
+	Matches    []MatchDiagnostic
+}
+
+// signatures in the order scanModuleInitPCHeader runs them
+var diagnosticSignatures = []string{"x64", "x86", "arm64", "arm32", "arm32movw", "riscv64", "loong64", "s390x", "ppc64be", "ppc64le", "mips", "mipsle", "mips64", "mips64le"}
+
+// scanDiagnostics collects ScanDiagnostics while the pclntab scan goroutine runs. A nil *scanDiagnostics records nothing.
+type scanDiagnostics struct {
+	mu       sync.Mutex
+	result   ScanDiagnostics
+	validate matchValidator
+	log      Logger
+	matching time.Duration // spent matching the signature hits counts next
+	matched  bool          // whether that signature ran, the ones of other architectures and byte orders are skipped
+}
+
+func newScanDiagnostics(read_memory func(VA uint64, size uint64) ([]byte, error)) *scanDiagnostics {
+	d := &scanDiagnostics{validate: pcHeaderValidator(true, read_memory)}
+	d.reset()
+	return d
+}
+
+// reset drops the previous scan, the pclntab scan reruns when the text base had to be corrected
+func (d *scanDiagnostics) reset() {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.result = ScanDiagnostics{}
+	for _, name := range diagnosticSignatures {
+		d.result.Signatures = append(d.result.Signatures, SignatureDiagnostic{Signature: name})
+	}
+}
+
+func (d *scanDiagnostics) section(name string, va uint64, size uint64, executable bool, scanned uint64) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.result.Sections = append(d.result.Sections, SectionDiagnostic{Name: name, VA: va, Size: size, Executable: executable, Scanned: scanned})
+	if scanned > 0 {
+		d.log.Printf(1, "scanning %s at 0x%x, %d bytes, executable %v, %d bytes of it in the scan ranges", name, va, size, executable, scanned)
+		return
+	}
+	d.log.Printf(1, "scanning %s at 0x%x, %d bytes, executable %v", name, va, size, executable)
+}
+
+// lastSection is the name of the section scanned last, the matches are attributed to it
+func (d *scanDiagnostics) lastSection() string {
+	if len(d.result.Sections) == 0 {
+		return ""
+	}
+	return d.result.Sections[len(d.result.Sections)-1].Name
+}
+
+// timed times the matching of find, for the elapsed time hits logs
+func (d *scanDiagnostics) timed(find signatureFinder) signatureFinder {
+	if d == nil || d.log == nil {
+		return find
+	}
+	return func(regexInfo *RegexAndNeedle) ([][]int, error) {
+		start := time.Now()
+		matches, err := find(regexInfo)
+		d.mu.Lock()
+		d.matching += time.Since(start)
+		d.matched = true
+		d.mu.Unlock()
+		return matches, err
+	}
+}
+
+func (d *scanDiagnostics) signature(name string) *SignatureDiagnostic {
+	for i := range d.result.Signatures {
+		if d.result.Signatures[i].Signature == name {
+			return &d.result.Signatures[i]
+		}
+	}
+	d.result.Signatures = append(d.result.Signatures, SignatureDiagnostic{Signature: name})
+	return &d.result.Signatures[len(d.result.Signatures)-1]
+}
+
+func (d *scanDiagnostics) hits(name string, count int) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.signature(name).Hits += count
+	if d.matched {
+		level := 2
+		if count > 0 {
+			level = 1
+		}
+		d.log.Printf(level, "signature %s: %d matches in %s in %s", name, count, d.lastSection(), d.matching)
+	}
+	d.matching, d.matched = 0, false
+}
+
+// decoded validates a decoded match for the diagnostics only, it doesn't filter the results. The match is attributed to the last section added.
+func (d *scanDiagnostics) decoded(match SignatureMatch) {
+	if d == nil {
+		return
+	}
+	validated := d.validate(match)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	section := d.lastSection()
+	d.log.Printf(2, "%s match at %s+0x%x: moduledata candidate 0x%x, points at a pcHeader %v", match.signature, section, match.matchOffset, match.moduleDataVA, validated)
+	d.result.Matches = append(d.result.Matches, MatchDiagnostic{
+		Signature:     match.signature,
+		Section:       section,
+		SectionOffset: match.matchOffset,
+		VA:            match.matchVA,
+		ModuleDataVA:  match.moduleDataVA,
+		Validated:     validated,
+	})
+	if validated {
+		d.signature(match.signature).Validated++
+	}
+}
+
+func (d *scanDiagnostics) scored(match SignatureMatch, score int) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := len(d.result.Matches) - 1; i >= 0; i-- {
+		m := &d.result.Matches[i]
+		if m.Signature == match.signature && m.VA == match.matchVA && m.ModuleDataVA == match.moduleDataVA {
+			m.Score = score
+			d.log.Printf(2, "moduledata candidate 0x%x of the %s match at 0x%x scored %d", match.moduleDataVA, match.signature, match.matchVA, score)
+			return
+		}
+	}
+}
+
+func (d *scanDiagnostics) snapshot() *ScanDiagnostics {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	result := ScanDiagnostics{
+		Sections:   append([]SectionDiagnostic{}, d.result.Sections...),
+		Signatures: append([]SignatureDiagnostic{}, d.result.Signatures...),
+		Matches:    append([]MatchDiagnostic{}, d.result.Matches...),
+	}
+	return &result
+}
+
+// SetDiagnostics turns on collecting ScanDiagnostics during PCLineTable. The signature matches are validated for the counts, which costs a few reads per match.
+// Go objects have no signature scan and ignore this.
+func (e *Entry) SetDiagnostics(enabled bool) {
+	var diag *scanDiagnostics
+	if enabled {
+		diag = newScanDiagnostics(e.raw.read_memory)
+		diag.log = e.log
+	}
+
+	switch f := e.raw.(type) {
+	case *elfFile:
+		f.diagnostics = diag
+	case *machoFile:
+		f.diagnostics = diag
+	case *peFile:
+		f.diagnostics = diag
+	case *dumpFile:
+		f.diagnostics = diag
+	case *wasmFile:
+		f.diagnostics = diag
+	}
+}
+
+// Diagnostics returns what the last PCLineTable scan saw, or nil when diagnostics are off
+func (e *Entry) Diagnostics() *ScanDiagnostics {
+	return e.scanDiagnostics().snapshot()
+}
+
+// scanDiagnostics is the collector SetDiagnostics handed the raw file, nil when diagnostics are off
+func (e *Entry) scanDiagnostics() *scanDiagnostics {
+	switch f := e.raw.(type) {
+	case *elfFile:
+		return f.diagnostics
+	case *machoFile:
+		return f.diagnostics
+	case *peFile:
+		return f.diagnostics
+	case *dumpFile:
+		return f.diagnostics
+	case *wasmFile:
+		return f.diagnostics
+	}
+	return nil
+}
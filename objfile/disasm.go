@@ -398,13 +398,20 @@ var disasms = map[string]disasmFunc{
 }
 
 var byteOrders = map[string]binary.ByteOrder{
-	"386":     binary.LittleEndian,
-	"amd64":   binary.LittleEndian,
-	"arm":     binary.LittleEndian,
-	"arm64":   binary.LittleEndian,
-	"ppc64":   binary.BigEndian,
-	"ppc64le": binary.LittleEndian,
-	"s390x":   binary.BigEndian,
+	"386":      binary.LittleEndian,
+	"amd64":    binary.LittleEndian,
+	"arm":      binary.LittleEndian,
+	"arm64":    binary.LittleEndian,
+	"ppc64":    binary.BigEndian,
+	"ppc64le":  binary.LittleEndian,
+	"s390x":    binary.BigEndian,
+	"mips":     binary.BigEndian,
+	"mipsle":   binary.LittleEndian,
+	"mips64":   binary.BigEndian,
+	"mips64le": binary.LittleEndian,
+	"riscv64":  binary.LittleEndian,
+	"loong64":  binary.LittleEndian,
+	"wasm":     binary.LittleEndian,
 }
 
 type Liner interface {
@@ -0,0 +1,437 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mandiant/GoReSym/debug/dwarf"
+	"github.com/mandiant/GoReSym/debug/elf"
+	"github.com/mandiant/GoReSym/debug/pe"
+)
+
+// DumpInfo describes an input opened in dump mode. The text range and sanity checks of the parse use the regions, for a raw dump the whole input.
+type DumpInfo struct {
+	Base   uint64
+	Size   uint64
+	Format string // headers found at the start of the dump: pe, elf or raw for none, core for an ELF core, or minidump
+	Region string // section, segment or mapped file holding the parsed moduledata
+	// moduledata pointers outside the dump, ex: Types. What they point at wasn't recovered.
+	Unresolved []string
+	// every Go runtime in the dump, the parse only covers the first
+	Modules []DumpModule
+	// ranges of the file mapped at Region that weren't dumped, symbols in them have no data
+	Gaps []DumpGap
+}
+
+// DumpGap is a range of a mapped file missing from the dump, End is exclusive
+type DumpGap struct {
+	Start uint64
+	End   uint64
+}
+
+// dumpRegion is a range of the dump at its in-memory address, a section or segment of the mapped headers or the whole dump
+type dumpRegion struct {
+	name       string
+	addr       uint64
+	data       []byte
+	executable bool
+}
+
+type dumpFile struct {
+	base           uint64
+	size           uint64
+	format         string
+	arch           string
+	byteOrder      binary.ByteOrder
+	regions        []dumpRegion
+	mappings       []fileMapping             // files mapped into the process, for cores and minidumps
+	firstMatchOnly bool                      // stop the moduledata signature scan at the first validated match
+	scanWorkers    int                       // goroutines per region for the signature scan, 0 for GOMAXPROCS
+	diagnostics    *scanDiagnostics          // nil unless SetDiagnostics
+	ctx            context.Context           // nil unless SetContext
+	scanRanges     []ScanRange               // nil unless SetScanRanges
+	signatures     []compiledCustomSignature // nil unless SetSignatures
+	slide          uint64                    // the load bias of a position independent ELF image, added to the default base
+}
+
+// GOARCHes whose dumps are big endian, the rest are little endian
+var bigEndianGoarch = map[string]bool{"mips": true, "mips64": true, "ppc64": true, "s390x": true}
+
+func openDump(r io.ReaderAt, base uint64, slide uint64, goarch string, parseHeaders bool) (rawFile, error) {
+	// a mapped dump is parsed in place
+	data, ok := []byte(nil), false
+	if v, isViewer := r.(viewer); isViewer {
+		data, ok = v.View(0, v.Size())
+	}
+	if !ok {
+		var err error
+		if data, err = io.ReadAll(io.NewSectionReader(r, 0, 1<<62)); err != nil {
+			return nil, err
+		}
+	}
+
+	f := &dumpFile{base: base, size: uint64(len(data)), format: "raw", arch: goarch, slide: slide}
+	// without parseHeaders whatever is at the start is left alone, stomped headers can't be trusted
+	switch headers := mappedHeaders(data); {
+	case parseHeaders && bytes.HasPrefix(data, []byte("MZ")):
+		if pef, err := pe.NewFile(bytes.NewReader(headers)); err == nil {
+			f.mapPE(data, pef)
+		}
+	case parseHeaders && bytes.HasPrefix(data, []byte(elf.ELFMAG)):
+		if elff, err := elf.NewFile(bytes.NewReader(headers)); err == nil {
+			f.mapELF(data, elff)
+		}
+	}
+
+	if f.format == "raw" {
+		if f.base == 0 {
+			return nil, errors.New("a dump without headers needs a base address")
+		}
+		if len(f.arch) == 0 {
+			return nil, errors.New("a dump without headers needs a GOARCH")
+		}
+		f.regions = []dumpRegion{{name: "dump", addr: f.base, data: data, executable: true}}
+	}
+	if len(f.regions) == 0 {
+		return nil, errors.New("the dump headers map nothing")
+	}
+
+	f.byteOrder = binary.LittleEndian
+	if bigEndianGoarch[f.arch] {
+		f.byteOrder = binary.BigEndian
+	}
+	return f, nil
+}
+
+// mappedHeaders copies the headers at the start of a dump without the tables only the file has, the loader doesn't map the PE COFF symbols or the ELF
+// section headers, so their file offsets point at unrelated memory
+func mappedHeaders(data []byte) []byte {
+	headers := append([]byte{}, data[:min(len(data), 0x1000)]...)
+	switch {
+	case bytes.HasPrefix(headers, []byte("MZ")) && len(headers) >= 0x40:
+		// PointerToSymbolTable and NumberOfSymbols of the COFF header, after the PE signature
+		coff := int(binary.LittleEndian.Uint32(headers[0x3c:])) + 4
+		if coff <= 4 || coff+20 > len(headers) {
+			break
+		}
+		clear(headers[coff+8 : coff+16])
+
+		// long section names, ex: /19, are string table offsets. Keep the offset as the name, ex: #19.
+		sections := coff + 20 + int(binary.LittleEndian.Uint16(headers[coff+16:]))
+		for i := 0; i < int(binary.LittleEndian.Uint16(headers[coff+2:])) && sections+40*(i+1) <= len(headers); i++ {
+			if name := headers[sections+40*i:]; name[0] == '/' {
+				name[0] = '#'
+			}
+		}
+	case bytes.HasPrefix(headers, []byte(elf.ELFMAG)) && len(headers) >= 0x40:
+		// e_shoff, and e_shnum and e_shstrndx
+		if elf.Class(headers[elf.EI_CLASS]) == elf.ELFCLASS64 {
+			clear(headers[0x28:0x30])
+			clear(headers[0x3c:0x40])
+		} else {
+			clear(headers[0x20:0x24])
+			clear(headers[0x30:0x34])
+		}
+	}
+	return headers
+}
+
+// mapPE lays out the sections of mapped PE headers at their RVAs. The loader writes the base it picked into the ImageBase, so that's the default base.
+func (f *dumpFile) mapPE(data []byte, pef *pe.File) {
+	f.format = "pe"
+	if len(f.arch) == 0 {
+		f.arch = (&peFile{pe: pef}).goarch()
+	}
+	if f.base == 0 {
+		switch oh := pef.OptionalHeader.(type) {
+		case *pe.OptionalHeader32:
+			f.base = uint64(oh.ImageBase)
+		case *pe.OptionalHeader64:
+			f.base = oh.ImageBase
+		}
+	}
+
+	const memExecute = 0x20000000
+	for _, sec := range pef.Sections {
+		size := uint64(sec.VirtualSize)
+		if size == 0 {
+			size = uint64(sec.Size)
+		}
+		if region, ok := f.region(data, sec.Name, uint64(sec.VirtualAddress), size, sec.Characteristics&memExecute != 0); ok {
+			f.regions = append(f.regions, region)
+		}
+	}
+}
+
+// mapELF lays out the PT_LOAD segments of mapped ELF headers relative to the first one, which is the default base. A position independent
+// image defaults to that base slid by f.slide, or by the slide of its relocated pointers when that is 0.
+func (f *dumpFile) mapELF(data []byte, elff *elf.File) {
+	f.format = "elf"
+	if len(f.arch) == 0 {
+		f.arch = (&elfFile{elf: elff}).goarch()
+	}
+
+	var first uint64
+	found := false
+	for _, p := range elff.Progs {
+		if p.Type != elf.PT_LOAD {
+			continue
+		}
+		start := p.Vaddr
+		if p.Align > 1 {
+			start -= start % p.Align
+		}
+		if !found || start < first {
+			first = start
+		}
+		found = true
+	}
+	defaultBase := f.base == 0
+	if defaultBase {
+		f.base = first
+	}
+
+	for i, p := range elff.Progs {
+		if p.Type != elf.PT_LOAD {
+			continue
+		}
+		if region, ok := f.region(data, fmt.Sprintf("load%d", i), p.Vaddr-first, p.Memsz, p.Flags&elf.PF_X != 0); ok {
+			f.regions = append(f.regions, region)
+		}
+	}
+
+	// a position independent image was mapped wherever the loader picked, the slide of its pointers from its headers tells where
+	if defaultBase && elff.Type == elf.ET_DYN {
+		slide := f.slide
+		if slide == 0 {
+			slide, _ = detectSlide(f.read_memory, elff)
+		}
+		f.slide = slide
+		f.base += slide
+		for i := range f.regions {
+			f.regions[i].addr += slide
+		}
+	}
+}
+
+// region is the size bytes at offset of the dump, cut short at its end. Pages that weren't dumped are simply missing.
+func (f *dumpFile) region(data []byte, name string, offset uint64, size uint64, executable bool) (dumpRegion, bool) {
+	if offset >= uint64(len(data)) || size == 0 {
+		return dumpRegion{}, false
+	}
+	if size > uint64(len(data))-offset {
+		size = uint64(len(data)) - offset
+	}
+	return dumpRegion{name: name, addr: f.base + offset, data: data[offset : offset+size], executable: executable}, true
+}
+
+func (f *dumpFile) read_memory(VA uint64, size uint64) (data []byte, err error) {
+	for _, region := range f.regions {
+		if VA >= region.addr && VA-region.addr < uint64(len(region.data)) {
+			data = region.data[VA-region.addr:]
+			if uint64(len(data)) > size {
+				data = data[:size]
+			}
+			return append([]byte{}, data...), nil
+		}
+	}
+	return nil, fmt.Errorf("0x%x is outside the dump", VA)
+}
+
+func (f *dumpFile) symbols() ([]Sym, error) {
+	return nil, errors.New("dumps have no symbol table")
+}
+
+func (f *dumpFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
+	var magics [][]byte
+	for _, magic := range []uint32{0xfffffff1, 0xfffffff0, 0xfffffffa, 0xfffffffb} {
+		sig := make([]byte, 6)
+		f.byteOrder.PutUint32(sig, magic)
+		magics = append(magics, sig)
+	}
+
+	ptrSize := uint64(8)
+	if layout, ok := goarchPCHeader[f.arch]; ok {
+		ptrSize = uint64(layout.ptrSize)
+	}
+
+	ch_tab := make(chan PclntabCandidate)
+
+	// a stomped magic is retried as every magic, like the file scans do
+	send_candidates := func(region dumpRegion, idx int, meta *StompMagicCandidate) {
+		for _, magic := range magics {
+			var candidate PclntabCandidate
+			candidate.StompMagicCandidateMeta = meta
+			candidate.setMagic(region.data[idx:], magic)
+			candidate.SecStart = region.addr
+			candidate.Location = region.name
+			candidate.PclntabVA = region.addr + uint64(idx)
+			ch_tab <- candidate
+		}
+	}
+
+	f.diagnostics.reset()
+	go func() {
+		defer close(ch_tab)
+
+		for _, region := range f.regions {
+			windows := scanWindows(f.scanRanges, region.addr, true, 0, false, uint64(len(region.data)), len(region.data))
+			if len(windows) == 0 {
+				continue
+			}
+			f.diagnostics.section(region.name, region.addr, uint64(len(region.data)), region.executable, scannedBytes(f.scanRanges, windows))
+
+			for _, pclntab_idx := range findWindowOccurrences(region.data, windows, magics) {
+				send_candidates(region, pclntab_idx, nil)
+			}
+
+			if !region.executable {
+				continue
+			}
+			var sigResults []SignatureMatch
+			for _, w := range windows {
+				sigResults = append(sigResults, findModuleInitPCHeaderWorkers(f.ctx, region.data[w.start:w.end], region.addr+uint64(w.start), f.byteOrder, f.arch, f.signatures, 0, f.scanWorkers, pcHeaderValidator(f.firstMatchOnly, f.read_memory), f.diagnostics)...)
+			}
+			sigResults = rankSignatureMatches(sigResults, f.read_memory, f.diagnostics)
+			for _, sigResult := range sigResults {
+				// the moduledata starts with the pclntab pointer, the GOARCH gives its size and byte order
+				raw, err := f.read_memory(sigResult.moduleDataVA, ptrSize)
+				if err != nil || uint64(len(raw)) < ptrSize {
+					continue
+				}
+				pclntabVA := uint64(f.byteOrder.Uint32(raw))
+				if ptrSize == 8 {
+					pclntabVA = f.byteOrder.Uint64(raw)
+				}
+
+				meta := &StompMagicCandidate{pclntabVA, sigResult.moduleDataVA, f.byteOrder == binary.LittleEndian}
+				for _, target := range f.regions {
+					if pclntabVA >= target.addr && pclntabVA-target.addr < uint64(len(target.data)) {
+						send_candidates(target, int(pclntabVA-target.addr), meta)
+					}
+				}
+			}
+		}
+	}()
+	return ch_tab, nil
+}
+
+func (f *dumpFile) pcln() (candidates <-chan PclntabCandidate, err error) {
+	return f.pcln_scan()
+}
+
+func (f *dumpFile) moduledata_scan(pclntabVA uint64, is64bit bool, littleendian bool, ignorelist []uint64) (candidate *ModuleDataCandidate, err error) {
+	for _, region := range f.regions {
+		moduledata_idx := findModuleDataPointer(region.data, len(region.data), region.addr, pclntabVA, is64bit, littleendian, ignorelist)
+		if moduledata_idx != -1 {
+			return &ModuleDataCandidate{SecStart: region.addr, ModuledataVA: region.addr + uint64(moduledata_idx), Moduledata: region.data[moduledata_idx:]}, nil
+		}
+	}
+	return nil, fmt.Errorf("moduledata containing region could not be located")
+}
+
+func (f *dumpFile) text() (textStart uint64, text []byte, err error) {
+	for _, region := range f.regions {
+		if region.executable {
+			return region.addr, region.data, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("text region not found")
+}
+
+// endian is the one of the architecture the dump was opened as
+func (f *dumpFile) endian() binary.ByteOrder {
+	return f.byteOrder
+}
+
+func (f *dumpFile) goarch() string {
+	return f.arch
+}
+
+func (f *dumpFile) loadAddress() (uint64, error) {
+	return f.base, nil
+}
+
+func (f *dumpFile) dwarf() (*dwarf.Data, error) {
+	return nil, errors.New("dumps have no DWARF")
+}
+
+// Dump describes the input when it was opened in dump mode, else nil. moduleData, when found, is checked for pointers outside the dump.
+func (e *Entry) Dump(moduleData *ModuleData) *DumpInfo {
+	f, ok := e.raw.(*dumpFile)
+	if !ok {
+		return nil
+	}
+
+	info := &DumpInfo{Base: f.base, Size: f.size, Format: f.format, Modules: f.dumpModules()}
+	if moduleData == nil {
+		return info
+	}
+	info.Region = f.regionName(moduleData.VA)
+	info.Gaps = f.gaps(info.Region)
+	for _, pointer := range []struct {
+		name string
+		VA   uint64
+	}{
+		{"TextVA", moduleData.TextVA},
+		{"Types", moduleData.Types},
+		{"Typelinks", uint64(moduleData.Typelinks.Data)},
+		{"ITablinks", uint64(moduleData.ITablinks.Data)},
+		{"Noptrdata", moduleData.Noptrdata},
+		{"Data", moduleData.Data},
+	} {
+		if pointer.VA == 0 {
+			continue
+		}
+		if _, err := f.read_memory(pointer.VA, 1); err != nil {
+			info.Unresolved = append(info.Unresolved, pointer.name)
+		}
+	}
+	return info
+}
+
+// gaps lists the ranges of the mappings of path that no region covers
+func (f *dumpFile) gaps(path string) []DumpGap {
+	var gaps []DumpGap
+	for _, mapping := range f.mappings {
+		if mapping.path != path {
+			continue
+		}
+
+		for next := mapping.start; next < mapping.end; {
+			covered := false
+			gapEnd := mapping.end
+			for _, region := range f.regions {
+				regionEnd := region.addr + uint64(len(region.data))
+				if next >= region.addr && next < regionEnd {
+					next = regionEnd
+					covered = true
+					break
+				}
+				if region.addr > next && region.addr < gapEnd {
+					gapEnd = region.addr
+				}
+			}
+			if !covered {
+				gaps = append(gaps, DumpGap{Start: next, End: gapEnd})
+				next = gapEnd
+			}
+		}
+	}
+	return gaps
+}
+
+// Mapped reports whether VA is in the dump, ex: a function whose entry isn't has no code to disassemble. Always true outside dump mode.
+func (e *Entry) Mapped(VA uint64) bool {
+	f, ok := e.raw.(*dumpFile)
+	if !ok {
+		return true
+	}
+	_, err := f.read_memory(VA, 1)
+	return err == nil
+}
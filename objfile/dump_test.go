@@ -0,0 +1,84 @@
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+)
+
+func TestOpenDump(t *testing.T) {
+	raw := make([]byte, 0x100)
+	for _, c := range []struct {
+		name     string
+		base     uint64
+		goarch   string
+		expected string
+	}{
+		{"no base", 0, "amd64", "needs a base address"},
+		{"no goarch", 0x400000, "", "needs a GOARCH"},
+	} {
+		if _, err := openDump(bytes.NewReader(raw), c.base, 0, c.goarch, true); err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", c.name, c.expected, err)
+		}
+	}
+
+	binary.BigEndian.PutUint32(raw[0x10:], 0xdeadbeef)
+	opened, err := openDump(bytes.NewReader(raw), 0x400000, 0, "mips", true)
+	if err != nil {
+		t.Fatalf("raw dump errored: %s", err)
+	}
+	f := opened.(*dumpFile)
+	if f.format != "raw" || f.byteOrder != binary.BigEndian || len(f.regions) != 1 || !f.regions[0].executable {
+		t.Errorf("unexpected layout %+v", f)
+	}
+
+	if data, err := f.read_memory(0x400010, 4); err != nil || binary.BigEndian.Uint32(data) != 0xdeadbeef {
+		t.Errorf("expected 0xdeadbeef, got %x %v", data, err)
+	}
+	if data, err := f.read_memory(0x4000fe, 4); err != nil || len(data) != 2 {
+		t.Errorf("expected the read cut at the end of the dump, got %x %v", data, err)
+	}
+	if _, err := f.read_memory(0x500000, 4); err == nil || !strings.Contains(err.Error(), "outside the dump") {
+		t.Errorf("expected a read outside the dump to fail, got %v", err)
+	}
+
+	info := (&Entry{raw: f}).Dump(&ModuleData{TextVA: 0x400000, Types: 0x400080, Data: 0x600000})
+	if info.Base != 0x400000 || info.Size != 0x100 || len(info.Unresolved) != 1 || info.Unresolved[0] != "Data" {
+		t.Errorf("unexpected dump info %+v", info)
+	}
+	if (&Entry{raw: &elfFile{}}).Dump(nil) != nil {
+		t.Errorf("files aren't dumps")
+	}
+	if e := (&Entry{raw: f}); !e.Mapped(0x4000ff) || e.Mapped(0x400100) || !(&Entry{raw: &elfFile{}}).Mapped(0x400100) {
+		t.Errorf("expected only VAs past the dump to be unmapped")
+	}
+
+	// stomped headers are skipped, the dump stays one region at the base
+	copy(raw, "MZ")
+	opened, err = openDump(bytes.NewReader(raw), 0x400000, 0, "amd64", false)
+	if err != nil {
+		t.Fatalf("raw dump with a header errored: %s", err)
+	}
+	if f := opened.(*dumpFile); f.format != "raw" || len(f.regions) != 1 || f.regions[0].addr != 0x400000 {
+		t.Errorf("expected the headers to be ignored, got %+v", f)
+	}
+}
+
+func TestMappedHeaders(t *testing.T) {
+	header := make([]byte, 0x40)
+	copy(header, elf.ELFMAG)
+	header[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	binary.LittleEndian.PutUint64(header[0x28:], 0x123456) // e_shoff
+	binary.LittleEndian.PutUint16(header[0x3c:], 30)       // e_shnum
+
+	stripped := mappedHeaders(header)
+	if binary.LittleEndian.Uint64(stripped[0x28:]) != 0 || binary.LittleEndian.Uint16(stripped[0x3c:]) != 0 {
+		t.Errorf("section headers kept: %x", stripped)
+	}
+	if binary.LittleEndian.Uint64(header[0x28:]) != 0x123456 {
+		t.Errorf("the dump itself was modified")
+	}
+}
@@ -0,0 +1,314 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/mandiant/GoReSym/debug/dwarf"
+	"github.com/mandiant/GoReSym/debug/elf"
+	"github.com/mandiant/GoReSym/debug/gosym"
+)
+
+// DwarfFunc is a function to describe in generated DWARF, Rows are its source lines from the pcfile and pcln tables
+type DwarfFunc struct {
+	Name  string
+	Entry uint64
+	End   uint64
+	Rows  []gosym.LineRow
+}
+
+// the abbreviation codes of the generated DIEs
+const (
+	abbrevCompileUnit = 1
+	abbrevSubprogram  = 2
+)
+
+// the line program opcodes, DWARF 4 section 6.2.5
+const (
+	lineBase   = -5
+	lineRange  = 14
+	opcodeBase = 13
+
+	dwLnsCopy        = 1
+	dwLnsAdvancePc   = 2
+	dwLnsAdvanceLine = 3
+	dwLnsSetFile     = 4
+	dwLneEndSequence = 1
+	dwLneSetAddress  = 2
+
+	dwLangGo = 0x16
+)
+
+// the attribute forms of the generated DIEs, debug/dwarf only decodes them
+const (
+	dwFormAddr        = 0x01
+	dwFormData2       = 0x05
+	dwFormData4       = 0x06
+	dwFormData8       = 0x07
+	dwFormStrp        = 0x0e
+	dwFormUdata       = 0x0f
+	dwFormSecOffset   = 0x17
+	dwFormFlagPresent = 0x19
+)
+
+// dwarfWriter appends the values of one section in the byte order and address size of the ELF
+type dwarfWriter struct {
+	bytes.Buffer
+	order   binary.ByteOrder
+	ptrSize int
+}
+
+func (w *dwarfWriter) u8(v uint8) { w.WriteByte(v) }
+
+func (w *dwarfWriter) u16(v uint16) {
+	b := make([]byte, 2)
+	w.order.PutUint16(b, v)
+	w.Write(b)
+}
+
+func (w *dwarfWriter) u32(v uint32) {
+	b := make([]byte, 4)
+	w.order.PutUint32(b, v)
+	w.Write(b)
+}
+
+// addr writes an address, or a length the size of one like the DW_AT_high_pc of the generated DIEs
+func (w *dwarfWriter) addr(v uint64) {
+	if w.ptrSize == 8 {
+		b := make([]byte, 8)
+		w.order.PutUint64(b, v)
+		w.Write(b)
+	} else {
+		w.u32(uint32(v))
+	}
+}
+
+func (w *dwarfWriter) uleb(v uint64) {
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			b |= 0x80
+		}
+		w.WriteByte(b)
+		if v == 0 {
+			return
+		}
+	}
+}
+
+func (w *dwarfWriter) sleb(v int64) {
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			w.WriteByte(b)
+			return
+		}
+		w.WriteByte(b | 0x80)
+	}
+}
+
+// patchLength writes the 32 bit unit length at offset, the bytes after it to the end
+func (w *dwarfWriter) patchLength(offset int) {
+	w.order.PutUint32(w.Bytes()[offset:], uint32(w.Len()-offset-4))
+}
+
+// dwarfStrings is .debug_str, each string once
+type dwarfStrings struct {
+	data    []byte
+	offsets map[string]uint32
+}
+
+func (s *dwarfStrings) offset(str string) uint32 {
+	if offset, ok := s.offsets[str]; ok {
+		return offset
+	}
+	offset := uint32(len(s.data))
+	s.offsets[str] = offset
+	s.data = append(append(s.data, str...), 0)
+	return offset
+}
+
+// ElfDwarf generates the .debug_abbrev, .debug_info, .debug_line and .debug_str sections describing funcs for the ELF in data: one
+// compile unit with a DW_TAG_subprogram per function, and a line program sequence per function with a row wherever its file or line
+// changes. Functions are given in address order and don't overlap. Only the functions and statement lines are described, no types
+// or variables.
+func ElfDwarf(data []byte, funcs []DwarfFunc) ([]ElfSection, error) {
+	if !bytes.HasPrefix(data, []byte(elf.ELFMAG)) || len(data) <= elf.EI_DATA {
+		return nil, fmt.Errorf("not an ELF, only ELF files are patched")
+	}
+	if len(funcs) == 0 {
+		return nil, fmt.Errorf("no functions to describe")
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if elf.Data(data[elf.EI_DATA]) == elf.ELFDATA2MSB {
+		order = binary.BigEndian
+	}
+	ptrSize := 8
+	if elf.Class(data[elf.EI_CLASS]) == elf.ELFCLASS32 {
+		ptrSize = 4
+	}
+
+	// the file numbers start at 1, in the order the rows use them
+	var fileNames []string
+	files := make(map[string]uint64)
+	for _, fn := range funcs {
+		for _, row := range fn.Rows {
+			if _, ok := files[row.File]; !ok && len(row.File) > 0 {
+				fileNames = append(fileNames, row.File)
+				files[row.File] = uint64(len(fileNames))
+			}
+		}
+	}
+	low, high := funcs[0].Entry, funcs[len(funcs)-1].End
+
+	abbrev := dwarfWriter{order: order, ptrSize: ptrSize}
+	highPcForm := uint64(dwFormData8)
+	if ptrSize == 4 {
+		highPcForm = dwFormData4
+	}
+	for _, entry := range []struct {
+		code     uint64
+		tag      dwarf.Tag
+		children bool
+		attrs    [][2]uint64
+	}{
+		{abbrevCompileUnit, dwarf.TagCompileUnit, true, [][2]uint64{
+			{uint64(dwarf.AttrProducer), dwFormStrp},
+			{uint64(dwarf.AttrLanguage), dwFormData2},
+			{uint64(dwarf.AttrName), dwFormStrp},
+			{uint64(dwarf.AttrStmtList), dwFormSecOffset},
+			{uint64(dwarf.AttrLowpc), dwFormAddr},
+			{uint64(dwarf.AttrHighpc), highPcForm},
+		}},
+		{abbrevSubprogram, dwarf.TagSubprogram, false, [][2]uint64{
+			{uint64(dwarf.AttrName), dwFormStrp},
+			{uint64(dwarf.AttrExternal), dwFormFlagPresent},
+			{uint64(dwarf.AttrLowpc), dwFormAddr},
+			{uint64(dwarf.AttrHighpc), highPcForm},
+			{uint64(dwarf.AttrDeclFile), dwFormUdata},
+			{uint64(dwarf.AttrDeclLine), dwFormUdata},
+		}},
+	} {
+		abbrev.uleb(entry.code)
+		abbrev.uleb(uint64(entry.tag))
+		if entry.children {
+			abbrev.u8(1)
+		} else {
+			abbrev.u8(0)
+		}
+		for _, attr := range entry.attrs {
+			abbrev.uleb(attr[0])
+			abbrev.uleb(attr[1])
+		}
+		abbrev.uleb(0)
+		abbrev.uleb(0)
+	}
+	abbrev.u8(0)
+
+	strs := dwarfStrings{offsets: make(map[string]uint32)}
+
+	info := dwarfWriter{order: order, ptrSize: ptrSize}
+	info.u32(0)
+	info.u16(4)
+	info.u32(0) // the only abbreviation table
+	info.u8(uint8(ptrSize))
+	info.uleb(abbrevCompileUnit)
+	info.u32(strs.offset("GoReSym"))
+	info.u16(dwLangGo)
+	info.u32(strs.offset("go"))
+	info.u32(0) // the only line program
+	info.addr(low)
+	info.addr(high - low)
+	for _, fn := range funcs {
+		info.uleb(abbrevSubprogram)
+		info.u32(strs.offset(fn.Name))
+		info.addr(fn.Entry)
+		info.addr(fn.End - fn.Entry)
+		file, line := uint64(0), 0
+		if len(fn.Rows) > 0 {
+			file, line = files[fn.Rows[0].File], max(fn.Rows[0].Line, 0)
+		}
+		info.uleb(file)
+		info.uleb(uint64(line))
+	}
+	info.u8(0)
+	info.patchLength(0)
+
+	lines := dwarfWriter{order: order, ptrSize: ptrSize}
+	lines.u32(0)
+	lines.u16(4)
+	lines.u32(0)
+	headerStart := lines.Len()
+	lines.u8(1) // minimum_instruction_length
+	lines.u8(1) // maximum_operations_per_instruction
+	lines.u8(1) // default_is_stmt
+	lines.u8(lineBase & 0xff)
+	lines.u8(lineRange)
+	lines.u8(opcodeBase)
+	lines.Write([]byte{0, 1, 1, 1, 1, 0, 0, 0, 1, 0, 0, 1})
+	lines.u8(0) // no include directories, the file names are as the pclntab has them
+	for _, name := range fileNames {
+		lines.WriteString(name)
+		lines.u8(0)
+		lines.uleb(0)
+		lines.uleb(0)
+		lines.uleb(0)
+	}
+	lines.u8(0)
+	order.PutUint32(lines.Bytes()[headerStart-4:], uint32(lines.Len()-headerStart))
+
+	for _, fn := range funcs {
+		if len(fn.Rows) == 0 {
+			continue
+		}
+		lines.u8(0)
+		lines.uleb(uint64(1 + ptrSize))
+		lines.u8(dwLneSetAddress)
+		lines.addr(fn.Entry)
+		pc, file, line := fn.Entry, uint64(1), 1
+		for _, row := range fn.Rows {
+			if row.PC < pc || row.PC >= fn.End {
+				continue
+			}
+			if rowFile := files[row.File]; rowFile != file && rowFile != 0 {
+				lines.u8(dwLnsSetFile)
+				lines.uleb(rowFile)
+				file = rowFile
+			}
+			if rowLine := max(row.Line, 0); rowLine != line {
+				lines.u8(dwLnsAdvanceLine)
+				lines.sleb(int64(rowLine - line))
+				line = rowLine
+			}
+			if row.PC != pc {
+				lines.u8(dwLnsAdvancePc)
+				lines.uleb(row.PC - pc)
+				pc = row.PC
+			}
+			lines.u8(dwLnsCopy)
+		}
+		lines.u8(dwLnsAdvancePc)
+		lines.uleb(fn.End - pc)
+		lines.u8(0)
+		lines.uleb(1)
+		lines.u8(dwLneEndSequence)
+	}
+	lines.patchLength(0)
+
+	for _, section := range [][]byte{info.Bytes(), lines.Bytes(), strs.data} {
+		if uint64(len(section)) > math.MaxUint32 {
+			return nil, fmt.Errorf("too many functions for 32 bit DWARF")
+		}
+	}
+	return []ElfSection{
+		{Name: ".debug_abbrev", Type: elf.SHT_PROGBITS, Addralign: 1, Data: abbrev.Bytes()},
+		{Name: ".debug_info", Type: elf.SHT_PROGBITS, Addralign: 1, Data: info.Bytes()},
+		{Name: ".debug_line", Type: elf.SHT_PROGBITS, Addralign: 1, Data: lines.Bytes()},
+		{Name: ".debug_str", Type: elf.SHT_PROGBITS, Flags: elf.SHF_MERGE | elf.SHF_STRINGS, Addralign: 1, Entsize: 1, Data: strs.data},
+	}, nil
+}
@@ -10,6 +10,7 @@ package objfile
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -19,33 +20,218 @@ import (
 )
 
 type elfFile struct {
-	elf *elf.File
+	elf            *elf.File
+	firstMatchOnly bool                      // stop the moduledata signature scan at the first validated match
+	scanWorkers    int                       // goroutines per section for the signature scan, 0 for GOMAXPROCS
+	diagnostics    *scanDiagnostics          // nil unless SetDiagnostics
+	ctx            context.Context           // nil unless SetContext
+	scanRanges     []ScanRange               // nil unless SetScanRanges
+	signatures     []compiledCustomSignature // nil unless SetSignatures
+	rebaseDelta    uint64                    // added to the segments and sections by rebase, the symbol table isn't rewritten
 }
 
-func openElf(r io.ReaderAt) (rawFile, error) {
+// where the sections of a relocatable object are laid out, past the zero page so no pointer into them is null
+const relocatableBase = 0x10000
+
+func openElf(r io.ReaderAt, opts OpenOptions) (rawFile, error) {
 	f, err := elf.NewFile(r)
 	if err != nil {
-		return nil, err
+		// malware zeroes the section header table or points it nowhere, the program headers are all the loader needs
+		if f, err = elf.NewFile(withoutSectionHeaders(r)); err != nil {
+			return nil, err
+		}
+	}
+	if f.Type == elf.ET_CORE {
+		return openCore(f)
+	}
+	// a relocatable object, the go.o of a c-archive, has no segments to read it through. Its sections are all at 0 until laid out.
+	if f.Type == elf.ET_REL {
+		if err := f.LayoutRelocatable(relocatableBase); err != nil {
+			return nil, err
+		}
+	} else if !sectionsMatchSegments(f) {
+		f.SectionsFromProgs()
+	}
+	ef := &elfFile{elf: f}
+	ef.rebase(opts.LoadBase)
+	// a prelinked image or one written back out of memory holds pointers slid from the addresses of its headers
+	if opts.LoadBase == 0 && f.Type == elf.ET_DYN {
+		slide := opts.Slide
+		if slide == 0 {
+			slide, _ = detectSlide(ef.read_memory, f)
+		}
+		ef.slide(slide)
 	}
-	return &elfFile{f}, nil
+	return ef, nil
 }
 
-func (f *elfFile) read_memory(VA uint64, size uint64) (data []byte, err error) {
-	for _, prog := range f.elf.Progs {
+// headerReaderAt reads r with its start replaced by header
+type headerReaderAt struct {
+	r      io.ReaderAt
+	header []byte
+}
+
+func (h headerReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := h.r.ReadAt(p, off)
+	if off < int64(len(h.header)) {
+		copy(p[:n], h.header[off:])
+	}
+	return n, err
+}
+
+// View views r in place past the replaced header
+func (h headerReaderAt) View(off int64, n int64) ([]byte, bool) {
+	v, ok := h.r.(viewer)
+	if !ok || off < int64(len(h.header)) {
+		return nil, false
+	}
+	return v.View(off, n)
+}
+
+// withoutSectionHeaders reads an ELF with e_shoff, e_shnum and e_shstrndx zeroed, like a mapped image, see mappedHeaders
+func withoutSectionHeaders(r io.ReaderAt) io.ReaderAt {
+	header := make([]byte, 0x40)
+	n, _ := r.ReadAt(header, 0)
+	return headerReaderAt{r, mappedHeaders(header[:n])}
+}
+
+// sectionsMatchSegments reports whether some allocated section lies in a PT_LOAD segment at the segment's file offset.
+// Zeroed or garbage section headers don't, the ELF is then read through its segments.
+func sectionsMatchSegments(f *elf.File) bool {
+	for _, sect := range f.Sections {
+		if sect.Type != elf.SHT_PROGBITS || sect.Flags&elf.SHF_ALLOC == 0 {
+			continue
+		}
+		for _, prog := range f.Progs {
+			if prog.Type != elf.PT_LOAD || sect.Addr < prog.Vaddr || sect.Addr-prog.Vaddr+sect.Size > prog.Filesz {
+				continue
+			}
+			if sect.Offset-prog.Off == sect.Addr-prog.Vaddr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SegmentOverlap is a VA range mapped by more than one PT_LOAD segment, reads from it are resolved by resolveSegment
+type SegmentOverlap struct {
+	Start    uint64
+	End      uint64
+	Segments []int // indexes into the program headers
+}
+
+// loadSegments returns the PT_LOAD segments with file backing. Files without any fall back to every program header.
+func loadSegments(progs []*elf.Prog) []*elf.Prog {
+	var loads []*elf.Prog
+	for _, prog := range progs {
+		if prog.Type == elf.PT_LOAD && prog.Filesz > 0 {
+			loads = append(loads, prog)
+		}
+	}
+	if len(loads) == 0 {
+		return progs
+	}
+	return loads
+}
+
+// sectionContaining returns the allocated, file backed section holding VA, or nil
+func sectionContaining(sections []*elf.Section, VA uint64) *elf.Section {
+	for _, sect := range sections {
+		if sect.Flags&elf.SHF_ALLOC != 0 && sect.Type != elf.SHT_NOBITS && sect.Addr <= VA && VA-sect.Addr < sect.Size {
+			return sect
+		}
+	}
+	return nil
+}
+
+// resolveSegment picks the segment to read VA from. When segments overlap, prefer the one mapping VA to the same file offset as its section,
+// then one whose permissions match the section's flags, and otherwise the last one, since the loader maps them in order and later mappings replace earlier ones.
+func resolveSegment(progs []*elf.Prog, sections []*elf.Section, VA uint64) *elf.Prog {
+	var candidates []*elf.Prog
+	for _, prog := range loadSegments(progs) {
 		if prog.Vaddr <= VA && VA <= prog.Vaddr+prog.Filesz-1 {
-			n := prog.Vaddr + prog.Filesz - VA
-			if n > size {
-				n = size
+			candidates = append(candidates, prog)
+		}
+	}
+
+	if len(candidates) <= 1 {
+		if len(candidates) == 0 {
+			return nil
+		}
+		return candidates[0]
+	}
+
+	if sect := sectionContaining(sections, VA); sect != nil {
+		for _, prog := range candidates {
+			if sect.Offset+(VA-sect.Addr) == prog.Off+(VA-prog.Vaddr) {
+				return prog
+			}
+		}
+
+		isExec := sect.Flags&elf.SHF_EXECINSTR != 0
+		isWrite := sect.Flags&elf.SHF_WRITE != 0
+		for _, prog := range candidates {
+			if (prog.Flags&elf.PF_X != 0) == isExec && (prog.Flags&elf.PF_W != 0) == isWrite {
+				return prog
+			}
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// segmentOverlaps reports every pair of PT_LOAD segments whose memory ranges intersect. Normal toolchain output has none.
+func segmentOverlaps(progs []*elf.Prog) []SegmentOverlap {
+	var overlaps []SegmentOverlap
+	for i, a := range progs {
+		if a.Type != elf.PT_LOAD || a.Memsz == 0 {
+			continue
+		}
+		for j := i + 1; j < len(progs); j++ {
+			b := progs[j]
+			if b.Type != elf.PT_LOAD || b.Memsz == 0 {
+				continue
+			}
+
+			start, end := a.Vaddr, a.Vaddr+a.Memsz
+			if b.Vaddr > start {
+				start = b.Vaddr
 			}
-			data := make([]byte, n)
-			_, err := prog.ReadAt(data, int64(VA-prog.Vaddr))
-			if err != nil {
-				return nil, err
+			if b.Vaddr+b.Memsz < end {
+				end = b.Vaddr + b.Memsz
+			}
+			if start < end {
+				overlaps = append(overlaps, SegmentOverlap{Start: start, End: end, Segments: []int{i, j}})
 			}
-			return data, nil
 		}
 	}
-	return nil, fmt.Errorf("Failed to read memory")
+	return overlaps
+}
+
+// SegmentOverlaps lists the VA ranges mapped by more than one PT_LOAD segment, a sign of a manipulated binary. Only ELF files are checked.
+func (e *Entry) SegmentOverlaps() []SegmentOverlap {
+	f, ok := e.raw.(*elfFile)
+	if !ok {
+		return nil
+	}
+	return segmentOverlaps(f.elf.Progs)
+}
+
+func (f *elfFile) read_memory(VA uint64, size uint64) (data []byte, err error) {
+	prog := resolveSegment(f.elf.Progs, f.elf.Sections, VA)
+	if prog == nil {
+		// a relocatable object has no segments, its laid out sections are read instead
+		if sect := sectionContaining(f.elf.Sections, VA); sect != nil && f.elf.Type == elf.ET_REL {
+			return readAvailable(sect, make([]byte, min(sect.Addr+sect.Size-VA, size)), int64(VA-sect.Addr), VA)
+		}
+		return nil, fmt.Errorf("Failed to read memory")
+	}
+
+	n := prog.Vaddr + prog.Filesz - VA
+	if n > size {
+		n = size
+	}
+	return readAvailable(prog, make([]byte, n), int64(VA-prog.Vaddr), VA)
 }
 
 func (f *elfFile) symbols() ([]Sym, error) {
@@ -68,6 +254,13 @@ func (f *elfFile) symbols() ([]Sym, error) {
 				break
 			}
 			sect := f.elf.Sections[i]
+			if sect.Flags&elf.SHF_ALLOC != 0 {
+				sym.Addr += f.rebaseDelta
+			}
+			// symbols of a relocatable object are relative to their section
+			if f.elf.Type == elf.ET_REL {
+				sym.Addr += sect.Addr
+			}
 			switch sect.Flags & (elf.SHF_WRITE | elf.SHF_ALLOC | elf.SHF_EXECINSTR) {
 			case elf.SHF_ALLOC | elf.SHF_EXECINSTR:
 				sym.Code = 'T'
@@ -141,13 +334,9 @@ func (f *elfFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 
 		if !has_some_valid_magic {
 			for _, magic := range append(pclntab_sigs_le, pclntab_sigs_be...) {
-				pclntab_copy := make([]byte, len(candidate.Pclntab))
-				copy(pclntab_copy, candidate.Pclntab)
-				copy(pclntab_copy, magic)
-
-				new_candidate := candidate
-				new_candidate.Pclntab = pclntab_copy
-				send_tab(new_candidate)
+				new_candidate := *candidate
+				new_candidate.setMagic(candidate.Pclntab, magic)
+				send_tab(&new_candidate)
 			}
 		}
 	}
@@ -164,28 +353,22 @@ func (f *elfFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 
 				if stompedMagicCandidate.LittleEndian {
 					for _, magicLE := range pclntab_sigs_le {
-						pclntab_copy := make([]byte, len(pclntab))
-						copy(pclntab_copy, pclntab)
-						copy(pclntab_copy, magicLE)
-
 						var candidate PclntabCandidate
 						candidate.StompMagicCandidateMeta = stompedMagicCandidate
-						candidate.Pclntab = pclntab_copy
+						candidate.setMagic(pclntab, magicLE)
 						candidate.SecStart = uint64(sec.Addr)
+						candidate.Location = sec.Name
 						candidate.PclntabVA = pclntab_va_candidate
 
 						send_tab(&candidate)
 					}
 				} else {
 					for _, magicBE := range pclntab_sigs_be {
-						pclntab_copy := make([]byte, len(pclntab))
-						copy(pclntab_copy, pclntab)
-						copy(pclntab_copy, magicBE)
-
 						var candidate PclntabCandidate
 						candidate.StompMagicCandidateMeta = stompedMagicCandidate
-						candidate.Pclntab = pclntab_copy
+						candidate.setMagic(pclntab, magicBE)
 						candidate.SecStart = uint64(sec.Addr)
+						candidate.Location = sec.Name
 						candidate.PclntabVA = pclntab_va_candidate
 
 						send_tab(&candidate)
@@ -195,6 +378,8 @@ func (f *elfFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 		}
 	}
 
+	toc := f.tocBase()
+	f.diagnostics.reset()
 	go func() {
 		defer close(ch_tab)
 
@@ -205,10 +390,15 @@ func (f *elfFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 			}
 
 			data := f.elf.DataAfterSection(sec)
+			windows := scanWindows(f.scanRanges, sec.Addr, true, sec.Offset, sec.Type != elf.SHT_NOBITS, sec.Size, len(data))
+			if len(windows) == 0 {
+				continue
+			}
+			f.diagnostics.section(sec.Name, sec.Addr, sec.Size, sec.Flags&elf.SHF_EXECINSTR != 0, scannedBytes(f.scanRanges, windows))
 			if !foundpcln {
 				// malware can split the pclntab across multiple sections, re-merge
 				// https://github.com/golang/go/blob/2cb9042dc2d5fdf6013305a077d013dbbfbaac06/src/debug/gosym/pclntab.go#L172
-				matches := findAllOccurrences(data, pclntab_sigs)
+				matches := findWindowOccurrences(data, windows, pclntab_sigs)
 				for _, pclntab_idx := range matches {
 					if pclntab_idx != -1 && pclntab_idx < int(sec.Size) {
 						pclntab = data[pclntab_idx:]
@@ -217,6 +407,7 @@ func (f *elfFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 						candidate.Pclntab = pclntab
 
 						candidate.SecStart = uint64(sec.Addr)
+						candidate.Location = sec.Name
 						candidate.PclntabVA = candidate.SecStart + uint64(pclntab_idx)
 						send_patched_magic_candidates(&candidate)
 
@@ -227,10 +418,11 @@ func (f *elfFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 			} else {
 				// 3) if we found it earlier, figure out which section base to return (might be wrong for packed things)
 				pclntab_idx := bytes.Index(data, pclntab)
-				if pclntab_idx != -1 && pclntab_idx < int(sec.Size) {
+				if pclntab_idx != -1 && pclntab_idx < int(sec.Size) && inWindows(windows, pclntab_idx) {
 					var candidate PclntabCandidate
 					candidate.Pclntab = pclntab
 					candidate.SecStart = uint64(sec.Addr)
+					candidate.Location = sec.Name
 					candidate.PclntabVA = candidate.SecStart + uint64(pclntab_idx)
 
 					send_patched_magic_candidates(&candidate)
@@ -240,7 +432,11 @@ func (f *elfFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 
 			// 4) Always try this other way! Sometimes the pclntab magic is stomped as well so our byte OR symbol location fail. Byte scan for the moduledata, use that to find the pclntab instead, fix up magic with all combinations.
 			// See the obfuscator 'garble' for an example of randomizing the pclntab magic
-			sigResults := findModuleInitPCHeader(data, sec.Addr)
+			var sigResults []SignatureMatch
+			for _, w := range windows {
+				sigResults = append(sigResults, findModuleInitPCHeaderWorkers(f.ctx, data[w.start:w.end], sec.Addr+uint64(w.start), f.elf.ByteOrder, f.goarch(), f.signatures, toc, f.scanWorkers, pcHeaderValidator(f.firstMatchOnly, f.read_memory), f.diagnostics)...)
+			}
+			sigResults = rankSignatureMatches(sigResults, f.read_memory, f.diagnostics)
 			for _, sigResult := range sigResults {
 				// example: off_69D0C0 is the moduleData we found via our scan, the first ptr unk_5DF6E0, is the pclntab!
 				// 0x000000000069D0C0 E0 F6 5D 00 00 00 00 00 off_69D0C0      dq offset unk_5DF6E0    ; DATA XREF: runtime_SetFinalizer+119↑o
@@ -298,13 +494,35 @@ func (f *elfFile) pcln() (candidates <-chan PclntabCandidate, err error) {
 	return candidates, nil
 }
 
+// tocBase returns the ppc64 TOC pointer position independent code addresses data from, or 0 for other architectures.
+// The ELFv2 ABI places it 0x8000 into the .got so signed 16 bit offsets reach the whole table, .TOC. names it when symbols are present.
+func (f *elfFile) tocBase() uint64 {
+	if f.elf.Machine != elf.EM_PPC64 {
+		return 0
+	}
+
+	if syms, err := f.elf.Symbols(); err == nil {
+		for _, sym := range syms {
+			if sym.Name == ".TOC." {
+				return sym.Value
+			}
+		}
+	}
+
+	for _, name := range []string{".got", ".toc"} {
+		if sect := f.elf.Section(name); sect != nil {
+			return sect.Addr + 0x8000
+		}
+	}
+	return 0
+}
+
 func (f *elfFile) moduledata_scan(pclntabVA uint64, is64bit bool, littleendian bool, ignorelist []uint64) (candidate *ModuleDataCandidate, err error) {
 	found := false
 
 	var secStart uint64
 	var moduledata []uint8
 	var moduledataVA uint64
-scan:
 	for _, sec := range f.elf.Sections {
 		// first section is all zeros, skip
 		if sec.Type == elf.SHT_NULL {
@@ -315,36 +533,11 @@ scan:
 		data := f.elf.DataAfterSection(sec)
 
 		// fall back to scanning for structure using address of pclntab, which is first value in struc
-		var pclntabVA_bytes []byte
-		if is64bit {
-			pclntabVA_bytes = make([]byte, 8)
-			if littleendian {
-				binary.LittleEndian.PutUint64(pclntabVA_bytes, pclntabVA)
-			} else {
-				binary.BigEndian.PutUint64(pclntabVA_bytes, pclntabVA)
-			}
-		} else {
-			pclntabVA_bytes = make([]byte, 4)
-			if littleendian {
-				binary.LittleEndian.PutUint32(pclntabVA_bytes, uint32(pclntabVA))
-			} else {
-				binary.BigEndian.PutUint32(pclntabVA_bytes, uint32(pclntabVA))
-			}
-		}
-
-		moduledata_idx := bytes.Index(data, pclntabVA_bytes)
-		if moduledata_idx != -1 && moduledata_idx < int(sec.Size) {
+		moduledata_idx := findModuleDataPointer(data, int(sec.Size), sec.Addr, pclntabVA, is64bit, littleendian, ignorelist)
+		if moduledata_idx != -1 {
 			moduledata = data[moduledata_idx:]
 			moduledataVA = sec.Addr + uint64(moduledata_idx)
 			secStart = sec.Addr
-
-			// optionally consult ignore list, to skip past previous (bad) scan results
-			for _, ignore := range ignorelist {
-				if ignore == secStart+uint64(moduledata_idx) {
-					continue scan
-				}
-			}
-
 			found = true
 			break
 		}
@@ -359,6 +552,15 @@ scan:
 
 func (f *elfFile) text() (textStart uint64, text []byte, err error) {
 	sect := f.elf.Section(".text")
+	if sect == nil {
+		// sections made from the segments have no names, the first executable one holds the text
+		for _, s := range f.elf.Sections {
+			if s.Flags&elf.SHF_EXECINSTR != 0 {
+				sect = s
+				break
+			}
+		}
+	}
 	if sect == nil {
 		return 0, nil, fmt.Errorf("text section not found")
 	}
@@ -387,6 +589,11 @@ func (f *elfFile) rel_rdata() (textStart uint64, text []byte, err error) {
 	return
 }
 
+// endian is the data encoding of the ELF header
+func (f *elfFile) endian() binary.ByteOrder {
+	return f.elf.ByteOrder
+}
+
 func (f *elfFile) goarch() string {
 	switch f.elf.Machine {
 	case elf.EM_386:
@@ -402,8 +609,24 @@ func (f *elfFile) goarch() string {
 			return "ppc64le"
 		}
 		return "ppc64"
+	case elf.EM_MIPS:
+		// bi-endian, the header's data encoding picks the GOARCH
+		if f.elf.Class == elf.ELFCLASS64 {
+			if f.elf.ByteOrder == binary.LittleEndian {
+				return "mips64le"
+			}
+			return "mips64"
+		}
+		if f.elf.ByteOrder == binary.LittleEndian {
+			return "mipsle"
+		}
+		return "mips"
 	case elf.EM_S390:
 		return "s390x"
+	case elf.EM_RISCV:
+		return "riscv64"
+	case elf.EM_LOONGARCH:
+		return "loong64"
 	}
 	return ""
 }
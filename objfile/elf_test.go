@@ -0,0 +1,206 @@
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/mandiant/GoReSym/archive"
+	"github.com/mandiant/GoReSym/debug/elf"
+)
+
+const (
+	overlapVA      = 0x401000
+	overlapDecoy   = 0x100 // file offset of the bytes that must not be read
+	overlapReal    = 0x108 // file offset of the bytes .rodata describes
+	overlapStrtab  = 0x110
+	overlapSection = 0x130
+)
+
+// buildOverlapElf crafts an ELF with two PT_LOAD segments mapping the same VA to different file offsets, one of them agrees with the .rodata section header
+func buildOverlapElf(decoyFirst bool) []byte {
+	shstrtab := []byte("\x00.rodata\x00.shstrtab\x00")
+
+	var hdr elf.Header64
+	copy(hdr.Ident[:], elf.ELFMAG)
+	hdr.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	hdr.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	hdr.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	hdr.Type = uint16(elf.ET_EXEC)
+	hdr.Machine = uint16(elf.EM_X86_64)
+	hdr.Version = uint32(elf.EV_CURRENT)
+	hdr.Phoff = 64
+	hdr.Shoff = overlapSection
+	hdr.Ehsize = 64
+	hdr.Phentsize = 56
+	hdr.Phnum = 2
+	hdr.Shentsize = 64
+	hdr.Shnum = 3
+	hdr.Shstrndx = 2
+
+	decoy := elf.Prog64{Type: uint32(elf.PT_LOAD), Flags: uint32(elf.PF_R | elf.PF_X), Off: overlapDecoy, Vaddr: overlapVA, Paddr: overlapVA, Filesz: 8, Memsz: 8, Align: 0x1000}
+	genuine := elf.Prog64{Type: uint32(elf.PT_LOAD), Flags: uint32(elf.PF_R), Off: overlapReal, Vaddr: overlapVA, Paddr: overlapVA, Filesz: 8, Memsz: 8, Align: 0x1000}
+	progs := []elf.Prog64{genuine, decoy}
+	if decoyFirst {
+		progs = []elf.Prog64{decoy, genuine}
+	}
+
+	sections := []elf.Section64{
+		{},
+		{Name: 1, Type: uint32(elf.SHT_PROGBITS), Flags: uint64(elf.SHF_ALLOC), Addr: overlapVA, Off: overlapReal, Size: 8, Addralign: 1},
+		{Name: 9, Type: uint32(elf.SHT_STRTAB), Off: overlapStrtab, Size: uint64(len(shstrtab)), Addralign: 1},
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, hdr)
+	binary.Write(&buf, binary.LittleEndian, progs)
+	buf.Write(make([]byte, overlapDecoy-buf.Len()))
+	buf.WriteString("BAD!BAD!")
+	buf.WriteString("GOODGOOD")
+	buf.Write(shstrtab)
+	buf.Write(make([]byte, overlapSection-buf.Len()))
+	binary.Write(&buf, binary.LittleEndian, sections)
+	return buf.Bytes()
+}
+
+func TestOverlappingSegments(t *testing.T) {
+	for _, decoyFirst := range []bool{true, false} {
+		raw, err := openElf(bytes.NewReader(buildOverlapElf(decoyFirst)), OpenOptions{})
+		if err != nil {
+			t.Fatalf("failed to parse crafted elf: %s", err)
+		}
+
+		data, err := raw.read_memory(overlapVA, 8)
+		if err != nil {
+			t.Fatalf("read failed: %s", err)
+		}
+		if string(data) != "GOODGOOD" {
+			t.Errorf("decoyFirst=%v: read %q from the wrong segment", decoyFirst, data)
+		}
+
+		overlaps := (&Entry{raw: raw}).SegmentOverlaps()
+		if len(overlaps) != 1 || overlaps[0].Start != overlapVA || overlaps[0].End != overlapVA+8 {
+			t.Errorf("decoyFirst=%v: unexpected overlaps %+v", decoyFirst, overlaps)
+		}
+	}
+}
+
+func TestResolveSegment(t *testing.T) {
+	rx := &elf.Prog{ProgHeader: elf.ProgHeader{Type: elf.PT_LOAD, Flags: elf.PF_R | elf.PF_X, Off: 0x2000, Vaddr: 0x1000, Filesz: 0x100, Memsz: 0x100}}
+	r := &elf.Prog{ProgHeader: elf.ProgHeader{Type: elf.PT_LOAD, Flags: elf.PF_R, Off: 0x3000, Vaddr: 0x1000, Filesz: 0x100, Memsz: 0x100}}
+	note := &elf.Prog{ProgHeader: elf.ProgHeader{Type: elf.PT_NOTE, Off: 0x4000, Vaddr: 0x1000, Filesz: 0x100, Memsz: 0x100}}
+	progs := []*elf.Prog{note, rx, r}
+
+	// section offsets agree with neither segment, so its flags decide
+	text := []*elf.Section{{SectionHeader: elf.SectionHeader{Type: elf.SHT_PROGBITS, Flags: elf.SHF_ALLOC | elf.SHF_EXECINSTR, Addr: 0x1000, Offset: 0x9000, Size: 0x100}}}
+	if prog := resolveSegment(progs, text, 0x1010); prog != rx {
+		t.Errorf("executable section did not resolve to the executable segment")
+	}
+
+	// without section headers the last mapping wins, like the loader
+	if prog := resolveSegment(progs, nil, 0x1010); prog != r {
+		t.Errorf("expected the last segment")
+	}
+
+	if prog := resolveSegment(progs, nil, 0x1100); prog != nil {
+		t.Errorf("resolved a VA past the end of every segment")
+	}
+}
+
+// buildRelocatableElf crafts the object of a c-archive in miniature: .text and .data both at 0, a pointer in .data to .text+4 left to a relocation
+func buildRelocatableElf() []byte {
+	shstrtab := []byte("\x00.text\x00.data\x00.symtab\x00.strtab\x00.rela.data\x00.shstrtab\x00")
+	const (
+		textOff     = 0x40
+		dataOff     = 0x50
+		symtabOff   = 0x58
+		strtabOff   = 0x88
+		relaOff     = 0x90
+		shstrtabOff = 0xa8
+		sectionOff  = 0x100
+	)
+
+	var hdr elf.Header64
+	copy(hdr.Ident[:], elf.ELFMAG)
+	hdr.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	hdr.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	hdr.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	hdr.Type = uint16(elf.ET_REL)
+	hdr.Machine = uint16(elf.EM_X86_64)
+	hdr.Version = uint32(elf.EV_CURRENT)
+	hdr.Shoff = sectionOff
+	hdr.Ehsize = 64
+	hdr.Shentsize = 64
+	hdr.Shnum = 7
+	hdr.Shstrndx = 6
+
+	// the null symbol, then the section symbol of .text
+	symbols := []elf.Sym64{{}, {Info: elf.ST_INFO(elf.STB_LOCAL, elf.STT_SECTION), Shndx: 1}}
+	rela := elf.Rela64{Off: 0, Info: elf.R_INFO(1, uint32(elf.R_X86_64_64)), Addend: 4}
+
+	sections := []elf.Section64{
+		{},
+		{Name: 1, Type: uint32(elf.SHT_PROGBITS), Flags: uint64(elf.SHF_ALLOC | elf.SHF_EXECINSTR), Off: textOff, Size: 0x10, Addralign: 0x10},
+		{Name: 7, Type: uint32(elf.SHT_PROGBITS), Flags: uint64(elf.SHF_ALLOC | elf.SHF_WRITE), Off: dataOff, Size: 8, Addralign: 8},
+		{Name: 13, Type: uint32(elf.SHT_SYMTAB), Off: symtabOff, Size: 48, Link: 4, Info: 2, Addralign: 8, Entsize: 24},
+		{Name: 21, Type: uint32(elf.SHT_STRTAB), Off: strtabOff, Size: 1, Addralign: 1},
+		{Name: 29, Type: uint32(elf.SHT_RELA), Off: relaOff, Size: 24, Link: 3, Info: 2, Addralign: 8, Entsize: 24},
+		{Name: 40, Type: uint32(elf.SHT_STRTAB), Off: shstrtabOff, Size: uint64(len(shstrtab)), Addralign: 1},
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, hdr)
+	buf.WriteString("\x90\x90\x90\x90\xc3\x90\x90\x90\x90\x90\x90\x90\x90\x90\x90\x90")
+	buf.Write(make([]byte, 8)) // the pointer, 0 until relocated
+	binary.Write(&buf, binary.LittleEndian, symbols)
+	buf.Write(make([]byte, strtabOff-buf.Len()+8))
+	binary.Write(&buf, binary.LittleEndian, rela)
+	buf.Write(shstrtab)
+	buf.Write(make([]byte, sectionOff-buf.Len()))
+	binary.Write(&buf, binary.LittleEndian, sections)
+	return buf.Bytes()
+}
+
+func TestRelocatableObject(t *testing.T) {
+	raw, err := openElf(bytes.NewReader(buildRelocatableElf()), OpenOptions{})
+	if err != nil {
+		t.Fatalf("failed to parse crafted object: %s", err)
+	}
+
+	// .text first at the base, .data after it
+	text, data := uint64(relocatableBase), uint64(relocatableBase+0x10)
+	if code, err := raw.read_memory(text+4, 1); err != nil || code[0] != 0xc3 {
+		t.Errorf("expected the ret at .text+4, got %x %v", code, err)
+	}
+	pointer, err := raw.read_memory(data, 8)
+	if err != nil {
+		t.Fatalf("read of .data failed: %s", err)
+	}
+	if value := binary.LittleEndian.Uint64(pointer); value != text+4 {
+		t.Errorf("expected the pointer relocated to 0x%x, got 0x%x", text+4, value)
+	}
+	if mode := (&Entry{raw: raw}).BuildMode(); mode != "c-archive" {
+		t.Errorf("expected c-archive, got %s", mode)
+	}
+}
+
+func TestByteOrder(t *testing.T) {
+	cases := []struct {
+		name  string
+		entry *Entry
+		order binary.ByteOrder
+	}{
+		// mips is bi-endian, only the header tells
+		{"mips", &Entry{raw: &elfFile{elf: &elf.File{FileHeader: elf.FileHeader{Class: elf.ELFCLASS32, Machine: elf.EM_MIPS, ByteOrder: binary.BigEndian}}}}, binary.BigEndian},
+		{"mipsle", &Entry{raw: &elfFile{elf: &elf.File{FileHeader: elf.FileHeader{Class: elf.ELFCLASS32, Machine: elf.EM_MIPS, ByteOrder: binary.LittleEndian}}}}, binary.LittleEndian},
+		{"s390x", &Entry{raw: &elfFile{elf: &elf.File{FileHeader: elf.FileHeader{Class: elf.ELFCLASS64, Machine: elf.EM_S390, ByteOrder: binary.BigEndian}}}}, binary.BigEndian},
+		{"dump", &Entry{raw: &dumpFile{format: "raw", arch: "ppc64", byteOrder: binary.BigEndian}}, binary.BigEndian},
+		// an object of an architecture without a byte order known
+		{"unknown", &Entry{raw: &goobjFile{goobj: &archive.GoObj{Arch: "sparc64"}}}, binary.LittleEndian},
+	}
+	for _, c := range cases {
+		if order := c.entry.ByteOrder(); order != c.order {
+			t.Errorf("%s: expected %s, got %s", c.name, c.order, order)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+)
+
+// ElfSymbol is a function to add to the symbol table of a patched ELF
+type ElfSymbol struct {
+	Name  string
+	Value uint64
+	Size  uint64
+}
+
+// ElfSection is a section to add to a patched ELF. Link names another added section, ex: the string table of a symbol table.
+type ElfSection struct {
+	Name      string
+	Type      elf.SectionType
+	Flags     elf.SectionFlag
+	Link      string
+	Info      uint32
+	Addralign uint64
+	Entsize   uint64
+	Data      []byte
+}
+
+// a section header to write, name is the offset of the name in the new .shstrtab
+type patchSection struct {
+	elf.Section64
+	name string
+}
+
+// PatchElf returns a copy of the ELF in data with a .symtab and .strtab of symbols and the extra sections appended, ex: DWARF. The
+// original bytes are left as they are, so the program headers and every section stay put and the binary still runs. The new
+// sections, a new .shstrtab and a new section header table go after the end of the file and the ELF header points at them. A file
+// whose section headers were stripped or are bogus gets one section per PT_LOAD segment, named load<index> like the reader names them.
+func PatchElf(data []byte, symbols []ElfSymbol, extra []ElfSection) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(elf.ELFMAG)) {
+		return nil, fmt.Errorf("not an ELF, only ELF files are patched")
+	}
+	f, err := elf.NewFile(bytes.NewReader(data))
+	synthesize := false
+	if err != nil {
+		if f, err = elf.NewFile(withoutSectionHeaders(bytes.NewReader(data))); err != nil {
+			return nil, err
+		}
+		synthesize = true
+	}
+	if f.Type == elf.ET_REL || f.Type == elf.ET_CORE {
+		return nil, fmt.Errorf("only executables and shared objects are patched, not %s", f.Type)
+	}
+	if len(f.Sections) == 0 || !sectionsMatchSegments(f) {
+		synthesize = true
+	}
+	for _, sect := range f.Sections {
+		if sect.Type == elf.SHT_SYMTAB && !synthesize {
+			return nil, fmt.Errorf("the file already has a symbol table, %s", sect.Name)
+		}
+	}
+	is64 := f.Class == elf.ELFCLASS64
+
+	// the existing section headers, the .shstrtab moves to the end with the new names appended
+	var sections []patchSection
+	var shstrtab []byte
+	shstrndx := 0
+	if synthesize {
+		f.SectionsFromProgs()
+		shstrtab = []byte{0}
+		sections = append(sections, patchSection{})
+		for _, sect := range f.Sections {
+			sections = append(sections, patchSection{elf.Section64{Type: uint32(sect.Type), Flags: uint64(sect.Flags), Addr: sect.Addr, Off: sect.Offset, Size: sect.Size, Addralign: sect.Addralign}, sect.Name})
+		}
+	} else {
+		shstrndx = sectionNameIndex(data, f)
+		for i, sect := range f.Sections {
+			header := patchSection{elf.Section64{Type: uint32(sect.Type), Flags: uint64(sect.Flags), Addr: sect.Addr, Off: sect.Offset, Size: sect.FileSize, Link: sect.Link, Info: sect.Info, Addralign: sect.Addralign, Entsize: sect.Entsize}, sect.Name}
+			if i == shstrndx && sect.Type == elf.SHT_STRTAB {
+				if shstrtab, err = sect.Data(); err != nil {
+					return nil, fmt.Errorf("failed to read the section names: %w", err)
+				}
+			}
+			sections = append(sections, header)
+		}
+		if shstrtab == nil {
+			shstrndx, shstrtab = 0, []byte{0}
+		}
+	}
+
+	symtab, strtab, err := buildSymtab(f, sections, symbols)
+	if err != nil {
+		return nil, err
+	}
+	entsize := uint64(24)
+	if !is64 {
+		entsize = 16
+	}
+	added := append([]ElfSection{
+		{Name: ".symtab", Type: elf.SHT_SYMTAB, Link: ".strtab", Info: 1, Addralign: 8, Entsize: entsize, Data: symtab},
+		{Name: ".strtab", Type: elf.SHT_STRTAB, Addralign: 1, Data: strtab},
+	}, extra...)
+	if shstrndx == 0 {
+		added = append(added, ElfSection{Name: ".shstrtab", Type: elf.SHT_STRTAB, Addralign: 1})
+	}
+
+	// names the old headers point at keep their offsets, the new ones are appended to the end of the section names
+	indices := make(map[string]int)
+	for i, sect := range sections {
+		sections[i].Name = sectionName(&shstrtab, sect.name, i == 0)
+	}
+	for _, sect := range added {
+		indices[sect.Name] = len(sections)
+		ps := patchSection{elf.Section64{Type: uint32(sect.Type), Flags: uint64(sect.Flags), Info: sect.Info, Addralign: sect.Addralign, Entsize: sect.Entsize}, sect.Name}
+		ps.Name = sectionName(&shstrtab, sect.Name, false)
+		sections = append(sections, ps)
+	}
+	if shstrndx == 0 {
+		shstrndx = indices[".shstrtab"]
+	}
+
+	out := append([]byte{}, data...)
+	place := func(content []byte, align uint64) uint64 {
+		for align > 1 && uint64(len(out))%align != 0 {
+			out = append(out, 0)
+		}
+		offset := uint64(len(out))
+		out = append(out, content...)
+		return offset
+	}
+	for i, sect := range added {
+		header := &sections[len(sections)-len(added)+i]
+		if len(sect.Link) > 0 {
+			link, ok := indices[sect.Link]
+			if !ok {
+				return nil, fmt.Errorf("section %s links to %s, which isn't added", sect.Name, sect.Link)
+			}
+			header.Link = uint32(link)
+		}
+		if sect.Name != ".shstrtab" {
+			header.Off = place(sect.Data, sect.Addralign)
+			header.Size = uint64(len(sect.Data))
+		}
+	}
+	sections[shstrndx].Off = place(shstrtab, 1)
+	sections[shstrndx].Size = uint64(len(shstrtab))
+
+	// extended numbering, the counts that don't fit the ELF header go in section 0
+	shnum, shstrndxField := len(sections), shstrndx
+	if shnum >= int(elf.SHN_LORESERVE) {
+		sections[0].Size, shnum = uint64(shnum), 0
+	}
+	if shstrndxField >= int(elf.SHN_LORESERVE) {
+		sections[0].Link, shstrndxField = uint32(shstrndxField), int(elf.SHN_XINDEX)
+	}
+
+	var table bytes.Buffer
+	for _, sect := range sections {
+		if is64 {
+			err = binary.Write(&table, f.ByteOrder, sect.Section64)
+		} else {
+			if sect.Flags > math.MaxUint32 || sect.Addr > math.MaxUint32 || sect.Off > math.MaxUint32 || sect.Size > math.MaxUint32 || sect.Addralign > math.MaxUint32 || sect.Entsize > math.MaxUint32 {
+				return nil, fmt.Errorf("section %s doesn't fit a 32 bit ELF", sect.name)
+			}
+			err = binary.Write(&table, f.ByteOrder, elf.Section32{Name: sect.Name, Type: sect.Type, Flags: uint32(sect.Flags), Addr: uint32(sect.Addr), Off: uint32(sect.Off), Size: uint32(sect.Size), Link: sect.Link, Info: sect.Info, Addralign: uint32(sect.Addralign), Entsize: uint32(sect.Entsize)})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	shoff := place(table.Bytes(), 8)
+
+	// e_shoff, e_shentsize, e_shnum and e_shstrndx
+	if is64 {
+		f.ByteOrder.PutUint64(out[0x28:], shoff)
+		f.ByteOrder.PutUint16(out[0x3a:], 64)
+		f.ByteOrder.PutUint16(out[0x3c:], uint16(shnum))
+		f.ByteOrder.PutUint16(out[0x3e:], uint16(shstrndxField))
+	} else {
+		if shoff > math.MaxUint32 {
+			return nil, fmt.Errorf("the patched file doesn't fit a 32 bit ELF")
+		}
+		f.ByteOrder.PutUint32(out[0x20:], uint32(shoff))
+		f.ByteOrder.PutUint16(out[0x2e:], 40)
+		f.ByteOrder.PutUint16(out[0x30:], uint16(shnum))
+		f.ByteOrder.PutUint16(out[0x32:], uint16(shstrndxField))
+	}
+	return out, nil
+}
+
+// sectionNameIndex reads e_shstrndx, or the sh_link of section 0 it points at when there are too many sections for the ELF header
+func sectionNameIndex(data []byte, f *elf.File) int {
+	var index int
+	if f.Class == elf.ELFCLASS64 {
+		index = int(f.ByteOrder.Uint16(data[0x3e:]))
+	} else {
+		index = int(f.ByteOrder.Uint16(data[0x32:]))
+	}
+	if index == int(elf.SHN_XINDEX) && len(f.Sections) > 0 {
+		index = int(f.Sections[0].Link)
+	}
+	if index >= len(f.Sections) {
+		return 0
+	}
+	return index
+}
+
+// sectionName returns the offset of name in the section names, appending it when it isn't there. Section 0 has no name.
+func sectionName(shstrtab *[]byte, name string, null bool) uint32 {
+	if null {
+		return 0
+	}
+	if i := bytes.Index(*shstrtab, append([]byte(name), 0)); i >= 0 && (i == 0 || (*shstrtab)[i-1] == 0) {
+		return uint32(i)
+	}
+	offset := len(*shstrtab)
+	*shstrtab = append(append(*shstrtab, name...), 0)
+	return uint32(offset)
+}
+
+// buildSymtab encodes symbols as global functions in the section containing them, with their names in a string table
+func buildSymtab(f *elf.File, sections []patchSection, symbols []ElfSymbol) (symtab []byte, strtab []byte, err error) {
+	is64 := f.Class == elf.ELFCLASS64
+	strtab = []byte{0}
+	names := make(map[string]uint32)
+	var buf bytes.Buffer
+	// the null symbol
+	if is64 {
+		err = binary.Write(&buf, f.ByteOrder, elf.Sym64{})
+	} else {
+		err = binary.Write(&buf, f.ByteOrder, elf.Sym32{})
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info := elf.ST_INFO(elf.STB_GLOBAL, elf.STT_FUNC)
+	for _, sym := range symbols {
+		name, ok := names[sym.Name]
+		if !ok {
+			if uint64(len(strtab)) > math.MaxUint32 {
+				return nil, nil, fmt.Errorf("too many symbol names for a string table")
+			}
+			name = uint32(len(strtab))
+			names[sym.Name] = name
+			strtab = append(append(strtab, sym.Name...), 0)
+		}
+
+		// with more sections than the 16 bit index holds the symbol is left absolute, it would need an SHT_SYMTAB_SHNDX
+		shndx := uint16(elf.SHN_ABS)
+		for i, sect := range sections {
+			if sect.Flags&uint64(elf.SHF_ALLOC) != 0 && sect.Type != uint32(elf.SHT_NOBITS) && sym.Value >= sect.Addr && sym.Value-sect.Addr < sect.Size && i < int(elf.SHN_LORESERVE) {
+				shndx = uint16(i)
+				break
+			}
+		}
+
+		if is64 {
+			err = binary.Write(&buf, f.ByteOrder, elf.Sym64{Name: name, Info: info, Shndx: shndx, Value: sym.Value, Size: sym.Size})
+		} else {
+			if sym.Value > math.MaxUint32 || sym.Size > math.MaxUint32 {
+				return nil, nil, fmt.Errorf("symbol %s doesn't fit a 32 bit ELF", sym.Name)
+			}
+			err = binary.Write(&buf, f.ByteOrder, elf.Sym32{Name: name, Value: uint32(sym.Value), Size: uint32(sym.Size), Info: info, Shndx: shndx})
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return buf.Bytes(), strtab, nil
+}
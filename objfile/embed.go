@@ -0,0 +1,182 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"strings"
+	"unicode/utf8"
+)
+
+// the most files of an embed.FS read, more is a bogus slice
+const maxEmbeddedFiles = 1 << 20
+
+// EmbeddedFile is a file of an embed.FS, directories end in a / and have no data. SHA256 is of the data, Data is only kept to be
+// written out.
+type EmbeddedFile struct {
+	Name   string
+	Size   uint64
+	VA     uint64 // of the data
+	SHA256 string
+	Data   []byte `json:"-"`
+}
+
+// EmbeddedFS is a go:embed embed.FS variable and its files, in the order embed sorts them. The entries that didn't decode are
+// skipped and listed in Warnings.
+type EmbeddedFS struct {
+	VA       uint64
+	Name     string // of the variable, from the symbols
+	FilesVA  uint64 // of the []file
+	Files    []EmbeddedFile
+	Warnings []string
+}
+
+// readFull reads size bytes at VA, across the ends of the sections and segments read_memory stops at. The size comes from the
+// binary, so its last byte is read first and nothing is allocated for a range past the mapped data.
+func (e *Entry) readFull(VA uint64, size uint64) ([]byte, error) {
+	if size == 0 {
+		return nil, nil
+	}
+	if size > maxSubtableSize || VA+size < VA {
+		return nil, fmt.Errorf("Bad size %d at 0x%x", size, VA)
+	}
+	if last, err := e.raw.read_memory(VA+size-1, 1); err != nil || len(last) == 0 {
+		return nil, fmt.Errorf("0x%x-0x%x isn't mapped", VA, VA+size)
+	}
+	var data []byte
+	for uint64(len(data)) < size {
+		chunk, err := e.raw.read_memory(VA+uint64(len(data)), size-uint64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		if len(chunk) == 0 {
+			return nil, fmt.Errorf("Failed to read memory")
+		}
+		data = append(data, chunk...)
+	}
+	return data, nil
+}
+
+// FindEmbeddedFS scans the initialized data of the module for embed.FS variables. embed.FS is a *[]file the compiler points at
+// a <variable>.files symbol in rodata holding the slice header, then the array it points at:
+//
+//	type FS struct {
+//		files *[]file
+//	}
+//
+//	type file struct {
+//		name string
+//		data string
+//		hash [16]byte // truncated SHA256 hash
+//	}
+//
+// A pointer to a slice header whose array starts right after it, with a len equal to its cap, is a candidate. It's an embed.FS
+// when one of its files has a valid name and the truncated SHA256 of its data.
+func (e *Entry) FindEmbeddedFS(moduleData *ModuleData, is64bit bool, littleendian bool) ([]EmbeddedFS, error) {
+	var byteOrder binary.ByteOrder = binary.BigEndian
+	if littleendian {
+		byteOrder = binary.LittleEndian
+	}
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+	word := func(data []byte) uint64 {
+		if is64bit {
+			return byteOrder.Uint64(data)
+		}
+		return uint64(byteOrder.Uint32(data))
+	}
+
+	var results []EmbeddedFS
+	ranges := [][2]uint64{{moduleData.Noptrdata, moduleData.Enoptrdata}, {moduleData.Data, moduleData.Edata}}
+	for _, r := range ranges {
+		if r[0] == 0 || r[1] <= r[0] {
+			continue
+		}
+		data, err := e.raw.read_memory(r[0], r[1]-r[0])
+		if err != nil {
+			continue
+		}
+		for off := uint64(0); off+ptrSize <= uint64(len(data)); off += ptrSize {
+			filesVA := word(data[off:])
+			if filesVA == 0 || filesVA%ptrSize != 0 {
+				continue
+			}
+			header, err := e.raw.read_memory(filesVA, 3*ptrSize)
+			if err != nil || uint64(len(header)) < 3*ptrSize {
+				continue
+			}
+			array, count := word(header), word(header[ptrSize:])
+			if array != filesVA+3*ptrSize || count != word(header[2*ptrSize:]) || count == 0 || count > maxEmbeddedFiles {
+				continue
+			}
+			if embedded, ok := e.readEmbeddedFS(array, count, ptrSize, word); ok {
+				embedded.VA, embedded.FilesVA = r[0]+off, filesVA
+				results = append(results, embedded)
+			}
+		}
+	}
+	return results, nil
+}
+
+// readEmbeddedFS decodes the count files at array, ok is false when none of them is an embed file
+func (e *Entry) readEmbeddedFS(array uint64, count uint64, ptrSize uint64, word func([]byte) uint64) (EmbeddedFS, bool) {
+	var embedded EmbeddedFS
+	entrySize := 4*ptrSize + 16
+	table, err := e.readFull(array, count*entrySize)
+	if err != nil {
+		return embedded, false
+	}
+
+	valid := false
+	for i := uint64(0); i < count; i++ {
+		entry := table[i*entrySize : (i+1)*entrySize]
+		nameVA, nameLen := word(entry), word(entry[ptrSize:])
+		dataVA, dataLen := word(entry[2*ptrSize:]), word(entry[3*ptrSize:])
+		hash := entry[4*ptrSize:]
+
+		if nameLen == 0 || nameLen > 4096 {
+			embedded.Warnings = append(embedded.Warnings, fmt.Sprintf("file %d: bad name length %d", i, nameLen))
+			continue
+		}
+		name, err := e.readFull(nameVA, nameLen)
+		if err != nil || !utf8.Valid(name) || !fs.ValidPath(strings.TrimSuffix(string(name), "/")) {
+			embedded.Warnings = append(embedded.Warnings, fmt.Sprintf("file %d: bad name at 0x%x", i, nameVA))
+			continue
+		}
+		file := EmbeddedFile{Name: string(name), Size: dataLen}
+		if strings.HasSuffix(file.Name, "/") {
+			embedded.Files = append(embedded.Files, file)
+			continue
+		}
+
+		// an empty file has no data
+		if dataLen > 0 {
+			content, err := e.readFull(dataVA, dataLen)
+			if err != nil {
+				embedded.Warnings = append(embedded.Warnings, fmt.Sprintf("%s: data at 0x%x of %d bytes can't be read", file.Name, dataVA, dataLen))
+				continue
+			}
+			file.VA, file.Data = dataVA, content
+		}
+		sum := sha256.Sum256(file.Data)
+		file.SHA256 = hex.EncodeToString(sum[:])
+		// the toolchains that hash with cmd/internal/notsha256 write the complement
+		var complement [16]byte
+		for i := range complement {
+			complement[i] = ^sum[i]
+		}
+		if bytes.Equal(sum[:16], hash) || bytes.Equal(complement[:], hash) {
+			valid = true
+		} else {
+			embedded.Warnings = append(embedded.Warnings, fmt.Sprintf("%s: the data doesn't match its hash", file.Name))
+		}
+		embedded.Files = append(embedded.Files, file)
+	}
+	return embedded, valid
+}
@@ -0,0 +1,96 @@
+package objfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestFindEmbeddedFS(t *testing.T) {
+	// the variable at 0x1000, the <variable>.files slice header at 0x2000 and its array, the strings from 0x3000
+	const base = 0x400000
+	image := make([]byte, 0x4000)
+	put := func(off uint64, v uint64) { binary.LittleEndian.PutUint64(image[off:], v) }
+	strs := uint64(0x3000)
+	str := func(s string) (uint64, uint64) {
+		va := base + strs
+		strs += uint64(copy(image[strs:], s))
+		return va, uint64(len(s))
+	}
+
+	put(0x1000, base+0x2000)
+	files := []struct {
+		name, data string
+		hash       func([32]byte) []byte
+	}{
+		{"assets/", "", nil},
+		{"assets/config.json", `{"c2": "example.com"}`, func(sum [32]byte) []byte { return sum[:16] }},
+		// notsha256
+		{"assets/run.sh", "#!/bin/sh", func(sum [32]byte) []byte {
+			for i := range sum {
+				sum[i] = ^sum[i]
+			}
+			return sum[:16]
+		}},
+		{"assets/tampered", "data", func(sum [32]byte) []byte { return make([]byte, 16) }},
+		{"../escape", "x", func(sum [32]byte) []byte { return sum[:16] }},
+		// a corrupt length past the end of the image
+		{"assets/huge", "", nil},
+	}
+	put(0x2000, base+0x2018)
+	put(0x2008, uint64(len(files)+1))
+	put(0x2010, uint64(len(files)+1))
+	for i, file := range files {
+		entry := uint64(0x2018 + i*48)
+		nameVA, nameLen := str(file.name)
+		put(entry, nameVA)
+		put(entry+8, nameLen)
+		if len(file.data) > 0 {
+			dataVA, dataLen := str(file.data)
+			put(entry+16, dataVA)
+			put(entry+24, dataLen)
+		}
+		if file.hash != nil {
+			copy(image[entry+32:], file.hash(sha256.Sum256([]byte(file.data))))
+		}
+	}
+	put(uint64(0x2018+(len(files)-1)*48+16), base+0x3000)
+	put(uint64(0x2018+(len(files)-1)*48+24), 1<<62)
+	// a name outside the image
+	put(uint64(0x2018+len(files)*48), 0x900000)
+	put(uint64(0x2018+len(files)*48+8), 4)
+
+	f, err := OpenImage(bytes.NewReader(image), base, "amd64")
+	if err != nil {
+		t.Fatalf("failed to open the image: %s", err)
+	}
+	found, err := f.FindEmbeddedFS(&ModuleData{Data: base + 0x1000, Edata: base + 0x1010}, true, true)
+	if err != nil || len(found) != 1 {
+		t.Fatalf("expected one embed.FS, got %+v %v", found, err)
+	}
+	embedded := found[0]
+	if embedded.VA != base+0x1000 || embedded.FilesVA != base+0x2000 {
+		t.Errorf("expected the variable at 0x%x, got %+v", base+0x1000, embedded)
+	}
+	var names []string
+	for _, file := range embedded.Files {
+		names = append(names, file.Name)
+	}
+	if strings.Join(names, " ") != "assets/ assets/config.json assets/run.sh assets/tampered" {
+		t.Errorf("expected the bad names skipped, got %v", names)
+	}
+	if string(embedded.Files[1].Data) != `{"c2": "example.com"}` || embedded.Files[1].Size != 21 || len(embedded.Files[1].SHA256) != 64 {
+		t.Errorf("expected the config's data, got %+v", embedded.Files[1])
+	}
+	if len(embedded.Warnings) != 4 || !strings.Contains(embedded.Warnings[0], "assets/tampered") || !strings.Contains(embedded.Warnings[2], "assets/huge") {
+		t.Errorf("expected warnings for the hash, the two names and the length, got %v", embedded.Warnings)
+	}
+
+	// a pointer to the array rather than the slice header
+	found, _ = f.FindEmbeddedFS(&ModuleData{Data: base + 0x2000, Edata: base + 0x2008}, true, true)
+	if len(found) != 0 {
+		t.Errorf("expected no embed.FS, got %+v", found)
+	}
+}
@@ -0,0 +1,132 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+	"github.com/mandiant/GoReSym/debug/pe"
+)
+
+// the longest exported name read, the loader has no limit but a longer one is garbage
+const maxExportName = 256
+
+// the most exports read from a PE export directory, a larger count is garbage
+const maxPEExports = 0x10000
+
+// Export is a function the file exports to the dynamic loader, the C entry points of a c-shared library
+type Export struct {
+	Name string
+	VA   uint64
+}
+
+// parsePEExports parses the export directory dir of an image at imageBase, read reads size bytes at an RVA.
+// Exports forwarded to another DLL point back into the directory, they have no code in the image and are left out.
+func parsePEExports(read func(rva uint32, size uint32) ([]byte, error), dir pe.DataDirectory, imageBase uint64) ([]Export, error) {
+	header, err := read(dir.VirtualAddress, 40)
+	if err != nil || len(header) < 40 {
+		return nil, fmt.Errorf("export directory at rva 0x%x doesn't read", dir.VirtualAddress)
+	}
+	functionCount := binary.LittleEndian.Uint32(header[20:])
+	nameCount := binary.LittleEndian.Uint32(header[24:])
+	functionsRVA := binary.LittleEndian.Uint32(header[28:])
+	namesRVA := binary.LittleEndian.Uint32(header[32:])
+	ordinalsRVA := binary.LittleEndian.Uint32(header[36:])
+	if functionCount > maxPEExports || nameCount > maxPEExports {
+		return nil, fmt.Errorf("export directory has %d functions and %d names", functionCount, nameCount)
+	}
+
+	functions, err := read(functionsRVA, 4*functionCount)
+	if err != nil || uint32(len(functions)) != 4*functionCount {
+		return nil, fmt.Errorf("export address table at rva 0x%x doesn't read", functionsRVA)
+	}
+	names, err := read(namesRVA, 4*nameCount)
+	if err != nil || uint32(len(names)) != 4*nameCount {
+		return nil, fmt.Errorf("export name table at rva 0x%x doesn't read", namesRVA)
+	}
+	ordinals, err := read(ordinalsRVA, 2*nameCount)
+	if err != nil || uint32(len(ordinals)) != 2*nameCount {
+		return nil, fmt.Errorf("export ordinal table at rva 0x%x doesn't read", ordinalsRVA)
+	}
+
+	var exports []Export
+	for i := uint32(0); i < nameCount; i++ {
+		ordinal := uint32(binary.LittleEndian.Uint16(ordinals[2*i:]))
+		if ordinal >= functionCount {
+			continue
+		}
+		rva := binary.LittleEndian.Uint32(functions[4*ordinal:])
+		if rva == 0 || (rva >= dir.VirtualAddress && rva < dir.VirtualAddress+dir.Size) {
+			continue
+		}
+
+		name, err := read(binary.LittleEndian.Uint32(names[4*i:]), maxExportName)
+		if err != nil {
+			continue
+		}
+		if end := bytes.IndexByte(name, 0); end >= 0 {
+			name = name[:end]
+		}
+		if len(name) > 0 {
+			exports = append(exports, Export{Name: string(name), VA: imageBase + uint64(rva)})
+		}
+	}
+	return exports, nil
+}
+
+// Exports lists the functions the file exports by name: the PE export directory, the defined ELF dynamic symbols and the external
+// Mach-O symbols. Returns nil for executables that export nothing and for other file types.
+func (e *Entry) Exports() ([]Export, error) {
+	switch f := e.raw.(type) {
+	case *peFile:
+		var imageBase uint64
+		var dirs []pe.DataDirectory
+		switch oh := f.pe.OptionalHeader.(type) {
+		case *pe.OptionalHeader32:
+			imageBase, dirs = uint64(oh.ImageBase), oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+		case *pe.OptionalHeader64:
+			imageBase, dirs = oh.ImageBase, oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+		}
+		if len(dirs) <= pe.IMAGE_DIRECTORY_ENTRY_EXPORT || dirs[pe.IMAGE_DIRECTORY_ENTRY_EXPORT].Size == 0 {
+			return nil, nil
+		}
+		read := func(rva uint32, size uint32) ([]byte, error) {
+			return f.read_memory(imageBase+uint64(rva), uint64(size))
+		}
+		return parsePEExports(read, dirs[pe.IMAGE_DIRECTORY_ENTRY_EXPORT], imageBase)
+	case *elfFile:
+		syms, err := f.elf.DynamicSymbols()
+		if err != nil {
+			return nil, nil
+		}
+		var exports []Export
+		for _, sym := range syms {
+			bind := elf.ST_BIND(sym.Info)
+			if sym.Section != elf.SHN_UNDEF && elf.ST_TYPE(sym.Info) == elf.STT_FUNC && (bind == elf.STB_GLOBAL || bind == elf.STB_WEAK) {
+				exports = append(exports, Export{Name: sym.Name, VA: sym.Value + f.rebaseDelta})
+			}
+		}
+		return exports, nil
+	case *machoFile:
+		if f.macho.Symtab == nil {
+			return nil, nil
+		}
+		const (
+			N_STAB = 0xe0
+			N_EXT  = 0x01
+			N_TYPE = 0x0e
+			N_SECT = 0x0e
+		)
+		var exports []Export
+		for _, sym := range f.macho.Symtab.Syms {
+			if sym.Type&N_STAB == 0 && sym.Type&N_EXT != 0 && sym.Type&N_TYPE == N_SECT {
+				exports = append(exports, Export{Name: strings.TrimPrefix(sym.Name, "_"), VA: sym.Value})
+			}
+		}
+		return exports, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,51 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/mandiant/GoReSym/debug/pe"
+)
+
+func TestParsePEExports(t *testing.T) {
+	// the directory at rva 0x1000: three names for three functions in reverse order, Sleep is forwarded to another DLL
+	image := make([]byte, 0x1200)
+	directory := image[0x1000:]
+	binary.LittleEndian.PutUint32(directory[20:], 3)      // NumberOfFunctions
+	binary.LittleEndian.PutUint32(directory[24:], 3)      // NumberOfNames
+	binary.LittleEndian.PutUint32(directory[28:], 0x1040) // AddressOfFunctions
+	binary.LittleEndian.PutUint32(directory[32:], 0x1060) // AddressOfNames
+	binary.LittleEndian.PutUint32(directory[36:], 0x1080) // AddressOfNameOrdinals
+
+	binary.LittleEndian.PutUint32(image[0x1040:], 0x2000)
+	binary.LittleEndian.PutUint32(image[0x1044:], 0x2040)
+	binary.LittleEndian.PutUint32(image[0x1048:], 0x10a0) // forwarder string inside the directory
+	for i, name := range []string{"Sleep", "Greet", "Add"} {
+		binary.LittleEndian.PutUint32(image[0x1060+4*i:], uint32(0x1100+0x20*i))
+		binary.LittleEndian.PutUint16(image[0x1080+2*i:], uint16(2-i))
+		copy(image[0x1100+0x20*i:], name+"\x00")
+	}
+	copy(image[0x10a0:], "KERNEL32.Sleep\x00")
+
+	read := func(rva uint32, size uint32) ([]byte, error) {
+		if uint64(rva) >= uint64(len(image)) {
+			return nil, fmt.Errorf("rva 0x%x outside the image", rva)
+		}
+		return image[rva:min(uint64(rva)+uint64(size), uint64(len(image)))], nil
+	}
+	exports, err := parsePEExports(read, pe.DataDirectory{VirtualAddress: 0x1000, Size: 0x100}, 0x180000000)
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+
+	expected := []Export{{"Greet", 0x180002040}, {"Add", 0x180002000}}
+	if fmt.Sprint(exports) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, exports)
+	}
+
+	binary.LittleEndian.PutUint32(directory[24:], maxPEExports+1)
+	if _, err := parsePEExports(read, pe.DataDirectory{VirtualAddress: 0x1000, Size: 0x100}, 0x180000000); err == nil {
+		t.Errorf("expected a bogus name count to fail")
+	}
+}
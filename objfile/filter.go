@@ -0,0 +1,84 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"regexp"
+	"strings"
+)
+
+// NameFilter picks names by RE2 patterns: a name is kept when it matches an include pattern, or there are none, and matches no
+// exclude pattern. Excludes win over includes.
+type NameFilter struct {
+	Include []*regexp.Regexp
+	Exclude []*regexp.Regexp
+}
+
+// Keep reports whether name passes the filter, a nil filter keeps everything
+func (f *NameFilter) Keep(name string) bool {
+	if f == nil {
+		return true
+	}
+	for _, re := range f.Exclude {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, re := range f.Include {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the filter drops anything at all
+func (f *NameFilter) Active() bool {
+	return f != nil && len(f.Include)+len(f.Exclude) > 0
+}
+
+// Patterns is a repeatable flag of RE2 patterns, ex: -exclude-type '^runtime\.' -exclude-type '^internal/'
+type Patterns []*regexp.Regexp
+
+func (p *Patterns) String() string {
+	var patterns []string
+	for _, re := range *p {
+		patterns = append(patterns, re.String())
+	}
+	return strings.Join(patterns, ", ")
+}
+
+func (p *Patterns) Set(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	*p = append(*p, re)
+	return nil
+}
+
+// SetTypeFilter drops the types whose names don't pass filter while parsing, before their structure is read, so the types only
+// they point to aren't parsed either. The types an interface table names are filtered the same, a table with either dropped is
+// left out. nil, the default, keeps every type. Set it before parsing types.
+func (e *Entry) SetTypeFilter(filter *NameFilter) {
+	e.typeFilter = filter
+}
+
+// keepType reports whether the type at address named name passes the type filter, counting those that don't once each
+func (e *Entry) keepType(address uint64, name string) bool {
+	if e.typeFilter.Keep(name) {
+		return true
+	}
+	if e.filteredTypes == nil {
+		e.filteredTypes = make(map[uint64]bool)
+	}
+	e.filteredTypes[address] = true
+	return false
+}
+
+// FilteredTypes is how many distinct types the type filter dropped so far
+func (e *Entry) FilteredTypes() int {
+	return len(e.filteredTypes)
+}
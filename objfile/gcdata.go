@@ -0,0 +1,264 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"unsafe"
+)
+
+const (
+	// the bit of the kind telling that gcdata is a GC program rather than a bitmap, for the types too large for one up to Go 1.23
+	kindGCProg = 1 << 6
+	// from Go 1.24 on the types too large for a bitmap have no program, their gcdata points at where the runtime keeps a pointer to the
+	// bitmap it builds from their fields and elements the first time it's needed
+	tflagGCMaskOnDemand tflag = 1 << 4
+
+	// the most words a layout is decoded for, 128MB of pointers on 64 bit
+	maxGCWords = 1 << 24
+	// the most pointer offsets GCLayout lists, the bitmap has them all
+	maxGCPointers = 1 << 12
+	// the deepest nesting of arrays and structs followed to build an on-demand bitmap
+	maxGCDepth = 64
+)
+
+// GCLayout is which words of a value of a type the garbage collector scans as pointers, as recorded for the type up to its PtrBytes
+type GCLayout struct {
+	// where it's from: bitmap for the gcdata bitmap of a small type, program for the GC program of a large one up to Go 1.23, layout
+	// for a large one from Go 1.24 on, built from its fields and elements like the runtime does, and runtime for the one the runtime
+	// built, ex: in a dump.
+	Source string
+	// a bit per word in hex, the low bit of the first byte is the first word like in the runtime's bitmaps
+	Bitmap string
+	// the offsets in bytes of the pointer words, the first maxGCPointers of them
+	Pointers []uint64
+}
+
+// gcLayout decodes the pointer layout of t from its gcdata, nil for a type without pointers or one that doesn't decode
+func (e *Entry) gcLayout(t *Type, is64bit bool, littleendian bool) *GCLayout {
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+	words := t.PtrBytes / ptrSize
+	if words == 0 || words > maxGCWords || t.gcdata == 0 {
+		return nil
+	}
+
+	var bits []byte
+	var source string
+	var err error
+	switch {
+	case t.flags&tflagGCMaskOnDemand != 0:
+		// the pointer is zero in a file, a dump of a process that needed the bitmap has it
+		if built, readErr := e.ReadPointerSizeMem(t.gcdata, is64bit, littleendian); readErr == nil && built != 0 {
+			bits, err = e.raw.read_memory(built, (words+7)/8)
+			source = "runtime"
+		} else {
+			bits = make([]byte, (words+7)/8)
+			err = e.buildGCMask(t.VA, bits, 0, ptrSize, littleendian, 0)
+			source = "layout"
+		}
+	case t.kindFlags&kindGCProg != 0:
+		// the program follows its length
+		var header []byte
+		if header, err = e.raw.read_memory(t.gcdata, 4); err == nil && len(header) == 4 {
+			var order binary.ByteOrder = binary.LittleEndian
+			if !littleendian {
+				order = binary.BigEndian
+			}
+			var prog []byte
+			if prog, err = e.raw.read_memory(t.gcdata+4, uint64(order.Uint32(header))); err == nil {
+				bits, err = runGCProg(prog, words)
+			}
+		}
+		source = "program"
+	default:
+		bits, err = e.raw.read_memory(t.gcdata, (words+7)/8)
+		source = "bitmap"
+	}
+	if err != nil || uint64(len(bits)) < (words+7)/8 {
+		return nil
+	}
+	return newGCLayout(source, bits, words, ptrSize)
+}
+
+// newGCLayout describes the first words bits of bits
+func newGCLayout(source string, bits []byte, words uint64, ptrSize uint64) *GCLayout {
+	bits = append([]byte(nil), bits[:(words+7)/8]...)
+	// what's past the last word isn't the type's
+	if words%8 != 0 {
+		bits[len(bits)-1] &= byte(1)<<(words%8) - 1
+	}
+	layout := &GCLayout{Source: source, Bitmap: hex.EncodeToString(bits)}
+	for word := uint64(0); word < words && len(layout.Pointers) < maxGCPointers; word++ {
+		if bits[word/8]&(1<<(word%8)) != 0 {
+			layout.Pointers = append(layout.Pointers, word*ptrSize)
+		}
+	}
+	return layout
+}
+
+// buildGCMask sets the bits of the pointer words of the type at VA in bits from word on, like the runtime's buildGCMask: a type with
+// a bitmap of its own is copied, an array repeats its element and a struct places its fields. It only runs for Go 1.24 on, whose
+// array and struct types are laid out like those of 1.22.
+func (e *Entry) buildGCMask(VA uint64, bits []byte, word uint64, ptrSize uint64, littleendian bool, depth int) error {
+	if depth > maxGCDepth {
+		return fmt.Errorf("the types nest deeper than %d at 0x%x", maxGCDepth, VA)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if !littleendian {
+		order = binary.BigEndian
+	}
+	baseSize := uint64(unsafe.Sizeof(Rtype114_115_116_117_118_32{}))
+	if ptrSize == 8 {
+		baseSize = uint64(unsafe.Sizeof(Rtype114_115_116_117_118_64{}))
+	}
+	// the rtype and the first three words after it: an array's elem, slice and len, a struct's pkgPath and fields
+	data, err := e.raw.read_memory(VA, baseSize+4*ptrSize)
+	if err != nil || uint64(len(data)) < baseSize+4*ptrSize {
+		return fmt.Errorf("the type at 0x%x doesn't read", VA)
+	}
+	header, ok := decodeRtypeHeader(data, ptrSize, order)
+	if !ok {
+		return fmt.Errorf("the type at 0x%x doesn't decode", VA)
+	}
+	readWord := func(data []byte) uint64 {
+		if ptrSize == 8 {
+			return order.Uint64(data)
+		}
+		return uint64(order.Uint32(data))
+	}
+	limit := uint64(len(bits)) * 8
+	if header.ptrdata == 0 || word >= limit {
+		return nil
+	}
+
+	if header.tflag&tflagGCMaskOnDemand == 0 {
+		words := header.ptrdata / ptrSize
+		mask, err := e.raw.read_memory(header.gcdata, (words+7)/8)
+		if err != nil || uint64(len(mask)) < (words+7)/8 {
+			return fmt.Errorf("the bitmap of the type at 0x%x doesn't read", VA)
+		}
+		for i := uint64(0); i < words && word+i < limit; i++ {
+			if mask[i/8]&(1<<(i%8)) != 0 {
+				bits[(word+i)/8] |= 1 << ((word + i) % 8)
+			}
+		}
+		return nil
+	}
+
+	extra := data[baseSize:]
+	switch header.kind {
+	case Array:
+		// type ArrayType struct {
+		// 	Type
+		// 	Elem  *Type
+		// 	Slice *Type
+		// 	Len   uintptr
+		// }
+		elem, length := readWord(extra), readWord(extra[2*ptrSize:])
+		elemData, err := e.raw.read_memory(elem, baseSize)
+		if err != nil || uint64(len(elemData)) < baseSize {
+			return fmt.Errorf("the element of the array at 0x%x doesn't read", VA)
+		}
+		elemHeader, ok := decodeRtypeHeader(elemData, ptrSize, order)
+		if !ok || elemHeader.size < ptrSize {
+			return fmt.Errorf("the element of the array at 0x%x doesn't decode", VA)
+		}
+		// the array has pointers so its element does, a word or more of each, the elements past the bitmap aren't the type's
+		for i := uint64(0); i < length && word+i*(elemHeader.size/ptrSize) < limit; i++ {
+			if err := e.buildGCMask(elem, bits, word+i*(elemHeader.size/ptrSize), ptrSize, littleendian, depth+1); err != nil {
+				return err
+			}
+		}
+	case Struct:
+		// type StructType struct {
+		// 	Type
+		// 	PkgPath Name
+		// 	Fields  []StructField
+		// }
+		// type StructField struct {
+		// 	Name   Name
+		// 	Typ    *Type
+		// 	Offset uintptr
+		// }
+		fields, count := readWord(extra[ptrSize:]), readWord(extra[2*ptrSize:])
+		if count > maxGCWords {
+			return fmt.Errorf("the struct at 0x%x has %d fields", VA, count)
+		}
+		for i := uint64(0); i < count; i++ {
+			field, err := e.raw.read_memory(fields+i*3*ptrSize, 3*ptrSize)
+			if err != nil || uint64(len(field)) < 3*ptrSize {
+				return fmt.Errorf("the field %d of the struct at 0x%x doesn't read", i, VA)
+			}
+			if err := e.buildGCMask(readWord(field[ptrSize:]), bits, word+readWord(field[2*ptrSize:])/ptrSize, ptrSize, littleendian, depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("the %s at 0x%x builds its bitmap on demand, only arrays and structs do", header.kind, VA)
+	}
+	return nil
+}
+
+// runGCProg runs the GC program prog for the first words words of the type, like the runtime's runGCProg. Each instruction is a
+// byte: 0 ends the program, 0x00|n emits the n bits that follow it, and 0x80|n repeats the last n bits, varint n when it's 0, a
+// varint count of times.
+func runGCProg(prog []byte, words uint64) ([]byte, error) {
+	bits := make([]byte, (words+7)/8)
+	var emitted uint64
+	bit := func(i uint64) byte {
+		if i >= words {
+			return 0
+		}
+		return bits[i/8] >> (i % 8) & 1
+	}
+	emit := func(b byte) {
+		if emitted < words {
+			bits[emitted/8] |= b << (emitted % 8)
+		}
+		emitted++
+	}
+	for i := 0; i < len(prog); {
+		op := prog[i]
+		i++
+		if op == 0 {
+			return bits, nil
+		}
+		n := uint64(op & 0x7f)
+		if op&0x80 == 0 {
+			if uint64(len(prog)-i) < (n+7)/8 {
+				return nil, fmt.Errorf("the literal of %d bits at 0x%x runs past the program", n, i-1)
+			}
+			for j := uint64(0); j < n; j++ {
+				emit(prog[i+int(j/8)] >> (j % 8) & 1)
+			}
+			i += int((n + 7) / 8)
+			continue
+		}
+		if n == 0 {
+			var size int
+			if n, size = binary.Uvarint(prog[i:]); size <= 0 {
+				return nil, fmt.Errorf("the repeat at 0x%x has no bit count", i-1)
+			}
+			i += size
+		}
+		count, size := binary.Uvarint(prog[i:])
+		if size <= 0 {
+			return nil, fmt.Errorf("the repeat at 0x%x has no count", i-1)
+		}
+		i += size
+		if n == 0 || n > emitted || count > maxGCWords/n {
+			return nil, fmt.Errorf("the repeat at 0x%x of %d bits %d times doesn't fit the %d bits emitted", i-1, n, count, emitted)
+		}
+		// the bits past the type's words aren't kept, only counted
+		total := n * count
+		for ; total > 0 && emitted < words; total-- {
+			emit(bit(emitted - n))
+		}
+		emitted += total
+	}
+	return nil, fmt.Errorf("the program doesn't end")
+}
@@ -0,0 +1,98 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunGCProg(t *testing.T) {
+	cases := []struct {
+		name     string
+		prog     []byte
+		words    uint64
+		pointers []uint64
+	}{
+		// a pointer, a scalar and a pointer, then the last two words twice more
+		{"repeat", []byte{0x03, 0x05, 0x82, 0x02, 0x00}, 7, []uint64{0, 16, 32, 48}},
+		// [3]*T, the repeat count is a varint
+		{"varint", []byte{0x01, 0x01, 0x81, 0x02, 0x00}, 3, []uint64{0, 8, 16}},
+		// [2]struct{ p *int; x int }, the bit count of the repeat is a varint too
+		{"varint bits", []byte{0x02, 0x01, 0x80, 0x02, 0x01, 0x00}, 3, []uint64{0, 16}},
+	}
+	for _, c := range cases {
+		bits, err := runGCProg(c.prog, c.words)
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err)
+			continue
+		}
+		if layout := newGCLayout("program", bits, c.words, 8); !reflect.DeepEqual(layout.Pointers, c.pointers) {
+			t.Errorf("%s: expected pointers at %v, got %v", c.name, c.pointers, layout.Pointers)
+		}
+	}
+
+	// [100000]unsafe.Pointer, as the compiler writes it
+	prog := append([]byte{0x01, 0x01, 0x81}, binary.AppendUvarint(nil, 99999)...)
+	bits, err := runGCProg(append(prog, 0x00), 100000)
+	if err != nil {
+		t.Fatalf("[100000]unsafe.Pointer: %s", err)
+	}
+	if layout := newGCLayout("program", bits, 100000, 8); len(layout.Pointers) != maxGCPointers || layout.Bitmap != strings.Repeat("ff", 12500) {
+		t.Errorf("expected every word of [100000]unsafe.Pointer a pointer, got %d and %s", len(layout.Pointers), layout.Bitmap[:16])
+	}
+
+	if _, err := runGCProg([]byte{0x81, 0x02, 0x00}, 4); err == nil {
+		t.Errorf("expected a repeat of bits not emitted yet to fail")
+	}
+	if _, err := runGCProg([]byte{0x01, 0x01}, 1); err == nil {
+		t.Errorf("expected a program without its end to fail")
+	}
+}
+
+func TestBuildGCMask(t *testing.T) {
+	// the types of Go 1.24 on, a struct too large for a bitmap of its own, for the test one with a bitmap the runtime builds:
+	//
+	//	type pair struct{ n int; p *int }       // at 0x1000, its bitmap 0b10 at 0x1800
+	//	type pairs [2]pair                      // at 0x1100, on demand
+	//	type outer struct{ first pair; rest pairs } // at 0x1200, on demand, its fields at 0x1300
+	memory := make([]byte, 0x1000)
+	rtype := func(at uint64, size uint64, ptrdata uint64, flags tflag, kind Kind, gcdata uint64) {
+		data := memory[at-0x1000:]
+		binary.LittleEndian.PutUint64(data, size)
+		binary.LittleEndian.PutUint64(data[8:], ptrdata)
+		data[20], data[21], data[22], data[23] = byte(flags), 8, 8, byte(kind)
+		binary.LittleEndian.PutUint64(data[32:], gcdata)
+	}
+	rtype(0x1000, 16, 16, 0, Struct, 0x1800)
+	memory[0x800] = 0b10
+	rtype(0x1100, 32, 32, tflagGCMaskOnDemand, Array, 0x1900)
+	binary.LittleEndian.PutUint64(memory[0x100+48:], 0x1000)
+	binary.LittleEndian.PutUint64(memory[0x100+64:], 2)
+	rtype(0x1200, 48, 48, tflagGCMaskOnDemand, Struct, 0x1908)
+	binary.LittleEndian.PutUint64(memory[0x200+56:], 0x1300)
+	binary.LittleEndian.PutUint64(memory[0x200+64:], 2)
+	for i, field := range [][2]uint64{{0x1000, 0}, {0x1100, 16}} {
+		binary.LittleEndian.PutUint64(memory[0x300+i*24+8:], field[0])
+		binary.LittleEndian.PutUint64(memory[0x300+i*24+16:], field[1])
+	}
+	e := &Entry{raw: &dumpFile{format: "raw", base: 0x1000, size: uint64(len(memory)), regions: []dumpRegion{{name: "image", addr: 0x1000, data: memory}}}}
+
+	outer := &Type{VA: 0x1200, PtrBytes: 48, flags: tflagGCMaskOnDemand, gcdata: 0x1908}
+	layout := e.gcLayout(outer, true, true)
+	if layout == nil || layout.Source != "layout" || !reflect.DeepEqual(layout.Pointers, []uint64{8, 24, 40}) || layout.Bitmap != "2a" {
+		t.Errorf("expected the pointers of every pair, got %+v", layout)
+	}
+
+	// once the runtime built it a dump has the bitmap
+	binary.LittleEndian.PutUint64(memory[0x908:], 0x1a00)
+	memory[0xa00] = 0b101010
+	if layout := e.gcLayout(outer, true, true); layout == nil || layout.Source != "runtime" || layout.Bitmap != "2a" {
+		t.Errorf("expected the bitmap the runtime built, got %+v", layout)
+	}
+
+	pair := &Type{VA: 0x1000, PtrBytes: 16, gcdata: 0x1800}
+	if layout := e.gcLayout(pair, true, true); layout == nil || layout.Source != "bitmap" || !reflect.DeepEqual(layout.Pointers, []uint64{8}) {
+		t.Errorf("expected the bitmap of pair, got %+v", layout)
+	}
+}
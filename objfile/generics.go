@@ -0,0 +1,194 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"strings"
+)
+
+const shapePrefix = "go.shape."
+
+func isIdentByte(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+// matchingBracket returns the index of the ']' closing the '[' at open, or -1
+func matchingBracket(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTypeArgs splits a type argument list on its top level commas. Nested brackets, parens, and braces are kept intact.
+func splitTypeArgs(s string) []string {
+	var args []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[', '(', '{':
+			depth++
+		case ']', ')', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(args, strings.TrimSpace(s[start:]))
+}
+
+// findInstantiation locates the first generic instantiation bracket in a type name.
+// A '[' only starts a type argument list when it directly follows a type name, that excludes slices, arrays, and map keys.
+func findInstantiation(name string) (open int, close int) {
+	for i := 1; i < len(name); i++ {
+		if name[i] != '[' || !isIdentByte(name[i-1]) {
+			continue
+		}
+
+		wordStart := i
+		for wordStart > 0 && isIdentByte(name[wordStart-1]) {
+			wordStart--
+		}
+		if name[wordStart:i] == "map" {
+			continue
+		}
+
+		if end := matchingBracket(name, i); end != -1 {
+			return i, end
+		}
+	}
+	return -1, -1
+}
+
+// collapseShape turns 'go.shape.int_0' (1.18) or 'go.shape.int' (>= 1.19) into 'int'
+func collapseShape(arg string) string {
+	arg = strings.ReplaceAll(arg, shapePrefix, "")
+	if idx := strings.LastIndexByte(arg, '_'); idx != -1 && idx+1 < len(arg) {
+		allDigits := true
+		for _, c := range arg[idx+1:] {
+			if c < '0' || c > '9' {
+				allDigits = false
+				break
+			}
+		}
+		if allDigits {
+			arg = arg[:idx]
+		}
+	}
+	return arg
+}
+
+// demangle_generic_name cleans up the name of a generic instantiation such as main.Stack[go.shape.int_0].
+// It returns the readable name (empty if nothing changed), the type arguments of the first instantiation, and a note describing any GC shape or elided arguments.
+func demangle_generic_name(name string) (demangled string, typeArgs []string, note string) {
+	open, close := findInstantiation(name)
+	if open == -1 {
+		return "", nil, ""
+	}
+
+	var notes []string
+	inner := name[open+1 : close]
+	if inner == "..." {
+		// the compiler elides the arguments in some symbol names, nothing to recover
+		return "", nil, "type arguments elided by the compiler"
+	}
+
+	var cleanedArgs []string
+	var shapes []string
+	for _, arg := range splitTypeArgs(inner) {
+		if strings.Contains(arg, shapePrefix) {
+			shapes = append(shapes, arg)
+			arg = collapseShape(arg)
+		}
+
+		// arguments may themselves be instantiations, ex: main.List[main.Pair[int,string]]
+		if nested, _, _ := demangle_generic_name(arg); len(nested) > 0 {
+			arg = nested
+		}
+		cleanedArgs = append(cleanedArgs, arg)
+	}
+
+	if len(shapes) > 0 {
+		notes = append(notes, "GC shape instantiation, shared by all type arguments with the same underlying shape: "+strings.Join(shapes, ", "))
+	}
+
+	// the remainder can contain more instantiations, ex: func(main.Box[int]) main.Box[string]
+	rest := name[close+1:]
+	if restDemangled, _, restNote := demangle_generic_name(rest); len(restDemangled) > 0 {
+		rest = restDemangled
+		if len(restNote) > 0 {
+			notes = append(notes, restNote)
+		}
+	}
+
+	demangled = name[:open+1] + strings.Join(cleanedArgs, ",") + "]" + rest
+	if demangled == name {
+		demangled = ""
+	}
+	return demangled, cleanedArgs, strings.Join(notes, "; ")
+}
+
+// GenericFunctionName splits the name of an instantiated function or method, ex: main.(*Stack[go.shape.int]).Push, into the name
+// without any type argument lists, main.(*Stack).Push, and the type arguments of the first list with the shapes collapsed. shape is
+// set for the code the compiler shares between every type argument of a GC shape. base is empty for a function that isn't generic.
+func GenericFunctionName(name string) (base string, typeArgs []string, shape bool) {
+	open, close := findInstantiation(name)
+	if open == -1 {
+		return "", nil, false
+	}
+	_, typeArgs, _ = demangle_generic_name(name)
+	shape = strings.Contains(name, shapePrefix)
+
+	for open != -1 {
+		base += name[:open]
+		name = name[close+1:]
+		open, close = findInstantiation(name)
+	}
+	return base + name, typeArgs, shape
+}
+
+// GenericDictionary parses the name of the symbol of a dictionary, ex: main..dict.Keys[string,int], the type arguments a shaped
+// instantiation is called with. owner is the generic function or, for the methods, the generic type it belongs to: main.Keys.
+func GenericDictionary(name string) (owner string, typeArgs []string, ok bool) {
+	idx := strings.Index(name, "..dict.")
+	if idx == -1 {
+		return "", nil, false
+	}
+	base, typeArgs, _ := GenericFunctionName(name[idx+len("..dict."):])
+	if len(base) == 0 {
+		return "", nil, false
+	}
+	return name[:idx] + "." + base, typeArgs, true
+}
+
+// GenericOwner is the generic function a generic function name from GenericFunctionName belongs to, or the generic type of a
+// method, whose dictionary they're called with. ex: main.Keys for main.Keys.func1 and main.Stack for main.(*Stack).Push.
+func GenericOwner(base string) string {
+	pkgEnd := strings.LastIndexByte(base, '/') + 1
+	dot := strings.IndexByte(base[pkgEnd:], '.')
+	if dot == -1 {
+		return base
+	}
+	pkg, rest := base[:pkgEnd+dot], base[pkgEnd+dot+1:]
+	if strings.HasPrefix(rest, "(*") {
+		if end := strings.IndexByte(rest, ')'); end != -1 {
+			return pkg + "." + rest[2:end]
+		}
+	}
+	if end := strings.IndexByte(rest, '.'); end != -1 {
+		rest = rest[:end]
+	}
+	return pkg + "." + rest
+}
@@ -0,0 +1,82 @@
+package objfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDemangleGenericName(t *testing.T) {
+	cases := []struct {
+		name      string
+		demangled string
+		typeArgs  []string
+		hasNote   bool
+	}{
+		{"main.Stack[int]", "", []string{"int"}, false},
+		{"main.Stack[go.shape.int_0]", "main.Stack[int]", []string{"int"}, true},
+		{"*main.Stack[go.shape.int]", "*main.Stack[int]", []string{"int"}, true},
+		{"main.Pair[go.shape.string_0,go.shape.*uint8_1]", "main.Pair[string,*uint8]", []string{"string", "*uint8"}, true},
+		{"main.List[main.Pair[int,go.shape.string]]", "main.List[main.Pair[int,string]]", []string{"main.Pair[int,string]"}, true},
+		{"main.Map[...]", "", nil, true},
+		{"func(main.Box[go.shape.int]) string", "func(main.Box[int]) string", []string{"int"}, true},
+		{"map[string]int", "", nil, false},
+		{"[]main.Foo", "", nil, false},
+		{"[4]int", "", nil, false},
+		{"main.Foo", "", nil, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			demangled, typeArgs, note := demangle_generic_name(c.name)
+			if demangled != c.demangled {
+				t.Errorf("expected demangled %q, got %q", c.demangled, demangled)
+			}
+
+			if !reflect.DeepEqual(typeArgs, c.typeArgs) {
+				t.Errorf("expected type args %v, got %v", c.typeArgs, typeArgs)
+			}
+
+			if (len(note) > 0) != c.hasNote {
+				t.Errorf("unexpected note %q", note)
+			}
+		})
+	}
+}
+
+func TestGenericFunctionName(t *testing.T) {
+	cases := []struct {
+		name     string
+		base     string
+		typeArgs []string
+		shape    bool
+		owner    string
+	}{
+		{"main.Sum[go.shape.int]", "main.Sum", []string{"int"}, true, "main.Sum"},
+		{"main.(*Stack[go.shape.string]).Push", "main.(*Stack).Push", []string{"string"}, true, "main.Stack"},
+		{"main.Pair[string,int].String", "main.Pair.String", []string{"string", "int"}, false, "main.Pair"},
+		{"main.Keys[go.shape.int,go.shape.*uint8].func1", "main.Keys.func1", []string{"int", "*uint8"}, true, "main.Keys"},
+		{"gopkg.in/yaml%2ev3.Map[...]", "gopkg.in/yaml%2ev3.Map", nil, false, "gopkg.in/yaml%2ev3.Map"},
+		{"sync/atomic.(*Pointer[go.shape.struct {}]).Load", "sync/atomic.(*Pointer).Load", []string{"struct {}"}, true, "sync/atomic.Pointer"},
+		{"main.main", "", nil, false, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			base, typeArgs, shape := GenericFunctionName(c.name)
+			if base != c.base || !reflect.DeepEqual(typeArgs, c.typeArgs) || shape != c.shape {
+				t.Errorf("expected %q %v %v, got %q %v %v", c.base, c.typeArgs, c.shape, base, typeArgs, shape)
+			}
+			if len(base) > 0 && GenericOwner(base) != c.owner {
+				t.Errorf("expected the owner %q, got %q", c.owner, GenericOwner(base))
+			}
+		})
+	}
+
+	owner, typeArgs, ok := GenericDictionary("main..dict.Keys[int,*main.Stack[int]]")
+	if !ok || owner != "main.Keys" || !reflect.DeepEqual(typeArgs, []string{"int", "*main.Stack[int]"}) {
+		t.Errorf("unexpected dictionary %q %v %v", owner, typeArgs, ok)
+	}
+	if _, _, ok := GenericDictionary("main.Keys[go.shape.int]"); ok {
+		t.Errorf("expected a function not to be a dictionary")
+	}
+}
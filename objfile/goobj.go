@@ -9,6 +9,7 @@
 package objfile
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -61,9 +62,13 @@ L:
 			})
 			continue
 		case archive.EntryNativeObj:
+			// the symbol index and long name table ar adds to a c-archive aren't objects
+			if isArchiveIndex(e.Name) {
+				continue
+			}
 			nr := io.NewSectionReader(f, e.Offset, e.Size)
 			for _, try := range openers {
-				if raw, err := try(nr); err == nil {
+				if raw, err := try(nr, OpenOptions{}); err == nil {
 					entries = append(entries, &Entry{
 						name: e.Name,
 						raw:  raw,
@@ -74,7 +79,16 @@ L:
 		}
 		return nil, fmt.Errorf("open %s: unrecognized archive member %s", f.Name(), e.Name)
 	}
-	return &File{f, entries}, nil
+	return &File{r: f, entries: entries, closer: f}, nil
+}
+
+// isArchiveIndex reports whether name is a member ar keeps its own tables in: the GNU symbol index and long names, the BSD symbol index
+func isArchiveIndex(name string) bool {
+	switch name {
+	case "/", "//", "/SYM64/", "__.SYMDEF", "__.SYMDEF SORTED", "__.SYMDEF_64":
+		return true
+	}
+	return false
 }
 
 func goobjName(name string, ver int) string {
@@ -355,6 +369,14 @@ func (f *goobjFile) rdata() (textStart uint64, text []byte, err error) {
 	return
 }
 
+// endian is the one of the object's architecture, nil for one unknown
+func (f *goobjFile) endian() binary.ByteOrder {
+	if f.arch == nil {
+		return nil
+	}
+	return f.arch.ByteOrder
+}
+
 func (f *goobjFile) goarch() string {
 	return f.goobj.Arch
 }
@@ -0,0 +1,308 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// the Method of a HeuristicRecovery
+const heuristicMorestack = "morestack call graph"
+
+const (
+	// the fewest stack split stubs a callee needs to be taken for runtime.morestack without its signature
+	minMorestackTails = 4
+	// the most instructions between the call to morestack and the jump back, the reloads of the register arguments
+	maxTailInstructions = 32
+	// the most instructions of a prologue before the branch to its stack split stub
+	maxPrologueInstructions = 16
+)
+
+// runtime.morestack_noctxt clears the context register and jumps to runtime.morestack: XORL DX, DX, or MOVL $0, DX from 1.17 on
+var morestackNoctxtSeeds = [][]byte{{0x31, 0xd2, 0xe9}, {0xba, 0, 0, 0, 0, 0xe9}}
+
+// HeuristicRecovery is the functions found without the pclntab, from the code alone. They're a guess, not what the pclntab records.
+type HeuristicRecovery struct {
+	Method    string   // how the starts were found
+	Morestack []uint64 // the runtime.morestack functions the prologues call when the stack is too small
+	Functions []HeuristicFunction
+}
+
+// HeuristicFunction is a function HeuristicFunctions found. It ends where the next one starts, so it can take in the padding and the
+// functions without a stack check after it.
+type HeuristicFunction struct {
+	Start      uint64
+	End        uint64
+	RVA        uint64  // of Start
+	FileOffset *uint64 // of Start, null when it isn't in the file
+	Name       string  // sub_<start> unless NamedBy tells where the name is from
+	NamedBy    string  // symbol, export, method or signature
+}
+
+// a stack split stub: the call to morestack at the end of a function, then the jump back to its start
+type stackSplitStub struct {
+	callee uint64
+	call   uint64
+	start  uint64
+	end    uint64 // of the jump
+}
+
+// stackSplitStub decodes the call at off of the text as a stack split stub: the registers reloaded after the call, then a jump back
+func decodeStackSplitStub(text []byte, textStart uint64, off int) (stackSplitStub, bool) {
+	call := textStart + uint64(off)
+	callee := uint64(int64(call) + 5 + int64(int32(binary.LittleEndian.Uint32(text[off+1:]))))
+	if callee < textStart || callee >= textStart+uint64(len(text)) {
+		return stackSplitStub{}, false
+	}
+	pc := off + 5
+	for i := 0; i < maxTailInstructions && pc < len(text); i++ {
+		inst, err := x86asm.Decode(text[pc:], 64)
+		if err != nil || inst.Len == 0 {
+			return stackSplitStub{}, false
+		}
+		switch inst.Op {
+		case x86asm.MOV, x86asm.MOVQ, x86asm.MOVSD_XMM, x86asm.MOVSS, x86asm.MOVUPS, x86asm.MOVAPS, x86asm.NOP:
+			pc += inst.Len
+			continue
+		case x86asm.JMP:
+			rel, ok := inst.Args[0].(x86asm.Rel)
+			start := uint64(int64(textStart) + int64(pc+inst.Len) + int64(rel))
+			if !ok || start >= call || start < textStart {
+				return stackSplitStub{}, false
+			}
+			return stackSplitStub{callee: callee, call: call, start: start, end: textStart + uint64(pc+inst.Len)}, true
+		}
+		return stackSplitStub{}, false
+	}
+	return stackSplitStub{}, false
+}
+
+// splitsStack tells if the prologue at the start of a stub branches to it: the stack guard compare, then a JBE, or a JB for the frames
+// too large to compare with the stack pointer alone, to the spills before the call
+func splitsStack(text []byte, textStart uint64, stub stackSplitStub) bool {
+	pc := int(stub.start - textStart)
+	for i := 0; i < maxPrologueInstructions && pc < len(text); i++ {
+		inst, err := x86asm.Decode(text[pc:], 64)
+		if err != nil || inst.Len == 0 {
+			return false
+		}
+		pc += inst.Len
+		switch inst.Op {
+		case x86asm.JBE, x86asm.JB:
+			rel, ok := inst.Args[0].(x86asm.Rel)
+			target := uint64(int64(textStart) + int64(pc) + int64(rel))
+			if ok && target > stub.start && target <= stub.call {
+				return true
+			}
+		case x86asm.CALL, x86asm.RET, x86asm.JMP:
+			return false
+		}
+	}
+	return false
+}
+
+// morestackNoctxt tells if the function at VA is runtime.morestack_noctxt by its body, and returns the runtime.morestack it jumps to
+func morestackNoctxt(text []byte, textStart uint64, VA uint64) (uint64, bool) {
+	if VA < textStart {
+		return 0, false
+	}
+	off := VA - textStart
+	for _, seed := range morestackNoctxtSeeds {
+		if off+uint64(len(seed))+4 <= uint64(len(text)) && bytes.Equal(text[off:off+uint64(len(seed))], seed) {
+			next := VA + uint64(len(seed)) + 4
+			return uint64(int64(next) + int64(int32(binary.LittleEndian.Uint32(text[off+uint64(len(seed)):])))), true
+		}
+	}
+	return 0, false
+}
+
+// HeuristicFunctions finds the functions of an amd64 binary whose pclntab is gone from the calls to runtime.morestack, a last resort.
+// Every function with a stack check ends with a stub calling morestack_noctxt, or morestack for closures, when the stack is too small
+// and jumping back to its start, the prologue branching to the stub. So each call followed by a jump back to a prologue branching to
+// it gives a function start. morestack_noctxt is told by its body, clearing DX and jumping to morestack, else it's the callee of the
+// most stubs. A function ends where the next starts, the last at the end of its stub.
+//
+// The starts are named sub_<start>, or by the symbol table, the exports, the methods of the types found by ScanTypes when runtimeVersion
+// is known, and the signature for morestack itself. The functions without a stack check, ex: the small leaf functions, aren't found.
+func (e *Entry) HeuristicFunctions(runtimeVersion string) (*HeuristicRecovery, error) {
+	if goarch := e.GOARCH(); goarch != "amd64" {
+		return nil, fmt.Errorf("heuristic function recovery is amd64 only, not %q", goarch)
+	}
+	textStart, text, err := e.raw.text()
+	if err != nil {
+		return nil, err
+	}
+
+	stubs := make(map[uint64][]stackSplitStub)
+	for off := 0; off+5 <= len(text); off++ {
+		if text[off] != 0xe8 {
+			continue
+		}
+		if off%0x100000 == 0 {
+			if err := contextErr(e.ctx); err != nil {
+				return nil, err
+			}
+		}
+		if stub, ok := decodeStackSplitStub(text, textStart, off); ok && splitsStack(text, textStart, stub) {
+			stubs[stub.callee] = append(stubs[stub.callee], stub)
+		}
+	}
+
+	names := make(map[uint64]HeuristicFunction)
+	var morestack []uint64
+	var best uint64
+	for callee, calls := range stubs {
+		_, seeded := morestackNoctxt(text, textStart, callee)
+		_, bestSeeded := morestackNoctxt(text, textStart, best)
+		if best == 0 || (seeded && !bestSeeded) || (seeded == bestSeeded && (len(calls) > len(stubs[best]) || (len(calls) == len(stubs[best]) && callee < best))) {
+			best = callee
+		}
+	}
+	if target, ok := morestackNoctxt(text, textStart, best); ok {
+		morestack = []uint64{best, target}
+		names[best] = HeuristicFunction{Name: "runtime.morestack_noctxt", NamedBy: "signature"}
+		names[target] = HeuristicFunction{Name: "runtime.morestack", NamedBy: "signature"}
+	} else if len(stubs[best]) >= minMorestackTails {
+		morestack = []uint64{best}
+	} else {
+		return nil, fmt.Errorf("no runtime.morestack: no call is followed by a jump back to a stack check")
+	}
+
+	ends := make(map[uint64]uint64)
+	for _, callee := range morestack {
+		for _, stub := range stubs[callee] {
+			ends[stub.start] = max(ends[stub.start], stub.end)
+		}
+	}
+	for _, VA := range morestack {
+		if _, ok := ends[VA]; !ok {
+			ends[VA] = 0
+		}
+	}
+	starts := make([]uint64, 0, len(ends))
+	for start := range ends {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	name := func(VA uint64, fullName string, namedBy string) {
+		if _, ok := ends[VA]; !ok || fullName == "" {
+			return
+		}
+		if _, named := names[VA]; !named {
+			names[VA] = HeuristicFunction{Name: fullName, NamedBy: namedBy}
+		}
+	}
+	if syms, err := e.Symbols(); err == nil {
+		for _, sym := range syms {
+			if sym.Code == 'T' || sym.Code == 't' {
+				name(sym.Addr, sym.Name, "symbol")
+			}
+		}
+	}
+	if exports, err := e.Exports(); err == nil {
+		for _, export := range exports {
+			name(export.VA, export.Name, "export")
+		}
+	}
+	if runtimeVersion != "" {
+		module := &ModuleData{TextVA: textStart, ETextVA: textStart + uint64(len(text))}
+		// the names come from the methods whatever SetMethods says
+		methods := e.methods
+		e.methods = true
+		types, _, _ := e.ScanTypes(runtimeVersion, module, true, true)
+		e.methods = methods
+		for _, typ := range types {
+			for _, method := range typ.Methods {
+				receiver := methodReceiver(typ)
+				if method.VA != nil {
+					name(*method.VA, receiver+"."+method.Name, "method")
+				}
+				// the wrapper an interface calls a value receiver through
+				if method.InterfaceVA != nil && !strings.HasPrefix(typ.Str, "*") {
+					name(*method.InterfaceVA, pointerReceiver(receiver)+"."+method.Name, "method")
+				}
+			}
+		}
+	}
+
+	recovery := &HeuristicRecovery{Method: heuristicMorestack, Morestack: morestack}
+	for i, start := range starts {
+		fn := names[start]
+		if fn.Name == "" {
+			fn.Name = fmt.Sprintf("sub_%x", start)
+		}
+		fn.Start = start
+		fn.End = ends[start]
+		if i+1 < len(starts) {
+			fn.End = starts[i+1]
+		} else if fn.End == 0 {
+			fn.End = textStart + uint64(len(text))
+		}
+		recovery.Functions = append(recovery.Functions, fn)
+	}
+	return recovery, nil
+}
+
+// methodReceiver is the receiver of the methods of typ as the function names have it, ex: net/http.(*Client) for *http.Client
+func methodReceiver(typ Type) string {
+	str := strings.TrimPrefix(typ.Str, "*")
+	if idx := strings.Index(str, "."); idx != -1 && typ.PkgPath != "" {
+		str = typ.PkgPath + str[idx:]
+	}
+	if strings.HasPrefix(typ.Str, "*") {
+		return pointerReceiver(str)
+	}
+	return str
+}
+
+// pointerReceiver is the pointer receiver of a named type, ex: main.(*T) of main.T. The type arguments of a generic type can have
+// dots of their own, the package ends at the last one before them.
+func pointerReceiver(receiver string) string {
+	name := receiver
+	if idx := strings.Index(name, "["); idx != -1 {
+		name = name[:idx]
+	}
+	idx := strings.LastIndex(name, ".")
+	if idx == -1 {
+		return "(*" + receiver + ")"
+	}
+	return receiver[:idx] + ".(*" + receiver[idx+1:] + ")"
+}
+
+// the most bytes of a function taken for a stub, the moves of the arguments and a jump
+const maxStubSize = 64
+
+// TailJumpStub tells if the amd64 code from start to end is a stub: register moves then a jump out of it, ex: the wrapper the compiler
+// generates for a method value, whose name an obfuscator rewrote. The padding after the jump isn't decoded.
+func (e *Entry) TailJumpStub(start uint64, end uint64) bool {
+	if e.raw.goarch() != "amd64" || end <= start || end-start > maxStubSize {
+		return false
+	}
+	code, err := e.raw.read_memory(start, end-start)
+	if err != nil {
+		return false
+	}
+	for pc := 0; pc < len(code); {
+		inst, err := x86asm.Decode(code[pc:], 64)
+		if err != nil || inst.Len == 0 {
+			return false
+		}
+		pc += inst.Len
+		switch inst.Op {
+		case x86asm.MOV, x86asm.MOVQ, x86asm.MOVSD_XMM, x86asm.MOVUPS, x86asm.LEA, x86asm.XCHG, x86asm.NOP:
+			continue
+		case x86asm.JMP:
+			rel, ok := inst.Args[0].(x86asm.Rel)
+			target := uint64(int64(start) + int64(pc) + int64(rel))
+			return ok && (target < start || target >= end)
+		}
+		return false
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// fakeStackChecks is the text of two functions at 0x401000 and 0x401020 with a stack check, a 1.17 one and an older one, calling
+// runtime.morestack_noctxt at 0x401100, and a call at 0x401060 jumping back to code without one
+func fakeStackChecks() *dumpFile {
+	text := make([]byte, 0x200)
+	for i := range text {
+		text[i] = 0xcc
+	}
+	call := func(off int, target int) {
+		text[off] = 0xe8
+		binary.LittleEndian.PutUint32(text[off+1:], uint32(int32(target-(off+5))))
+	}
+	// CMPQ SP, 16(R14); JBE stub; RET; stub: MOVQ AX, 8(SP); CALL morestack_noctxt; MOVQ 8(SP), AX; JMP start
+	copy(text[0x0:], []byte{0x49, 0x3b, 0x66, 0x10, 0x76, 0x01, 0xc3, 0x48, 0x89, 0x44, 0x24, 0x08})
+	call(0xc, 0x100)
+	copy(text[0x11:], []byte{0x48, 0x8b, 0x44, 0x24, 0x08, 0xeb, 0x100 - 0x18})
+	// MOVQ TLS, CX; CMPQ SP, 16(CX); JBE stub; RET; stub: CALL morestack_noctxt; JMP start
+	copy(text[0x20:], []byte{0x64, 0x48, 0x8b, 0x0c, 0x25, 0xf8, 0xff, 0xff, 0xff, 0x48, 0x3b, 0x61, 0x10, 0x76, 0x01, 0xc3})
+	call(0x30, 0x100)
+	copy(text[0x35:], []byte{0xeb, 0x100 - 0x17})
+	// no stack check branches to this call
+	copy(text[0x50:], []byte{0x90, 0xc3})
+	call(0x60, 0x100)
+	copy(text[0x65:], []byte{0xeb, 0x100 - 0x17})
+	// XORL DX, DX; JMP morestack
+	copy(text[0x100:], []byte{0x31, 0xd2, 0xe9})
+	binary.LittleEndian.PutUint32(text[0x103:], 0x180-0x107)
+
+	return &dumpFile{format: "raw", arch: "amd64", base: 0x401000, size: uint64(len(text)), regions: []dumpRegion{
+		{name: "text", addr: 0x401000, data: text, executable: true},
+	}}
+}
+
+func TestHeuristicFunctions(t *testing.T) {
+	e := &Entry{raw: fakeStackChecks()}
+	recovery, err := e.HeuristicFunctions("")
+	if err != nil {
+		t.Fatalf("heuristic recovery errored: %s", err)
+	}
+	if len(recovery.Morestack) != 2 || recovery.Morestack[0] != 0x401100 || recovery.Morestack[1] != 0x401180 {
+		t.Errorf("expected morestack_noctxt and morestack, got %x", recovery.Morestack)
+	}
+	expected := []HeuristicFunction{
+		{Start: 0x401000, End: 0x401020, Name: "sub_401000"},
+		{Start: 0x401020, End: 0x401100, Name: "sub_401020"},
+		{Start: 0x401100, End: 0x401180, Name: "runtime.morestack_noctxt", NamedBy: "signature"},
+		{Start: 0x401180, End: 0x401200, Name: "runtime.morestack", NamedBy: "signature"},
+	}
+	if len(recovery.Functions) != len(expected) {
+		t.Fatalf("expected %d functions, got %+v", len(expected), recovery.Functions)
+	}
+	for i, fn := range recovery.Functions {
+		if fn != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], fn)
+		}
+	}
+
+	raw := fakeStackChecks()
+	raw.arch = "arm64"
+	if _, err := (&Entry{raw: raw}).HeuristicFunctions(""); err == nil {
+		t.Errorf("expected arm64 to be unsupported")
+	}
+}
+
+func TestMethodReceiver(t *testing.T) {
+	cases := []struct {
+		typ      Type
+		receiver string
+		pointer  string
+	}{
+		{Type{Str: "main.T"}, "main.T", "main.(*T)"},
+		{Type{Str: "*http.Client", PkgPath: "net/http"}, "net/http.(*Client)", ""},
+		{Type{Str: "x.List[main.Item]", PkgPath: "example.com/x"}, "example.com/x.List[main.Item]", "example.com/x.(*List[main.Item])"},
+	}
+	for _, c := range cases {
+		receiver := methodReceiver(c.typ)
+		if receiver != c.receiver {
+			t.Errorf("expected %s for %s, got %s", c.receiver, c.typ.Str, receiver)
+		}
+		if c.pointer != "" && pointerReceiver(receiver) != c.pointer {
+			t.Errorf("expected %s for %s, got %s", c.pointer, receiver, pointerReceiver(receiver))
+		}
+	}
+}
+
+func TestTailJumpStub(t *testing.T) {
+	raw := fakeStackChecks()
+	text := raw.regions[0].data
+	// MOVQ 8(AX), AX; LEAQ 16(BX), BX; JMP 0x401000, the method value wrapper shape
+	copy(text[0x140:], []byte{0x48, 0x8b, 0x40, 0x08, 0x48, 0x8d, 0x5b, 0x10, 0xe9})
+	rel := int32(0 - 0x14d)
+	binary.LittleEndian.PutUint32(text[0x149:], uint32(rel))
+	// MOVQ AX, BX; JMP to the next instruction
+	copy(text[0x160:], []byte{0x48, 0x89, 0xc3, 0xeb, 0x00})
+	e := &Entry{raw: raw}
+
+	cases := []struct {
+		name       string
+		start, end uint64
+		stub       bool
+	}{
+		{"moves then a jump out", 0x401140, 0x401150, true},
+		{"a stack check and a return", 0x401000, 0x401018, false},
+		{"a call", 0x40100c, 0x401018, false},
+		{"a jump within", 0x401160, 0x401168, false},
+		{"too large for a stub", 0x401140, 0x401200, false},
+	}
+	for _, c := range cases {
+		if got := e.TailJumpStub(c.start, c.end); got != c.stub {
+			t.Errorf("%s: expected %v, got %v", c.name, c.stub, got)
+		}
+	}
+
+	raw.arch = "arm64"
+	if e.TailJumpStub(0x401140, 0x401150) {
+		t.Errorf("expected only amd64 code decoded")
+	}
+}
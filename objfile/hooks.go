@@ -0,0 +1,231 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+	"github.com/mandiant/GoReSym/debug/gosym"
+
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// the bytes of the entry of a function checked for a hook, an inline hook's jump and its address fit in them
+const hookPrologueSize = 16
+
+// how the entry of a function was patched, Hook.Kind
+const (
+	HookJump         = "jump"          // a jmp or branch to an address outside every known module
+	HookIndirectJump = "indirect-jump" // a jump through an absolute address, ex: jmp [rip+0] with the target after it, or mov rax, imm; jmp rax
+	HookModified     = "modified"      // the entry differs from that of the original file
+)
+
+// Hook is a function whose entry looks patched. Target is where the jump at its entry goes, 0 when it doesn't decode to one, and
+// TargetRegion the section, segment or mapped file holding it, empty when nothing does. Prologue is the hex of the first bytes of
+// the function, Original that of the same function in the original file when they differ.
+type Hook struct {
+	Function     string
+	Entry        uint64
+	Kind         string
+	Target       uint64
+	TargetRegion string
+	Prologue     string
+	Original     string
+}
+
+// knownModule is code a jump at the entry of a function may go to without being a hook, ex: the PLT of the image or a library the
+// process mapped
+type knownModule struct {
+	start uint64
+	end   uint64
+}
+
+// knownModules lists the code of the image outside the Go text: its executable sections or segments, and for a core or a minidump
+// every file the process mapped. The regions of a raw dump aren't, the whole dump is one.
+func (e *Entry) knownModules() []knownModule {
+	var modules []knownModule
+	switch f := e.raw.(type) {
+	case *dumpFile:
+		for _, mapping := range f.mappings {
+			modules = append(modules, knownModule{mapping.start, mapping.end})
+		}
+		if f.format == "pe" || f.format == "elf" {
+			for _, region := range f.regions {
+				if region.executable {
+					modules = append(modules, knownModule{region.addr, region.addr + uint64(len(region.data))})
+				}
+			}
+		}
+	case *elfFile:
+		for _, prog := range f.elf.Progs {
+			if prog.Type == elf.PT_LOAD && prog.Flags&elf.PF_X != 0 {
+				modules = append(modules, knownModule{prog.Vaddr, prog.Vaddr + prog.Memsz})
+			}
+		}
+	case *peFile:
+		const memExecute = 0x20000000
+		imageBase, _ := f.loadAddress()
+		for _, sect := range f.pe.Sections {
+			if sect.Characteristics&memExecute != 0 {
+				start := imageBase + uint64(sect.VirtualAddress)
+				modules = append(modules, knownModule{start, start + uint64(sect.VirtualSize)})
+			}
+		}
+	case *machoFile:
+		for _, sect := range f.macho.Sections {
+			if sect.Seg == "__TEXT" {
+				modules = append(modules, knownModule{sect.Addr, sect.Addr + sect.Size})
+			}
+		}
+	}
+	return modules
+}
+
+// targetRegion names what holds VA: the region of a dump, or the section of a file
+func (e *Entry) targetRegion(VA uint64) string {
+	if f, ok := e.raw.(*dumpFile); ok {
+		return f.regionName(VA)
+	}
+	if offset, err := e.VAToFileOffset(VA); err == nil {
+		return e.fileRegion(offset)
+	}
+	return ""
+}
+
+// entryJump decodes the jump code at entry starts with, the first bytes of the function: a jmp or branch to an address, or one through
+// an address read from memory. ok is false when it doesn't start with one.
+func (e *Entry) entryJump(code []byte, entry uint64) (target uint64, indirect bool, ok bool) {
+	order := e.ByteOrder()
+	switch arch := e.raw.goarch(); arch {
+	case "amd64", "386":
+		mode, ptrSize := 64, uint64(8)
+		if arch == "386" {
+			mode, ptrSize = 32, 4
+		}
+		pointer := func(VA uint64) (uint64, bool) {
+			data, err := e.raw.read_memory(VA, ptrSize)
+			if err != nil || uint64(len(data)) < ptrSize {
+				return 0, false
+			}
+			if ptrSize == 4 {
+				return uint64(order.Uint32(data)), true
+			}
+			return order.Uint64(data), true
+		}
+		inst, err := x86asm.Decode(code, mode)
+		if err != nil {
+			return 0, false, false
+		}
+		next := uint64(inst.Len)
+		switch arg := inst.Args[0].(type) {
+		case x86asm.Rel:
+			if inst.Op == x86asm.JMP {
+				return uint64(int64(entry) + int64(next) + int64(arg)), false, true
+			}
+		case x86asm.Mem:
+			// jmp [rip+disp] on amd64, jmp [disp] on 386
+			if inst.Op != x86asm.JMP || arg.Index != 0 || (arg.Base != x86asm.RIP && arg.Base != 0) {
+				return 0, false, false
+			}
+			VA := uint64(arg.Disp)
+			if arg.Base == x86asm.RIP {
+				VA = uint64(int64(entry) + int64(next) + arg.Disp)
+			}
+			target, ok := pointer(VA)
+			return target, true, ok
+		case x86asm.Reg:
+			// mov reg, imm; jmp reg
+			if imm, isImm := inst.Args[1].(x86asm.Imm); inst.Op == x86asm.MOV && isImm {
+				jump, err := x86asm.Decode(code[next:], mode)
+				if reg, isReg := jump.Args[0].(x86asm.Reg); err == nil && jump.Op == x86asm.JMP && isReg && reg == arg {
+					return uint64(imm), true, true
+				}
+			}
+		case x86asm.Imm:
+			// push imm; ret
+			if ret, err := x86asm.Decode(code[next:], mode); inst.Op == x86asm.PUSH && err == nil && ret.Op == x86asm.RET {
+				return uint64(uint32(arg)), true, true
+			}
+		}
+	case "arm64":
+		if len(code) < 8 {
+			return 0, false, false
+		}
+		first, second := binary.LittleEndian.Uint32(code), binary.LittleEndian.Uint32(code[4:])
+		switch {
+		case first&0xfc000000 == 0x14000000:
+			// b imm26, in words
+			offset := int64(int32(first<<6) >> 4)
+			return uint64(int64(entry) + offset), false, true
+		case first&0xff000000 == 0x58000000 && second&0xfffffc1f == 0xd61f0000 && (second>>5)&0x1f == first&0x1f:
+			// ldr xn, literal; br xn
+			offset := int64(int32(first<<8)>>13) * 4
+			data, err := e.raw.read_memory(uint64(int64(entry)+offset), 8)
+			if err != nil || len(data) < 8 {
+				return 0, false, false
+			}
+			return binary.LittleEndian.Uint64(data), true, true
+		}
+	}
+	return 0, false, false
+}
+
+// DetectHooks checks the entry of each of funcs for a hook: a jump to an address outside the Go text of texts and outside the code
+// of the image and of the files the process mapped, the trampolines of cgo and the PLT of the image going there. With original, the
+// file the image was loaded from, the first bytes of each function are also compared to those at the entry originalEntries gives for
+// its name, any difference is a hook whatever it jumps to. Only the jumps of amd64, 386 and arm64 are decoded.
+func (e *Entry) DetectHooks(funcs []gosym.Func, texts []gosym.PCRange, original *File, originalEntries map[string]uint64) []Hook {
+	known := e.knownModules()
+	isKnown := func(VA uint64) bool {
+		for _, text := range texts {
+			if VA >= text.Start && VA < text.End {
+				return true
+			}
+		}
+		for _, module := range known {
+			if VA >= module.start && VA < module.end {
+				return true
+			}
+		}
+		return false
+	}
+
+	var hooks []Hook
+	for _, fn := range funcs {
+		if fn.End <= fn.Entry {
+			continue
+		}
+		code, err := e.raw.read_memory(fn.Entry, min(hookPrologueSize, fn.End-fn.Entry))
+		if err != nil || len(code) == 0 {
+			continue
+		}
+		hook := Hook{Function: fn.Name, Entry: fn.Entry, Prologue: hex.EncodeToString(code)}
+		target, indirect, jumps := e.entryJump(code, fn.Entry)
+		if jumps {
+			hook.Target, hook.TargetRegion = target, e.targetRegion(target)
+		}
+
+		switch {
+		case jumps && !isKnown(target) && indirect:
+			hook.Kind = HookIndirectJump
+		case jumps && !isKnown(target):
+			hook.Kind = HookJump
+		}
+		if entry, ok := originalEntries[fn.Name]; ok && original != nil {
+			before, err := original.entries[0].raw.read_memory(entry, uint64(len(code)))
+			if err == nil && len(before) == len(code) && !bytes.Equal(before, code) {
+				hook.Original = hex.EncodeToString(before)
+				if len(hook.Kind) == 0 {
+					hook.Kind = HookModified
+				}
+			}
+		}
+		if len(hook.Kind) == 0 {
+			continue
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks
+}
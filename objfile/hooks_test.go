@@ -0,0 +1,99 @@
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+)
+
+func TestEntryJump(t *testing.T) {
+	const entry = 0x401000
+	// the address a jmp [rip+0] at the entry reads, right after it, and the literal of an arm64 ldr
+	memory := make([]byte, 0x100)
+	binary.LittleEndian.PutUint64(memory[6:], 0x7f0000002000)
+	binary.LittleEndian.PutUint64(memory[16:], 0x7f0000003000)
+	open := func(arch string) *Entry {
+		return &Entry{raw: &dumpFile{format: "raw", arch: arch, byteOrder: binary.LittleEndian, base: entry, size: uint64(len(memory)), regions: []dumpRegion{{name: "dump", addr: entry, data: memory}}}}
+	}
+
+	tests := []struct {
+		arch     string
+		code     []byte
+		target   uint64
+		indirect bool
+		ok       bool
+	}{
+		// jmp rel32
+		{"amd64", []byte{0xe9, 0xfb, 0xef, 0xbf, 0x0f}, 0x10000000, false, true},
+		// jmp [rip+0]
+		{"amd64", []byte{0xff, 0x25, 0, 0, 0, 0}, 0x7f0000002000, true, true},
+		// mov rax, imm64; jmp rax
+		{"amd64", []byte{0x48, 0xb8, 0, 0x10, 0, 0, 0, 0x7f, 0, 0, 0xff, 0xe0}, 0x7f0000001000, true, true},
+		// mov rax, imm64; jmp rcx
+		{"amd64", []byte{0x48, 0xb8, 0, 0x10, 0, 0, 0, 0x7f, 0, 0, 0xff, 0xe1}, 0, false, false},
+		// push imm32; ret
+		{"386", []byte{0x68, 0, 0x20, 0, 0x10, 0xc3}, 0x10002000, true, true},
+		// the stack check of a Go function
+		{"amd64", []byte{0x49, 0x3b, 0x66, 0x10, 0x76, 0x20}, 0, false, false},
+		// b, 0x10 words before
+		{"arm64", []byte{0xf0, 0xff, 0xff, 0x17, 0, 0, 0, 0}, entry - 0x40, false, true},
+		// ldr x16, #16; br x16
+		{"arm64", []byte{0x90, 0, 0, 0x58, 0, 0x02, 0x1f, 0xd6}, 0x7f0000003000, true, true},
+		// ldr x16, #16; br x17
+		{"arm64", []byte{0x90, 0, 0, 0x58, 0x20, 0x02, 0x1f, 0xd6}, 0, false, false},
+	}
+	for _, test := range tests {
+		target, indirect, ok := open(test.arch).entryJump(test.code, entry)
+		if ok != test.ok || (ok && (target != test.target || indirect != test.indirect)) {
+			t.Errorf("%s % x: expected 0x%x %v %v, got 0x%x %v %v", test.arch, test.code, test.target, test.indirect, test.ok, target, indirect, ok)
+		}
+	}
+}
+
+func TestDetectHooks(t *testing.T) {
+	// four functions of 0x40 bytes: one jumping far away, one jumping to the next, one patched to stop and one left alone
+	const text = 0x401000
+	original := bytes.Repeat([]byte{0x90}, 0x100)
+	image := append([]byte(nil), original...)
+	copy(image, []byte{0xe9, 0xfb, 0xef, 0xbf, 0x0f})
+	copy(image[0x40:], []byte{0xe9, 0x3b, 0, 0, 0})
+	image[0x80] = 0xcc
+	open := func(data []byte) *File {
+		e := &Entry{raw: &dumpFile{format: "raw", arch: "amd64", byteOrder: binary.LittleEndian, base: text, size: uint64(len(data)), regions: []dumpRegion{{name: "dump", addr: text, data: data}}}}
+		return &File{r: bytes.NewReader(data), entries: []*Entry{e}}
+	}
+	funcs := []gosym.Func{
+		{Entry: text, End: text + 0x40, Sym: &gosym.Sym{Name: "runtime.newproc"}},
+		{Entry: text + 0x40, End: text + 0x80, Sym: &gosym.Sym{Name: "main.wrapper"}},
+		{Entry: text + 0x80, End: text + 0xc0, Sym: &gosym.Sym{Name: "crypto/tls.(*Conn).Write"}},
+		{Entry: text + 0xc0, End: text + 0x100, Sym: &gosym.Sym{Name: "main.main"}},
+	}
+	texts := []gosym.PCRange{{Start: text, End: text + 0x100}}
+	entries := map[string]uint64{"runtime.newproc": text, "main.wrapper": text + 0x40, "crypto/tls.(*Conn).Write": text + 0x80, "main.main": text + 0xc0}
+
+	hooks := open(image).DetectHooks(funcs, texts, nil, nil)
+	if len(hooks) != 1 || hooks[0].Function != "runtime.newproc" || hooks[0].Kind != HookJump || hooks[0].Target != 0x10000000 || hooks[0].Prologue != "e9fbefbf0f9090909090909090909090" {
+		t.Fatalf("expected the jump out of the text, got %+v", hooks)
+	}
+
+	// every patched entry differs from the original, the jump within the text too
+	hooks = open(image).DetectHooks(funcs, texts, open(original), entries)
+	kinds := make(map[string]string)
+	for _, hook := range hooks {
+		kinds[hook.Function] = hook.Kind
+		if len(hook.Original) != 32 {
+			t.Errorf("expected the original bytes of %s, got %q", hook.Function, hook.Original)
+		}
+	}
+	expected := map[string]string{"runtime.newproc": HookJump, "main.wrapper": HookModified, "crypto/tls.(*Conn).Write": HookModified}
+	if len(kinds) != len(expected) {
+		t.Fatalf("expected %v, got %+v", expected, hooks)
+	}
+	for name, kind := range expected {
+		if kinds[name] != kind {
+			t.Errorf("expected %s to be %s, got %q", name, kind, kinds[name])
+		}
+	}
+}
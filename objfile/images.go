@@ -0,0 +1,328 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+	"github.com/mandiant/GoReSym/debug/macho"
+	"github.com/mandiant/GoReSym/debug/pe"
+)
+
+// the magics an embedded executable starts with: PE, ELF and Mach-O of either byte order and pointer size. A fat Mach-O's is a Java
+// class's too, its slices are found on their own.
+var executableMagics = [][]byte{[]byte("MZ"), []byte("\x7fELF"), {0xcf, 0xfa, 0xed, 0xfe}, {0xce, 0xfa, 0xed, 0xfe}, {0xfe, 0xed, 0xfa, 0xcf}, {0xfe, 0xed, 0xfa, 0xce}}
+
+// a Go binary has its build info from 1.13 on, and a pclntab starting with one of pcHeaderMagics in either byte order
+var buildInfoMagic = []byte("\xff Go buildinf:")
+
+// the most embedded images listed, more is a sample of samples rather than a dropper
+const maxEmbeddedImages = 16
+
+// EmbeddedImage is a Go executable embedded whole in the input, ex: by go:embed in the data of a dropper or in a PE resource, its Size
+// bytes at FileOffset. Region is the part of the input it's in: a section, ex: .noptrdata, a PE resource, ex: resource RCDATA/101/1033,
+// or the overlay. Index is what OpenEmbeddedImage takes, from 1 in file order, 0 being the input itself.
+type EmbeddedImage struct {
+	Index      int
+	Format     string // elf, pe or macho
+	FileOffset uint64
+	Size       uint64
+	Region     string
+}
+
+// eachChunk calls fn with the bytes of r from start to size, a chunk at a time, each running overlap bytes into the next so that what
+// spans two is whole in one, with the file offset of each. It stops once fn returns false.
+func eachChunk(r io.ReaderAt, start int64, size int64, overlap int, fn func(base int64, data []byte) bool) {
+	buf := make([]byte, scanChunkSize+overlap)
+	for base := start; base < size; base += scanChunkSize {
+		n, _ := r.ReadAt(buf[:min(int64(len(buf)), size-base)], base)
+		if !fn(base, buf[:n]) || n < int(min(int64(len(buf)), size-base)) {
+			return
+		}
+	}
+}
+
+// holdsGo tells if the bytes of r in [start, end) have a Go build info or pcHeader in them
+func holdsGo(r io.ReaderAt, start int64, end int64) bool {
+	found := false
+	eachChunk(r, start, end, len(buildInfoMagic)-1, func(base int64, data []byte) bool {
+		if bytes.Contains(data, buildInfoMagic) {
+			found = true
+			return false
+		}
+		for _, magic := range pcHeaderMagics {
+			for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+				needle := make([]byte, 4)
+				order.PutUint32(needle, magic)
+				for off := 0; ; {
+					idx := bytes.Index(data[off:], needle)
+					if idx == -1 {
+						break
+					}
+					if off += idx; off+8 <= len(data) && isPCHeader(data[off:off+8]) {
+						found = true
+						return false
+					}
+					off++
+				}
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// elfHeaderExtent is the end of the section header table of the ELF header at the start of r, what the sections and segments don't cover
+func elfHeaderExtent(r io.ReaderAt) uint64 {
+	header := make([]byte, 64)
+	if n, _ := r.ReadAt(header, 0); n < 52 {
+		return 0
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if header[elf.EI_DATA] == byte(elf.ELFDATA2MSB) {
+		order = binary.BigEndian
+	}
+	if header[elf.EI_CLASS] == byte(elf.ELFCLASS64) {
+		return order.Uint64(header[0x28:]) + uint64(order.Uint16(header[0x3a:]))*uint64(order.Uint16(header[0x3c:]))
+	}
+	return uint64(order.Uint32(header[0x20:])) + uint64(order.Uint16(header[0x2e:]))*uint64(order.Uint16(header[0x30:]))
+}
+
+// imageExtent is how many bytes of r from its start the executable raw takes: its sections, segments and the tables after them
+func imageExtent(raw rawFile, r io.ReaderAt) uint64 {
+	var extent uint64
+	switch f := raw.(type) {
+	case *elfFile:
+		for _, prog := range f.elf.Progs {
+			extent = max(extent, prog.Off+prog.Filesz)
+		}
+		for _, sect := range f.elf.Sections {
+			if sect.Type != elf.SHT_NOBITS {
+				extent = max(extent, sect.Offset+sect.Size)
+			}
+		}
+		extent = max(extent, elfHeaderExtent(r))
+	case *peFile:
+		// past the sections come the COFF symbols and the certificate table, whose VirtualAddress is a file offset
+		for _, sect := range f.pe.Sections {
+			extent = max(extent, uint64(sect.Offset)+uint64(sect.Size))
+		}
+		if f.pe.PointerToSymbolTable != 0 {
+			extent = max(extent, uint64(f.pe.PointerToSymbolTable)+18*uint64(f.pe.NumberOfSymbols)+4+uint64(len(f.pe.StringTable)))
+		}
+		var dirs []pe.DataDirectory
+		switch oh := f.pe.OptionalHeader.(type) {
+		case *pe.OptionalHeader32:
+			dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+		case *pe.OptionalHeader64:
+			dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+		}
+		if len(dirs) > pe.IMAGE_DIRECTORY_ENTRY_SECURITY && dirs[pe.IMAGE_DIRECTORY_ENTRY_SECURITY].Size != 0 {
+			cert := dirs[pe.IMAGE_DIRECTORY_ENTRY_SECURITY]
+			extent = max(extent, uint64(cert.VirtualAddress)+uint64(cert.Size))
+		}
+	case *machoFile:
+		for _, load := range f.macho.Loads {
+			if seg, ok := load.(*macho.Segment); ok {
+				extent = max(extent, seg.Offset+seg.Filesz)
+			}
+		}
+	}
+	return extent
+}
+
+// embeddedExecutable opens the executable that starts with magic at offset of r, cut to the end of its data
+func embeddedExecutable(r io.ReaderAt, size int64, offset int64, magic []byte) (EmbeddedImage, bool) {
+	rest := io.NewSectionReader(r, offset, size-offset)
+	var raw rawFile
+	var err error
+	image := EmbeddedImage{FileOffset: uint64(offset)}
+	switch magic[0] {
+	case 'M':
+		// the DOS header points at the PE signature, most MZs of the data are something else
+		header := make([]byte, 0x40)
+		if n, _ := rest.ReadAt(header, 0); n < len(header) {
+			return image, false
+		}
+		signature := make([]byte, 4)
+		if _, err := rest.ReadAt(signature, int64(binary.LittleEndian.Uint32(header[0x3c:]))); err != nil || string(signature) != "PE\x00\x00" {
+			return image, false
+		}
+		raw, err = openPE(rest, OpenOptions{})
+		image.Format = "pe"
+	case 0x7f:
+		// the tables the header declares fit the rest of the file, the parser allocates them before reading
+		header := make([]byte, 64)
+		if n, _ := rest.ReadAt(header, 0); n < 52 || header[elf.EI_CLASS] == 0 || header[elf.EI_CLASS] > 2 || header[elf.EI_DATA] == 0 || header[elf.EI_DATA] > 2 || header[elf.EI_VERSION] != 1 {
+			return image, false
+		}
+		if elfHeaderExtent(rest) > uint64(size-offset) {
+			return image, false
+		}
+		raw, err = openElf(rest, OpenOptions{})
+		image.Format = "elf"
+	default:
+		// likewise for the load commands, and the file type is one of those of the loader
+		header := make([]byte, 28)
+		if n, _ := rest.ReadAt(header, 0); n < len(header) {
+			return image, false
+		}
+		var order binary.ByteOrder = binary.LittleEndian
+		if magic[0] == 0xfe {
+			order = binary.BigEndian
+		}
+		fileType, ncmds, sizeofcmds := order.Uint32(header[12:]), order.Uint32(header[16:]), order.Uint32(header[20:])
+		if fileType == 0 || fileType > 12 || ncmds == 0 || uint64(ncmds)*8 > uint64(sizeofcmds) || uint64(sizeofcmds) > uint64(size-offset) {
+			return image, false
+		}
+		raw, err = openMacho(rest, OpenOptions{})
+		image.Format = "macho"
+	}
+	if err != nil {
+		return image, false
+	}
+	image.Size = min(imageExtent(raw, rest), uint64(size-offset))
+	return image, image.Size != 0
+}
+
+// EmbeddedImages lists the Go executables embedded whole in the input, in file order, ex: the payload of a dropper in its data or in
+// a resource. They're found by the headers they start with at any offset, and kept when they open, hold a Go build info or pcHeader
+// and the input holds one outside of them too. An input whose only Go is embedded is the Go binary the extraction finds, ex: a payload
+// appended to a stub, see OpenOptions.ScanOverlay, so it has none. Dumps and the slices of a fat Mach-O have none either.
+func (f *File) EmbeddedImages() []EmbeddedImage {
+	if len(f.entries) != 1 {
+		return nil
+	}
+	switch raw := f.entries[0].raw.(type) {
+	case *dumpFile:
+		return nil
+	case *machoFile:
+		if raw.slice != nil {
+			return nil
+		}
+	}
+	size := int64(readerSize(f.r))
+
+	var images []EmbeddedImage
+	var resume int64 = 1
+	eachChunk(f.r, 1, size, 3, func(base int64, data []byte) bool {
+		var offsets []int64
+		magics := make(map[int64][]byte)
+		for _, magic := range executableMagics {
+			for off := 0; ; off++ {
+				idx := bytes.Index(data[off:], magic)
+				if idx == -1 {
+					break
+				}
+				off += idx
+				offsets = append(offsets, base+int64(off))
+				magics[base+int64(off)] = magic
+			}
+		}
+		sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+		for _, offset := range offsets {
+			// what's inside an image is its own, ex: the magics its code compares with
+			if offset < resume {
+				continue
+			}
+			image, ok := embeddedExecutable(f.r, size, offset, magics[offset])
+			if !ok || !holdsGo(f.r, offset, offset+int64(image.Size)) {
+				continue
+			}
+			images = append(images, image)
+			resume = offset + int64(image.Size)
+			if len(images) == maxEmbeddedImages {
+				return false
+			}
+		}
+		return true
+	})
+	if len(images) == 0 {
+		return nil
+	}
+
+	outer := false
+	var start int64
+	for _, image := range images {
+		outer = outer || holdsGo(f.r, start, int64(image.FileOffset))
+		start = int64(image.FileOffset + image.Size)
+	}
+	if !outer && !holdsGo(f.r, start, size) {
+		return nil
+	}
+	for i := range images {
+		images[i].Index = i + 1
+		images[i].Region = f.entries[0].fileRegion(images[i].FileOffset)
+	}
+	return images
+}
+
+// OpenEmbeddedImage opens the embedded image of EmbeddedImages at index, read from f. Closing it closes f.
+func (f *File) OpenEmbeddedImage(index int) (*File, error) {
+	images := f.EmbeddedImages()
+	if index < 1 || index > len(images) {
+		return nil, fmt.Errorf("no embedded image %d, the input embeds %d", index, len(images))
+	}
+	image := images[index-1]
+	r := io.NewSectionReader(f.r, int64(image.FileOffset), int64(image.Size))
+	for _, try := range openers {
+		if raw, err := try(r, OpenOptions{}); err == nil {
+			return &File{r: r, entries: []*Entry{{raw: raw}}, closer: f, image: &image}, nil
+		}
+	}
+	return nil, fmt.Errorf("the embedded image %d doesn't open", index)
+}
+
+// Image is the embedded image of the input f was opened as by OpenEmbeddedImage, nil for the input itself
+func (f *File) Image() *EmbeddedImage {
+	return f.image
+}
+
+// EmbeddedImages lists the Go executables embedded whole in the named file, see File.EmbeddedImages
+func EmbeddedImages(name string) ([]EmbeddedImage, error) {
+	f, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.EmbeddedImages(), nil
+}
+
+// fileRegion names the part of the file at offset: its section, a PE resource or the PE overlay, empty when none holds it
+func (e *Entry) fileRegion(offset uint64) string {
+	switch f := e.raw.(type) {
+	case *elfFile:
+		for _, sect := range f.elf.Sections {
+			if sect.Type != elf.SHT_NOBITS && sect.Offset <= offset && offset < sect.Offset+sect.Size {
+				return sect.Name
+			}
+		}
+	case *peFile:
+		imageBase, _ := f.loadAddress()
+		for _, resource := range f.resources() {
+			start, err := e.VAToFileOffset(imageBase + uint64(resource.rva))
+			if err == nil && start <= offset && offset < start+uint64(resource.size) {
+				return "resource " + resource.path
+			}
+		}
+		for _, sect := range f.pe.Sections {
+			if uint64(sect.Offset) <= offset && offset < uint64(sect.Offset)+uint64(sect.Size) {
+				return sect.Name
+			}
+		}
+		if f.overlaySize != 0 && f.overlayOffset <= offset && offset < f.overlayOffset+f.overlaySize {
+			return "overlay"
+		}
+	case *machoFile:
+		for _, sect := range f.macho.Sections {
+			if uint64(sect.Offset) <= offset && offset < uint64(sect.Offset)+sect.Size {
+				return sect.Seg + " " + sect.Name
+			}
+		}
+	}
+	return ""
+}
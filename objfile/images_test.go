@@ -0,0 +1,38 @@
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestEmbeddedExecutableHeaders(t *testing.T) {
+	// magics in the data whose headers declare more than the file holds, the parsers would allocate it all
+	macho := make([]byte, 0x100)
+	copy(macho, []byte{0xcf, 0xfa, 0xed, 0xfe})
+	binary.LittleEndian.PutUint32(macho[12:], 2)
+	binary.LittleEndian.PutUint32(macho[16:], 0x10000)
+	binary.LittleEndian.PutUint32(macho[20:], 0x7fffffff)
+	elfHeader := make([]byte, 0x100)
+	copy(elfHeader, "\x7fELF\x02\x01\x01")
+	binary.LittleEndian.PutUint64(elfHeader[0x28:], 0x40)
+	binary.LittleEndian.PutUint16(elfHeader[0x3a:], 0x40)
+	binary.LittleEndian.PutUint16(elfHeader[0x3c:], 0xffff)
+	// an MZ that isn't a DOS header
+	mz := append([]byte("MZ"), make([]byte, 0x100)...)
+
+	for _, c := range []struct {
+		name  string
+		data  []byte
+		magic []byte
+	}{
+		{"macho", macho, macho[:4]},
+		{"elf", elfHeader, elfHeader[:4]},
+		{"mz", mz, mz[:2]},
+	} {
+		data := append(make([]byte, 0x10), c.data...)
+		if image, ok := embeddedExecutable(bytes.NewReader(data), int64(len(data)), 0x10, c.magic); ok {
+			t.Errorf("%s: expected the header rejected, got %+v", c.name, image)
+		}
+	}
+}
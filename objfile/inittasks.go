@@ -0,0 +1,253 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+)
+
+// the most init tasks and functions of a task read, more is a bogus task
+const (
+	maxInitTasks     = 1 << 16
+	maxInitFunctions = 1 << 12
+)
+
+// InitFunction is a function an init task runs, Outside is set when it isn't in the text of the module, ex: a corrupted task
+type InitFunction struct {
+	VA      uint64
+	Name    string // from the pclntab
+	Outside bool
+}
+
+// InitTask is the <package>..inittask of a package, its functions run in order before main.main. Package is from the symbols,
+// else from the names of the functions.
+type InitTask struct {
+	VA        uint64
+	Package   string
+	Functions []InitFunction
+}
+
+// InitTasks recovers the init tasks of the module in the order they run, from Go 1.13 on. 1.21 and later keep the tasks the
+// linker sorted in the moduledata:
+//
+//	type initTask struct {
+//		state uint32 // 0 = uninitialized, 1 = in progress, 2 = done
+//		nfns  uint32
+//		// followed by nfns pcs, uintptr sized, one per init function to run
+//	}
+//
+// Before, runtime.main runs runtime..inittask then main..inittask, each running the tasks of its imports first, depth first:
+//
+//	type initTask struct {
+//		state uintptr // 0 = uninitialized, 1 = in progress, 2 = done
+//		ndeps uintptr
+//		nfns  uintptr
+//		// followed by ndeps instances of an *initTask, one per package depended on
+//		// followed by nfns pcs, one per init function to run
+//	}
+//
+// The two roots are from the symbols, else from the calls to runtime.doInit in runtime.main on amd64 and arm64. The tasks without
+// functions are left out, they run nothing.
+func (e *Entry) InitTasks(moduleData *ModuleData, runtimeVersion string, funcs []gosym.Func, is64bit bool, littleendian bool) ([]InitTask, error) {
+	minor, ok := goMinorVersion(runtimeVersion)
+	if !ok || minor < 13 {
+		return nil, fmt.Errorf("the init tasks of Go %s aren't decoded, only those of 1.13 and later", runtimeVersion)
+	}
+	if moduleData == nil {
+		return nil, fmt.Errorf("the init tasks need the moduledata")
+	}
+
+	var byteOrder binary.ByteOrder = binary.BigEndian
+	if littleendian {
+		byteOrder = binary.LittleEndian
+	}
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+	word := func(data []byte) uint64 {
+		if is64bit {
+			return byteOrder.Uint64(data)
+		}
+		return uint64(byteOrder.Uint32(data))
+	}
+
+	byEntry := make(map[uint64]gosym.Func)
+	for _, fn := range funcs {
+		byEntry[fn.Entry] = fn
+	}
+	taskNames := make(map[uint64]string)
+	if syms, err := e.Symbols(); err == nil {
+		for _, sym := range syms {
+			if strings.HasSuffix(sym.Name, "..inittask") {
+				taskNames[sym.Addr] = strings.TrimSuffix(sym.Name, "..inittask")
+			}
+		}
+	}
+
+	// the functions of the task at va, of nfns pcs at fnsVA
+	readTask := func(va uint64, fnsVA uint64, nfns uint64) (InitTask, error) {
+		task := InitTask{VA: va, Package: taskNames[va]}
+		if nfns > maxInitFunctions {
+			return task, fmt.Errorf("bogus init task at 0x%x of %d functions", va, nfns)
+		}
+		pcs, err := e.raw.read_memory(fnsVA, nfns*ptrSize)
+		if err != nil || uint64(len(pcs)) != nfns*ptrSize {
+			return task, fmt.Errorf("the functions of the init task at 0x%x can't be read", va)
+		}
+		for i := uint64(0); i < nfns; i++ {
+			pc := word(pcs[i*ptrSize:])
+			fn, known := byEntry[pc]
+			// a pc that isn't the entry of a function has no name, ex: one of the functions a corrupt pclntab lost
+			var name string
+			if known {
+				name = fn.Name
+				if len(task.Package) == 0 {
+					task.Package = fn.PackageName()
+				}
+			}
+			task.Functions = append(task.Functions, InitFunction{
+				VA:      pc,
+				Name:    name,
+				Outside: pc < moduleData.TextVA || (moduleData.ETextVA > 0 && pc >= moduleData.ETextVA),
+			})
+		}
+		return task, nil
+	}
+
+	var tasks []InitTask
+	if minor >= 21 {
+		count := moduleData.initTasks.Len
+		if count > maxInitTasks {
+			return nil, fmt.Errorf("bogus inittasks of %d tasks", count)
+		}
+		pointers, err := e.raw.read_memory(uint64(moduleData.initTasks.Data), count*ptrSize)
+		if err != nil || uint64(len(pointers)) != count*ptrSize {
+			return nil, fmt.Errorf("the inittasks at 0x%x can't be read", moduleData.initTasks.Data)
+		}
+		for i := uint64(0); i < count; i++ {
+			va := word(pointers[i*ptrSize:])
+			header, err := e.raw.read_memory(va, 8)
+			if err != nil || len(header) != 8 {
+				return tasks, fmt.Errorf("the init task at 0x%x can't be read", va)
+			}
+			task, err := readTask(va, va+8, uint64(byteOrder.Uint32(header[4:])))
+			if err != nil {
+				return tasks, err
+			}
+			if len(task.Functions) > 0 {
+				tasks = append(tasks, task)
+			}
+		}
+		return tasks, nil
+	}
+
+	var roots []uint64
+	for va, pkg := range taskNames {
+		if pkg == "runtime" {
+			roots = append([]uint64{va}, roots...)
+		} else if pkg == "main" {
+			roots = append(roots, va)
+		}
+	}
+	if len(roots) == 0 {
+		roots = e.initTaskRoots(funcs)
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("runtime..inittask and main..inittask weren't found")
+	}
+
+	visited := make(map[uint64]bool)
+	var walk func(va uint64) error
+	walk = func(va uint64) error {
+		if visited[va] {
+			return nil
+		}
+		visited[va] = true
+		if len(visited) > maxInitTasks {
+			return fmt.Errorf("more than %d init tasks, the graph is bogus", maxInitTasks)
+		}
+		header, err := e.raw.read_memory(va, 3*ptrSize)
+		if err != nil || uint64(len(header)) != 3*ptrSize {
+			return fmt.Errorf("the init task at 0x%x can't be read", va)
+		}
+		ndeps, nfns := word(header[ptrSize:]), word(header[2*ptrSize:])
+		if ndeps > maxInitTasks {
+			return fmt.Errorf("bogus init task at 0x%x of %d dependencies", va, ndeps)
+		}
+		deps, err := e.raw.read_memory(va+3*ptrSize, ndeps*ptrSize)
+		if err != nil || uint64(len(deps)) != ndeps*ptrSize {
+			return fmt.Errorf("the dependencies of the init task at 0x%x can't be read", va)
+		}
+		for i := uint64(0); i < ndeps; i++ {
+			if err := walk(word(deps[i*ptrSize:])); err != nil {
+				return err
+			}
+		}
+		task, err := readTask(va, va+(3+ndeps)*ptrSize, nfns)
+		if err != nil {
+			return err
+		}
+		if len(task.Functions) > 0 {
+			tasks = append(tasks, task)
+		}
+		return nil
+	}
+	for _, root := range roots {
+		if err := walk(root); err != nil {
+			return tasks, err
+		}
+	}
+	return tasks, nil
+}
+
+// initTaskRoots finds the tasks runtime.main passes runtime.doInit, in call order, as the address built right before each call
+func (e *Entry) initTaskRoots(funcs []gosym.Func) []uint64 {
+	goarch := e.GOARCH()
+	operandsOf, decode, byteOrder := stringOperandDecoders[goarch], callDecoders[goarch], byteOrders[goarch]
+	if operandsOf == nil || decode == nil || byteOrder == nil {
+		return nil
+	}
+	var runtimeMain, doInit *gosym.Func
+	for i := range funcs {
+		switch funcs[i].Name {
+		case "runtime.main":
+			runtimeMain = &funcs[i]
+		case "runtime.doInit":
+			doInit = &funcs[i]
+		}
+	}
+	if runtimeMain == nil || doInit == nil || runtimeMain.End <= runtimeMain.Entry {
+		return nil
+	}
+	code, err := e.raw.read_memory(runtimeMain.Entry, runtimeMain.End-runtimeMain.Entry)
+	if err != nil {
+		return nil
+	}
+
+	operands := operandsOf(code, runtimeMain.Entry)
+	var roots []uint64
+	for off := 0; off < len(code); {
+		pc := runtimeMain.Entry + uint64(off)
+		size, target, isCall := decode(code[off:], pc, byteOrder)
+		if isCall && target == doInit.Entry {
+			// the argument is set up at most a store away, ex: LEAQ runtime..inittask(SB), AX; MOVQ AX, (SP); CALL runtime.doInit(SB)
+			for i := len(operands) - 1; i >= 0; i-- {
+				if operands[i].pc < pc && operands[i].addr {
+					if pc-operands[i].pc <= 16 {
+						roots = append(roots, operands[i].value)
+					}
+					break
+				}
+			}
+		}
+		if size <= 0 {
+			size = 1
+		}
+		off += size
+	}
+	return roots
+}
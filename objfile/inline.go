@@ -0,0 +1,45 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"fmt"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+)
+
+// the largest inline tree read, the trees of real functions are a few KB
+const maxInlineTreeSize = 1 << 20
+
+// InlinedCalls decodes the inline tree of fn from the funcdata of table, the functions the compiler inlined into fn with their call
+// sites and code. moduleData gives the base of the funcdata from Go 1.18 on, without it those trees aren't found. The trees of Go
+// before 1.12 are laid out differently and aren't decoded, and up to 1.15 they are another funcdata, goVersion tells them apart as
+// the tables look the same from 1.2 to 1.15. nil without an error means nothing was inlined into fn.
+func (e *Entry) InlinedCalls(table *gosym.Table, fn *gosym.Func, moduleData *ModuleData, goVersion string) ([]gosym.InlinedCall, error) {
+	if table.Go12line == nil {
+		return nil, nil
+	}
+	minor, ok := goMinorVersion(goVersion)
+	if !ok || minor < 12 {
+		return nil, fmt.Errorf("the inline trees of Go %s aren't decoded, only those of 1.12 and later", goVersion)
+	}
+	var gofunc uint64
+	if moduleData != nil {
+		gofunc = moduleData.Gofunc
+	}
+	addr, size, ok := table.InlineTree(fn, gofunc, minor < 16)
+	if !ok {
+		return nil, nil
+	}
+	if size > maxInlineTreeSize {
+		return nil, fmt.Errorf("bad inline tree of %s", fn.Name)
+	}
+	tree, err := e.raw.read_memory(addr, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the inline tree of %s: %w", fn.Name, err)
+	}
+	calls := table.InlinedCalls(fn, tree)
+	if calls == nil {
+		return nil, fmt.Errorf("bad inline tree of %s", fn.Name)
+	}
+	return calls, nil
+}
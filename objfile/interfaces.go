@@ -0,0 +1,161 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elliotchance/orderedmap"
+)
+
+// readIMethodName reads the name of an interface method from Go 1.7 on, with the import path of an unexported one: the one following
+// the name for a method of another package than the interface's, else interfacePkgPath. The flags of the name are:
+//
+//	1<<0 the name is exported
+//	1<<1 tag data follows the name
+//	1<<2 pkgPath nameOff follows the name and tag
+func (e *Entry) readIMethodName(runtimeVersion string, moduleData *ModuleData, namePtr uint64, interfacePkgPath string, is64bit bool, littleendian bool) (name string, pkgPath string, err error) {
+	minor, ok := goMinorVersion(runtimeVersion)
+	if !ok || minor < 7 {
+		return "", "", fmt.Errorf("no name flags before Go 1.7")
+	}
+	flags, err := e.raw.read_memory(namePtr, 1)
+	if err != nil || len(flags) < 1 {
+		return "", "", fmt.Errorf("Failed to read name")
+	}
+	name, next, err := e.readNameString(minor, namePtr+1)
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to read name")
+	}
+	if flags[0]&(1<<0) != 0 {
+		return name, "", nil
+	}
+
+	pkgPath = interfacePkgPath
+	if flags[0]&(1<<1) != 0 {
+		if _, next, err = e.readNameString(minor, next); err != nil {
+			return name, pkgPath, nil
+		}
+	}
+	if flags[0]&(1<<2) != 0 {
+		if off, err := e.raw.read_memory(next, 4); err == nil && len(off) == 4 {
+			var byteOrder binary.ByteOrder = binary.LittleEndian
+			if !littleendian {
+				byteOrder = binary.BigEndian
+			}
+			if path, err := e.readRTypeName(runtimeVersion, 0, moduleData.Types+uint64(byteOrder.Uint32(off)), is64bit, littleendian); err == nil {
+				pkgPath = path
+			}
+		}
+	}
+	return name, pkgPath, nil
+}
+
+// interfaceMethod describes the method name of an interface, pkgPath qualifying an unexported one, whose func type is at typeAddr.
+// The func type is parsed, its parameters and results too.
+func (e *Entry) interfaceMethod(runtimeVersion string, moduleData *ModuleData, name string, pkgPath string, typeAddr uint64, is64bit bool, littleendian bool, parsedTypesIn *orderedmap.OrderedMap) (InterfaceMethod, *orderedmap.OrderedMap) {
+	method := InterfaceMethod{Name: name, PkgPath: pkgPath}
+	parsedTypesIn, _ = e.ParseType_impl(runtimeVersion, moduleData, typeAddr, is64bit, littleendian, parsedTypesIn)
+	found, ok := parsedTypesIn.Get(typeAddr)
+	if !ok {
+		return method, parsedTypesIn
+	}
+	funcType := found.(Type)
+	method.Type = funcType.Str
+
+	var in, out []string
+	if in, out, ok, parsedTypesIn = e.funcSignature(runtimeVersion, moduleData, &funcType, is64bit, littleendian, parsedTypesIn); ok {
+		method.Params, method.Results = in, out
+		method.Variadic = len(in) > 0 && strings.HasPrefix(in[len(in)-1], "...")
+		qualified := name
+		if len(pkgPath) > 0 {
+			qualified = pkgPath + "." + name
+		}
+		method.Signature = qualified + signatureString(in, out)
+	}
+	return method, parsedTypesIn
+}
+
+// methodKey tells the methods of interfaces apart, an unexported method is only the same one in the same package
+func methodKey(method InterfaceMethod) string {
+	return method.PkgPath + "." + method.Name + " " + method.Type
+}
+
+// AttributeEmbeddedMethods sets the From of the methods of the interfaces of lists to the named interface they're likely embedded
+// from: the one with the most methods of the named interfaces of lists whose every method the interface has, ex: io.Reader for Read
+// of io.ReadWriter. The compiler flattens embedded interfaces into the method list so which were embedded isn't recorded, an
+// interface declaring the same methods as another itself is told apart from one embedding it only by its source.
+func AttributeEmbeddedMethods(lists ...[]Type) {
+	type named struct {
+		name    string
+		methods map[string]bool
+	}
+	var candidates []named
+	seen := make(map[string]bool)
+	for _, types := range lists {
+		for _, typ := range types {
+			if typ.kindEnum != Interface || typ.flags&tflagNamed == 0 || len(typ.InterfaceMethods) == 0 || seen[typ.Str] {
+				continue
+			}
+			seen[typ.Str] = true
+			methods := make(map[string]bool, len(typ.InterfaceMethods))
+			for _, method := range typ.InterfaceMethods {
+				methods[methodKey(method)] = true
+			}
+			candidates = append(candidates, named{typ.Str, methods})
+		}
+	}
+	// the largest first, the name breaks ties so the pick doesn't depend on the order of the types
+	sort.Slice(candidates, func(i, j int) bool {
+		if len(candidates[i].methods) != len(candidates[j].methods) {
+			return len(candidates[i].methods) > len(candidates[j].methods)
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	for _, types := range lists {
+		for i := range types {
+			typ := &types[i]
+			if typ.kindEnum != Interface || len(typ.InterfaceMethods) < 2 {
+				continue
+			}
+			keys := make(map[string]bool, len(typ.InterfaceMethods))
+			for _, method := range typ.InterfaceMethods {
+				keys[methodKey(method)] = true
+			}
+			var embedded []named
+			for _, candidate := range candidates {
+				if candidate.name == typ.Str || len(candidate.methods) >= len(keys) {
+					continue
+				}
+				subset := true
+				for key := range candidate.methods {
+					if !keys[key] {
+						subset = false
+						break
+					}
+				}
+				if subset {
+					embedded = append(embedded, candidate)
+				}
+			}
+			if len(embedded) == 0 {
+				continue
+			}
+			// a copy, the method list is shared with the copies of the type the parse kept
+			methods := append([]InterfaceMethod(nil), typ.InterfaceMethods...)
+			for j := range methods {
+				methods[j].From = ""
+				for _, candidate := range embedded {
+					if candidate.methods[methodKey(methods[j])] {
+						methods[j].From = candidate.name
+						break
+					}
+				}
+			}
+			typ.InterfaceMethods = methods
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package objfile
+
+import "testing"
+
+func TestAttributeEmbeddedMethods(t *testing.T) {
+	method := func(name string, pkgPath string, typ string) InterfaceMethod {
+		return InterfaceMethod{Name: name, PkgPath: pkgPath, Type: typ}
+	}
+	read, write, close := method("Read", "", "func([]uint8) (int, error)"), method("Write", "", "func([]uint8) (int, error)"), method("Close", "", "func() error")
+	iface := func(name string, methods ...InterfaceMethod) Type {
+		return Type{Str: name, kindEnum: Interface, flags: tflagNamed, InterfaceMethods: methods}
+	}
+
+	// type Transport interface { io.ReadWriteCloser; handshake() bool }, the unexported handshake of another package is another method
+	transport := iface("main.Transport", close, read, method("handshake", "main", "func() bool"), write)
+	types := []Type{
+		iface("io.Reader", read),
+		iface("io.Writer", write),
+		iface("io.ReadWriteCloser", close, read, write),
+		iface("other.Handshaker", method("handshake", "other", "func() bool")),
+		transport,
+	}
+	// an unnamed interface is never a From
+	interfaces := []Type{{Str: "interface { Close() error; Read([]uint8) (int, error) }", kindEnum: Interface, InterfaceMethods: []InterfaceMethod{close, read}}}
+	AttributeEmbeddedMethods(types, interfaces)
+
+	from := func(typ Type) map[string]string {
+		names := make(map[string]string)
+		for _, method := range typ.InterfaceMethods {
+			names[method.Name] = method.From
+		}
+		return names
+	}
+	if got := from(types[4]); got["Read"] != "io.ReadWriteCloser" || got["Write"] != "io.ReadWriteCloser" || got["Close"] != "io.ReadWriteCloser" || got["handshake"] != "" {
+		t.Errorf("expected the methods of io.ReadWriteCloser from it, got %v", got)
+	}
+	if got := from(types[2]); got["Read"] != "io.Reader" || got["Write"] != "io.Writer" || got["Close"] != "" {
+		t.Errorf("expected Read and Write from io.Reader and io.Writer, got %v", got)
+	}
+	if got := from(interfaces[0]); got["Read"] != "io.Reader" || got["Close"] != "" {
+		t.Errorf("expected Read of the unnamed interface from io.Reader, got %v", got)
+	}
+	if got := from(types[0]); got["Read"] != "" {
+		t.Errorf("expected an interface not to be embedded from itself, got %v", got)
+	}
+	// the types the method lists were copied from aren't changed
+	if transport.InterfaceMethods[0].From != "" {
+		t.Errorf("expected the method list of the parsed type kept, got %+v", transport.InterfaceMethods[0])
+	}
+}
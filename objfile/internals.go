@@ -65,8 +65,10 @@ type GoString32 struct {
 }
 
 // https://github.com/golang/go/blob/dbd3cf884986c88f5b3350709c0f51fa02330805/src/runtime/stack.go#L583
+// the blank fields are the padding the compiler aligns the next field with, binary.Read packs the structs
 type GoBitVector64 struct {
 	Bitnum   int32
+	_        [4]byte
 	Bytedata pvoid64
 }
 
@@ -352,10 +354,12 @@ type ModuleData12_64 struct {
 	Modulename   GoString64
 	Modulehashes GoSlice64
 	Hasmain      bool
+	_            [7]byte
 	Gcdatamask   GoBitVector64
 	Gcbssmask    GoBitVector64
 	Typemap      pvoid64
 	Badload      bool
+	_            [7]byte
 	Next         pvoid64
 }
 
@@ -403,10 +407,12 @@ type ModuleData12_32 struct {
 	Modulename   GoString32
 	Modulehashes GoSlice32
 	Hasmain      bool
+	_            [3]byte
 	Gcdatamask   GoBitVector32
 	Gcbssmask    GoBitVector32
 	Typemap      pvoid32
 	Badload      bool
+	_            [3]byte
 	Next         pvoid32
 }
 
@@ -458,10 +464,12 @@ type ModuleData116_64 struct {
 	Modulename   GoString64
 	Modulehashes GoSlice64
 	Hasmain      bool
+	_            [7]byte
 	Gcdatamask   GoBitVector64
 	Gcbssmask    GoBitVector64
 	Typemap      pvoid64
 	Badload      bool
+	_            [7]byte
 	Next         pvoid64
 }
 
@@ -513,10 +521,12 @@ type ModuleData116_32 struct {
 	Modulename   GoString32
 	Modulehashes GoSlice32
 	Hasmain      bool
+	_            [3]byte
 	Gcdatamask   GoBitVector32
 	Gcbssmask    GoBitVector32
 	Typemap      pvoid32
 	Badload      bool
+	_            [3]byte
 	Next         pvoid32
 }
 
@@ -570,10 +580,12 @@ type ModuleData118_64 struct {
 	Modulename   GoString64
 	Modulehashes GoSlice64
 	Hasmain      bool
+	_            [7]byte
 	Gcdatamask   GoBitVector64
 	Gcbssmask    GoBitVector64
 	Typemap      pvoid64
 	Badload      bool
+	_            [7]byte
 	Next         pvoid64
 }
 
@@ -627,10 +639,12 @@ type ModuleData118_32 struct {
 	Modulename   GoString32
 	Modulehashes GoSlice32
 	Hasmain      bool
+	_            [3]byte
 	Gcdatamask   GoBitVector32
 	Gcbssmask    GoBitVector32
 	Typemap      pvoid32
 	Badload      bool
+	_            [3]byte
 	Next         pvoid32
 }
 
@@ -686,10 +700,12 @@ type ModuleData120_64 struct {
 	Modulename   GoString64
 	Modulehashes GoSlice64
 	Hasmain      bool
+	_            [7]byte
 	Gcdatamask   GoBitVector64
 	Gcbssmask    GoBitVector64
 	Typemap      pvoid64
 	Badload      bool
+	_            [7]byte
 	Next         pvoid64
 }
 
@@ -745,10 +761,12 @@ type ModuleData120_32 struct {
 	Modulename   GoString32
 	Modulehashes GoSlice32
 	Hasmain      bool
+	_            [3]byte
 	Gcdatamask   GoBitVector32
 	Gcbssmask    GoBitVector32
 	Typemap      pvoid32
 	Badload      bool
+	_            [3]byte
 	Next         pvoid32
 }
 
@@ -805,10 +823,12 @@ type ModuleData121_64 struct {
 	Modulename   GoString64
 	Modulehashes GoSlice64
 	Hasmain      bool
+	_            [7]byte
 	Gcdatamask   GoBitVector64
 	Gcbssmask    GoBitVector64
 	Typemap      pvoid64
 	Badload      bool
+	_            [7]byte
 	Next         pvoid64
 }
 
@@ -865,10 +885,12 @@ type ModuleData121_32 struct {
 	Modulename   GoString32
 	Modulehashes GoSlice32
 	Hasmain      bool
+	_            [3]byte
 	Gcdatamask   GoBitVector32
 	Gcbssmask    GoBitVector32
 	Typemap      pvoid32
 	Badload      bool
+	_            [3]byte
 	Next         pvoid32
 }
 
@@ -1001,6 +1023,8 @@ func (k Kind) String() string {
 		return "Uintptr"
 	case Float32:
 		return "Float32"
+	case Float64:
+		return "Float64"
 	case Complex64:
 		return "Complex64"
 	case Complex128:
@@ -1275,35 +1299,109 @@ func (rtype *Rtype114_115_116_117_118_32) parse(rawData []byte, littleEndian boo
 	return binary.Read(srcBytes, byteOrder, rtype)
 }
 
+// StructField is a field of a struct type, Type is the Str of its type and empty when that didn't parse
+type StructField struct {
+	Name     string
+	Type     string
+	TypeVA   uint64
+	Offset   uint64
+	Embedded bool
+	Tag      string
+}
+
+// InterfaceMethod is a method of an interface type, Type is the Str of its func type
+type InterfaceMethod struct {
+	Name string
+	Type string
+	// the import path of an unexported method, two interfaces have the same unexported method only in the same package
+	PkgPath string
+	// the method as it's declared, the name qualified by PkgPath, ex: Read([]uint8) (int, error)
+	Signature string
+	Params    []string // the types of the parameters, ...T for the last one of a Variadic method
+	Results   []string
+	Variadic  bool
+	// the named interface the method is likely embedded from, see AttributeEmbeddedMethods
+	From string
+}
+
 // This is a general structure that just holds the fields I care about
 // this lets us return a single type, even though rtypes change between go version
 type Type struct {
 	VA             uint64
+	RVA            uint64  // VA relative to the RVABase of the file
+	FileOffset     *uint64 // of VA, null when the type isn't in the file, ex: in a dump
+	Size           uint64  // of a value of the type in bytes
+	PtrBytes       uint64  // the prefix of a value that can hold pointers, in bytes, ptrdata
+	Align          uint8   // of a value of the type in bytes
 	Str            string
 	CStr           string
 	Kind           string
-	Reconstructed  string `json:",omitempty"` // for Some types we can reconstruct the original definition back to Go code
-	CReconstructed string `json:",omitempty"` // for Some types we can reconstruct the original definition back to C code
+	Reconstructed  string   // for Some types we can reconstruct the original definition back to Go code
+	CReconstructed string   // for Some types we can reconstruct the original definition back to C code
+	Demangled      string   // for generic instantiations, Str with GC shape types collapsed to their underlying type
+	TypeArgs       []string // for generic instantiations, the type arguments
+	GenericNote    string   // for generic instantiations, explains shape types or elided arguments
+	Shape          bool     // a GC shape type the compiler synthesized for shared generic code, not a type of the program
+	Methods        []Method // for types with an uncommonType, their methods with the functions implementing them, only with SetMethods
+	PkgPath        string   // for types with an uncommonType, the import path of the package declaring them
+	// for named types from Go 1.7 on, the type they're declared as, ex: map[string][]string for http.Header. Structs and interfaces
+	// list their Fields and InterfaceMethods instead.
+	Underlying       string
+	Fields           []StructField     // for structs, the fields in offset order
+	InterfaceMethods []InterfaceMethod // for interfaces, the methods
+	// "recovered without typelinks" for the types ScanTypes found in the read only data, when the moduledata's typelinks were unusable
+	Recovery string
+	// the words of a value that hold pointers, decoded from gcdata, only with SetGCData
+	GC *GCLayout
 
 	// rtypes change between runtime versions. Depending on the 'Kind' additional data follows the 'base' rtype.
 	// We store the size so that this base type can be skipped past, and the additional data read directly in a version independant way.
 	baseSize uint16
 	kindEnum Kind
 	flags    tflag
+	// the gcdata of the rtype and its kind byte before the mask, which flags a GC program up to Go 1.23
+	gcdata    uint64
+	kindFlags uint8
 }
 
 // This is a general structure that just holds the fields I care about
 // this lets us return a single type, even though moduledata changes between go version
 type ModuleData struct {
-	VA        uint64
-	TextVA    uint64    // adjusted (ex: CGO) .text base that pclntab offsets are relative to
-	Types     uint64    // points to type information
-	ETypes    uint64    // points to end of type information
-	Typelinks GoSlice64 // points to metadata about offsets into types for structures and other types
-	ITablinks GoSlice64 // points to metadata about offsets into types for interfaces
+	VA         uint64
+	RVA        uint64    // VA relative to the RVABase of the file
+	FileOffset *uint64   // of VA, null when the moduledata isn't in the file, ex: in a dump
+	TextVA     uint64    // adjusted (ex: CGO) .text base that pclntab offsets are relative to
+	ETextVA    uint64    // end of the text, the module covers the PCs up to it
+	Types      uint64    // points to type information
+	ETypes     uint64    // points to end of type information
+	Gofunc     uint64    // the go:func.* symbol the funcdata of the pclntab are relative to, >= 1.18
+	Typelinks  GoSlice64 // points to metadata about offsets into types for structures and other types
+	ITablinks  GoSlice64 // points to metadata about offsets into types for interfaces
+
+	// initialized data, pointer free (noptrdata) and with pointers (data)
+	Noptrdata  uint64
+	Enoptrdata uint64
+	Data       uint64
+	Edata      uint64
 
 	// Some versions of go with 1.2 moduledata use a slice instead of the types + offset typelinks list
 	LegacyTypes GoSlice64
+
+	// where the offsets of the fields came from, LayoutTable or LayoutProbed, and the byte offset of each field probed by name
+	LayoutSource  string
+	ProbedOffsets map[string]uint64
+
+	PluginPath string        // set for a module loaded by plugin.Open, >= 1.8
+	initTasks  GoSlice64     // the []*initTask in the order they run, >= 1.21
+	next       uint64        // the moduledata of the next module
+	modules    []*ModuleData // the whole list once walked, see ModuleDataList
+	layout     string        // the version of the moduledata struct that validated
+}
+
+// Layout is the version of the moduledata struct that validated, named after the first pclntab version using it, ex: '1.18'.
+// 1.21 changed the struct but kept the 1.20 pclntab, its layout is '1.21'. Empty when the layout was probed.
+func (m *ModuleData) Layout() string {
+	return m.layout
 }
 
 const (
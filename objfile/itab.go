@@ -0,0 +1,106 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"unsafe"
+)
+
+// the most methods of an interface read, more is a bogus slice
+const maxItabMethods = 4096
+
+// Itab is an interface table of the itablinks, Type implementing Interface. Methods are those of the interface in the order of
+// its method table, sorted by name, each with the function of Type implementing it.
+type Itab struct {
+	VA        uint64
+	Interface string
+	Type      string
+	Methods   []ItabMethod
+}
+
+// ItabMethod is a slot of the method table of an itab, VA is 0 when the slot is empty
+type ItabMethod struct {
+	Name string
+	VA   uint64
+}
+
+// itabFunOffset is where the method table of an itab starts, after inter, _type and hash. Go 1.7 to 1.9 have a link pointer and
+// flags in between, and from Go 1.23 on hash isn't padded to 8 bytes on 32bit.
+//
+//	type itab struct {
+//		inter *interfacetype
+//		_type *_type
+//		hash  uint32 // copy of _type.hash. Used for type switches.
+//		_     [4]byte
+//		fun   [1]uintptr // variable sized. fun[0]==0 means _type does not implement inter.
+//	}
+func itabFunOffset(minor int, ptrSize uint64) uint64 {
+	switch {
+	case minor < 10:
+		return 3*ptrSize + 8
+	case minor >= 23:
+		return 2*ptrSize + max(4, ptrSize)
+	}
+	return 2*ptrSize + 8
+}
+
+// interfaceMethodNames reads the method names of the interface type iface in the order of its method table, nil before Go 1.7
+// where the imethods are pointers
+func (e *Entry) interfaceMethodNames(runtimeVersion string, moduleData *ModuleData, iface Type, is64bit bool, littleendian bool) []string {
+	minor, ok := goMinorVersion(runtimeVersion)
+	if !ok || minor < 7 || iface.kindEnum != Interface {
+		return nil
+	}
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+	// the methods slice follows the rtype and the pkgPath name
+	methodsAddr := iface.VA + uint64(iface.baseSize) + ptrSize
+	data, err := e.raw.read_memory(methodsAddr, 2*ptrSize)
+	if err != nil {
+		return nil
+	}
+	methodsData := decodePtrSizeBytes(data[:ptrSize], is64bit, littleendian)
+	count := decodePtrSizeBytes(data[ptrSize:], is64bit, littleendian)
+	if count > maxItabMethods {
+		return nil
+	}
+
+	entrySize := uint64(unsafe.Sizeof(IMethod{}))
+	names := make([]string, 0, count)
+	for i := uint64(0); i < count; i++ {
+		imethoddata, err := e.raw.read_memory(methodsData+entrySize*i, entrySize)
+		if err != nil {
+			return nil
+		}
+		var method IMethod
+		if err := method.parse(imethoddata, littleendian); err != nil {
+			return nil
+		}
+		name, err := e.readRTypeName(runtimeVersion, 0, moduleData.Types+uint64(method.Name), is64bit, littleendian)
+		if err != nil {
+			return nil
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// readItab reads the method table of the itab at itabAddr for the interface iface implemented by the type named concrete. The
+// method names are left out when the interface's don't read, the table is as long as they are.
+func (e *Entry) readItab(runtimeVersion string, moduleData *ModuleData, itabAddr uint64, iface Type, concrete string, is64bit bool, littleendian bool) Itab {
+	itab := Itab{VA: itabAddr, Interface: iface.Str, Type: concrete}
+	names := e.interfaceMethodNames(runtimeVersion, moduleData, iface, is64bit, littleendian)
+	minor, _ := goMinorVersion(runtimeVersion)
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+	fun := itabAddr + itabFunOffset(minor, ptrSize)
+	for i, name := range names {
+		// a slot that doesn't read is empty, like one the linker left 0
+		va, _ := e.ReadPointerSizeMem(fun+uint64(i)*ptrSize, is64bit, littleendian)
+		itab.Methods = append(itab.Methods, ItabMethod{Name: name, VA: va})
+	}
+	return itab
+}
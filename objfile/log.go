@@ -0,0 +1,23 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+// Logger receives the progress of the scans of a File as they run, for the -verbose logs of the command. level 1 is for the decisions,
+// ex: the sections scanned and the moduledata picked, level 2 for the detail, ex: every signature match. It may be called from
+// several goroutines at once.
+type Logger func(level int, format string, args ...interface{})
+
+// Printf calls l, a nil Logger discards the message
+func (l Logger) Printf(level int, format string, args ...interface{}) {
+	if l != nil {
+		l(level, format, args...)
+	}
+}
+
+// SetLogger sets the Logger of the signature scan, the pclntab candidates and the moduledata candidates. The signature scan is only
+// logged with SetDiagnostics on. nil, the default, logs nothing.
+func (e *Entry) SetLogger(l Logger) {
+	e.log = l
+	if diag := e.scanDiagnostics(); diag != nil {
+		diag.log = l
+	}
+}
@@ -10,7 +10,9 @@ package objfile
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"sort"
@@ -22,18 +24,73 @@ import (
 const stabTypeMask = 0xe0
 
 type machoFile struct {
-	macho *macho.File
+	macho          *macho.File
+	slice          *io.SectionReader         // the slice of a fat Mach-O, nil for a thin one
+	firstMatchOnly bool                      // stop the moduledata signature scan at the first validated match
+	scanWorkers    int                       // goroutines per section for the signature scan, 0 for GOMAXPROCS
+	diagnostics    *scanDiagnostics          // nil unless SetDiagnostics
+	ctx            context.Context           // nil unless SetContext
+	scanRanges     []ScanRange               // nil unless SetScanRanges
+	signatures     []compiledCustomSignature // nil unless SetSignatures
+	fixups         []chainedFixup            // the pointers of the LC_DYLD_CHAINED_FIXUPS, nil for a file with rebase opcodes
 }
 
-func openMacho(r io.ReaderAt) (rawFile, error) {
+func openMacho(r io.ReaderAt, opts OpenOptions) (rawFile, error) {
 	f, err := macho.NewFile(r)
 	if err != nil {
 		return nil, err
 	}
-	return &machoFile{f}, nil
+	mf := &machoFile{macho: f}
+	mf.fixups = mf.chainedFixups()
+	return mf, nil
 }
 
+// openFatMacho opens the slice of a fat Mach-O OpenOptions.FatArch picks, each slice is a whole Mach-O at its own offset with its own VAs
+func openFatMacho(r io.ReaderAt, opts OpenOptions) (rawFile, error) {
+	ff, err := macho.NewFatFile(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, arch := range ff.Arches {
+		f := &machoFile{macho: arch.File, slice: io.NewSectionReader(r, int64(arch.Offset), int64(arch.Size))}
+		if len(opts.FatArch) == 0 || f.goarch() == opts.FatArch {
+			f.fixups = f.chainedFixups()
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("the fat Mach-O has no %s slice", opts.FatArch)
+}
+
+// FatArchs lists the GOARCH of every slice of a fat Mach-O, in file order. Other files have none.
+func FatArchs(name string) ([]string, error) {
+	ff, err := macho.OpenFat(name)
+	var formatErr *macho.FormatError
+	if errors.As(err, &formatErr) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer ff.Close()
+
+	var archs []string
+	for _, arch := range ff.Arches {
+		archs = append(archs, (&machoFile{macho: arch.File}).goarch())
+	}
+	return archs, nil
+}
+
+// read_memory reads the memory at VA with the chained fixups in it decoded
 func (f *machoFile) read_memory(VA uint64, size uint64) (data []byte, err error) {
+	data, err = f.readSegment(VA, size)
+	if err != nil {
+		return nil, err
+	}
+	return f.applyFixups(data, VA), nil
+}
+
+// readSegment reads the memory at VA as it is in the file
+func (f *machoFile) readSegment(VA uint64, size uint64) (data []byte, err error) {
 	for _, load := range f.macho.Loads {
 		seg, ok := load.(*macho.Segment)
 		if !ok {
@@ -47,12 +104,7 @@ func (f *machoFile) read_memory(VA uint64, size uint64) (data []byte, err error)
 			if n > size {
 				n = size
 			}
-			data := make([]byte, n)
-			_, err := seg.ReadAt(data, int64(VA-seg.Addr))
-			if err != nil {
-				return nil, err
-			}
-			return data, nil
+			return readAvailable(seg, make([]byte, n), int64(VA-seg.Addr), VA)
 		}
 	}
 	return nil, fmt.Errorf("Failed to read memory")
@@ -161,13 +213,9 @@ func (f *machoFile) pcln_scan() (candidates <-chan PclntabCandidate, err error)
 
 		if !has_some_valid_magic {
 			for _, magic := range append(pclntab_sigs_le, pclntab_sigs_be...) {
-				pclntab_copy := make([]byte, len(candidate.Pclntab))
-				copy(pclntab_copy, candidate.Pclntab)
-				copy(pclntab_copy, magic)
-
-				new_candidate := candidate
-				new_candidate.Pclntab = pclntab_copy
-				send_tab(new_candidate)
+				new_candidate := *candidate
+				new_candidate.setMagic(candidate.Pclntab, magic)
+				send_tab(&new_candidate)
 			}
 		}
 	}
@@ -184,28 +232,22 @@ func (f *machoFile) pcln_scan() (candidates <-chan PclntabCandidate, err error)
 
 				if stompedMagicCandidate.LittleEndian {
 					for _, magicLE := range pclntab_sigs_le {
-						pclntab_copy := make([]byte, len(pclntab))
-						copy(pclntab_copy, pclntab)
-						copy(pclntab_copy, magicLE)
-
 						var candidate PclntabCandidate
 						candidate.StompMagicCandidateMeta = stompedMagicCandidate
-						candidate.Pclntab = pclntab_copy
+						candidate.setMagic(pclntab, magicLE)
 						candidate.SecStart = uint64(sec.Addr)
+						candidate.Location = sec.Name
 						candidate.PclntabVA = pclntab_va_candidate
 
 						send_tab(&candidate)
 					}
 				} else {
 					for _, magicBE := range pclntab_sigs_be {
-						pclntab_copy := make([]byte, len(pclntab))
-						copy(pclntab_copy, pclntab)
-						copy(pclntab_copy, magicBE)
-
 						var candidate PclntabCandidate
 						candidate.StompMagicCandidateMeta = stompedMagicCandidate
-						candidate.Pclntab = pclntab_copy
+						candidate.setMagic(pclntab, magicBE)
 						candidate.SecStart = uint64(sec.Addr)
+						candidate.Location = sec.Name
 						candidate.PclntabVA = pclntab_va_candidate
 
 						send_tab(&candidate)
@@ -217,14 +259,25 @@ func (f *machoFile) pcln_scan() (candidates <-chan PclntabCandidate, err error)
 	}
 
 	// 2) if not found, byte scan for it
+	f.diagnostics.reset()
 	go func() {
 		defer close(ch_tab)
 		for _, sec := range f.macho.Sections {
 			// malware can split the pclntab across multiple sections, re-merge
 			data := f.macho.DataAfterSection(sec)
 
+			const (
+				attrPureInstructions = 0x80000000
+				attrSomeInstructions = 0x400
+			)
+			windows := scanWindows(f.scanRanges, sec.Addr, true, uint64(sec.Offset), sec.Offset != 0, sec.Size, len(data))
+			if len(windows) == 0 {
+				continue
+			}
+			f.diagnostics.section(sec.Seg+" "+sec.Name, sec.Addr, sec.Size, sec.Flags&(attrPureInstructions|attrSomeInstructions) != 0, scannedBytes(f.scanRanges, windows))
+
 			if !foundpcln {
-				matches := findAllOccurrences(data, pclntab_sigs)
+				matches := findWindowOccurrences(data, windows, pclntab_sigs)
 				for _, pclntab_idx := range matches {
 					if pclntab_idx != -1 && pclntab_idx < int(sec.Size) {
 						pclntab = data[pclntab_idx:]
@@ -233,6 +286,7 @@ func (f *machoFile) pcln_scan() (candidates <-chan PclntabCandidate, err error)
 						candidate.Pclntab = pclntab
 
 						candidate.SecStart = uint64(sec.Addr)
+						candidate.Location = sec.Name
 						candidate.PclntabVA = candidate.SecStart + uint64(pclntab_idx)
 
 						send_patched_magic_candidates(&candidate)
@@ -243,11 +297,12 @@ func (f *machoFile) pcln_scan() (candidates <-chan PclntabCandidate, err error)
 			} else {
 				// 3) if we found it earlier, figure out which section base to return (might be wrong for packed things)
 				pclntab_idx := bytes.Index(data, pclntab)
-				if pclntab_idx != -1 {
+				if pclntab_idx != -1 && inWindows(windows, pclntab_idx) {
 					var candidate PclntabCandidate
 					candidate.Pclntab = pclntab
 
 					candidate.SecStart = uint64(sec.Addr)
+					candidate.Location = sec.Name
 					candidate.PclntabVA = candidate.SecStart + uint64(pclntab_idx)
 
 					send_patched_magic_candidates(&candidate)
@@ -257,7 +312,11 @@ func (f *machoFile) pcln_scan() (candidates <-chan PclntabCandidate, err error)
 
 			// 4) Always try this other way! Sometimes the pclntab magic is stomped as well so our byte OR symbol location fail. Byte scan for the moduledata, use that to find the pclntab instead, fix up magic with all combinations.
 			// See the obfuscator 'garble' for an example of randomizing the pclntab magic
-			sigResults := findModuleInitPCHeader(data, sec.Addr)
+			var sigResults []SignatureMatch
+			for _, w := range windows {
+				sigResults = append(sigResults, findModuleInitPCHeaderWorkers(f.ctx, data[w.start:w.end], sec.Addr+uint64(w.start), f.macho.ByteOrder, f.goarch(), f.signatures, 0, f.scanWorkers, pcHeaderValidator(f.firstMatchOnly, f.read_memory), f.diagnostics)...)
+			}
+			sigResults = rankSignatureMatches(sigResults, f.read_memory, f.diagnostics)
 			for _, sigResult := range sigResults {
 				// example: off_69D0C0 is the moduleData we found via our scan, the first ptr unk_5DF6E0, is the pclntab!
 				// 0x000000000069D0C0 E0 F6 5D 00 00 00 00 00 off_69D0C0      dq offset unk_5DF6E0    ; DATA XREF: runtime_SetFinalizer+119↑o
@@ -320,41 +379,17 @@ func (f *machoFile) moduledata_scan(pclntabVA uint64, is64bit bool, littleendian
 	var secStart uint64
 	var moduledata []uint8
 	var moduledataVA uint64
-scan:
 	for _, sec := range f.macho.Sections {
 		// malware can split the pclntab across multiple sections, re-merge
 		data := f.macho.DataAfterSection(sec)
+		// the pointer to the pclntab is a chain entry when the file has chained fixups, only the section is decoded for the scan
+		scanned := f.applyFixups(data[:min(len(data), int(sec.Size)+8)], sec.Addr)
 		// fall back to scanning for structure using address of pclntab, which is first value in struc
-		var pclntabVA_bytes []byte
-		if is64bit {
-			pclntabVA_bytes = make([]byte, 8)
-			if littleendian {
-				binary.LittleEndian.PutUint64(pclntabVA_bytes, pclntabVA)
-			} else {
-				binary.BigEndian.PutUint64(pclntabVA_bytes, pclntabVA)
-			}
-		} else {
-			pclntabVA_bytes = make([]byte, 4)
-			if littleendian {
-				binary.LittleEndian.PutUint32(pclntabVA_bytes, uint32(pclntabVA))
-			} else {
-				binary.BigEndian.PutUint32(pclntabVA_bytes, uint32(pclntabVA))
-			}
-		}
-
-		moduledata_idx := bytes.Index(data, pclntabVA_bytes)
-		if moduledata_idx != -1 && moduledata_idx < int(sec.Size) {
-			moduledata = data[moduledata_idx:]
+		moduledata_idx := findModuleDataPointer(scanned, int(sec.Size), sec.Addr, pclntabVA, is64bit, littleendian, ignorelist)
+		if moduledata_idx != -1 {
+			moduledata = f.applyFixups(data[moduledata_idx:], sec.Addr+uint64(moduledata_idx))
 			moduledataVA = sec.Addr + uint64(moduledata_idx)
 			secStart = sec.Addr
-
-			// optionally consult ignore list, to skip past previous (bad) scan results
-			for _, ignore := range ignorelist {
-				if ignore == moduledataVA {
-					continue scan
-				}
-			}
-
 			found = true
 			break
 		}
@@ -387,6 +422,11 @@ func (f *machoFile) rdata() (textStart uint64, text []byte, err error) {
 	return
 }
 
+// endian is the byte order of the Mach-O magic
+func (f *machoFile) endian() binary.ByteOrder {
+	return f.macho.ByteOrder
+}
+
 func (f *machoFile) goarch() string {
 	switch f.macho.Cpu {
 	case macho.Cpu386:
@@ -419,3 +459,11 @@ func (f *machoFile) loadAddress() (uint64, error) {
 func (f *machoFile) dwarf() (*dwarf.Data, error) {
 	return f.macho.DWARF()
 }
+
+// FatSlice is the slice of a fat Mach-O the entry was opened from, for reading the slice alone, ex: its build info. Nil for other files.
+func (e *Entry) FatSlice() *io.SectionReader {
+	if f, ok := e.raw.(*machoFile); ok {
+		return f.slice
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mandiant/GoReSym/debug/macho"
+)
+
+// fakeFat is a fat Mach-O of empty amd64 and arm64 executables, with the 64 bit fat_arch headers when wide
+func fakeFat(wide bool) []byte {
+	var fat bytes.Buffer
+	magic := macho.MagicFat
+	if wide {
+		magic = macho.MagicFat64
+	}
+	binary.Write(&fat, binary.BigEndian, []uint32{magic, 2})
+
+	cpus := []macho.Cpu{macho.CpuAmd64, macho.CpuArm64}
+	for i, cpu := range cpus {
+		offset := uint32(0x1000 * (i + 1))
+		if wide {
+			binary.Write(&fat, binary.BigEndian, []uint32{uint32(cpu), 0})
+			binary.Write(&fat, binary.BigEndian, []uint64{uint64(offset), 0x1000})
+			binary.Write(&fat, binary.BigEndian, []uint32{12, 0})
+		} else {
+			binary.Write(&fat, binary.BigEndian, []uint32{uint32(cpu), 0, offset, 0x1000, 12})
+		}
+	}
+	for _, cpu := range cpus {
+		fat.Write(make([]byte, 0x1000-fat.Len()%0x1000))
+		binary.Write(&fat, binary.LittleEndian, []uint32{macho.Magic64, uint32(cpu), 0, uint32(macho.TypeExec), 0, 0, 0, 0})
+	}
+	fat.Write(make([]byte, 0x1000-fat.Len()%0x1000))
+	return fat.Bytes()
+}
+
+func TestFatMacho(t *testing.T) {
+	for _, wide := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "fat")
+		if err := os.WriteFile(path, fakeFat(wide), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		archs, err := FatArchs(path)
+		if err != nil || len(archs) != 2 || archs[0] != "amd64" || archs[1] != "arm64" {
+			t.Errorf("wide %t: expected amd64 and arm64 slices, got %v %v", wide, archs, err)
+		}
+
+		for _, c := range []struct {
+			goarch   string
+			expected string
+			cpu      macho.Cpu
+		}{
+			{"", "amd64", macho.CpuAmd64},
+			{"arm64", "arm64", macho.CpuArm64},
+		} {
+			raw, err := openFatMacho(bytes.NewReader(fakeFat(wide)), OpenOptions{FatArch: c.goarch})
+			if err != nil {
+				t.Fatalf("wide %t, %q: %s", wide, c.goarch, err)
+			}
+			slice := (&Entry{raw: raw}).FatSlice()
+			if raw.goarch() != c.expected || slice == nil {
+				t.Fatalf("wide %t, %q: expected the %s slice, got %s", wide, c.goarch, c.expected, raw.goarch())
+			}
+			// the slice starts with its own Mach-O header
+			header := make([]byte, 8)
+			if _, err := slice.ReadAt(header, 0); err != nil || binary.LittleEndian.Uint32(header) != macho.Magic64 || macho.Cpu(binary.LittleEndian.Uint32(header[4:])) != c.cpu {
+				t.Errorf("wide %t, %q: expected the slice to start at its header, got %x %v", wide, c.goarch, header, err)
+			}
+		}
+
+		if _, err := openFatMacho(bytes.NewReader(fakeFat(wide)), OpenOptions{FatArch: "386"}); err == nil {
+			t.Errorf("wide %t: expected an error for a missing slice", wide)
+		}
+	}
+
+	if archs, err := FatArchs(os.Args[0]); err != nil || archs != nil {
+		t.Errorf("expected no slices for a thin binary, got %v %v", archs, err)
+	}
+}
+
+func TestChainedFixupFormats(t *testing.T) {
+	const base = 0x100000000
+	for _, c := range []struct {
+		format   uint16
+		stride   uint64
+		entries  []uint64 // without their next
+		expected []uint64
+	}{
+		{chainedPtr64, 4, []uint64{0x100001234, 0x12<<36 | 0x100000010, 1 << 63}, []uint64{0x100001234, 0x12<<56 | 0x100000010, 0}},
+		{chainedPtr64Offset, 4, []uint64{0x1234, 1 << 63}, []uint64{0x100001234, 0}},
+		{chainedPtrArm64e, 8, []uint64{0x100001234, 1<<63 | 0x77<<32 | 0x40, 1<<63 | 1<<62}, []uint64{0x100001234, 0x100000040, 0}},
+		{chainedPtrArm64eUserland, 8, []uint64{0x1234, 1<<63 | 0x40}, []uint64{0x100001234, 0x100000040}},
+		{chainedPtrArm64eUserland24, 8, []uint64{0x1234, 1 << 62}, []uint64{0x100001234, 0}},
+	} {
+		// an entry every 16 bytes from 8 bytes into the page
+		page := make([]byte, 0x100)
+		for i, entry := range c.entries {
+			if i+1 < len(c.entries) {
+				entry |= 16 / c.stride << 51
+			}
+			binary.LittleEndian.PutUint64(page[8+16*i:], entry)
+		}
+		fixups := walkChain(page, base+0x4000, 8, c.format, base, binary.LittleEndian)
+		if len(fixups) != len(c.expected) {
+			t.Fatalf("format %d: expected %d fixups, got %v", c.format, len(c.expected), fixups)
+		}
+		for i, fixup := range fixups {
+			if fixup.VA != base+0x4000+8+16*uint64(i) || fixup.value != c.expected[i] {
+				t.Errorf("format %d: expected 0x%x at 0x%x, got 0x%x at 0x%x", c.format, c.expected[i], base+0x4000+8+16*i, fixup.value, fixup.VA)
+			}
+		}
+	}
+
+	if fixups := walkChain(make([]byte, 0x100), base, 0, 7, base, binary.LittleEndian); len(fixups) != 0 {
+		t.Errorf("expected no fixups of the kernel format, got %v", fixups)
+	}
+
+	// a read starting in the middle of an entry gets the rest of its pointer
+	f := &machoFile{macho: &macho.File{ByteOrder: binary.LittleEndian}, fixups: []chainedFixup{{0x1000, 0x1122334455667788}, {0x1010, 0x99}}}
+	data := make([]byte, 12)
+	fixed := f.applyFixups(data, 0x1004)
+	if binary.LittleEndian.Uint32(fixed) != 0x11223344 || binary.LittleEndian.Uint64(fixed[4:]) != 0 || binary.LittleEndian.Uint64(data) != 0 {
+		t.Errorf("expected the high half of the first pointer and a copy of the data, got %x", fixed)
+	}
+	if untouched := f.applyFixups(data[:8], 0x1008); &untouched[0] != &data[0] {
+		t.Errorf("expected data without entries as it is")
+	}
+}
@@ -0,0 +1,131 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+)
+
+// the most methods of a type read, more is a bogus uncommonType
+const maxTypeMethods = 1 << 16
+
+// Method is a method of a type from the method table of its uncommonType. VA is the method, called directly, and InterfaceVA what
+// an interface call runs: the wrapper taking a pointer receiver for a value an interface holds indirectly, else the method itself. A VA
+// is nil when the linker eliminated the function, ex: a method nothing calls.
+type Method struct {
+	Name        string
+	VA          *uint64
+	InterfaceVA *uint64
+}
+
+// uncommonOffset is the size of the data of the kind after the rtype, the uncommonType follows it
+func uncommonOffset(minor int, kind Kind, ptrSize uint64) uint64 {
+	switch kind {
+	case Array:
+		// elem, slice, len
+		return 3 * ptrSize
+	case Chan:
+		// elem, dir
+		return 2 * ptrSize
+	case Func:
+		// inCount, outCount, padded. The parameter types follow the uncommonType.
+		return ptrSize
+	case Interface, Struct:
+		// pkgPath, then the methods or the fields slice
+		return 4 * ptrSize
+	case Map:
+		// key, elem and bucket then the sizes and flags, 1.7 to 1.10 had the hmap type after bucket and 1.14 on the hasher
+		if minor >= 11 && minor < 14 {
+			return 3*ptrSize + 8
+		}
+		return 4*ptrSize + 8
+	case Pointer, Slice:
+		// elem
+		return ptrSize
+	}
+	return 0
+}
+
+// textVA resolves a textOff of the module, nil for the -1 the linker writes for eliminated functions, 0 before 1.16, and offsets past
+// the text
+func textVA(minor int, moduleData *ModuleData, off uint32) *uint64 {
+	if off == ^uint32(0) || (off == 0 && minor < 16) || (moduleData.ETextVA > 0 && moduleData.TextVA+uint64(off) >= moduleData.ETextVA) {
+		return nil
+	}
+	va := moduleData.TextVA + uint64(off)
+	return &va
+}
+
+// typeMethods decodes the package path and the method table of the uncommonType of typ, from Go 1.7 on. Types without one have
+// neither, the method table is only decoded with SetMethods.
+//
+//	type uncommonType struct {
+//		pkgPath nameOff // import path; empty for built-in types like int, string
+//		mcount  uint16  // number of methods
+//		xcount  uint16  // number of exported methods
+//		moff    uint32  // offset from this uncommontype to [mcount]method
+//		_       uint32  // unused
+//	}
+//
+//	type method struct {
+//		name nameOff // name of method
+//		mtyp typeOff // method type (without receiver)
+//		ifn  textOff // fn used in interface call (one-word receiver)
+//		tfn  textOff // fn used for normal method call
+//	}
+func (e *Entry) typeMethods(runtimeVersion string, moduleData *ModuleData, typ Type, is64bit bool, littleendian bool) (string, []Method) {
+	minor, ok := goMinorVersion(runtimeVersion)
+	if !ok || minor < 7 || typ.flags&tflagUncommon == 0 {
+		return "", nil
+	}
+	// a plugin's types are relative to their own module
+	if typ.VA < moduleData.Types || typ.VA >= moduleData.ETypes {
+		for _, module := range moduleData.modules {
+			if typ.VA >= module.Types && typ.VA < module.ETypes {
+				moduleData = module
+				break
+			}
+		}
+	}
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	if !littleendian {
+		byteOrder = binary.BigEndian
+	}
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+
+	uncommonAddr := typ.VA + uint64(typ.baseSize) + uncommonOffset(minor, typ.kindEnum, ptrSize)
+	uncommon, err := e.raw.read_memory(uncommonAddr, 12)
+	if err != nil || len(uncommon) < 12 {
+		return "", nil
+	}
+	var pkgPath string
+	if off := byteOrder.Uint32(uncommon); off != 0 {
+		pkgPath, _ = e.readRTypeName(runtimeVersion, 0, moduleData.Types+uint64(off), is64bit, littleendian)
+	}
+	count := uint64(byteOrder.Uint16(uncommon[4:]))
+	// 1.7 has a 16 bit moff right after mcount
+	moff := uint64(byteOrder.Uint32(uncommon[8:]))
+	if minor == 7 {
+		moff = uint64(byteOrder.Uint16(uncommon[6:]))
+	}
+	if !e.methods || count == 0 || count > maxTypeMethods {
+		return pkgPath, nil
+	}
+	table, err := e.raw.read_memory(uncommonAddr+moff, count*16)
+	if err != nil {
+		return pkgPath, nil
+	}
+
+	methods := make([]Method, 0, count)
+	for i := uint64(0); i < count; i++ {
+		entry := table[i*16 : (i+1)*16]
+		name, err := e.readRTypeName(runtimeVersion, 0, moduleData.Types+uint64(byteOrder.Uint32(entry)), is64bit, littleendian)
+		if err != nil {
+			return pkgPath, nil
+		}
+		methods = append(methods, Method{Name: name, InterfaceVA: textVA(minor, moduleData, byteOrder.Uint32(entry[8:])), VA: textVA(minor, moduleData, byteOrder.Uint32(entry[12:]))})
+	}
+	return pkgPath, methods
+}
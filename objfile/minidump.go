@@ -0,0 +1,202 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf16"
+)
+
+// minidump stream types, from minidumpapiset.h
+const (
+	moduleListStream     = 4
+	memoryListStream     = 5
+	systemInfoStream     = 7
+	memory64ListStream   = 9
+	memoryInfoListStream = 16
+)
+
+// the protections of MINIDUMP_MEMORY_INFO allowing execution: PAGE_EXECUTE and its read, write and copy variants
+const pageExecuteMask = 0xf0
+
+// the ProcessorArchitecture of MINIDUMP_SYSTEM_INFO as a GOARCH
+var minidumpGoarch = map[uint16]string{0: "386", 5: "arm", 9: "amd64", 12: "arm64"}
+
+type minidumpRange struct {
+	start uint64
+	size  uint64
+	rva   uint64
+}
+
+type minidumpInfo struct {
+	base    uint64
+	size    uint64
+	protect uint32
+}
+
+// openMinidump opens a Windows minidump, ex: from procdump, as a dump of the process: its memory ranges at their VAs, named after the module
+// holding them. The first module of the ModuleList is the main executable, injected DLLs follow it. Pages the dump left out are missing, see DumpInfo.Gaps.
+func openMinidump(r io.ReaderAt, opts OpenOptions) (rawFile, error) {
+	header := make([]byte, 32)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+	if string(header[:4]) != "MDMP" {
+		return nil, errors.New("not a minidump")
+	}
+	le := binary.LittleEndian
+
+	streams := make(map[uint32][]byte)
+	count := le.Uint32(header[8:])
+	directory := make([]byte, 12*uint64(count))
+	if _, err := r.ReadAt(directory, int64(le.Uint32(header[12:]))); err != nil {
+		return nil, fmt.Errorf("minidump stream directory: %w", err)
+	}
+	for i := uint32(0); i < count; i++ {
+		entry := directory[12*i:]
+		switch streamType := le.Uint32(entry); streamType {
+		case moduleListStream, memoryListStream, systemInfoStream, memory64ListStream, memoryInfoListStream:
+			stream := make([]byte, le.Uint32(entry[4:]))
+			if _, err := r.ReadAt(stream, int64(le.Uint32(entry[8:]))); err != nil {
+				return nil, fmt.Errorf("minidump stream %d: %w", streamType, err)
+			}
+			streams[streamType] = stream
+		}
+	}
+
+	f := &dumpFile{format: "minidump", arch: opts.DumpGoarch, byteOrder: le}
+	if info := streams[systemInfoStream]; len(info) >= 2 {
+		if goarch, ok := minidumpGoarch[le.Uint16(info)]; ok {
+			f.arch = goarch
+		}
+	}
+	if len(f.arch) == 0 {
+		return nil, errors.New("the minidump has no known processor architecture, pass a GOARCH")
+	}
+	f.mappings = minidumpModules(r, streams[moduleListStream])
+	infos := minidumpMemoryInfo(streams[memoryInfoListStream])
+
+	found := false
+	for _, mem := range minidumpRanges(streams[memoryListStream], streams[memory64ListStream]) {
+		data := make([]byte, mem.size)
+		if n, err := r.ReadAt(data, int64(mem.rva)); err != nil {
+			// a truncated dump keeps what it has
+			data = data[:n]
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		name := fmt.Sprintf("memory@0x%x", mem.start)
+		for _, mapping := range f.mappings {
+			if mem.start >= mapping.start && mem.start < mapping.end {
+				name = mapping.path
+				break
+			}
+		}
+		// without memory info every range is scanned for the signatures
+		executable := len(infos) == 0
+		for _, info := range infos {
+			if mem.start >= info.base && mem.start-info.base < info.size {
+				executable = info.protect&pageExecuteMask != 0
+				break
+			}
+		}
+		f.regions = append(f.regions, dumpRegion{name: name, addr: mem.start, data: data, executable: executable})
+
+		if !found || mem.start < f.base {
+			f.base = mem.start
+		}
+		f.size += uint64(len(data))
+		found = true
+	}
+	if !found {
+		return nil, errors.New("the minidump has no memory")
+	}
+	return f, nil
+}
+
+// minidumpModules parses the MINIDUMP_MODULE_LIST, its 108 byte modules start with the base and size and hold the RVA of their UTF-16 path
+func minidumpModules(r io.ReaderAt, stream []byte) []fileMapping {
+	le := binary.LittleEndian
+	if len(stream) < 4 {
+		return nil
+	}
+
+	var modules []fileMapping
+	count := uint64(le.Uint32(stream))
+	for i := uint64(0); i < count && 4+108*(i+1) <= uint64(len(stream)); i++ {
+		module := stream[4+108*i:]
+		base := le.Uint64(module)
+		size := uint64(le.Uint32(module[8:]))
+
+		var path string
+		nameRVA := int64(le.Uint32(module[20:]))
+		length := make([]byte, 4)
+		if _, err := r.ReadAt(length, nameRVA); err == nil {
+			name := make([]byte, min(le.Uint32(length), 0x10000)&^1)
+			if _, err := r.ReadAt(name, nameRVA+4); err == nil {
+				units := make([]uint16, len(name)/2)
+				for j := range units {
+					units[j] = le.Uint16(name[2*j:])
+				}
+				path = string(utf16.Decode(units))
+			}
+		}
+		if len(path) == 0 {
+			path = fmt.Sprintf("module@0x%x", base)
+		}
+		modules = append(modules, fileMapping{start: base, end: base + size, path: path})
+	}
+	return modules
+}
+
+// minidumpRanges lists the dumped memory of both lists. A MINIDUMP_MEMORY_LIST holds a start, size and RVA per range, a full memory
+// MINIDUMP_MEMORY64_LIST holds a start and size per range with their data back to back from one base RVA.
+func minidumpRanges(memoryList []byte, memory64List []byte) []minidumpRange {
+	le := binary.LittleEndian
+
+	var ranges []minidumpRange
+	if len(memoryList) >= 4 {
+		count := uint64(le.Uint32(memoryList))
+		for i := uint64(0); i < count && 4+16*(i+1) <= uint64(len(memoryList)); i++ {
+			descriptor := memoryList[4+16*i:]
+			ranges = append(ranges, minidumpRange{start: le.Uint64(descriptor), size: uint64(le.Uint32(descriptor[8:])), rva: uint64(le.Uint32(descriptor[12:]))})
+		}
+	}
+	if len(memory64List) >= 16 {
+		count := le.Uint64(memory64List)
+		rva := le.Uint64(memory64List[8:])
+		for i := uint64(0); i < count && 16+16*(i+1) <= uint64(len(memory64List)); i++ {
+			descriptor := memory64List[16+16*i:]
+			size := le.Uint64(descriptor[8:])
+			ranges = append(ranges, minidumpRange{start: le.Uint64(descriptor), size: size, rva: rva})
+			rva += size
+		}
+	}
+	return ranges
+}
+
+// minidumpMemoryInfo parses the MINIDUMP_MEMORY_INFO_LIST, its header gives the size of itself and of each entry
+func minidumpMemoryInfo(stream []byte) []minidumpInfo {
+	le := binary.LittleEndian
+	if len(stream) < 16 {
+		return nil
+	}
+
+	headerSize := uint64(le.Uint32(stream))
+	entrySize := uint64(le.Uint32(stream[4:]))
+	count := le.Uint64(stream[8:])
+	if entrySize < 48 {
+		return nil
+	}
+
+	var infos []minidumpInfo
+	for i := uint64(0); i < count && headerSize+entrySize*(i+1) <= uint64(len(stream)); i++ {
+		entry := stream[headerSize+entrySize*i:]
+		infos = append(infos, minidumpInfo{base: le.Uint64(entry), size: le.Uint64(entry[24:]), protect: le.Uint32(entry[36:])})
+	}
+	return infos
+}
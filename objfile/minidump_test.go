@@ -0,0 +1,104 @@
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+type fakeMinidumpRange struct {
+	start   uint64
+	data    []byte
+	protect uint32
+}
+
+// fakeMinidump is an amd64 minidump of one module at 0x400000 named path, with ranges in a MINIDUMP_MEMORY64_LIST
+func fakeMinidump(path string, moduleSize uint32, ranges []fakeMinidumpRange) []byte {
+	le := binary.LittleEndian
+	var dump bytes.Buffer
+	dump.Write(make([]byte, 32+4*12))
+
+	var directory []uint32
+	stream := func(streamType uint32, write func()) {
+		start := dump.Len()
+		write()
+		directory = append(directory, streamType, uint32(dump.Len()-start), uint32(start))
+	}
+
+	stream(systemInfoStream, func() {
+		binary.Write(&dump, le, uint16(9))
+		dump.Write(make([]byte, 54))
+	})
+	nameRVA := dump.Len()
+	name := utf16.Encode([]rune(path))
+	binary.Write(&dump, le, uint32(2*len(name)))
+	binary.Write(&dump, le, name)
+	stream(moduleListStream, func() {
+		binary.Write(&dump, le, uint32(1))
+		binary.Write(&dump, le, uint64(0x400000))
+		binary.Write(&dump, le, []uint32{moduleSize, 0, 0, uint32(nameRVA)})
+		dump.Write(make([]byte, 108-24))
+	})
+	stream(memoryInfoListStream, func() {
+		binary.Write(&dump, le, []uint32{16, 48})
+		binary.Write(&dump, le, uint64(len(ranges)))
+		for _, r := range ranges {
+			binary.Write(&dump, le, []uint64{r.start, 0x400000, 0, uint64(len(r.data))})
+			binary.Write(&dump, le, []uint32{0x1000, r.protect, 0x1000000, 0})
+		}
+	})
+	stream(memory64ListStream, func() {
+		binary.Write(&dump, le, []uint64{uint64(len(ranges)), uint64(dump.Len() + 16 + 16*len(ranges))})
+		for _, r := range ranges {
+			binary.Write(&dump, le, []uint64{r.start, uint64(len(r.data))})
+		}
+		for _, r := range ranges {
+			dump.Write(r.data)
+		}
+	})
+
+	data := dump.Bytes()
+	copy(data, "MDMP")
+	le.PutUint32(data[8:], uint32(len(directory)/3))
+	le.PutUint32(data[12:], 32)
+	for i, field := range directory {
+		le.PutUint32(data[32+4*i:], field)
+	}
+	return data
+}
+
+func TestOpenMinidump(t *testing.T) {
+	dump := fakeMinidump(`C:\app\injected.dll`, 0x4000, []fakeMinidumpRange{
+		{0x400000, make([]byte, 0x1000), 0x02},
+		{0x401000, bytes.Repeat([]byte{0xcc}, 0x1000), 0x20},
+		// 0x402000 wasn't dumped
+		{0x403000, make([]byte, 0x1000), 0x04},
+		{0x7ff000, make([]byte, 0x10), 0x04},
+	})
+	raw, err := openMinidump(bytes.NewReader(dump), OpenOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := raw.(*dumpFile)
+	if f.format != "minidump" || f.goarch() != "amd64" || f.base != 0x400000 || f.size != 0x3010 || len(f.regions) != 4 {
+		t.Fatalf("unexpected dump %s %s 0x%x 0x%x %d", f.format, f.goarch(), f.base, f.size, len(f.regions))
+	}
+	if name := f.regionName(0x401800); name != `C:\app\injected.dll` {
+		t.Errorf("expected the module path, got %s", name)
+	}
+	if name := f.regionName(0x7ff000); name != "memory@0x7ff000" {
+		t.Errorf("expected memory outside the modules to be named by address, got %s", name)
+	}
+	if start, text, err := f.text(); err != nil || start != 0x401000 || text[0] != 0xcc {
+		t.Errorf("expected the executable range as text, got 0x%x %v", start, err)
+	}
+	if gaps := f.gaps(`C:\app\injected.dll`); len(gaps) != 1 || gaps[0] != (DumpGap{0x402000, 0x403000}) {
+		t.Errorf("expected the missing page as the only gap, got %+v", gaps)
+	}
+
+	if _, err := openMinidump(bytes.NewReader(make([]byte, 64)), OpenOptions{}); err == nil {
+		t.Errorf("expected an error for a file without the MDMP signature")
+	}
+}
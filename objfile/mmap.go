@@ -0,0 +1,76 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"io"
+	"os"
+)
+
+// viewer is a reader whose bytes can be had in place, see mappedFile
+type viewer interface {
+	View(off int64, n int64) ([]byte, bool)
+	Size() int64
+}
+
+// mappedFile reads a file out of memory, a read only mapping of it or, where it can't be mapped, ex: a pipe, all of it read in.
+// The debug packages see it can View its bytes, the data of the sections is then a view of it rather than a copy.
+type mappedFile struct {
+	f     *os.File
+	data  []byte
+	unmap func() error // nil when the file was read in
+}
+
+// openMapped maps f, which it closes on error. Files that can't be mapped are read in whole.
+func openMapped(f *os.File) (*mappedFile, error) {
+	if info, err := f.Stat(); err == nil && info.Mode().IsRegular() && info.Size() > 0 && int64(int(info.Size())) == info.Size() {
+		if data, unmap, err := mapFile(f, info.Size()); err == nil {
+			return &mappedFile{f: f, data: data, unmap: unmap}, nil
+		}
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &mappedFile{f: f, data: data}, nil
+}
+
+func (m *mappedFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+	if off >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (m *mappedFile) Size() int64 {
+	return int64(len(m.data))
+}
+
+// View returns the n bytes at off in place, they must not be written to
+func (m *mappedFile) View(off int64, n int64) ([]byte, bool) {
+	if off < 0 || n < 0 || off > int64(len(m.data)) || n > int64(len(m.data))-off {
+		return nil, false
+	}
+	return m.data[off : off+n : off+n], true
+}
+
+// Close unmaps the file then closes it, the views are invalid after
+func (m *mappedFile) Close() error {
+	var err error
+	if m.unmap != nil {
+		err = m.unmap()
+		m.unmap = nil
+	}
+	m.data = nil
+	if cerr := m.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
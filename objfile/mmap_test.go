@@ -0,0 +1,130 @@
+package objfile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+)
+
+// inMapping reports whether data lies in the bytes of m
+func inMapping(m *mappedFile, data []byte) bool {
+	if len(data) == 0 || len(m.data) == 0 {
+		return false
+	}
+	start, first := uintptr(unsafe.Pointer(&m.data[0])), uintptr(unsafe.Pointer(&data[0]))
+	return first >= start && first+uintptr(len(data)) <= start+uintptr(len(m.data))
+}
+
+// concatAfter is what DataAfterSection returns, the data of the sections from the one at i on up to the first that can't be read.
+// It's cached by address, only the allocated sections are compared, the others are all at 0.
+func concatAfter(sections []*elf.Section, i int) []byte {
+	data := []byte{}
+	for _, s := range sections[i:] {
+		raw, err := s.Data()
+		data = append(data, raw...)
+		if err != nil {
+			break
+		}
+	}
+	return data
+}
+
+func TestOpenMapped(t *testing.T) {
+	contents := buildOverlapElf(false)
+	path := filepath.Join(t.TempDir(), "app")
+	if err := os.WriteFile(path, contents, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %s", path, err)
+	}
+	m, ok := f.r.(*mappedFile)
+	if !ok {
+		t.Fatalf("expected the file to be read through a mappedFile, got %T", f.r)
+	}
+	if !bytes.Equal(m.data, contents) {
+		t.Errorf("the mapping doesn't hold the file")
+	}
+
+	ef := f.entries[0].raw.(*elfFile).elf
+	rodata, err := ef.Section(".rodata").Data()
+	if err != nil || string(rodata) != "GOODGOOD" {
+		t.Errorf("expected .rodata to read GOODGOOD, got %q, %v", rodata, err)
+	}
+	mapped := runtime.GOOS == "linux" || runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+	if mapped && (m.unmap == nil || !inMapping(m, rodata)) {
+		t.Errorf("expected the data of .rodata to be a view of the mapping")
+	}
+	for i, sect := range ef.Sections {
+		if sect.Flags&elf.SHF_ALLOC == 0 {
+			continue
+		}
+		if data := ef.DataAfterSection(sect); !bytes.Equal(data, concatAfter(ef.Sections, i)) {
+			t.Errorf("the data after %q differs from its sections back to back", sect.Name)
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		t.Errorf("failed to close: %s", err)
+	}
+	if m.unmap != nil || m.data != nil {
+		t.Errorf("expected the mapping to be released on close")
+	}
+}
+
+func TestDataAfterSectionCopy(t *testing.T) {
+	// without a viewer every section is copied once, the data after each section is that copy sliced
+	ef, err := elf.NewFile(bytes.NewReader(buildOverlapElf(false)))
+	if err != nil {
+		t.Fatalf("failed to parse crafted elf: %s", err)
+	}
+	for i, sect := range ef.Sections {
+		if sect.Flags&elf.SHF_ALLOC == 0 {
+			continue
+		}
+		data := ef.DataAfterSection(sect)
+		if !bytes.Equal(data, concatAfter(ef.Sections, i)) {
+			t.Errorf("the data after %q differs from its sections back to back", sect.Name)
+		}
+		if len(data) != cap(data) {
+			t.Errorf("the data after %q can be appended to over the next section", sect.Name)
+		}
+	}
+}
+
+func TestOpenPipe(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the pipe is opened through /proc/self/fd")
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	go func() {
+		w.Write(buildOverlapElf(false))
+		w.Close()
+	}()
+
+	// a pipe can't be mapped, it's read in whole instead
+	f, err := Open(fmt.Sprintf("/proc/self/fd/%d", r.Fd()))
+	if err != nil {
+		t.Fatalf("failed to open the pipe: %s", err)
+	}
+	defer f.Close()
+	if m, ok := f.r.(*mappedFile); !ok || m.unmap != nil {
+		t.Fatalf("expected the pipe to be read in, got %T", f.r)
+	}
+	data, err := f.entries[0].raw.read_memory(overlapVA, 8)
+	if err != nil || string(data) != "GOODGOOD" {
+		t.Errorf("expected to read GOODGOOD from the pipe, got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,31 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// mapFile maps a read only view of the size bytes of f, the mapping handle is closed with the view
+func mapFile(f *os.File, size int64) ([]byte, func() error, error) {
+	mapping, err := syscall.CreateFileMapping(syscall.Handle(f.Fd()), nil, syscall.PAGE_READONLY, uint32(size>>32), uint32(size), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	addr, err := syscall.MapViewOfFile(mapping, syscall.FILE_MAP_READ, 0, 0, uintptr(size))
+	if err != nil {
+		syscall.CloseHandle(mapping)
+		return nil, nil, err
+	}
+	// addr is of memory outside the Go heap, reinterpreted rather than converted so vet takes it
+	data := unsafe.Slice(*(**byte)(unsafe.Pointer(&addr)), int(size))
+	unmap := func() error {
+		err := syscall.UnmapViewOfFile(addr)
+		if cerr := syscall.CloseHandle(mapping); err == nil {
+			err = cerr
+		}
+		return err
+	}
+	return data, unmap, nil
+}
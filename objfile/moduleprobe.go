@@ -0,0 +1,299 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// the most words of a moduledata candidate probeModuleData looks at, past the next field of every layout
+const probeWords = 128
+
+// the LayoutSource of a ModuleData
+const (
+	LayoutTable  = "table"  // the offsets of the struct of a known version
+	LayoutProbed = "probed" // the offsets probeModuleData inferred, see ProbedOffsets
+)
+
+// probeModuleData parses a moduledata candidate without knowing its layout, for the candidates the struct of their version doesn't
+// parse, ex: of a Go release newer than the table. Fields are only ever added, removed or moved between releases, rarely changed, so
+// each group of them is recognized by its values where it's expected relative to the previous one: the pointer to the pcHeader, or
+// to the pclntable before 1.16, starts the struct. minpc, maxpc, text and etext are the first four pointers into the text in a row, and
+// the ftab before them is the slice whose first entry is minpc. The bounds of the data and bss sections follow, then end,
+// gcdata, gcbss, types and etypes. The textsectmap is the slice whose first section starts at text, the typelinks, gone in 1.27, are
+// the one after it holding offsets into the types, and next is the pointer to another moduledata after the fields of the module.
+// It validates like the table does, the first function of the ftab is minpc, and fails naming the group that wasn't found.
+func (e *Entry) probeModuleData(candidate *ModuleDataCandidate, pclntabVA uint64, version string, is64bit bool, littleendian bool) (*ModuleData, error) {
+	ptrSize := 4
+	if is64bit {
+		ptrSize = 8
+	}
+	var byteOrder binary.ByteOrder = binary.BigEndian
+	if littleendian {
+		byteOrder = binary.LittleEndian
+	}
+	var words []uint64
+	for off := 0; off+ptrSize <= len(candidate.Moduledata) && len(words) < probeWords; off += ptrSize {
+		words = append(words, decodePtrSizeBytes(candidate.Moduledata[off:], is64bit, littleendian))
+	}
+	word := func(i int) uint64 {
+		if i < 0 || i >= len(words) {
+			return 0
+		}
+		return words[i]
+	}
+	// a slice of fields i to i+2, with no more than max elements when max isn't 0
+	slice := func(i int, max uint64) bool {
+		return word(i+1) == word(i+2) && word(i+1) > 0 && (max == 0 || word(i+1) <= max)
+	}
+	offsets := make(map[string]uint64)
+	field := func(name string, i int) {
+		offsets[name] = uint64(i * ptrSize)
+	}
+
+	if word(0) == 0 || (pclntabVA != 0 && word(0) != pclntabVA) {
+		return nil, fmt.Errorf("it doesn't start with a pointer to the pclntab at 0x%x", pclntabVA)
+	}
+	if version == "1.2" {
+		field("pclntable", 0)
+	} else {
+		field("pcHeader", 0)
+	}
+
+	textStart, text, err := e.raw.text()
+	if err != nil {
+		return nil, err
+	}
+	inText := func(VA uint64) bool {
+		return VA >= textStart && VA <= textStart+uint64(len(text))
+	}
+	minpc := -1
+	for i := 1; i+3 < len(words); i++ {
+		if inText(word(i)) && inText(word(i+1)) && inText(word(i+2)) && inText(word(i+3)) && word(i) <= word(i+1) && word(i+2) < word(i+3) {
+			minpc = i
+			break
+		}
+	}
+	// the ftab and findfunctab are before minpc
+	if minpc < 5 {
+		return nil, fmt.Errorf("no minpc, maxpc, text and etext in the text at 0x%x", textStart)
+	}
+	textIdx := minpc + 2
+	field("minpc", minpc)
+	field("maxpc", minpc+1)
+	field("text", textIdx)
+	field("etext", textIdx+1)
+
+	dataIdx := minpc + 4
+	// external linkers don't always lay the sections out in this order, each only starts before it ends
+	for i := dataIdx; i < dataIdx+8; i += 2 {
+		if word(i) == 0 || word(i+1) < word(i) {
+			return nil, fmt.Errorf("no noptrdata to enoptrbss bounds after etext")
+		}
+	}
+	field("noptrdata", dataIdx)
+	field("enoptrdata", dataIdx+1)
+	field("data", dataIdx+2)
+	field("edata", dataIdx+3)
+
+	// end is the end of the noptrbss, after the coverage counters from 1.20 on
+	end := -1
+	for _, i := range []int{dataIdx + 10, dataIdx + 8} {
+		if word(i) == word(dataIdx+7) {
+			end = i
+			break
+		}
+	}
+	if end == -1 {
+		return nil, fmt.Errorf("no end after enoptrbss")
+	}
+	types := end + 3
+	field("types", types)
+
+	tsm := -1
+	var textsectmap []Textsect_64
+	for i := types + 2; i < types+12 && tsm == -1; i++ {
+		if !slice(i, 0x100) {
+			continue
+		}
+		raw, err := e.raw.read_memory(word(i), word(i+1)*uint64(3*ptrSize))
+		if err != nil || uint64(len(raw)) < word(i+1)*uint64(3*ptrSize) {
+			continue
+		}
+		textsectmap = textsectmap[:0]
+		for sect := raw; len(sect) >= 3*ptrSize; sect = sect[3*ptrSize:] {
+			textsectmap = append(textsectmap, Textsect_64{
+				Vaddr:    pvoid64(decodePtrSizeBytes(sect, is64bit, littleendian)),
+				End:      pvoid64(decodePtrSizeBytes(sect[ptrSize:], is64bit, littleendian)),
+				Baseaddr: pvoid64(decodePtrSizeBytes(sect[2*ptrSize:], is64bit, littleendian)),
+			})
+		}
+		if textsectmap[0].Vaddr == 0 && uint64(textsectmap[0].Baseaddr) == word(textIdx) {
+			tsm = i
+		}
+	}
+	if tsm == -1 {
+		return nil, fmt.Errorf("no textsectmap starting at text 0x%x", word(textIdx))
+	}
+	field("textsectmap", tsm)
+
+	// 1.27 put the length of the type descriptors between types and etypes, it's less than their extent
+	etypes := types + 1
+	if word(etypes) <= word(types) {
+		etypes++
+		if word(etypes) <= word(types) || word(types+1) > word(etypes)-word(types) {
+			return nil, fmt.Errorf("no etypes after types 0x%x", word(types))
+		}
+		field("typedesclen", types+1)
+	}
+	field("etypes", etypes)
+	typesSize := word(etypes) - word(types)
+
+	// then the offsets of the itabs from types of 1.27, which came with typedesclen, and the pointers: the rodata from 1.20, the
+	// gofunc from 1.18 and the end of the pclntab of 1.27
+	pointers := etypes + 1
+	if _, ok := offsets["typedesclen"]; ok && pointers+2 <= tsm {
+		field("itaboffset", pointers)
+		field("itabsize", pointers+1)
+		pointers += 2
+	}
+	var gofunc uint64
+	switch tsm - pointers {
+	case 0:
+	case 1:
+		gofunc = word(pointers)
+		field("gofunc", pointers)
+	case 2, 3:
+		field("rodata", pointers)
+		gofunc = word(pointers + 1)
+		field("gofunc", pointers+1)
+		if tsm-pointers == 3 {
+			field("epclntab", pointers+2)
+		}
+	default:
+		return nil, fmt.Errorf("%d fields between etypes and the textsectmap", tsm-pointers)
+	}
+
+	var firstFunc []byte
+	var ftab int
+	for ftab = minpc - 4; ftab >= 1; ftab-- {
+		if !slice(ftab, 0) {
+			continue
+		}
+		var entry uint64
+		if version == "1.2" || version == "1.16" {
+			firstFunc, err = e.raw.read_memory(word(ftab), uint64(ptrSize))
+			if err == nil && len(firstFunc) == ptrSize {
+				entry = decodePtrSizeBytes(firstFunc, is64bit, littleendian)
+			}
+		} else {
+			var functab FuncTab118
+			firstFunc, err = e.raw.read_memory(word(ftab), 8)
+			if err == nil && functab.parse(firstFunc, littleendian) == nil {
+				entry = textAddr64(uint64(functab.Entryoffset), word(textIdx), textsectmap)
+			}
+		}
+		if err == nil && entry == word(minpc) {
+			break
+		}
+	}
+	if ftab < 1 {
+		return nil, fmt.Errorf("no ftab starting at minpc 0x%x", word(minpc))
+	}
+	field("ftab", ftab)
+
+	moduleData := &ModuleData{
+		VA:           candidate.ModuledataVA,
+		TextVA:       word(textIdx),
+		ETextVA:      word(textIdx + 1),
+		Types:        word(types),
+		ETypes:       word(etypes),
+		Gofunc:       gofunc,
+		Noptrdata:    word(dataIdx),
+		Enoptrdata:   word(dataIdx + 1),
+		Data:         word(dataIdx + 2),
+		Edata:        word(dataIdx + 3),
+		LayoutSource: LayoutProbed,
+	}
+
+	// the typelinks are offsets from types, a slice after the textsectmap holding ones within them
+	after := tsm + 3
+	if slice(after, 1<<20) {
+		count := min(word(after+1), 16)
+		links, err := e.raw.read_memory(word(after), count*4)
+		typelinks := err == nil && uint64(len(links)) == count*4
+		for ; typelinks && len(links) >= 4; links = links[4:] {
+			typelinks = uint64(byteOrder.Uint32(links)) < typesSize
+		}
+		if typelinks {
+			moduleData.Typelinks = GoSlice64{Data: pvoid64(word(after)), Len: word(after + 1), Capacity: word(after + 2)}
+			moduleData.ITablinks = GoSlice64{Data: pvoid64(word(after + 3)), Len: word(after + 4), Capacity: word(after + 5)}
+			field("typelinks", after)
+			field("itablinks", after+3)
+			after += 6
+		}
+	}
+
+	// ptab, pluginpath, pkghashes, the inittasks from 1.21, modulename and modulehashes. next follows hasmain, the gc masks, the typemap
+	// and bad, which 1.27 packed with hasmain.
+	pluginpath := after + 3
+	field("pluginpath", pluginpath)
+	moduleData.PluginPath = e.readGoString(word(pluginpath), word(pluginpath+1))
+	after = pluginpath + 5
+	if word(after) != 0 && slice(after, 0x10000) {
+		moduleData.initTasks = GoSlice64{Data: pvoid64(word(after)), Len: word(after + 1), Capacity: word(after + 2)}
+		field("inittasks", after)
+		after += 3
+	}
+	after += 5
+	for _, i := range []int{after + 7, after + 6} {
+		if e.probeNextModuleData(word(i), is64bit, littleendian) {
+			moduleData.next = word(i)
+			field("next", i)
+			break
+		}
+	}
+
+	moduleData.ProbedOffsets = offsets
+	return moduleData, nil
+}
+
+// probedModuleData is the probeModuleData of a candidate the table rejected, logging what it concluded
+func (e *Entry) probedModuleData(candidate *ModuleDataCandidate, pclntabVA uint64, version string, is64bit bool, littleendian bool) (*ModuleData, bool) {
+	moduleData, err := e.probeModuleData(candidate, pclntabVA, version, is64bit, littleendian)
+	if err != nil {
+		e.log.Printf(2, "moduledata candidate 0x%x doesn't probe: %v", candidate.ModuledataVA, err)
+		return nil, false
+	}
+	e.log.Printf(1, "moduledata candidate 0x%x doesn't parse as a %s moduledata, its layout was probed: %d fields", candidate.ModuledataVA, version, len(moduleData.ProbedOffsets))
+	return moduleData, true
+}
+
+// probeNextModuleData tells if VA could be the next field of a moduledata: that of another module, starting with a pointer to a pcHeader
+func (e *Entry) probeNextModuleData(VA uint64, is64bit bool, littleendian bool) bool {
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+	if VA == 0 {
+		return false
+	}
+	pointer, err := e.raw.read_memory(VA, ptrSize)
+	if err != nil || uint64(len(pointer)) < ptrSize {
+		return false
+	}
+	header, err := e.raw.read_memory(decodePtrSizeBytes(pointer, is64bit, littleendian), 4)
+	if err != nil || len(header) < 4 {
+		return false
+	}
+	magic := binary.LittleEndian.Uint32(header)
+	if !littleendian {
+		magic = binary.BigEndian.Uint32(header)
+	}
+	for _, known := range pcHeaderMagics {
+		if magic == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// fakeModuleData127 is a dump of a 1.27 moduledata at 0x500000, which no table has: typedesclen between types and etypes, the itabs
+// and epclntab after them and no typelinks. next points at a moduledata starting with the same pcHeader.
+func fakeModuleData127(firstFunc uint32) *dumpFile {
+	le := binary.LittleEndian
+	data := make([]byte, 0x2000)
+	words := []uint64{
+		0x500800,             // pcHeader
+		0x500c80, 0x10, 0x10, // funcnametab
+		0x500c80, 0x10, 0x10, // cutab
+		0x500c80, 0x10, 0x10, // filetab
+		0x500c80, 0x10, 0x10, // pctab
+		0x500c80, 0x10, 0x10, // pclntable
+		0x500900, 2, 2, // ftab
+		0x500b00,                               // findfunctab
+		0x401000, 0x401800, 0x401000, 0x401800, // minpc, maxpc, text, etext
+		0x501000, 0x501100, 0x501100, 0x501200, 0x501200, 0x501300, 0x501300, 0x501400, // noptrdata to enoptrbss
+		0x501400, 0x501400, 0x501400, // covctrs, ecovctrs, end
+		0x501500, 0x501600, // gcdata, gcbss
+		0x600000, 0x100, 0x600200, 0x180, 0x20, // types, typedesclen, etypes, itaboffset, itabsize
+		0x5f0000, 0x5f8000, 0x500a00, // rodata, gofunc, epclntab
+		0x500a00, 1, 1, // textsectmap
+		0, 0, 0, // ptab
+		0x500c80, 6, // pluginpath
+		0, 0, 0, // pkghashes
+		0x500d00, 2, 2, // inittasks
+		0, 0, // modulename
+		0, 0, 0, // modulehashes
+		1, 0, 0, 0, 0, 0, // hasmain and bad, gcdatamask, gcbssmask, typemap
+		0x500e00, // next
+	}
+	for i, word := range words {
+		le.PutUint64(data[i*8:], word)
+	}
+	le.PutUint32(data[0x800:], 0xfffffff1)
+	le.PutUint32(data[0x900:], firstFunc)
+	// the textsect of the whole text
+	le.PutUint64(data[0xa08:], 0x800)
+	le.PutUint64(data[0xa10:], 0x401000)
+	copy(data[0xc80:], "plug/p")
+	le.PutUint64(data[0xe00:], 0x500800)
+
+	return &dumpFile{format: "raw", arch: "amd64", base: 0x401000, size: 0x1000 + uint64(len(data)), regions: []dumpRegion{
+		{name: "text", addr: 0x401000, data: make([]byte, 0x1000), executable: true},
+		{name: "data", addr: 0x500000, data: data},
+	}}
+}
+
+func TestProbeModuleData(t *testing.T) {
+	raw := fakeModuleData127(0)
+	e := &Entry{raw: raw}
+	candidate := &ModuleDataCandidate{SecStart: 0x500000, ModuledataVA: 0x500000, Moduledata: raw.regions[1].data}
+	module, err := e.probeModuleData(candidate, 0x500800, "1.20", true, true)
+	if err != nil {
+		t.Fatalf("probe errored: %s", err)
+	}
+	if module.LayoutSource != LayoutProbed || module.Layout() != "" {
+		t.Errorf("expected a probed layout, got %q %q", module.LayoutSource, module.Layout())
+	}
+	if module.TextVA != 0x401000 || module.ETextVA != 0x401800 || module.Types != 0x600000 || module.ETypes != 0x600200 || module.Gofunc != 0x5f8000 {
+		t.Errorf("unexpected fields %+v", module)
+	}
+	if module.Noptrdata != 0x501000 || module.Edata != 0x501200 || module.Typelinks.Len != 0 || module.PluginPath != "plug/p" {
+		t.Errorf("unexpected fields %+v", module)
+	}
+	if module.initTasks.Data != 0x500d00 || module.initTasks.Len != 2 || module.next != 0x500e00 {
+		t.Errorf("unexpected inittasks 0x%x, %d or next 0x%x", module.initTasks.Data, module.initTasks.Len, module.next)
+	}
+	for name, offset := range map[string]uint64{"ftab": 128, "minpc": 160, "types": 296, "typedesclen": 304, "etypes": 312, "textsectmap": 360, "next": 560} {
+		if module.ProbedOffsets[name] != offset {
+			t.Errorf("expected %s at %d, got %d", name, offset, module.ProbedOffsets[name])
+		}
+	}
+	if _, ok := module.ProbedOffsets["typelinks"]; ok {
+		t.Errorf("expected no typelinks")
+	}
+
+	// a first function that isn't minpc, or another pclntab, doesn't validate
+	raw = fakeModuleData127(0x10)
+	e = &Entry{raw: raw}
+	candidate.Moduledata = raw.regions[1].data
+	if _, err := e.probeModuleData(candidate, 0x500800, "1.20", true, true); err == nil || !strings.Contains(err.Error(), "no ftab") {
+		t.Errorf("expected no ftab, got %v", err)
+	}
+	if _, err := e.probeModuleData(candidate, 0x500900, "1.20", true, true); err == nil || !strings.Contains(err.Error(), "pointer to the pclntab") {
+		t.Errorf("expected a pclntab mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+)
+
+// a process has a module per plugin, a longer list is a wild next pointer
+const maxModules = 64
+
+// the longest pluginpath read, a larger length is garbage
+const maxPluginPath = 4096
+
+// the moduledata read at a known address, more than any version's struct needs
+const moduleDataReadSize = 0x400
+
+// knownModuleData is a rawFile whose moduledata scan returns the moduledata at VA, to parse a module the next pointer leads to
+// rather than one pointing at a pclntab
+type knownModuleData struct {
+	rawFile
+	VA uint64
+}
+
+func (f knownModuleData) moduledata_scan(pclntabVA uint64, is64bit bool, littleendian bool, ignorelist []uint64) (*ModuleDataCandidate, error) {
+	for _, ignore := range ignorelist {
+		if ignore == f.VA {
+			return nil, fmt.Errorf("moduledata at 0x%x didn't validate", f.VA)
+		}
+	}
+	data, err := f.read_memory(f.VA, moduleDataReadSize)
+	if err != nil {
+		return nil, err
+	}
+	return &ModuleDataCandidate{SecStart: f.VA, ModuledataVA: f.VA, Moduledata: data}, nil
+}
+
+// readGoString reads a string of length bytes at VA, empty when there's none or it doesn't read
+func (e *Entry) readGoString(VA uint64, length uint64) string {
+	if VA == 0 || length == 0 || length > maxPluginPath {
+		return ""
+	}
+	data, err := e.raw.read_memory(VA, length)
+	if err != nil || uint64(len(data)) != length {
+		return ""
+	}
+	return string(data)
+}
+
+// ModuleDataList follows the next pointers from first, the firstmoduledata, through the modules of plugins and shared libraries loaded
+// after it. Each moduledata is validated like the first, the walk stops at the first one that doesn't validate, the first repeat or maxModules.
+// The list starts with first.
+func (e *Entry) ModuleDataList(first *ModuleData, runtimeVersion string, version string, is64bit bool, littleendian bool) []*ModuleData {
+	modules := []*ModuleData{first}
+	seen := map[uint64]bool{first.VA: true}
+	for next := first.next; next != 0 && !seen[next] && len(modules) < maxModules; {
+		seen[next] = true
+		_, module, err := (&Entry{raw: knownModuleData{e.raw, next}}).ModuleDataTable(0, runtimeVersion, version, is64bit, littleendian)
+		if err != nil || module == nil || module.VA != next {
+			break
+		}
+		modules = append(modules, module)
+		next = module.next
+	}
+	for _, module := range modules {
+		module.modules = modules
+	}
+	return modules
+}
+
+// ModulePclntab parses the pclntab of module, which its moduledata starts with a pointer to. The functions of a module other than the
+// first are only found this way, the pclntab scans stop at the first.
+func (e *Entry) ModulePclntab(module *ModuleData, versionOverride string, is64bit bool, littleendian bool) (*gosym.Table, error) {
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+	raw, err := e.raw.read_memory(module.VA, ptrSize)
+	if err != nil || uint64(len(raw)) != ptrSize {
+		return nil, fmt.Errorf("moduledata at 0x%x doesn't read", module.VA)
+	}
+	pclntabVA := decodePtrSizeBytes(raw, is64bit, littleendian)
+
+	pclntab, err := e.raw.read_memory(pclntabVA, maxSubtableSize)
+	if err != nil {
+		return nil, fmt.Errorf("pclntab at 0x%x doesn't read: %w", pclntabVA, err)
+	}
+	lineTable := gosym.NewLineTable(pclntab, module.TextVA)
+	lineTable.Tolerant = e.tolerant
+	lineTable.HeaderVA = pclntabVA
+	lineTable.ReadMemory = func(VA uint64) ([]byte, error) {
+		return e.raw.read_memory(VA, maxSubtableSize)
+	}
+
+	table, err := gosym.NewTable(nil, lineTable, versionOverride)
+	if err != nil {
+		return nil, err
+	}
+	if table.Go12line == nil {
+		return nil, fmt.Errorf("pclntab at 0x%x isn't a go 1.2+ pclntab", pclntabVA)
+	}
+	return table, nil
+}
+
+// the longest runtime.buildVersion read, a version string with a devel suffix is well under this
+const maxBuildVersion = 256
+
+// BuildVersion reads the runtime.buildVersion string the linker sets, ex: 'go1.21.3'. It's found through the symbol table, so a stripped
+// binary returns an error. The string header is two pointers, the symbol size gives their size.
+func (e *Entry) BuildVersion() (string, error) {
+	syms, err := e.raw.symbols()
+	if err != nil {
+		return "", err
+	}
+	byteOrder := e.ByteOrder()
+	for _, sym := range syms {
+		if sym.Name != "runtime.buildVersion" {
+			continue
+		}
+		if sym.Size != 8 && sym.Size != 16 {
+			return "", fmt.Errorf("runtime.buildVersion at 0x%x is 0x%x bytes", sym.Addr, sym.Size)
+		}
+
+		ptrSize := uint64(sym.Size / 2)
+		header, err := e.raw.read_memory(sym.Addr, 2*ptrSize)
+		if err != nil || uint64(len(header)) != 2*ptrSize {
+			return "", fmt.Errorf("runtime.buildVersion at 0x%x doesn't read", sym.Addr)
+		}
+		is64bit, littleendian := ptrSize == 8, byteOrder == binary.LittleEndian
+		length := decodePtrSizeBytes(header[ptrSize:], is64bit, littleendian)
+		if length > maxBuildVersion {
+			return "", fmt.Errorf("runtime.buildVersion at 0x%x is 0x%x bytes long", sym.Addr, length)
+		}
+		if version := e.readGoString(decodePtrSizeBytes(header, is64bit, littleendian), length); len(version) > 0 {
+			return version, nil
+		}
+		return "", fmt.Errorf("runtime.buildVersion at 0x%x points at nothing", sym.Addr)
+	}
+	return "", fmt.Errorf("no runtime.buildVersion symbol")
+}
+
+// the most functions a pcHeader is believed to count, a larger nfunc is garbage
+const maxPlausibleFuncs = 1 << 22
+
+// the pcHeader magics of every layout: 1.2, 1.16, 1.18 and 1.20
+var pcHeaderMagics = []uint32{0xfffffffb, 0xfffffffa, 0xfffffff0, 0xfffffff1}
+
+// SetModuleData makes PCLineTable and ModuleDataTable take the moduledata at VA instead of scanning for one, ex: one located by hand
+// in a binary the signature scan misses. It's validated like a scanned candidate, and they fail naming the field that looks wrong
+// when it doesn't validate. 0, the default, scans.
+func (e *Entry) SetModuleData(VA uint64) {
+	e.moduleDataVA = VA
+}
+
+// knownModuleDataPcln is the pcln of SetModuleData: the pclntab the moduledata at e.moduleDataVA starts with a pointer to, once its
+// pcHeader and function count look right. A stomped magic is tried as every layout, like the scans do.
+func (e *Entry) knownModuleDataPcln() (<-chan PclntabCandidate, error) {
+	VA := e.moduleDataVA
+	arch := e.raw.goarch()
+	byteOrder := e.ByteOrder()
+	is64bit := strings.Contains(arch, "64") || arch == "s390x" || arch == "wasm"
+	littleendian := byteOrder == binary.LittleEndian
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+
+	raw, err := e.raw.read_memory(VA, ptrSize)
+	if err != nil || uint64(len(raw)) != ptrSize {
+		return nil, fmt.Errorf("moduledata at 0x%x isn't mapped", VA)
+	}
+	pclntabVA := decodePtrSizeBytes(raw, is64bit, littleendian)
+	pclntab, err := e.raw.read_memory(pclntabVA, maxSubtableSize)
+	if err != nil || uint64(len(pclntab)) < 8+ptrSize {
+		return nil, fmt.Errorf("pcHeader magic mismatch: the moduledata at 0x%x points at 0x%x, which isn't mapped", VA, pclntabVA)
+	}
+
+	// the bytes after the magic tell a stomped magic from a pointer to something else
+	if pclntab[4] != 0 || pclntab[5] != 0 || (pclntab[6] != 1 && pclntab[6] != 2 && pclntab[6] != 4) || uint64(pclntab[7]) != ptrSize {
+		return nil, fmt.Errorf("pcHeader magic mismatch: the moduledata at 0x%x points at 0x%x, which starts with % x rather than a %s %d-bit pcHeader", VA, pclntabVA, pclntab[:8], byteOrder, 8*ptrSize)
+	}
+	nfunc := decodePtrSizeBytes(pclntab[8:], is64bit, littleendian)
+	// every functab entry takes 8 bytes at least, after the header
+	if nfunc == 0 || nfunc > maxPlausibleFuncs || 8+ptrSize+8*nfunc > uint64(len(pclntab)) {
+		return nil, fmt.Errorf("nfunc implausible: the pcHeader at 0x%x the moduledata at 0x%x points at counts %d functions", pclntabVA, VA, nfunc)
+	}
+
+	textStart, _, _ := e.raw.text()
+	meta := &StompMagicCandidate{PclntabVa: pclntabVA, SuspectedModuleDataVa: VA, LittleEndian: littleendian}
+	return e.pcHeaderCandidates(pclntabVA, pclntab, byteOrder, textStart, meta), nil
+}
+
+// pcHeaderCandidates are the candidates of the pclntab at VA, whose header checked out but for the magic: the pclntab as is, or
+// with a stomped magic patched as every layout of byteOrder. The functions are relative to textStart, or when it's 0 to the text
+// start a 1.18 or later header records.
+func (e *Entry) pcHeaderCandidates(VA uint64, pclntab []byte, byteOrder binary.ByteOrder, textStart uint64, meta *StompMagicCandidate) <-chan PclntabCandidate {
+	magics := pcHeaderMagics
+	if magic := byteOrder.Uint32(pclntab); slices.Contains(magics, magic) {
+		magics = []uint32{magic}
+	} else {
+		e.log.Printf(1, "the pcHeader at 0x%x has the stomped magic 0x%x, trying every layout", VA, magic)
+	}
+
+	ch := make(chan PclntabCandidate, len(magics))
+	for _, magic := range magics {
+		candidate := PclntabCandidate{SecStart: textStart, PclntabVA: VA, StompMagicCandidateMeta: meta}
+		patched := make([]byte, 4)
+		byteOrder.PutUint32(patched, magic)
+		candidate.setMagic(pclntab, patched)
+		if textStart == 0 {
+			candidate.SecStart = pcHeaderTextStart(candidate.header(pcHeaderTextStartEnd))
+		}
+		ch <- candidate
+	}
+	close(ch)
+	return ch
+}
+
+// SetPclntab makes PCLineTable parse only the pclntab at VA instead of scanning for one, ex: one found by its magic in a binary
+// whose moduledata is gone. Its layout is told by its magic. The functions of a 1.18 or later pclntab are relative to textStart, or
+// when it's 0 to the text start the header records. 0, the default, scans.
+func (e *Entry) SetPclntab(VA uint64, textStart uint64) {
+	e.pclntabVA = VA
+	e.pclntabTextStart = textStart
+}
+
+// knownPclntabPcln is the pcln of SetPclntab: the pclntab at e.pclntabVA, once it starts like a pcHeader. A stomped magic is tried
+// as every layout of the byte order of the file.
+func (e *Entry) knownPclntabPcln() (<-chan PclntabCandidate, error) {
+	VA := e.pclntabVA
+	pclntab, err := e.raw.read_memory(VA, maxSubtableSize)
+	if err != nil || len(pclntab) < 8 {
+		return nil, fmt.Errorf("pclntab at 0x%x isn't mapped", VA)
+	}
+	if pclntab[4] != 0 || pclntab[5] != 0 || (pclntab[6] != 1 && pclntab[6] != 2 && pclntab[6] != 4) || (pclntab[7] != 4 && pclntab[7] != 8) {
+		return nil, fmt.Errorf("no pcHeader at 0x%x, it starts with % x", VA, pclntab[:8])
+	}
+
+	byteOrder := e.ByteOrder()
+	if isPCHeader(pclntab) {
+		byteOrder = binary.BigEndian
+		if slices.Contains(pcHeaderMagics, binary.LittleEndian.Uint32(pclntab)) {
+			byteOrder = binary.LittleEndian
+		}
+	}
+	return e.pcHeaderCandidates(VA, pclntab, byteOrder, e.pclntabTextStart, nil), nil
+}
+
+// knownModuleDataTable is the ModuleDataTable of SetModuleData: the moduledata at e.moduleDataVA, once it parses agreeing with the
+// pclntab at pclntabVA and its text range lies in the executable section
+func (e *Entry) knownModuleDataTable(pclntabVA uint64, runtimeVersion string, version string, is64bit bool, littleendian bool) (uint64, *ModuleData, error) {
+	VA := e.moduleDataVA
+	known := &Entry{raw: knownModuleData{e.raw, VA}, log: e.log, ctx: e.ctx}
+	secStart, module, err := known.ModuleDataTable(pclntabVA, runtimeVersion, version, is64bit, littleendian)
+	if err != nil || module == nil {
+		return 0, nil, fmt.Errorf("moduledata at 0x%x doesn't parse as a %s moduledata agreeing with the pclntab at 0x%x", VA, version, pclntabVA)
+	}
+
+	textStart, text, err := e.raw.text()
+	textEnd := textStart + uint64(len(text))
+	if err == nil && len(text) > 0 && (module.ETextVA <= module.TextVA || module.TextVA < textStart || module.ETextVA > textEnd) {
+		return 0, nil, fmt.Errorf("text range implausible: the moduledata at 0x%x has the text 0x%x-0x%x, which isn't within the executable section at 0x%x-0x%x", VA, module.TextVA, module.ETextVA, textStart, textEnd)
+	}
+	return secStart, module, nil
+}
@@ -0,0 +1,102 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestModuleDataListStops(t *testing.T) {
+	memory := make([]byte, 0x2000)
+	copy(memory[0x100:], "plug/p")
+	raw := &dumpFile{format: "raw", arch: "amd64", base: 0x10000, size: uint64(len(memory)), regions: []dumpRegion{{name: "memory", addr: 0x10000, data: memory}}}
+	e := &Entry{raw: raw}
+
+	// a cycle back to the first, zeros that don't validate and an unmapped address all end the walk at the first module
+	for _, next := range []uint64{0x10000, 0x10800, 0xdead0000} {
+		first := &ModuleData{VA: 0x10000, next: next}
+		modules := e.ModuleDataList(first, "go1.22.1", "1.20", true, true)
+		if len(modules) != 1 || modules[0] != first {
+			t.Errorf("next 0x%x: expected only the first module, got %d", next, len(modules))
+		}
+	}
+
+	if path := e.readGoString(0x10100, 6); path != "plug/p" {
+		t.Errorf("expected plug/p, got %q", path)
+	}
+	if path := e.readGoString(0x10100, maxPluginPath+1); path != "" {
+		t.Errorf("expected a bogus length to read as empty, got %q", path)
+	}
+}
+
+func TestKnownModuleDataValidates(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		header     []byte
+		nfunc      uint64
+		expected   string
+		candidates int
+	}{
+		{"zeros", make([]byte, 8), 10, "pcHeader magic mismatch", 0},
+		{"wrong pointer size", []byte{0xf1, 0xff, 0xff, 0xff, 0, 0, 1, 4}, 10, "pcHeader magic mismatch", 0},
+		{"no functions", []byte{0xf1, 0xff, 0xff, 0xff, 0, 0, 1, 8}, 0, "nfunc implausible", 0},
+		{"more functions than fit", []byte{0xf1, 0xff, 0xff, 0xff, 0, 0, 1, 8}, 0x10000, "nfunc implausible", 0},
+		{"1.20", []byte{0xf1, 0xff, 0xff, 0xff, 0, 0, 1, 8}, 10, "", 1},
+		{"stomped", []byte{0x12, 0x34, 0x56, 0x78, 0, 0, 1, 8}, 10, "", len(pcHeaderMagics)},
+	} {
+		memory := make([]byte, 0x2000)
+		binary.LittleEndian.PutUint64(memory, 0x10100)
+		copy(memory[0x100:], c.header)
+		binary.LittleEndian.PutUint64(memory[0x108:], c.nfunc)
+		raw := &dumpFile{format: "raw", arch: "amd64", base: 0x10000, size: uint64(len(memory)), regions: []dumpRegion{{name: "memory", addr: 0x10000, data: memory}}}
+		e := &Entry{raw: raw}
+		e.SetModuleData(0x10000)
+
+		ch, err := e.knownModuleDataPcln()
+		if len(c.expected) > 0 {
+			if err == nil || !strings.Contains(err.Error(), c.expected) {
+				t.Errorf("%s: expected an error containing %q, got %v", c.name, c.expected, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: errored: %s", c.name, err)
+			continue
+		}
+		candidates := 0
+		for candidate := range ch {
+			candidates++
+			if candidate.PclntabVA != 0x10100 || candidate.StompMagicCandidateMeta.SuspectedModuleDataVa != 0x10000 || candidate.ReconstructedMagic != (c.candidates > 1) {
+				t.Errorf("%s: unexpected candidate %+v", c.name, candidate)
+			}
+		}
+		if candidates != c.candidates {
+			t.Errorf("%s: expected %d candidates, got %d", c.name, c.candidates, candidates)
+		}
+	}
+}
+
+func TestKnownPclntabTextStart(t *testing.T) {
+	memory := make([]byte, 0x2000)
+	copy(memory, []byte{0xf1, 0xff, 0xff, 0xff, 0, 0, 1, 8})
+	binary.LittleEndian.PutUint64(memory[8+2*8:], 0x401000)
+	raw := &dumpFile{format: "raw", arch: "amd64", base: 0x10000, size: uint64(len(memory)), regions: []dumpRegion{{name: "memory", addr: 0x10000, data: memory}}}
+	e := &Entry{raw: raw}
+
+	// the header's text start, or the one given
+	for _, c := range []struct{ given, expected uint64 }{{0, 0x401000}, {0x501000, 0x501000}} {
+		e.SetPclntab(0x10000, c.given)
+		ch, err := e.knownPclntabPcln()
+		if err != nil {
+			t.Fatalf("errored: %s", err)
+		}
+		if candidate := <-ch; candidate.SecStart != c.expected || candidate.PclntabVA != 0x10000 || candidate.ReconstructedMagic {
+			t.Errorf("given 0x%x: unexpected candidate %+v", c.given, candidate)
+		}
+	}
+
+	e.SetPclntab(0x10008, 0)
+	if _, err := e.knownPclntabPcln(); err == nil || !strings.Contains(err.Error(), "no pcHeader") {
+		t.Errorf("expected no pcHeader, got %v", err)
+	}
+}
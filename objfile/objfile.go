@@ -9,11 +9,13 @@ package objfile
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
-	"math/rand"
 	"os"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -37,6 +39,13 @@ type PclntabCandidate struct {
 	Pclntab                 []byte
 	Symtab                  []byte // optional
 	ParsedPclntab           *gosym.Table
+	ReconstructedMagic      bool   // the header's magic was stomped and patched in, ex: by garble
+	Overlay                 bool   // found in a PE overlay, it has no VA
+	FileOffset              uint64 // of an overlay pclntab
+	Location                string // where the scan found it: the name of its section, overlay or resource <path>
+	Resource                bool   // in a PE resource, with OpenOptions.ScanResources. Like an overlay pclntab no moduledata points at it.
+	// the magic patched over the start of Pclntab when it's parsed, nil when it's its own, see setMagic
+	magic []byte
 }
 
 type ModuleDataCandidate struct {
@@ -53,19 +62,36 @@ type rawFile interface {
 	read_memory(VA uint64, size uint64) (data []byte, err error)
 	text() (textStart uint64, text []byte, err error)
 	goarch() string
+	endian() binary.ByteOrder
 	loadAddress() (uint64, error)
 	dwarf() (*dwarf.Data, error)
 }
 
 // A File is an opened executable file.
 type File struct {
-	r       *os.File
+	r       io.ReaderAt
 	entries []*Entry
+	closer  io.Closer      // what Open opened, nil for a reader the caller holds
+	image   *EmbeddedImage // nil unless OpenEmbeddedImage
 }
 
 type Entry struct {
 	name string
 	raw  rawFile
+	// the addresses of the types SetTypeFilter dropped
+	filteredTypes map[uint64]bool
+	typeFilter    *NameFilter     // nil unless SetTypeFilter
+	ctx           context.Context // nil unless SetContext
+	log           Logger          // nil unless SetLogger
+	moduleDataVA  uint64          // 0 unless SetModuleData
+	// 0 unless SetPclntab
+	pclntabVA        uint64
+	pclntabTextStart uint64
+	tolerant         bool // false unless SetTolerant
+	gcData           bool // false unless SetGCData
+	methods          bool // false unless SetMethods
+	// the types ParseType returned by their VA, the typelinks and itabs reach the same ones over and over
+	parsedTypes map[uint64]Type
 }
 
 // A Sym is a symbol defined in an executable file.
@@ -90,33 +116,138 @@ type RelocStringer interface {
 	String(insnOffset uint64) string
 }
 
-var openers = []func(io.ReaderAt) (rawFile, error){
+// read_memory stops at the end of the section, sub-tables are read whole
+const maxSubtableSize = 1 << 30
+
+var openers = []func(io.ReaderAt, OpenOptions) (rawFile, error){
 	openElf,
 	openMacho,
 	openPE,
+	openMinidump,
+	openFatMacho,
+	openWasm,
+}
+
+// OpenOptions are how OpenWith and OpenReaderWith lay out and scan a file. The zero value opens it as it's laid out on disk, as
+// Open does.
+type OpenOptions struct {
+	// the address the input was loaded at, for dumps of an image the loader relocated, 0 for none. The x86 lea and every pointer in
+	// the moduledata then hold addresses relative to that base instead of the one in the headers. Files whose relocated pointers still
+	// fit the headers are left alone, so a wrong base doesn't break them. A Dump starts at it.
+	LoadBase uint64
+	// the load bias of a position independent ELF image, the address it was loaded at minus the one in its headers, for dumps of a
+	// mapped process and prelinked images whose pointers were relocated. 0, the default, detects it from the relocated pointers, which
+	// only the RELA relocations of 64 bit images keep apart from the field. The headers are then moved by the slide so the addresses
+	// they give agree with the pointers the data holds.
+	Slide uint64
+	// open the input as a dump of already mapped memory, ex: an image carved out of a memory acquisition, instead of as a file. The
+	// dump starts at LoadBase, or at the base in its headers when it starts with mapped PE or ELF headers and DumpHeaders is set.
+	// Without headers, ex: for stomped headers or firmware, the whole input is one region at LoadBase.
+	Dump        bool
+	DumpHeaders bool
+	// picks the signatures and byte order of a Dump, required for dumps without headers, ex: amd64
+	DumpGoarch string
+	// picks the slice of a fat Mach-O to open by its GOARCH, ex: arm64. Without one the first slice is opened.
+	FatArch string
+	// makes the pclntab scan of PE files also cover the overlay, the data appended after the last section that the loader never
+	// maps. Droppers keep their Go payload there.
+	ScanOverlay bool
+	// makes the pclntab scan of PE files also cover each resource of the resource directory on its own, a pclntab in one is reported
+	// with the path of the resource. Droppers keep their Go payload there, its pclntab isn't the image's so no moduledata points at it.
+	ScanResources bool
 }
 
-// Open opens the named file.
-// The caller must call f.Close when the file is no longer needed.
+// Open opens the named file. It's memory mapped, or read in whole when it can't be, ex: a pipe, so the sections are read in place.
+// Go object files and archives are read from the file. The caller must call f.Close when the file is no longer needed.
 func Open(name string) (*File, error) {
-	r, err := os.Open(name)
+	return OpenWith(name, OpenOptions{})
+}
+
+// OpenWith is Open laying out and scanning the file as opts says, ex: as a dump
+func OpenWith(name string, opts OpenOptions) (*File, error) {
+	osFile, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	// the archive parser seeks through the file itself
+	if info, err := osFile.Stat(); err == nil && info.Mode().IsRegular() && !opts.Dump {
+		if f, err := openGoFile(osFile); err == nil {
+			return f, nil
+		}
+	}
+	r, err := openMapped(osFile)
 	if err != nil {
 		return nil, err
 	}
-	if f, err := openGoFile(r); err == nil {
-		return f, nil
+	f, err := OpenReaderWith(r, r.Size(), opts)
+	if err != nil {
+		r.Close()
+		return nil, fmt.Errorf("open %s: %w", name, err)
+	}
+	f.closer = r
+	return f, nil
+}
+
+// OpenReader opens the executable file of size bytes r reads, ex: a sample or an unpacked payload held in memory, parsed like a
+// file Open opened. Go object files and archives aren't read. r is the caller's, f.Close doesn't close it.
+func OpenReader(r io.ReaderAt, size int64) (*File, error) {
+	return OpenReaderWith(r, size, OpenOptions{})
+}
+
+// OpenReaderWith is OpenReader laying out and scanning the file as opts says
+func OpenReaderWith(r io.ReaderAt, size int64, opts OpenOptions) (*File, error) {
+	if _, ok := r.(viewer); !ok {
+		r = io.NewSectionReader(r, 0, size)
+	}
+	if opts.Dump {
+		raw, err := openDump(r, opts.LoadBase, opts.Slide, opts.DumpGoarch, opts.DumpHeaders)
+		if err != nil {
+			return nil, err
+		}
+		return &File{r: r, entries: []*Entry{{raw: raw}}}, nil
 	}
 	for _, try := range openers {
-		if raw, err := try(r); err == nil {
-			return &File{r, []*Entry{{raw: raw}}}, nil
+		if raw, err := try(r, opts); err == nil {
+			return &File{r: r, entries: []*Entry{{raw: raw}}}, nil
 		}
 	}
-	r.Close()
-	return nil, fmt.Errorf("open %s: unrecognized object file or bad filepath", name)
+	return nil, fmt.Errorf("unrecognized object file or bad filepath")
+}
+
+// OpenImage opens an image already mapped in memory at base, ex: a payload an emulator or unpacker recovered, read through r.
+// It's parsed like a dump with its headers, which lay it out and give the base and architecture when they're still there.
+// Without headers the whole image is one region at base, for which goarch must be given. There's nothing to close.
+func OpenImage(r io.ReaderAt, base uint64, goarch string) (*File, error) {
+	raw, err := openDump(r, base, 0, goarch, true)
+	if err != nil {
+		return nil, fmt.Errorf("open image: %w", err)
+	}
+	return &File{r: r, entries: []*Entry{{raw: raw}}}, nil
 }
 
 func (f *File) Close() error {
-	return f.r.Close()
+	if f.closer != nil {
+		return f.closer.Close()
+	}
+	return nil
+}
+
+// Reader returns what the file is read through, of the file for Open, ex: for the build info
+func (f *File) Reader() io.ReaderAt {
+	return f.r
+}
+
+// IndexFile returns the file offset of the first needle in the file and the up to n bytes after it, -1 and nil without one. The file is
+// scanned a chunk at a time rather than read whole, see IndexReader.
+func (f *File) IndexFile(needle []byte, n int) (int64, []byte) {
+	size := int64(readerSize(f.r))
+	idx, err := IndexReader(f.r, size, needle, scanChunkSize)
+	if err != nil || idx == -1 {
+		return -1, nil
+	}
+	after := make([]byte, min(int64(n), size-idx-int64(len(needle))))
+	read, _ := f.r.ReadAt(after, idx+int64(len(needle)))
+	return idx, after[:read]
 }
 
 func (f *File) Entries() []*Entry {
@@ -144,10 +275,211 @@ func (f *File) ParseTypeLinks(runtimeVersion string, moduleData *ModuleData, is6
 	return f.entries[0].ParseTypeLinks(runtimeVersion, moduleData, is64bit, littleendian)
 }
 
-func (f *File) ParseITabLinks(runtimeVersion string, moduleData *ModuleData, is64bit bool, littleendian bool) (types []Type, err error) {
+func (f *File) ScanTypes(runtimeVersion string, moduleData *ModuleData, is64bit bool, littleendian bool) (types []Type, typesBase uint64, err error) {
+	return f.entries[0].ScanTypes(runtimeVersion, moduleData, is64bit, littleendian)
+}
+
+func (f *File) ParseITabLinks(runtimeVersion string, moduleData *ModuleData, is64bit bool, littleendian bool) (types []Type, itabs []Itab, err error) {
 	return f.entries[0].ParseITabLinks(runtimeVersion, moduleData, is64bit, littleendian)
 }
 
+func (f *File) FindCallSites(funcs []gosym.Func, allFuncs []gosym.Func, targets map[string]bool) ([]CallSite, error) {
+	return f.entries[0].FindCallSites(funcs, allFuncs, targets)
+}
+
+func (f *File) DebugLink(binaryPath string) (*DebugLink, error) {
+	return f.entries[0].DebugLink(binaryPath)
+}
+
+func (f *File) FindTimeConstants(moduleData *ModuleData, is64bit bool, littleendian bool) ([]TimeConstant, error) {
+	return f.entries[0].FindTimeConstants(moduleData, is64bit, littleendian)
+}
+
+func (f *File) FindEmbeddedFS(moduleData *ModuleData, is64bit bool, littleendian bool) ([]EmbeddedFS, error) {
+	return f.entries[0].FindEmbeddedFS(moduleData, is64bit, littleendian)
+}
+
+func (f *File) InitTasks(moduleData *ModuleData, runtimeVersion string, funcs []gosym.Func, is64bit bool, littleendian bool) ([]InitTask, error) {
+	return f.entries[0].InitTasks(moduleData, runtimeVersion, funcs, is64bit, littleendian)
+}
+
+func (f *File) SegmentOverlaps() []SegmentOverlap {
+	return f.entries[0].SegmentOverlaps()
+}
+
+func (f *File) RuntimeOffsets(goVersion string, is64bit bool) []RuntimeOffset {
+	return f.entries[0].RuntimeOffsets(goVersion, is64bit)
+}
+
+func (f *File) RuntimeGlobals(funcs []gosym.Func, goVersion string, moduleData *ModuleData, is64bit bool, littleendian bool) []RuntimeGlobal {
+	return f.entries[0].RuntimeGlobals(funcs, goVersion, moduleData, is64bit, littleendian)
+}
+
+func (f *File) Packer() string {
+	return f.entries[0].Packer(f.r)
+}
+
+func (f *File) BuildVersion() (string, error) {
+	return f.entries[0].BuildVersion()
+}
+
+func (f *File) VersionStrings() []VersionString {
+	return f.entries[0].VersionStrings()
+}
+
+func (f *File) HeuristicFunctions(runtimeVersion string) (*HeuristicRecovery, error) {
+	return f.entries[0].HeuristicFunctions(runtimeVersion)
+}
+
+func (f *File) TailJumpStub(start uint64, end uint64) bool {
+	return f.entries[0].TailJumpStub(start, end)
+}
+
+func (f *File) TinyGo() *TinyGoInfo {
+	return f.entries[0].TinyGo(f.r)
+}
+
+func (f *File) TinyGoFunctions() ([]Sym, error) {
+	return f.entries[0].TinyGoFunctions()
+}
+
+func (f *File) LikelyPacked() *PackingInfo {
+	return f.entries[0].LikelyPacked(f.r)
+}
+
+func (f *File) SetFirstMatchOnly(enabled bool) {
+	for _, entry := range f.entries {
+		entry.SetFirstMatchOnly(enabled)
+	}
+}
+
+func (f *File) SetScanWorkers(workers int) {
+	for _, entry := range f.entries {
+		entry.SetScanWorkers(workers)
+	}
+}
+
+func (f *File) SetTypeFilter(filter *NameFilter) {
+	for _, entry := range f.entries {
+		entry.SetTypeFilter(filter)
+	}
+}
+
+func (f *File) SetContext(ctx context.Context) {
+	for _, entry := range f.entries {
+		entry.SetContext(ctx)
+	}
+}
+
+func (f *File) SetModuleData(VA uint64) {
+	for _, entry := range f.entries {
+		entry.SetModuleData(VA)
+	}
+}
+
+func (f *File) SetScanRanges(ranges []ScanRange) {
+	for _, entry := range f.entries {
+		entry.SetScanRanges(ranges)
+	}
+}
+
+func (f *File) SetPclntab(VA uint64, textStart uint64) {
+	for _, entry := range f.entries {
+		entry.SetPclntab(VA, textStart)
+	}
+}
+
+func (f *File) SetTolerant(enabled bool) {
+	for _, entry := range f.entries {
+		entry.SetTolerant(enabled)
+	}
+}
+
+func (f *File) SetGCData(enabled bool) {
+	for _, entry := range f.entries {
+		entry.SetGCData(enabled)
+	}
+}
+
+func (f *File) SetMethods(enabled bool) {
+	for _, entry := range f.entries {
+		entry.SetMethods(enabled)
+	}
+}
+
+func (f *File) SetSignatures(sigs []CustomSignature) error {
+	for _, entry := range f.entries {
+		if err := entry.SetSignatures(sigs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *File) SetLogger(l Logger) {
+	for _, entry := range f.entries {
+		entry.SetLogger(l)
+	}
+}
+
+func (f *File) SetDiagnostics(enabled bool) {
+	for _, entry := range f.entries {
+		entry.SetDiagnostics(enabled)
+	}
+}
+
+func (f *File) Diagnostics() *ScanDiagnostics {
+	return f.entries[0].Diagnostics()
+}
+
+func (f *File) Dump(moduleData *ModuleData) *DumpInfo {
+	return f.entries[0].Dump(moduleData)
+}
+
+func (f *File) FatSlice() *io.SectionReader {
+	return f.entries[0].FatSlice()
+}
+
+func (f *File) Mapped(VA uint64) bool {
+	return f.entries[0].Mapped(VA)
+}
+
+func (f *File) ModuleDataList(first *ModuleData, runtimeVersion string, version string, is64bit bool, littleendian bool) []*ModuleData {
+	return f.entries[0].ModuleDataList(first, runtimeVersion, version, is64bit, littleendian)
+}
+
+func (f *File) ModulePclntab(module *ModuleData, versionOverride string, is64bit bool, littleendian bool) (*gosym.Table, error) {
+	return f.entries[0].ModulePclntab(module, versionOverride, is64bit, littleendian)
+}
+
+func (f *File) Overlay() *PEOverlay {
+	return f.entries[0].Overlay()
+}
+
+func (f *File) BuildMode() string {
+	return f.entries[0].BuildMode()
+}
+
+func (f *File) Exports() ([]Export, error) {
+	return f.entries[0].Exports()
+}
+
+func (f *File) FindStdGlobals(funcs []gosym.Func, runtimeVersion string, moduleData *ModuleData, is64bit bool, littleendian bool) ([]StdGlobal, error) {
+	return f.entries[0].FindStdGlobals(funcs, runtimeVersion, moduleData, is64bit, littleendian)
+}
+
+func (f *File) FindStringArgCallSites(funcs []gosym.Func, allFuncs []gosym.Func, targets map[string]bool) ([]StringArgCallSite, error) {
+	return f.entries[0].FindStringArgCallSites(funcs, allFuncs, targets)
+}
+
+func (f *File) FindStringLiterals(funcs []gosym.Func, moduleData *ModuleData, is64bit bool, littleendian bool) (*StringLiterals, error) {
+	return f.entries[0].FindStringLiterals(funcs, moduleData, is64bit, littleendian)
+}
+
+func (f *File) HashFunctions(funcs []gosym.Func, minSize uint64) map[uint64]FunctionHash {
+	return f.entries[0].HashFunctions(funcs, minSize)
+}
+
 func (f *File) Text() (uint64, []byte, error) {
 	return f.entries[0].Text()
 }
@@ -156,10 +488,58 @@ func (f *File) GOARCH() string {
 	return f.entries[0].GOARCH()
 }
 
+func (f *File) ByteOrder() binary.ByteOrder {
+	return f.entries[0].ByteOrder()
+}
+
 func (f *File) LoadAddress() (uint64, error) {
 	return f.entries[0].LoadAddress()
 }
 
+func (f *File) FileOffsetToVA(offset uint64) (uint64, error) {
+	return f.entries[0].FileOffsetToVA(offset)
+}
+
+func (f *File) VAToFileOffset(VA uint64) (uint64, error) {
+	return f.entries[0].VAToFileOffset(VA)
+}
+
+func (f *File) RVABase() uint64 {
+	return f.entries[0].RVABase()
+}
+
+func (f *File) Truncation() *Truncation {
+	return f.entries[0].Truncation()
+}
+
+func (f *File) PastEOF(VA uint64, size uint64) bool {
+	return f.entries[0].PastEOF(VA, size)
+}
+
+func (f *File) ImageBase() uint64 {
+	return f.entries[0].ImageBase()
+}
+
+func (f *File) Slide() uint64 {
+	return f.entries[0].Slide()
+}
+
+func (f *File) InlinedCalls(table *gosym.Table, fn *gosym.Func, moduleData *ModuleData, goVersion string) ([]gosym.InlinedCall, error) {
+	return f.entries[0].InlinedCalls(table, fn, moduleData, goVersion)
+}
+
+func (f *File) PCData(table *gosym.Table, fn *gosym.Func, moduleData *ModuleData, goVersion string) (*FunctionPCData, error) {
+	return f.entries[0].PCData(table, fn, moduleData, goVersion)
+}
+
+func (f *File) DetectHooks(funcs []gosym.Func, texts []gosym.PCRange, original *File, originalEntries map[string]uint64) []Hook {
+	return f.entries[0].DetectHooks(funcs, texts, original, originalEntries)
+}
+
+func (f *File) FilteredTypes() int {
+	return f.entries[0].FilteredTypes()
+}
+
 func (f *File) DWARF() (*dwarf.Data, error) {
 	return f.entries[0].DWARF()
 }
@@ -209,6 +589,127 @@ func findAllOccurrences(data []byte, searches [][]byte) []int {
 	return results
 }
 
+// setMagic makes pclntab the candidate's with magic patched over its own, ReconstructedMagic tells if that replaced a different one.
+// The data isn't copied until the candidate is parsed, see patched: a stomped magic is retried as every magic, most of the retries are
+// rejected on their header and a copy of everything after each would outweigh the file.
+func (c *PclntabCandidate) setMagic(pclntab []byte, magic []byte) {
+	c.Pclntab, c.ReconstructedMagic, c.magic = pclntab, !bytes.HasPrefix(pclntab, magic), nil
+	if c.ReconstructedMagic {
+		c.magic = magic
+	}
+}
+
+// patched returns Pclntab with the magic of setMagic patched in, a copy of it when that magic is another
+func (c *PclntabCandidate) patched() []byte {
+	if c.magic == nil {
+		return c.Pclntab
+	}
+	patched := make([]byte, len(c.Pclntab))
+	copy(patched, c.Pclntab)
+	copy(patched, c.magic)
+	return patched
+}
+
+// header returns the first n bytes of the patched Pclntab without copying the rest
+func (c *PclntabCandidate) header(n int) []byte {
+	header := c.Pclntab[:min(n, len(c.Pclntab))]
+	if c.magic == nil {
+		return header
+	}
+	header = append([]byte(nil), header...)
+	copy(header, c.magic)
+	return header
+}
+
+// pc quantum and pointer size of each GOARCH, a reconstructed header must agree with the file's
+var goarchPCHeader = map[string]struct{ quantum, ptrSize uint32 }{
+	"386":      {1, 4},
+	"amd64":    {1, 8},
+	"arm":      {4, 4},
+	"arm64":    {4, 8},
+	"loong64":  {4, 8},
+	"mips":     {4, 4},
+	"mipsle":   {4, 4},
+	"mips64":   {4, 8},
+	"mips64le": {4, 8},
+	"ppc64":    {4, 8},
+	"ppc64le":  {4, 8},
+	"riscv64":  {4, 8},
+	"s390x":    {2, 8},
+	"wasm":     {1, 8},
+}
+
+// plausibleReconstruction reports whether a table parsed from a patched magic is real rather than whatever followed the moduledata pointer.
+// The rest of the header must fit the file's GOARCH, when known, and the functab must hold at least one valid entry.
+func plausibleReconstruction(lineTable *gosym.LineTable, goarch string) bool {
+	if expected, ok := goarchPCHeader[goarch]; ok && (lineTable.Quantum != expected.quantum || lineTable.Ptrsize != expected.ptrSize) {
+		return false
+	}
+	return lineTable.ValidatedFuncs > 0
+}
+
+// findModuleDataPointer is the moduledata fallback for when the init code doesn't match a signature, ex: it was hooked. The moduledata starts with
+// the pclntab pointer, so the first pointer aligned occurrence of pclntabVA in the first size bytes of data is a candidate, unless it's in the ignorelist.
+// data starts at sectionBase. Returns the offset in data or -1.
+func findModuleDataPointer(data []byte, size int, sectionBase uint64, pclntabVA uint64, is64bit bool, littleendian bool, ignorelist []uint64) int {
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	if !littleendian {
+		byteOrder = binary.BigEndian
+	}
+
+	var pointer []byte
+	if is64bit {
+		pointer = make([]byte, 8)
+		byteOrder.PutUint64(pointer, pclntabVA)
+	} else {
+		pointer = make([]byte, 4)
+		byteOrder.PutUint32(pointer, uint32(pclntabVA))
+	}
+
+	// the pointer may run past the section, only its start must be within
+	end := size + len(pointer) - 1
+	if end > len(data) {
+		end = len(data)
+	}
+
+next:
+	for _, idx := range findAllOccurrences(data[:end], [][]byte{pointer}) {
+		if idx >= size {
+			break
+		}
+		// the linker aligns the moduledata, an unaligned hit is the pointer bytes appearing by chance
+		if (sectionBase+uint64(idx))%uint64(len(pointer)) != 0 {
+			continue
+		}
+
+		// skip past previous (bad) scan results, the next hit in the same section can still be the real one
+		for _, ignore := range ignorelist {
+			if ignore == sectionBase+uint64(idx) {
+				continue next
+			}
+		}
+		return idx
+	}
+	return -1
+}
+
+// SetTolerant makes the pclntabs PCLineTable and ModulePclntab parse gosym.LineTable.Tolerant: a function that doesn't parse gets a
+// placeholder name or loses its line info rather than ending the table, and the table's Corruption lists what was worked around.
+func (e *Entry) SetTolerant(enabled bool) {
+	e.tolerant = enabled
+}
+
+// SetGCData makes the types parsed list the pointer words of their values in Type.GC, decoded from their gcdata
+func (e *Entry) SetGCData(enabled bool) {
+	e.gcData = enabled
+}
+
+// SetMethods makes the types parsed list their methods in Type.Methods and the itabs of ParseITabLinks their method tables. Each
+// method reads its name and both its functions, which adds up on a large binary.
+func (e *Entry) SetMethods(enabled bool) {
+	e.methods = enabled
+}
+
 // previously: func (e *Entry) PCLineTable() (Liner, error)
 func (e *Entry) PCLineTable(versionOverride string, knownPclntabVA uint64, knownGoTextBase uint64) (<-chan PclntabCandidate, error) {
 	// If the raw file implements Liner directly, use that.
@@ -222,7 +723,13 @@ func (e *Entry) PCLineTable(versionOverride string, knownPclntabVA uint64, known
 	// Otherwise, read the pcln tables and build a Liner out of that.
 	// https://github.com/golang/go/blob/89f687d6dbc11613f715d1644b4983905293dd33/src/debug/gosym/pclntab.go#L169
 	// https://github.com/golang/go/issues/42954
-	ch_tab, err := e.raw.pcln()
+	pcln := e.raw.pcln
+	if e.moduleDataVA != 0 {
+		pcln = e.knownModuleDataPcln
+	} else if e.pclntabVA != 0 {
+		pcln = e.knownPclntabPcln
+	}
+	ch_tab, err := pcln()
 	if err != nil {
 		return nil, err
 	}
@@ -232,6 +739,11 @@ func (e *Entry) PCLineTable(versionOverride string, knownPclntabVA uint64, known
 	go func() {
 		defer close(ch)
 		for candidate := range ch_tab {
+			// the scan behind ch_tab is drained, it's left with nothing to match once canceled
+			if contextErr(e.ctx) != nil {
+				continue
+			}
+
 			/* See https://github.com/mandiant/GoReSym/pull/11
 			Locating the .text base is not safe by name due to packers which mangle names. We also have to consider CGO
 			which appears to update the base with an 'adjusted' one to add some shim code. So, PCLineTable
@@ -248,20 +760,59 @@ func (e *Entry) PCLineTable(versionOverride string, knownPclntabVA uint64, known
 				continue
 			}
 
-			parsedTable, err := gosym.NewTable(candidate.Symtab, gosym.NewLineTable(candidate.Pclntab, candidate.SecStart), versionOverride)
+			// every magic in the data is a candidate, the ones whose header can't be a pclntab's aren't parsed
+			if err := checkPclntabHeader(candidate.Pclntab, candidate.magic, e.tolerant); err != nil {
+				level := 1
+				if candidate.ReconstructedMagic {
+					level = 2
+				}
+				e.log.Printf(level, "pclntab candidate at 0x%x in %s rejected before parsing: %v", candidate.PclntabVA, candidateLocation(candidate), err)
+				continue
+			}
+
+			candidate.Pclntab, candidate.magic = candidate.patched(), nil
+			lineTable := gosym.NewLineTable(candidate.Pclntab, candidate.SecStart)
+			lineTable.Tolerant = e.tolerant
+			if candidate.PclntabVA != 0 {
+				lineTable.HeaderVA = candidate.PclntabVA
+				lineTable.ReadMemory = func(VA uint64) ([]byte, error) {
+					return e.raw.read_memory(VA, maxSubtableSize)
+				}
+			}
+
+			parsedTable, err := gosym.NewTable(candidate.Symtab, lineTable, versionOverride)
 			if err != nil || parsedTable.Go12line == nil {
+				e.log.Printf(2, "pclntab candidate at 0x%x in the section at 0x%x doesn't parse: %v", candidate.PclntabVA, candidate.SecStart, err)
+				continue
+			}
+			if candidate.ReconstructedMagic && !plausibleReconstruction(parsedTable.Go12line, e.raw.goarch()) {
+				e.log.Printf(2, "pclntab candidate at 0x%x with the magic restored as %s is implausible", candidate.PclntabVA, parsedTable.Go12line.Version)
 				continue
 			}
 
 			// the first good one happens to be correct more often than the last
 			candidate.ParsedPclntab = parsedTable
-			ch <- candidate
+			if e.ctx == nil {
+				ch <- candidate
+				continue
+			}
+			select {
+			case ch <- candidate:
+			case <-e.ctx.Done():
+			}
 		}
 	}()
 	return ch, nil
 }
 
+// ErrModuleDataInvalid is what ModuleDataTable fails with, wrapped, when it found moduledata candidates but none parses as a moduledata
+// agreeing with the pclntab, rather than finding none
+var ErrModuleDataInvalid = errors.New("the moduledata candidates don't validate")
+
 func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version string, is64bit bool, littleendian bool) (secStart uint64, moduleData *ModuleData, err error) {
+	if e.moduleDataVA != 0 {
+		return e.knownModuleDataTable(pclntabVA, runtimeVersion, version, is64bit, littleendian)
+	}
 	moduleData = &ModuleData{}
 	// Major version only, 1.15.5 -> 1.15
 	parts := strings.Split(runtimeVersion, ".")
@@ -269,18 +820,30 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 		runtimeVersion = parts[0] + "." + parts[1]
 	}
 
+	// 1.21 added the inittasks to the moduledata but kept the 1.20 pclntab, only the runtime version tells the layouts apart
+	if minor, ok := goMinorVersion(strings.TrimPrefix(runtimeVersion, "go")); ok && minor >= 21 && version == "1.20" {
+		version = "1.21"
+	}
+	moduleData.layout = version
+	moduleData.LayoutSource = LayoutTable
+
 	var moduleDataCandidate *ModuleDataCandidate = nil
 
 	const maxattempts = 5
 	var ignorelist []uint64
 	for i := 0; i < maxattempts; i++ {
-		// we're trying again, ignore the previous candidate
+		// we're trying again, probe the layout of the previous candidate before ignoring it
 		if moduleDataCandidate != nil {
+			if probed, ok := e.probedModuleData(moduleDataCandidate, pclntabVA, version, is64bit, littleendian); ok {
+				return secStart, probed, nil
+			}
+			e.log.Printf(1, "moduledata candidate 0x%x rejected: it doesn't parse as a %s moduledata agreeing with the pclntab at 0x%x", moduleDataCandidate.ModuledataVA, version, pclntabVA)
 			ignorelist = append(ignorelist, moduleDataCandidate.ModuledataVA)
 		}
 
 		moduleDataCandidate, err = e.raw.moduledata_scan(pclntabVA, is64bit, littleendian, ignorelist)
 		if err != nil {
+			e.log.Printf(2, "no moduledata candidate for the pclntab at 0x%x: %v", pclntabVA, err)
 			continue
 		}
 
@@ -339,10 +902,19 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 
 				moduleData.VA = moduleDataCandidate.ModuledataVA
 				moduleData.TextVA = uint64(module.Text)
+				moduleData.ETextVA = uint64(module.Etext)
+				moduleData.next = uint64(module.Next)
+				moduleData.Noptrdata = uint64(module.Noptrdata)
+				moduleData.Enoptrdata = uint64(module.Enoptrdata)
+				moduleData.Data = uint64(module.Data)
+				moduleData.Edata = uint64(module.Edata)
 				moduleData.Types = uint64(module.Types)
 				moduleData.ETypes = uint64(module.Etypes)
+				moduleData.Gofunc = uint64(module.Gofunc)
 				moduleData.Typelinks = module.Typelinks
 				moduleData.ITablinks = module.Itablinks
+				moduleData.initTasks = module.InitTasks
+				moduleData.PluginPath = e.readGoString(uint64(module.Pluginpath.Data), uint64(module.Pluginpath.Len))
 				return secStart, moduleData, err
 			} else {
 				var module ModuleData121_32
@@ -393,8 +965,15 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 
 				moduleData.VA = moduleDataCandidate.ModuledataVA
 				moduleData.TextVA = uint64(module.Text)
+				moduleData.ETextVA = uint64(module.Etext)
+				moduleData.next = uint64(module.Next)
+				moduleData.Noptrdata = uint64(module.Noptrdata)
+				moduleData.Enoptrdata = uint64(module.Enoptrdata)
+				moduleData.Data = uint64(module.Data)
+				moduleData.Edata = uint64(module.Edata)
 				moduleData.Types = uint64(module.Types)
 				moduleData.ETypes = uint64(module.Etypes)
+				moduleData.Gofunc = uint64(module.Gofunc)
 				moduleData.Typelinks.Data = pvoid64(module.Typelinks.Data)
 				moduleData.Typelinks.Len = uint64(module.Typelinks.Len)
 				moduleData.Typelinks.Capacity = uint64(module.Typelinks.Capacity)
@@ -402,6 +981,11 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 				moduleData.ITablinks.Data = pvoid64(module.Itablinks.Data)
 				moduleData.ITablinks.Len = uint64(module.Itablinks.Len)
 				moduleData.ITablinks.Capacity = uint64(module.Itablinks.Capacity)
+
+				moduleData.initTasks.Data = pvoid64(module.InitTasks.Data)
+				moduleData.initTasks.Len = uint64(module.InitTasks.Len)
+				moduleData.initTasks.Capacity = uint64(module.InitTasks.Capacity)
+				moduleData.PluginPath = e.readGoString(uint64(module.Pluginpath.Data), uint64(module.Pluginpath.Len))
 				return secStart, moduleData, err
 			}
 		case "1.20":
@@ -454,10 +1038,18 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 
 				moduleData.VA = moduleDataCandidate.ModuledataVA
 				moduleData.TextVA = uint64(module.Text)
+				moduleData.ETextVA = uint64(module.Etext)
+				moduleData.next = uint64(module.Next)
+				moduleData.Noptrdata = uint64(module.Noptrdata)
+				moduleData.Enoptrdata = uint64(module.Enoptrdata)
+				moduleData.Data = uint64(module.Data)
+				moduleData.Edata = uint64(module.Edata)
 				moduleData.Types = uint64(module.Types)
 				moduleData.ETypes = uint64(module.Etypes)
+				moduleData.Gofunc = uint64(module.Gofunc)
 				moduleData.Typelinks = module.Typelinks
 				moduleData.ITablinks = module.Itablinks
+				moduleData.PluginPath = e.readGoString(uint64(module.Pluginpath.Data), uint64(module.Pluginpath.Len))
 				return secStart, moduleData, err
 			} else {
 				var module ModuleData120_32
@@ -508,8 +1100,15 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 
 				moduleData.VA = moduleDataCandidate.ModuledataVA
 				moduleData.TextVA = uint64(module.Text)
+				moduleData.ETextVA = uint64(module.Etext)
+				moduleData.next = uint64(module.Next)
+				moduleData.Noptrdata = uint64(module.Noptrdata)
+				moduleData.Enoptrdata = uint64(module.Enoptrdata)
+				moduleData.Data = uint64(module.Data)
+				moduleData.Edata = uint64(module.Edata)
 				moduleData.Types = uint64(module.Types)
 				moduleData.ETypes = uint64(module.Etypes)
+				moduleData.Gofunc = uint64(module.Gofunc)
 				moduleData.Typelinks.Data = pvoid64(module.Typelinks.Data)
 				moduleData.Typelinks.Len = uint64(module.Typelinks.Len)
 				moduleData.Typelinks.Capacity = uint64(module.Typelinks.Capacity)
@@ -517,6 +1116,7 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 				moduleData.ITablinks.Data = pvoid64(module.Itablinks.Data)
 				moduleData.ITablinks.Len = uint64(module.Itablinks.Len)
 				moduleData.ITablinks.Capacity = uint64(module.Itablinks.Capacity)
+				moduleData.PluginPath = e.readGoString(uint64(module.Pluginpath.Data), uint64(module.Pluginpath.Len))
 				return secStart, moduleData, err
 			}
 		case "1.18":
@@ -569,10 +1169,18 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 
 				moduleData.VA = moduleDataCandidate.ModuledataVA
 				moduleData.TextVA = uint64(module.Text)
+				moduleData.ETextVA = uint64(module.Etext)
+				moduleData.next = uint64(module.Next)
+				moduleData.Noptrdata = uint64(module.Noptrdata)
+				moduleData.Enoptrdata = uint64(module.Enoptrdata)
+				moduleData.Data = uint64(module.Data)
+				moduleData.Edata = uint64(module.Edata)
 				moduleData.Types = uint64(module.Types)
 				moduleData.ETypes = uint64(module.Etypes)
+				moduleData.Gofunc = uint64(module.Gofunc)
 				moduleData.Typelinks = module.Typelinks
 				moduleData.ITablinks = module.Itablinks
+				moduleData.PluginPath = e.readGoString(uint64(module.Pluginpath.Data), uint64(module.Pluginpath.Len))
 				return secStart, moduleData, err
 			} else {
 				var module ModuleData118_32
@@ -623,8 +1231,15 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 
 				moduleData.VA = moduleDataCandidate.ModuledataVA
 				moduleData.TextVA = uint64(module.Text)
+				moduleData.ETextVA = uint64(module.Etext)
+				moduleData.next = uint64(module.Next)
+				moduleData.Noptrdata = uint64(module.Noptrdata)
+				moduleData.Enoptrdata = uint64(module.Enoptrdata)
+				moduleData.Data = uint64(module.Data)
+				moduleData.Edata = uint64(module.Edata)
 				moduleData.Types = uint64(module.Types)
 				moduleData.ETypes = uint64(module.Etypes)
+				moduleData.Gofunc = uint64(module.Gofunc)
 				moduleData.Typelinks.Data = pvoid64(module.Typelinks.Data)
 				moduleData.Typelinks.Len = uint64(module.Typelinks.Len)
 				moduleData.Typelinks.Capacity = uint64(module.Typelinks.Capacity)
@@ -632,6 +1247,7 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 				moduleData.ITablinks.Data = pvoid64(module.Itablinks.Data)
 				moduleData.ITablinks.Len = uint64(module.Itablinks.Len)
 				moduleData.ITablinks.Capacity = uint64(module.Itablinks.Capacity)
+				moduleData.PluginPath = e.readGoString(uint64(module.Pluginpath.Data), uint64(module.Pluginpath.Len))
 				return secStart, moduleData, err
 			}
 		case "1.16":
@@ -662,10 +1278,17 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 
 				moduleData.VA = moduleDataCandidate.ModuledataVA
 				moduleData.TextVA = uint64(module.Text)
+				moduleData.ETextVA = uint64(module.Etext)
+				moduleData.next = uint64(module.Next)
+				moduleData.Noptrdata = uint64(module.Noptrdata)
+				moduleData.Enoptrdata = uint64(module.Enoptrdata)
+				moduleData.Data = uint64(module.Data)
+				moduleData.Edata = uint64(module.Edata)
 				moduleData.Types = uint64(module.Types)
 				moduleData.ETypes = uint64(module.Etypes)
 				moduleData.Typelinks = module.Typelinks
 				moduleData.ITablinks = module.Itablinks
+				moduleData.PluginPath = e.readGoString(uint64(module.Pluginpath.Data), uint64(module.Pluginpath.Len))
 				return secStart, moduleData, err
 			} else {
 				var module ModuleData116_32
@@ -694,6 +1317,12 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 
 				moduleData.VA = moduleDataCandidate.ModuledataVA
 				moduleData.TextVA = uint64(module.Text)
+				moduleData.ETextVA = uint64(module.Etext)
+				moduleData.next = uint64(module.Next)
+				moduleData.Noptrdata = uint64(module.Noptrdata)
+				moduleData.Enoptrdata = uint64(module.Enoptrdata)
+				moduleData.Data = uint64(module.Data)
+				moduleData.Edata = uint64(module.Edata)
 				moduleData.Types = uint64(module.Types)
 				moduleData.ETypes = uint64(module.Etypes)
 				moduleData.Typelinks.Data = pvoid64(module.Typelinks.Data)
@@ -703,6 +1332,7 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 				moduleData.ITablinks.Data = pvoid64(module.Itablinks.Data)
 				moduleData.ITablinks.Len = uint64(module.Itablinks.Len)
 				moduleData.ITablinks.Capacity = uint64(module.Itablinks.Capacity)
+				moduleData.PluginPath = e.readGoString(uint64(module.Pluginpath.Data), uint64(module.Pluginpath.Len))
 				return secStart, moduleData, err
 			}
 
@@ -741,6 +1371,12 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 					// The base would be the normal typelinks pointer, and then we
 					moduleData.VA = moduleDataCandidate.ModuledataVA
 					moduleData.TextVA = uint64(module.Text)
+					moduleData.ETextVA = uint64(module.Etext)
+					moduleData.next = uint64(module.Next)
+					moduleData.Noptrdata = uint64(module.Noptrdata)
+					moduleData.Enoptrdata = uint64(module.Enoptrdata)
+					moduleData.Data = uint64(module.Data)
+					moduleData.Edata = uint64(module.Edata)
 					moduleData.LegacyTypes = module.Typelinks
 					return secStart, moduleData, err
 				} else {
@@ -770,6 +1406,12 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 
 					moduleData.VA = moduleDataCandidate.ModuledataVA
 					moduleData.TextVA = uint64(module.Text)
+					moduleData.ETextVA = uint64(module.Etext)
+					moduleData.next = uint64(module.Next)
+					moduleData.Noptrdata = uint64(module.Noptrdata)
+					moduleData.Enoptrdata = uint64(module.Enoptrdata)
+					moduleData.Data = uint64(module.Data)
+					moduleData.Edata = uint64(module.Edata)
 					moduleData.LegacyTypes.Data = pvoid64(module.Typelinks.Data)
 					moduleData.LegacyTypes.Len = uint64(module.Typelinks.Len)
 					moduleData.LegacyTypes.Capacity = uint64(module.Typelinks.Capacity)
@@ -805,6 +1447,12 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 					// The base would be the normal typelinks pointer, and then we
 					moduleData.VA = moduleDataCandidate.ModuledataVA
 					moduleData.TextVA = uint64(module.Text)
+					moduleData.ETextVA = uint64(module.Etext)
+					moduleData.next = uint64(module.Next)
+					moduleData.Noptrdata = uint64(module.Noptrdata)
+					moduleData.Enoptrdata = uint64(module.Enoptrdata)
+					moduleData.Data = uint64(module.Data)
+					moduleData.Edata = uint64(module.Edata)
 					moduleData.Types = uint64(module.Types)
 					moduleData.ETypes = uint64(module.Etypes)
 					moduleData.Typelinks = module.Typelinks
@@ -837,6 +1485,12 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 
 					moduleData.VA = moduleDataCandidate.ModuledataVA
 					moduleData.TextVA = uint64(module.Text)
+					moduleData.ETextVA = uint64(module.Etext)
+					moduleData.next = uint64(module.Next)
+					moduleData.Noptrdata = uint64(module.Noptrdata)
+					moduleData.Enoptrdata = uint64(module.Enoptrdata)
+					moduleData.Data = uint64(module.Data)
+					moduleData.Edata = uint64(module.Edata)
 					moduleData.Types = uint64(module.Types)
 					moduleData.ETypes = uint64(module.Etypes)
 					moduleData.Typelinks.Data = pvoid64(module.Typelinks.Data)
@@ -890,10 +1544,17 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 
 					moduleData.VA = moduleDataCandidate.ModuledataVA
 					moduleData.TextVA = uint64(module.Text)
+					moduleData.ETextVA = uint64(module.Etext)
+					moduleData.next = uint64(module.Next)
+					moduleData.Noptrdata = uint64(module.Noptrdata)
+					moduleData.Enoptrdata = uint64(module.Enoptrdata)
+					moduleData.Data = uint64(module.Data)
+					moduleData.Edata = uint64(module.Edata)
 					moduleData.Types = uint64(module.Types)
 					moduleData.ETypes = uint64(module.Etypes)
 					moduleData.Typelinks = module.Typelinks
 					moduleData.ITablinks = module.Itablinks
+					moduleData.PluginPath = e.readGoString(uint64(module.Pluginpath.Data), uint64(module.Pluginpath.Len))
 					return secStart, moduleData, err
 				} else {
 					var module ModuleData12_32
@@ -922,6 +1583,12 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 
 					moduleData.VA = moduleDataCandidate.ModuledataVA
 					moduleData.TextVA = uint64(module.Text)
+					moduleData.ETextVA = uint64(module.Etext)
+					moduleData.next = uint64(module.Next)
+					moduleData.Noptrdata = uint64(module.Noptrdata)
+					moduleData.Enoptrdata = uint64(module.Enoptrdata)
+					moduleData.Data = uint64(module.Data)
+					moduleData.Edata = uint64(module.Edata)
 					moduleData.Types = uint64(module.Types)
 					moduleData.ETypes = uint64(module.Etypes)
 					moduleData.Typelinks.Data = pvoid64(module.Typelinks.Data)
@@ -931,6 +1598,7 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 					moduleData.ITablinks.Data = pvoid64(module.Itablinks.Data)
 					moduleData.ITablinks.Len = uint64(module.Itablinks.Len)
 					moduleData.ITablinks.Capacity = uint64(module.Itablinks.Capacity)
+					moduleData.PluginPath = e.readGoString(uint64(module.Pluginpath.Data), uint64(module.Pluginpath.Len))
 					return secStart, moduleData, err
 				}
 			}
@@ -938,6 +1606,15 @@ func (e *Entry) ModuleDataTable(pclntabVA uint64, runtimeVersion string, version
 	}
 
 	// should only happen if all scan attempts and validation fail
+	if moduleDataCandidate != nil {
+		if probed, ok := e.probedModuleData(moduleDataCandidate, pclntabVA, version, is64bit, littleendian); ok {
+			return secStart, probed, nil
+		}
+		e.log.Printf(1, "moduledata candidate 0x%x rejected: it doesn't parse as a %s moduledata agreeing with the pclntab at 0x%x", moduleDataCandidate.ModuledataVA, version, pclntabVA)
+	}
+	if moduleDataCandidate != nil || len(ignorelist) > 0 {
+		return 0, nil, fmt.Errorf("moduledata not found: %w", ErrModuleDataInvalid)
+	}
 	return 0, nil, fmt.Errorf("moduledata not found")
 }
 
@@ -1037,7 +1714,7 @@ func (e *Entry) readRTypeName(runtimeVersion string, typeFlags tflag, namePtr ui
 		}
 
 		name := string(name_raw)
-		if typeFlags&tflagExtraStar != 0 {
+		if typeFlags&tflagExtraStar != 0 && len(name) > 0 {
 			return name[1:], nil
 		} else {
 			return name, nil
@@ -1064,7 +1741,7 @@ func (e *Entry) readRTypeName(runtimeVersion string, typeFlags tflag, namePtr ui
 		}
 
 		name := string(name_raw)
-		if typeFlags&tflagExtraStar != 0 {
+		if typeFlags&tflagExtraStar != 0 && len(name) > 0 {
 			return name[1:], nil
 		} else {
 			return name, nil
@@ -1119,8 +1796,8 @@ func typename_to_c(typename string) string {
 	return result
 }
 
-// not exhaustive, just the likely ones to be in Go
-func replace_cpp_keywords(fieldname string) string {
+// not exhaustive, just the likely ones to be in Go. A blank field is named after its index in the struct, so the output is the same every run.
+func replace_cpp_keywords(fieldname string, index int) string {
 	switch fieldname {
 	case "private":
 		fallthrough
@@ -1151,7 +1828,7 @@ func replace_cpp_keywords(fieldname string) string {
 	case "class":
 		return "_" + fieldname
 	case "_":
-		return "_anon" + fmt.Sprint(rand.Intn(1000))
+		return "_anon" + strconv.Itoa(index)
 	}
 	return fieldname
 }
@@ -1163,6 +1840,16 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 		return parsedTypesIn, nil
 	}
 
+	// a plugin's types point at the ones of the modules loaded before it, whose names are relative to those modules' types
+	if typeAddress < moduleData.Types || typeAddress >= moduleData.ETypes {
+		for _, module := range moduleData.modules {
+			if typeAddress >= module.Types && typeAddress < module.ETypes {
+				moduleData = module
+				break
+			}
+		}
+	}
+
 	var _type *Type = nil
 
 	switch runtimeVersion {
@@ -1184,7 +1871,7 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 				return parsedTypesIn, fmt.Errorf("Failed to read type name")
 			}
 
-			_type = &Type{VA: typeAddress, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: tflagNamed}
+			_type = &Type{VA: typeAddress, Size: uint64(rtype.Size), PtrBytes: uint64(rtype.Ptrdata), Align: rtype.Align, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: tflagNamed, gcdata: uint64(rtype.Gcdata), kindFlags: uint8(rtype.Kind)}
 		} else {
 			var rtype Rtype15_32
 			rtype_raw, err := e.raw.read_memory(typeAddress, uint64(unsafe.Sizeof(rtype)))
@@ -1201,7 +1888,7 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 			if err != nil {
 				return parsedTypesIn, fmt.Errorf("Failed to read type name")
 			}
-			_type = &Type{VA: typeAddress, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: tflagNamed}
+			_type = &Type{VA: typeAddress, Size: uint64(rtype.Size), PtrBytes: uint64(rtype.Ptrdata), Align: rtype.Align, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: tflagNamed, gcdata: uint64(rtype.Gcdata), kindFlags: uint8(rtype.Kind)}
 		}
 	case "1.6":
 		if is64bit {
@@ -1220,7 +1907,7 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 			if err != nil {
 				return parsedTypesIn, fmt.Errorf("Failed to read type name")
 			}
-			_type = &Type{VA: typeAddress, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: tflagNamed}
+			_type = &Type{VA: typeAddress, Size: uint64(rtype.Size), PtrBytes: uint64(rtype.Ptrdata), Align: rtype.Align, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: tflagNamed, gcdata: uint64(rtype.Gcdata), kindFlags: uint8(rtype.Kind)}
 		} else {
 			var rtype Rtype16_32
 			rtype_raw, err := e.raw.read_memory(typeAddress, uint64(unsafe.Sizeof(rtype)))
@@ -1237,7 +1924,7 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 			if err != nil {
 				return parsedTypesIn, fmt.Errorf("Failed to read type name")
 			}
-			_type = &Type{VA: typeAddress, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: tflagNamed}
+			_type = &Type{VA: typeAddress, Size: uint64(rtype.Size), PtrBytes: uint64(rtype.Ptrdata), Align: rtype.Align, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: tflagNamed, gcdata: uint64(rtype.Gcdata), kindFlags: uint8(rtype.Kind)}
 		}
 	case "1.7":
 		fallthrough
@@ -1267,7 +1954,7 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 			if err != nil {
 				return parsedTypesIn, fmt.Errorf("Failed to read type name")
 			}
-			_type = &Type{VA: typeAddress, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: rtype.Tflag}
+			_type = &Type{VA: typeAddress, Size: uint64(rtype.Size), PtrBytes: uint64(rtype.Ptrdata), Align: rtype.Align, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: rtype.Tflag, gcdata: uint64(rtype.Gcdata), kindFlags: uint8(rtype.Kind)}
 		} else {
 			var rtype Rtype17_18_19_110_111_112_113_32
 			rtype_raw, err := e.raw.read_memory(typeAddress, uint64(unsafe.Sizeof(rtype)))
@@ -1283,7 +1970,7 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 			if err != nil {
 				return parsedTypesIn, fmt.Errorf("Failed to read type name")
 			}
-			_type = &Type{VA: typeAddress, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: rtype.Tflag}
+			_type = &Type{VA: typeAddress, Size: uint64(rtype.Size), PtrBytes: uint64(rtype.Ptrdata), Align: rtype.Align, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: rtype.Tflag, gcdata: uint64(rtype.Gcdata), kindFlags: uint8(rtype.Kind)}
 		}
 	case "1.14":
 		fallthrough
@@ -1317,7 +2004,7 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 			if err != nil {
 				return parsedTypesIn, fmt.Errorf("Failed to read type name")
 			}
-			_type = &Type{VA: typeAddress, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: rtype.Tflag}
+			_type = &Type{VA: typeAddress, Size: uint64(rtype.Size), PtrBytes: uint64(rtype.Ptrdata), Align: rtype.Align, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: rtype.Tflag, gcdata: uint64(rtype.Gcdata), kindFlags: uint8(rtype.Kind)}
 		} else {
 			var rtype Rtype114_115_116_117_118_32
 			rtype_raw, err := e.raw.read_memory(typeAddress, uint64(unsafe.Sizeof(rtype)))
@@ -1333,12 +2020,30 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 			if err != nil {
 				return parsedTypesIn, fmt.Errorf("Failed to read type name")
 			}
-			_type = &Type{VA: typeAddress, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: rtype.Tflag}
+			_type = &Type{VA: typeAddress, Size: uint64(rtype.Size), PtrBytes: uint64(rtype.Ptrdata), Align: rtype.Align, Str: name, CStr: typename_to_c(name), Kind: ((Kind)(rtype.Kind & 0x1f)).String(), baseSize: uint16(unsafe.Sizeof(rtype)), kindEnum: ((Kind)(rtype.Kind & 0x1f)), flags: rtype.Tflag, gcdata: uint64(rtype.Gcdata), kindFlags: uint8(rtype.Kind)}
 		}
 	default:
 		return parsedTypesIn, fmt.Errorf("Unknown runtime version")
 	}
 
+	// a filtered type isn't recursed into, what only it points to isn't parsed
+	if !e.keepType(typeAddress, _type.Str) {
+		return parsedTypesIn, nil
+	}
+
+	// generic instantiations keep the raw name in Str, readable form is stored beside it
+	_type.Demangled, _type.TypeArgs, _type.GenericNote = demangle_generic_name(_type.Str)
+	_type.Shape = strings.Contains(_type.Str, shapePrefix)
+	named := _type.flags&tflagNamed != 0
+	// builtin types are named too, ex: int
+	if underlying := basicUnderlying(_type.kindEnum); named && underlying != _type.Str {
+		_type.Underlying = underlying
+	}
+
+	if e.gcData {
+		_type.GC = e.gcLayout(_type, is64bit, littleendian)
+	}
+
 	// insert into seen list
 	parsedTypesIn.Set(typeAddress, *_type)
 
@@ -1371,6 +2076,10 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 		// TODO: parse this nicer to get C style args and return
 		(*_type).CStr = "void*"
 		parsedTypesIn.Set(typeAddress, *_type)
+		if named {
+			(*_type).Underlying, parsedTypesIn = e.funcUnderlying(runtimeVersion, moduleData, _type, is64bit, littleendian, parsedTypesIn)
+			parsedTypesIn.Set(typeAddress, *_type)
+		}
 	case Array:
 		// type arraytype struct {
 		// 	typ   _type
@@ -1398,6 +2107,9 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 		if found {
 			(*_type).Reconstructed = (*_type).Str // ends up being the same for an array
 			(*_type).CReconstructed = "typedef " + elemType.(Type).CStr + " " + (*_type).CStr + "[" + strconv.Itoa(int(arrayLen)) + "];"
+			if named {
+				(*_type).Underlying = arrayUnderlying(arrayLen, elemType.(Type).Str)
+			}
 			parsed.Set(typeAddress, *_type)
 		}
 		return e.ParseType_impl(runtimeVersion, moduleData, sliceTypeAddress, is64bit, littleendian, parsed)
@@ -1440,8 +2152,17 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 
 		elemType, found := parsedTypesIn.Get(elemTypeAddress)
 		if found {
-			(*_type).Str = "chan(" + elemType.(Type).Str + ")"
-			(*_type).CStr = "chan_" + elemType.(Type).CStr
+			if named {
+				dir, err := e.ReadPointerSizeMem(typeAddress+uint64(_type.baseSize)+ptrSize, is64bit, littleendian)
+				if err == nil {
+					(*_type).Underlying = chanUnderlying(ChanDir(dir), elemType.(Type).Str)
+				}
+			}
+			// a named channel keeps its name
+			if !named {
+				(*_type).Str = "chan(" + elemType.(Type).Str + ")"
+				(*_type).CStr = "chan_" + elemType.(Type).CStr
+			}
 			(*_type).Reconstructed = "chan(" + elemType.(Type).Str + ")"
 			(*_type).CReconstructed = "typedef void* chan_" + elemType.(Type).CStr + ";"
 			parsedTypesIn.Set(typeAddress, *_type)
@@ -1465,6 +2186,9 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 		if found {
 			(*_type).Reconstructed = "struct " + (*_type).Str + "{ ptr *" + elemType.(Type).Str + "\nlen int\ncap int }"
 			(*_type).CReconstructed = "struct " + (*_type).CStr + "{ " + elemType.(Type).CStr + "* ptr;" + "size_t len; size_t cap; }"
+			if named {
+				(*_type).Underlying = "[]" + elemType.(Type).Str
+			}
 			parsedTypesIn.Set(typeAddress, *_type)
 		}
 	case Pointer:
@@ -1486,6 +2210,9 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 		if found {
 			(*_type).Reconstructed = "type " + (*_type).Str + " = " + elemType.(Type).CStr
 			(*_type).CReconstructed = "typedef " + elemType.(Type).CStr + "* " + (*_type).CStr + ";"
+			if named {
+				(*_type).Underlying = "*" + elemType.(Type).Str
+			}
 			parsedTypesIn.Set(typeAddress, *_type)
 		}
 	case UnsafePointer:
@@ -1527,6 +2254,12 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 
 		parsed, _ := e.ParseType_impl(runtimeVersion, moduleData, keyTypeAddress, is64bit, littleendian, parsedTypesIn)
 		parsed2, _ := e.ParseType_impl(runtimeVersion, moduleData, elemTypeAddress, is64bit, littleendian, parsed)
+		keyType, keyFound := parsed2.Get(keyTypeAddress)
+		elemType, elemFound := parsed2.Get(elemTypeAddress)
+		if named && keyFound && elemFound {
+			(*_type).Underlying = "map[" + keyType.(Type).Str + "]" + elemType.(Type).Str
+			parsed2.Set(typeAddress, *_type)
+		}
 		return e.ParseType_impl(runtimeVersion, moduleData, bucketTypeAddress, is64bit, littleendian, parsed2)
 	case Interface:
 		// type interfaceType struct {
@@ -1589,12 +2322,19 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 				if err != nil {
 					continue
 				}
+				var pkgPath string
+				if pkgPathPtr := decodePtrSizeBytes(imethoddata[ptrSize:ptrSize*2], is64bit, littleendian); pkgPathPtr != 0 {
+					pkgPath, _ = e.readRTypeName(runtimeVersion, 0, pkgPathPtr, is64bit, littleendian)
+				}
 
 				methodfunc, found := parsedTypesIn.Get(typeAddr)
 				if found {
 					interfaceDef += strings.Replace(methodfunc.(Type).Str, "func", name, 1) + "\n"
 					cinterfaceDef += methodfunc.(Type).CStr + " " + name + ";\n"
 				}
+				var method InterfaceMethod
+				method, parsedTypesIn = e.interfaceMethod(runtimeVersion, moduleData, name, pkgPath, typeAddr, is64bit, littleendian, parsedTypesIn)
+				(*_type).InterfaceMethods = append((*_type).InterfaceMethods, method)
 			}
 			interfaceDef += "\n}"
 			cinterfaceDef += "}"
@@ -1655,6 +2395,12 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 				methods.Capacity = uint64(tmp.Capacity)
 			}
 
+			// the import path of the package declaring the interface, it qualifies the unexported methods
+			var interfacePkgPath string
+			if pkgPathPtr, err := e.ReadPointerSizeMem(typeAddress+uint64(_type.baseSize), is64bit, littleendian); err == nil && pkgPathPtr != 0 {
+				interfacePkgPath, _ = e.readRTypeName(runtimeVersion, 0, pkgPathPtr, is64bit, littleendian)
+			}
+
 			interfaceDef := "type interface {"
 			cinterfaceDef := "struct interface {\n"
 			if _type.flags&tflagNamed != 0 {
@@ -1683,7 +2429,7 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 				parsedTypesIn, _ = e.ParseType_impl(runtimeVersion, moduleData, typeAddr, is64bit, littleendian, parsedTypesIn)
 
 				name_ptr := moduleData.Types + uint64(method.Name)
-				name, err := e.readRTypeName(runtimeVersion, 0, name_ptr, is64bit, littleendian)
+				name, pkgPath, err := e.readIMethodName(runtimeVersion, moduleData, name_ptr, interfacePkgPath, is64bit, littleendian)
 				if err != nil {
 					continue
 				}
@@ -1693,6 +2439,9 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 					interfaceDef += strings.Replace(methodfunc.(Type).Str, "func", name, 1) + "\n"
 					cinterfaceDef += methodfunc.(Type).CStr + " " + name + ";\n"
 				}
+				var imethod InterfaceMethod
+				imethod, parsedTypesIn = e.interfaceMethod(runtimeVersion, moduleData, name, pkgPath, typeAddr, is64bit, littleendian, parsedTypesIn)
+				(*_type).InterfaceMethods = append((*_type).InterfaceMethods, imethod)
 			}
 			interfaceDef += "\n}"
 			cinterfaceDef += "}"
@@ -1752,14 +2501,29 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 				typeAddr := decodePtrSizeBytes(data[ptrSize*2:ptrSize*3], is64bit, littleendian)
 				parsedTypesIn, _ = e.ParseType_impl(runtimeVersion, moduleData, typeAddr, is64bit, littleendian, parsedTypesIn)
 				field, found := parsedTypesIn.Get(typeAddr)
+				typeNameAddr := decodePtrSizeBytes(data[0:ptrSize], is64bit, littleendian)
 				if found {
-					typeNameAddr := decodePtrSizeBytes(data[0:ptrSize], is64bit, littleendian)
 					typeName, err := e.readRTypeName(runtimeVersion, 0, typeNameAddr, is64bit, littleendian)
 					if err == nil {
 						structDef += fmt.Sprintf("\n    %-10s %s", typeName, field.(Type).Str)
-						cstructDef += fmt.Sprintf("    %-10s %s;\n", field.(Type).CStr, replace_cpp_keywords(typeName))
+						cstructDef += fmt.Sprintf("    %-10s %s;\n", field.(Type).CStr, replace_cpp_keywords(typeName, i))
 					}
 				}
+
+				// the name of an embedded field is nil, the tag a pointer to a string
+				structField := StructField{TypeVA: typeAddr, Offset: decodePtrSizeBytes(data[ptrSize*4:ptrSize*5], is64bit, littleendian), Embedded: typeNameAddr == 0}
+				if found {
+					structField.Type = field.(Type).Str
+				}
+				if structField.Embedded {
+					structField.Name = embeddedName(structField.Type)
+				} else if structField.Name, err = e.readRTypeName(runtimeVersion, 0, typeNameAddr, is64bit, littleendian); err != nil {
+					continue
+				}
+				if tagAddr := decodePtrSizeBytes(data[ptrSize*3:ptrSize*4], is64bit, littleendian); tagAddr != 0 {
+					structField.Tag, _ = e.readRTypeName(runtimeVersion, 0, tagAddr, is64bit, littleendian)
+				}
+				(*_type).Fields = append((*_type).Fields, structField)
 			}
 			structDef += "\n}"
 			cstructDef += "}"
@@ -1803,6 +2567,7 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 			// 	pkgPath name // pointer
 			// 	fields  []structField // sorted by offset
 			// }
+			minor, _ := goMinorVersion(runtimeVersion)
 			var fieldsStartAddr uint64 = typeAddress + uint64(_type.baseSize) + ptrSize
 			var fields GoSlice64 = GoSlice64{}
 			if is64bit {
@@ -1849,14 +2614,32 @@ func (e *Entry) ParseType_impl(runtimeVersion string, moduleData *ModuleData, ty
 				parsedTypesIn, _ = e.ParseType_impl(runtimeVersion, moduleData, typeAddr, is64bit, littleendian, parsedTypesIn)
 
 				field, found := parsedTypesIn.Get(typeAddr)
+				typeNameAddr := decodePtrSizeBytes(data[0:ptrSize], is64bit, littleendian)
+				typeName, tag, embedded, err := e.readStructFieldName(minor, typeNameAddr)
+				if err != nil {
+					continue
+				}
 				if found {
-					typeNameAddr := decodePtrSizeBytes(data[0:ptrSize], is64bit, littleendian)
-					typeName, err := e.readRTypeName(runtimeVersion, 0, typeNameAddr, is64bit, littleendian)
-					if err == nil {
-						structDef += fmt.Sprintf("\n    %-10s %s", typeName, field.(Type).Str)
-						cstructDef += fmt.Sprintf("    %-10s %s;\n", field.(Type).CStr, replace_cpp_keywords(typeName))
-					}
+					structDef += fmt.Sprintf("\n    %-10s %s", typeName, field.(Type).Str)
+					cstructDef += fmt.Sprintf("    %-10s %s;\n", field.(Type).CStr, replace_cpp_keywords(typeName, i))
+				}
+
+				// 1.9 to 1.18 shift the offset left for the embedded bit, before that an embedded field has no name
+				offset := decodePtrSizeBytes(data[ptrSize*2:ptrSize*3], is64bit, littleendian)
+				if minor >= 9 && minor < 19 {
+					embedded = offset&1 != 0
+					offset >>= 1
+				} else if minor < 9 {
+					embedded = len(typeName) == 0
+				}
+				structField := StructField{Name: typeName, TypeVA: typeAddr, Offset: offset, Embedded: embedded, Tag: tag}
+				if found {
+					structField.Type = field.(Type).Str
 				}
+				if len(structField.Name) == 0 {
+					structField.Name = embeddedName(structField.Type)
+				}
+				(*_type).Fields = append((*_type).Fields, structField)
 			}
 			structDef += "\n}"
 			cstructDef += "}"
@@ -1953,12 +2736,30 @@ func (e *Entry) ParseType(runtimeVersion string, moduleData *ModuleData, typeAdd
 	values := make([]Type, 0, parsedTypes.Len())
 
 	for el := m.Front(); el != nil; el = el.Next() {
-		values = append(values, (el.Value).(Type))
+		typ := (el.Value).(Type)
+		typ.PkgPath, typ.Methods = e.typeMethods(runtimeVersion, moduleData, typ, is64bit, littleendian)
+		values = append(values, e.internType(typ))
 	}
 
 	return values, nil
 }
 
+// internType is typ, or the type parsed at its VA before when they're the same. The walks of the typelinks and itabs parse each
+// type again for every type reaching it, the copies then share their strings and slices rather than each holding its own.
+func (e *Entry) internType(typ Type) Type {
+	if parsed, ok := e.parsedTypes[typ.VA]; ok {
+		if reflect.DeepEqual(parsed, typ) {
+			return parsed
+		}
+		return typ
+	}
+	if e.parsedTypes == nil {
+		e.parsedTypes = make(map[uint64]Type)
+	}
+	e.parsedTypes[typ.VA] = typ
+	return typ
+}
+
 func (e *Entry) ParseTypeLinks(runtimeVersion string, moduleData *ModuleData, is64bit bool, littleendian bool) (types []Type, err error) {
 	// Major version only, 1.15.5 -> 1.15
 	parts := strings.Split(runtimeVersion, ".")
@@ -1972,10 +2773,23 @@ func (e *Entry) ParseTypeLinks(runtimeVersion string, moduleData *ModuleData, is
 	} else {
 		ptrSize = 4
 	}
+	// each walk returns the types its type reaches too, a type is listed once
+	seen := make(map[uint64]bool)
+	appendUnseen := func(parsed []Type) {
+		for _, typ := range parsed {
+			if !seen[typ.VA] {
+				seen[typ.VA] = true
+				types = append(types, typ)
+			}
+		}
+	}
 
 	// Handle legacy layout first (1.5, 1.6). The typelinks is a pointer array
 	if moduleData.LegacyTypes.Data != 0 && moduleData.LegacyTypes.Len != 0 {
 		for i := 0; i < int(moduleData.LegacyTypes.Len); i++ {
+			if err := contextErr(e.ctx); err != nil {
+				return types, err
+			}
 			typeAddress, err := e.ReadPointerSizeMem(uint64(moduleData.LegacyTypes.Data)+ptrSize*uint64(i), is64bit, littleendian)
 			if err != nil {
 				continue
@@ -1983,7 +2797,7 @@ func (e *Entry) ParseTypeLinks(runtimeVersion string, moduleData *ModuleData, is
 
 			parsed, err := e.ParseType(runtimeVersion, moduleData, typeAddress, is64bit, littleendian)
 			if err == nil {
-				types = append(types, parsed...)
+				appendUnseen(parsed)
 			}
 		}
 		return types, nil
@@ -1991,6 +2805,9 @@ func (e *Entry) ParseTypeLinks(runtimeVersion string, moduleData *ModuleData, is
 
 	// Modern layout, the typelinks is an array of offsets
 	for i := 0; i < int(moduleData.Typelinks.Len); i++ {
+		if err := contextErr(e.ctx); err != nil {
+			return types, err
+		}
 		// array of int32 offsets into moduleData.Types
 		offset, err := e.raw.read_memory(uint64(moduleData.Typelinks.Data)+uint64(i)*4, 4)
 		if err != nil {
@@ -2008,13 +2825,15 @@ func (e *Entry) ParseTypeLinks(runtimeVersion string, moduleData *ModuleData, is
 
 		parsed, err := e.ParseType(runtimeVersion, moduleData, typeAddress, is64bit, littleendian)
 		if err == nil {
-			types = append(types, parsed...)
+			appendUnseen(parsed)
 		}
 	}
 	return types, nil
 }
 
-func (e *Entry) ParseITabLinks(runtimeVersion string, moduleData *ModuleData, is64bit bool, littleendian bool) (types []Type, err error) {
+// ParseITabLinks parses the interface and concrete types of the itabs of the module, with a type per itab named after the two, and
+// with SetMethods the itabs with the methods of their tables
+func (e *Entry) ParseITabLinks(runtimeVersion string, moduleData *ModuleData, is64bit bool, littleendian bool) (types []Type, itabs []Itab, err error) {
 	// Major version only, 1.15.5 -> 1.15
 	parts := strings.Split(runtimeVersion, ".")
 	if len(parts) >= 2 {
@@ -2027,8 +2846,21 @@ func (e *Entry) ParseITabLinks(runtimeVersion string, moduleData *ModuleData, is
 	} else {
 		ptrSize = 4
 	}
+	// each walk returns the types its type reaches too, a type is listed once
+	seen := make(map[uint64]bool)
+	appendUnseen := func(parsed []Type) {
+		for _, typ := range parsed {
+			if !seen[typ.VA] {
+				seen[typ.VA] = true
+				types = append(types, typ)
+			}
+		}
+	}
 
 	for i := 0; i < int(moduleData.ITablinks.Len); i++ {
+		if err := contextErr(e.ctx); err != nil {
+			return types, itabs, err
+		}
 		itabAddr, err := e.ReadPointerSizeMem(uint64(moduleData.ITablinks.Data)+ptrSize*uint64(i), is64bit, littleendian)
 		if err != nil {
 			continue
@@ -2053,22 +2885,32 @@ func (e *Entry) ParseITabLinks(runtimeVersion string, moduleData *ModuleData, is
 		// }
 		parsed, err := e.ParseType(runtimeVersion, moduleData, interfaceAddr, is64bit, littleendian)
 		if err == nil {
-			types = append(types, parsed...)
+			appendUnseen(parsed)
 		}
 
 		parsed2, err2 := e.ParseType(runtimeVersion, moduleData, typeAddr, is64bit, littleendian)
 		if err2 == nil {
-			types = append(types, parsed2...)
+			appendUnseen(parsed2)
 		}
 
 		// the interface itself, we need to insert as a type. We'll name it after its interface + its implementing type, the 0th of each parsed array
 		if err == nil && err2 == nil && len(parsed) > 0 && len(parsed2) > 0 {
 			interfaceName := parsed[0].Str
 			implementerName := parsed2[0].Str
-			types = append(types, Type{VA: itabAddr, Str: fmt.Sprintf("interface_%s_impl_%s", interfaceName, implementerName), Kind: Interface.String()})
+			appendUnseen([]Type{{VA: itabAddr, Str: fmt.Sprintf("interface_%s_impl_%s", interfaceName, implementerName), Kind: Interface.String()}})
+			// the method tables only with SetMethods
+			for _, iface := range parsed {
+				if !e.methods {
+					break
+				}
+				if iface.VA == interfaceAddr {
+					itabs = append(itabs, e.readItab(runtimeVersion, moduleData, itabAddr, iface, implementerName, is64bit, littleendian))
+					break
+				}
+			}
 		}
 	}
-	return types, nil
+	return types, itabs, nil
 }
 
 func (e *Entry) Text() (uint64, []byte, error) {
@@ -2079,6 +2921,18 @@ func (e *Entry) GOARCH() string {
 	return e.raw.goarch()
 }
 
+// ByteOrder is the byte order the file header declares, or for a dump the one of its architecture. The pclntab magic tells it too,
+// this is for the reads made before one is found or when its magic is stomped. Little endian when neither tells.
+func (e *Entry) ByteOrder() binary.ByteOrder {
+	if order := e.raw.endian(); order != nil {
+		return order
+	}
+	if order := byteOrders[e.raw.goarch()]; order != nil {
+		return order
+	}
+	return binary.LittleEndian
+}
+
 // LoadAddress returns the expected load address of the file.
 // This differs from the actual load address for a position-independent
 // executable.
@@ -2086,6 +2940,16 @@ func (e *Entry) LoadAddress() (uint64, error) {
 	return e.raw.loadAddress()
 }
 
+// ImageBase returns the base the RVAs of a PE or a dump are relative to, after any rebase. 0 for other files.
+func (e *Entry) ImageBase() uint64 {
+	switch e.raw.(type) {
+	case *peFile, *dumpFile:
+		base, _ := e.raw.loadAddress()
+		return base
+	}
+	return 0
+}
+
 // DWARF returns DWARF debug data for the file, if any.
 // This is for cmd/pprof to locate cgo functions.
 func (e *Entry) DWARF() (*dwarf.Data, error) {
@@ -0,0 +1,101 @@
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// closeRecorder is a reader of the caller's, which the file must leave open
+type closeRecorder struct {
+	*bytes.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestOpenReader(t *testing.T) {
+	contents := buildOverlapElf(false)
+	r := &closeRecorder{Reader: bytes.NewReader(contents)}
+	f, err := OpenReader(r, int64(len(contents)))
+	if err != nil {
+		t.Fatalf("failed to open the reader: %s", err)
+	}
+	data, err := f.entries[0].raw.read_memory(overlapVA, 8)
+	if err != nil || string(data) != "GOODGOOD" {
+		t.Errorf("expected to read GOODGOOD, got %q, %v", data, err)
+	}
+	if got := readerSize(f.Reader()); got != uint64(len(contents)) {
+		t.Errorf("expected the file to be %d bytes, got %d", len(contents), got)
+	}
+	if err := f.Close(); err != nil || r.closed {
+		t.Errorf("expected closing the file to leave the reader open, closed=%v, %v", r.closed, err)
+	}
+
+	// the size bounds what's read, past it the file is truncated
+	truncated := io.NewSectionReader(bytes.NewReader(append(contents, make([]byte, 0x100)...)), 0, int64(len(contents)))
+	if _, err := OpenReader(truncated, 0x40); err == nil {
+		t.Errorf("expected a file cut at its ELF header not to open")
+	}
+	if _, err := OpenReader(bytes.NewReader([]byte("not an executable")), 17); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("expected an unrecognized file error, got %v", err)
+	}
+}
+
+func TestModuleDataTable32(t *testing.T) {
+	// a 1.12 moduledata of a 386 binary at 0x8100000, the pclntab it points at and a functab whose first entry is minpc
+	le := binary.LittleEndian
+	data := make([]byte, 0x1000)
+	words := []uint32{
+		0x8100800, 0x100, 0x100, // pclntable
+		0x8100900, 3, 3, // ftab
+		0x8100a00, 2, 2, // filetab
+		0x8100b00,                                  // findfunctab
+		0x8049000, 0x8049800, 0x8049000, 0x8049800, // minpc, maxpc, text, etext
+		0x8101000, 0x8101100, 0x8101100, 0x8101200, 0x8101200, 0x8101300, 0x8101300, 0x8101400, // noptrdata to enoptrbss
+		0x8101400,            // end
+		0x8101500, 0x8101600, // gcdata, gcbss
+		0x8080000, 0x8080400, // types, etypes
+		0x8100c00, 1, 1, // textsectmap
+		0x8100d00, 5, 5, // typelinks
+		0x8100e00, 2, 2, // itablinks
+		0, 0, 0, // ptab
+		0x8100f00, 6, // pluginpath
+		0, 0, 0, // pkghashes
+		0, 0, // modulename
+		0, 0, 0, // modulehashes
+		1,          // hasmain
+		0, 0, 0, 0, // gcdatamask, gcbssmask
+		0, 0, // typemap, badload
+		0, // next
+	}
+	for i, word := range words {
+		le.PutUint32(data[i*4:], word)
+	}
+	le.PutUint32(data[0x800:], 0xfffffffb)
+	le.PutUint32(data[0x900:], 0x8049000)
+	copy(data[0xf00:], "plug/p")
+	raw := &dumpFile{format: "raw", arch: "386", byteOrder: le, base: 0x8049000, size: 0x1000 + uint64(len(data)), regions: []dumpRegion{
+		{name: "text", addr: 0x8049000, data: make([]byte, 0x1000), executable: true},
+		{name: "data", addr: 0x8100000, data: data},
+	}}
+
+	_, module, err := (&Entry{raw: raw}).ModuleDataTable(0x8100800, "1.12.17", "1.2", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if module.VA != 0x8100000 || module.LayoutSource != LayoutTable || module.TextVA != 0x8049000 || module.ETextVA != 0x8049800 {
+		t.Errorf("unexpected moduledata %+v", module)
+	}
+	if module.Types != 0x8080000 || module.ETypes != 0x8080400 || module.Typelinks.Data != 0x8100d00 || module.Typelinks.Len != 5 || module.ITablinks.Len != 2 {
+		t.Errorf("unexpected types %+v", module)
+	}
+	if module.Noptrdata != 0x8101000 || module.Edata != 0x8101200 || module.PluginPath != "plug/p" {
+		t.Errorf("unexpected data %+v", module)
+	}
+}
@@ -0,0 +1,92 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"fmt"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+	"github.com/mandiant/GoReSym/debug/macho"
+	"github.com/mandiant/GoReSym/debug/pe"
+)
+
+// peFileExtent is how many bytes of a PE section the file holds and the loader maps: its raw data, unless the section is smaller in
+// memory. The rest of a section larger in memory than on disk is zeroed, it has no file offset.
+func peFileExtent(sect *pe.Section) uint64 {
+	if sect.VirtualSize != 0 && sect.VirtualSize < sect.Size {
+		return uint64(sect.VirtualSize)
+	}
+	return uint64(sect.Size)
+}
+
+// FileOffsetToVA returns the VA the byte at offset of the file is loaded at, through the ELF segments, the PE sections or the Mach-O
+// segments. The offset of a fat Mach-O slice is from the start of the slice. Dumps and wasm files have no file layout to map.
+func (e *Entry) FileOffsetToVA(offset uint64) (uint64, error) {
+	switch f := e.raw.(type) {
+	case *elfFile:
+		for _, prog := range f.elf.Progs {
+			if prog.Type == elf.PT_LOAD && prog.Off <= offset && offset < prog.Off+prog.Filesz {
+				return prog.Vaddr + offset - prog.Off, nil
+			}
+		}
+	case *peFile:
+		imageBase, _ := f.loadAddress()
+		for _, sect := range f.pe.Sections {
+			if uint64(sect.Offset) <= offset && offset < uint64(sect.Offset)+peFileExtent(sect) {
+				return imageBase + uint64(sect.VirtualAddress) + offset - uint64(sect.Offset), nil
+			}
+		}
+	case *machoFile:
+		for _, load := range f.macho.Loads {
+			if seg, ok := load.(*macho.Segment); ok && seg.Name != "__PAGEZERO" && seg.Offset <= offset && offset < seg.Offset+seg.Filesz {
+				return seg.Addr + offset - seg.Offset, nil
+			}
+		}
+	default:
+		return 0, fmt.Errorf("file offsets don't map to addresses in this format")
+	}
+	return 0, fmt.Errorf("file offset 0x%x isn't loaded", offset)
+}
+
+// VAToFileOffset returns the offset of the file the byte at VA is loaded from, the reverse of FileOffsetToVA. A VA the loader zeroes
+// has none, ex: the bss or the tail of a PE section larger in memory than on disk, and neither does any VA of a dump.
+func (e *Entry) VAToFileOffset(VA uint64) (uint64, error) {
+	switch f := e.raw.(type) {
+	case *elfFile:
+		for _, prog := range f.elf.Progs {
+			if prog.Type == elf.PT_LOAD && prog.Vaddr <= VA && VA < prog.Vaddr+prog.Filesz {
+				return prog.Off + VA - prog.Vaddr, nil
+			}
+		}
+	case *peFile:
+		imageBase, _ := f.loadAddress()
+		for _, sect := range f.pe.Sections {
+			start := imageBase + uint64(sect.VirtualAddress)
+			if start <= VA && VA < start+peFileExtent(sect) {
+				return uint64(sect.Offset) + VA - start, nil
+			}
+		}
+	case *machoFile:
+		for _, load := range f.macho.Loads {
+			if seg, ok := load.(*macho.Segment); ok && seg.Name != "__PAGEZERO" && seg.Addr <= VA && VA < seg.Addr+seg.Filesz {
+				return seg.Offset + VA - seg.Addr, nil
+			}
+		}
+	default:
+		return 0, fmt.Errorf("addresses don't map to file offsets in this format")
+	}
+	return 0, fmt.Errorf("0x%x isn't in the file", VA)
+}
+
+// RVABase returns the base the RVAs are relative to: the image base of a PE or a dump, the start of the first ELF segment, rounded
+// down to its alignment, or the __TEXT segment of a Mach-O. 0 when there's none, the RVAs are the VAs then.
+func (e *Entry) RVABase() uint64 {
+	switch f := e.raw.(type) {
+	case *elfFile:
+		start, _, _ := loadExtent(f.elf.Progs)
+		return start
+	case *peFile, *dumpFile, *machoFile:
+		base, _ := f.loadAddress()
+		return base
+	}
+	return 0
+}
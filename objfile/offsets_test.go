@@ -0,0 +1,81 @@
+package objfile
+
+import (
+	"testing"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+	"github.com/mandiant/GoReSym/debug/macho"
+	"github.com/mandiant/GoReSym/debug/pe"
+)
+
+func TestAddressTranslation(t *testing.T) {
+	// the text, then data whose tail is bss: 0x400 bytes on disk of 0x1000 in memory
+	elfEntry := &Entry{raw: &elfFile{elf: &elf.File{Progs: []*elf.Prog{
+		{ProgHeader: elf.ProgHeader{Type: elf.PT_LOAD, Off: 0, Vaddr: 0x400000, Filesz: 0x2000, Memsz: 0x2000, Align: 0x1000}},
+		{ProgHeader: elf.ProgHeader{Type: elf.PT_LOAD, Off: 0x2000, Vaddr: 0x403000, Filesz: 0x400, Memsz: 0x1000, Align: 0x1000}},
+	}}}}
+	// .text is padded on disk past its virtual size, .data is larger in memory than on disk
+	peEntry := &Entry{raw: &peFile{pe: &pe.File{OptionalHeader: &pe.OptionalHeader64{ImageBase: 0x140000000}, Sections: []*pe.Section{
+		{SectionHeader: pe.SectionHeader{Name: ".text", VirtualAddress: 0x1000, VirtualSize: 0xe00, Size: 0x1000, Offset: 0x400}},
+		{SectionHeader: pe.SectionHeader{Name: ".data", VirtualAddress: 0x2000, VirtualSize: 0x3000, Size: 0x200, Offset: 0x1400}},
+	}}}}
+	// __DATA is larger in memory than on disk
+	machoEntry := &Entry{raw: &machoFile{macho: &macho.File{Loads: []macho.Load{
+		&macho.Segment{SegmentHeader: macho.SegmentHeader{Name: "__PAGEZERO", Addr: 0, Memsz: 0x1000000}},
+		&macho.Segment{SegmentHeader: macho.SegmentHeader{Name: "__TEXT", Addr: 0x1000000, Memsz: 0x2000, Offset: 0, Filesz: 0x2000}},
+		&macho.Segment{SegmentHeader: macho.SegmentHeader{Name: "__DATA", Addr: 0x1002000, Memsz: 0x2000, Offset: 0x2000, Filesz: 0x800}},
+	}}}}
+
+	cases := []struct {
+		name   string
+		entry  *Entry
+		base   uint64
+		VA     uint64
+		offset uint64
+		mapped bool
+	}{
+		{"elf text", elfEntry, 0x400000, 0x401234, 0x1234, true},
+		{"elf data", elfEntry, 0x400000, 0x4033ff, 0x23ff, true},
+		{"elf bss", elfEntry, 0x400000, 0x403400, 0, false},
+		{"pe text", peEntry, 0x140000000, 0x140001010, 0x410, true},
+		{"pe text padding", peEntry, 0x140000000, 0x140001e00, 0, false},
+		{"pe data", peEntry, 0x140000000, 0x1400021ff, 0x15ff, true},
+		{"pe data past its raw size", peEntry, 0x140000000, 0x140002200, 0, false},
+		{"macho text", machoEntry, 0x1000000, 0x1001000, 0x1000, true},
+		{"macho data", machoEntry, 0x1000000, 0x1002010, 0x2010, true},
+		{"macho zerofill", machoEntry, 0x1000000, 0x1002800, 0, false},
+		{"macho pagezero", machoEntry, 0x1000000, 0x10, 0, false},
+	}
+	for _, c := range cases {
+		if base := c.entry.RVABase(); base != c.base {
+			t.Errorf("%s: expected the RVAs relative to 0x%x, got 0x%x", c.name, c.base, base)
+		}
+		offset, err := c.entry.VAToFileOffset(c.VA)
+		if !c.mapped {
+			if err == nil {
+				t.Errorf("%s: expected 0x%x to have no file offset, got 0x%x", c.name, c.VA, offset)
+			}
+			continue
+		}
+		if err != nil || offset != c.offset {
+			t.Errorf("%s: expected 0x%x at file offset 0x%x, got 0x%x %v", c.name, c.VA, c.offset, offset, err)
+			continue
+		}
+		if VA, err := c.entry.FileOffsetToVA(offset); err != nil || VA != c.VA {
+			t.Errorf("%s: expected file offset 0x%x to load at 0x%x, got 0x%x %v", c.name, offset, c.VA, VA, err)
+		}
+	}
+
+	// the padding of .text is in the file but isn't loaded
+	if VA, err := peEntry.FileOffsetToVA(0x1300); err == nil {
+		t.Errorf("expected the padding past the virtual size of .text to be unloaded, got 0x%x", VA)
+	}
+
+	dump := &Entry{raw: &dumpFile{format: "raw", base: 0x400000, size: 0x100, regions: []dumpRegion{{name: "image", addr: 0x400000, data: make([]byte, 0x100)}}}}
+	if offset, err := dump.VAToFileOffset(0x400010); err == nil {
+		t.Errorf("expected a dump to have no file offsets, got 0x%x", offset)
+	}
+	if base := dump.RVABase(); base != 0x400000 {
+		t.Errorf("expected the RVAs of a dump relative to its base, got 0x%x", base)
+	}
+}
@@ -0,0 +1,113 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/mandiant/GoReSym/debug/pe"
+)
+
+// PEOverlay is the data of a PE file past the end of its last section, not counting the certificate and COFF symbol tables
+type PEOverlay struct {
+	Offset  uint64 // in the file, the overlay has no VA
+	Size    uint64
+	Scanned bool // included in the pclntab scan, see OpenOptions.ScanOverlay
+}
+
+// overlay finds the overlay of a PE file of fileSize bytes. The certificate table and the COFF symbols are appended after the sections too,
+// those are trimmed off either end.
+func overlay(f *pe.File, fileSize uint64) (offset uint64, size uint64) {
+	var start uint64
+	for _, sect := range f.Sections {
+		if sect.Offset != 0 {
+			start = max(start, uint64(sect.Offset)+uint64(sect.Size))
+		}
+	}
+	end := fileSize
+
+	type trailer struct{ start, end uint64 }
+	var trailers []trailer
+	var dirs []pe.DataDirectory
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+	case *pe.OptionalHeader64:
+		dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+	}
+	// the certificate table's VirtualAddress is a file offset
+	if len(dirs) > pe.IMAGE_DIRECTORY_ENTRY_SECURITY && dirs[pe.IMAGE_DIRECTORY_ENTRY_SECURITY].Size != 0 {
+		cert := dirs[pe.IMAGE_DIRECTORY_ENTRY_SECURITY]
+		trailers = append(trailers, trailer{uint64(cert.VirtualAddress), uint64(cert.VirtualAddress) + uint64(cert.Size)})
+	}
+	if f.PointerToSymbolTable != 0 {
+		symbols := uint64(f.PointerToSymbolTable) + 18*uint64(f.NumberOfSymbols)
+		trailers = append(trailers, trailer{uint64(f.PointerToSymbolTable), symbols + 4 + uint64(len(f.StringTable))})
+	}
+
+	for trimmed := true; trimmed && start < end; {
+		trimmed = false
+		for _, t := range trailers {
+			if t.start <= start && t.end > start {
+				start, trimmed = t.end, true
+			} else if t.end >= end && t.start < end {
+				end, trimmed = max(t.start, start), true
+			}
+		}
+	}
+	if start >= end {
+		return 0, 0
+	}
+	return start, end - start
+}
+
+// readerSize returns the size of the file r reads
+func readerSize(r io.ReaderAt) uint64 {
+	switch s := r.(type) {
+	case interface{ Size() int64 }:
+		return uint64(s.Size())
+	case *os.File:
+		if info, err := s.Stat(); err == nil {
+			return uint64(info.Size())
+		}
+	}
+	n, _ := io.Copy(io.Discard, io.NewSectionReader(r, 0, 1<<62))
+	return uint64(n)
+}
+
+// the bytes of a 64 bit pcHeader up to the end of its textStart, all pcHeaderTextStart reads
+const pcHeaderTextStartEnd = 8 + 3*8
+
+// pcHeaderTextStart reads the textStart of a 1.18+ pcHeader, the base of its function entries. Older pclntabs hold absolute entries, 0 is returned for them.
+func pcHeaderTextStart(header []byte) uint64 {
+	if len(header) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if header[0] == 0xff {
+		order = binary.BigEndian
+	}
+	if magic := order.Uint32(header); magic != 0xfffffff0 && magic != 0xfffffff1 {
+		return 0
+	}
+
+	ptrSize := int(header[7])
+	field := 8 + 2*ptrSize
+	switch {
+	case ptrSize == 4 && len(header) >= field+4:
+		return uint64(order.Uint32(header[field:]))
+	case ptrSize == 8 && len(header) >= field+8:
+		return order.Uint64(header[field:])
+	}
+	return 0
+}
+
+// Overlay returns the overlay of a PE file, or nil when it has none or isn't a PE
+func (e *Entry) Overlay() *PEOverlay {
+	f, ok := e.raw.(*peFile)
+	if !ok || f.overlaySize == 0 {
+		return nil
+	}
+	return &PEOverlay{Offset: f.overlayOffset, Size: f.overlaySize, Scanned: f.overlay != nil}
+}
@@ -0,0 +1,166 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+	"github.com/mandiant/GoReSym/debug/macho"
+	"github.com/mandiant/GoReSym/debug/pe"
+)
+
+// UPX writes its l_info block, with this magic, right after the headers of the packed image
+var upxMagic = []byte("UPX!")
+
+// how much of the start of the file counts as headers. The magic must be structural, Go binaries that merely mention UPX (like this one) carry the string in rodata.
+const packerHeaderWindow = 0x1000
+
+// detectPackerHeaders reports the packer whose marker sits in the header region of the file, or an empty string
+func detectPackerHeaders(r io.ReaderAt) string {
+	header := make([]byte, packerHeaderWindow)
+	n, _ := r.ReadAt(header, 0)
+	if bytes.Contains(header[:n], upxMagic) {
+		return "UPX"
+	}
+	return ""
+}
+
+// Packer reports the executable packer the file was processed with, ex: 'UPX', or an empty string if none was detected.
+// Packed files only expose the unpacking stub, the Go metadata is compressed and can't be recovered until the file is unpacked.
+func (e *Entry) Packer(r io.ReaderAt) string {
+	// the UPX PE stub renames the sections, the l_info block can sit past the header window when there are many of them
+	if f, ok := e.raw.(*peFile); ok {
+		for _, sect := range f.pe.Sections {
+			if sect.Name == "UPX0" || sect.Name == "UPX1" {
+				return "UPX"
+			}
+		}
+	}
+	return detectPackerHeaders(r)
+}
+
+// compressed or encrypted code is close to 8 bits of entropy per byte, machine code stays well under 7
+const packedEntropy = 7.2
+
+// the most of a section read to measure its entropy
+const entropySampleSize = 16 << 20
+
+// a packed PE imports little more than what the stub needs to load and map the payload, a Go binary imports dozens of functions
+const (
+	maxPackedImports   = 12
+	minPackedImageSize = 1 << 20
+)
+
+// an executable segment mapping this many times its file size is decompressed into at run time
+const packedInflation = 4
+
+// PackingInfo is why a file looks packed, the Go metadata of a packed file isn't readable until it's unpacked
+type PackingInfo struct {
+	Packer   string // the packer identified by its markers, ex: UPX
+	Evidence []string
+}
+
+// entropy computes the Shannon entropy of data in bits per byte
+func entropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	var bits float64
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(len(data))
+			bits -= p * math.Log2(p)
+		}
+	}
+	return bits
+}
+
+// fileEntropy measures the entropy of size bytes of the file at offset, at most entropySampleSize of them
+func fileEntropy(r io.ReaderAt, offset uint64, size uint64) float64 {
+	data := make([]byte, min(size, entropySampleSize))
+	n, _ := r.ReadAt(data, int64(offset))
+	return entropy(data[:n])
+}
+
+// executableRange is a section or segment with code in it, where it is in the file and how much memory it maps
+type executableRange struct {
+	name   string
+	offset uint64
+	filesz uint64
+	memsz  uint64
+}
+
+// executableRanges lists the ranges of the file holding code: the executable PE sections, ELF PT_LOAD segments and Mach-O segments
+func (e *Entry) executableRanges() []executableRange {
+	var ranges []executableRange
+	switch f := e.raw.(type) {
+	case *peFile:
+		const memExecute = 0x20000000
+		for _, sect := range f.pe.Sections {
+			if sect.Characteristics&memExecute != 0 {
+				ranges = append(ranges, executableRange{sect.Name, uint64(sect.Offset), uint64(sect.Size), uint64(sect.VirtualSize)})
+			}
+		}
+	case *elfFile:
+		for _, prog := range f.elf.Progs {
+			if prog.Type == elf.PT_LOAD && prog.Flags&elf.PF_X != 0 {
+				ranges = append(ranges, executableRange{fmt.Sprintf("PT_LOAD at 0x%x", prog.Vaddr), prog.Off, prog.Filesz, prog.Memsz})
+			}
+		}
+	case *machoFile:
+		const protExecute = 4
+		for _, load := range f.macho.Loads {
+			if seg, ok := load.(*macho.Segment); ok && seg.Prot&protExecute != 0 {
+				ranges = append(ranges, executableRange{seg.Name, seg.Offset, seg.Filesz, seg.Memsz})
+			}
+		}
+	}
+	return ranges
+}
+
+// LikelyPacked collects the signs of an executable packer or crypter: the markers of a known packer, a single executable section
+// that is nearly random, a tiny import table on a large PE image, or code that is decompressed into a much larger mapping.
+// Returns nil when there are none, r reads the file.
+func (e *Entry) LikelyPacked(r io.ReaderAt) *PackingInfo {
+	info := &PackingInfo{Packer: e.Packer(r)}
+	if len(info.Packer) > 0 {
+		info.Evidence = append(info.Evidence, fmt.Sprintf("%s markers in the headers or section names", info.Packer))
+	}
+
+	ranges := e.executableRanges()
+	if len(ranges) == 1 && ranges[0].filesz > 0 {
+		if bits := fileEntropy(r, ranges[0].offset, ranges[0].filesz); bits >= packedEntropy {
+			info.Evidence = append(info.Evidence, fmt.Sprintf("the only executable section, %s, has an entropy of %.2f bits per byte", ranges[0].name, bits))
+		}
+	}
+	for _, rng := range ranges {
+		if rng.memsz >= packedInflation*rng.filesz && rng.memsz >= minPackedImageSize {
+			info.Evidence = append(info.Evidence, fmt.Sprintf("%s maps 0x%x bytes of memory from 0x%x bytes of file", rng.name, rng.memsz, rng.filesz))
+		}
+	}
+
+	if f, ok := e.raw.(*peFile); ok {
+		var imageSize uint64
+		switch oh := f.pe.OptionalHeader.(type) {
+		case *pe.OptionalHeader32:
+			imageSize = uint64(oh.SizeOfImage)
+		case *pe.OptionalHeader64:
+			imageSize = uint64(oh.SizeOfImage)
+		}
+		if imports, err := f.pe.ImportedSymbols(); err == nil && len(imports) < maxPackedImports && imageSize >= minPackedImageSize {
+			info.Evidence = append(info.Evidence, fmt.Sprintf("%d imported functions for a 0x%x byte image", len(imports), imageSize))
+		}
+	}
+
+	if len(info.Evidence) == 0 {
+		return nil
+	}
+	return info
+}
@@ -0,0 +1,42 @@
+package objfile
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDetectPackerHeaders(t *testing.T) {
+	header := make([]byte, 0x200)
+	copy(header, "\x7fELF")
+
+	if packer := detectPackerHeaders(bytes.NewReader(header)); packer != "" {
+		t.Errorf("unpacked header detected as %s", packer)
+	}
+
+	// l_info follows the program headers
+	copy(header[0x7c:], "UPX!")
+	if packer := detectPackerHeaders(bytes.NewReader(header)); packer != "UPX" {
+		t.Errorf("expected UPX, got %q", packer)
+	}
+
+	// the marker past the headers is just data
+	data := make([]byte, packerHeaderWindow+0x100)
+	copy(data[packerHeaderWindow+0x10:], "UPX!")
+	if packer := detectPackerHeaders(bytes.NewReader(data)); packer != "" {
+		t.Errorf("marker outside the headers detected as %s", packer)
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	if bits := entropy(bytes.Repeat([]byte{0x90}, 0x100)); bits != 0 {
+		t.Errorf("expected no entropy for a run of one byte, got %f", bits)
+	}
+
+	every := make([]byte, 0x100)
+	for i := range every {
+		every[i] = byte(i)
+	}
+	if bits := entropy(bytes.Repeat(every, 4)); bits < packedEntropy || bits > 8 {
+		t.Errorf("expected 8 bits for every byte value equally often, got %f", bits)
+	}
+}
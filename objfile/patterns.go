@@ -1,10 +1,16 @@
 package objfile
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"golang.org/x/exp/slices"
 	"rsc.io/binaryregexp"
@@ -33,6 +39,78 @@ func isHex(s string) bool {
 	return true
 }
 
+// validatePattern checks the characters and spacing of a pattern, the conversion drops the spaces so a typo like "4 8" would silently become 48.
+// Errors name the offset in pattern. Hex digits are case insensitive, as in YARA.
+func validatePattern(pattern string) error {
+	body := strings.TrimSuffix(strings.TrimPrefix(pattern, "{"), "}")
+	if len(strings.TrimSpace(body)) == 0 {
+		return errors.New("empty pattern")
+	}
+
+	pending := -1 // offset of the first nibble of an unfinished byte
+	group := -1   // offset of the open (
+	for i := 0; i < len(body); i++ {
+		pos := i + 1 // in pattern, past the {
+		c := body[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if pending != -1 {
+				return fmt.Errorf("position %d: byte split by whitespace", pending+1)
+			}
+		case isHexRune(rune(c|0x20)) || c == '?':
+			if pending == -1 {
+				pending = i
+				continue
+			}
+			if body[pending] == '?' && c != '?' {
+				return fmt.Errorf("position %d: cannot mask the first nibble", pending+1)
+			}
+			pending = -1
+		case c == '~':
+			if pending != -1 || i+1 >= len(body) || body[i+1] == ' ' {
+				return fmt.Errorf("position %d: ~ must prefix a byte", pos)
+			}
+		case c == '(':
+			if pending != -1 || group != -1 {
+				return fmt.Errorf("position %d: unexpected (", pos)
+			}
+			group = i
+		case c == '|' || c == ')':
+			if pending != -1 || group == -1 {
+				return fmt.Errorf("position %d: unexpected %c", pos, c)
+			}
+			if c == ')' {
+				group = -1
+			}
+		case c == '[':
+			if pending != -1 {
+				return fmt.Errorf("position %d: unexpected [", pos)
+			}
+			end := strings.IndexByte(body[i:], ']')
+			if end == -1 {
+				return fmt.Errorf("position %d: unbalanced [", pos)
+			}
+			low, high, found := strings.Cut(body[i+1:i+end], "-")
+			lowInt, lowErr := strconv.Atoi(low)
+			highInt, highErr := strconv.Atoi(high)
+			if !found || lowErr != nil || highErr != nil || lowInt < 0 || lowInt > highInt {
+				return fmt.Errorf("position %d: range %s is not [low-high]", pos, body[i:i+end+1])
+			}
+			i += end
+		default:
+			return fmt.Errorf("position %d: unexpected character %q", pos, c)
+		}
+	}
+
+	if pending != -1 {
+		return fmt.Errorf("position %d: incomplete byte", pending+1)
+	}
+	if group != -1 {
+		return fmt.Errorf("position %d: unbalanced (", group+1)
+	}
+	return nil
+}
+
 // translate from a yara-style pattern, like:
 //
 //	{ 48 8D 0? ?? ?? ?? ?? EB ?? 48 8? 8? ?? 02 00 00 66 0F 1F 44 00 00 }
@@ -54,6 +132,10 @@ func RegexpPatternFromYaraPattern(pattern string) (*RegexAndNeedle, error) {
 		return nil, errors.New("missing suffix")
 	}
 
+	if err := validatePattern(pattern); err != nil {
+		return nil, err
+	}
+
 	pattern = strings.Trim(pattern, "{}")
 
 	pattern = strings.ReplaceAll(pattern, " ", "")
@@ -65,6 +147,14 @@ func RegexpPatternFromYaraPattern(pattern string) (*RegexAndNeedle, error) {
 	needleOffset := 0
 	needle := make([]byte, 0)
 	tmpNeedle := make([]byte, 0)
+	// the bytes before the first range sit at a fixed offset from the match start
+	var prefix []patternByte
+	fixedOffset := true
+	addPrefix := func(mask byte, value byte) {
+		if fixedOffset {
+			prefix = append(prefix, patternByte{mask, value})
+		}
+	}
 
 	resetNeedle := func() {
 		patLen += sequenceLen
@@ -80,7 +170,10 @@ func RegexpPatternFromYaraPattern(pattern string) (*RegexAndNeedle, error) {
 	for i := 0; i < len(pattern); {
 		// at the start of this loop,
 		// i will be aligned to the start of a nibble (or [] range),
-		// so both i and i+1 will be valid.
+		// so both i and i+1 will be valid, unless the pattern ends on a lone character.
+		if i+1 >= len(pattern) {
+			return nil, errors.New("incomplete byte")
+		}
 
 		c := pattern[i : i+1]
 		d := pattern[i+1 : i+2]
@@ -93,6 +186,7 @@ func RegexpPatternFromYaraPattern(pattern string) (*RegexAndNeedle, error) {
 			}
 
 			regex_pattern += "."
+			addPrefix(0, 0)
 
 			i += 2
 			resetNeedle()
@@ -137,6 +231,7 @@ func RegexpPatternFromYaraPattern(pattern string) (*RegexAndNeedle, error) {
 			// must produce:
 			// AA BB
 			regex_pattern += "?"
+			fixedOffset = false
 
 			i += end + 1
 			resetNeedle()
@@ -153,13 +248,28 @@ func RegexpPatternFromYaraPattern(pattern string) (*RegexAndNeedle, error) {
 			}
 
 			chunk := pattern[i+1 : i+end]
+			if strings.Contains(chunk, "(") {
+				return nil, errors.New("nested (")
+			}
 			choices := strings.Split(chunk, "|")
 
 			regex_pattern += "("
+			// the bits every choice agrees on
+			var choiceMask, choiceValue byte = 0xFF, 0
 			for j, choice := range choices {
 				if !isHex(choice) {
 					return nil, errors.New("choice not hex")
 				}
+				// every choice is one byte, the group counts as one byte of the pattern
+				if len(choice) != 2 {
+					return nil, errors.New("choice not a single byte")
+				}
+				byt, _ := strconv.ParseUint(choice, 16, 8)
+				if j == 0 {
+					choiceValue = byte(byt)
+				} else {
+					choiceMask &^= choiceValue ^ byte(byt)
+				}
 
 				if j != 0 {
 					regex_pattern += "|"
@@ -168,6 +278,7 @@ func RegexpPatternFromYaraPattern(pattern string) (*RegexAndNeedle, error) {
 				regex_pattern += `\x` + strings.ToUpper(choice)
 			}
 			regex_pattern += ")"
+			addPrefix(choiceMask, choiceValue&choiceMask)
 
 			i += end + 1
 			resetNeedle()
@@ -187,6 +298,8 @@ func RegexpPatternFromYaraPattern(pattern string) (*RegexAndNeedle, error) {
 			regex_pattern += "-"
 			regex_pattern += `\x` + strings.ToUpper(c) + "F"
 			regex_pattern += "]"
+			nibble, _ := strconv.ParseUint(c, 16, 8)
+			addPrefix(0xF0, byte(nibble<<4))
 
 			i += 2
 			resetNeedle()
@@ -203,6 +316,7 @@ func RegexpPatternFromYaraPattern(pattern string) (*RegexAndNeedle, error) {
 				return nil, errors.New("not hex digit")
 			}
 			tmpNeedle = append(tmpNeedle, byte(byt))
+			addPrefix(0xFF, byte(byt))
 			i += 2
 			sequenceLen += 1
 			continue
@@ -219,6 +333,7 @@ func RegexpPatternFromYaraPattern(pattern string) (*RegexAndNeedle, error) {
 			regex_pattern += "[^"
 			regex_pattern += `\x` + strings.ToUpper(d+e)
 			regex_pattern += "]"
+			addPrefix(0, 0)
 
 			i += 3
 			resetNeedle()
@@ -236,7 +351,7 @@ func RegexpPatternFromYaraPattern(pattern string) (*RegexAndNeedle, error) {
 	if err != nil {
 		return nil, errors.New("failed to compile regex")
 	}
-	return &RegexAndNeedle{patLen, regex_pattern, r, needleOffset, needle}, nil
+	return &RegexAndNeedle{patLen, regex_pattern, r, needleOffset, needle, prefix}, nil
 }
 
 func getOrSetRegion(regionMap map[int]map[int]bool, start, end int) bool {
@@ -286,6 +401,9 @@ func FindRegex(data []byte, regexInfo *RegexAndNeedle) [][]int {
 		// adjust the window to the pattern start and end
 		data_start := needleMatch - regexInfo.needleOffset
 		data_end := data_start + regexInfo.len
+		if regexInfo.rejects(data, data_start) {
+			continue
+		}
 		if data_start >= data_len {
 			continue
 		}
@@ -329,10 +447,182 @@ func FindRegex(data []byte, regexInfo *RegexAndNeedle) [][]int {
 	return regionMapToSlices(matchMap)
 }
 
+// FindRegexReader is FindRegex over the first size bytes of r, read window bytes at a time instead of all at once.
+// Each read is padded by the pattern length on both sides, so a match is scanned exactly as FindRegex would even when it spans windows.
+// window is raised to the pattern length if smaller.
+func FindRegexReader(r io.ReaderAt, size int64, regexInfo *RegexAndNeedle, window int) ([][]int, error) {
+	return findRegexReader(nil, r, size, regexInfo, window)
+}
+
+// findRegexReader is FindRegexReader stopping with ctx.Err() before the next window once ctx is done. ctx may be nil.
+func findRegexReader(ctx context.Context, r io.ReaderAt, size int64, regexInfo *RegexAndNeedle, window int) ([][]int, error) {
+	if window < regexInfo.len {
+		window = regexInfo.len
+	}
+	pad := int64(regexInfo.len)
+
+	matchMap := make(map[int]map[int]bool)
+	buf := make([]byte, int64(window)+2*pad)
+	for base := int64(0); base < size; base += int64(window) {
+		if err := contextErr(ctx); err != nil {
+			return nil, err
+		}
+		chunkStart := base - pad
+		if chunkStart < 0 {
+			chunkStart = 0
+		}
+		chunkEnd := base + int64(window) + pad
+		if chunkEnd > size {
+			chunkEnd = size
+		}
+
+		chunk := buf[:chunkEnd-chunkStart]
+		if _, err := r.ReadAt(chunk, chunkStart); err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		// matches starting in the padding belong to the neighbouring windows
+		for _, match := range FindRegex(chunk, regexInfo) {
+			start := chunkStart + int64(match[0])
+			if start >= base && start < base+int64(window) {
+				getOrSetRegion(matchMap, int(start), int(chunkStart)+match[1])
+			}
+		}
+	}
+	return regionMapToSlices(matchMap), nil
+}
+
+// the bytes IndexReader reads at a time by default, the data scanned for a needle is never held whole
+const scanChunkSize = 4 << 20
+
+// IndexReader is bytes.Index over the first size bytes of r, read chunk bytes at a time instead of all at once. Each read runs
+// len(needle)-1 bytes into the next chunk, so a needle spanning two is found where bytes.Index would find it. -1 without one.
+func IndexReader(r io.ReaderAt, size int64, needle []byte, chunk int) (int64, error) {
+	if len(needle) == 0 {
+		return 0, nil
+	}
+	if chunk <= 0 {
+		chunk = scanChunkSize
+	}
+	buf := make([]byte, chunk+len(needle)-1)
+	for base := int64(0); base < size; base += int64(chunk) {
+		n, err := r.ReadAt(buf[:min(int64(len(buf)), size-base)], base)
+		if err != nil && err != io.EOF {
+			return -1, err
+		}
+		if idx := bytes.Index(buf[:n], needle); idx != -1 {
+			return base + int64(idx), nil
+		}
+		if n < int(min(int64(len(buf)), size-base)) {
+			break
+		}
+	}
+	return -1, nil
+}
+
+// FindRegexParallel is FindRegex with data split between workers goroutines, 0 for GOMAXPROCS. The chunks are padded by the pattern length
+// like the windows of FindRegexReader, and the matches are merged in chunk order, so the result is the same as FindRegex.
+func FindRegexParallel(data []byte, regexInfo *RegexAndNeedle, workers int) [][]int {
+	matches, _ := findRegexParallel(nil, data, regexInfo, workers)
+	return matches
+}
+
+// findRegexParallel is FindRegexParallel stopping with ctx.Err() once ctx is done. The chunks are no larger than scanChunkSize, more of
+// them than workers if need be, and ctx is checked before each so a large section doesn't run to its end once canceled. ctx may be nil.
+func findRegexParallel(ctx context.Context, data []byte, regexInfo *RegexAndNeedle, workers int) ([][]int, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	window := (len(data) + workers - 1) / workers
+	if window > scanChunkSize {
+		window = scanChunkSize
+	}
+	if window < regexInfo.len {
+		window = regexInfo.len
+	}
+	if window >= len(data) {
+		if err := contextErr(ctx); err != nil {
+			return nil, err
+		}
+		return FindRegex(data, regexInfo), nil
+	}
+	pad := regexInfo.len
+
+	chunks := (len(data) + window - 1) / window
+	chunkMatches := make([][][]int, chunks)
+	running := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < chunks && contextErr(ctx) == nil; i++ {
+		wg.Add(1)
+		running <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-running
+				wg.Done()
+			}()
+			if contextErr(ctx) != nil {
+				return
+			}
+
+			base := i * window
+			chunkStart := base - pad
+			if chunkStart < 0 {
+				chunkStart = 0
+			}
+			chunkEnd := base + window + pad
+			if chunkEnd > len(data) {
+				chunkEnd = len(data)
+			}
+
+			// matches starting in the padding belong to the neighbouring chunks
+			for _, match := range FindRegex(data[chunkStart:chunkEnd], regexInfo) {
+				start := chunkStart + match[0]
+				if start >= base && start < base+window {
+					chunkMatches[i] = append(chunkMatches[i], []int{start, chunkStart + match[1]})
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := contextErr(ctx); err != nil {
+		return nil, err
+	}
+
+	var result [][]int = make([][]int, 0)
+	for _, matches := range chunkMatches {
+		result = append(result, matches...)
+	}
+	return result, nil
+}
+
 type RegexAndNeedle struct {
 	len          int
 	rawre        string
 	re           *binaryregexp.Regexp
 	needleOffset int    // offset within the pattern
 	needle       []byte // longest fixed sub-sequence of regex
+	prefix       []patternByte
+}
+
+// patternByte is one byte of a pattern as the bits that must match, zero for a wildcard
+type patternByte struct {
+	mask  byte
+	value byte
+}
+
+// rejects reports whether no match can start at start, from the masked prefix bytes alone. It's a cheap check before the regex runs,
+// mostly for the signatures whose needle is a single byte. It only applies when the needle is in the prefix, otherwise the needle doesn't fix the start.
+func (r *RegexAndNeedle) rejects(data []byte, start int) bool {
+	if len(r.prefix) < r.needleOffset+len(r.needle) {
+		return false
+	}
+	if start < 0 || start+len(r.prefix) > len(data) {
+		return true
+	}
+	for i, b := range r.prefix {
+		if data[start+i]&b.mask != b.value {
+			return true
+		}
+	}
+	return false
 }
@@ -2,7 +2,10 @@ package objfile
 
 import (
 	"bytes"
+	"context"
+	"io"
 	"reflect"
+	"sync/atomic"
 	"testing"
 
 	"rsc.io/binaryregexp"
@@ -107,18 +110,78 @@ func TestRegexGrouping(t *testing.T) {
 	})
 }
 
+func TestValidatePattern(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		expected string
+	}{
+		{"{ 4 8 8D }", "position 2: byte split by whitespace"},
+		{"{ 48 8D 0}", "position 8: incomplete byte"},
+		{"{ 48 8G }", "position 6: unexpected character 'G'"},
+		{"{ 48, 8D }", "position 4: unexpected character ','"},
+		{"{ 48 ?8 }", "position 5: cannot mask the first nibble"},
+		{"{ 48 (8D|8B }", "position 5: unbalanced ("},
+		{"{ 48 8D | 0D }", "position 8: unexpected |"},
+		{"{ 48 [4-2] 8D }", "position 5: range [4-2] is not [low-high]"},
+		{"{ 48 [4] 8D }", "position 5: range [4] is not [low-high]"},
+		{"{ 48 ~ 8D }", "position 5: ~ must prefix a byte"},
+	}
+	for _, c := range cases {
+		if _, err := RegexpPatternFromYaraPattern(c.pattern); err == nil || err.Error() != c.expected {
+			t.Errorf("%s: expected %q, got %v", c.pattern, c.expected, err)
+		}
+	}
+
+	// everything the built-in signatures use, lowercase hex is fine as in YARA
+	for _, sig := range []string{x64sig.signature, x86sig.signature, ARM64_sig.signature, ARM32_sig.signature, ARM32_movw_sig.signature, RISCV64_sig.signature,
+		LOONG64_sig.signature, S390X_sig.signature, PPC_BE_sig.signature, PPC64LE_sig.signature, MIPS_BE_sig.signature, MIPS_LE_sig.signature,
+		MIPS64_BE_sig.signature, MIPS64_LE_sig.signature, "{ 48 ~8d 0? [0-4] (aa|Bb) }"} {
+		if err := validatePattern(sig); err != nil {
+			t.Errorf("%s: %s", sig, err)
+		}
+	}
+}
+
+func TestRegexpPatternAlternation(t *testing.T) {
+	reg, err := RegexpPatternFromYaraPattern("{ (48|4C) 8D 0? }")
+	if err != nil {
+		t.Fatalf("pattern errored: %s", err)
+	}
+	if reg.rawre != `(\x48|\x4C)\x8D[\x00-\x0F]` || reg.len != 3 {
+		t.Errorf("incorrect pattern %s, length %d", reg.rawre, reg.len)
+	}
+
+	data := []byte{0x48, 0x8D, 0x05, 0x4C, 0x8D, 0x0D, 0x49, 0x8D, 0x05}
+	if matches := FindRegex(data, reg); !reflect.DeepEqual(matches, [][]int{{0, 3}, {3, 6}}) {
+		t.Errorf("expected both choices to match, got %v", matches)
+	}
+
+	for _, bad := range []string{
+		"{ (48|4C 8D }",       // unterminated
+		"{ ((48|4C)|8D) }",    // nested
+		"{ (48|) 8D }",        // empty choice
+		"{ () 8D }",           // empty group
+		"{ (4889|4C89) }",     // multi byte choice
+		"{ (4?|48) 8D }",      // wildcard choice
+		"{ (48|4C) 8D 0? ) }", // unbalanced )
+	} {
+		if _, err := RegexpPatternFromYaraPattern(bad); err == nil {
+			t.Errorf("%s should have errored", bad)
+		}
+	}
+}
+
 func TestRegexpPatternFromYaraPattern(t *testing.T) {
 	t.Run("empty", func(t *testing.T) {
 		if _, err := RegexpPatternFromYaraPattern(""); err == nil {
 			t.Errorf("empty pattern should have errored")
 		}
 
-		reg, err := RegexpPatternFromYaraPattern("{}")
-		if err != nil {
-			t.Errorf("empty pattern errored")
-		}
-		if reg.rawre != "" {
-			t.Errorf("incorrect empty pattern")
+		// a signature that can't match anything is a mistake
+		for _, empty := range []string{"{}", "{  }"} {
+			if _, err := RegexpPatternFromYaraPattern(empty); err == nil {
+				t.Errorf("%q should have errored", empty)
+			}
 		}
 	})
 
@@ -292,3 +355,230 @@ func TestRegexpPatternFromYaraPattern(t *testing.T) {
 		}
 	})
 }
+
+func TestFindRegexReader(t *testing.T) {
+	reg, err := RegexpPatternFromYaraPattern("{ AA [0-4] BB CC }")
+	if err != nil {
+		t.Fatalf("pattern errored")
+	}
+
+	var data []byte
+	for i := 0; i < 40; i++ {
+		data = append(data, 0xAA, 0xAA, byte(i), 0xBB, 0xCC, 0x11, 0xAA, 0xBB, 0xCC)
+	}
+
+	expected := FindRegex(data, reg)
+	if len(expected) == 0 {
+		t.Fatalf("no matches in the buffered scan")
+	}
+
+	// windows below the pattern length are raised to it, every match then spans or abuts a boundary somewhere
+	for _, window := range []int{1, reg.len, 7, 13, len(data)} {
+		matches, err := FindRegexReader(bytes.NewReader(data), int64(len(data)), reg, window)
+		if err != nil {
+			t.Fatalf("window %d: %s", window, err)
+		}
+		if !reflect.DeepEqual(matches, expected) {
+			t.Errorf("window %d: expected %v, got %v", window, expected, matches)
+		}
+	}
+}
+
+func TestIndexReader(t *testing.T) {
+	needle := []byte("go1.")
+	data := noiseData(4096)
+	// straddling the 1024 byte chunks, then the first whole one
+	copy(data[1022:], "go1.")
+	copy(data[2047:], "go1.")
+
+	for _, chunk := range []int{1, 3, 1024, 1025, len(data), 0} {
+		for _, cut := range []int{len(data), 1025, 1024, 1023} {
+			expected := int64(bytes.Index(data[:cut], needle))
+			idx, err := IndexReader(bytes.NewReader(data), int64(cut), needle, chunk)
+			if err != nil || idx != expected {
+				t.Errorf("chunk %d over %d bytes: expected %d, got %d %v", chunk, cut, expected, idx, err)
+			}
+		}
+	}
+	if idx, _ := IndexReader(bytes.NewReader(data), 1000, needle, 64); idx != -1 {
+		t.Errorf("expected no needle in the first 1000 bytes, got %d", idx)
+	}
+}
+
+func BenchmarkIndexReader(b *testing.B) {
+	// a section the needle is at the end of, everything is scanned
+	data := noiseData(64 << 20)
+	copy(data[len(data)-16:], "/src/runtime/os_")
+	for _, bench := range []struct {
+		name  string
+		index func(r io.ReaderAt, size int64) int64
+	}{
+		{"whole", func(r io.ReaderAt, size int64) int64 {
+			all, _ := io.ReadAll(io.NewSectionReader(r, 0, size))
+			return int64(bytes.Index(all, []byte("/src/runtime/os_")))
+		}},
+		{"chunked", func(r io.ReaderAt, size int64) int64 {
+			idx, _ := IndexReader(r, size, []byte("/src/runtime/os_"), scanChunkSize)
+			return idx
+		}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if bench.index(bytes.NewReader(data), int64(len(data))) != int64(len(data)-16) {
+					b.Fatal("the needle wasn't found")
+				}
+			}
+		})
+	}
+}
+
+// the same pattern without the prefix check, scanned by the regex alone
+func withoutPrefix(reg *RegexAndNeedle) *RegexAndNeedle {
+	plain := *reg
+	plain.prefix = nil
+	return &plain
+}
+
+// pseudo random bytes, with the same seed every run
+func noiseData(size int) []byte {
+	data := make([]byte, size)
+	state := uint32(1)
+	for i := range data {
+		state = state*1664525 + 1013904223
+		data[i] = byte(state >> 24)
+	}
+	return data
+}
+
+func TestFindRegexPrefix(t *testing.T) {
+	reg, err := RegexpPatternFromYaraPattern("{ ?? (1A|1B) 5? 02 ?? [0-2] 28 }")
+	if err != nil {
+		t.Fatalf("pattern errored")
+	}
+	if !reflect.DeepEqual(reg.prefix, []patternByte{{0, 0}, {0xFE, 0x1A}, {0xF0, 0x50}, {0xFF, 0x02}, {0, 0}}) {
+		t.Errorf("incorrect prefix %v", reg.prefix)
+	}
+
+	// a match at both ends of the buffer, a truncated one and two that overlap
+	data := noiseData(4096)
+	copy(data, []byte{0x00, 0x1B, 0x53, 0x02, 0x00, 0x28})
+	copy(data[100:], []byte{0x00, 0x1A, 0x50, 0x02, 0x00, 0x1A, 0x51, 0x02, 0x00, 0x28, 0x28})
+	copy(data[len(data)-7:], []byte{0x11, 0x1B, 0x5F, 0x02, 0x22, 0x33, 0x28})
+	data = append(data, 0x00, 0x1A, 0x50, 0x02)
+
+	for _, pattern := range []string{"{ ?? (1A|1B) 5? 02 ?? [0-2] 28 }", x64sig.signature, x86sig.signature, ARM32_movw_sig.signature, LOONG64_sig.signature, "{ 00 (1A|1B) ~51 02 }"} {
+		reg, err := RegexpPatternFromYaraPattern(pattern)
+		if err != nil {
+			t.Fatalf("%s errored", pattern)
+		}
+
+		expected := FindRegex(data, withoutPrefix(reg))
+		if matches := FindRegex(data, reg); !reflect.DeepEqual(matches, expected) {
+			t.Errorf("%s: expected %v, got %v", pattern, expected, matches)
+		}
+	}
+}
+
+func BenchmarkFindRegexPrefix(b *testing.B) {
+	data := noiseData(48 << 20)
+	for _, bench := range []struct {
+		name string
+		reg  *RegexAndNeedle
+	}{
+		{"prefix", LOONG64_sig.compiledRegex},
+		{"regex", withoutPrefix(LOONG64_sig.compiledRegex)},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				FindRegex(data, bench.reg)
+			}
+		})
+	}
+}
+
+func TestFindRegexParallel(t *testing.T) {
+	reg, err := RegexpPatternFromYaraPattern("{ AA [0-4] BB CC }")
+	if err != nil {
+		t.Fatalf("pattern errored")
+	}
+
+	// 7 workers split 4096 bytes into chunks of 586, plant a match straddling or touching every boundary
+	data := noiseData(4096)
+	for boundary := 586; boundary < len(data); boundary += 586 {
+		copy(data[boundary-3:], []byte{0xAA, 0x00, 0xBB, 0xCC})
+		copy(data[boundary+1:], []byte{0xAA, 0xBB, 0xCC})
+	}
+	copy(data[len(data)-3:], []byte{0xAA, 0xBB, 0xCC})
+
+	expected := FindRegex(data, reg)
+	if len(expected) < 12 {
+		t.Fatalf("expected the planted matches, got %v", expected)
+	}
+	for _, workers := range []int{0, 1, 2, 7, 4096, 10000} {
+		if matches := FindRegexParallel(data, reg, workers); !reflect.DeepEqual(matches, expected) {
+			t.Errorf("%d workers: expected %v, got %v", workers, expected, matches)
+		}
+	}
+}
+
+// cancelingReader cancels its scan once it served reads reads
+type cancelingReader struct {
+	io.ReaderAt
+	cancel func()
+	reads  int
+	after  int
+}
+
+func (r *cancelingReader) ReadAt(p []byte, off int64) (int, error) {
+	r.reads++
+	if r.reads == r.after {
+		r.cancel()
+	}
+	return r.ReaderAt.ReadAt(p, off)
+}
+
+// doneAfterContext is done once Err was asked calls times
+type doneAfterContext struct {
+	context.Context
+	calls atomic.Int32
+}
+
+func (c *doneAfterContext) Err() error {
+	if c.calls.Add(-1) < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestFindRegexCanceled(t *testing.T) {
+	reg, err := RegexpPatternFromYaraPattern("{ AA [0-4] BB CC }")
+	if err != nil {
+		t.Fatalf("pattern errored")
+	}
+
+	// the reader scan stops before the window after the one it was canceled in
+	data := bytes.Repeat([]byte{0xAA, 0xBB, 0xCC, 0x00}, 64)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := &cancelingReader{ReaderAt: bytes.NewReader(data), cancel: cancel, after: 2}
+	if matches, err := findRegexReader(ctx, r, int64(len(data)), reg, 16); err != context.Canceled || matches != nil {
+		t.Errorf("expected the reader scan canceled, got %v, %v", matches, err)
+	}
+	if r.reads != 2 {
+		t.Errorf("expected 2 windows read, got %d", r.reads)
+	}
+
+	// a single worker still checks ctx between its chunks of scanChunkSize bytes
+	large := make([]byte, 4*scanChunkSize)
+	ctxDone := &doneAfterContext{Context: context.Background()}
+	ctxDone.calls.Store(2)
+	if matches, err := findRegexParallel(ctxDone, large, reg, 1); err != context.Canceled || matches != nil {
+		t.Errorf("expected the parallel scan canceled, got %v, %v", matches, err)
+	}
+	if matches, err := findRegexParallel(context.Background(), large, reg, 1); err != nil || len(matches) != 0 {
+		t.Errorf("expected no matches, got %v, %v", matches, err)
+	}
+}
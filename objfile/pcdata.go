@@ -0,0 +1,110 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+)
+
+// the pcdata table of the unsafe points from Go 1.14 on, before 1.16 it's the register maps whose unsafe value marks them
+const pcdataUnsafePoint = 0
+
+// the most stack maps a function is taken to have, a larger count is a corrupt runtime.stackmap
+const maxStackMaps = 1 << 16
+
+// what a pc is to the asynchronous preemption, UnsafePoint.Point
+const (
+	PointSafe           = "safe"             // the goroutine can be preempted there
+	PointUnsafe         = "unsafe"           // it can't, ex: the write barrier or a runtime function
+	PointRestart1       = "restart-1"        // preempted, the sequence is restarted from its start, 1.16 on
+	PointRestart2       = "restart-2"        // like restart-1, for a sequence next to one of those, 1.16 on
+	PointRestartAtEntry = "restart-at-entry" // preempted, the function is restarted from its entry, 1.16 on
+	PointUnknown        = "unknown"          // a value the runtime doesn't define
+)
+
+// UnsafePoint is what the code from Start up to End is to the asynchronous preemption, Value is the pcdata value it's decoded from
+type UnsafePoint struct {
+	Start uint64
+	End   uint64
+	Point string
+	Value int
+}
+
+// FunctionPCData is the safe points of a function: whether each pc can be preempted, and how many stack maps of its arguments and of
+// its locals there are for the garbage collector at its calls
+type FunctionPCData struct {
+	UnsafePoints    []UnsafePoint
+	ArgsStackMaps   int
+	LocalsStackMaps int
+}
+
+// unsafePoint names the value of the unsafe point table of Go 1.16 on, or that of the register maps of 1.14 and 1.15 where only the
+// unsafe value is special, the others being the index of a register map
+func unsafePoint(value int, minor int) string {
+	switch {
+	case value == -2:
+		return PointUnsafe
+	case minor < 16:
+		return PointSafe
+	case value == -1:
+		return PointSafe
+	case value == -3:
+		return PointRestart1
+	case value == -4:
+		return PointRestart2
+	case value == -5:
+		return PointRestartAtEntry
+	}
+	return PointUnknown
+}
+
+// stackMapCount reads the n of the runtime.stackmap at addr, the number of bitmaps it has
+func (e *Entry) stackMapCount(addr uint64, order binary.ByteOrder) (int, error) {
+	if addr == 0 {
+		return 0, nil
+	}
+	data, err := e.raw.read_memory(addr, 4)
+	if err != nil || len(data) < 4 {
+		return 0, fmt.Errorf("failed to read the stack map at 0x%x", addr)
+	}
+	n := order.Uint32(data)
+	if n > maxStackMaps {
+		return 0, fmt.Errorf("bad stack map at 0x%x", addr)
+	}
+	return int(n), nil
+}
+
+// PCData decodes the safe points of fn from the pcdata and funcdata of table. The unsafe points came with the asynchronous preemption
+// of Go 1.14 and the restartable sequences with 1.16, goVersion tells them and the count of funcdata before 1.12 apart as the tables
+// look the same, the points of the earlier versions are nil. moduleData gives the base of the funcdata from Go 1.18 on, without it the stack maps aren't counted.
+func (e *Entry) PCData(table *gosym.Table, fn *gosym.Func, moduleData *ModuleData, goVersion string) (*FunctionPCData, error) {
+	if table.Go12line == nil {
+		return nil, nil
+	}
+	minor, ok := goMinorVersion(goVersion)
+	if !ok {
+		return nil, fmt.Errorf("the pcdata of Go %s aren't decoded, its version is needed", goVersion)
+	}
+	pcdata := &FunctionPCData{}
+	if minor >= 14 {
+		for _, r := range table.PCDataRanges(fn, pcdataUnsafePoint) {
+			pcdata.UnsafePoints = append(pcdata.UnsafePoints, UnsafePoint{Start: r.Start, End: r.End, Point: unsafePoint(r.Value, minor), Value: r.Value})
+		}
+	}
+
+	var gofunc uint64
+	if moduleData != nil {
+		gofunc = moduleData.Gofunc
+	}
+	args, locals := table.StackMaps(fn, gofunc, minor < 12)
+	var err error
+	if pcdata.ArgsStackMaps, err = e.stackMapCount(args, table.Go12line.Binary); err != nil {
+		return pcdata, err
+	}
+	if pcdata.LocalsStackMaps, err = e.stackMapCount(locals, table.Go12line.Binary); err != nil {
+		return pcdata, err
+	}
+	return pcdata, nil
+}
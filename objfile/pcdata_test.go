@@ -0,0 +1,51 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestUnsafePoint(t *testing.T) {
+	tests := []struct {
+		value int
+		minor int
+		point string
+	}{
+		{-1, 22, PointSafe},
+		{-2, 22, PointUnsafe},
+		{-3, 16, PointRestart1},
+		{-4, 16, PointRestart2},
+		{-5, 22, PointRestartAtEntry},
+		{3, 22, PointUnknown},
+		// before 1.16 the table is the register maps, only the unsafe value is special
+		{-2, 14, PointUnsafe},
+		{-1, 15, PointSafe},
+		{3, 15, PointSafe},
+		{-3, 15, PointSafe},
+	}
+	for _, test := range tests {
+		if point := unsafePoint(test.value, test.minor); point != test.point {
+			t.Errorf("value %d of 1.%d: expected %s, got %s", test.value, test.minor, test.point, point)
+		}
+	}
+}
+
+func TestStackMapCount(t *testing.T) {
+	memory := make([]byte, 0x100)
+	binary.BigEndian.PutUint32(memory[0x10:], 7)
+	binary.BigEndian.PutUint32(memory[0x20:], maxStackMaps+1)
+	e := &Entry{raw: &dumpFile{format: "raw", base: 0x1000, size: uint64(len(memory)), regions: []dumpRegion{{name: "image", addr: 0x1000, data: memory}}}}
+
+	if n, err := e.stackMapCount(0x1010, binary.BigEndian); err != nil || n != 7 {
+		t.Errorf("expected 7 stack maps, got %d %v", n, err)
+	}
+	if n, err := e.stackMapCount(0, binary.BigEndian); err != nil || n != 0 {
+		t.Errorf("expected none without a stack map, got %d %v", n, err)
+	}
+	if _, err := e.stackMapCount(0x1020, binary.BigEndian); err == nil {
+		t.Errorf("expected a corrupt count to fail")
+	}
+	if _, err := e.stackMapCount(0x5000, binary.BigEndian); err == nil {
+		t.Errorf("expected an unmapped stack map to fail")
+	}
+}
@@ -0,0 +1,105 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// the most functions a pclntab header can declare, the largest binaries have a few hundred thousand
+	maxPclntabFuncs = 1 << 22
+	// the longest function name checkPclntabHeader reads
+	maxCheckedFuncName = 0x1000
+)
+
+// checkPclntabHeader tells if a pclntab candidate is worth parsing, the checks a magic that happens to be in random data fails: the
+// header is a magic, two zero bytes, a quantum of 1, 2 or 4 and a pointer size of 4 or 8, it declares at least one function and not
+// absurdly many, and its first function name is printable. The name is the first of the funcnametab from 1.16 on, of the first func
+// before. An offset past the data isn't held against it, the data of a truncated file ends early. When tolerant the name isn't
+// checked, the tolerant parse makes do with names out of bounds. magic, unless nil, is patched over the magic of data as setMagic
+// does, without the copy.
+func checkPclntabHeader(data []byte, magic []byte, tolerant bool) error {
+	header := data[:min(8, len(data))]
+	if magic != nil {
+		header = append([]byte(nil), header...)
+		copy(header, magic)
+	}
+	if !isPCHeader(header) {
+		return fmt.Errorf("no pcHeader, its pad, quantum or pointer size is wrong")
+	}
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	if header[0] == 0xff {
+		byteOrder = binary.BigEndian
+	}
+	ptrSize := int(header[7])
+	word := func(i int) (uint64, bool) {
+		off := 8 + i*ptrSize
+		if off+ptrSize > len(data) {
+			return 0, false
+		}
+		if ptrSize == 4 {
+			return uint64(byteOrder.Uint32(data[off:])), true
+		}
+		return byteOrder.Uint64(data[off:]), true
+	}
+
+	nfunc, ok := word(0)
+	if !ok || nfunc == 0 || nfunc > maxPclntabFuncs {
+		return fmt.Errorf("%d functions declared", nfunc)
+	}
+	if tolerant {
+		return nil
+	}
+
+	var nameOff uint64
+	switch byteOrder.Uint32(header) {
+	case 0xfffffffb:
+		// the ftab follows nfunc, the funcoff of its first entry locates the func whose nameoff follows its entry
+		funcOff, ok := word(2)
+		if !ok || funcOff+uint64(ptrSize)+4 > uint64(len(data)) {
+			return nil
+		}
+		nameOff = uint64(byteOrder.Uint32(data[funcOff+uint64(ptrSize):]))
+	case 0xfffffffa:
+		if nameOff, ok = word(2); !ok {
+			return nil
+		}
+	default:
+		// textStart comes before the offsets from 1.18 on
+		if nameOff, ok = word(3); !ok {
+			return nil
+		}
+	}
+	if nameOff >= uint64(len(data)) {
+		return nil
+	}
+	// the magic bytes aren't a name
+	if nameOff < uint64(len(magic)) {
+		return fmt.Errorf("the first function name at 0x%x isn't printable", nameOff)
+	}
+	name := data[nameOff:min(uint64(len(data)), nameOff+maxCheckedFuncName)]
+	for i := 0; ; {
+		if i == len(name) {
+			return nil
+		}
+		r, size := utf8.DecodeRune(name[i:])
+		if r == 0 && i > 0 {
+			return nil
+		}
+		if r == utf8.RuneError || !unicode.IsPrint(r) {
+			return fmt.Errorf("the first function name at 0x%x isn't printable", nameOff)
+		}
+		i += size
+	}
+}
+
+// candidateLocation names where a candidate was found for the logs, the section at its start when the scan didn't name it
+func candidateLocation(candidate PclntabCandidate) string {
+	if candidate.Location != "" {
+		return candidate.Location
+	}
+	return fmt.Sprintf("the section at 0x%x", candidate.SecStart)
+}
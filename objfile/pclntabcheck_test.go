@@ -0,0 +1,54 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// fakePclntab is a 1.20 pcHeader declaring nfunc functions, the first named name
+func fakePclntab(nfunc uint64, name string) []byte {
+	data := make([]byte, 0x100)
+	binary.LittleEndian.PutUint32(data, 0xfffffff1)
+	data[6], data[7] = 1, 8
+	binary.LittleEndian.PutUint64(data[8:], nfunc)
+	binary.LittleEndian.PutUint64(data[8+3*8:], 0x80) // funcnameOffset
+	copy(data[0x80:], name+"\x00")
+	return data
+}
+
+func TestCheckPclntabHeader(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     []byte
+		tolerant bool
+		valid    bool
+	}{
+		{"valid", fakePclntab(2, "runtime.text"), false, true},
+		{"no functions", fakePclntab(0, "runtime.text"), false, false},
+		{"too many functions", fakePclntab(maxPclntabFuncs+1, "runtime.text"), false, false},
+		{"unprintable name", fakePclntab(2, "\x01\x02"), false, false},
+		{"unprintable name tolerated", fakePclntab(2, "\x01\x02"), true, true},
+		{"truncated", fakePclntab(2, "runtime.text")[:0x20], false, true},
+	}
+	for _, c := range cases {
+		if err := checkPclntabHeader(c.data, nil, c.tolerant); (err == nil) != c.valid {
+			t.Errorf("%s: expected valid %v, got %v", c.name, c.valid, err)
+		}
+	}
+
+	// a stomped magic is checked as the magic patched over it, the data isn't touched
+	stomped := fakePclntab(2, "runtime.text")
+	copy(stomped, []byte{0, 0, 0, 0})
+	if err := checkPclntabHeader(stomped, []byte{0xf1, 0xff, 0xff, 0xff}, false); err != nil || stomped[0] != 0 {
+		t.Errorf("expected the patched magic to be checked, got %v", err)
+	}
+	if checkPclntabHeader(stomped, nil, false) == nil {
+		t.Errorf("expected the stomped magic to be rejected")
+	}
+
+	badQuantum := fakePclntab(2, "runtime.text")
+	badQuantum[6] = 3
+	if checkPclntabHeader(badQuantum, nil, false) == nil {
+		t.Errorf("expected a quantum of 3 to be rejected")
+	}
+}
@@ -10,6 +10,7 @@ package objfile
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -20,15 +21,34 @@ import (
 )
 
 type peFile struct {
-	pe *pe.File
+	pe             *pe.File
+	firstMatchOnly bool                      // stop the moduledata signature scan at the first validated match
+	scanWorkers    int                       // goroutines per section for the signature scan, 0 for GOMAXPROCS
+	diagnostics    *scanDiagnostics          // nil unless SetDiagnostics
+	ctx            context.Context           // nil unless SetContext
+	scanRanges     []ScanRange               // nil unless SetScanRanges
+	signatures     []compiledCustomSignature // nil unless SetSignatures
+	overlayOffset  uint64
+	overlaySize    uint64
+	overlay        []byte // nil unless OpenOptions.ScanOverlay
+	scanResources  bool   // OpenOptions.ScanResources
 }
 
-func openPE(r io.ReaderAt) (rawFile, error) {
+func openPE(r io.ReaderAt, opts OpenOptions) (rawFile, error) {
 	f, err := pe.NewFile(r)
 	if err != nil {
 		return nil, err
 	}
-	return &peFile{f}, nil
+	pf := &peFile{pe: f, scanResources: opts.ScanResources}
+	pf.overlayOffset, pf.overlaySize = overlay(f, readerSize(r))
+	if opts.ScanOverlay && pf.overlaySize != 0 {
+		pf.overlay = make([]byte, pf.overlaySize)
+		if n, err := r.ReadAt(pf.overlay, int64(pf.overlayOffset)); err != nil {
+			pf.overlay = pf.overlay[:n]
+		}
+	}
+	pf.rebase(opts.LoadBase)
+	return pf, nil
 }
 
 func (f *peFile) read_memory(VA uint64, size uint64) (data []byte, err error) {
@@ -47,10 +67,9 @@ func (f *peFile) read_memory(VA uint64, size uint64) (data []byte, err error) {
 			if n > size {
 				n = size
 			}
-			data := make([]byte, n)
-			_, err := sect.ReadAt(data, int64(VA-uint64(sect.VirtualAddress)))
+			data, err := readAvailable(sect, make([]byte, n), int64(VA-uint64(sect.VirtualAddress)), VA+imageBase)
 			if err != nil {
-				return nil, fmt.Errorf("Reading section data failed")
+				return nil, fmt.Errorf("Reading section data failed: %w", err)
 			}
 			return data, nil
 		}
@@ -195,13 +214,9 @@ func (f *peFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 
 		if !has_some_valid_magic {
 			for _, magic := range append(pclntab_sigs_le, pclntab_sigs_be...) {
-				pclntab_copy := make([]byte, len(candidate.Pclntab))
-				copy(pclntab_copy, candidate.Pclntab)
-				copy(pclntab_copy, magic)
-
-				new_candidate := candidate
-				new_candidate.Pclntab = pclntab_copy
-				send_tab(new_candidate)
+				new_candidate := *candidate
+				new_candidate.setMagic(candidate.Pclntab, magic)
+				send_tab(&new_candidate)
 			}
 		}
 	}
@@ -221,14 +236,11 @@ func (f *peFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 					for _, magicLE := range pclntab_sigs_le {
 						// Make a copy of the pclntab with each magic possible. For when the magic is intentionally corrupted
 						// Parsing will fail at some later point for the magics that don't match the version, filtering out that candidate
-						pclntab_copy := make([]byte, len(pclntab))
-						copy(pclntab_copy, pclntab)
-						copy(pclntab_copy, magicLE)
-
 						var candidate PclntabCandidate
 						candidate.StompMagicCandidateMeta = stompedMagicCandidate
-						candidate.Pclntab = pclntab_copy
+						candidate.setMagic(pclntab, magicLE)
 						candidate.SecStart = imageBase + uint64(sec.VirtualAddress)
+						candidate.Location = sec.Name
 						candidate.PclntabVA = pclntab_va_candidate
 
 						send_tab(&candidate)
@@ -237,14 +249,11 @@ func (f *peFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 					for _, magicBE := range pclntab_sigs_be {
 						// Make a copy of the pclntab with each magic possible. For when the magic is intentionally corrupted
 						// Parsing will fail at some later point for the magics that don't match the version, filtering out that candidate
-						pclntab_copy := make([]byte, len(pclntab))
-						copy(pclntab_copy, pclntab)
-						copy(pclntab_copy, magicBE)
-
 						var candidate PclntabCandidate
 						candidate.StompMagicCandidateMeta = stompedMagicCandidate
-						candidate.Pclntab = pclntab_copy
+						candidate.setMagic(pclntab, magicBE)
 						candidate.SecStart = imageBase + uint64(sec.VirtualAddress)
+						candidate.Location = sec.Name
 						candidate.PclntabVA = pclntab_va_candidate
 
 						send_tab(&candidate)
@@ -254,6 +263,7 @@ func (f *peFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 		}
 	}
 
+	f.diagnostics.reset()
 	go func() {
 		defer close(ch_tab)
 
@@ -262,8 +272,15 @@ func (f *peFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 			// malware can split the pclntab across multiple sections, re-merge
 			data := f.pe.DataAfterSection(sec)
 
+			const memExecute = 0x20000000
+			windows := scanWindows(f.scanRanges, imageBase+uint64(sec.VirtualAddress), true, uint64(sec.Offset), sec.Offset != 0, uint64(sec.Size), len(data))
+			if len(windows) == 0 {
+				continue
+			}
+			f.diagnostics.section(sec.Name, imageBase+uint64(sec.VirtualAddress), uint64(sec.Size), sec.Characteristics&memExecute != 0, scannedBytes(f.scanRanges, windows))
+
 			if !foundpcln {
-				matches := findAllOccurrences(data, pclntab_sigs)
+				matches := findWindowOccurrences(data, windows, pclntab_sigs)
 				for _, pclntab_idx := range matches {
 					if pclntab_idx != -1 {
 						pclntab = data[pclntab_idx:]
@@ -272,6 +289,7 @@ func (f *peFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 						candidate.Pclntab = pclntab
 
 						candidate.SecStart = imageBase + uint64(sec.VirtualAddress)
+						candidate.Location = sec.Name
 						candidate.PclntabVA = candidate.SecStart + uint64(pclntab_idx)
 
 						send_patched_magic_candidates(&candidate)
@@ -282,11 +300,12 @@ func (f *peFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 			} else {
 				// 3) if we found it earlier, figure out which section base to return (might be wrong for packed things)
 				pclntab_idx := bytes.Index(data, pclntab)
-				if pclntab_idx != -1 {
+				if pclntab_idx != -1 && inWindows(windows, pclntab_idx) {
 					var candidate PclntabCandidate
 					candidate.Pclntab = pclntab
 
 					candidate.SecStart = imageBase + uint64(sec.VirtualAddress)
+					candidate.Location = sec.Name
 					candidate.PclntabVA = candidate.SecStart + uint64(pclntab_idx)
 
 					send_patched_magic_candidates(&candidate)
@@ -297,7 +316,11 @@ func (f *peFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 			// TODO this scan needs to occur in both big and little endian mode
 			// 4) Always try this other way! Sometimes the pclntab magic is stomped as well so our byte OR symbol location fail. Byte scan for the moduledata, use that to find the pclntab instead, fix up magic with all combinations.
 			// See the obfuscator 'garble' for an example of randomizing the pclntab magic
-			sigResults := findModuleInitPCHeader(data, uint64(sec.VirtualAddress)+imageBase)
+			var sigResults []SignatureMatch
+			for _, w := range windows {
+				sigResults = append(sigResults, findModuleInitPCHeaderWorkers(f.ctx, data[w.start:w.end], uint64(sec.VirtualAddress)+imageBase+uint64(w.start), binary.LittleEndian, f.goarch(), f.signatures, 0, f.scanWorkers, pcHeaderValidator(f.firstMatchOnly, f.read_memory), f.diagnostics)...)
+			}
+			sigResults = rankSignatureMatches(sigResults, f.read_memory, f.diagnostics)
 			for _, sigResult := range sigResults {
 				// example: off_69D0C0 is the moduleData we found via our scan, the first ptr unk_5DF6E0, is the pclntab!
 				// 0x000000000069D0C0 E0 F6 5D 00 00 00 00 00 off_69D0C0      dq offset unk_5DF6E0    ; DATA XREF: runtime_SetFinalizer+119↑o
@@ -341,6 +364,46 @@ func (f *peFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
 				}
 			}
 		}
+
+		// 5) last the overlay, it's never mapped so only the magic is scanned for, the signatures need code at a VA
+		if windows := scanWindows(f.scanRanges, 0, false, f.overlayOffset, true, uint64(len(f.overlay)), len(f.overlay)); f.overlay != nil && len(windows) > 0 {
+			f.diagnostics.section("overlay", 0, uint64(len(f.overlay)), false, scannedBytes(f.scanRanges, windows))
+			for _, pclntab_idx := range findWindowOccurrences(f.overlay, windows, pclntab_sigs) {
+				var candidate PclntabCandidate
+				candidate.Pclntab = f.overlay[pclntab_idx:]
+				candidate.SecStart = pcHeaderTextStart(candidate.Pclntab)
+				candidate.FileOffset = f.overlayOffset + uint64(pclntab_idx)
+				candidate.Overlay = true
+				candidate.Location = "overlay"
+				send_tab(&candidate)
+			}
+		}
+
+		// 6) and each resource on its own, the pclntab of a payload in one isn't the image's, its entries are relative to the payload's text
+		if !f.scanResources {
+			return
+		}
+		for _, resource := range f.resources() {
+			VA := imageBase + uint64(resource.rva)
+			data, err := f.read_memory(VA, uint64(resource.size))
+			if err != nil {
+				continue
+			}
+			windows := scanWindows(f.scanRanges, VA, true, 0, false, uint64(len(data)), len(data))
+			if len(windows) == 0 {
+				continue
+			}
+			f.diagnostics.section("resource "+resource.path, VA, uint64(len(data)), false, scannedBytes(f.scanRanges, windows))
+			for _, pclntab_idx := range findWindowOccurrences(data, windows, pclntab_sigs) {
+				var candidate PclntabCandidate
+				candidate.Pclntab = data[pclntab_idx:]
+				candidate.SecStart = pcHeaderTextStart(candidate.Pclntab)
+				candidate.PclntabVA = VA + uint64(pclntab_idx)
+				candidate.Location = "resource " + resource.path
+				candidate.Resource = true
+				send_tab(&candidate)
+			}
+		}
 	}()
 	return ch_tab, nil
 }
@@ -369,42 +432,14 @@ func (f *peFile) moduledata_scan(pclntabVA uint64, is64bit bool, littleendian bo
 	var moduledata []uint8
 	var secStart uint64
 	var moduledata_idx = 0
-scan:
 	for _, sec := range f.pe.Sections {
 		// malware can split the pclntab across multiple sections, re-merge
 		data := f.pe.DataAfterSection(sec)
 		// fall back to scanning for structure using address of pclntab, which is first value in struc
-		var pclntabVA_bytes []byte
-		if is64bit {
-			pclntabVA_bytes = make([]byte, 8)
-			if littleendian {
-				binary.LittleEndian.PutUint64(pclntabVA_bytes, pclntabVA)
-			} else {
-				binary.BigEndian.PutUint64(pclntabVA_bytes, pclntabVA)
-			}
-		} else {
-			pclntabVA_bytes = make([]byte, 4)
-			if littleendian {
-				binary.LittleEndian.PutUint32(pclntabVA_bytes, uint32(pclntabVA))
-			} else {
-				binary.BigEndian.PutUint32(pclntabVA_bytes, uint32(pclntabVA))
-			}
-		}
-
-		moduledata_idx = bytes.Index(data, pclntabVA_bytes)
-		if moduledata_idx != -1 && moduledata_idx < int(sec.Size) {
+		moduledata_idx = findModuleDataPointer(data, int(sec.Size), imageBase+uint64(sec.VirtualAddress), pclntabVA, is64bit, littleendian, ignorelist)
+		if moduledata_idx != -1 {
 			moduledata = data[moduledata_idx:]
 			secStart = imageBase + uint64(sec.VirtualAddress)
-
-			// optionally consult ignore list, to skip past previous (bad) scan results
-			if ignorelist != nil {
-				for _, ignore := range ignorelist {
-					if ignore == secStart+uint64(moduledata_idx) {
-						continue scan
-					}
-				}
-			}
-
 			found = true
 			break
 		}
@@ -501,6 +536,11 @@ func loadPETable(f *pe.File, sname, ename string) ([]byte, error) {
 	return data[ssym.Value:esym.Value], nil
 }
 
+// endian is little endian, every machine Go targets with PE is
+func (f *peFile) endian() binary.ByteOrder {
+	return binary.LittleEndian
+}
+
 func (f *peFile) goarch() string {
 	switch f.pe.Machine {
 	case pe.IMAGE_FILE_MACHINE_I386:
@@ -509,12 +549,20 @@ func (f *peFile) goarch() string {
 		return "amd64"
 	case pe.IMAGE_FILE_MACHINE_ARMNT:
 		return "arm"
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		return "arm64"
 	default:
 		return ""
 	}
 }
 
 func (f *peFile) loadAddress() (uint64, error) {
+	switch oh := f.pe.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		return uint64(oh.ImageBase), nil
+	case *pe.OptionalHeader64:
+		return oh.ImageBase, nil
+	}
 	return 0, fmt.Errorf("unknown load address")
 }
 
@@ -0,0 +1,124 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// moduledata word offsets of minpc and maxpc. The 1.2 layout starts with the pclntable, ftab and filetab slices and findfunctab,
+// 1.16+ with the pcHeader pointer and six slices and findfunctab.
+const (
+	minpcWord12 = 10
+	minpcWord16 = 20
+)
+
+// candidateScore rates how much the moduledata of a match looks real, from what it points at. It doesn't need the version or the
+// pointer size, every combination is tried and the best kept:
+//
+//	2 the first word points at something shaped like a pcHeader: two zeros, a pc quantum and the pointer size
+//	3 with a known magic, stomped magics don't get these
+//	1 the header's function count is sane
+//	1 minpc and maxpc are a sane range
+//	2 the range covers the matched code, the tie break when a binary embeds another Go binary as data
+func candidateScore(match SignatureMatch, read_memory func(VA uint64, size uint64) ([]byte, error)) int {
+	best := 0
+	for _, ptrSize := range []uint64{8, 4} {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			readPtr := func(VA uint64) (uint64, bool) {
+				data, err := read_memory(VA, ptrSize)
+				if err != nil || uint64(len(data)) < ptrSize {
+					return 0, false
+				}
+				if ptrSize == 4 {
+					return uint64(order.Uint32(data)), true
+				}
+				return order.Uint64(data), true
+			}
+
+			pcHeaderVA, ok := readPtr(match.moduleDataVA)
+			if !ok {
+				continue
+			}
+			header, err := read_memory(pcHeaderVA, 8)
+			if err != nil || len(header) < 8 || header[4] != 0 || header[5] != 0 || (header[6] != 1 && header[6] != 2 && header[6] != 4) || uint64(header[7]) != ptrSize {
+				continue
+			}
+
+			score := 2
+			minpcWords := []uint64{minpcWord16, minpcWord12}
+			switch order.Uint32(header) {
+			case 0xfffffffb:
+				score += 3
+				minpcWords = []uint64{minpcWord12}
+			case 0xfffffffa, 0xfffffff0, 0xfffffff1:
+				score += 3
+				minpcWords = []uint64{minpcWord16}
+			}
+
+			if nfunc, ok := readPtr(pcHeaderVA + 8); ok && nfunc > 0 && nfunc < 1<<24 {
+				score++
+			}
+
+			rangeScore := 0
+			for _, word := range minpcWords {
+				minpc, minOk := readPtr(match.moduleDataVA + word*ptrSize)
+				maxpc, maxOk := readPtr(match.moduleDataVA + (word+1)*ptrSize)
+				if !minOk || !maxOk || minpc >= maxpc || maxpc-minpc >= 1<<30 {
+					continue
+				}
+				wordScore := 1
+				if match.matchVA >= minpc && match.matchVA < maxpc {
+					wordScore += 2
+				}
+				if wordScore > rangeScore {
+					rangeScore = wordScore
+				}
+			}
+			score += rangeScore
+
+			if score > best {
+				best = score
+			}
+		}
+	}
+	return best
+}
+
+// rankSignatureMatches orders the matches by candidateScore, best first, so the moduledata most likely to be real is tried first.
+// Repeats of a moduledata VA are dropped, ties keep the scan order. Nothing is dropped for a low score, stomped magics still score low.
+func rankSignatureMatches(matches []SignatureMatch, read_memory func(VA uint64, size uint64) ([]byte, error), diag *scanDiagnostics) []SignatureMatch {
+	var ranked []SignatureMatch
+	var scores []int
+	seen := make(map[uint64]int)
+	for _, match := range matches {
+		score := candidateScore(match, read_memory)
+		diag.scored(match, score)
+
+		// a repeat keeps the best score of its matches, ex: only one of them sits in the module's text
+		if i, ok := seen[match.moduleDataVA]; ok {
+			if score > scores[i] {
+				ranked[i] = match
+				scores[i] = score
+			}
+			continue
+		}
+		seen[match.moduleDataVA] = len(ranked)
+		ranked = append(ranked, match)
+		scores = append(scores, score)
+	}
+
+	order := make([]int, len(ranked))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	result := make([]SignatureMatch, 0, len(ranked))
+	for _, i := range order {
+		result = append(result, ranked[i])
+	}
+	return result
+}
@@ -0,0 +1,163 @@
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+)
+
+// fakeMemory reads from regions keyed by their start VA
+func fakeMemory(regions map[uint64][]byte) func(VA uint64, size uint64) ([]byte, error) {
+	return func(VA uint64, size uint64) ([]byte, error) {
+		for start, data := range regions {
+			if VA >= start && VA+size <= start+uint64(len(data)) {
+				return data[VA-start : VA-start+size], nil
+			}
+		}
+		return nil, fmt.Errorf("unmapped 0x%x", VA)
+	}
+}
+
+// a 1.18 style 64 bit moduledata at VA pointing at a pcHeader at pcHeaderVA, with minpc and maxpc
+func fakeModuleData(pcHeaderVA uint64, minpc uint64, maxpc uint64) []byte {
+	moduleData := make([]byte, 22*8)
+	binary.LittleEndian.PutUint64(moduleData, pcHeaderVA)
+	binary.LittleEndian.PutUint64(moduleData[minpcWord16*8:], minpc)
+	binary.LittleEndian.PutUint64(moduleData[(minpcWord16+1)*8:], maxpc)
+	return moduleData
+}
+
+func fakePCHeader(magic uint32, nfunc uint64) []byte {
+	header := make([]byte, 16)
+	binary.LittleEndian.PutUint32(header, magic)
+	header[6] = 1
+	header[7] = 8
+	binary.LittleEndian.PutUint64(header[8:], nfunc)
+	return header
+}
+
+func TestRankSignatureMatches(t *testing.T) {
+	readMemory := fakeMemory(map[uint64][]byte{
+		// the embedded binary's runtime, real looking but its text doesn't cover the matches
+		0x500000: fakeModuleData(0x600000, 0x900000, 0x980000),
+		0x600000: fakePCHeader(0xfffffff1, 1000),
+		// the runtime of the scanned binary
+		0x510000: fakeModuleData(0x610000, 0x401000, 0x480000),
+		0x610000: fakePCHeader(0xfffffff1, 1000),
+		// garble stomps the magic, the rest of the header still looks right
+		0x520000: fakeModuleData(0x620000, 0x401000, 0x480000),
+		0x620000: fakePCHeader(0x12345678, 1000),
+		// points at nothing
+		0x530000: fakeModuleData(0x1234, 0, 0),
+	})
+
+	matches := []SignatureMatch{
+		{moduleDataVA: 0x530000, signature: "x64", matchVA: 0x402000},
+		{moduleDataVA: 0x500000, signature: "x64", matchVA: 0x403000},
+		{moduleDataVA: 0x520000, signature: "x64", matchVA: 0x404000},
+		{moduleDataVA: 0x510000, signature: "x64", matchVA: 0x405000},
+		{moduleDataVA: 0x510000, signature: "x64", matchVA: 0x406000},
+	}
+	ranked := rankSignatureMatches(matches, readMemory, nil)
+
+	expected := []uint64{0x510000, 0x500000, 0x520000, 0x530000}
+	if len(ranked) != len(expected) {
+		t.Fatalf("expected %x, got %+v", expected, ranked)
+	}
+	for i, match := range ranked {
+		if match.moduleDataVA != expected[i] {
+			t.Errorf("rank %d: expected 0x%x, got 0x%x", i, expected[i], match.moduleDataVA)
+		}
+	}
+	// the repeat is dropped, the first match of a moduledata is kept on a tie
+	if ranked[0].matchVA != 0x405000 {
+		t.Errorf("expected the first match of the repeat, got 0x%x", ranked[0].matchVA)
+	}
+
+	for _, c := range []struct {
+		match    SignatureMatch
+		expected int
+	}{
+		{matches[0], 0},
+		{matches[1], 2 + 3 + 1 + 1},
+		{matches[2], 2 + 1 + 1 + 2},
+		{matches[3], 2 + 3 + 1 + 1 + 2},
+	} {
+		if score := candidateScore(c.match, readMemory); score != c.expected {
+			t.Errorf("0x%x: expected score %d, got %d", c.match.moduleDataVA, c.expected, score)
+		}
+	}
+}
+
+func TestFindModuleDataPointer(t *testing.T) {
+	data := make([]byte, 0x40)
+	// unaligned, then two aligned copies of the 64 bit pointer
+	binary.LittleEndian.PutUint64(data[0x3:], 0x4c5000)
+	binary.LittleEndian.PutUint64(data[0x10:], 0x4c5000)
+	binary.LittleEndian.PutUint64(data[0x20:], 0x4c5000)
+	// a 32 bit big endian one
+	binary.BigEndian.PutUint32(data[0x34:], 0x4c5000)
+
+	cases := []struct {
+		name         string
+		size         int
+		sectionBase  uint64
+		is64bit      bool
+		littleendian bool
+		ignorelist   []uint64
+		expected     int
+	}{
+		{"first aligned", len(data), 0x500000, true, true, nil, 0x10},
+		{"first ignored", len(data), 0x500000, true, true, []uint64{0x500010}, 0x20},
+		{"all ignored", len(data), 0x500000, true, true, []uint64{0x500010, 0x500020}, -1},
+		{"unaligned base", len(data), 0x500005, true, true, nil, 0x3},
+		{"past the section", 0x10, 0x500000, true, true, nil, -1},
+		{"runs past the section", 0x11, 0x500000, true, true, nil, 0x10},
+		{"32 bit big endian", len(data), 0x500000, false, false, nil, 0x34},
+		{"32 bit, only ignored hits", len(data), 0x500000, false, true, []uint64{0x500010, 0x500020}, -1},
+	}
+	for _, c := range cases {
+		if idx := findModuleDataPointer(data, c.size, c.sectionBase, 0x4c5000, c.is64bit, c.littleendian, c.ignorelist); idx != c.expected {
+			t.Errorf("%s: expected 0x%x, got 0x%x", c.name, c.expected, idx)
+		}
+	}
+}
+
+func TestReconstructedMagic(t *testing.T) {
+	stomped := []byte{0, 0, 0, 0, 0, 0, 1, 8}
+	var candidate PclntabCandidate
+	candidate.setMagic(stomped, []byte{0xf1, 0xff, 0xff, 0xff})
+	if !candidate.ReconstructedMagic || binary.LittleEndian.Uint32(candidate.header(4)) != 0xfffffff1 || stomped[0] != 0 {
+		t.Errorf("expected a patched header, got %x %t", candidate.header(4), candidate.ReconstructedMagic)
+	}
+	// the copy is only made for the parse
+	patched := candidate.patched()
+	if binary.LittleEndian.Uint32(patched) != 0xfffffff1 || stomped[0] != 0 || !bytes.Equal(patched[4:], stomped[4:]) {
+		t.Errorf("expected a patched copy, got %x", patched)
+	}
+	candidate.setMagic(patched, []byte{0xf1, 0xff, 0xff, 0xff})
+	if candidate.ReconstructedMagic || &candidate.patched()[0] != &patched[0] {
+		t.Errorf("the same magic isn't a reconstruction")
+	}
+
+	cases := []struct {
+		name     string
+		table    *gosym.LineTable
+		goarch   string
+		expected bool
+	}{
+		{"amd64", &gosym.LineTable{Quantum: 1, Ptrsize: 8, ValidatedFuncs: 10}, "amd64", true},
+		{"unknown goarch", &gosym.LineTable{Quantum: 4, Ptrsize: 4, ValidatedFuncs: 10}, "", true},
+		{"wrong pointer size", &gosym.LineTable{Quantum: 1, Ptrsize: 4, ValidatedFuncs: 10}, "amd64", false},
+		{"wrong quantum", &gosym.LineTable{Quantum: 1, Ptrsize: 8, ValidatedFuncs: 10}, "arm64", false},
+		{"no valid functions", &gosym.LineTable{Quantum: 2, Ptrsize: 8}, "s390x", false},
+	}
+	for _, c := range cases {
+		if got := plausibleReconstruction(c.table, c.goarch); got != c.expected {
+			t.Errorf("%s: expected %t, got %t", c.name, c.expected, got)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"strings"
+	"unsafe"
+)
+
+// the most bytes of a structure read, a corrupt length beyond it is clamped to it
+const maxRawStructureSize = 64 << 20
+
+// RawStructure is the raw bytes of a structure the extraction located, Name is moduledata, pclntab, typelinks or buildinfo. Size is
+// how many bytes Data has, the size the structure claims unless it was Clamped to maxRawStructureSize or ends past what's mapped.
+// Section is what holds VA, see EmbeddedImage.Region, FileOffset is nil when VA isn't in the file. Data is only kept to be written out.
+type RawStructure struct {
+	Name        string
+	Section     string `json:",omitempty"`
+	VA          uint64
+	FileOffset  *uint64
+	Size        uint64
+	ClaimedSize uint64
+	Clamped     bool   `json:",omitempty"`
+	Data        []byte `json:"-"`
+}
+
+// moduleDataSize is the size of the moduledata struct of the layout of moduleData. A probed one takes up to the last field probed, for
+// a 1.2 layout the runtime version tells the structs of 1.5 and 1.6, 1.7 and the later ones apart, an unknown one is a later one.
+func moduleDataSize(moduleData *ModuleData, runtimeVersion string, is64bit bool) uint64 {
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+	if moduleData.LayoutSource == LayoutProbed {
+		var last uint64
+		for _, offset := range moduleData.ProbedOffsets {
+			last = max(last, offset)
+		}
+		return last + 3*ptrSize
+	}
+
+	minor, ok := goMinorVersion(strings.TrimPrefix(runtimeVersion, "go"))
+	if !ok {
+		minor = 8
+	}
+	var size64, size32 uintptr
+	switch {
+	case moduleData.layout == "1.2" && minor <= 6:
+		size64, size32 = unsafe.Sizeof(ModuleData12_r15_r16_64{}), unsafe.Sizeof(ModuleData12_r15_r16_32{})
+	case moduleData.layout == "1.2" && minor == 7:
+		size64, size32 = unsafe.Sizeof(ModuleData12_r17_64{}), unsafe.Sizeof(ModuleData12_r17_32{})
+	case moduleData.layout == "1.2":
+		size64, size32 = unsafe.Sizeof(ModuleData12_64{}), unsafe.Sizeof(ModuleData12_32{})
+	case moduleData.layout == "1.16":
+		size64, size32 = unsafe.Sizeof(ModuleData116_64{}), unsafe.Sizeof(ModuleData116_32{})
+	case moduleData.layout == "1.18":
+		size64, size32 = unsafe.Sizeof(ModuleData118_64{}), unsafe.Sizeof(ModuleData118_32{})
+	case moduleData.layout == "1.20":
+		size64, size32 = unsafe.Sizeof(ModuleData120_64{}), unsafe.Sizeof(ModuleData120_32{})
+	default:
+		size64, size32 = unsafe.Sizeof(ModuleData121_64{}), unsafe.Sizeof(ModuleData121_32{})
+	}
+	if is64bit {
+		return uint64(size64)
+	}
+	return uint64(size32)
+}
+
+// pclntabEnd is where the pclntab of moduleData ends, the end of its pclntable: the last of the tables, from the pcHeader on for
+// 1.2 and after the pcHeader, funcnametab, cutab, filetab and pctab from 1.16, or the epclntab of 1.27. 0 when it can't be read.
+func (e *Entry) pclntabEnd(moduleData *ModuleData, is64bit bool, littleendian bool) uint64 {
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+	var field uint64
+	switch {
+	case moduleData.LayoutSource == LayoutProbed && moduleData.ProbedOffsets["epclntab"] != 0:
+		end, err := e.raw.read_memory(moduleData.VA+moduleData.ProbedOffsets["epclntab"], ptrSize)
+		if err != nil || uint64(len(end)) < ptrSize {
+			return 0
+		}
+		return decodePtrSizeBytes(end, is64bit, littleendian)
+	case moduleData.LayoutSource == LayoutProbed:
+		ftab, ok := moduleData.ProbedOffsets["ftab"]
+		if !ok || ftab < 3*ptrSize {
+			return 0
+		}
+		field = ftab - 3*ptrSize
+	case moduleData.layout == "1.2":
+		field = 0
+	default:
+		field = 13 * ptrSize
+	}
+	slice, err := e.raw.read_memory(moduleData.VA+field, 2*ptrSize)
+	if err != nil || uint64(len(slice)) < 2*ptrSize {
+		return 0
+	}
+	return decodePtrSizeBytes(slice, is64bit, littleendian) + decodePtrSizeBytes(slice[ptrSize:], is64bit, littleendian)
+}
+
+// buildInfoBlob is the file offset and the size of the build info blob: its 32 byte header, and from Go 1.18 on the version and the
+// module info following it
+func (f *File) buildInfoBlob() (uint64, uint64, bool) {
+	const buildInfoSize = 32
+	idx, after := f.IndexFile(buildInfoMagic, 2)
+	if idx < 0 || len(after) < 2 {
+		return 0, 0, false
+	}
+	size := uint64(buildInfoSize)
+	if after[1]&2 != 0 {
+		// each prefixed by its length
+		for i := 0; i < 2; i++ {
+			prefix := make([]byte, binary.MaxVarintLen64)
+			read, _ := f.r.ReadAt(prefix, idx+int64(size))
+			length, n := binary.Uvarint(prefix[:read])
+			if n <= 0 {
+				break
+			}
+			size += uint64(n) + length
+		}
+	}
+	return uint64(idx), size, true
+}
+
+// RawStructures reads the raw bytes of the structures the extraction located: the moduledata, the pclntab at pclntabVA, the typelinks
+// and the build info blob. The end of the pclntab is its moduledata's, pclntabSize bytes without one. An empty moduleData has none,
+// ex: that of a pclntab given by VA, so its typelinks aren't either.
+func (f *File) RawStructures(moduleData *ModuleData, pclntabVA uint64, pclntabSize uint64, runtimeVersion string, is64bit bool, littleendian bool) []RawStructure {
+	e := f.entries[0]
+	located := func(name string, VA uint64, size uint64) RawStructure {
+		raw := RawStructure{Name: name, VA: VA, ClaimedSize: size, Size: size}
+		if size > maxRawStructureSize {
+			raw.Size, raw.Clamped = maxRawStructureSize, true
+		}
+		if offset, err := e.VAToFileOffset(VA); err == nil {
+			raw.FileOffset = &offset
+			raw.Section = e.fileRegion(offset)
+		}
+		// what's mapped of it, a dump or a truncated file may end before it does
+		for uint64(len(raw.Data)) < raw.Size {
+			chunk, err := e.raw.read_memory(VA+uint64(len(raw.Data)), raw.Size-uint64(len(raw.Data)))
+			if err != nil || len(chunk) == 0 {
+				break
+			}
+			raw.Data = append(raw.Data, chunk...)
+		}
+		raw.Size = uint64(len(raw.Data))
+		return raw
+	}
+
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+	var structures []RawStructure
+	if moduleData != nil && moduleData.VA != 0 {
+		structures = append(structures, located("moduledata", moduleData.VA, moduleDataSize(moduleData, runtimeVersion, is64bit)))
+		if end := e.pclntabEnd(moduleData, is64bit, littleendian); end > pclntabVA {
+			pclntabSize = end - pclntabVA
+		}
+	}
+	if pclntabVA != 0 && pclntabSize != 0 {
+		structures = append(structures, located("pclntab", pclntabVA, pclntabSize))
+	}
+	if moduleData != nil && moduleData.VA != 0 {
+		if moduleData.Typelinks.Data != 0 {
+			structures = append(structures, located("typelinks", uint64(moduleData.Typelinks.Data), moduleData.Typelinks.Len*4))
+		} else if moduleData.LegacyTypes.Data != 0 {
+			// before 1.7 the typelinks are the pointers to the types
+			structures = append(structures, located("typelinks", uint64(moduleData.LegacyTypes.Data), moduleData.LegacyTypes.Len*ptrSize))
+		}
+	}
+	if offset, size, ok := f.buildInfoBlob(); ok {
+		if VA, err := e.FileOffsetToVA(offset); err == nil {
+			structures = append(structures, located("buildinfo", VA, size))
+		}
+	}
+	return structures
+}
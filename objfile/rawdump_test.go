@@ -0,0 +1,45 @@
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestRawStructures(t *testing.T) {
+	// a 1.21 moduledata at 0x1000 of a dump whose pclntable, the last of the tables of the pclntab at 0x1800, ends at 0x1d00
+	memory := make([]byte, 0x1800)
+	binary.LittleEndian.PutUint64(memory[13*8:], 0x1900)
+	binary.LittleEndian.PutUint64(memory[14*8:], 0x400)
+	e := &Entry{raw: &dumpFile{format: "raw", base: 0x1000, size: uint64(len(memory)), regions: []dumpRegion{{name: "image", addr: 0x1000, data: memory}}}}
+	f := &File{r: bytes.NewReader(memory), entries: []*Entry{e}}
+
+	// a corrupt typelinks length claiming 4GB
+	moduleData := &ModuleData{VA: 0x1000, Typelinks: GoSlice64{Data: 0x1400, Len: 1 << 30}, LayoutSource: LayoutTable, layout: "1.21"}
+	structures := f.RawStructures(moduleData, 0x1800, 0x10, "1.22", true, true)
+	sizes := make(map[string]RawStructure)
+	for _, structure := range structures {
+		sizes[structure.Name] = structure
+		if uint64(len(structure.Data)) != structure.Size {
+			t.Errorf("expected the %d bytes of the %s, got %d", structure.Size, structure.Name, len(structure.Data))
+		}
+	}
+	if len(structures) != 3 {
+		t.Fatalf("expected the moduledata, pclntab and typelinks, got %+v", structures)
+	}
+	if got := sizes["moduledata"]; got.VA != 0x1000 || got.Size != 592 || got.Clamped {
+		t.Errorf("expected the 592 bytes of a 1.21 moduledata, got %+v", got)
+	}
+	if got := sizes["pclntab"]; got.VA != 0x1800 || got.Size != 0x500 {
+		t.Errorf("expected the pclntab to end with its pclntable, got %+v", got)
+	}
+	// clamped, then cut at the end of the dump
+	if got := sizes["typelinks"]; !got.Clamped || got.ClaimedSize != 4<<30 || got.Size != 0x1400 {
+		t.Errorf("expected the typelinks clamped, got %+v", got)
+	}
+
+	// without a moduledata the pclntab is the size given
+	if structures := f.RawStructures(&ModuleData{}, 0x1800, 0x10, "1.22", true, true); len(structures) != 1 || structures[0].Size != 0x10 {
+		t.Errorf("expected the pclntab alone, got %+v", structures)
+	}
+}
@@ -0,0 +1,170 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+	"github.com/mandiant/GoReSym/debug/pe"
+)
+
+// base relocation types of the fields holding a pointer, the others are padding or only patch part of an instruction
+const (
+	imageRelBasedHighLow = 3
+	imageRelBasedDir64   = 10
+)
+
+// at most this many relocated pointers are read to decide if the image was relocated
+const maxRelocationSamples = 1024
+
+// relocatedBase returns the base the pointers of an image are relative to: loadBase when more of the relocated pointers in values fall within
+// size bytes of it than of the preferred base, else preferred. Without values the relocations are unknown and loadBase is trusted.
+func relocatedBase(values []uint64, preferred uint64, size uint64, loadBase uint64) uint64 {
+	if loadBase == 0 || loadBase == preferred {
+		return preferred
+	}
+	if len(values) == 0 {
+		return loadBase
+	}
+
+	inPreferred := 0
+	inLoaded := 0
+	for _, value := range values {
+		if value >= preferred && value-preferred < size {
+			inPreferred++
+		}
+		if value >= loadBase && value-loadBase < size {
+			inLoaded++
+		}
+	}
+	if inLoaded > inPreferred {
+		return loadBase
+	}
+	return preferred
+}
+
+// parseBaseRelocations returns the RVAs of the pointers listed in a PE base relocation directory. Blocks are a page RVA and a block size
+// followed by 16 bit entries, the type in the high nibble and the page offset in the rest.
+func parseBaseRelocations(data []byte) []uint32 {
+	var rvas []uint32
+	for len(data) >= 8 {
+		pageRVA := binary.LittleEndian.Uint32(data)
+		blockSize := binary.LittleEndian.Uint32(data[4:])
+		if blockSize < 8 || uint64(blockSize) > uint64(len(data)) {
+			break
+		}
+
+		for entries := data[8:blockSize]; len(entries) >= 2; entries = entries[2:] {
+			entry := binary.LittleEndian.Uint16(entries)
+			switch entry >> 12 {
+			case imageRelBasedHighLow, imageRelBasedDir64:
+				rvas = append(rvas, pageRVA+uint32(entry&0xfff))
+			}
+		}
+		data = data[blockSize:]
+	}
+	return rvas
+}
+
+// rebase moves the image to the base its relocated pointers are relative to, by rewriting the ImageBase the rest of peFile reads
+func (f *peFile) rebase(loadBase uint64) {
+	var preferred, size, ptrSize uint64
+	var dir pe.DataDirectory
+	switch oh := f.pe.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		preferred, size, ptrSize = uint64(oh.ImageBase), uint64(oh.SizeOfImage), 4
+		if oh.NumberOfRvaAndSizes > pe.IMAGE_DIRECTORY_ENTRY_BASERELOC {
+			dir = oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_BASERELOC]
+		}
+	case *pe.OptionalHeader64:
+		preferred, size, ptrSize = oh.ImageBase, uint64(oh.SizeOfImage), 8
+		if oh.NumberOfRvaAndSizes > pe.IMAGE_DIRECTORY_ENTRY_BASERELOC {
+			dir = oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_BASERELOC]
+		}
+	default:
+		return
+	}
+	// without base relocations the loader can't move the image
+	if loadBase == 0 || loadBase == preferred || dir.Size == 0 {
+		return
+	}
+
+	var values []uint64
+	if data, err := f.read_memory(preferred+uint64(dir.VirtualAddress), uint64(dir.Size)); err == nil {
+		for _, rva := range parseBaseRelocations(data) {
+			if len(values) == maxRelocationSamples {
+				break
+			}
+			if field, err := f.read_memory(preferred+uint64(rva), ptrSize); err == nil && uint64(len(field)) == ptrSize {
+				if ptrSize == 4 {
+					values = append(values, uint64(binary.LittleEndian.Uint32(field)))
+				} else {
+					values = append(values, binary.LittleEndian.Uint64(field))
+				}
+			}
+		}
+	}
+
+	base := relocatedBase(values, preferred, size, loadBase)
+	switch oh := f.pe.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		oh.ImageBase = uint32(base)
+	case *pe.OptionalHeader64:
+		oh.ImageBase = base
+	}
+}
+
+// rebase moves the image to the base its relocated pointers are relative to, by shifting the segments and sections. Only the 32 bit
+// SHT_REL relocations of x86 are read, the RELA ones keep their addend out of the relocated field.
+func (f *elfFile) rebase(loadBase uint64) {
+	preferred, end, ok := loadExtent(f.elf.Progs)
+	// only shared objects and pies get relocated
+	if !ok || loadBase == 0 || loadBase == preferred || f.elf.Type != elf.ET_DYN {
+		return
+	}
+
+	var values []uint64
+	if f.elf.Class == elf.ELFCLASS32 {
+	sections:
+		for _, sec := range f.elf.Sections {
+			if sec.Type != elf.SHT_REL {
+				continue
+			}
+			data, err := sec.Data()
+			if err != nil {
+				continue
+			}
+			for ; len(data) >= 8; data = data[8:] {
+				if len(values) == maxRelocationSamples {
+					break sections
+				}
+				switch elf.R_386(f.elf.ByteOrder.Uint32(data[4:]) & 0xff) {
+				case elf.R_386_32, elf.R_386_RELATIVE:
+					if field, err := f.read_memory(uint64(f.elf.ByteOrder.Uint32(data)), 4); err == nil && len(field) == 4 {
+						values = append(values, uint64(f.elf.ByteOrder.Uint32(field)))
+					}
+				}
+			}
+		}
+	}
+
+	f.slide(relocatedBase(values, preferred, end-preferred, loadBase) - preferred)
+}
+
+// slide moves the segments and sections by delta, the slide of the image
+func (f *elfFile) slide(delta uint64) {
+	if delta == 0 {
+		return
+	}
+	f.rebaseDelta = delta
+	for _, p := range f.elf.Progs {
+		if p.Type == elf.PT_LOAD {
+			p.Vaddr += delta
+		}
+	}
+	for _, sec := range f.elf.Sections {
+		if sec.Flags&elf.SHF_ALLOC != 0 {
+			sec.Addr += delta
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+)
+
+func TestParseBaseRelocations(t *testing.T) {
+	var data []byte
+	block := func(pageRVA uint32, entries ...uint16) {
+		header := make([]byte, 8)
+		binary.LittleEndian.PutUint32(header, pageRVA)
+		binary.LittleEndian.PutUint32(header[4:], uint32(8+2*len(entries)))
+		data = append(data, header...)
+		for _, entry := range entries {
+			data = binary.LittleEndian.AppendUint16(data, entry)
+		}
+	}
+	block(0x1000, 0x3010, 0x3ffc, 0x0000) // two HIGHLOW, padding
+	block(0x5000, 0xa008, 0x2004)         // a DIR64, a LOW that only patches half a pointer
+	data = append(data, 0xff, 0xff, 0xff) // trailing garbage
+
+	expected := []uint32{0x1010, 0x1ffc, 0x5008}
+	rvas := parseBaseRelocations(data)
+	if len(rvas) != len(expected) {
+		t.Fatalf("expected %x, got %x", expected, rvas)
+	}
+	for i := range expected {
+		if rvas[i] != expected[i] {
+			t.Errorf("relocation %d: expected 0x%x, got 0x%x", i, expected[i], rvas[i])
+		}
+	}
+
+	// a block claiming more than there is stops the walk
+	binary.LittleEndian.PutUint32(data[4:], 0x1000)
+	if rvas := parseBaseRelocations(data); len(rvas) != 0 {
+		t.Errorf("expected nothing from a truncated block, got %x", rvas)
+	}
+}
+
+func TestRelocatedBase(t *testing.T) {
+	const preferred, size = 0x400000, 0x100000
+	unrelocated := []uint64{0x401000, 0x4a0000, 0x4ff000, 0x12345678}
+	relocated := []uint64{0x10001000, 0x100a0000, 0x100ff000, 0x12345678}
+
+	cases := []struct {
+		name     string
+		values   []uint64
+		loadBase uint64
+		expected uint64
+	}{
+		{"no load base", relocated, 0, preferred},
+		{"load base is the preferred one", relocated, preferred, preferred},
+		{"relocated dump", relocated, 0x10000000, 0x10000000},
+		{"file that wasn't relocated", unrelocated, 0x10000000, preferred},
+		{"wrong load base", relocated, 0x20000000, preferred},
+		{"unreadable relocations", nil, 0x10000000, 0x10000000},
+	}
+	for _, c := range cases {
+		if base := relocatedBase(c.values, preferred, size, c.loadBase); base != c.expected {
+			t.Errorf("%s: expected 0x%x, got 0x%x", c.name, c.expected, base)
+		}
+	}
+}
+
+func TestDetectSlide(t *testing.T) {
+	// a PIE linked at 0: its dynamic section at 0x1000 and four RELATIVE relocations at 0x2000 of the fields at 0x3000
+	const slide = 0x7f0000000000
+	image := func(relocated bool, relocatedDynamic bool) ([]byte, *elf.File) {
+		memory := make([]byte, 0x4000)
+		rela := uint64(0x2000)
+		if relocatedDynamic {
+			rela += slide
+		}
+		binary.LittleEndian.PutUint64(memory[0x1000:], uint64(elf.DT_RELA))
+		binary.LittleEndian.PutUint64(memory[0x1008:], rela)
+		binary.LittleEndian.PutUint64(memory[0x1010:], uint64(elf.DT_RELASZ))
+		binary.LittleEndian.PutUint64(memory[0x1018:], 4*24)
+		for i := uint64(0); i < 4; i++ {
+			entry := memory[0x2000+24*i:]
+			binary.LittleEndian.PutUint64(entry, 0x3000+8*i)
+			binary.LittleEndian.PutUint64(entry[8:], uint64(elf.R_X86_64_RELATIVE))
+			binary.LittleEndian.PutUint64(entry[16:], 0x100*i)
+			field := 0x100 * i
+			if relocated {
+				field += slide
+			}
+			binary.LittleEndian.PutUint64(memory[0x3000+8*i:], field)
+		}
+		f := &elf.File{FileHeader: elf.FileHeader{Class: elf.ELFCLASS64, ByteOrder: binary.LittleEndian, Type: elf.ET_DYN, Machine: elf.EM_X86_64}}
+		f.Progs = []*elf.Prog{
+			{ProgHeader: elf.ProgHeader{Type: elf.PT_LOAD, Vaddr: 0, Memsz: 0x4000, Align: 0x1000}},
+			{ProgHeader: elf.ProgHeader{Type: elf.PT_DYNAMIC, Vaddr: 0x1000, Memsz: 0x20}},
+		}
+		return memory, f
+	}
+	reader := func(memory []byte) func(VA uint64, size uint64) ([]byte, error) {
+		return func(VA uint64, size uint64) ([]byte, error) {
+			if VA >= uint64(len(memory)) {
+				return nil, errors.New("unmapped")
+			}
+			return memory[VA:min(VA+size, uint64(len(memory)))], nil
+		}
+	}
+
+	for _, c := range []struct {
+		name             string
+		relocated        bool
+		relocatedDynamic bool
+		expected         uint64
+	}{
+		{"on disk", false, false, 0},
+		{"prelinked", true, false, slide},
+		{"mapped", true, true, slide},
+	} {
+		memory, f := image(c.relocated, c.relocatedDynamic)
+		if delta, ok := detectSlide(reader(memory), f); !ok || delta != c.expected {
+			t.Errorf("%s: expected a slide of 0x%x, got 0x%x %t", c.name, c.expected, delta, ok)
+		}
+	}
+
+	// fields that don't agree, ex: zeroed by the linker, and executables don't decide
+	memory, f := image(false, false)
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint64(memory[0x3000+8*i:], 0)
+	}
+	if delta, ok := detectSlide(reader(memory), f); ok {
+		t.Errorf("expected fields away from their addends to decide nothing, got 0x%x", delta)
+	}
+	memory, f = image(true, false)
+	f.Type = elf.ET_EXEC
+	if _, ok := detectSlide(reader(memory), f); ok {
+		t.Errorf("expected an executable to have no slide")
+	}
+}
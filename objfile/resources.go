@@ -0,0 +1,117 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/mandiant/GoReSym/debug/pe"
+)
+
+const (
+	// the type, name and language levels of the resource tree, deeper is a loop
+	maxResourceDepth = 3
+	// the most resources listed, a directory with more is garbage
+	maxResources = 0x10000
+)
+
+// the names of the predefined resource types, the first level of the tree
+var resourceTypes = map[uint32]string{
+	1: "CURSOR", 2: "BITMAP", 3: "ICON", 4: "MENU", 5: "DIALOG", 6: "STRING", 7: "FONTDIR", 8: "FONT", 9: "ACCELERATOR", 10: "RCDATA",
+	11: "MESSAGETABLE", 12: "GROUP_CURSOR", 14: "GROUP_ICON", 16: "VERSION", 23: "HTML", 24: "MANIFEST",
+}
+
+// peResource is the data of a leaf of the resource tree, at its path of type, name and language, ex: RCDATA/101/1033
+type peResource struct {
+	path string
+	rva  uint32
+	size uint32
+}
+
+// parsePEResources walks the resource directory dir, read reads size bytes at an RVA. Every IMAGE_RESOURCE_DIRECTORY is followed by its
+// named then its numbered entries, each pointing at a subdirectory when the high bit of its offset is set and else at the
+// IMAGE_RESOURCE_DATA_ENTRY giving the RVA and size of the data. The offsets are from the start of the directory.
+func parsePEResources(read func(rva uint32, size uint32) ([]byte, error), dir pe.DataDirectory) ([]peResource, error) {
+	var resources []peResource
+	var walk func(offset uint32, path []string) error
+	walk = func(offset uint32, path []string) error {
+		header, err := read(dir.VirtualAddress+offset, 16)
+		if err != nil || len(header) < 16 {
+			return fmt.Errorf("the resource directory at 0x%x doesn't read", dir.VirtualAddress+offset)
+		}
+		count := uint32(binary.LittleEndian.Uint16(header[12:])) + uint32(binary.LittleEndian.Uint16(header[14:]))
+		entries, err := read(dir.VirtualAddress+offset+16, count*8)
+		if err != nil || uint32(len(entries)) < count*8 {
+			return fmt.Errorf("the %d entries of the resource directory at 0x%x don't read", count, dir.VirtualAddress+offset)
+		}
+		for ; len(entries) >= 8 && len(resources) < maxResources; entries = entries[8:] {
+			id, target := binary.LittleEndian.Uint32(entries), binary.LittleEndian.Uint32(entries[4:])
+			name := resourceName(read, dir, id, len(path))
+			if target&0x80000000 != 0 {
+				if len(path)+1 < maxResourceDepth {
+					if err := walk(target&0x7fffffff, append(path, name)); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			data, err := read(dir.VirtualAddress+target, 16)
+			if err != nil || len(data) < 16 {
+				continue
+			}
+			resources = append(resources, peResource{strings.Join(append(path, name), "/"), binary.LittleEndian.Uint32(data), binary.LittleEndian.Uint32(data[4:])})
+		}
+		return nil
+	}
+	if err := walk(0, nil); err != nil {
+		return resources, err
+	}
+	return resources, nil
+}
+
+// resourceName is the name of a resource directory entry at the depth of the tree: its UTF-16 string when the high bit of id is set,
+// else its number, named for the predefined types
+func resourceName(read func(rva uint32, size uint32) ([]byte, error), dir pe.DataDirectory, id uint32, depth int) string {
+	if id&0x80000000 == 0 {
+		if name, ok := resourceTypes[id]; ok && depth == 0 {
+			return name
+		}
+		return fmt.Sprint(id)
+	}
+	offset := dir.VirtualAddress + id&0x7fffffff
+	length, err := read(offset, 2)
+	if err != nil || len(length) < 2 {
+		return "?"
+	}
+	raw, err := read(offset+2, 2*uint32(binary.LittleEndian.Uint16(length)))
+	if err != nil {
+		return "?"
+	}
+	name := make([]uint16, len(raw)/2)
+	for i := range name {
+		name[i] = binary.LittleEndian.Uint16(raw[2*i:])
+	}
+	return string(utf16.Decode(name))
+}
+
+// resources lists the resources of the resource directory of the image, nil without one
+func (f *peFile) resources() []peResource {
+	imageBase, _ := f.loadAddress()
+	var dirs []pe.DataDirectory
+	switch oh := f.pe.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+	case *pe.OptionalHeader64:
+		dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, 16)]
+	}
+	if len(dirs) <= pe.IMAGE_DIRECTORY_ENTRY_RESOURCE || dirs[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE].Size == 0 {
+		return nil
+	}
+	read := func(rva uint32, size uint32) ([]byte, error) {
+		return f.read_memory(imageBase+uint64(rva), uint64(size))
+	}
+	resources, _ := parsePEResources(read, dirs[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE])
+	return resources
+}
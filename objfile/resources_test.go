@@ -0,0 +1,55 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/mandiant/GoReSym/debug/pe"
+)
+
+func TestParsePEResources(t *testing.T) {
+	// the directory at rva 0x1000: RCDATA holds the resource named PAYLOAD in language 1033, and one numbered 7 with no language level
+	image := make([]byte, 0x1200)
+	entry := func(off int, id uint32, target uint32) {
+		binary.LittleEndian.PutUint32(image[off:], id)
+		binary.LittleEndian.PutUint32(image[off+4:], target)
+	}
+	binary.LittleEndian.PutUint16(image[0x1000+14:], 1) // one numbered entry
+	entry(0x1010, 10, 0x80000020)
+	binary.LittleEndian.PutUint16(image[0x1020+12:], 1) // one named entry
+	binary.LittleEndian.PutUint16(image[0x1020+14:], 1) // one numbered entry
+	entry(0x1030, 0x80000100, 0x80000040)
+	entry(0x1038, 7, 0x80)
+	binary.LittleEndian.PutUint16(image[0x1040+14:], 1)
+	entry(0x1050, 1033, 0x90)
+	binary.LittleEndian.PutUint32(image[0x1080:], 0x2000)
+	binary.LittleEndian.PutUint32(image[0x1084:], 0x10)
+	binary.LittleEndian.PutUint32(image[0x1090:], 0x3000)
+	binary.LittleEndian.PutUint32(image[0x1094:], 0x400)
+	binary.LittleEndian.PutUint16(image[0x1100:], 7)
+	for i, c := range "PAYLOAD" {
+		binary.LittleEndian.PutUint16(image[0x1102+2*i:], uint16(c))
+	}
+
+	read := func(rva uint32, size uint32) ([]byte, error) {
+		if uint64(rva) >= uint64(len(image)) {
+			return nil, fmt.Errorf("rva 0x%x outside the image", rva)
+		}
+		return image[rva:min(uint64(rva)+uint64(size), uint64(len(image)))], nil
+	}
+	resources, err := parsePEResources(read, pe.DataDirectory{VirtualAddress: 0x1000, Size: 0x200})
+	if err != nil {
+		t.Fatalf("parse failed: %s", err)
+	}
+	expected := []peResource{{"RCDATA/PAYLOAD/1033", 0x3000, 0x400}, {"RCDATA/7", 0x2000, 0x10}}
+	if fmt.Sprint(resources) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, resources)
+	}
+
+	// a subdirectory pointing back at the root doesn't loop
+	entry(0x1050, 1033, 0x80000000)
+	if resources, _ := parsePEResources(read, pe.DataDirectory{VirtualAddress: 0x1000, Size: 0x200}); len(resources) != 1 {
+		t.Errorf("expected the loop to stop at the language level, got %v", resources)
+	}
+}
@@ -0,0 +1,316 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// RuntimeGlobal is a runtime global that walking the goroutines and threads of a memory image starts from, ex: runtime.allgs
+type RuntimeGlobal struct {
+	Name     string
+	VA       uint64
+	Method   string   // 'symtab', 'dwarf' or 'references'
+	Evidence []string // with references, the runtime functions whose code located it
+}
+
+// every global reported, in output order
+var runtimeGlobalNames = []string{"runtime.allgs", "runtime.allglen", "runtime.allgptr", "runtime.allm", "runtime.sched", "runtime.g0", "runtime.m0"}
+
+// the default of schedt.maxmcount schedinit stores
+const schedMaxMCount = 10000
+
+// the offset of schedt.lastpoll, after goidgen in every version
+const schedLastpollOffset = 8
+
+// the offset of schedt.maxmcount on 64bit for a range of minor versions, inclusive. mnext replaced mcount in 1.10 and pollUntil came
+// with 1.14, the versions in between the ones below weren't checked so the sched isn't located by references for them.
+var schedMaxMCountOffsets = []struct {
+	minMinor int
+	maxMinor int
+	offset   uint64
+}{
+	{8, 9, 44},
+	{10, 13, 48},
+	{14, 22, 56},
+	{27, 27, 80},
+}
+
+// globalAccess is an instruction reading, writing or taking the address of a global through a rip relative operand
+type globalAccess struct {
+	addr  uint64
+	op    x86asm.Op
+	store bool  // the global is the destination
+	imm   int64 // the immediate stored, when store has one
+	size  int   // the bytes accessed
+}
+
+func (a globalAccess) load() bool {
+	return a.op == x86asm.MOV && !a.store
+}
+
+// globalAccesses decodes fn and lists its accesses of globals at minGlobal or above
+func (e *Entry) globalAccesses(fn *gosym.Func, minGlobal uint64) []globalAccess {
+	if fn == nil || fn.End <= fn.Entry {
+		return nil
+	}
+	code, err := e.raw.read_memory(fn.Entry, fn.End-fn.Entry)
+	if err != nil {
+		return nil
+	}
+
+	var accesses []globalAccess
+	for off := 0; off < len(code); {
+		pc := fn.Entry + uint64(off)
+		inst, err := x86asm.Decode(code[off:], 64)
+		if err != nil || inst.Len == 0 {
+			off++
+			continue
+		}
+		off += inst.Len
+
+		for argIdx, arg := range inst.Args {
+			mem, ok := arg.(x86asm.Mem)
+			if !ok || mem.Base != x86asm.RIP || mem.Index != 0 {
+				continue
+			}
+			addr := uint64(int64(pc) + int64(inst.Len) + mem.Disp)
+			if addr < minGlobal {
+				continue
+			}
+			access := globalAccess{addr: addr, op: inst.Op, store: argIdx == 0 && inst.Op == x86asm.MOV, size: inst.MemBytes}
+			if imm, isImm := inst.Args[1].(x86asm.Imm); access.store && isImm {
+				access.imm = int64(imm)
+			}
+			accesses = append(accesses, access)
+		}
+	}
+	return accesses
+}
+
+// g0AndM0 finds the stores of runtime.rt0_go linking g0 and m0, m0.g0 = &g0 then g0.m = &m0, both taken by a lea of the global
+func (e *Entry) g0AndM0(fn *gosym.Func) (g0 uint64, m0 uint64, ok bool) {
+	if fn == nil || fn.End <= fn.Entry {
+		return 0, 0, false
+	}
+	code, err := e.raw.read_memory(fn.Entry, fn.End-fn.Entry)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	leas := make(map[x86asm.Reg]uint64)
+	var linked [2]uint64 // the m and g of the m.g0 store
+	for off := 0; off < len(code); {
+		pc := fn.Entry + uint64(off)
+		inst, err := x86asm.Decode(code[off:], 64)
+		if err != nil || inst.Len == 0 {
+			off++
+			continue
+		}
+		off += inst.Len
+
+		dst, isReg := inst.Args[0].(x86asm.Reg)
+		if mem, isMem := inst.Args[1].(x86asm.Mem); isReg && inst.Op == x86asm.LEA && isMem && mem.Base == x86asm.RIP && mem.Index == 0 {
+			leas[dst] = uint64(int64(pc) + int64(inst.Len) + mem.Disp)
+			continue
+		}
+		if isReg {
+			delete(leas, dst)
+			continue
+		}
+
+		mem, isMem := inst.Args[0].(x86asm.Mem)
+		src, srcReg := inst.Args[1].(x86asm.Reg)
+		if inst.Op != x86asm.MOV || !isMem || !srcReg || mem.Index != 0 {
+			continue
+		}
+		base, baseKnown := leas[mem.Base]
+		value, valueKnown := leas[src]
+		if !baseKnown || !valueKnown {
+			continue
+		}
+		switch {
+		case mem.Disp == 0:
+			linked = [2]uint64{base, value}
+		case linked[0] == value && linked[1] == base && value != base:
+			return base, value, true
+		}
+	}
+	return 0, 0, false
+}
+
+// referencedRuntimeGlobals locates the runtime globals of an amd64 binary by how the runtime functions of funcs access them. Only
+// accesses fitting one global alone count, a global is left out rather than guessed when they don't. minor is the minor Go version,
+// 0 when unknown, and minGlobal the end of the initialized data the globals follow.
+func (e *Entry) referencedRuntimeGlobals(funcs []gosym.Func, minor int, minGlobal uint64) map[string]RuntimeGlobal {
+	// an ABI wrapper shares the name of the function it calls, ex: runtime.schedinit called from rt0_go, the function is the larger
+	byName := make(map[string]*gosym.Func)
+	for i := range funcs {
+		if known := byName[funcs[i].Name]; known == nil || funcs[i].End-funcs[i].Entry > known.End-known.Entry {
+			byName[funcs[i].Name] = &funcs[i]
+		}
+	}
+	found := make(map[string]RuntimeGlobal)
+	add := func(name string, VA uint64, evidence ...string) {
+		found[name] = RuntimeGlobal{Name: name, VA: VA, Method: "references", Evidence: evidence}
+	}
+
+	// allgs = append(allgs, gp) stores the three words of the slice, the walks of the goroutines load it
+	allgadd := e.globalAccesses(byName["runtime.allgadd"], minGlobal)
+	stores := make(map[uint64]bool)
+	for _, access := range allgadd {
+		if access.store && access.size == 8 {
+			stores[access.addr] = true
+		}
+	}
+	walked := make(map[uint64]string)
+	for _, name := range []string{"runtime.forEachG", "runtime.checkdead", "runtime.schedtrace"} {
+		for _, access := range e.globalAccesses(byName[name], minGlobal) {
+			if _, ok := walked[access.addr]; access.load() && !ok {
+				walked[access.addr] = name
+			}
+		}
+	}
+	slices := make(map[uint64]bool)
+	var allgs []uint64
+	for addr := range stores {
+		if stores[addr+8] && stores[addr+16] {
+			slices[addr], slices[addr+8], slices[addr+16] = true, true, true
+			if _, ok := walked[addr]; ok {
+				allgs = append(allgs, addr)
+			}
+		}
+	}
+	if len(allgs) == 1 {
+		add("runtime.allgs", allgs[0], "runtime.allgadd", walked[allgs[0]])
+	}
+
+	// forEachGRace loads the length then the pointer, which allgadd publishes. Before it allgadd stores the length alone.
+	if race := byName["runtime.forEachGRace"]; race != nil {
+		var loads []uint64
+		for _, access := range e.globalAccesses(race, minGlobal) {
+			if access.load() && (len(loads) == 0 || loads[len(loads)-1] != access.addr) {
+				loads = append(loads, access.addr)
+			}
+		}
+		inAllgadd := func(addr uint64) bool {
+			for _, access := range allgadd {
+				if access.addr == addr {
+					return true
+				}
+			}
+			return false
+		}
+		if len(loads) == 2 && loads[0] != loads[1] && inAllgadd(loads[0]) && inAllgadd(loads[1]) {
+			add("runtime.allglen", loads[0], "runtime.forEachGRace", "runtime.allgadd")
+			add("runtime.allgptr", loads[1], "runtime.forEachGRace", "runtime.allgadd")
+		}
+	} else if len(allgs) == 1 {
+		var others []uint64
+		for addr := range stores {
+			if !slices[addr] {
+				others = append(others, addr)
+			}
+		}
+		if len(others) == 1 {
+			add("runtime.allglen", others[0], "runtime.allgadd")
+		}
+	}
+
+	// mcommoninit links the m in, mp.alllink = allm then atomicstorep(&allm, mp), and schedtrace walks it
+	if trace := byName["runtime.schedtrace"]; trace != nil {
+		loaded, taken := make(map[uint64]bool), make(map[uint64]bool)
+		for _, access := range e.globalAccesses(byName["runtime.mcommoninit"], minGlobal) {
+			loaded[access.addr] = loaded[access.addr] || access.load()
+			taken[access.addr] = taken[access.addr] || access.op == x86asm.LEA
+		}
+		linked := make(map[uint64]bool)
+		for _, access := range e.globalAccesses(trace, minGlobal) {
+			if access.load() && loaded[access.addr] && taken[access.addr] {
+				linked[access.addr] = true
+			}
+		}
+		if len(linked) == 1 {
+			for addr := range linked {
+				add("runtime.allm", addr, "runtime.mcommoninit", "runtime.schedtrace")
+			}
+		}
+	}
+
+	// schedinit sets sched.maxmcount = 10000 and sched.lastpoll, the first tells the sched from the version and the second confirms it
+	for _, layout := range schedMaxMCountOffsets {
+		if minor < layout.minMinor || minor > layout.maxMinor {
+			continue
+		}
+		accesses := e.globalAccesses(byName["runtime.schedinit"], minGlobal)
+		var scheds []uint64
+		for _, access := range accesses {
+			if access.store && access.size == 4 && access.imm == schedMaxMCount && access.addr >= layout.offset {
+				scheds = append(scheds, access.addr-layout.offset)
+			}
+		}
+		for _, access := range accesses {
+			if len(scheds) == 1 && access.addr == scheds[0]+schedLastpollOffset {
+				add("runtime.sched", scheds[0], "runtime.schedinit")
+				break
+			}
+		}
+		break
+	}
+
+	rt0 := byName["runtime.rt0_go"]
+	if rt0 == nil {
+		rt0 = byName["runtime.rt0_go.abi0"]
+	}
+	if g0, m0, ok := e.g0AndM0(rt0); ok && g0 >= minGlobal && m0 >= minGlobal {
+		add("runtime.g0", g0, "runtime.rt0_go")
+		add("runtime.m0", m0, "runtime.rt0_go")
+	}
+	return found
+}
+
+// RuntimeGlobals locates runtime.allgs, allglen, allgptr, allm, sched, g0 and m0. The symbol table and the DWARF are used when the
+// binary has them, else the accesses of the runtime functions of funcs, on amd64 only. A global no method is confident about is left
+// out, a wrong one would send a walk of the goroutines astray. moduleData bounds the accesses to the globals after the data.
+func (e *Entry) RuntimeGlobals(funcs []gosym.Func, goVersion string, moduleData *ModuleData, is64bit bool, littleendian bool) []RuntimeGlobal {
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	if !littleendian {
+		byteOrder = binary.BigEndian
+	}
+	syms, _ := e.raw.symbols()
+	data, err := e.raw.dwarf()
+	if err != nil {
+		data = nil
+	}
+
+	var referenced map[string]RuntimeGlobal
+	var globals []RuntimeGlobal
+	for _, name := range runtimeGlobalNames {
+		if VA, ok := locateGlobal(syms, nil, name, byteOrder, is64bit); ok {
+			globals = append(globals, RuntimeGlobal{Name: name, VA: VA, Method: "symtab"})
+			continue
+		}
+		if VA, ok := locateGlobal(nil, data, name, byteOrder, is64bit); ok {
+			globals = append(globals, RuntimeGlobal{Name: name, VA: VA, Method: "dwarf"})
+			continue
+		}
+		if e.GOARCH() != "amd64" {
+			continue
+		}
+		if referenced == nil {
+			minor, _ := goMinorVersion(goVersion)
+			var minGlobal uint64
+			if moduleData != nil {
+				minGlobal = moduleData.Edata
+			}
+			referenced = e.referencedRuntimeGlobals(funcs, minor, minGlobal)
+		}
+		if global, ok := referenced[name]; ok {
+			globals = append(globals, global)
+		}
+	}
+	return globals
+}
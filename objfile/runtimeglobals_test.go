@@ -0,0 +1,86 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+)
+
+// ripInst appends to code, laid out from base, an instruction of prefix, a rip relative disp32 reaching target and suffix
+func ripInst(code []byte, base uint64, target uint64, prefix []byte, suffix ...byte) []byte {
+	end := base + uint64(len(code)+len(prefix)+4+len(suffix))
+	code = append(code, prefix...)
+	code = binary.LittleEndian.AppendUint32(code, uint32(int32(int64(target)-int64(end))))
+	return append(code, suffix...)
+}
+
+func TestRuntimeGlobals(t *testing.T) {
+	const text = 0x401000
+	const allgs, work, allm, allglen, sched, g0, m0 = 0x500100, 0x500200, 0x500300, 0x500400, 0x500800, 0x500a00, 0x500c00
+	var (
+		movStoreRAX = []byte{0x48, 0x89, 0x05}
+		movStoreRCX = []byte{0x48, 0x89, 0x0d}
+		movStoreRDX = []byte{0x48, 0x89, 0x15}
+		movLoadRAX  = []byte{0x48, 0x8b, 0x05}
+		leaRAX      = []byte{0x48, 0x8d, 0x05}
+		leaRCX      = []byte{0x48, 0x8d, 0x0d}
+	)
+
+	// runtime functions of 0x100 bytes each, in the order of names
+	names := []string{"runtime.allgadd", "runtime.checkdead", "runtime.schedinit", "runtime.mcommoninit", "runtime.schedtrace", "runtime.rt0_go"}
+	bodies := make([][]byte, len(names))
+	at := func(i int) uint64 { return text + uint64(i)*0x100 }
+	// allgs and another slice appended to, then allglen
+	for _, slice := range []uint64{allgs, work} {
+		bodies[0] = ripInst(bodies[0], at(0), slice, movStoreRAX)
+		bodies[0] = ripInst(bodies[0], at(0), slice+8, movStoreRCX)
+		bodies[0] = ripInst(bodies[0], at(0), slice+16, movStoreRDX)
+	}
+	bodies[0] = ripInst(bodies[0], at(0), allglen, movStoreRAX)
+	bodies[1] = ripInst(bodies[1], at(1), allgs, movLoadRAX)
+	// movl $10000, sched.maxmcount of 1.22 then lea sched.lastpoll
+	bodies[2] = ripInst(bodies[2], at(2), sched+56, []byte{0xc7, 0x05}, 0x10, 0x27, 0, 0)
+	bodies[2] = ripInst(bodies[2], at(2), sched+8, leaRCX)
+	bodies[3] = ripInst(bodies[3], at(3), allm, movLoadRAX)
+	bodies[3] = ripInst(bodies[3], at(3), allm, leaRCX)
+	bodies[4] = ripInst(bodies[4], at(4), allm, movLoadRAX)
+	bodies[4] = ripInst(bodies[4], at(4), sched+48, movLoadRAX)
+	// m0.g0 = &g0 then g0.m = &m0
+	bodies[5] = ripInst(bodies[5], at(5), g0, leaRCX)
+	bodies[5] = ripInst(bodies[5], at(5), m0, leaRAX)
+	bodies[5] = append(bodies[5], 0x48, 0x89, 0x08, 0x48, 0x89, 0x41, 0x30)
+
+	memory := make([]byte, len(names)*0x100)
+	var funcs []gosym.Func
+	for i, body := range bodies {
+		copy(memory[i*0x100:], body)
+		funcs = append(funcs, gosym.Func{Entry: at(i), End: at(i) + uint64(len(body)), Sym: &gosym.Sym{Name: names[i]}})
+	}
+	e := &Entry{raw: &dumpFile{format: "raw", arch: "amd64", byteOrder: binary.LittleEndian, base: text, size: uint64(len(memory)), regions: []dumpRegion{{name: "text", addr: text, data: memory, executable: true}}}}
+
+	expected := map[string]uint64{"runtime.allgs": allgs, "runtime.allglen": allglen, "runtime.allm": allm, "runtime.sched": sched, "runtime.g0": g0, "runtime.m0": m0}
+	globals := e.RuntimeGlobals(funcs, "1.22.3", nil, true, true)
+	if len(globals) != len(expected) {
+		t.Fatalf("expected %d globals, got %+v", len(expected), globals)
+	}
+	for _, global := range globals {
+		if global.VA != expected[global.Name] || global.Method != "references" || len(global.Evidence) == 0 {
+			t.Errorf("expected %s at 0x%x by references, got %+v", global.Name, expected[global.Name], global)
+		}
+	}
+	if globals[0].Name != "runtime.allgs" || len(globals[0].Evidence) != 2 || globals[0].Evidence[1] != "runtime.checkdead" {
+		t.Errorf("expected allgs confirmed by checkdead, got %+v", globals[0])
+	}
+
+	// the offset of maxmcount isn't known for 1.24, nor is the sched. Without a walk the two slices of allgadd are alike.
+	globals = e.RuntimeGlobals(append(funcs[:1:1], funcs[2:]...), "1.24.1", nil, true, true)
+	for _, global := range globals {
+		if global.Name == "runtime.sched" || global.Name == "runtime.allgs" || global.Name == "runtime.allglen" {
+			t.Errorf("expected %s left out, got %+v", global.Name, global)
+		}
+	}
+	if len(globals) != 3 {
+		t.Errorf("expected allm, g0 and m0, got %+v", globals)
+	}
+}
@@ -0,0 +1,196 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mandiant/GoReSym/debug/dwarf"
+)
+
+// RuntimeOffset is the byte offset of a field of runtime.g or runtime.m, for tooling that walks goroutines in memory
+type RuntimeOffset struct {
+	Field    string // ex: 'g.goid', nested fields are dotted 'g.stack.hi'
+	Offset   uint64
+	Source   string // 'table' or 'dwarf'
+	Verified bool   // DWARF is present and agrees with the table
+	Mismatch bool   // DWARF is present and disagrees with the table, Offset is the DWARF value
+}
+
+// every field reported, in output order. Those not in the table are only known from DWARF.
+var runtimeOffsetFields = []string{"g.stack.lo", "g.stack.hi", "g.stackguard0", "g.stackguard1", "g._panic", "g._defer", "g.m", "g.sched", "g.goid", "m.g0", "m.curg", "m.procid", "m.p"}
+
+// runtimeOffsetLayout gives offsets in bytes for a range of minor versions, inclusive. Fields are stored per pointer size.
+type runtimeOffsetLayout struct {
+	minMinor int
+	maxMinor int
+	offsets  map[string][2]uint64 // field -> {32bit, 64bit}
+}
+
+// src/runtime/runtime2.go. Only the parts of the structs that don't depend on GOOS are tabled, ex: m.curg moves with the size of sigset.
+var runtimeOffsetLayouts = []runtimeOffsetLayout{
+	// the head of g is fixed, the stack guard offsets are hardcoded in the compiler
+	{5, 1 << 16, map[string][2]uint64{
+		"g.stack.lo":    {0x0, 0x0},
+		"g.stack.hi":    {0x4, 0x8},
+		"g.stackguard0": {0x8, 0x10},
+		"g.stackguard1": {0xc, 0x18},
+		"g._panic":      {0x10, 0x20},
+		"g._defer":      {0x14, 0x28},
+		"g.m":           {0x18, 0x30},
+		"m.g0":          {0x0, 0x0},
+	}},
+	// stackAlloc gone, gobuf is sp, pc, g, ctxt, ret, lr, bp
+	{10, 22, map[string][2]uint64{
+		"g.sched": {0x1c, 0x38},
+		"g.goid":  {0x50, 0x98},
+	}},
+	// gobuf.ret removed and g.syscallbp added, which cancel out for goid
+	{27, 27, map[string][2]uint64{
+		"g.sched": {0x1c, 0x38},
+		"g.goid":  {0x50, 0x98},
+	}},
+}
+
+// goMinorVersion parses '1.22.3' -> 22
+func goMinorVersion(goVersion string) (int, bool) {
+	parts := strings.Split(goVersion, ".")
+	if len(parts) < 2 || parts[0] != "1" {
+		return 0, false
+	}
+
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, false
+	}
+	return minor, true
+}
+
+// tableRuntimeOffsets returns the known offsets for the version, an unknown version gets an empty map
+func tableRuntimeOffsets(goVersion string, is64bit bool) map[string]uint64 {
+	offsets := make(map[string]uint64)
+	minor, ok := goMinorVersion(goVersion)
+	if !ok {
+		return offsets
+	}
+
+	ptrIdx := 0
+	if is64bit {
+		ptrIdx = 1
+	}
+
+	for _, layout := range runtimeOffsetLayouts {
+		if minor < layout.minMinor || minor > layout.maxMinor {
+			continue
+		}
+		for field, offset := range layout.offsets {
+			offsets[field] = offset[ptrIdx]
+		}
+	}
+	return offsets
+}
+
+// structFieldOffset resolves a dotted field path within typ, descending into nested structs
+func structFieldOffset(typ dwarf.Type, path []string) (uint64, bool) {
+	var offset uint64
+	for _, name := range path {
+		for {
+			typedef, ok := typ.(*dwarf.TypedefType)
+			if !ok {
+				break
+			}
+			typ = typedef.Type
+		}
+
+		structType, ok := typ.(*dwarf.StructType)
+		if !ok {
+			return 0, false
+		}
+
+		found := false
+		for _, field := range structType.Field {
+			if field.Name == name {
+				offset += uint64(field.ByteOffset)
+				typ = field.Type
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return offset, true
+}
+
+// dwarfRuntimeOffsets reads the offsets from the runtime.g and runtime.m DWARF types
+func dwarfRuntimeOffsets(data *dwarf.Data) map[string]uint64 {
+	offsets := make(map[string]uint64)
+	structs := map[string]dwarf.Type{}
+
+	reader := data.Reader()
+	for len(structs) < 2 {
+		entry, err := reader.Next()
+		if entry == nil || err != nil {
+			break
+		}
+
+		if entry.Tag != dwarf.TagStructType {
+			continue
+		}
+
+		name, _ := entry.Val(dwarf.AttrName).(string)
+		if name != "runtime.g" && name != "runtime.m" {
+			continue
+		}
+
+		typ, err := data.Type(entry.Offset)
+		if err == nil {
+			structs[strings.TrimPrefix(name, "runtime.")] = typ
+		}
+	}
+
+	for _, field := range runtimeOffsetFields {
+		path := strings.Split(field, ".")
+		typ, ok := structs[path[0]]
+		if !ok {
+			continue
+		}
+
+		if offset, ok := structFieldOffset(typ, path[1:]); ok {
+			offsets[field] = offset
+		}
+	}
+	return offsets
+}
+
+// RuntimeOffsets reports the runtime.g and runtime.m field offsets for the Go version, cross checked against DWARF when the binary has it.
+// Fields missing from both sources are omitted.
+func (e *Entry) RuntimeOffsets(goVersion string, is64bit bool) []RuntimeOffset {
+	table := tableRuntimeOffsets(goVersion, is64bit)
+
+	fromDwarf := make(map[string]uint64)
+	if data, err := e.raw.dwarf(); err == nil && data != nil {
+		fromDwarf = dwarfRuntimeOffsets(data)
+	}
+
+	var offsets []RuntimeOffset
+	for _, field := range runtimeOffsetFields {
+		tableOffset, inTable := table[field]
+		dwarfOffset, inDwarf := fromDwarf[field]
+
+		switch {
+		case inDwarf:
+			offsets = append(offsets, RuntimeOffset{
+				Field:    field,
+				Offset:   dwarfOffset,
+				Source:   "dwarf",
+				Verified: inTable && tableOffset == dwarfOffset,
+				Mismatch: inTable && tableOffset != dwarfOffset,
+			})
+		case inTable:
+			offsets = append(offsets, RuntimeOffset{Field: field, Offset: tableOffset, Source: "table"})
+		}
+	}
+	return offsets
+}
@@ -0,0 +1,46 @@
+package objfile
+
+import (
+	"testing"
+
+	"github.com/mandiant/GoReSym/debug/dwarf"
+)
+
+func TestTableRuntimeOffsets(t *testing.T) {
+	cases := []struct {
+		version string
+		is64bit bool
+		field   string
+		offset  uint64
+		known   bool
+	}{
+		{"1.15.5", true, "g.goid", 0x98, true},
+		{"1.22", false, "g.goid", 0x50, true},
+		{"1.8.7", true, "g.m", 0x30, true},
+		// stackAlloc still sits before sched
+		{"1.8.7", true, "g.goid", 0, false},
+		{"1.20", true, "m.curg", 0, false},
+		{"unknown", true, "g.m", 0, false},
+	}
+
+	for _, c := range cases {
+		offset, known := tableRuntimeOffsets(c.version, c.is64bit)[c.field]
+		if known != c.known || offset != c.offset {
+			t.Errorf("%s %s: expected 0x%x known=%v, got 0x%x known=%v", c.version, c.field, c.offset, c.known, offset, known)
+		}
+	}
+}
+
+func TestStructFieldOffset(t *testing.T) {
+	word := &dwarf.UintType{BasicType: dwarf.BasicType{CommonType: dwarf.CommonType{ByteSize: 8, Name: "uintptr"}}}
+	stack := &dwarf.StructType{StructName: "runtime.stack", Field: []*dwarf.StructField{{Name: "lo", Type: word, ByteOffset: 0}, {Name: "hi", Type: word, ByteOffset: 8}}}
+	g := &dwarf.StructType{StructName: "runtime.g", Field: []*dwarf.StructField{{Name: "stack", Type: &dwarf.TypedefType{Type: stack}, ByteOffset: 0x10}, {Name: "goid", Type: word, ByteOffset: 0x98}}}
+
+	if offset, ok := structFieldOffset(g, []string{"stack", "hi"}); !ok || offset != 0x18 {
+		t.Errorf("expected stack.hi at 0x18, got 0x%x", offset)
+	}
+
+	if _, ok := structFieldOffset(g, []string{"goid", "lo"}); ok {
+		t.Errorf("resolved a field through a non-struct")
+	}
+}
@@ -1,25 +1,64 @@
 package objfile
 
-import "encoding/binary"
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"runtime"
+	"sort"
+)
+
+// Each signature decodes the moduledata address one of two ways. Relative encodings (x64 lea rip+disp, arm64 adrp/add, riscv64 auipc/addi, loong64 pcalau12i/addi.d, s390x larl) are resolved
+// against sectionBase + the match offset. Absolute encodings (x86 lea disp32, ppc64 lis/addi, ppc64 addis/addi from the TOC, mips lui/addiu and lui/daddiu, the arm32 literal pool and movw/movt) already are the VA
+// and sectionBase is not used.
 
 type signatureModuleDataInitx64 struct {
 	moduleDataPtrLoc       uint64 // offset in signature to the location of the pointer to the PCHeader
 	moduleDataPtrOffsetLoc uint64 // Ptr is a relative ptr, we need to include the instruction length + next instruction IP to resolve final VA
 	signature              string
 	compiledRegex          *RegexAndNeedle
+	byteOrder              binary.ByteOrder // instruction encoding the signature matches
 }
 
 type signatureModuleDataInitx86 struct {
-	moduleDataPtrLoc uint64 // offset in signature to the location of the pointer to the PCHeader (ptr is absolute addr)
+	moduleDataPtrLoc uint64 // offset in signature to the location of the pointer to the PCHeader (ptr is absolute addr, not rebased)
 	signature        string
 	compiledRegex    *RegexAndNeedle
+	byteOrder        binary.ByteOrder // instruction encoding the signature matches
 }
 
+// ppc64 and ppc64le share the layout, the immediates are decoded from whole instruction words of either byte order
 type signatureModuleDataInitPPC struct {
-	moduleDataPtrHi uint64
-	moduleDataPtrLo uint64
+	moduleDataPtrHi uint64 // offset to LIS/ADDIS instruction holding the high 16 bits
+	moduleDataPtrLo uint64 // offset to ADDI instruction holding the sign extended low 16 bits
 	signature       string
 	compiledRegex   *RegexAndNeedle
+	byteOrder       binary.ByteOrder // instruction encoding the signature matches
+}
+
+type signatureModuleDataInitMIPS struct {
+	moduleDataPtrHi uint64 // offset to LUI instruction holding the high 16 bits
+	moduleDataPtrLo uint64 // offset to ADDIU instruction holding the sign extended low 16 bits
+	signature       string
+	compiledRegex   *RegexAndNeedle
+	byteOrder       binary.ByteOrder // instruction encoding the signature matches
+}
+
+type signatureModuleDataInitMIPS64 struct {
+	moduleDataPtrHi uint64 // offset to LUI instruction holding the high 16 bits
+	moduleDataPtrSB uint64 // offset to DADDU adding the static base register, zero in Go binaries
+	moduleDataPtrLo uint64 // offset to DADDIU instruction holding the sign extended low 16 bits
+	signature       string
+	compiledRegex   *RegexAndNeedle
+	byteOrder       binary.ByteOrder // instruction encoding the signature matches
+}
+
+type signatureModuleDataInitS390X struct {
+	moduleDataPtrLARL uint64 // offset to LARL instruction holding the halfword scaled displacement, relative to its own PC
+	moduleDataPtrCMP  uint64 // offset to the compare and branch testing the loaded register
+	signature         string
+	compiledRegex     *RegexAndNeedle
+	byteOrder         binary.ByteOrder // instruction encoding the signature matches
 }
 
 type signatureModuleDataInitARM64 struct {
@@ -27,22 +66,52 @@ type signatureModuleDataInitARM64 struct {
 	moduleDataPtrADD  uint64 // offset to ADD instruction holding PAGE offset
 	signature         string
 	compiledRegex     *RegexAndNeedle
+	byteOrder         binary.ByteOrder // instruction encoding the signature matches
+}
+
+type signatureModuleDataInitRISCV64 struct {
+	moduleDataPtrAUIPC uint64 // offset to AUIPC instruction holding the upper 20 bits, relative to its own PC
+	moduleDataPtrADDI  uint64 // offset to ADDI instruction holding the sign extended lower 12 bits
+	signature          string
+	compiledRegex      *RegexAndNeedle
+	byteOrder          binary.ByteOrder // instruction encoding the signature matches
+}
+
+type signatureModuleDataInitLOONG64 struct {
+	moduleDataPtrPCALAU12I uint64 // offset to PCALAU12I instruction holding the PAGE, relative to the page of its own PC
+	moduleDataPtrADDID     uint64 // offset to ADDI.D instruction holding the sign extended PAGE offset
+	signature              string
+	compiledRegex          *RegexAndNeedle
+	byteOrder              binary.ByteOrder // instruction encoding the signature matches
 }
 
 type signatureModuleDataInitARM32 struct {
 	moduleDataPtrLDR uint64 // offset to LDR instruction holding pc relative imm offset to PCHeader
 	signature        string
 	compiledRegex    *RegexAndNeedle
+	byteOrder        binary.ByteOrder // instruction encoding the signature matches
 }
 
+type signatureModuleDataInitARM32MovwMovt struct {
+	moduleDataPtrMOVW uint64 // offset to MOVW instruction holding the low 16 bits
+	moduleDataPtrMOVT uint64 // offset to MOVT instruction holding the high 16 bits
+	signature         string
+	compiledRegex     *RegexAndNeedle
+	byteOrder         binary.ByteOrder // instruction encoding the signature matches
+}
+
+// SignatureMatch is a moduledata found by signature. moduleDataVA is a final VA for every architecture, callers must not add a section base to it.
 type SignatureMatch struct {
 	moduleDataVA uint64
+	signature    string // name of the signature that matched, as in ScanDiagnostics
+	matchOffset  uint64 // offset of the match in the scanned section
+	matchVA      uint64 // VA of the matched instructions
 }
 
 // 0x000000000044D80A: 48 8D 0D 8F DA 26 00                    lea     rcx, runtime_firstmoduledata
 // 0x000000000044D811: EB 0D                                   jmp     short loc_44D820
 // 0x000000000044D813: 48 8B 89 30 02 00 00                    mov     rcx, [rcx+230h]
-var x64sig = signatureModuleDataInitx64{3, 7, `{ 48 8D 0? ?? ?? ?? ?? E? ?? 48 8? 8? ?? 02 00 00 }`, nil}
+var x64sig = signatureModuleDataInitx64{3, 7, `{ 48 8D 0? ?? ?? ?? ?? E? ?? 48 8? 8? ?? 02 00 00 }`, nil, binary.LittleEndian}
 
 // 0x00438A94: 8D 05 60 49 6A 00                       lea     eax, off_6A4960
 // 0x00438A9A: EB 1A                                   jmp     short loc_438AB6
@@ -53,7 +122,7 @@ var x64sig = signatureModuleDataInitx64{3, 7, `{ 48 8D 0? ?? ?? ?? ?? E? ?? 48 8
 // 0x00438AB6:                         loc_438AB6:                             ; CODE XREF: sub_438A60+3A↑j
 // 0x00438AB6: 85 C0                                   test    eax, eax
 // 0x00438AB8: 75 E2                                   jnz     short loc_438A9C
-var x86sig = signatureModuleDataInitx86{2, `{ 8D ?? ?? ?? ?? ?? EB ?? [0-50] 8B ?? ?? 01 00 00 8B ?? ?? ?? 85 ?? 75 ?? }`, nil}
+var x86sig = signatureModuleDataInitx86{2, `{ 8D ?? ?? ?? ?? ?? EB ?? [0-50] 8B ?? ?? 01 00 00 8B ?? ?? ?? 85 ?? 75 ?? }`, nil, binary.LittleEndian}
 
 // 0x0000000000061a74:  3C 80 00 2C    lis  r4, 0x2c       // moduledata
 // 0x0000000000061a78:  38 84 80 00    addi r4, r4, 0x8000  // moduledata ((0x2c << 16) - 0x8000)
@@ -61,7 +130,42 @@ var x86sig = signatureModuleDataInitx86{2, `{ 8D ?? ?? ?? ?? ?? EB ?? [0-50] 8B
 // 0x0000000000061a80:  E8 84 02 30    ld   r4, 0x230(r4)
 // 0x0000000000061a84:  7C 24 00 00    cmpd r4, r0
 // 0x0000000000061a88:  41 82 01 A8    beq  0x61c30
-var PPC_BE_sig = signatureModuleDataInitPPC{2, 6, `{ 3? 80 00 ?? 3? ?? ?? ?? 48 ?? ?? ?? E? ?? 02 ?? 7C ?? ?? ?? 41 82 ?? ?? }`, nil}
+var PPC_BE_sig = signatureModuleDataInitPPC{0, 4, `{ 3? 80 00 ?? 3? ?? ?? ?? 48 ?? ?? ?? E? ?? 02 ?? 7C ?? ?? ?? 41 82 ?? ?? }`, nil, binary.BigEndian}
+
+// ppc64le is the same code word swapped. Position independent builds address the moduledata from the TOC pointer instead
+// 0x00000000000725C4:  00 00 82 3C    addis r4, r2, 0      // TOC (.got + 0x8000) relative
+// 0x00000000000725C8:  F0 B3 84 38    addi  r4, r4, -19472 // moduledata (0x4281d0 - 0x4c10)
+// 0x00000000000725CC:  08 00 00 48    b     0x725d4
+// 0x00000000000725D0:  48 02 84 E8    ld    r4, 0x248(r4)
+// 0x00000000000725D4:  00 00 24 7C    cmpd  r4, r0
+// 0x00000000000725D8:  20 00 82 41    beq   0x725f8
+var PPC64LE_sig = signatureModuleDataInitPPC{0, 4, `{ ?? 00 ?? 3? ?? ?? ?? 3? ?? ?? ?? 48 ?? 02 ?? E? ?? ?? ?? 7C ?? ?? 82 41 }`, nil, binary.LittleEndian}
+
+// 0x0008B254: 3C 01 00 43    lui   at, 0x43          // moduledata
+// 0x0008B258: 24 21 25 A0    addiu at, at, 0x25a0    // moduledata (0x43 << 16) + 0x25a0
+// 0x0008B25C: 10 00 00 02    b     0x8b268
+// 0x0008B260: 00 00 00 00    nop
+// 0x0008B264: 8C 21 01 24    lw    at, 0x124(at)
+// 0x0008B268: 10 20 00 0B    beqz  at, 0x8b298
+// mipsle is the same code with every instruction word byte swapped, the immediates are decoded from the whole word
+var MIPS_BE_sig = signatureModuleDataInitMIPS{0, 4, `{ 3C ?? ?? ?? (24|25|26|27) ?? ?? ?? 10 00 00 02 00 00 00 00 (8C|8D|8E|8F) ?? ?? ?? (10|11|12|13) ?? 00 ?? }`, nil, binary.BigEndian}
+var MIPS_LE_sig = signatureModuleDataInitMIPS{0, 4, `{ ?? ?? ?? 3C ?? ?? ?? (24|25|26|27) 02 00 00 10 00 00 00 00 ?? ?? ?? (8C|8D|8E|8F) ?? 00 ?? (10|11|12|13) }`, nil, binary.LittleEndian}
+
+// 0x0008886C: 3C 01 00 41    lui    at, 0x41         // moduledata
+// 0x00088870: 00 3C 08 2D    daddu  at, at, gp       // gp is REGSB, the static base Go keeps at zero
+// 0x00088874: 64 21 35 60    daddiu at, at, 0x3560   // moduledata (0x41 << 16) + 0x3560
+// 0x00088878: 10 00 00 02    b      0x88884
+// 0x0008887C: 00 00 00 00    nop
+// 0x00088880: DC 21 02 48    ld     at, 0x248(at)
+// 0x00088884: 10 20 00 0B    beqz   at, 0x888b4
+var MIPS64_BE_sig = signatureModuleDataInitMIPS64{0, 4, 8, `{ 3C ?? ?? ?? (00|01|02|03) ?? ?? 2D (64|65|66|67) ?? ?? ?? 10 00 00 02 00 00 00 00 (DC|DD|DE|DF) ?? ?? ?? (10|11|12|13) ?? 00 ?? }`, nil, binary.BigEndian}
+var MIPS64_LE_sig = signatureModuleDataInitMIPS64{0, 4, 8, `{ ?? ?? ?? 3C 2D ?? ?? (00|01|02|03) ?? ?? ?? (64|65|66|67) 02 00 00 10 00 00 00 00 ?? ?? ?? (DC|DD|DE|DF) ?? 00 ?? (10|11|12|13) }`, nil, binary.LittleEndian}
+
+// 0x000000000007BAEA C0 00 00 1B BD AB    larl   %r0, 0x3f3640           // 0x7baea + 0x1bbdab * 2 = firstmoduledata
+// 0x000000000007BAF0 A7 F4 00 05          j      0x7bafa
+// 0x000000000007BAF4 E3 00 42 48 00 04    lg     %r0, 0x248(%r4)
+// 0x000000000007BAFA EC 08 00 13 00 7C    cgije  %r0, 0, 0x7bb20
+var S390X_sig = signatureModuleDataInitS390X{0, 16, `{ C0 ?? ?? ?? ?? ?? A7 F4 00 05 E3 ?? ?? ?? ?? 04 EC ?? ?? ?? 00 7C }`, nil, binary.BigEndian}
 
 // 0x000000000005C1E8 41 14 00 F0        ADRP            X1, #unk_2E7000    // 0xF0001441 -> 0b1 11 10000 0000000000010100010 00001 -> op=1, immlo=0b11, immhi=0b0000000000010100010
 // ........................................................................ // X1 = ((0b0000000000010100010 11 << 12) + 0x5C1E8) = 0b1011100111000111101000 = 0b1011100111000111101000 & 0xFFFFFFFFFFFFF000 = 0x2E7000
@@ -71,134 +175,682 @@ var PPC_BE_sig = signatureModuleDataInitPPC{2, 6, `{ 3? 80 00 ?? 3? ?? ?? ?? 48
 // 0x000000000005C1F4 21 18 41 F9        LDR             X1, [X1,#0x230]
 // 0x000000000005C1F8 21 0D 00 B4        CBZ             X1, loc_5C39C   0xb4000d21
 // THIS SIG ENCODES the 0x230 struct field offset - might need to mask that more if we see misses - TODO
-var ARM64_sig = signatureModuleDataInitARM64{0, 4, `{ ?? ?? ?? (90 | b0 | f0 | d0) ?? ?? ?? 91 ?? ?? ?? (14 | 17) ?? ?? 41 F9 ?? ?? ?? B4 }`, nil}
+var ARM64_sig = signatureModuleDataInitARM64{0, 4, `{ ?? ?? ?? (90 | b0 | f0 | d0) ?? ?? ?? 91 ?? ?? ?? (14 | 17) ?? ?? 41 F9 ?? ?? ?? B4 }`, nil, binary.LittleEndian}
 
 // 0x0006AA00 80 12 9F E5    LDR             R1, =firstmoduleData   // 0xE59F1280 -> 0b11 100101100111110001001010000000 -> size = 11,
 // 0x0006AA04 00 00 00 EA    B               loc_6AA0C
 // 0x0006AA08 18 11 91 E5    LDR             R1, [R1,#0x118]
 // 0x0006AA0C 00 00 51 E3    CMP             R1, #0
 // 0x0006AA10 69 00 00 0A    BEQ             loc_6ABBC
-var ARM32_sig = signatureModuleDataInitARM32{0, `{ ?? ?? 9F E5 ?? ?? ?? EA ?? ?? ?? E5 ?? ?? ?? E3 ?? ?? ?? 0A }`, nil}
+var ARM32_sig = signatureModuleDataInitARM32{0, `{ ?? ?? 9F E5 ?? ?? ?? EA ?? ?? ?? E5 ?? ?? ?? E3 ?? ?? ?? 0A }`, nil, binary.LittleEndian}
 
-func findModuleInitPCHeader(data []byte, sectionBase uint64) []SignatureMatch {
-	var matches []SignatureMatch = make([]SignatureMatch, 0)
+// ARMv7 code may build the address in place rather than load it from a literal pool, the loop is the same
+// 0x0006AA00 C8 01 02 E3    MOVW            R0, #0x21C8            // 0xE30201C8 -> imm4 = 0x2, Rd = 0, imm12 = 0x1C8
+// 0x0006AA04 3C 00 40 E3    MOVT            R0, #0x3C              // R0 = 0x3C21C8 firstmoduleData
+// 0x0006AA08 00 00 00 EA    B               loc_6AA10
+// 0x0006AA0C 24 01 90 E5    LDR             R0, [R0,#0x124]
+// 0x0006AA10 00 00 50 E3    CMP             R0, #0
+// 0x0006AA14 06 00 00 0A    BEQ             loc_6AA34
+var ARM32_movw_sig = signatureModuleDataInitARM32MovwMovt{0, 4, `{ ?? ?? 0? E3 ?? ?? 4? E3 ?? ?? ?? EA ?? ?? ?? E5 ?? ?? ?? E3 ?? ?? ?? 0A }`, nil, binary.LittleEndian}
 
-	var x64reg = x64sig.compiledRegex
-	if x64reg == nil {
-		var err error
-		x64reg, err = RegexpPatternFromYaraPattern(x64sig.signature)
-		if err != nil {
-			panic(err)
+// signatureFinder returns the offsets of a signature's matches within the scanned section
+type signatureFinder func(regexInfo *RegexAndNeedle) ([][]int, error)
+
+// sectionReader returns n bytes at off within the scanned section, false if they run past its end
+type sectionReader func(off uint64, n uint64) ([]byte, bool)
+
+// 0x0000000000064688 97 F2 31 00    auipc t0, 0x31f        // t0 = 0x64688 + (0x31f << 12)
+// 0x000000000006468C 93 82 82 F1    addi  t0, t0, -232     // t0 = 0x3835a0 firstmoduledata
+// 0x0000000000064690 6F 00 80 00    j     0x64698
+// 0x0000000000064694 83 B2 82 24    ld    t0, 584(t0)      // datap = datap.next
+// 0x0000000000064698 63 8C 02 00    beqz  t0, 0x646b0
+// The low opcode byte carries the low bit of rd, both encodings are matched
+var RISCV64_sig = signatureModuleDataInitRISCV64{0, 4, `{ (17|97) ?? ?? ?? (13|93) ?? ?? ?? 6F 00 80 00 (03|83) ?? ?? ?? }`, nil, binary.LittleEndian}
+
+// 0x000000000006F5DC 85 68 00 1A    pcalau12i $r5, 0x344      // si20 is bits 5-24, r5 = (0x6f5dc & ~0xfff) + (0x344 << 12) = 0x3b3000
+// 0x000000000006F5E0 A5 00 D6 02    addi.d    $r5, $r5, 0x580 // si12 is bits 10-21, r5 = 0x3b3580 firstmoduledata
+// 0x000000000006F5E4 00 10 00 50    b         0x6f5f4
+// 0x000000000006F5E8 00 00 40 03    nop                       // loop alignment, the count varies
+// 0x000000000006F5EC 00 00 40 03    nop
+// 0x000000000006F5F0 A5 20 C9 28    ld.d      $r5, $r5, 0x248 // datap = datap.next
+// 0x000000000006F5F4 A0 28 00 40    beqz      $r5, 0x6f61c
+var LOONG64_sig = signatureModuleDataInitLOONG64{0, 4, `{ ?? ?? ?? (1A|1B) ?? ?? ?? 02 ?? ?? ?? (50|51|52|53) [0-16] ?? ?? ?? 28 ?? ?? ?? (40|41|42|43) }`, nil, binary.LittleEndian}
+
+// mustCompileSignature converts a built-in signature, they're constants so a bad one is a programming error
+func mustCompileSignature(signature string) *RegexAndNeedle {
+	compiled, err := RegexpPatternFromYaraPattern(signature)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// the signatures are compiled once here, before any scan can run concurrently with another
+func init() {
+	x64sig.compiledRegex = mustCompileSignature(x64sig.signature)
+	x86sig.compiledRegex = mustCompileSignature(x86sig.signature)
+	ARM64_sig.compiledRegex = mustCompileSignature(ARM64_sig.signature)
+	ARM32_sig.compiledRegex = mustCompileSignature(ARM32_sig.signature)
+	ARM32_movw_sig.compiledRegex = mustCompileSignature(ARM32_movw_sig.signature)
+	RISCV64_sig.compiledRegex = mustCompileSignature(RISCV64_sig.signature)
+	LOONG64_sig.compiledRegex = mustCompileSignature(LOONG64_sig.signature)
+	S390X_sig.compiledRegex = mustCompileSignature(S390X_sig.signature)
+	for _, sig := range []*signatureModuleDataInitPPC{&PPC_BE_sig, &PPC64LE_sig} {
+		sig.compiledRegex = mustCompileSignature(sig.signature)
+	}
+	for _, sig := range []*signatureModuleDataInitMIPS{&MIPS_BE_sig, &MIPS_LE_sig} {
+		sig.compiledRegex = mustCompileSignature(sig.signature)
+	}
+	for _, sig := range []*signatureModuleDataInitMIPS64{&MIPS64_BE_sig, &MIPS64_LE_sig} {
+		sig.compiledRegex = mustCompileSignature(sig.signature)
+	}
+}
+
+// signatureGoarch is the GOARCH whose code each signature matches
+var signatureGoarch = map[string]string{
+	"x64": "amd64", "x86": "386", "arm64": "arm64", "arm32": "arm", "arm32movw": "arm", "riscv64": "riscv64", "loong64": "loong64", "s390x": "s390x",
+	"ppc64be": "ppc64", "ppc64le": "ppc64le", "mips": "mips", "mipsle": "mipsle", "mips64": "mips64", "mips64le": "mips64le",
+}
+
+// SupportedGoarch reports whether a moduledata signature covers the code of goarch. The moduledata of the others can't be found
+// by scanning, ex: a 32-bit ppc binary. An empty GOARCH, ex: a raw blob, runs every signature so it's supported.
+func SupportedGoarch(goarch string) bool {
+	if len(goarch) == 0 || goarch == "wasm" {
+		return true
+	}
+	for _, arch := range signatureGoarch {
+		if arch == goarch {
+			return true
+		}
+	}
+	return false
+}
+
+// signatureFilter reports which signatures to run for the GOARCH from the file header. The code of other architectures only produces false positives.
+// An empty or unsupported GOARCH, ex: a raw blob, runs every signature.
+func signatureFilter(goarch string) func(signature string) bool {
+	for _, arch := range signatureGoarch {
+		if arch == goarch {
+			return func(signature string) bool {
+				return signatureGoarch[signature] == goarch
+			}
+		}
+	}
+	return func(signature string) bool {
+		return true
+	}
+}
+
+// findSignature runs a signature over the section, unless it's filtered out or the image is of the other endianess. The immediates would decode to garbage VAs.
+// A nil image byte order means the header didn't tell us, so every signature is tried.
+func findSignature(find signatureFinder, enabled bool, regexInfo *RegexAndNeedle, sigOrder binary.ByteOrder, imageOrder binary.ByteOrder) ([][]int, error) {
+	if !enabled || (imageOrder != nil && sigOrder != imageOrder) {
+		return nil, nil
+	}
+	return find(regexInfo)
+}
+
+// matchValidator reports whether the moduledata a signature resolved to is real
+type matchValidator func(match SignatureMatch) bool
+
+// isPCHeader reports whether data starts like a pcHeader of any version, in either byte order: magic, two zeros, pc quantum, pointer size
+func isPCHeader(data []byte) bool {
+	if len(data) < 8 || data[4] != 0 || data[5] != 0 || (data[6] != 1 && data[6] != 2 && data[6] != 4) || (data[7] != 4 && data[7] != 8) {
+		return false
+	}
+
+	for _, magic := range []uint32{0xfffffffb, 0xfffffffa, 0xfffffff0, 0xfffffff1} {
+		if binary.LittleEndian.Uint32(data) == magic || binary.BigEndian.Uint32(data) == magic {
+			return true
+		}
+	}
+	return false
+}
+
+// pcHeaderValidator accepts matches whose moduledata starts with a pointer to a pcHeader, or returns nil when not enabled.
+// Stomped magics, like garble's, never validate, so those scans stay exhaustive.
+func pcHeaderValidator(enabled bool, read_memory func(VA uint64, size uint64) ([]byte, error)) matchValidator {
+	if !enabled {
+		return nil
+	}
+
+	return func(match SignatureMatch) bool {
+		ptr, err := read_memory(match.moduleDataVA, 8)
+		if err != nil || len(ptr) < 4 {
+			return false
+		}
+
+		// the width and byte order aren't known yet, try every combination
+		candidates := []uint64{uint64(binary.LittleEndian.Uint32(ptr)), uint64(binary.BigEndian.Uint32(ptr))}
+		if len(ptr) >= 8 {
+			candidates = append(candidates, binary.LittleEndian.Uint64(ptr), binary.BigEndian.Uint64(ptr))
+		}
+
+		for _, pcHeaderVA := range candidates {
+			if header, err := read_memory(pcHeaderVA, 8); err == nil && isPCHeader(header) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SetFirstMatchOnly makes the moduledata signature scan stop at the first match whose pcHeader validates, rather than collecting every match.
+// Off by default since binaries can carry more than one runtime. Go objects have no signature scan and ignore this.
+func (e *Entry) SetFirstMatchOnly(enabled bool) {
+	switch f := e.raw.(type) {
+	case *elfFile:
+		f.firstMatchOnly = enabled
+	case *machoFile:
+		f.firstMatchOnly = enabled
+	case *peFile:
+		f.firstMatchOnly = enabled
+	case *dumpFile:
+		f.firstMatchOnly = enabled
+	}
+}
+
+// SetScanWorkers sets how many goroutines the moduledata signature scan splits each section between, 0 (the default) for GOMAXPROCS.
+// Small sections are scanned by fewer. The matches don't depend on the count.
+func (e *Entry) SetScanWorkers(workers int) {
+	switch f := e.raw.(type) {
+	case *elfFile:
+		f.scanWorkers = workers
+	case *machoFile:
+		f.scanWorkers = workers
+	case *peFile:
+		f.scanWorkers = workers
+	case *dumpFile:
+		f.scanWorkers = workers
+	}
+}
+
+// findModuleInitPCHeader scans data for the moduledata initialization signatures, imageOrder is the byte order from the file header.
+// sectionBase is the VA of data[0], it's only needed to resolve the relative encodings. The results are final VAs.
+// toc is the ppc64 TOC pointer (r2) for position independent code, or 0 when the image has none.
+// With a firstValid validator the scan stops at the first match it accepts and returns only that one, useful when a single runtime is expected.
+// A nil validator is exhaustive, binaries can carry more than one runtime. diag may be nil.
+func findModuleInitPCHeader(data []byte, sectionBase uint64, imageOrder binary.ByteOrder, toc uint64, firstValid matchValidator, diag *scanDiagnostics) []SignatureMatch {
+	return findModuleInitPCHeaderWorkers(nil, data, sectionBase, imageOrder, "", nil, toc, 1, firstValid, diag)
+}
+
+// sections are split between workers no smaller than this, goroutines cost more than scanning a small section
+const minScanChunk = 1 << 20
+
+// findModuleInitPCHeaderWorkers is findModuleInitPCHeader with the pattern matching split between workers goroutines, 0 for GOMAXPROCS.
+// Only the matching runs in parallel, the matches are returned sorted by moduledata VA so the results don't depend on the scheduling.
+// goarch limits the scan to the signatures of that architecture, empty for all of them, custom are scanned after them. Once ctx is
+// done the chunks left aren't matched, the scan returns no matches. ctx may be nil.
+func findModuleInitPCHeaderWorkers(ctx context.Context, data []byte, sectionBase uint64, imageOrder binary.ByteOrder, goarch string, custom []compiledCustomSignature, toc uint64, workers int, firstValid matchValidator, diag *scanDiagnostics) []SignatureMatch {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if maxWorkers := len(data) / minScanChunk; workers > maxWorkers {
+		workers = maxWorkers
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	find := func(regexInfo *RegexAndNeedle) ([][]int, error) {
+		return findRegexParallel(ctx, data, regexInfo, workers)
+	}
+	read := func(off uint64, n uint64) ([]byte, bool) {
+		// n is checked against the remainder so a garbage offset can't wrap around
+		if off > uint64(len(data)) || n > uint64(len(data))-off {
+			return nil, false
+		}
+		return data[off : off+n], true
+	}
+
+	matches, _ := scanModuleInitPCHeader(find, read, sectionBase, imageOrder, goarch, custom, toc, firstValid, diag)
+	sortSignatureMatches(matches)
+	return matches
+}
+
+// sortSignatureMatches orders matches by their moduledata VA, then their offset
+func sortSignatureMatches(matches []SignatureMatch) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].moduleDataVA != matches[j].moduleDataVA {
+			return matches[i].moduleDataVA < matches[j].moduleDataVA
+		}
+		return matches[i].matchOffset < matches[j].matchOffset
+	})
+}
+
+// findModuleInitPCHeaderReader is findModuleInitPCHeader for sections too large to hold in memory. The signatures are matched window bytes at a time,
+// then only the few bytes each match decodes are read. The matches are sorted like findModuleInitPCHeaderWorkers', ctx stops the scan
+// before the next window with ctx.Err(). ctx may be nil.
+func findModuleInitPCHeaderReader(ctx context.Context, r io.ReaderAt, size int64, sectionBase uint64, imageOrder binary.ByteOrder, goarch string, custom []compiledCustomSignature, toc uint64, window int, firstValid matchValidator, diag *scanDiagnostics) ([]SignatureMatch, error) {
+	find := func(regexInfo *RegexAndNeedle) ([][]int, error) {
+		return findRegexReader(ctx, r, size, regexInfo, window)
+	}
+	read := func(off uint64, n uint64) ([]byte, bool) {
+		if off > uint64(size) || n > uint64(size)-off {
+			return nil, false
 		}
-		x64sig.compiledRegex = x64reg
+		buf := make([]byte, n)
+		if _, err := r.ReadAt(buf, int64(off)); err != nil && err != io.EOF {
+			return nil, false
+		}
+		return buf, true
+	}
+	matches, err := scanModuleInitPCHeader(find, read, sectionBase, imageOrder, goarch, custom, toc, firstValid, diag)
+	sortSignatureMatches(matches)
+	return matches, err
+}
+
+func scanModuleInitPCHeader(find signatureFinder, read sectionReader, sectionBase uint64, imageOrder binary.ByteOrder, goarch string, custom []compiledCustomSignature, toc uint64, firstValid matchValidator, diag *scanDiagnostics) ([]SignatureMatch, error) {
+	var matches []SignatureMatch = make([]SignatureMatch, 0)
+	runs := signatureFilter(goarch)
+	find = diag.timed(find)
+	// in first match mode the remaining offsets and signatures are skipped once a match validates
+	accept := func(result SignatureMatch) bool {
+		matches = append(matches, result)
+		diag.decoded(result)
+		return firstValid != nil && firstValid(result)
+	}
+	found := func(signature string, sigPtr uint64, moduleDataVA uint64) SignatureMatch {
+		return SignatureMatch{moduleDataVA: moduleDataVA, signature: signature, matchOffset: sigPtr, matchVA: sectionBase + sigPtr}
 	}
 
-	for _, match := range FindRegex(data, x64reg) {
+	var x64reg = x64sig.compiledRegex
+
+	sigMatches, err := findSignature(find, runs("x64"), x64reg, x64sig.byteOrder, imageOrder)
+	if err != nil {
+		return nil, err
+	}
+	diag.hits("x64", len(sigMatches))
+	for _, match := range sigMatches {
 		sigPtr := uint64(match[0]) // from int
 
 		// this is the pointer offset stored in the instruction
 		// 0x44E06A:       48 8D 0D 4F F0 24 00 lea     rcx, off_69D0C0 (result: 0x24f04f)
-		moduleDataPtrOffset := uint64(binary.LittleEndian.Uint32(data[sigPtr+x64sig.moduleDataPtrLoc:][:4]))
+		ptrBytes, ok := read(sigPtr+x64sig.moduleDataPtrLoc, 4)
+		if !ok {
+			continue
+		}
+		moduleDataPtrOffset := uint64(x64sig.byteOrder.Uint32(ptrBytes))
 
 		// the ptr we get is position dependant, add the sigPtr + sectionBase to get current IP, then offset to next instruction
 		// as relative ptrs are encoded by the NEXT instruction va, not the current one
 		moduleDataIpOffset := sigPtr + sectionBase + x64sig.moduleDataPtrOffsetLoc
-		matches = append(matches, SignatureMatch{
-			moduleDataPtrOffset + moduleDataIpOffset,
-		})
+		result := found("x64", sigPtr, moduleDataPtrOffset+moduleDataIpOffset)
+		if accept(result) {
+			return []SignatureMatch{result}, nil
+		}
 	}
 
 	var x86reg = x86sig.compiledRegex
-	if x86reg == nil {
-		var err error
-		x86reg, err = RegexpPatternFromYaraPattern(x86sig.signature)
-		if err != nil {
-			panic(err)
-		}
-		x86sig.compiledRegex = x86reg
-	}
 
-	for _, match := range FindRegex(data, x86reg) {
+	sigMatches, err = findSignature(find, runs("x86"), x86reg, x86sig.byteOrder, imageOrder)
+	if err != nil {
+		return nil, err
+	}
+	diag.hits("x86", len(sigMatches))
+	for _, match := range sigMatches {
 		sigPtr := uint64(match[0]) // from int
 
-		moduleDataPtr := uint64(binary.LittleEndian.Uint32(data[sigPtr+x86sig.moduleDataPtrLoc:][:4]))
-		matches = append(matches, SignatureMatch{
-			moduleDataPtr,
-		})
+		ptrBytes, ok := read(sigPtr+x86sig.moduleDataPtrLoc, 4)
+		if !ok {
+			continue
+		}
+		moduleDataPtr := uint64(x86sig.byteOrder.Uint32(ptrBytes))
+		result := found("x86", sigPtr, moduleDataPtr)
+		if accept(result) {
+			return []SignatureMatch{result}, nil
+		}
 	}
 
 	var arm64reg = ARM64_sig.compiledRegex
-	if arm64reg == nil {
-		var err error
-		arm64reg, err = RegexpPatternFromYaraPattern(ARM64_sig.signature)
-		if err != nil {
-			panic(err)
-		}
-		ARM64_sig.compiledRegex = arm64reg
-	}
 
-	for _, match := range FindRegex(data, arm64reg) {
+	sigMatches, err = findSignature(find, runs("arm64"), arm64reg, ARM64_sig.byteOrder, imageOrder)
+	if err != nil {
+		return nil, err
+	}
+	diag.hits("arm64", len(sigMatches))
+	for _, match := range sigMatches {
 		sigPtr := uint64(match[0]) // from int
 
-		adrp := binary.LittleEndian.Uint32(data[sigPtr+ARM64_sig.moduleDataPtrADRP:][:4])
-		add := binary.LittleEndian.Uint32(data[sigPtr+ARM64_sig.moduleDataPtrADD:][:4])
+		adrpBytes, adrpOk := read(sigPtr+ARM64_sig.moduleDataPtrADRP, 4)
+		addBytes, addOk := read(sigPtr+ARM64_sig.moduleDataPtrADD, 4)
+		if !adrpOk || !addOk {
+			continue
+		}
+		adrp := ARM64_sig.byteOrder.Uint32(adrpBytes)
+		add := ARM64_sig.byteOrder.Uint32(addBytes)
 		moduleDataIpOffset := sigPtr + sectionBase
 
-		adrp_immhi := uint64((adrp & 0xFFFFF0) >> 5)
+		// the add must build on the register the adrp wrote, adrp Rd is bits 0-4, add Rn bits 5-9
+		if adrp&0x1F != (add>>5)&0x1F {
+			continue
+		}
+
+		adrp_immhi := uint64((adrp & 0xFFFFE0) >> 5)
 		adrp_immlo := uint64((adrp & 0x60000000) >> 29)
-		adrp_imm := adrp_immhi<<2 | adrp_immlo                               // combine hi:lo
-		page := ((adrp_imm << 12) + moduleDataIpOffset) & 0xFFFFFFFFFFFFF000 // PAGE imm is aligned to page, left shift 12 and zero lower 12 to align
+		adrp_imm := int64(adrp_immhi<<2|adrp_immlo) << 43 >> 43                     // combine hi:lo, a signed 21 bit page count
+		page := uint64(int64(moduleDataIpOffset&0xFFFFFFFFFFFFF000) + adrp_imm<<12) // PAGE imm is relative to the page of the adrp, left shift 12 to align
 
-		// the page offset fills in lower 12
+		// the page offset fills in lower 12, unless sh asks for imm12 << 12
 		page_off := uint64((add & 0x3FFC00) >> 10)
+		if add&0x400000 != 0 {
+			page_off <<= 12
+		}
 
 		final := page + page_off
-		matches = append(matches, SignatureMatch{
-			final,
-		})
+		result := found("arm64", sigPtr, final)
+		if accept(result) {
+			return []SignatureMatch{result}, nil
+		}
 	}
 
 	var arm32reg = ARM32_sig.compiledRegex
-	if arm32reg == nil {
-		var err error
-		arm32reg, err = RegexpPatternFromYaraPattern(ARM32_sig.signature)
-		if err != nil {
-			panic(err)
-		}
-		ARM32_sig.compiledRegex = arm32reg
-	}
 
-	for _, match := range FindRegex(data, arm32reg) {
+	sigMatches, err = findSignature(find, runs("arm32"), arm32reg, ARM32_sig.byteOrder, imageOrder)
+	if err != nil {
+		return nil, err
+	}
+	diag.hits("arm32", len(sigMatches))
+	for _, match := range sigMatches {
 		sigPtr := uint64(match[0]) // from int
-		ldr := binary.LittleEndian.Uint32(data[sigPtr+ARM32_sig.moduleDataPtrLDR:][:4])
+		ldrBytes, ok := read(sigPtr+ARM32_sig.moduleDataPtrLDR, 4)
+		if !ok {
+			continue
+		}
+		ldr := ARM32_sig.byteOrder.Uint32(ldrBytes)
 		// ARM PC relative is always +8 due to legacy nonsense
 		ldr_pointer_stub := uint64((ldr & 0x00000FFF) + 8)
-		final := uint64(binary.LittleEndian.Uint32(data[sigPtr+ARM32_sig.moduleDataPtrLDR+ldr_pointer_stub:][:4]))
-		matches = append(matches, SignatureMatch{
-			final,
-		})
+		// the literal pool can sit past the end of the match
+		literal, ok := read(sigPtr+ARM32_sig.moduleDataPtrLDR+ldr_pointer_stub, 4)
+		if !ok {
+			continue
+		}
+		final := uint64(ARM32_sig.byteOrder.Uint32(literal))
+		result := found("arm32", sigPtr, final)
+		if accept(result) {
+			return []SignatureMatch{result}, nil
+		}
 	}
 
-	var ppcBEreg = PPC_BE_sig.compiledRegex
-	if ppcBEreg == nil {
-		var err error
-		ppcBEreg, err = RegexpPatternFromYaraPattern(PPC_BE_sig.signature)
-		if err != nil {
-			panic(err)
+	var arm32movwreg = ARM32_movw_sig.compiledRegex
+
+	sigMatches, err = findSignature(find, runs("arm32movw"), arm32movwreg, ARM32_movw_sig.byteOrder, imageOrder)
+	if err != nil {
+		return nil, err
+	}
+	diag.hits("arm32movw", len(sigMatches))
+	for _, match := range sigMatches {
+		sigPtr := uint64(match[0]) // from int
+
+		movwBytes, movwOk := read(sigPtr+ARM32_movw_sig.moduleDataPtrMOVW, 4)
+		movtBytes, movtOk := read(sigPtr+ARM32_movw_sig.moduleDataPtrMOVT, 4)
+		if !movwOk || !movtOk {
+			continue
+		}
+		movw := ARM32_movw_sig.byteOrder.Uint32(movwBytes)
+		movt := ARM32_movw_sig.byteOrder.Uint32(movtBytes)
+
+		// both halves must go to the same register, Rd is bits 12-15
+		if (movw>>12)&0xF != (movt>>12)&0xF {
+			continue
+		}
+
+		// imm16 is split as imm4:imm12, imm4 in bits 16-19
+		lo := uint64((movw>>16)&0xF<<12 | movw&0xFFF)
+		hi := uint64((movt>>16)&0xF<<12 | movt&0xFFF)
+		result := found("arm32movw", sigPtr, hi<<16|lo)
+		if accept(result) {
+			return []SignatureMatch{result}, nil
 		}
-		PPC_BE_sig.compiledRegex = ppcBEreg
 	}
 
-	for _, match := range FindRegex(data, ppcBEreg) {
+	var riscv64reg = RISCV64_sig.compiledRegex
+
+	sigMatches, err = findSignature(find, runs("riscv64"), riscv64reg, RISCV64_sig.byteOrder, imageOrder)
+	if err != nil {
+		return nil, err
+	}
+	diag.hits("riscv64", len(sigMatches))
+	for _, match := range sigMatches {
 		sigPtr := uint64(match[0]) // from int
-		moduleDataPtrHi := int64(binary.BigEndian.Uint16(data[sigPtr+PPC_BE_sig.moduleDataPtrHi:][:2]))
-		// addi takes a signed immediate
-		moduleDataPtrLo := int64(int16(binary.BigEndian.Uint16(data[sigPtr+PPC_BE_sig.moduleDataPtrLo:][:2])))
-		moduleDataIpOffset := uint64((moduleDataPtrHi << 16) + moduleDataPtrLo)
-		matches = append(matches, SignatureMatch{
-			moduleDataIpOffset,
-		})
+
+		auipcBytes, auipcOk := read(sigPtr+RISCV64_sig.moduleDataPtrAUIPC, 4)
+		addiBytes, addiOk := read(sigPtr+RISCV64_sig.moduleDataPtrADDI, 4)
+		if !auipcOk || !addiOk {
+			continue
+		}
+		auipc := RISCV64_sig.byteOrder.Uint32(auipcBytes)
+		addi := RISCV64_sig.byteOrder.Uint32(addiBytes)
+
+		// the addi must add to the register the auipc wrote, auipc rd is bits 7-11, addi rs1 bits 15-19
+		if (auipc>>7)&0x1F != (addi>>15)&0x1F {
+			continue
+		}
+
+		// both immediates are signed, the upper 20 bits sit in place and the lower 12 are the top of the addi
+		upper := int64(int32(auipc & 0xFFFFF000))
+		lower := int64(int32(addi) >> 20)
+		result := found("riscv64", sigPtr, uint64(int64(sigPtr+sectionBase)+upper+lower))
+		if accept(result) {
+			return []SignatureMatch{result}, nil
+		}
 	}
 
-	return matches
+	var loong64reg = LOONG64_sig.compiledRegex
+
+	sigMatches, err = findSignature(find, runs("loong64"), loong64reg, LOONG64_sig.byteOrder, imageOrder)
+	if err != nil {
+		return nil, err
+	}
+	diag.hits("loong64", len(sigMatches))
+	for _, match := range sigMatches {
+		sigPtr := uint64(match[0]) // from int
+
+		pcalauBytes, pcalauOk := read(sigPtr+LOONG64_sig.moduleDataPtrPCALAU12I, 4)
+		addiBytes, addiOk := read(sigPtr+LOONG64_sig.moduleDataPtrADDID, 4)
+		if !pcalauOk || !addiOk {
+			continue
+		}
+		pcalau := LOONG64_sig.byteOrder.Uint32(pcalauBytes)
+		addi := LOONG64_sig.byteOrder.Uint32(addiBytes)
+
+		// pcalau12i is opcode 0b0001101 in bits 25-31, addi.d 0b0000001011 in bits 22-31.
+		// The addi.d must add to the register the pcalau12i wrote, rd is bits 0-4 and rj bits 5-9
+		if pcalau>>25 != 0x0D || addi>>22 != 0x0B || pcalau&0x1F != (addi>>5)&0x1F {
+			continue
+		}
+
+		// both immediates are signed, si20 counts pages from the page of the pcalau12i
+		pages := int64(int32(pcalau<<7) >> 12)
+		pageOff := int64(int32(addi<<10) >> 20)
+		page := int64((sigPtr+sectionBase)&0xFFFFFFFFFFFFF000) + pages<<12
+		result := found("loong64", sigPtr, uint64(page+pageOff))
+		if accept(result) {
+			return []SignatureMatch{result}, nil
+		}
+	}
+
+	var s390xreg = S390X_sig.compiledRegex
+
+	sigMatches, err = findSignature(find, runs("s390x"), s390xreg, S390X_sig.byteOrder, imageOrder)
+	if err != nil {
+		return nil, err
+	}
+	diag.hits("s390x", len(sigMatches))
+	for _, match := range sigMatches {
+		sigPtr := uint64(match[0]) // from int
+
+		larl, larlOk := read(sigPtr+S390X_sig.moduleDataPtrLARL, 6)
+		cmp, cmpOk := read(sigPtr+S390X_sig.moduleDataPtrCMP, 2)
+		if !larlOk || !cmpOk {
+			continue
+		}
+
+		// C0 is shared by the RIL instructions, LARL has a zero low nibble. The compare must test the register it loaded, R1 is the high nibble of both
+		if larl[1]&0xF != 0 || larl[1]>>4 != cmp[1]>>4 {
+			continue
+		}
+
+		// the displacement counts halfwords from the start of the larl
+		disp := int64(int32(S390X_sig.byteOrder.Uint32(larl[2:]))) * 2
+		result := found("s390x", sigPtr, uint64(int64(sigPtr+sectionBase)+disp))
+		if accept(result) {
+			return []SignatureMatch{result}, nil
+		}
+	}
+
+	for _, ppcSig := range []*signatureModuleDataInitPPC{&PPC_BE_sig, &PPC64LE_sig} {
+		var ppcreg = ppcSig.compiledRegex
+
+		name := "ppc64be"
+		if ppcSig.byteOrder == binary.LittleEndian {
+			name = "ppc64le"
+		}
+
+		sigMatches, err = findSignature(find, runs(name), ppcreg, ppcSig.byteOrder, imageOrder)
+		if err != nil {
+			return nil, err
+		}
+		diag.hits(name, len(sigMatches))
+		for _, match := range sigMatches {
+			sigPtr := uint64(match[0]) // from int
+			hiBytes, hiOk := read(sigPtr+ppcSig.moduleDataPtrHi, 4)
+			loBytes, loOk := read(sigPtr+ppcSig.moduleDataPtrLo, 4)
+			if !hiOk || !loOk {
+				continue
+			}
+			addis := ppcSig.byteOrder.Uint32(hiBytes)
+			addi := ppcSig.byteOrder.Uint32(loBytes)
+
+			// addis is opcode 15 and addi 14. addi rD, rA must add to the register the addis wrote, rD is bits 21-25 and rA bits 16-20
+			if addis>>26 != 15 || addi>>26 != 14 || (addis>>21)&0x1F != (addi>>16)&0x1F {
+				continue
+			}
+
+			// addi takes a signed immediate
+			moduleDataPtrLo := int64(int16(addi & 0xFFFF))
+			var moduleDataIpOffset uint64
+			switch base := (addis >> 16) & 0x1F; {
+			case base == 0:
+				// lis, the halves are the absolute address
+				moduleDataPtrHi := int64(addis & 0xFFFF)
+				moduleDataIpOffset = uint64((moduleDataPtrHi << 16) + moduleDataPtrLo)
+			case base == 2 && toc != 0:
+				// addis from r2, the TOC pointer. Both halves are signed offsets from it
+				moduleDataPtrHi := int64(int16(addis & 0xFFFF))
+				moduleDataIpOffset = uint64(int64(toc) + (moduleDataPtrHi << 16) + moduleDataPtrLo)
+			default:
+				continue
+			}
+
+			result := found(name, sigPtr, moduleDataIpOffset)
+			if accept(result) {
+				return []SignatureMatch{result}, nil
+			}
+		}
+	}
+
+	for _, mipsSig := range []*signatureModuleDataInitMIPS{&MIPS_BE_sig, &MIPS_LE_sig} {
+		var mipsreg = mipsSig.compiledRegex
+
+		name := "mips"
+		if mipsSig.byteOrder == binary.LittleEndian {
+			name = "mipsle"
+		}
+
+		sigMatches, err = findSignature(find, runs(name), mipsreg, mipsSig.byteOrder, imageOrder)
+		if err != nil {
+			return nil, err
+		}
+		diag.hits(name, len(sigMatches))
+		for _, match := range sigMatches {
+			sigPtr := uint64(match[0]) // from int
+			hiBytes, hiOk := read(sigPtr+mipsSig.moduleDataPtrHi, 4)
+			loBytes, loOk := read(sigPtr+mipsSig.moduleDataPtrLo, 4)
+			if !hiOk || !loOk {
+				continue
+			}
+			lui := mipsSig.byteOrder.Uint32(hiBytes)
+			addiu := mipsSig.byteOrder.Uint32(loBytes)
+
+			// addiu rt, rs, imm must add to the register the lui wrote, lui rt and addiu rs are both bits 16-20 and 21-25
+			if (lui>>16)&0x1F != (addiu>>21)&0x1F {
+				continue
+			}
+
+			moduleDataPtrHi := int64(lui & 0xFFFF)
+			// addiu takes a signed immediate
+			moduleDataPtrLo := int64(int16(addiu & 0xFFFF))
+			result := found(name, sigPtr, uint64(uint32((moduleDataPtrHi<<16)+moduleDataPtrLo)))
+			if accept(result) {
+				return []SignatureMatch{result}, nil
+			}
+		}
+	}
+
+	for _, mipsSig := range []*signatureModuleDataInitMIPS64{&MIPS64_BE_sig, &MIPS64_LE_sig} {
+		var mipsreg = mipsSig.compiledRegex
+
+		name := "mips64"
+		if mipsSig.byteOrder == binary.LittleEndian {
+			name = "mips64le"
+		}
+
+		sigMatches, err = findSignature(find, runs(name), mipsreg, mipsSig.byteOrder, imageOrder)
+		if err != nil {
+			return nil, err
+		}
+		diag.hits(name, len(sigMatches))
+		for _, match := range sigMatches {
+			sigPtr := uint64(match[0]) // from int
+			hiBytes, hiOk := read(sigPtr+mipsSig.moduleDataPtrHi, 4)
+			sbBytes, sbOk := read(sigPtr+mipsSig.moduleDataPtrSB, 4)
+			loBytes, loOk := read(sigPtr+mipsSig.moduleDataPtrLo, 4)
+			if !hiOk || !sbOk || !loOk {
+				continue
+			}
+			lui := mipsSig.byteOrder.Uint32(hiBytes)
+			daddu := mipsSig.byteOrder.Uint32(sbBytes)
+			daddiu := mipsSig.byteOrder.Uint32(loBytes)
+
+			// lui rt -> daddu rd, rs, r28 -> daddiu rt, rs must be one register chain, the daddu rs/rt/rd are bits 21-25, 16-20 and 11-15
+			reg := (lui >> 16) & 0x1F
+			if (daddu>>21)&0x1F != reg || (daddu>>16)&0x1F != 28 || (daddu>>11)&0x1F != (daddiu>>21)&0x1F {
+				continue
+			}
+
+			// the linker relocates these as a 32 bit address. lui sign extends into the upper word on real hardware, which would turn
+			// 0x8xxxxxxx into 0xffffffff8xxxxxxx, so the halves are combined in 32 bits and zero extended instead
+			moduleDataPtrHi := int64(lui & 0xFFFF)
+			// daddiu takes a signed immediate
+			moduleDataPtrLo := int64(int16(daddiu & 0xFFFF))
+			result := found(name, sigPtr, uint64(uint32((moduleDataPtrHi<<16)+moduleDataPtrLo)))
+			if accept(result) {
+				return []SignatureMatch{result}, nil
+			}
+		}
+	}
+
+	for i := range custom {
+		customSig := &custom[i]
+		enabled := len(customSig.Goarch) == 0 || len(goarch) == 0 || customSig.Goarch == goarch
+		sigMatches, err = findSignature(find, enabled, customSig.compiledRegex, customSig.byteOrder, imageOrder)
+		if err != nil {
+			return nil, err
+		}
+		diag.hits(customSig.Name, len(sigMatches))
+		for _, match := range sigMatches {
+			sigPtr := uint64(match[0]) // from int
+			moduleDataVA, ok := customSig.decode(read, sectionBase, sigPtr)
+			if !ok {
+				continue
+			}
+			result := found(customSig.Name, sigPtr, moduleDataVA)
+			if accept(result) {
+				return []SignatureMatch{result}, nil
+			}
+		}
+	}
+
+	return matches, nil
 }
@@ -0,0 +1,565 @@
+package objfile
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestFindModuleInitPCHeaderByteOrder(t *testing.T) {
+	// lea rcx, [rip+0x100]; jmp short; mov rcx, [rcx+0x230]
+	x64 := []byte{0x48, 0x8D, 0x0D, 0x00, 0x01, 0x00, 0x00, 0xEB, 0x0D, 0x48, 0x8B, 0x89, 0x30, 0x02, 0x00, 0x00}
+	// lis r4, 0x2c; addi r4, r4, -0x8000; b; ld r4, 0x230(r4); cmpd r4, r0; beq
+	ppc := []byte{0x3C, 0x80, 0x00, 0x2C, 0x38, 0x84, 0x80, 0x00, 0x48, 0x00, 0x00, 0x08, 0xE8, 0x84, 0x02, 0x30, 0x7C, 0x24, 0x00, 0x00, 0x41, 0x82, 0x01, 0xA8}
+
+	cases := []struct {
+		name       string
+		data       []byte
+		imageOrder binary.ByteOrder
+		expected   []uint64
+	}{
+		{"x64 on LE", x64, binary.LittleEndian, []uint64{0x401107}},
+		{"x64 on BE", x64, binary.BigEndian, nil},
+		{"ppc64 on BE", ppc, binary.BigEndian, []uint64{0x2B8000}},
+		{"ppc64 on LE", ppc, binary.LittleEndian, nil},
+		{"ppc64 unknown order", ppc, nil, []uint64{0x2B8000}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			matches := findModuleInitPCHeader(c.data, 0x401000, c.imageOrder, 0, nil, nil)
+			if len(matches) != len(c.expected) {
+				t.Fatalf("expected %d matches, got %+v", len(c.expected), matches)
+			}
+
+			for i, match := range matches {
+				if match.moduleDataVA != c.expected[i] {
+					t.Errorf("expected moduledata 0x%x, got 0x%x", c.expected[i], match.moduleDataVA)
+				}
+			}
+		})
+	}
+}
+
+func TestFindModuleInitPCHeaderGoarch(t *testing.T) {
+	x64 := []byte{0x48, 0x8D, 0x0D, 0x00, 0x01, 0x00, 0x00, 0xEB, 0x0D, 0x48, 0x8B, 0x89, 0x30, 0x02, 0x00, 0x00}
+	ppc := []byte{0x3C, 0x80, 0x00, 0x2C, 0x38, 0x84, 0x80, 0x00, 0x48, 0x00, 0x00, 0x08, 0xE8, 0x84, 0x02, 0x30, 0x7C, 0x24, 0x00, 0x00, 0x41, 0x82, 0x01, 0xA8}
+	data := append(append([]byte{}, x64...), ppc...)
+
+	cases := []struct {
+		goarch   string
+		expected []uint64
+	}{
+		{"", []uint64{0x2B8000, 0x401107}},
+		{"amd64", []uint64{0x401107}},
+		{"ppc64", []uint64{0x2B8000}},
+		{"arm64", nil},
+		// no signature for it, so everything is tried
+		{"wasm", []uint64{0x2B8000, 0x401107}},
+	}
+
+	for _, c := range cases {
+		var found []uint64
+		for _, match := range findModuleInitPCHeaderWorkers(nil, data, 0x401000, nil, c.goarch, nil, 0, 1, nil, nil) {
+			found = append(found, match.moduleDataVA)
+		}
+		if !reflect.DeepEqual(found, c.expected) {
+			t.Errorf("%q: expected %x, got %x", c.goarch, c.expected, found)
+		}
+	}
+}
+
+func TestFindModuleInitPCHeaderReader(t *testing.T) {
+	x64 := []byte{0x48, 0x8D, 0x0D, 0x00, 0x01, 0x00, 0x00, 0xEB, 0x0D, 0x48, 0x8B, 0x89, 0x30, 0x02, 0x00, 0x00}
+
+	// signatures at the start, straddling every small window boundary, and flush with the end
+	data := make([]byte, 0x200)
+	for _, off := range []int{0, 0x3b, 0x79, 0x100, len(data) - len(x64)} {
+		copy(data[off:], x64)
+	}
+
+	expected := findModuleInitPCHeader(data, 0x401000, binary.LittleEndian, 0, nil, nil)
+	if len(expected) != 5 {
+		t.Fatalf("expected 5 matches from the buffered scan, got %d", len(expected))
+	}
+
+	for _, window := range []int{1, 17, 64, 0x1000} {
+		matches, err := findModuleInitPCHeaderReader(nil, bytes.NewReader(data), int64(len(data)), 0x401000, binary.LittleEndian, "", nil, 0, window, nil, nil)
+		if err != nil {
+			t.Fatalf("window %d: %s", window, err)
+		}
+		if !reflect.DeepEqual(matches, expected) {
+			t.Errorf("window %d: expected %+v, got %+v", window, expected, matches)
+		}
+	}
+}
+
+func TestFindModuleInitPCHeaderFirstMatch(t *testing.T) {
+	x64 := []byte{0x48, 0x8D, 0x0D, 0x00, 0x01, 0x00, 0x00, 0xEB, 0x0D, 0x48, 0x8B, 0x89, 0x30, 0x02, 0x00, 0x00}
+	data := make([]byte, 0x100)
+	for _, off := range []int{0, 0x40, 0x80} {
+		copy(data[off:], x64)
+	}
+
+	// the three signatures resolve to 0x401107, 0x401147 and 0x401187, only the second moduledata points at a pcHeader
+	memory := map[uint64][]byte{
+		0x401107: {0x00, 0x10, 0x50, 0x00, 0x00, 0x00, 0x00, 0x00},
+		0x401147: {0x00, 0x20, 0x50, 0x00, 0x00, 0x00, 0x00, 0x00},
+		0x401187: {0x00, 0x20, 0x50, 0x00, 0x00, 0x00, 0x00, 0x00},
+		0x501000: {0xde, 0xad, 0xbe, 0xef, 0x00, 0x00, 0x01, 0x08},
+		0x502000: {0xf1, 0xff, 0xff, 0xff, 0x00, 0x00, 0x01, 0x08},
+	}
+	readMemory := func(VA uint64, size uint64) ([]byte, error) {
+		data, ok := memory[VA]
+		if !ok {
+			return nil, fmt.Errorf("unmapped")
+		}
+		return data[:size], nil
+	}
+
+	if matches := findModuleInitPCHeader(data, 0x401000, binary.LittleEndian, 0, nil, nil); len(matches) != 3 {
+		t.Errorf("exhaustive scan: expected 3 matches, got %+v", matches)
+	}
+
+	matches := findModuleInitPCHeader(data, 0x401000, binary.LittleEndian, 0, pcHeaderValidator(true, readMemory), nil)
+	if len(matches) != 1 || matches[0].moduleDataVA != 0x401147 {
+		t.Errorf("first match: expected only 0x401147, got %+v", matches)
+	}
+
+	// nothing validates, every match is kept
+	delete(memory, 0x502000)
+	if matches := findModuleInitPCHeader(data, 0x401000, binary.LittleEndian, 0, pcHeaderValidator(true, readMemory), nil); len(matches) != 3 {
+		t.Errorf("no valid match: expected 3 matches, got %+v", matches)
+	}
+
+	if pcHeaderValidator(false, readMemory) != nil {
+		t.Errorf("validator returned while disabled")
+	}
+}
+
+func TestModuleDataVARelativeAndAbsolute(t *testing.T) {
+	// from a go1.16.15 linux/amd64 build, lea rcx, [rip+0x40ae6a] at 0x41b76f. runtime.firstmoduledata is at 0x8265e0
+	x64 := []byte{0x48, 0x8d, 0x0d, 0x6a, 0xae, 0x40, 0x00, 0xeb, 0x0a, 0x48, 0x8b, 0x89, 0x10, 0x02, 0x00, 0x00}
+	// from a go1.16.15 linux/386 build, lea ecx, ds:0x83c4540 at 0x807c975. runtime.firstmoduledata is at 0x83c4540
+	x86 := []byte{0x8d, 0x0d, 0x40, 0x45, 0x3c, 0x08, 0xeb, 0x1a, 0x89, 0x4c, 0x24, 0x1c, 0x89, 0x0c, 0x24, 0xe8, 0x57, 0x6a, 0x01, 0x00,
+		0x8b, 0x44, 0x24, 0x1c, 0x8b, 0x88, 0x08, 0x01, 0x00, 0x00, 0x8b, 0x44, 0x24, 0x20, 0x85, 0xc9, 0x75, 0xe2}
+
+	if matches := findModuleInitPCHeader(x64, 0x41b76f, binary.LittleEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x8265e0 {
+		t.Errorf("x64: expected 0x8265e0, got %+v", matches)
+	}
+
+	// the absolute pointer is the VA wherever the section sits
+	for _, sectionBase := range []uint64{0x807c975, 0x8049000, 0} {
+		if matches := findModuleInitPCHeader(x86, sectionBase, binary.LittleEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x83c4540 {
+			t.Errorf("x86 at 0x%x: expected 0x83c4540, got %+v", sectionBase, matches)
+		}
+	}
+}
+
+func TestModuleDataRISCV64(t *testing.T) {
+	// from a go1.22.12 linux/riscv64 build, auipc t0, 0x31f at 0x64688 then addi t0, t0, -232. runtime.firstmoduledata is at 0x3835a0
+	rv := []byte{0x97, 0xf2, 0x31, 0x00, 0x93, 0x82, 0x82, 0xf1, 0x6f, 0x00, 0x80, 0x00, 0x83, 0xb2, 0x82, 0x24}
+
+	// stripped hello worlds, the loop over the modules is inlined in several places and the register varies
+	cases := []struct {
+		name        string
+		data        []byte
+		sectionBase uint64
+		expected    uint64
+	}{
+		{"go1.22 t0", rv, 0x64688, 0x3835a0},
+		{"go1.22 t2", []byte{0x97, 0x13, 0x32, 0x00, 0x93, 0x83, 0x43, 0x2a, 0x6f, 0x00, 0x80, 0x00, 0x83, 0xb3, 0x83, 0x24}, 0x622fc, 0x3835a0},
+		{"go1.16 t0", []byte{0x97, 0x12, 0x3a, 0x00, 0x93, 0x82, 0x02, 0x65, 0x6f, 0x00, 0x80, 0x00, 0x83, 0xb2, 0x02, 0x21}, 0x68090, 0x4096e0},
+		{"go1.16 t1 negative addi", []byte{0x17, 0x13, 0x3a, 0x00, 0x13, 0x03, 0x83, 0xae, 0x6f, 0x00, 0x80, 0x00, 0x03, 0x33, 0x03, 0x21}, 0x68bf8, 0x4096e0},
+	}
+	for _, c := range cases {
+		if matches := findModuleInitPCHeader(c.data, c.sectionBase, binary.LittleEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != c.expected {
+			t.Errorf("riscv64 %s: expected 0x%x, got %+v", c.name, c.expected, matches)
+		}
+	}
+
+	// the addi must build on the register the auipc wrote, addi t1, t1, -232 doesn't
+	mismatched := append([]byte{}, rv...)
+	copy(mismatched[4:8], []byte{0x13, 0x03, 0x83, 0xf1})
+	if matches := findModuleInitPCHeader(mismatched, 0x64688, binary.LittleEndian, 0, nil, nil); len(matches) != 0 {
+		t.Errorf("riscv64: expected no match for mismatched registers, got %+v", matches)
+	}
+}
+
+func TestFindModuleInitPCHeaderWorkers(t *testing.T) {
+	x64 := []byte{0x48, 0x8d, 0x0d, 0x6a, 0xae, 0x40, 0x00, 0xeb, 0x0a, 0x48, 0x8b, 0x89, 0x10, 0x02, 0x00, 0x00}
+
+	// four workers get a chunk of minScanChunk bytes each, the signatures straddle the chunk boundaries. The later a signature the lower
+	// its moduledata so the order of the matches isn't the offset order
+	data := make([]byte, 4*minScanChunk)
+	for i, boundary := range []int{minScanChunk - 8, 2*minScanChunk - 1, 3 * minScanChunk, len(data) - len(x64)} {
+		copy(data[boundary:], x64)
+		data[boundary+3] = byte(i)
+		data[boundary+5] = byte(0x80 - 0x20*i)
+	}
+
+	expected := findModuleInitPCHeader(data, 0x10000, binary.LittleEndian, 0, nil, nil)
+	if len(expected) != 4 {
+		t.Fatalf("expected 4 matches, got %+v", expected)
+	}
+	for i := 1; i < len(expected); i++ {
+		if expected[i-1].moduleDataVA >= expected[i].moduleDataVA || expected[i-1].matchOffset <= expected[i].matchOffset {
+			t.Errorf("expected the matches sorted by moduledata VA, got %+v", expected)
+		}
+	}
+	for _, workers := range []int{0, 2, 4, 16} {
+		if matches := findModuleInitPCHeaderWorkers(nil, data, 0x10000, binary.LittleEndian, "amd64", nil, 0, workers, nil, nil); !reflect.DeepEqual(matches, expected) {
+			t.Errorf("%d workers: expected %+v, got %+v", workers, expected, matches)
+		}
+	}
+
+	// a canceled scan stops before the first signature
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if matches := findModuleInitPCHeaderWorkers(ctx, data, 0x10000, binary.LittleEndian, "amd64", nil, 0, 4, nil, nil); len(matches) != 0 {
+		t.Errorf("expected no matches once canceled, got %+v", matches)
+	}
+}
+
+func TestFindModuleInitPCHeaderDiagnostics(t *testing.T) {
+	x64 := []byte{0x48, 0x8D, 0x0D, 0x00, 0x01, 0x00, 0x00, 0xEB, 0x0D, 0x48, 0x8B, 0x89, 0x30, 0x02, 0x00, 0x00}
+	data := make([]byte, 0x100)
+	for _, off := range []int{0, 0x40, 0x80} {
+		copy(data[off:], x64)
+	}
+
+	// only the moduledata at 0x401147 points at a pcHeader
+	memory := map[uint64][]byte{
+		0x401147: {0x00, 0x20, 0x50, 0x00, 0x00, 0x00, 0x00, 0x00},
+		0x502000: {0xf1, 0xff, 0xff, 0xff, 0x00, 0x00, 0x01, 0x08},
+	}
+	readMemory := func(VA uint64, size uint64) ([]byte, error) {
+		data, ok := memory[VA]
+		if !ok {
+			return nil, fmt.Errorf("unmapped")
+		}
+		return data[:size], nil
+	}
+
+	diag := newScanDiagnostics(readMemory)
+	diag.section(".text", 0x401000, uint64(len(data)), true, 0)
+	if matches := findModuleInitPCHeader(data, 0x401000, binary.LittleEndian, 0, nil, diag); len(matches) != 3 {
+		t.Errorf("expected the diagnostics not to filter, got %+v", matches)
+	}
+
+	result := diag.snapshot()
+	if len(result.Sections) != 1 || result.Sections[0] != (SectionDiagnostic{".text", 0x401000, 0x100, true, 0}) {
+		t.Errorf("unexpected sections %+v", result.Sections)
+	}
+	for _, sig := range result.Signatures {
+		expected := SignatureDiagnostic{Signature: sig.Signature}
+		if sig.Signature == "x64" {
+			expected = SignatureDiagnostic{"x64", 3, 1}
+		}
+		if sig != expected {
+			t.Errorf("expected %+v, got %+v", expected, sig)
+		}
+	}
+	expectedMatches := []MatchDiagnostic{
+		{"x64", ".text", 0, 0x401000, 0x401107, false, 0},
+		{"x64", ".text", 0x40, 0x401040, 0x401147, true, 0},
+		{"x64", ".text", 0x80, 0x401080, 0x401187, false, 0},
+	}
+	if !reflect.DeepEqual(result.Matches, expectedMatches) {
+		t.Errorf("expected matches %+v, got %+v", expectedMatches, result.Matches)
+	}
+
+	diag.reset()
+	if result := diag.snapshot(); len(result.Sections) != 0 || len(result.Matches) != 0 || len(result.Signatures) != len(diagnosticSignatures) || result.Signatures[0].Hits != 0 {
+		t.Errorf("reset kept %+v", result)
+	}
+
+	var disabled *scanDiagnostics
+	findModuleInitPCHeader(data, 0x401000, binary.LittleEndian, 0, nil, disabled)
+	if disabled.snapshot() != nil {
+		t.Errorf("disabled diagnostics returned a snapshot")
+	}
+}
+
+func TestFindModuleInitPCHeaderTruncated(t *testing.T) {
+	x64 := []byte{0x48, 0x8d, 0x0d, 0x6a, 0xae, 0x40, 0x00, 0xeb, 0x0a, 0x48, 0x8b, 0x89, 0x10, 0x02, 0x00, 0x00}
+	x86 := []byte{0x8d, 0x0d, 0x40, 0x45, 0x3c, 0x08, 0xeb, 0x1a, 0x89, 0x4c, 0x24, 0x1c, 0x89, 0x0c, 0x24, 0xe8, 0x57, 0x6a, 0x01, 0x00,
+		0x8b, 0x44, 0x24, 0x1c, 0x8b, 0x88, 0x08, 0x01, 0x00, 0x00, 0x8b, 0x44, 0x24, 0x20, 0x85, 0xc9, 0x75, 0xe2}
+	rv := []byte{0x97, 0xf2, 0x31, 0x00, 0x93, 0x82, 0x82, 0xf1, 0x6f, 0x00, 0x80, 0x00, 0x83, 0xb2, 0x82, 0x24}
+	// ldr r0, [pc, #0x10], the literal pool would be 0x18 bytes in, past the end of the match
+	arm32 := []byte{0x10, 0x00, 0x9f, 0xe5, 0x00, 0x00, 0x00, 0xea, 0x00, 0x00, 0x90, 0xe5, 0x00, 0x00, 0x50, 0xe3, 0x00, 0x00, 0x00, 0x0a}
+
+	cases := []struct {
+		name     string
+		sig      []byte
+		complete int // matches when the whole signature is in the buffer
+	}{
+		{"x64", x64, 1},
+		{"x86", x86, 1},
+		{"riscv64", rv, 1},
+		{"arm32", arm32, 0},
+	}
+
+	for _, c := range cases {
+		// the signature ends the buffer, then loses its last bytes one by one
+		for cut := 0; cut <= len(c.sig); cut++ {
+			data := append(make([]byte, 0x40), c.sig[:len(c.sig)-cut]...)
+			expected := 0
+			if cut == 0 {
+				expected = c.complete
+			}
+
+			if matches := findModuleInitPCHeader(data, 0x401000, binary.LittleEndian, 0, nil, nil); len(matches) != expected {
+				t.Errorf("%s cut %d: expected %d matches, got %+v", c.name, cut, expected, matches)
+			}
+
+			matches, err := findModuleInitPCHeaderReader(nil, bytes.NewReader(data), int64(len(data)), 0x401000, binary.LittleEndian, "", nil, 0, 0x10, nil, nil)
+			if err != nil || len(matches) != expected {
+				t.Errorf("%s cut %d reader: expected %d matches, got %+v %v", c.name, cut, expected, matches, err)
+			}
+		}
+	}
+}
+
+func TestModuleDataARM64(t *testing.T) {
+	// from a stripped go1.22.12 linux/arm64 build, adrp x1 at 0x66dcc then add x1, x1, #0x5c0. runtime.firstmoduledata is at 0x3935c0
+	arm64 := []byte{0x61, 0x19, 0x00, 0xb0, 0x21, 0x00, 0x17, 0x91, 0x02, 0x00, 0x00, 0x14, 0x21, 0x24, 0x41, 0xf9, 0x01, 0x01, 0x00, 0xb4}
+	if matches := findModuleInitPCHeader(arm64, 0x66dcc, binary.LittleEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x3935c0 {
+		t.Errorf("arm64: expected 0x3935c0, got %+v", matches)
+	}
+
+	// adrp x1, -0x32d000 from the same page lands below it
+	backwards := append([]byte{}, arm64...)
+	copy(backwards[0:4], []byte{0x81, 0xe6, 0xff, 0xf0})
+	if matches := findModuleInitPCHeader(backwards, 0x40066dcc, binary.LittleEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x40066dcc&^0xfff-0x32d000+0x5c0 {
+		t.Errorf("arm64 negative page: got %+v", matches)
+	}
+
+	// add x1, x2, #0x5c0 doesn't use the adrp result
+	mismatched := append([]byte{}, arm64...)
+	copy(mismatched[4:8], []byte{0x41, 0x00, 0x17, 0x91})
+	if matches := findModuleInitPCHeader(mismatched, 0x66dcc, binary.LittleEndian, 0, nil, nil); len(matches) != 0 {
+		t.Errorf("arm64: expected no match for mismatched registers, got %+v", matches)
+	}
+}
+
+func TestModuleDataARM32(t *testing.T) {
+	// from a go1.22.12 linux/arm GOARM=5 build, ldr r0, [pc, #0x198] at 0x758a8. runtime.firstmoduledata is at 0x3c21c8
+	pool := []byte{0x98, 0x01, 0x9f, 0xe5, 0x00, 0x00, 0x00, 0xea, 0x24, 0x01, 0x90, 0xe5, 0x00, 0x00, 0x50, 0xe3, 0x06, 0x00, 0x00, 0x0a}
+	data := make([]byte, 0x1a4)
+	copy(data, pool)
+	binary.LittleEndian.PutUint32(data[0x1a0:], 0x3c21c8)
+	if matches := findModuleInitPCHeader(data, 0x758a8, binary.LittleEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x3c21c8 {
+		t.Errorf("arm32 literal pool: expected 0x3c21c8, got %+v", matches)
+	}
+
+	// the same loop with the address built by movw r0, #0x21c8 and movt r0, #0x3c
+	movw := []byte{0xc8, 0x01, 0x02, 0xe3, 0x3c, 0x00, 0x40, 0xe3, 0x00, 0x00, 0x00, 0xea, 0x24, 0x01, 0x90, 0xe5, 0x00, 0x00, 0x50, 0xe3, 0x06, 0x00, 0x00, 0x0a}
+	if matches := findModuleInitPCHeader(movw, 0x758a8, binary.LittleEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x3c21c8 {
+		t.Errorf("arm32 movw/movt: expected 0x3c21c8, got %+v", matches)
+	}
+
+	// movt r1 writes another register than the movw
+	mismatched := append([]byte{}, movw...)
+	mismatched[5] = 0x10
+	if matches := findModuleInitPCHeader(mismatched, 0x758a8, binary.LittleEndian, 0, nil, nil); len(matches) != 0 {
+		t.Errorf("arm32 movw/movt: expected no match for mismatched registers, got %+v", matches)
+	}
+}
+
+func TestModuleDataMIPS(t *testing.T) {
+	// from a go1.22.12 linux/mips softfloat build, lui at, 0x43 at 0x8b254 then addiu at, at, 0x25a0. runtime.firstmoduledata is at 0x4325a0
+	be := []byte{0x3c, 0x01, 0x00, 0x43, 0x24, 0x21, 0x25, 0xa0, 0x10, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x8c, 0x21, 0x01, 0x24, 0x10, 0x20, 0x00, 0x0b}
+	// the mipsle build of the same program
+	le := []byte{0x43, 0x00, 0x01, 0x3c, 0xa0, 0x25, 0x21, 0x24, 0x02, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x00, 0x24, 0x01, 0x21, 0x8c, 0x0b, 0x00, 0x20, 0x10}
+
+	if matches := findModuleInitPCHeader(be, 0x8b254, binary.BigEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x4325a0 {
+		t.Errorf("mips: expected 0x4325a0, got %+v", matches)
+	}
+	if matches := findModuleInitPCHeader(le, 0x8b1b4, binary.LittleEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x4325a0 {
+		t.Errorf("mipsle: expected 0x4325a0, got %+v", matches)
+	}
+
+	// addiu sign extends, lui 0x44 then addiu -0x7a60 lands below 0x440000
+	negative := append([]byte{}, be...)
+	copy(negative[2:4], []byte{0x00, 0x44})
+	copy(negative[6:8], []byte{0x85, 0xa0})
+	if matches := findModuleInitPCHeader(negative, 0x8b254, binary.BigEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x4385a0 {
+		t.Errorf("mips negative low half: expected 0x4385a0, got %+v", matches)
+	}
+
+	// the little endian encoding isn't tried on a big endian image
+	if matches := findModuleInitPCHeader(le, 0x8b1b4, binary.BigEndian, 0, nil, nil); len(matches) != 0 {
+		t.Errorf("mipsle in a big endian image: expected no match, got %+v", matches)
+	}
+}
+
+func TestModuleDataMIPS64(t *testing.T) {
+	// from a stripped go1.22.12 linux/mips64le build, lui at, 0x41 at 0x8848c, daddu at, at, gp, daddiu at, at, 0x3580. runtime.firstmoduledata is at 0x413580
+	le := []byte{0x41, 0x00, 0x01, 0x3c, 0x2d, 0x08, 0x3c, 0x00, 0x80, 0x35, 0x21, 0x64, 0x02, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x00, 0x48, 0x02, 0x21, 0xdc, 0x0b, 0x00, 0x20, 0x10}
+	if matches := findModuleInitPCHeader(le, 0x8848c, binary.LittleEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x413580 {
+		t.Errorf("mips64le: expected 0x413580, got %+v", matches)
+	}
+
+	// the mips64 build is the same code word swapped
+	be := make([]byte, len(le))
+	for i := 0; i < len(le); i += 4 {
+		binary.BigEndian.PutUint32(be[i:], binary.LittleEndian.Uint32(le[i:]))
+	}
+	if matches := findModuleInitPCHeader(be, 0x8848c, binary.BigEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x413580 {
+		t.Errorf("mips64: expected 0x413580, got %+v", matches)
+	}
+
+	// lui 0x8042 then daddiu -0x7a80, the negative low half must borrow from the high half without sign extending the result
+	high := append([]byte{}, le...)
+	copy(high[0:2], []byte{0x42, 0x80})
+	copy(high[8:10], []byte{0x80, 0x85})
+	if matches := findModuleInitPCHeader(high, 0x8848c, binary.LittleEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x80418580 {
+		t.Errorf("mips64le high address: expected 0x80418580, got %+v", matches)
+	}
+
+	// daddu adding another register than the static base isn't the moduledata load
+	notSB := append([]byte{}, le...)
+	notSB[6] = 0x3b
+	if matches := findModuleInitPCHeader(notSB, 0x8848c, binary.LittleEndian, 0, nil, nil); len(matches) != 0 {
+		t.Errorf("mips64le without the static base: expected no match, got %+v", matches)
+	}
+}
+
+func TestModuleDataS390X(t *testing.T) {
+	// from a stripped go1.22.12 linux/s390x build, larl %r0 at 0x7baea with a displacement of 0x1bbdab halfwords. runtime.firstmoduledata is at 0x3f3640
+	s390x := []byte{0xc0, 0x00, 0x00, 0x1b, 0xbd, 0xab, 0xa7, 0xf4, 0x00, 0x05, 0xe3, 0x00, 0x42, 0x48, 0x00, 0x04, 0xec, 0x08, 0x00, 0x13, 0x00, 0x7c}
+	// the same loop with %r2
+	r2 := []byte{0xc0, 0x20, 0x00, 0x1b, 0xd6, 0x9f, 0xa7, 0xf4, 0x00, 0x05, 0xe3, 0x20, 0x22, 0x48, 0x00, 0x04, 0xec, 0x28, 0x00, 0x11, 0x00, 0x7c}
+
+	if matches := findModuleInitPCHeader(s390x, 0x7baea, binary.BigEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x3f3640 {
+		t.Errorf("s390x: expected 0x3f3640, got %+v", matches)
+	}
+	if matches := findModuleInitPCHeader(r2, 0x78902, binary.BigEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x3f3640 {
+		t.Errorf("s390x r2: expected 0x3f3640, got %+v", matches)
+	}
+
+	// a negative displacement counts back from the larl
+	backwards := append([]byte{}, s390x...)
+	copy(backwards[2:6], []byte{0xff, 0xff, 0xff, 0x00})
+	if matches := findModuleInitPCHeader(backwards, 0x7baea, binary.BigEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x7baea-0x200 {
+		t.Errorf("s390x negative displacement: got %+v", matches)
+	}
+
+	// brasl shares the C0 opcode byte, it isn't an address load
+	brasl := append([]byte{}, s390x...)
+	brasl[1] = 0x05
+	if matches := findModuleInitPCHeader(brasl, 0x7baea, binary.BigEndian, 0, nil, nil); len(matches) != 0 {
+		t.Errorf("s390x brasl: expected no match, got %+v", matches)
+	}
+}
+
+func TestModuleDataLOONG64(t *testing.T) {
+	// from a stripped go1.22.12 linux/loong64 build, pcalau12i r5, 0x344 at 0x6f5dc then addi.d r5, r5, 0x580. runtime.firstmoduledata is at 0x3b3580
+	loong64 := []byte{0x85, 0x68, 0x00, 0x1a, 0xa5, 0x00, 0xd6, 0x02, 0x00, 0x10, 0x00, 0x50, 0x00, 0x00, 0x40, 0x03, 0x00, 0x00, 0x40, 0x03, 0xa5, 0x20, 0xc9, 0x28, 0xa0, 0x28, 0x00, 0x40}
+
+	if matches := findModuleInitPCHeader(loong64, 0x6f5dc, binary.LittleEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x3b3580 {
+		t.Errorf("loong64: expected 0x3b3580, got %+v", matches)
+	}
+
+	// the page is taken from the pcalau12i, not the match offset within it
+	if matches := findModuleInitPCHeader(loong64, 0x6ffdc, binary.LittleEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x3b3580 {
+		t.Errorf("loong64 same page: expected 0x3b3580, got %+v", matches)
+	}
+
+	// both immediates are signed, si20 -1 and si12 -0x80 from page 0x6f000
+	negative := append([]byte{}, loong64...)
+	binary.LittleEndian.PutUint32(negative[0:], 0x1a000000|0xfffff<<5|5)
+	binary.LittleEndian.PutUint32(negative[4:], 0x02c00000|0xf80<<10|5<<5|5)
+	if matches := findModuleInitPCHeader(negative, 0x6f5dc, binary.LittleEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x6e000-0x80 {
+		t.Errorf("loong64 negative: got %+v", matches)
+	}
+
+	// addi.d from another register than the pcalau12i wrote
+	mismatched := append([]byte{}, loong64...)
+	mismatched[4] = 0xc5 // rj r6
+	if matches := findModuleInitPCHeader(mismatched, 0x6f5dc, binary.LittleEndian, 0, nil, nil); len(matches) != 0 {
+		t.Errorf("loong64 mismatched registers: expected no match, got %+v", matches)
+	}
+}
+
+func TestModuleDataPPC64LE(t *testing.T) {
+	// from a stripped go1.22.12 linux/ppc64le -buildmode=pie build, addis r4, r2, 0 at 0x725c4 then addi r4, r4, -19472. The TOC pointer is .got + 0x8000
+	pie := []byte{0x00, 0x00, 0x82, 0x3c, 0xf0, 0xb3, 0x84, 0x38, 0x08, 0x00, 0x00, 0x48, 0x48, 0x02, 0x84, 0xe8, 0x00, 0x00, 0x24, 0x7c, 0x20, 0x00, 0x82, 0x41}
+	// from a go1.16.15 linux/ppc64le build, lis r31, 0x3f at 0x620b4 then addi r3, r31, -27936
+	lis := []byte{0x3f, 0x00, 0xe0, 0x3f, 0xe0, 0x92, 0x7f, 0x38, 0x08, 0x00, 0x00, 0x48, 0x10, 0x02, 0x63, 0xe8, 0x00, 0x00, 0x23, 0x7c, 0x68, 0x01, 0x82, 0x41}
+
+	if matches := findModuleInitPCHeader(pie, 0x725c4, binary.LittleEndian, 0x4281d0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x4235c0 {
+		t.Errorf("ppc64le toc relative: expected 0x4235c0, got %+v", matches)
+	}
+	if matches := findModuleInitPCHeader(pie, 0x725c4, binary.LittleEndian, 0, nil, nil); len(matches) != 0 {
+		t.Errorf("ppc64le toc relative without a toc: expected no match, got %+v", matches)
+	}
+	if matches := findModuleInitPCHeader(lis, 0x620b4, binary.LittleEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x3e92e0 {
+		t.Errorf("ppc64le lis: expected 0x3e92e0, got %+v", matches)
+	}
+
+	// the big endian signature decodes the same words but only takes the lis r4 of the ppc64 runtime, not the r31 of ppc64le
+	be := make([]byte, len(lis))
+	for i := 0; i < len(lis); i += 4 {
+		binary.BigEndian.PutUint32(be[i:], binary.LittleEndian.Uint32(lis[i:]))
+	}
+	if matches := findModuleInitPCHeader(be, 0x620b4, binary.BigEndian, 0, nil, nil); len(matches) != 0 {
+		t.Errorf("ppc64 lis r31: expected no match, got %+v", matches)
+	}
+	binary.BigEndian.PutUint32(be[0:], 0x3c80003f) // lis r4, 0x3f
+	binary.BigEndian.PutUint32(be[4:], 0x388492e0) // addi r4, r4, -27936
+	if matches := findModuleInitPCHeader(be, 0x620b4, binary.BigEndian, 0, nil, nil); len(matches) != 1 || matches[0].moduleDataVA != 0x3e92e0 {
+		t.Errorf("ppc64 lis: expected 0x3e92e0, got %+v", matches)
+	}
+}
+
+func TestSignaturesPrecompiled(t *testing.T) {
+	sigs := map[string]*RegexAndNeedle{
+		"x64": x64sig.compiledRegex, "x86": x86sig.compiledRegex, "arm64": ARM64_sig.compiledRegex, "arm32": ARM32_sig.compiledRegex,
+		"arm32movw": ARM32_movw_sig.compiledRegex, "riscv64": RISCV64_sig.compiledRegex, "loong64": LOONG64_sig.compiledRegex, "s390x": S390X_sig.compiledRegex,
+		"ppc64be": PPC_BE_sig.compiledRegex, "ppc64le": PPC64LE_sig.compiledRegex, "mips": MIPS_BE_sig.compiledRegex, "mipsle": MIPS_LE_sig.compiledRegex,
+		"mips64": MIPS64_BE_sig.compiledRegex, "mips64le": MIPS64_LE_sig.compiledRegex,
+	}
+	for name, compiled := range sigs {
+		if compiled == nil {
+			t.Errorf("%s: not compiled at init", name)
+		}
+	}
+}
+
+// a text section sized buffer with one x64 moduledata init at the end
+func benchmarkScanData() []byte {
+	data := make([]byte, 16<<20)
+	state := uint32(1)
+	for i := range data {
+		state = state*1664525 + 1013904223
+		data[i] = byte(state >> 24)
+	}
+	x64 := []byte{0x48, 0x8d, 0x0d, 0x6a, 0xae, 0x40, 0x00, 0xeb, 0x0a, 0x48, 0x8b, 0x89, 0x10, 0x02, 0x00, 0x00}
+	copy(data[len(data)-len(x64):], x64)
+	return data
+}
+
+func BenchmarkFindModuleInitPCHeader(b *testing.B) {
+	data := benchmarkScanData()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		findModuleInitPCHeader(data, 0, nil, 0, nil, nil)
+	}
+}
+
+// the cost init pays once, which a scan would pay per section if the signatures were compiled on demand
+func BenchmarkCompileSignatures(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for _, sig := range []string{x64sig.signature, x86sig.signature, ARM64_sig.signature, ARM32_sig.signature, ARM32_movw_sig.signature, RISCV64_sig.signature,
+			LOONG64_sig.signature, S390X_sig.signature, PPC_BE_sig.signature, PPC64LE_sig.signature, MIPS_BE_sig.signature, MIPS_LE_sig.signature,
+			MIPS64_BE_sig.signature, MIPS64_LE_sig.signature} {
+			mustCompileSignature(sig)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ScanRange limits the pclntab and moduledata scans to [Start, End), VAs or with Offset file offsets, see SetScanRanges
+type ScanRange struct {
+	Start  uint64
+	End    uint64
+	Offset bool
+}
+
+func (r ScanRange) String() string {
+	return fmt.Sprintf("0x%x:0x%x", r.Start, r.End)
+}
+
+// ScanRanges is a repeatable flag of start:end ranges appended to Ranges, file offsets with Offset, ex: -scan-range 0x401000:0x480000
+type ScanRanges struct {
+	Ranges *[]ScanRange
+	Offset bool
+}
+
+func (s ScanRanges) String() string {
+	if s.Ranges == nil {
+		return ""
+	}
+	var ranges []string
+	for _, r := range *s.Ranges {
+		if r.Offset == s.Offset {
+			ranges = append(ranges, r.String())
+		}
+	}
+	return strings.Join(ranges, ", ")
+}
+
+func (s ScanRanges) Set(value string) error {
+	start, end, ok := strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("%q isn't a start:end range", value)
+	}
+	r := ScanRange{Offset: s.Offset}
+	var err error
+	if r.Start, err = strconv.ParseUint(start, 0, 64); err != nil {
+		return fmt.Errorf("bad start of %q: %w", value, err)
+	}
+	if r.End, err = strconv.ParseUint(end, 0, 64); err != nil {
+		return fmt.Errorf("bad end of %q: %w", value, err)
+	}
+	if r.End <= r.Start {
+		return fmt.Errorf("%q ends before it starts", value)
+	}
+	*s.Ranges = append(*s.Ranges, r)
+	return nil
+}
+
+// SetScanRanges limits the pclntab magic scan and the moduledata signature scan of PCLineTable to the parts of the sections within
+// ranges, the sections outside them aren't scanned at all. A match must lie within a range, the moduledata and pclntab it leads to
+// can be anywhere. The file offset ranges don't apply to dumps, which are scanned by VA. nil, the default, scans every section whole.
+func (e *Entry) SetScanRanges(ranges []ScanRange) {
+	switch f := e.raw.(type) {
+	case *elfFile:
+		f.scanRanges = ranges
+	case *machoFile:
+		f.scanRanges = ranges
+	case *peFile:
+		f.scanRanges = ranges
+	case *dumpFile:
+		f.scanRanges = ranges
+	case *wasmFile:
+		f.scanRanges = ranges
+	}
+}
+
+// a scan window is the [start, end) indexes of a section's data the scan ranges let the scans see
+type scanWindow struct {
+	start, end int
+}
+
+// scanWindows are the windows of the data of a section at VA, read from offset in the file, sorted and merged. The data is
+// dataSize bytes, it runs into the sections after it, and the windows stay within the section's own size bytes of it. A section
+// with no VA or no offset, ex: a PE overlay or a dump region, is only limited by the ranges of the other kind. No ranges is all the
+// data, as scanned before there were ranges.
+func scanWindows(ranges []ScanRange, VA uint64, hasVA bool, offset uint64, hasOffset bool, size uint64, dataSize int) []scanWindow {
+	if len(ranges) == 0 {
+		return []scanWindow{{0, dataSize}}
+	}
+	size = min(size, uint64(dataSize))
+	if size == 0 {
+		return nil
+	}
+
+	var windows []scanWindow
+	for _, r := range ranges {
+		base := VA
+		if r.Offset {
+			base = offset
+		}
+		if (r.Offset && !hasOffset) || (!r.Offset && !hasVA) || r.End <= base || r.Start >= base+size {
+			continue
+		}
+		start, end := 0, int(size)
+		if r.Start > base {
+			start = int(r.Start - base)
+		}
+		if r.End < base+size {
+			end = int(r.End - base)
+		}
+		windows = append(windows, scanWindow{start, end})
+	}
+
+	sort.Slice(windows, func(i, j int) bool { return windows[i].start < windows[j].start })
+	var merged []scanWindow
+	for _, w := range windows {
+		if last := len(merged) - 1; last >= 0 && w.start <= merged[last].end {
+			merged[last].end = max(merged[last].end, w.end)
+			continue
+		}
+		merged = append(merged, w)
+	}
+	return merged
+}
+
+// scannedBytes is the size of the windows, or 0 without ranges when a section is scanned whole
+func scannedBytes(ranges []ScanRange, windows []scanWindow) uint64 {
+	if len(ranges) == 0 {
+		return 0
+	}
+	var scanned uint64
+	for _, w := range windows {
+		scanned += uint64(w.end - w.start)
+	}
+	return scanned
+}
+
+// inWindows reports whether the index idx of a section's data is within one of its windows
+func inWindows(windows []scanWindow, idx int) bool {
+	for _, w := range windows {
+		if w.start <= idx && idx < w.end {
+			return true
+		}
+	}
+	return false
+}
+
+// findWindowOccurrences is findAllOccurrences within the windows of data, a match starting in a window may end past it
+func findWindowOccurrences(data []byte, windows []scanWindow, searches [][]byte) []int {
+	longest := 0
+	for _, search := range searches {
+		longest = max(longest, len(search))
+	}
+
+	var results []int
+	for _, w := range windows {
+		for _, idx := range findAllOccurrences(data[w.start:min(w.end+longest-1, len(data))], searches) {
+			if idx < w.end-w.start {
+				results = append(results, w.start+idx)
+			}
+		}
+	}
+	return results
+}
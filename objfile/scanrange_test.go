@@ -0,0 +1,66 @@
+package objfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanWindows(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		ranges   []ScanRange
+		hasVA    bool
+		expected []scanWindow
+	}{
+		{"no ranges", nil, true, []scanWindow{{0, 0x1800}}},
+		{"clamped", []ScanRange{{Start: 0x400800, End: 0x402000}}, true, []scanWindow{{0x800, 0x1000}}},
+		{"inside", []ScanRange{{Start: 0x400100, End: 0x400200}}, true, []scanWindow{{0x100, 0x200}}},
+		{"outside", []ScanRange{{Start: 0x402000, End: 0x403000}}, true, nil},
+		{"merged", []ScanRange{{Start: 0x400300, End: 0x400400}, {Start: 0x400100, End: 0x400200}, {Start: 0x400180, End: 0x400280}}, true, []scanWindow{{0x100, 0x280}, {0x300, 0x400}}},
+		{"offsets", []ScanRange{{Start: 0x2100, End: 0x2200, Offset: true}}, true, []scanWindow{{0x100, 0x200}}},
+		{"no VA", []ScanRange{{Start: 0x400100, End: 0x400200}, {Start: 0x2100, End: 0x2200, Offset: true}}, false, []scanWindow{{0x100, 0x200}}},
+	} {
+		windows := scanWindows(c.ranges, 0x400000, c.hasVA, 0x2000, true, 0x1000, 0x1800)
+		if !reflect.DeepEqual(windows, c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, windows)
+		}
+	}
+
+	// the ranges of a section with no bytes of its own leave nothing to scan
+	if windows := scanWindows([]ScanRange{{Start: 0x400000, End: 0x401000}}, 0x400000, true, 0x2000, true, 0, 0x1800); windows != nil {
+		t.Errorf("expected nothing of an empty section, got %v", windows)
+	}
+}
+
+func TestFindWindowOccurrences(t *testing.T) {
+	data := make([]byte, 0x100)
+	copy(data[0x10:], "\xf1\xff\xff\xff\x00\x00")
+	copy(data[0x80:], "\xf1\xff\xff\xff\x00\x00")
+	sigs := [][]byte{[]byte("\xf1\xff\xff\xff\x00\x00")}
+
+	// a match starting in a window counts even when it ends past it, one starting before doesn't
+	matches := findWindowOccurrences(data, []scanWindow{{0x12, 0x20}, {0x7e, 0x82}}, sigs)
+	if !reflect.DeepEqual(matches, []int{0x80}) {
+		t.Errorf("expected the match at 0x80, got %v", matches)
+	}
+}
+
+func TestScanRangesFlag(t *testing.T) {
+	var ranges []ScanRange
+	vas, offsets := ScanRanges{Ranges: &ranges}, ScanRanges{Ranges: &ranges, Offset: true}
+	if err := vas.Set("0x401000:0x402000"); err != nil {
+		t.Fatalf("errored: %s", err)
+	}
+	if err := offsets.Set("4096:8192"); err != nil {
+		t.Fatalf("errored: %s", err)
+	}
+	if !reflect.DeepEqual(ranges, []ScanRange{{0x401000, 0x402000, false}, {0x1000, 0x2000, true}}) || vas.String() != "0x401000:0x402000" {
+		t.Errorf("unexpected ranges %v", ranges)
+	}
+
+	for _, bad := range []string{"0x401000", "0x402000:0x401000", "text:0x401000", "0x401000:"} {
+		if err := vas.Set(bad); err == nil {
+			t.Errorf("expected %q to fail", bad)
+		}
+	}
+}
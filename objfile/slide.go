@@ -0,0 +1,137 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"github.com/mandiant/GoReSym/debug/elf"
+)
+
+// the R_*_RELATIVE type of each machine, the relocations whose field gets the load bias added to their addend
+var relativeRelocations = map[elf.Machine]uint32{
+	elf.EM_X86_64:    uint32(elf.R_X86_64_RELATIVE),
+	elf.EM_AARCH64:   uint32(elf.R_AARCH64_RELATIVE),
+	elf.EM_PPC64:     uint32(elf.R_PPC_RELATIVE),
+	elf.EM_RISCV:     uint32(elf.R_RISCV_RELATIVE),
+	elf.EM_S390:      uint32(elf.R_390_RELATIVE),
+	elf.EM_LOONGARCH: 3, // R_LARCH_RELATIVE
+}
+
+// images are mapped at page boundaries, so slides are multiples of a page
+const slidePage = 0x1000
+
+// loadExtent returns the start of the first PT_LOAD segment, rounded down to its alignment, and the end of the last one, false without any
+func loadExtent(progs []*elf.Prog) (start uint64, end uint64, ok bool) {
+	for _, p := range progs {
+		if p.Type != elf.PT_LOAD {
+			continue
+		}
+		first := p.Vaddr
+		if p.Align > 1 {
+			first -= first % p.Align
+		}
+		if !ok || first < start {
+			start = first
+		}
+		if !ok || p.Vaddr+p.Memsz > end {
+			end = p.Vaddr + p.Memsz
+		}
+		ok = true
+	}
+	return start, end, ok
+}
+
+// detectSlide returns the slide of a position independent image that read laid out at the addresses of its headers, from the RELATIVE
+// relocations of its dynamic section. The field of each holds its addend until the loader adds the slide, so the fields agreeing on how
+// far they are from their addends give it, 0 for an image that wasn't relocated. The dynamic section of a mapped image can itself have
+// been relocated, its DT_RELA is then looked for at the page offsets of the image it could have been slid from. False when nothing decides.
+func detectSlide(read func(VA uint64, size uint64) ([]byte, error), f *elf.File) (uint64, bool) {
+	relative, known := relativeRelocations[f.Machine]
+	start, end, ok := loadExtent(f.Progs)
+	if f.Type != elf.ET_DYN || f.Class != elf.ELFCLASS64 || !known || !ok {
+		return 0, false
+	}
+
+	var rela, relasz uint64
+	relaent := uint64(24)
+	for _, p := range f.Progs {
+		if p.Type != elf.PT_DYNAMIC {
+			continue
+		}
+		dynamic, err := read(p.Vaddr, p.Memsz)
+		if err != nil {
+			return 0, false
+		}
+		for ; len(dynamic) >= 16; dynamic = dynamic[16:] {
+			value := f.ByteOrder.Uint64(dynamic[8:])
+			switch elf.DynTag(f.ByteOrder.Uint64(dynamic)) {
+			case elf.DT_RELA:
+				rela = value
+			case elf.DT_RELASZ:
+				relasz = value
+			case elf.DT_RELAENT:
+				relaent = value
+			}
+		}
+	}
+	if relasz == 0 || relaent < 24 {
+		return 0, false
+	}
+
+	// relocationDelta is how far the fields of the relocations at VA are from their addends, when most of them agree
+	relocationDelta := func(VA uint64) (uint64, bool) {
+		table, err := read(VA, min(relasz, maxRelocationSamples*relaent))
+		if err != nil {
+			return 0, false
+		}
+		votes := make(map[uint64]int)
+		total := 0
+		for ; uint64(len(table)) >= relaent; table = table[relaent:] {
+			offset, info, addend := f.ByteOrder.Uint64(table), f.ByteOrder.Uint64(table[8:]), f.ByteOrder.Uint64(table[16:])
+			if uint32(info) != relative {
+				continue
+			}
+			// the relocations of the image are within it, anything else isn't a relocation table
+			if offset < start || offset >= end {
+				return 0, false
+			}
+			if field, err := read(offset, 8); err == nil && len(field) == 8 {
+				votes[f.ByteOrder.Uint64(field)-addend]++
+				total++
+			}
+		}
+		var best uint64
+		for delta, count := range votes {
+			if count > votes[best] || (count == votes[best] && delta < best) {
+				best = delta
+			}
+		}
+		return best, total > 0 && votes[best]*2 > total
+	}
+
+	if rela >= start && rela < end {
+		if delta, ok := relocationDelta(rela); ok {
+			return delta, true
+		}
+	}
+	for VA := start + (rela-start)%slidePage; VA < end; VA += slidePage {
+		// most pages don't start a table, their first entry doesn't relocate anything in the image
+		if entry, err := read(VA, 8); err != nil || len(entry) < 8 || f.ByteOrder.Uint64(entry) < start || f.ByteOrder.Uint64(entry) >= end {
+			continue
+		}
+		if delta, ok := relocationDelta(VA); ok && delta == rela-VA {
+			return delta, true
+		}
+	}
+	return 0, false
+}
+
+// Slide is the load bias the headers of a position independent ELF were moved by, detected or given by OpenOptions.Slide or LoadBase, 0 for
+// images read at the addresses of their headers and other files
+func (e *Entry) Slide() uint64 {
+	switch f := e.raw.(type) {
+	case *elfFile:
+		return f.rebaseDelta
+	case *dumpFile:
+		return f.slide
+	}
+	return 0
+}
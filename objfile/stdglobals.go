@@ -0,0 +1,231 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/mandiant/GoReSym/debug/dwarf"
+	"github.com/mandiant/GoReSym/debug/gosym"
+
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// StdGlobal is a standard library global that programs replace to reconfigure the standard library, ex: a proxy or TLS settings in http.DefaultTransport
+type StdGlobal struct {
+	Name         string
+	VA           uint64
+	Customized   bool     // assigned by code outside the standard library
+	ConcreteType string   // type assigned to an interface global, when the itab could be resolved
+	Writers      []string // functions assigning the global
+	Readers      []string // functions loading the global, ex: to type assert the default and modify it in place
+}
+
+// well known defaults. Interface globals are two words (itab, data), the rest are one pointer.
+var stdDefaultGlobals = []struct {
+	name  string
+	iface bool
+}{
+	{"net/http.DefaultTransport", true},
+	{"net/http.DefaultClient", false},
+	{"net/http.DefaultServeMux", false},
+	{"net.DefaultResolver", false},
+}
+
+const dwOpAddr = 0x03
+
+// locateGlobal finds the address of a global from the symbols, then the DWARF location of the variable
+func locateGlobal(syms []Sym, data *dwarf.Data, name string, byteOrder binary.ByteOrder, is64bit bool) (uint64, bool) {
+	for _, sym := range syms {
+		if sym.Name == name && sym.Code != 'T' && sym.Code != 't' && sym.Code != 'U' {
+			return sym.Addr, true
+		}
+	}
+
+	if data == nil {
+		return 0, false
+	}
+
+	reader := data.Reader()
+	for {
+		entry, err := reader.Next()
+		if entry == nil || err != nil {
+			return 0, false
+		}
+
+		if entry.Tag != dwarf.TagVariable {
+			continue
+		}
+
+		if varName, _ := entry.Val(dwarf.AttrName).(string); varName != name {
+			continue
+		}
+
+		// globals are located by a lone DW_OP_addr
+		loc, _ := entry.Val(dwarf.AttrLocation).([]byte)
+		if is64bit && len(loc) == 9 && loc[0] == dwOpAddr {
+			return byteOrder.Uint64(loc[1:]), true
+		} else if !is64bit && len(loc) == 5 && loc[0] == dwOpAddr {
+			return uint64(byteOrder.Uint32(loc[1:])), true
+		}
+	}
+}
+
+// FindStdGlobals locates the well known standard library defaults and scans funcs for code that reads or replaces them.
+// funcs should be the functions outside the standard library, which initializes the defaults itself. Only amd64 is supported.
+func (e *Entry) FindStdGlobals(funcs []gosym.Func, runtimeVersion string, moduleData *ModuleData, is64bit bool, littleendian bool) ([]StdGlobal, error) {
+	goarch := e.GOARCH()
+	if goarch != "amd64" {
+		return nil, fmt.Errorf("global access resolution unsupported for architecture %q", goarch)
+	}
+
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	if !littleendian {
+		byteOrder = binary.BigEndian
+	}
+
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+
+	syms, _ := e.raw.symbols()
+	data, err := e.raw.dwarf()
+	if err != nil {
+		data = nil
+	}
+
+	type globalRange struct {
+		global *StdGlobal
+		end    uint64
+		iface  bool
+	}
+
+	var globals []StdGlobal
+	var ifaces []bool
+	for _, known := range stdDefaultGlobals {
+		va, ok := locateGlobal(syms, data, known.name, byteOrder, is64bit)
+		if ok {
+			globals = append(globals, StdGlobal{Name: known.name, VA: va})
+			ifaces = append(ifaces, known.iface)
+		}
+	}
+
+	var ranges []globalRange
+	for i := range globals {
+		size := ptrSize
+		if ifaces[i] {
+			size = 2 * ptrSize
+		}
+		ranges = append(ranges, globalRange{global: &globals[i], end: globals[i].VA + size, iface: ifaces[i]})
+	}
+
+	if len(globals) == 0 {
+		return globals, nil
+	}
+
+	findRange := func(addr uint64) *globalRange {
+		for i := range ranges {
+			if addr >= ranges[i].global.VA && addr < ranges[i].end {
+				return &ranges[i]
+			}
+		}
+		return nil
+	}
+
+	// the word after the interface type in an itab is the concrete type
+	concreteType := func(itab uint64) string {
+		typeAddr, err := e.ReadPointerSizeMem(itab+ptrSize, is64bit, littleendian)
+		if err != nil || typeAddr == 0 {
+			return ""
+		}
+
+		types, err := e.ParseType(runtimeVersion, moduleData, typeAddr, is64bit, littleendian)
+		if err != nil || len(types) == 0 {
+			return ""
+		}
+		return types[0].Str
+	}
+
+	writers := make(map[*StdGlobal]map[string]bool)
+	readers := make(map[*StdGlobal]map[string]bool)
+	record := func(accesses map[*StdGlobal]map[string]bool, global *StdGlobal, fn string) {
+		if accesses[global] == nil {
+			accesses[global] = make(map[string]bool)
+		}
+		accesses[global][fn] = true
+	}
+
+	for _, fn := range funcs {
+		if fn.End <= fn.Entry {
+			continue
+		}
+
+		code, err := e.raw.read_memory(fn.Entry, fn.End-fn.Entry)
+		if err != nil {
+			continue
+		}
+
+		tracker := newConstTracker()
+		for off := 0; off < len(code); {
+			pc := fn.Entry + uint64(off)
+			inst, err := x86asm.Decode(code[off:], 64)
+			if err != nil || inst.Len == 0 {
+				off++
+				continue
+			}
+			off += inst.Len
+
+			for argIdx, arg := range inst.Args {
+				mem, ok := arg.(x86asm.Mem)
+				if !ok || mem.Base != x86asm.RIP || mem.Index != 0 {
+					continue
+				}
+
+				addr := uint64(int64(pc) + int64(inst.Len) + mem.Disp)
+				target := findRange(addr)
+				if target == nil {
+					continue
+				}
+
+				// an assignment stores straight to the global, the address of a global is only taken for a write barrier or to modify it in place
+				if inst.Op != x86asm.MOV || argIdx != 0 {
+					record(readers, target.global, fn.Name)
+					continue
+				}
+
+				record(writers, target.global, fn.Name)
+				target.global.Customized = true
+				if src, isReg := inst.Args[1].(x86asm.Reg); isReg && target.iface && addr == target.global.VA {
+					if itab, known := tracker.leas[canonicalReg(src)]; known {
+						if typeName := concreteType(itab); len(typeName) > 0 {
+							target.global.ConcreteType = typeName
+						}
+					}
+				}
+			}
+
+			if inst.Op == x86asm.CALL {
+				tracker = newConstTracker()
+			} else {
+				tracker.observe(inst, pc)
+			}
+		}
+	}
+
+	sortedNames := func(names map[string]bool) []string {
+		var sorted []string
+		for name := range names {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+		return sorted
+	}
+
+	for i := range globals {
+		globals[i].Writers = sortedNames(writers[&globals[i]])
+		globals[i].Readers = sortedNames(readers[&globals[i]])
+	}
+	return globals, nil
+}
@@ -0,0 +1,26 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestLocateGlobal(t *testing.T) {
+	syms := []Sym{
+		{Name: "net/http.DefaultTransport", Addr: 0x401000, Code: 'T'}, // a function can't be the global
+		{Name: "net/http.DefaultTransport", Addr: 0x7e0000, Code: 'D'},
+		{Name: "net.DefaultResolver", Addr: 0x7e1000, Code: 'B'},
+	}
+
+	if va, ok := locateGlobal(syms, nil, "net/http.DefaultTransport", binary.LittleEndian, true); !ok || va != 0x7e0000 {
+		t.Errorf("expected 0x7e0000, got 0x%x %t", va, ok)
+	}
+
+	if va, ok := locateGlobal(syms, nil, "net.DefaultResolver", binary.LittleEndian, true); !ok || va != 0x7e1000 {
+		t.Errorf("expected 0x7e1000, got 0x%x %t", va, ok)
+	}
+
+	if _, ok := locateGlobal(syms, nil, "net/http.DefaultClient", binary.LittleEndian, true); ok {
+		t.Errorf("missing global located")
+	}
+}
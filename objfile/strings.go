@@ -0,0 +1,271 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// the string literals reported, shorter ones are mostly noise and longer lengths are rarely lengths
+const (
+	minStringLiteralLen = 4
+	maxStringLiteralLen = 4096
+)
+
+// how many instructions apart the pointer and the length of a string header are set up, ex: LEAQ go:string."..."(SB), AX then
+// MOVL $5, BX, or the stores of both to the stack before the register ABI
+const stringPairWindow = 3
+
+// StringLiteral is a string constant of the binary, VA is of its bytes
+type StringLiteral struct {
+	VA    uint64
+	Value string
+}
+
+// StringLiterals are the strings the code of each function references, by entry, and the ones of string headers in the data no
+// code was seen to reference, ex: of a []string table
+type StringLiterals struct {
+	Functions    map[uint64][]StringLiteral
+	Unattributed []StringLiteral
+}
+
+// isStringLiteral reports whether data is printable UTF-8, tabs and line breaks included, our proxy for a real string constant
+func isStringLiteral(data []byte) bool {
+	if len(data) < minStringLiteralLen || !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// stringOperand is an address or a small immediate the instruction at pc sets up, index is of the instruction in its function
+type stringOperand struct {
+	index int
+	pc    uint64
+	value uint64
+	addr  bool
+}
+
+// stringOperands_amd64 lists the rip relative addresses the instructions of code take or load from and the immediates they move
+func stringOperands_amd64(code []byte, entry uint64) []stringOperand {
+	var operands []stringOperand
+	for off, index := 0, 0; off < len(code); index++ {
+		pc := entry + uint64(off)
+		inst, err := x86asm.Decode(code[off:], 64)
+		if err != nil || inst.Len == 0 {
+			off++
+			continue
+		}
+		off += inst.Len
+
+		switch inst.Op {
+		case x86asm.LEA, x86asm.MOV:
+			for _, arg := range inst.Args {
+				switch arg := arg.(type) {
+				case x86asm.Mem:
+					if arg.Base == x86asm.RIP && arg.Index == 0 {
+						operands = append(operands, stringOperand{index, pc, uint64(int64(pc) + int64(inst.Len) + arg.Disp), true})
+					}
+				case x86asm.Imm:
+					if arg > 0 && arg <= maxStringLiteralLen {
+						operands = append(operands, stringOperand{index, pc, uint64(arg), false})
+					}
+				}
+			}
+		}
+	}
+	return operands
+}
+
+// stringOperands_arm64 lists the addresses ADRP and ADD build and the immediates MOVZ moves, decoded from the encodings:
+//
+//	ADRP Xd, label           1 immlo:2 10000 immhi:19 Rd:5
+//	ADD  Xd, Xn, #imm{, LSL 12} 1 00 100010 sh:1 imm12:12 Rn:5 Rd:5
+//	MOVZ Rd, #imm{, LSL hw*16}  sf 10 100101 hw:2 imm16:16 Rd:5
+func stringOperands_arm64(code []byte, entry uint64) []stringOperand {
+	var operands []stringOperand
+	pages := make(map[uint32]uint64)
+	for off := 0; off+4 <= len(code); off += 4 {
+		pc := entry + uint64(off)
+		index := off / 4
+		x := binary.LittleEndian.Uint32(code[off:])
+		rd := x & 31
+		switch {
+		case x&0x9f000000 == 0x90000000:
+			imm := int64(x>>5&0x7ffff)<<2 | int64(x>>29&3)
+			imm = imm << 43 >> 31
+			pages[rd] = uint64(int64(pc&^0xfff) + imm)
+			continue
+		case x&0xff800000 == 0x91000000:
+			if page, ok := pages[x>>5&31]; ok {
+				imm := uint64(x >> 10 & 0xfff)
+				if x>>22&1 != 0 {
+					imm <<= 12
+				}
+				operands = append(operands, stringOperand{index, pc, page + imm, true})
+			}
+		case x&0x7f800000 == 0x52800000:
+			imm := uint64(x>>5&0xffff) << (16 * (x >> 21 & 3))
+			if imm > 0 && imm <= maxStringLiteralLen {
+				operands = append(operands, stringOperand{index, pc, imm, false})
+			}
+		}
+		delete(pages, rd)
+	}
+	return operands
+}
+
+var stringOperandDecoders = map[string]func(code []byte, entry uint64) []stringOperand{
+	"amd64": stringOperands_amd64,
+	"arm64": stringOperands_arm64,
+}
+
+// FindStringLiterals recovers the string literals the code of funcs references. A string is an address paired with the closest
+// length immediate set up right after it, or else right before it, whose bytes read as text. Only that closest length is tried,
+// a later one would read on into the strings the linker packs after it. The string headers in the initialized data are found
+// too, and go with the functions loading them, ex: a var s = "..." global. Only amd64 and arm64 are supported.
+func (e *Entry) FindStringLiterals(funcs []gosym.Func, moduleData *ModuleData, is64bit bool, littleendian bool) (*StringLiterals, error) {
+	goarch := e.GOARCH()
+	decode := stringOperandDecoders[goarch]
+	if decode == nil {
+		return nil, fmt.Errorf("string literal recovery unsupported for architecture %q", goarch)
+	}
+
+	var byteOrder binary.ByteOrder = binary.BigEndian
+	if littleendian {
+		byteOrder = binary.LittleEndian
+	}
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+	word := func(data []byte) uint64 {
+		if is64bit {
+			return byteOrder.Uint64(data)
+		}
+		return uint64(byteOrder.Uint32(data))
+	}
+	readString := func(ptr uint64, length uint64) (string, bool) {
+		data, err := e.raw.read_memory(ptr, length)
+		if err != nil || uint64(len(data)) != length || !isStringLiteral(data) {
+			return "", false
+		}
+		return string(data), true
+	}
+	// a string converted to an interface points at a static header in rodata, ex: the argument of fmt.Println("...")
+	readHeader := func(va uint64) (StringLiteral, bool) {
+		header, err := e.raw.read_memory(va, 2*ptrSize)
+		if err != nil || uint64(len(header)) != 2*ptrSize {
+			return StringLiteral{}, false
+		}
+		ptr, length := word(header), word(header[ptrSize:])
+		if ptr == 0 || length < minStringLiteralLen || length > maxStringLiteralLen {
+			return StringLiteral{}, false
+		}
+		value, ok := readString(ptr, length)
+		return StringLiteral{ptr, value}, ok
+	}
+
+	headers := make(map[uint64]StringLiteral)
+	if moduleData != nil {
+		headers = e.stringHeaders(moduleData, ptrSize, readHeader)
+	}
+	referenced := make(map[uint64]bool)
+
+	literals := &StringLiterals{Functions: make(map[uint64][]StringLiteral)}
+	for _, fn := range funcs {
+		if fn.End <= fn.Entry {
+			continue
+		}
+		code, err := e.raw.read_memory(fn.Entry, fn.End-fn.Entry)
+		if err != nil {
+			continue
+		}
+
+		operands := decode(code, fn.Entry)
+		seen := make(map[string]bool)
+		add := func(literal StringLiteral) {
+			if !seen[literal.Value] {
+				seen[literal.Value] = true
+				literals.Functions[fn.Entry] = append(literals.Functions[fn.Entry], literal)
+			}
+		}
+		for i, operand := range operands {
+			if !operand.addr {
+				continue
+			}
+			if header, ok := headers[operand.value]; ok {
+				referenced[operand.value] = true
+				add(header)
+				continue
+			}
+			if length, ok := closestLength(operands, i); ok {
+				if value, ok := readString(operand.value, length); ok {
+					add(StringLiteral{operand.value, value})
+				}
+			} else if header, ok := readHeader(operand.value); ok {
+				add(header)
+			}
+		}
+	}
+
+	// in the order of the headers, so the first of the duplicates is kept
+	vas := make([]uint64, 0, len(headers))
+	for va := range headers {
+		vas = append(vas, va)
+	}
+	sort.Slice(vas, func(i, j int) bool { return vas[i] < vas[j] })
+	seen := make(map[string]bool)
+	for _, va := range vas {
+		if header := headers[va]; !referenced[va] && !seen[header.Value] {
+			seen[header.Value] = true
+			literals.Unattributed = append(literals.Unattributed, header)
+		}
+	}
+	return literals, nil
+}
+
+// closestLength is the immediate of operands closest after the address at i, up to the next address, or else before it, within
+// stringPairWindow instructions
+func closestLength(operands []stringOperand, i int) (uint64, bool) {
+	for j := i + 1; j < len(operands) && operands[j].index-operands[i].index <= stringPairWindow; j++ {
+		if operands[j].addr {
+			break
+		}
+		return operands[j].value, true
+	}
+	for j := i - 1; j >= 0 && operands[i].index-operands[j].index <= stringPairWindow; j-- {
+		if !operands[j].addr {
+			return operands[j].value, true
+		}
+	}
+	return 0, false
+}
+
+// stringHeaders finds the pointer aligned string headers, a pointer then a length, in the initialized data whose bytes read as
+// text, by the address of the header
+func (e *Entry) stringHeaders(moduleData *ModuleData, ptrSize uint64, readHeader func(uint64) (StringLiteral, bool)) map[uint64]StringLiteral {
+	headers := make(map[uint64]StringLiteral)
+	for _, r := range [][2]uint64{{moduleData.Noptrdata, moduleData.Enoptrdata}, {moduleData.Data, moduleData.Edata}} {
+		if r[0] == 0 || r[1] <= r[0] {
+			continue
+		}
+		for va := r[0]; va+2*ptrSize <= r[1]; va += ptrSize {
+			if header, ok := readHeader(va); ok {
+				headers[va] = header
+			}
+		}
+	}
+	return headers
+}
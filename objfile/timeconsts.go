@@ -0,0 +1,111 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// TimeConstant is a statically initialized time.Time found in the data sections
+type TimeConstant struct {
+	VA          uint64
+	Time        string // RFC3339, UTC
+	Unix        int64
+	LocationPtr uint64 // 0 means UTC
+}
+
+const (
+	// seconds from Jan 1 year 1 to the unix epoch, see src/time/time.go unixToInternal
+	unixToInternal int64 = (1969*365 + 1969/4 - 1969/100 + 1969/400) * 24 * 60 * 60
+
+	// only years that land in here are reported, anything else in data is far more likely to be noise than a timestamp
+	minTimeConstant int64 = 946684800  // 2000-01-01
+	maxTimeConstant int64 = 4102444800 // 2100-01-01
+
+	walHasMonotonic = 1 << 63
+	wallNsecMask    = 1<<30 - 1
+)
+
+// src/time/time.go
+//
+//	type Time struct {
+//		wall uint64
+//		ext  int64
+//		loc *Location
+//	}
+//
+// Without the monotonic bit (never set for constants) the wall seconds field is zero, wall holds only the nanoseconds, and ext holds the full seconds since year 1.
+func parseTimeConstant(data []byte, is64bit bool, byteOrder binary.ByteOrder) (unix int64, nsec int64, loc uint64, ok bool) {
+	size := 20
+	if is64bit {
+		size = 24
+	}
+	if len(data) < size {
+		return 0, 0, 0, false
+	}
+
+	wall := byteOrder.Uint64(data)
+	ext := int64(byteOrder.Uint64(data[8:]))
+	if wall&walHasMonotonic != 0 || wall&^wallNsecMask != 0 || wall >= 1e9 {
+		return 0, 0, 0, false
+	}
+
+	unix = ext - unixToInternal
+	if unix < minTimeConstant || unix >= maxTimeConstant {
+		return 0, 0, 0, false
+	}
+
+	if is64bit {
+		loc = byteOrder.Uint64(data[16:])
+	} else {
+		loc = uint64(byteOrder.Uint32(data[16:]))
+	}
+	return unix, int64(wall), loc, true
+}
+
+// FindTimeConstants scans the initialized data of the module for time.Time shaped values, ex: hardcoded expiry dates or activation windows
+func (e *Entry) FindTimeConstants(moduleData *ModuleData, is64bit bool, littleendian bool) ([]TimeConstant, error) {
+	var byteOrder binary.ByteOrder = binary.BigEndian
+	if littleendian {
+		byteOrder = binary.LittleEndian
+	}
+
+	// pointers are 4 byte aligned on 32bit targets, so time.Time is too
+	align := 4
+	if is64bit {
+		align = 8
+	}
+
+	var results []TimeConstant
+	ranges := [][2]uint64{{moduleData.Noptrdata, moduleData.Enoptrdata}, {moduleData.Data, moduleData.Edata}}
+	for _, r := range ranges {
+		if r[0] == 0 || r[1] <= r[0] {
+			continue
+		}
+
+		data, err := e.raw.read_memory(r[0], r[1]-r[0])
+		if err != nil {
+			continue
+		}
+
+		for off := 0; off+align <= len(data); off += align {
+			unix, nsec, loc, ok := parseTimeConstant(data[off:], is64bit, byteOrder)
+			if !ok {
+				continue
+			}
+
+			// UTC is stored as nil. Anything else must be a Location in data or bss, which follows data, a static value can't point into the heap
+			if loc != 0 && (loc%uint64(align) != 0 || loc < moduleData.Data) {
+				continue
+			}
+
+			results = append(results, TimeConstant{
+				VA:          r[0] + uint64(off),
+				Time:        time.Unix(unix, nsec).UTC().Format(time.RFC3339Nano),
+				Unix:        unix,
+				LocationPtr: loc,
+			})
+		}
+	}
+	return results, nil
+}
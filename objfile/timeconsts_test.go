@@ -0,0 +1,44 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestParseTimeConstant(t *testing.T) {
+	expiry := time.Date(2024, 3, 1, 12, 30, 0, 500, time.UTC)
+
+	encode := func(wall uint64, ext int64, loc uint64, is64bit bool, byteOrder binary.ByteOrder) []byte {
+		data := make([]byte, 24)
+		byteOrder.PutUint64(data, wall)
+		byteOrder.PutUint64(data[8:], uint64(ext))
+		if is64bit {
+			byteOrder.PutUint64(data[16:], loc)
+		} else {
+			byteOrder.PutUint32(data[16:], uint32(loc))
+			data = data[:20]
+		}
+		return data
+	}
+
+	for _, is64bit := range []bool{true, false} {
+		for _, byteOrder := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			data := encode(uint64(expiry.Nanosecond()), expiry.Unix()+unixToInternal, 0, is64bit, byteOrder)
+			unix, nsec, loc, ok := parseTimeConstant(data, is64bit, byteOrder)
+			if !ok || unix != expiry.Unix() || nsec != 500 || loc != 0 {
+				t.Errorf("64bit=%v %s: failed to parse, got %d %d %x %v", is64bit, byteOrder, unix, nsec, loc, ok)
+			}
+		}
+	}
+
+	// monotonic readings only come from time.Now, never constants
+	if _, _, _, ok := parseTimeConstant(encode(walHasMonotonic|500, expiry.Unix()+unixToInternal, 0, true, binary.LittleEndian), true, binary.LittleEndian); ok {
+		t.Errorf("monotonic time accepted")
+	}
+
+	// out of the plausible range
+	if _, _, _, ok := parseTimeConstant(encode(0, 12345, 0, true, binary.LittleEndian), true, binary.LittleEndian); ok {
+		t.Errorf("implausible time accepted")
+	}
+}
@@ -0,0 +1,129 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// runtime functions only the TinyGo runtime has, the gc runtime panics and allocates through differently named ones
+var tinygoRuntimeFunctions = []string{
+	"runtime.runtimePanic",
+	"runtime.nilPanic",
+	"runtime.lookupPanic",
+	"runtime.slicePanic",
+	"runtime.alloc",
+	"runtime.hashmapMake",
+	"runtime.hashmapGet",
+	"internal/task.start",
+}
+
+// the assembly helpers of the TinyGo runtime are prefixed, ex: tinygo_getCurrentStackPointer
+const tinygoAsmPrefix = "tinygo_"
+
+// the fewest runtime functions that tell TinyGo, one could be an unlucky name in a C library
+const minTinyGoFunctions = 2
+
+// the TinyGo version as the compiler embeds it, ex: 'tinygo0.30.0' or 'tinygo version 0.30.0'
+var tinygoVersionPattern = regexp.MustCompile(`(?i)tinygo(?: version)? ?v?([0-9]+\.[0-9]+(?:\.[0-9]+)?)`)
+
+// the file is scanned for the version in chunks, overlapping by more than the longest match
+const (
+	tinygoScanChunk   = 1 << 20
+	tinygoScanOverlap = 64
+)
+
+// TinyGoInfo is why a file looks built by TinyGo. TinyGo compiles through LLVM, there's no pclntab or moduledata, so only the
+// function names and addresses of the symbol table can be recovered.
+type TinyGoInfo struct {
+	Version  string // the TinyGo version, not the Go version of its standard library
+	Evidence []string
+	Stripped bool // no symbol table, there's nothing to recover the functions from
+}
+
+// TinyGoFunctions lists the functions of the symbol table, or of the name section of a wasm module whose addresses are then the
+// function indices. Returns nil when it's stripped.
+func (e *Entry) TinyGoFunctions() ([]Sym, error) {
+	if f, ok := e.raw.(*wasmFile); ok {
+		names, err := f.wasm.FunctionNames()
+		if err != nil {
+			return nil, err
+		}
+		var funcs []Sym
+		for index, name := range names {
+			funcs = append(funcs, Sym{Name: name, Addr: uint64(index), Code: 'T'})
+		}
+		sort.Sort(byAddr(funcs))
+		return funcs, nil
+	}
+
+	syms, err := e.Symbols()
+	if err != nil {
+		return nil, nil
+	}
+	_, isMacho := e.raw.(*machoFile)
+	var funcs []Sym
+	for _, sym := range syms {
+		if sym.Code != 'T' && sym.Code != 't' {
+			continue
+		}
+		if isMacho {
+			sym.Name = strings.TrimPrefix(sym.Name, "_")
+		}
+		funcs = append(funcs, sym)
+	}
+	return funcs, nil
+}
+
+// scanTinyGoVersion finds the TinyGo version string in the file r reads, or returns an empty string
+func scanTinyGoVersion(r io.ReaderAt) string {
+	chunk := make([]byte, tinygoScanChunk+tinygoScanOverlap)
+	for offset := int64(0); ; offset += tinygoScanChunk {
+		n, err := r.ReadAt(chunk, offset)
+		if match := tinygoVersionPattern.FindSubmatch(chunk[:n]); match != nil {
+			return string(match[1])
+		}
+		if err != nil || n < len(chunk) {
+			return ""
+		}
+	}
+}
+
+// TinyGo collects the signs of a TinyGo build: runtime functions only TinyGo has and its version string. Returns nil when there
+// are none, r reads the file. The tinygo compiler is itself built by gc and mentions these names, check for a pclntab first.
+func (e *Entry) TinyGo(r io.ReaderAt) *TinyGoInfo {
+	info := &TinyGoInfo{Version: scanTinyGoVersion(r)}
+	if len(info.Version) > 0 {
+		info.Evidence = append(info.Evidence, fmt.Sprintf("TinyGo %s version string", info.Version))
+	}
+
+	funcs, _ := e.TinyGoFunctions()
+	names := make(map[string]bool, len(funcs))
+	for _, fn := range funcs {
+		names[fn.Name] = true
+	}
+	info.Stripped = len(funcs) == 0
+	var found []string
+	for _, name := range tinygoRuntimeFunctions {
+		if names[name] {
+			found = append(found, name)
+		}
+	}
+	for name := range names {
+		if strings.HasPrefix(name, tinygoAsmPrefix) {
+			found = append(found, name)
+		}
+	}
+	if len(found) >= minTinyGoFunctions {
+		sort.Strings(found)
+		info.Evidence = append(info.Evidence, fmt.Sprintf("TinyGo runtime functions %s", strings.Join(found, ", ")))
+	}
+
+	if len(info.Evidence) == 0 {
+		return nil
+	}
+	return info
+}
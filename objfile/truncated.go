@@ -0,0 +1,126 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+	"github.com/mandiant/GoReSym/debug/macho"
+)
+
+// TruncatedError is a read of memory the headers lay out in the file but the file ends before. It unwraps to io.ErrUnexpectedEOF.
+type TruncatedError struct {
+	VA   uint64
+	Size uint64
+}
+
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("0x%x bytes at 0x%x extend past the end of the file", e.Size, e.VA)
+}
+
+func (e *TruncatedError) Unwrap() error {
+	return io.ErrUnexpectedEOF
+}
+
+// readAvailable reads data at off of r, the memory at VA. A read the file ends in the middle of gives the bytes before the end, one
+// starting past the end is a TruncatedError.
+func readAvailable(r io.ReaderAt, data []byte, off int64, VA uint64) ([]byte, error) {
+	n, err := r.ReadAt(data, off)
+	if err == nil || n == len(data) {
+		return data, nil
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		if n > 0 {
+			return data[:n], nil
+		}
+		return nil, &TruncatedError{VA: VA, Size: uint64(len(data))}
+	}
+	return nil, err
+}
+
+// Truncation is how much of a file its headers lay out is missing: Size bytes of it are there and Extent are laid out. The sizes of
+// a fat Mach-O slice are from the start of the slice.
+type Truncation struct {
+	Size   uint64
+	Extent uint64
+}
+
+// a truncationRegion is Size bytes of the file at Offset the headers lay out, read through r
+type truncationRegion struct {
+	r      io.ReaderAt
+	offset uint64
+	size   uint64
+}
+
+// Truncation compares the ELF segments and sections, the PE sections or the Mach-O segments with the bytes the file has, nil when
+// it has them all. Dumps and wasm files have no layout to compare with.
+func (e *Entry) Truncation() *Truncation {
+	var regions []truncationRegion
+	switch f := e.raw.(type) {
+	case *elfFile:
+		for _, prog := range f.elf.Progs {
+			if prog.ReaderAt != nil && prog.Filesz > 0 {
+				regions = append(regions, truncationRegion{prog.ReaderAt, prog.Off, prog.Filesz})
+			}
+		}
+		for _, sect := range f.elf.Sections {
+			if sect.ReaderAt != nil && sect.Type != elf.SHT_NOBITS && sect.Size > 0 {
+				regions = append(regions, truncationRegion{sect.ReaderAt, sect.Offset, sect.Size})
+			}
+		}
+	case *peFile:
+		for _, sect := range f.pe.Sections {
+			if sect.ReaderAt != nil && sect.Size > 0 {
+				regions = append(regions, truncationRegion{sect.ReaderAt, uint64(sect.Offset), uint64(sect.Size)})
+			}
+		}
+	case *machoFile:
+		for _, load := range f.macho.Loads {
+			if seg, ok := load.(*macho.Segment); ok && seg.ReaderAt != nil && seg.Filesz > 0 {
+				regions = append(regions, truncationRegion{seg.ReaderAt, seg.Offset, seg.Filesz})
+			}
+		}
+	}
+
+	var truncation *Truncation
+	one := make([]byte, 1)
+	for _, region := range regions {
+		if _, err := region.r.ReadAt(one, int64(region.size-1)); err == nil {
+			continue
+		}
+		// the last byte that reads, the region reads up to the end of the file
+		lo, hi := uint64(0), region.size-1
+		for lo < hi {
+			mid := lo + (hi-lo)/2
+			if _, err := region.r.ReadAt(one, int64(mid)); err == nil {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		if truncation == nil {
+			truncation = &Truncation{Size: region.offset + lo, Extent: region.offset + region.size}
+		}
+		truncation.Size = min(truncation.Size, region.offset+lo)
+		truncation.Extent = max(truncation.Extent, region.offset+region.size)
+	}
+	if truncation != nil {
+		for _, region := range regions {
+			truncation.Extent = max(truncation.Extent, region.offset+region.size)
+		}
+	}
+	return truncation
+}
+
+// PastEOF reports whether the size bytes at VA are laid out in the file but the file ends before them, ex: the typelinks of a
+// truncated file. Memory that isn't in the file at all isn't past its end.
+func (e *Entry) PastEOF(VA uint64, size uint64) bool {
+	if size == 0 {
+		return false
+	}
+	var truncated *TruncatedError
+	_, err := e.raw.read_memory(VA+size-1, 1)
+	return errors.As(err, &truncated)
+}
@@ -0,0 +1,329 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// the Recovery of the types ScanTypes found
+const recoveredWithoutTypelinks = "recovered without typelinks"
+
+const (
+	// the fewest *T types whose elem and ptrToThis must agree on a types base for it to be trusted
+	minTypesBaseVotes = 4
+	// the longest type name the scan accepts, generic instantiations get long but not this long
+	maxScannedNameLen = 4096
+)
+
+// a read only data region the rtypes can be in, read whole
+type typeRegion struct {
+	start uint64
+	data  []byte
+}
+
+// rtypeHeader holds the fields of the rtype every type starts with from Go 1.7 on, the ones the scan validates:
+//
+//	type rtype struct {
+//		size       uintptr
+//		ptrdata    uintptr
+//		hash       uint32
+//		tflag      tflag
+//		align      uint8
+//		fieldAlign uint8
+//		kind       uint8
+//		equal      func(unsafe.Pointer, unsafe.Pointer) bool // alg *typeAlg before 1.14
+//		gcdata     *byte
+//		str        nameOff
+//		ptrToThis  typeOff
+//	}
+type rtypeHeader struct {
+	size       uint64
+	ptrdata    uint64
+	tflag      tflag
+	align      uint8
+	fieldAlign uint8
+	kind       Kind
+	gcdata     uint64
+	str        int32
+	ptrToThis  int32
+}
+
+func decodeRtypeHeader(data []byte, ptrSize uint64, byteOrder binary.ByteOrder) (header rtypeHeader, ok bool) {
+	if uint64(len(data)) < 4*ptrSize+16 {
+		return header, false
+	}
+	word := func(data []byte) uint64 {
+		if ptrSize == 8 {
+			return byteOrder.Uint64(data)
+		}
+		return uint64(byteOrder.Uint32(data))
+	}
+	header.size = word(data)
+	header.ptrdata = word(data[ptrSize:])
+	flags := data[2*ptrSize+4:]
+	header.tflag, header.align, header.fieldAlign, header.kind = tflag(flags[0]), flags[1], flags[2], Kind(flags[3]&0x1f)
+	header.gcdata = word(data[3*ptrSize+8:])
+	offsets := data[4*ptrSize+8:]
+	header.str = int32(byteOrder.Uint32(offsets))
+	header.ptrToThis = int32(byteOrder.Uint32(offsets[4:]))
+	return header, true
+}
+
+// plausible checks the header against what the compiler emits for its kind: the size of the kind, the pointers a value of it holds and an
+// alignment the size is a multiple of. Random data rarely gets all of them right.
+func (h rtypeHeader) plausible(ptrSize uint64) bool {
+	if h.kind < Bool || h.kind > UnsafePointer || h.tflag&^(tflagUncommon|tflagExtraStar|tflagNamed|tflagRegularMemory) != 0 || h.str <= 0 {
+		return false
+	}
+	validAlign := func(align uint8) bool {
+		return align == 1 || align == 2 || align == 4 || align == 8
+	}
+	if !validAlign(h.align) || !validAlign(h.fieldAlign) || h.fieldAlign > h.align || h.size%uint64(h.align) != 0 || h.ptrdata > h.size {
+		return false
+	}
+
+	var size, ptrdata uint64
+	switch h.kind {
+	case Bool, Int8, Uint8:
+		size = 1
+	case Int16, Uint16:
+		size = 2
+	case Int32, Uint32, Float32:
+		size = 4
+	case Int64, Uint64, Float64, Complex64:
+		size = 8
+	case Complex128:
+		size = 16
+	case Int, Uint, Uintptr:
+		size = ptrSize
+	case Pointer:
+		// a pointer to a not in heap type isn't one for the GC
+		return h.size == ptrSize && (h.ptrdata == ptrSize || h.ptrdata == 0)
+	case UnsafePointer, Chan, Map, Func:
+		size, ptrdata = ptrSize, ptrSize
+	case String:
+		size, ptrdata = 2*ptrSize, ptrSize
+	case Slice:
+		size, ptrdata = 3*ptrSize, ptrSize
+	case Interface:
+		size, ptrdata = 2*ptrSize, 2*ptrSize
+	default:
+		// arrays and structs are any size, a bogus one is still caught by its name
+		return h.size < 1<<32
+	}
+	return h.size == size && h.ptrdata == ptrdata
+}
+
+// plausibleTypeName checks the name read for a header is printable and fits the kind, ex: an unnamed slice type is named []T
+func plausibleTypeName(h rtypeHeader, name string) bool {
+	if len(name) == 0 || len(name) > maxScannedNameLen || !utf8.ValidString(name) {
+		return false
+	}
+	for _, c := range name {
+		if c < ' ' || c == 0x7f {
+			return false
+		}
+	}
+
+	if h.tflag&tflagNamed != 0 {
+		// a named type is an identifier qualified by its package, only type arguments have spaces, ex: go.shape.struct { X int }
+		return !strings.HasPrefix(name, "*") && (strings.Contains(name, "[") || !strings.Contains(name, " "))
+	}
+	switch h.kind {
+	case Pointer:
+		return strings.HasPrefix(name, "*")
+	case Slice:
+		return strings.HasPrefix(name, "[]")
+	case Array:
+		return strings.HasPrefix(name, "[") && !strings.HasPrefix(name, "[]")
+	case Map:
+		return strings.HasPrefix(name, "map[")
+	case Chan:
+		return strings.HasPrefix(name, "chan ") || strings.HasPrefix(name, "<-chan ")
+	case Func:
+		return strings.HasPrefix(name, "func(")
+	case Interface:
+		return strings.HasPrefix(name, "interface {")
+	case Struct:
+		// the map internals the compiler generates were unnamed before Go 1.9, ex: map.hdr[string]int
+		return strings.HasPrefix(name, "struct {") || strings.HasPrefix(name, "map.hdr[") || strings.HasPrefix(name, "map.bucket[") || strings.HasPrefix(name, "map.iter[")
+	}
+	// the basic kinds are always named, ex: int, except for the unsafe.Pointer and the results of reflection
+	return !strings.Contains(name, " ")
+}
+
+// typeRegions lists the read only data of the file the rtypes are laid out in, the types region of the moduledata comes first when it's readable
+func (e *Entry) typeRegions(moduleData *ModuleData) []typeRegion {
+	var regions []typeRegion
+	add := func(start uint64, data []byte) {
+		for _, region := range regions {
+			if start >= region.start && start < region.start+uint64(len(region.data)) {
+				return
+			}
+		}
+		if len(data) > 0 {
+			regions = append(regions, typeRegion{start: start, data: data})
+		}
+	}
+
+	if moduleData.Types != 0 && moduleData.ETypes > moduleData.Types && moduleData.ETypes-moduleData.Types < maxSubtableSize {
+		if data, err := e.raw.read_memory(moduleData.Types, moduleData.ETypes-moduleData.Types); err == nil {
+			add(moduleData.Types, data)
+		}
+	}
+
+	switch f := e.raw.(type) {
+	case *elfFile:
+		// position independent binaries keep the types in the relro data, their pointers are relocated
+		for _, name := range []string{".rodata", ".data.rel.ro"} {
+			if sect := f.elf.Section(name); sect != nil {
+				if data, err := sect.Data(); err == nil {
+					add(sect.Addr, data)
+				}
+			}
+		}
+	case *peFile:
+		if start, data, err := f.rdata(); err == nil {
+			add(start, data)
+		}
+	case *machoFile:
+		for _, sect := range f.macho.Sections {
+			if sect.Name == "__rodata" {
+				if data, err := sect.Data(); err == nil {
+					add(sect.Addr, f.applyFixups(data, sect.Addr))
+				}
+			}
+		}
+	}
+	return regions
+}
+
+// ScanTypes recovers the types of a module whose typelinks or types base are damaged, ex: zeroed by a packer, while the pclntab that
+// found the moduledata is intact. The rtypes are looked for directly in the read only data, from Go 1.7 on where their names are offsets
+// from the types base. That base is bootstrapped from the *T types: the elem of one points at T, whose ptrToThis is the offset of the
+// *T from the base, so every pair votes for a base. Only the headers with a size, pointers and alignment fitting their kind and a name
+// fitting it too are parsed, with the types they refer to. Each is marked as recovered without typelinks. The types base is returned,
+// the itabs and methods are resolved from it.
+func (e *Entry) ScanTypes(runtimeVersion string, moduleData *ModuleData, is64bit bool, littleendian bool) (types []Type, typesBase uint64, err error) {
+	// Major version only, 1.15.5 -> 1.15
+	parts := strings.Split(runtimeVersion, ".")
+	if len(parts) >= 2 {
+		runtimeVersion = parts[0] + "." + parts[1]
+	}
+	if minor, ok := goMinorVersion(runtimeVersion); !ok || minor < 7 {
+		return nil, 0, fmt.Errorf("the types of Go %s can't be scanned for, only those of 1.7 and later", runtimeVersion)
+	}
+
+	var byteOrder binary.ByteOrder = binary.BigEndian
+	if littleendian {
+		byteOrder = binary.LittleEndian
+	}
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+	headerSize := 4*ptrSize + 16
+
+	regions := e.typeRegions(moduleData)
+	if len(regions) == 0 {
+		return nil, 0, fmt.Errorf("no read only data to scan for types")
+	}
+	header := func(va uint64) (rtypeHeader, bool) {
+		for _, region := range regions {
+			if va >= region.start && va-region.start < uint64(len(region.data)) {
+				return decodeRtypeHeader(region.data[va-region.start:], ptrSize, byteOrder)
+			}
+		}
+		return rtypeHeader{}, false
+	}
+
+	// every *T whose T points back at it through ptrToThis votes for the base both are relative to
+	votes := make(map[uint64]int)
+	for _, region := range regions {
+		if err := contextErr(e.ctx); err != nil {
+			return nil, 0, err
+		}
+		for offset := uint64(0); offset+headerSize+ptrSize <= uint64(len(region.data)); offset += ptrSize {
+			ptr, ok := decodeRtypeHeader(region.data[offset:], ptrSize, byteOrder)
+			if !ok || ptr.kind != Pointer || !ptr.plausible(ptrSize) {
+				continue
+			}
+			elemData := region.data[offset+headerSize:]
+			elemVA := uint64(byteOrder.Uint32(elemData))
+			if is64bit {
+				elemVA = byteOrder.Uint64(elemData)
+			}
+			elem, ok := header(elemVA)
+			if !ok || elemVA%ptrSize != 0 || elem.ptrToThis <= 0 || !elem.plausible(ptrSize) {
+				continue
+			}
+			votes[region.start+offset-uint64(elem.ptrToThis)]++
+		}
+	}
+	for base, count := range votes {
+		if count > votes[typesBase] || (count == votes[typesBase] && base < typesBase) {
+			typesBase = base
+		}
+	}
+	if votes[typesBase] < minTypesBaseVotes {
+		return nil, 0, fmt.Errorf("no types base is backed by the *T types, the best has %d votes", votes[typesBase])
+	}
+
+	repaired := *moduleData
+	repaired.Types, repaired.ETypes = typesBase, typesBase
+	for _, region := range regions {
+		if typesBase >= region.start && typesBase < region.start+uint64(len(region.data)) {
+			repaired.ETypes = region.start + uint64(len(region.data))
+		}
+	}
+
+	seen := make(map[uint64]bool)
+	for _, region := range regions {
+		for offset := uint64(0); offset+headerSize <= uint64(len(region.data)); offset += ptrSize {
+			candidate, ok := decodeRtypeHeader(region.data[offset:], ptrSize, byteOrder)
+			if !ok || !candidate.plausible(ptrSize) || typesBase+uint64(candidate.str) >= repaired.ETypes {
+				continue
+			}
+			va := region.start + offset
+			if seen[va] {
+				offset += headerSize - ptrSize
+				continue
+			}
+
+			// the flags of a name only have the exported, tag, pkgPath and embedded bits
+			nameVA := typesBase + uint64(candidate.str)
+			flags, err := e.raw.read_memory(nameVA, 1)
+			if err != nil || len(flags) == 0 || flags[0]&0xf0 != 0 {
+				continue
+			}
+			name, err := e.readRTypeName(runtimeVersion, candidate.tflag, nameVA, is64bit, littleendian)
+			if err != nil || !plausibleTypeName(candidate, name) {
+				continue
+			}
+
+			if err := contextErr(e.ctx); err != nil {
+				return types, typesBase, err
+			}
+			parsed, err := e.ParseType(runtimeVersion, &repaired, va, is64bit, littleendian)
+			if err != nil {
+				continue
+			}
+			for _, typ := range parsed {
+				if !seen[typ.VA] {
+					seen[typ.VA] = true
+					typ.Recovery = recoveredWithoutTypelinks
+					types = append(types, typ)
+				}
+			}
+			// rtypes don't overlap
+			offset += headerSize - ptrSize
+		}
+	}
+	if len(types) == 0 {
+		return nil, typesBase, fmt.Errorf("no type validated")
+	}
+	return types, typesBase, nil
+}
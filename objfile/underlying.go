@@ -0,0 +1,214 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/elliotchance/orderedmap"
+)
+
+// basicUnderlying is the Go name of a kind that has one, the underlying type of a named type of the kind, ex: uint for reflect.Kind
+func basicUnderlying(kind Kind) string {
+	switch kind {
+	case Bool, Int, Int8, Int16, Int32, Int64, Uint, Uint8, Uint16, Uint32, Uint64, Uintptr, Float32, Float64, Complex64, Complex128, String:
+		return strings.ToLower(kind.String())
+	case UnsafePointer:
+		return "unsafe.Pointer"
+	}
+	return ""
+}
+
+// chanUnderlying is the channel type of elem in the direction dir, ex: <-chan int
+func chanUnderlying(dir ChanDir, elem string) string {
+	switch dir {
+	case RecvOnly:
+		return "<-chan " + elem
+	case SendOnly:
+		return "chan<- " + elem
+	}
+	return "chan " + elem
+}
+
+// embeddedName is the name of an embedded field of type typeStr, the type name without its package, ex: Mutex for *sync.Mutex
+func embeddedName(typeStr string) string {
+	name := strings.TrimPrefix(typeStr, "*")
+	if open := strings.IndexByte(name, '['); open != -1 {
+		name = name[:open]
+	}
+	return name[strings.LastIndexByte(name, '.')+1:]
+}
+
+// readNameString reads one length prefixed string of a name at addr, the length is 2 bytes big endian before Go 1.17 and a varint
+// from then on. It returns the string and the address after it.
+func (e *Entry) readNameString(minor int, addr uint64) (string, uint64, error) {
+	var length, prefix int
+	if minor < 17 {
+		raw, err := e.raw.read_memory(addr, 2)
+		if err != nil {
+			return "", 0, err
+		}
+		length, prefix = int(binary.BigEndian.Uint16(raw)), 2
+	} else {
+		var err error
+		if prefix, length, err = e.readVarint(addr); err != nil {
+			return "", 0, err
+		}
+	}
+	data, err := e.raw.read_memory(addr+uint64(prefix), uint64(length))
+	if err != nil {
+		return "", 0, err
+	}
+	return string(data), addr + uint64(prefix+length), nil
+}
+
+// readStructFieldName reads the name of a struct field from Go 1.7 on, the tag following it and, from 1.19 on, the embedded bit of
+// its flags. Before 1.19 the offset of the field tells whether it's embedded.
+//
+//	1<<0 the name is exported
+//	1<<1 tag data follows the name
+//	1<<2 pkgPath nameOff follows the name and tag
+//	1<<3 the field is embedded, from 1.19 on
+func (e *Entry) readStructFieldName(minor int, namePtr uint64) (name string, tag string, embedded bool, err error) {
+	flags, err := e.raw.read_memory(namePtr, 1)
+	if err != nil {
+		return "", "", false, fmt.Errorf("Failed to read name")
+	}
+	name, next, err := e.readNameString(minor, namePtr+1)
+	if err != nil {
+		return "", "", false, fmt.Errorf("Failed to read name")
+	}
+	if flags[0]&(1<<1) != 0 {
+		if tag, _, err = e.readNameString(minor, next); err != nil {
+			return "", "", false, fmt.Errorf("Failed to read tag")
+		}
+	}
+	return name, tag, minor >= 19 && flags[0]&(1<<3) != 0, nil
+}
+
+// funcUnderlying is the func type of a named one from Go 1.7 on, its parameters and results follow its uncommonType, ex:
+// func(string, ...interface {}) (int, error). The types of the parameters are parsed too. It's empty when one doesn't parse.
+func (e *Entry) funcUnderlying(runtimeVersion string, moduleData *ModuleData, _type *Type, is64bit bool, littleendian bool, parsedTypesIn *orderedmap.OrderedMap) (string, *orderedmap.OrderedMap) {
+	if minor, ok := goMinorVersion(runtimeVersion); !ok || minor < 7 {
+		return "", parsedTypesIn
+	}
+	in, out, ok, parsedTypesIn := e.funcSignature(runtimeVersion, moduleData, _type, is64bit, littleendian, parsedTypesIn)
+	if !ok {
+		return "", parsedTypesIn
+	}
+	return "func" + signatureString(in, out), parsedTypesIn
+}
+
+// funcSignature reads the types of the parameters and the results of a func type, parsing them too. The last parameter of a variadic
+// func is ...T rather than the []T the runtime records. ok is false when one doesn't parse.
+//
+// From Go 1.7 on the counts follow the rtype, the types follow the uncommonType of a named func:
+//
+//	type funcType struct {
+//		rtype
+//		inCount  uint16
+//		outCount uint16 // top bit is set if last input parameter is ...
+//	}
+//
+// Go 1.5 and 1.6 have a slice of each:
+//
+//	type funcType struct {
+//		rtype
+//		dotdotdot bool
+//		in        []*rtype
+//		out       []*rtype
+//	}
+func (e *Entry) funcSignature(runtimeVersion string, moduleData *ModuleData, _type *Type, is64bit bool, littleendian bool, parsedTypesIn *orderedmap.OrderedMap) (in []string, out []string, ok bool, parsed *orderedmap.OrderedMap) {
+	minor, ok := goMinorVersion(runtimeVersion)
+	if !ok {
+		return nil, nil, false, parsedTypesIn
+	}
+	ptrSize := uint64(4)
+	if is64bit {
+		ptrSize = 8
+	}
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	if !littleendian {
+		byteOrder = binary.BigEndian
+	}
+
+	var inAddr, outAddr, inCount, outCount uint64
+	var variadic bool
+	if minor < 7 {
+		header, err := e.raw.read_memory(_type.VA+uint64(_type.baseSize), 7*ptrSize)
+		if err != nil || uint64(len(header)) < 7*ptrSize {
+			return nil, nil, false, parsedTypesIn
+		}
+		variadic = header[0] != 0
+		inAddr, inCount = decodePtrSizeBytes(header[ptrSize:], is64bit, littleendian), decodePtrSizeBytes(header[2*ptrSize:], is64bit, littleendian)
+		outAddr, outCount = decodePtrSizeBytes(header[4*ptrSize:], is64bit, littleendian), decodePtrSizeBytes(header[5*ptrSize:], is64bit, littleendian)
+	} else {
+		counts, err := e.raw.read_memory(_type.VA+uint64(_type.baseSize), 4)
+		if err != nil || len(counts) < 4 {
+			return nil, nil, false, parsedTypesIn
+		}
+		inCount, outCount = uint64(byteOrder.Uint16(counts)), uint64(byteOrder.Uint16(counts[2:]))
+		variadic = outCount&(1<<15) != 0
+		outCount &^= 1 << 15
+
+		inAddr = _type.VA + uint64(_type.baseSize) + ptrSize
+		if _type.flags&tflagUncommon != 0 {
+			// 1.7 has no xcount and a 16 bit moff
+			if minor == 7 {
+				inAddr += 8
+			} else {
+				inAddr += 16
+			}
+		}
+		outAddr = inAddr + inCount*ptrSize
+	}
+	// a count is 15 bits from 1.7 on, a larger slice is garbage
+	if inCount > 1<<15 || outCount > 1<<15 {
+		return nil, nil, false, parsedTypesIn
+	}
+
+	readTypes := func(addr uint64, count uint64) ([]string, bool) {
+		var types []string
+		for i := uint64(0); i < count; i++ {
+			typeAddr, err := e.ReadPointerSizeMem(addr+i*ptrSize, is64bit, littleendian)
+			if err != nil {
+				return nil, false
+			}
+			parsedTypesIn, _ = e.ParseType_impl(runtimeVersion, moduleData, typeAddr, is64bit, littleendian, parsedTypesIn)
+			typ, found := parsedTypesIn.Get(typeAddr)
+			if !found {
+				return nil, false
+			}
+			types = append(types, typ.(Type).Str)
+		}
+		return types, true
+	}
+	if in, ok = readTypes(inAddr, inCount); !ok {
+		return nil, nil, false, parsedTypesIn
+	}
+	if out, ok = readTypes(outAddr, outCount); !ok {
+		return nil, nil, false, parsedTypesIn
+	}
+	if variadic && len(in) > 0 {
+		in[len(in)-1] = "..." + strings.TrimPrefix(in[len(in)-1], "[]")
+	}
+	return in, out, true, parsedTypesIn
+}
+
+// signatureString is the parameters and results of a func as Go writes them after func or a method name, ex: (string) (int, error)
+func signatureString(in []string, out []string) string {
+	signature := "(" + strings.Join(in, ", ") + ")"
+	if len(out) == 1 {
+		signature += " " + out[0]
+	} else if len(out) > 1 {
+		signature += " (" + strings.Join(out, ", ") + ")"
+	}
+	return signature
+}
+
+// arrayUnderlying is the array type of length elements of elem, ex: [4]uint8
+func arrayUnderlying(length uint64, elem string) string {
+	return "[" + strconv.FormatUint(length, 10) + "]" + elem
+}
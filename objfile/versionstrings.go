@@ -0,0 +1,158 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+
+	"github.com/mandiant/GoReSym/debug/elf"
+)
+
+// a Go release, ex: go1.21.3 or go1.22rc1. The strings of the data are packed without separators, so what's before it doesn't tell.
+var versionStringPattern = regexp.MustCompile(`^go1\.\d{1,3}(\.\d{1,3})?((rc|beta)\d{1,2})?`)
+
+// the longest string a header pointing at a version string can give, the version and maybe an experiment suffix, ex: ' X:boringcrypto'
+const maxVersionStringHeader = 0x80
+
+// the most go1. strings of the data checked for headers, ex: the source paths of the pclntab under a go1.X.Y directory
+const maxVersionStrings = 0x10000
+
+// VersionString is a Go version string of the data, ex: the one runtime.buildVersion points at, found without the build info or the
+// symbols. References counts the string headers of the data pointing at it, a version string the runtime uses has at least one.
+type VersionString struct {
+	Version    string
+	VA         uint64 // of the copy the most headers point at
+	References int
+}
+
+// dataRegion is a section with data in the file, ex: not the bss
+type dataRegion struct {
+	start      uint64
+	data       []byte
+	executable bool
+}
+
+// dataRegions lists the sections with data in the file, with the pointers as the file holds them
+func (e *Entry) dataRegions() []dataRegion {
+	var regions []dataRegion
+	switch f := e.raw.(type) {
+	case *elfFile:
+		for _, sect := range f.elf.Sections {
+			if sect.Flags&elf.SHF_ALLOC == 0 || sect.Type == elf.SHT_NOBITS {
+				continue
+			}
+			if data, err := sect.Data(); err == nil {
+				regions = append(regions, dataRegion{sect.Addr, data, sect.Flags&elf.SHF_EXECINSTR != 0})
+			}
+		}
+	case *peFile:
+		imageBase, _ := f.loadAddress()
+		for _, sect := range f.pe.Sections {
+			// IMAGE_SCN_CNT_UNINITIALIZED_DATA, and IMAGE_SCN_MEM_EXECUTE
+			if sect.Characteristics&0x80 != 0 {
+				continue
+			}
+			if data, err := sect.Data(); err == nil {
+				regions = append(regions, dataRegion{imageBase + uint64(sect.VirtualAddress), data, sect.Characteristics&0x20000000 != 0})
+			}
+		}
+	case *machoFile:
+		for _, sect := range f.macho.Sections {
+			// S_ZEROFILL has no data, and S_ATTR_PURE_INSTRUCTIONS
+			if sect.Flags&0xff == 1 {
+				continue
+			}
+			if data, err := sect.Data(); err == nil {
+				regions = append(regions, dataRegion{sect.Addr, f.applyFixups(data, sect.Addr), sect.Flags&0x80000000 != 0})
+			}
+		}
+	case *dumpFile:
+		for _, region := range f.regions {
+			regions = append(regions, dataRegion{region.addr, region.data, region.executable})
+		}
+	}
+	return regions
+}
+
+// VersionStrings scans the file for the go1.X(.Y) strings string headers of the data point at, a fallback for the binaries whose build
+// info is gone and whose symbols are stripped. The runtime always keeps its version in runtime.buildVersion, a string header of the data,
+// while a go1. string no header points at is likely a piece of another string, ex: a source path. The strings are looked for in the
+// code too, old linkers put the read only data of a PE there. A header is a pointer and a length of either size, the length covering
+// the version. Every distinct version is returned, ex: of a binary embedding another Go binary, the most
+// referenced first. Those without a header aren't.
+func (e *Entry) VersionStrings() []VersionString {
+	regions := e.dataRegions()
+	type match struct {
+		version string
+		VA      uint64
+	}
+	var matches []match
+	for _, region := range regions {
+		for off := 0; len(matches) < maxVersionStrings; {
+			idx := bytes.Index(region.data[off:], []byte("go1."))
+			if idx == -1 {
+				break
+			}
+			start := off + idx
+			off = start + 4
+			if version := versionStringPattern.Find(region.data[start:min(len(region.data), start+16)]); version != nil {
+				matches = append(matches, match{string(version), region.start + uint64(start)})
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	byVA := make(map[uint64]int, len(matches))
+	lowest, highest := matches[0].VA, matches[0].VA
+	for i, m := range matches {
+		byVA[m.VA] = i
+		lowest, highest = min(lowest, m.VA), max(highest, m.VA)
+	}
+	references := make([]int, len(matches))
+	byteOrder := e.ByteOrder()
+	for _, region := range regions {
+		if region.executable {
+			continue
+		}
+		data := region.data
+		for _, ptrSize := range []int{4, 8} {
+			for off := 0; off+2*ptrSize <= len(data); off += ptrSize {
+				var VA, length uint64
+				if ptrSize == 8 {
+					VA, length = byteOrder.Uint64(data[off:]), byteOrder.Uint64(data[off+8:])
+				} else {
+					VA, length = uint64(byteOrder.Uint32(data[off:])), uint64(byteOrder.Uint32(data[off+4:]))
+				}
+				if VA < lowest || VA > highest {
+					continue
+				}
+				if i, ok := byVA[VA]; ok && length >= uint64(len(matches[i].version)) && length <= maxVersionStringHeader {
+					references[i]++
+				}
+			}
+		}
+	}
+
+	var versions []VersionString
+	index := make(map[string]int)
+	for i, m := range matches {
+		if references[i] == 0 {
+			continue
+		}
+		j, seen := index[m.version]
+		if !seen {
+			index[m.version] = len(versions)
+			versions = append(versions, VersionString{Version: m.version, VA: m.VA, References: references[i]})
+			continue
+		}
+		if references[i] > references[byVA[versions[j].VA]] {
+			versions[j].VA = m.VA
+		}
+		versions[j].References += references[i]
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].References > versions[j].References })
+	return versions
+}
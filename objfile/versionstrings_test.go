@@ -0,0 +1,39 @@
+package objfile
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestVersionStrings(t *testing.T) {
+	le := binary.LittleEndian
+	rodata := make([]byte, 0x100)
+	copy(rodata, "go1.21.3")
+	copy(rodata[0x10:], "/usr/go1.9/src/runtime/proc.go")
+	copy(rodata[0x40:], "go1.22.1 X:boringcrypto")
+	copy(rodata[0x60:], "go1.21.3")
+	data := make([]byte, 0x100)
+	// the headers of the first go1.21.3 twice, of the second once and of go1.22.1 with its experiment, none of the source path
+	for i, header := range [][2]uint64{{0x500000, 8}, {0x500000, 8}, {0x500060, 8}, {0x500040, 23}, {0x500010, 0x1000}} {
+		le.PutUint64(data[i*16:], header[0])
+		le.PutUint64(data[i*16+8:], header[1])
+	}
+	raw := &dumpFile{format: "raw", arch: "amd64", base: 0x500000, size: 0x1200, regions: []dumpRegion{
+		{name: "rodata", addr: 0x500000, data: rodata},
+		{name: "data", addr: 0x501000, data: data},
+	}}
+	e := &Entry{raw: raw}
+
+	versions := e.VersionStrings()
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions, got %+v", versions)
+	}
+	if versions[0] != (VersionString{Version: "go1.21.3", VA: 0x500000, References: 3}) || versions[1] != (VersionString{Version: "go1.22.1", VA: 0x500040, References: 1}) {
+		t.Errorf("unexpected versions %+v", versions)
+	}
+
+	raw.regions = raw.regions[:1]
+	if versions := e.VersionStrings(); len(versions) != 0 {
+		t.Errorf("expected no version without headers, got %+v", versions)
+	}
+}
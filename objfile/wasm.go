@@ -0,0 +1,88 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package objfile
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/mandiant/GoReSym/debug/wasm"
+)
+
+// data segments closer than this are laid out as one region, the linker splits the data wherever it's zero
+const wasmSegmentGap = 0x10000
+
+// wasmFile is a Go WebAssembly module, GOOS=js or wasip1. Its pclntab and moduledata are in the data segments, which are read like a dump
+// of the initial linear memory. There's no native code, so only the pclntab magic is scanned for. The function entries are the PCs of the
+// Go wasm runtime: the function index above the 16 bit resumption point.
+type wasmFile struct {
+	*dumpFile
+	wasm *wasm.File
+}
+
+func openWasm(r io.ReaderAt, opts OpenOptions) (rawFile, error) {
+	f, err := wasm.NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []*wasm.Segment
+	for _, s := range f.Segments {
+		if s.Memory == 0 && len(s.Data) > 0 {
+			segments = append(segments, s)
+		}
+	}
+	if len(segments) == 0 {
+		return nil, errors.New("the module has no data segments")
+	}
+	sort.SliceStable(segments, func(i, j int) bool { return segments[i].Offset < segments[j].Offset })
+
+	memory := &dumpFile{format: "wasm", arch: "wasm", byteOrder: binary.LittleEndian, base: segments[0].Offset}
+	for i := 0; i < len(segments); {
+		start := segments[i].Offset
+		end := start + uint64(len(segments[i].Data))
+		for i++; i < len(segments) && segments[i].Offset <= end+wasmSegmentGap; i++ {
+			end = max(end, segments[i].Offset+uint64(len(segments[i].Data)))
+		}
+		memory.regions = append(memory.regions, dumpRegion{name: fmt.Sprintf("memory@0x%x", start), addr: start, data: f.ReadMemory(start, end-start)})
+		memory.size += end - start
+	}
+	return &wasmFile{dumpFile: memory, wasm: f}, nil
+}
+
+// pcln_scan scans the memory like a dump, the pclntab's text base is the pcHeader's textStart rather than the address of its region.
+// It's 0 for the function indices, so the moduledata doesn't correct it later either.
+func (f *wasmFile) pcln_scan() (candidates <-chan PclntabCandidate, err error) {
+	memory, err := f.dumpFile.pcln_scan()
+	if err != nil {
+		return nil, err
+	}
+
+	ch_tab := make(chan PclntabCandidate)
+	go func() {
+		defer close(ch_tab)
+		for candidate := range memory {
+			candidate.SecStart = pcHeaderTextStart(candidate.Pclntab)
+			ch_tab <- candidate
+		}
+	}()
+	return ch_tab, nil
+}
+
+func (f *wasmFile) pcln() (candidates <-chan PclntabCandidate, err error) {
+	return f.pcln_scan()
+}
+
+func (f *wasmFile) symbols() ([]Sym, error) {
+	return nil, errors.New("wasm modules have no symbol table")
+}
+
+func (f *wasmFile) text() (textStart uint64, text []byte, err error) {
+	return 0, nil, errors.New("wasm modules have no native code")
+}
+
+func (f *wasmFile) loadAddress() (uint64, error) {
+	return 0, nil
+}
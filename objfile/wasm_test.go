@@ -0,0 +1,96 @@
+package objfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// fakeWasm is a module whose data section holds a 1.20 pcHeader at 0x10000, split by a zero gap like the linker splits it, a passive
+// segment and a segment far past the rest
+func fakeWasm() []byte {
+	header := make([]byte, 0x48)
+	binary.LittleEndian.PutUint32(header, 0xfffffff1)
+	header[6], header[7] = 1, 8
+	binary.LittleEndian.PutUint64(header[8+2*8:], 0x10000000) // textStart
+
+	segment := func(offset uint32, data []byte) []byte {
+		var s bytes.Buffer
+		s.WriteByte(0)
+		s.WriteByte(0x41) // i32.const
+		s.Write(sleb128(int64(offset)))
+		s.WriteByte(0x0b)
+		s.Write(binary.AppendUvarint(nil, uint64(len(data))))
+		s.Write(data)
+		return s.Bytes()
+	}
+	passive := append([]byte{1}, append(binary.AppendUvarint(nil, 4), "skip"...)...)
+
+	var data bytes.Buffer
+	data.Write(binary.AppendUvarint(nil, 4))
+	data.Write(segment(0x10000, header[:0x20]))
+	data.Write(segment(0x10030, header[0x30:]))
+	data.Write(passive)
+	data.Write(segment(0x800000, []byte("far")))
+
+	var module bytes.Buffer
+	module.WriteString("\x00asm\x01\x00\x00\x00")
+	module.WriteByte(0) // a custom section before the data
+	module.Write(binary.AppendUvarint(nil, 5))
+	module.Write([]byte{4, 'n', 'a', 'm', 'e'})
+	module.WriteByte(11)
+	module.Write(binary.AppendUvarint(nil, uint64(data.Len())))
+	module.Write(data.Bytes())
+	return module.Bytes()
+}
+
+func sleb128(value int64) []byte {
+	var out []byte
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if (value == 0 && b&0x40 == 0) || (value == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+func TestOpenWasm(t *testing.T) {
+	raw, err := openWasm(bytes.NewReader(fakeWasm()), OpenOptions{})
+	if err != nil {
+		t.Fatalf("failed to open the module: %s", err)
+	}
+	f := raw.(*wasmFile)
+	if raw.goarch() != "wasm" {
+		t.Errorf("expected GOARCH wasm, got %s", raw.goarch())
+	}
+
+	// the split header is one region with the gap zeroed, the far segment gets its own
+	if len(f.regions) != 2 || f.regions[0].addr != 0x10000 || len(f.regions[0].data) != 0x48 || f.regions[1].addr != 0x800000 {
+		t.Fatalf("unexpected regions %+v", f.regions)
+	}
+	if gap, err := raw.read_memory(0x10020, 0x10); err != nil || !bytes.Equal(gap, make([]byte, 0x10)) {
+		t.Errorf("expected the gap to read as zeros, got %x, %v", gap, err)
+	}
+	if (&Entry{raw: raw}).Dump(nil) != nil {
+		t.Errorf("a module isn't a dump")
+	}
+
+	candidates, err := raw.pcln()
+	if err != nil {
+		t.Fatalf("pclntab scan failed: %s", err)
+	}
+	found := false
+	for candidate := range candidates {
+		if candidate.PclntabVA == 0x10000 && !candidate.ReconstructedMagic {
+			found = true
+			if candidate.SecStart != 0x10000000 {
+				t.Errorf("expected the text base of the pcHeader, got 0x%x", candidate.SecStart)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("the pcHeader wasn't found")
+	}
+}
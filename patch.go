@@ -0,0 +1,71 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/mandiant/GoReSym/debug/gosym"
+	"github.com/mandiant/GoReSym/goresym"
+
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// elfSymbols lists the functions of metadata for the symbol table of a patched ELF, each address once
+func elfSymbols(metadata goresym.Report) []objfile.ElfSymbol {
+	var symbols []objfile.ElfSymbol
+	named := make(map[uint64]bool)
+	for _, funcs := range [][]goresym.FuncMetadata{metadata.UserFunctions, metadata.StdFunctions} {
+		for _, fn := range funcs {
+			if fn.Unmapped || named[fn.Start] {
+				continue
+			}
+			named[fn.Start] = true
+			symbols = append(symbols, objfile.ElfSymbol{Name: fn.FullName, Value: fn.Start, Size: fn.End - fn.Start})
+		}
+	}
+	return symbols
+}
+
+// dwarfFuncs lists the functions of symbols in address order with their source lines, for generated DWARF
+func dwarfFuncs(symbols []objfile.ElfSymbol, pclntab *gosym.Table) []objfile.DwarfFunc {
+	var funcs []objfile.DwarfFunc
+	for _, sym := range symbols {
+		fn := objfile.DwarfFunc{Name: sym.Name, Entry: sym.Value, End: sym.Value + sym.Size}
+		if tabFunc := pclntab.PCToFunc(sym.Value); tabFunc != nil && tabFunc.Entry == sym.Value {
+			fn.Rows = pclntab.LineRows(tabFunc)
+		}
+		funcs = append(funcs, fn)
+	}
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].Entry < funcs[j].Entry })
+	return funcs
+}
+
+// writePatchedElf writes a copy of the ELF at fileName with the functions of metadata in a symbol table to outName, executable like the
+// input. withDwarf also describes the functions and their source lines in DWARF.
+func writePatchedElf(fileName string, outName string, metadata goresym.Report, withDwarf bool) error {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	symbols := elfSymbols(metadata)
+	var debugSections []objfile.ElfSection
+	if withDwarf {
+		if metadata.Pclntab() == nil {
+			return fmt.Errorf("no pclntab to take the source lines from")
+		}
+		if debugSections, err = objfile.ElfDwarf(data, dwarfFuncs(symbols, metadata.Pclntab())); err != nil {
+			return err
+		}
+	}
+	patched, err := objfile.PatchElf(data, symbols, debugSections)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outName, patched, info.Mode().Perm())
+}
@@ -0,0 +1,119 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mandiant/GoReSym/goresym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// r2Header is the start of the generated script, the flags follow it
+const r2Header = `# Generated by GoReSym for %s, load it with 'r2 -i <this script> %s' or '. <this script>' in a session, rizin takes it the same way
+# The addresses are the virtual addresses of the file, open it without -B so they line up.
+# Every package gets a flagspace, go.<package>, the types are in go.types.
+`
+
+// the flagspace of the type structures
+const r2TypeSpace = "go.types"
+
+// r2Name replaces the characters r2 and rizin treat specially in a flag name. The slashes of package paths become dots like the
+// separators of r2's own flags, everything but letters, digits, dots and _ becomes _, ex: 'github.com/x/y.(*T).M' -> 'github.com.x.y.__T_.M'
+func r2Name(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '/':
+			return '.'
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// r2Comment replaces the characters a comment would end or be redirected at: the command separator, pipes, redirection, the
+// temporary seek, command substitution, grep, quotes and the variables of rizin
+func r2Comment(comment string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(";|>`@~#\"'$\\", r) {
+			return '_'
+		}
+		return r
+	}, comment)
+}
+
+// printR2Script writes a radare2 and rizin script flagging the functions and types of metadata, naming the functions and commenting
+// them with their full Go name and source line. Each package is a flagspace, the packages are sorted and each is written once, so
+// the output is the same for the same input. fileName goes in the header. Std functions and types are included when they were
+// extracted, with -d and -t.
+func printR2Script(w io.Writer, fileName string, metadata goresym.Report) error {
+	var b strings.Builder
+	// a newline in the name would run the rest of it as commands, shell ones with !
+	name := r2Comment(filepath.Base(fileName))
+	fmt.Fprintf(&b, r2Header, name, name)
+
+	// flag names are global, an address is flagged once
+	named := make(map[uint64]bool)
+	packages := make(map[string][]goresym.FuncMetadata)
+	for _, funcs := range [][]goresym.FuncMetadata{metadata.UserFunctions, metadata.StdFunctions} {
+		for _, fn := range funcs {
+			if fn.Unmapped || named[fn.Start] {
+				continue
+			}
+			named[fn.Start] = true
+			packages[fn.PackageName] = append(packages[fn.PackageName], fn)
+		}
+	}
+	var packageNames []string
+	for pkg := range packages {
+		packageNames = append(packageNames, pkg)
+	}
+	sort.Strings(packageNames)
+
+	names := make(uniqueNames)
+	for _, pkg := range packageNames {
+		funcs := packages[pkg]
+		sort.SliceStable(funcs, func(i, j int) bool { return funcs[i].Start < funcs[j].Start })
+		space := "go"
+		if len(pkg) > 0 {
+			space = "go." + r2Name(pkg)
+		}
+		fmt.Fprintf(&b, "fs %s\n", space)
+		for _, fn := range funcs {
+			name := names.name("", "go."+r2Name(fn.FullName), fn.Start)
+			comment := fn.FullName
+			if len(fn.SourceFile) > 0 {
+				comment = fmt.Sprintf("%s %s:%d", fn.FullName, fn.SourceFile, fn.SourceLine)
+			}
+			fmt.Fprintf(&b, "f %s %d @ 0x%x\n", name, fn.End-fn.Start, fn.Start)
+			fmt.Fprintf(&b, "af @ 0x%x\n", fn.Start)
+			fmt.Fprintf(&b, "afn %s @ 0x%x\n", name, fn.Start)
+			fmt.Fprintf(&b, "CC %s @ 0x%x\n", r2Comment(comment), fn.Start)
+		}
+	}
+
+	var types []objfile.Type
+	for _, typ := range append(append([]objfile.Type(nil), metadata.Types...), metadata.Interfaces...) {
+		if !named[typ.VA] {
+			named[typ.VA] = true
+			types = append(types, typ)
+		}
+	}
+	if len(types) > 0 {
+		sort.SliceStable(types, func(i, j int) bool { return types[i].VA < types[j].VA })
+		fmt.Fprintf(&b, "fs %s\n", r2TypeSpace)
+		for _, typ := range types {
+			fmt.Fprintf(&b, "f %s @ 0x%x\n", names.name("", "go.type."+r2Name(typ.Str), typ.VA), typ.VA)
+			fmt.Fprintf(&b, "CC %s @ 0x%x\n", r2Comment(typ.Str), typ.VA)
+		}
+	}
+	b.WriteString("fs *\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
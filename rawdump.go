@@ -0,0 +1,65 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/mandiant/GoReSym/goresym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// rawDumpFile is a structure written out by -dump-raw, File is its name in the directory
+type rawDumpFile struct {
+	File string
+	objfile.RawStructure
+}
+
+// rawDumpManifest is the manifest.json of -dump-raw: where each structure came from and the version and layouts they were read as
+type rawDumpManifest struct {
+	Version          string
+	PclntabLayout    string
+	ModuleDataLayout string `json:",omitempty"` // the version of the moduledata struct, or probed, see objfile.ModuleData.Layout
+	PointerSize      uint32
+	Endianess        string
+	Structures       []rawDumpFile
+}
+
+// writeRawStructures writes the raw bytes of the structures the extraction of metadata located to dir, each to <name>_0x<VA>.bin, with
+// manifest.json describing them. A structure clamped to the most bytes read is warned about on stderr.
+func writeRawStructures(dir string, metadata goresym.Report) error {
+	structures, err := metadata.RawStructures()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	manifest := rawDumpManifest{
+		Version:       metadata.Version,
+		PclntabLayout: metadata.TabMeta.Version,
+		PointerSize:   metadata.TabMeta.PointerSize,
+		Endianess:     metadata.TabMeta.Endianess,
+		Structures:    []rawDumpFile{},
+	}
+	if metadata.ModuleMeta.VA != 0 {
+		manifest.ModuleDataLayout = metadata.ModuleMeta.Layout()
+		if metadata.ModuleMeta.LayoutSource == objfile.LayoutProbed {
+			manifest.ModuleDataLayout = objfile.LayoutProbed
+		}
+	}
+	for _, structure := range structures {
+		if structure.Clamped {
+			log.Printf("warning: the %s at 0x%x claims %d bytes, only the first %d are written", structure.Name, structure.VA, structure.ClaimedSize, structure.Size)
+		}
+		name := fmt.Sprintf("%s_0x%x.bin", structure.Name, structure.VA)
+		if err := os.WriteFile(filepath.Join(dir, name), structure.Data, 0644); err != nil {
+			return err
+		}
+		manifest.Structures = append(manifest.Structures, rawDumpFile{File: name, RawStructure: structure})
+	}
+	return os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(DataToJson(manifest)), 0644)
+}
@@ -0,0 +1,165 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mandiant/GoReSym/goresym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// the packages of compiler synthesized types, ex: map.bucket[string]int and go.shape.int, they have no declaration
+var synthesizedPackages = map[string]bool{"go": true, "map": true, "noalg": true, "unsafe": true}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+// splitTypeName splits the Str of a named type into its package and name, ex: main and Stack[int] for main.Stack[int]. ok is false
+// for type literals like *main.Node or map[string]int and for builtins.
+func splitTypeName(str string) (pkg string, name string, ok bool) {
+	dot := strings.IndexByte(str, '.')
+	if dot <= 0 || dot == len(str)-1 || !isIdentChar(str[dot+1]) {
+		return "", "", false
+	}
+	for i := 0; i < dot; i++ {
+		if !isIdentChar(str[i]) {
+			return "", "", false
+		}
+	}
+	return str[:dot], str[dot+1:], true
+}
+
+// unqualify drops the package qualifier pkg from the type names in expr, the types of the package being declared
+func unqualify(expr string, pkg string) string {
+	var out strings.Builder
+	prefix := pkg + "."
+	for i := 0; i < len(expr); {
+		if strings.HasPrefix(expr[i:], prefix) && (i == 0 || (!isIdentChar(expr[i-1]) && expr[i-1] != '.')) {
+			i += len(prefix)
+			continue
+		}
+		out.WriteByte(expr[i])
+		i++
+	}
+	return out.String()
+}
+
+// goFieldTag is a struct tag as a Go string literal, raw unless it has a backquote
+func goFieldTag(tag string) string {
+	if strings.ContainsRune(tag, '`') {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// declaredTypes lists the named types of the metadatas in package then name order, each name once
+func declaredTypes(metadata goresym.Report) []objfile.Type {
+	seen := make(map[string]bool)
+	var types []objfile.Type
+	for _, typ := range append(append([]objfile.Type{}, metadata.Types...), metadata.Interfaces...) {
+		pkg, _, ok := splitTypeName(typ.Str)
+		// the itabs are listed among the interfaces as interface_<interface>_impl_<type>
+		itab := strings.HasPrefix(typ.Str, "interface_") && strings.Contains(typ.Str, "_impl_")
+		if !ok || synthesizedPackages[pkg] || typ.Shape || seen[typ.Str] || itab {
+			continue
+		}
+		seen[typ.Str] = true
+		types = append(types, typ)
+	}
+	sort.SliceStable(types, func(i, j int) bool {
+		pkgI, nameI, _ := splitTypeName(types[i].Str)
+		pkgJ, nameJ, _ := splitTypeName(types[j].Str)
+		if pkgI != pkgJ {
+			return pkgI < pkgJ
+		}
+		return nameI < nameJ
+	})
+	return types
+}
+
+// printReconstructedGo writes a Go declaration of every named type of metadata from the types recovered with -t, grouped by
+// package. Structs have their fields in offset order with the tags, interfaces their methods and the other types the type they're
+// declared as. Types refer to each other by name, so recursive types are declared once. What didn't parse is an unsafe.Pointer with
+// a comment. It reads like Go, but the package qualifiers are the package names and the generic types keep their type arguments.
+func printReconstructedGo(w io.Writer, fileName string, metadata goresym.Report) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "// Code generated by GoReSym from %s, DO NOT EDIT.\n", filepath.Base(fileName))
+	fmt.Fprintf(out, "// The types of Go %s, reconstructed from their runtime type descriptors.\n", metadata.Version)
+
+	currentPkg := ""
+	for _, typ := range declaredTypes(metadata) {
+		pkg, name, _ := splitTypeName(typ.Str)
+		if pkg != currentPkg {
+			fmt.Fprintf(out, "\n// package %s\n", pkg)
+			currentPkg = pkg
+		}
+		fmt.Fprintf(out, "\n// %s at 0x%x, %d bytes\n", typ.Str, typ.VA, typ.Size)
+
+		switch typ.Kind {
+		case "Struct":
+			writeGoStruct(out, pkg, name, typ)
+		case "Interface":
+			fmt.Fprintf(out, "type %s interface {\n", name)
+			for _, method := range typ.InterfaceMethods {
+				if len(method.Type) == 0 {
+					fmt.Fprintf(out, "\t%s() // signature not recovered\n", method.Name)
+					continue
+				}
+				fmt.Fprintf(out, "\t%s%s\n", method.Name, unqualify(strings.TrimPrefix(method.Type, "func"), pkg))
+			}
+			fmt.Fprintln(out, "}")
+		default:
+			if len(typ.Underlying) == 0 {
+				fmt.Fprintf(out, "type %s unsafe.Pointer // %s, the underlying type wasn't recovered\n", name, typ.Kind)
+			} else {
+				fmt.Fprintf(out, "type %s %s\n", name, unqualify(typ.Underlying, pkg))
+			}
+		}
+	}
+	return out.Flush()
+}
+
+// writeGoStruct writes the declaration of a struct type, with the field names, types and tags in aligned columns like gofmt
+func writeGoStruct(out *bufio.Writer, pkg string, name string, typ objfile.Type) {
+	type line struct{ name, typ, tag, comment string }
+	var lines []line
+	nameWidth, typeWidth := 0, 0
+	for _, field := range typ.Fields {
+		l := line{typ: unqualify(field.Type, pkg), comment: fmt.Sprintf("offset %d", field.Offset)}
+		if len(field.Type) == 0 {
+			l.typ = "unsafe.Pointer"
+			l.comment += fmt.Sprintf(", unresolved type at 0x%x", field.TypeVA)
+		}
+		if !field.Embedded || len(field.Type) == 0 {
+			l.name = field.Name
+		}
+		if len(field.Tag) > 0 {
+			l.tag = goFieldTag(field.Tag)
+		}
+		if len(l.name) > 0 {
+			nameWidth = max(nameWidth, len(l.name))
+		}
+		typeWidth = max(typeWidth, len(l.typ))
+		lines = append(lines, l)
+	}
+
+	fmt.Fprintf(out, "type %s struct {\n", name)
+	for _, l := range lines {
+		decl := l.typ
+		if len(l.name) > 0 {
+			decl = fmt.Sprintf("%-*s %s", nameWidth, l.name, l.typ)
+		}
+		if len(l.tag) > 0 {
+			decl = fmt.Sprintf("%-*s %s", nameWidth+1+typeWidth, decl, l.tag)
+		}
+		fmt.Fprintf(out, "\t%s // %s\n", decl, l.comment)
+	}
+	fmt.Fprintln(out, "}")
+}
@@ -0,0 +1,209 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mandiant/GoReSym/goresym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// sqliteSchema creates the tables once, every result after the first appends to them. A binary is the sha256 of the file and the
+// arch, the slices of a fat Mach-O share the hash. The addresses are INTEGERs, sqlite's are signed 64 bit so a VA is stored as the
+// int64 of its bits, one past 0x7fffffffffffffff is negative.
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS binaries (
+    id INTEGER PRIMARY KEY,
+    sha256 TEXT NOT NULL,
+    arch TEXT NOT NULL,
+    name TEXT,
+    os TEXT,
+    compiler TEXT,
+    go_version TEXT,
+    build_mode TEXT,
+    build_id TEXT,
+    main_path TEXT,
+    buildinfo TEXT,
+    UNIQUE (sha256, arch)
+);
+CREATE TABLE IF NOT EXISTS functions (
+    binary_id INTEGER NOT NULL REFERENCES binaries (id),
+    name TEXT,
+    package TEXT,
+    start_va INTEGER NOT NULL,
+    end_va INTEGER NOT NULL,
+    source_file TEXT,
+    source_line INTEGER,
+    start_line INTEGER,
+    end_line INTEGER,
+    std INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS types (
+    binary_id INTEGER NOT NULL REFERENCES binaries (id),
+    name TEXT,
+    kind TEXT,
+    size INTEGER,
+    va INTEGER NOT NULL,
+    interface INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS functions_name ON functions (name);
+CREATE INDEX IF NOT EXISTS functions_package ON functions (package);
+CREATE INDEX IF NOT EXISTS functions_binary ON functions (binary_id);
+CREATE INDEX IF NOT EXISTS types_name ON types (name);
+CREATE INDEX IF NOT EXISTS types_binary ON types (binary_id);
+`
+
+// sqlString quotes s as an SQL string literal, a quote is escaped by doubling it
+func sqlString(s string) string {
+	if len(s) == 0 {
+		return "NULL"
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlAddress is a VA as sqlite's signed 64 bit INTEGER, a uint64 past its range would be stored as a REAL and lose its low bits
+func sqlAddress(va uint64) int64 {
+	return int64(va)
+}
+
+// sqlLine is a source line, NULL when it isn't known
+func sqlLine(line int) string {
+	if line <= 0 {
+		return "NULL"
+	}
+	return fmt.Sprint(line)
+}
+
+// sqlBool is sqlite's boolean, an INTEGER
+func sqlBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// fileSha256 hashes the file at path, the identity of its rows in the database
+func fileSha256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// printSqlite writes an SQL script for the sqlite3 shell, appending the results of the file with the given sha256 to a database.
+// Each result is a transaction replacing the rows of an earlier run on the same file and arch, a script cut short leaves the
+// database as it was. source_line is the line of the function's entry, start_line and end_line the range of its own code.
+func printSqlite(w io.Writer, fileName string, hash string, metadatas ...goresym.Report) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Generated by GoReSym for %s, load it with 'sqlite3 results.db < <this script>'\n", headerName(fileName))
+	b.WriteString(sqliteSchema)
+
+	for _, metadata := range metadatas {
+		buildInfo, err := json.Marshal(metadata.BuildInfo)
+		if err != nil {
+			return err
+		}
+		binary := fmt.Sprintf("(SELECT id FROM binaries WHERE sha256 = %s AND arch = %s)", sqlString(hash), sqlString(metadata.Arch))
+
+		b.WriteString("BEGIN;\n")
+		fmt.Fprintf(&b, "DELETE FROM functions WHERE binary_id = %s;\n", binary)
+		fmt.Fprintf(&b, "DELETE FROM types WHERE binary_id = %s;\n", binary)
+		fmt.Fprintf(&b, "DELETE FROM binaries WHERE sha256 = %s AND arch = %s;\n", sqlString(hash), sqlString(metadata.Arch))
+		fmt.Fprintf(&b, "INSERT INTO binaries (sha256, arch, name, os, compiler, go_version, build_mode, build_id, main_path, buildinfo) VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s);\n",
+			sqlString(hash), sqlString(metadata.Arch), sqlString(filepath.Base(fileName)), sqlString(metadata.OS), sqlString(metadata.Compiler), sqlString(metadata.Version),
+			sqlString(metadata.BuildMode), sqlString(metadata.BuildId), sqlString(metadata.BuildInfo.Main.Path), sqlString(string(buildInfo)))
+
+		for _, funcs := range []struct {
+			funcs []goresym.FuncMetadata
+			std   bool
+		}{{metadata.UserFunctions, false}, {metadata.StdFunctions, true}} {
+			for _, fn := range funcs.funcs {
+				fmt.Fprintf(&b, "INSERT INTO functions (binary_id, name, package, start_va, end_va, source_file, source_line, start_line, end_line, std) VALUES (%s, %s, %s, %d, %d, %s, %s, %s, %s, %d);\n",
+					binary, sqlString(fn.FullName), sqlString(fn.PackageName), sqlAddress(fn.Start), sqlAddress(fn.End), sqlString(fn.SourceFile),
+					sqlLine(fn.SourceLine), sqlLine(fn.StartLine), sqlLine(fn.EndLine), sqlBool(funcs.std))
+			}
+		}
+
+		for _, types := range []struct {
+			types []objfile.Type
+			iface bool
+		}{{metadata.Types, false}, {metadata.Interfaces, true}} {
+			for _, typ := range types.types {
+				fmt.Fprintf(&b, "INSERT INTO types (binary_id, name, kind, size, va, interface) VALUES (%s, %s, %s, %d, %d, %d);\n",
+					binary, sqlString(typ.Str), sqlString(typ.Kind), typ.Size, sqlAddress(typ.VA), sqlBool(types.iface))
+			}
+		}
+		b.WriteString("COMMIT;\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// sqliteShell is the path of the sqlite3 shell that writes the databases, GoReSym doesn't link an sqlite of its own. It's looked up
+// before anything is extracted, so a missing shell fails the run right away.
+func sqliteShell() (string, error) {
+	shell, err := exec.LookPath("sqlite3")
+	if err != nil {
+		return "", fmt.Errorf("-outputformat sqlite -out writes the database with the sqlite3 shell, install it on PATH or leave out -out to print the SQL script instead: %w", err)
+	}
+	return shell, nil
+}
+
+// runSqlite runs script into the database at dbPath with the sqlite3 shell, creating it if it doesn't exist. The shell stops at the
+// first error and rolls back the transaction it was in, the binaries committed before it keep their rows.
+func runSqlite(dbPath string, script []byte) error {
+	shell, err := sqliteShell()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(shell, "-bail", dbPath)
+	cmd.Stdin = bytes.NewReader(script)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// loadSqlite appends the results of the file with the given sha256 to the database at dbPath, see runSqlite
+func loadSqlite(dbPath string, fileName string, hash string, metadatas ...goresym.Report) error {
+	var script bytes.Buffer
+	if err := printSqlite(&script, fileName, hash, metadatas...); err != nil {
+		return err
+	}
+	return runSqlite(dbPath, script.Bytes())
+}
+
+// writeSqlite hashes the file and appends its results to the database at dbPath, or prints their script without one, exiting on failure
+func writeSqlite(w io.Writer, dbPath string, fileName string, metadatas ...goresym.Report) {
+	hash, err := fileSha256(fileName)
+	if err != nil {
+		fmt.Println(TextToJson("error", fmt.Sprintf("Failed to hash %s: %s", fileName, err)))
+		os.Exit(1)
+	}
+	if len(dbPath) > 0 {
+		if err := loadSqlite(dbPath, fileName, hash, metadatas...); err != nil {
+			fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write %s: %s", dbPath, err)))
+			os.Exit(1)
+		}
+		return
+	}
+	if err := printSqlite(w, fileName, hash, metadatas...); err != nil {
+		fmt.Println(TextToJson("error", fmt.Sprintf("Failed to write sql: %s", err)))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,97 @@
+# Generated by GoReSym for implant.exe, run it after the import in a headless batch:
+#   analyzeHeadless <project dir> <project name> -import implant.exe -postScript <this script>
+# or from the Script Manager. Jython and PyGhidra both run it.
+# Running it again is harmless: what's already applied is left as is, and names and comments set by hand are only replaced with FORCE.
+#@category GoReSym
+from ghidra.program.model.address import AddressSet
+from ghidra.program.model.symbol import SourceType
+
+FORCE = False
+
+# a position independent ELF linked at 0 is loaded at an image base of Ghidra's choosing, the addresses move with it
+LINKED_AT_ZERO = False
+
+# start, end, namespace path, name and the comment of each function
+FUNCTIONS = [
+    (0x401000, 0x401040, [u"main"], u"handleC2", u"main.handleC2\n/build/c2.go:12"),
+    (0x401040, 0x401080, [u"main"], u"(*Beacon).Send", u"main.(*Beacon).Send\n/build/beacon.go:40"),
+    (0x401080, 0x4010c0, [u"main"], u"Map[int]", u"main.Map[int]\n/build/util.go:7"),
+    (0x4010c0, 0x401100, [u"main"], u"Map(int)", u"main.Map(int)"),
+    (0x401100, 0x401140, [u"main"], u"handleC2_401100", u"main.handleC2"),
+    (0x402000, 0x402010, [u"fmt"], u"Println", u"fmt.Println\n/usr/local/go/src/fmt/print.go:313"),
+]
+
+# va, namespace path, name and the Go type of each type structure
+TYPES = [
+    (0x4a0000, [u"type"], u"*main.Beacon", u"*main.Beacon"),
+    (0x4a0100, [u"type"], u"map[string]\"quoted\"", u"map[string]\"quoted\""),
+]
+
+
+OFFSET = 0
+if LINKED_AT_ZERO and currentProgram.getMetadata()["Executable Format"] == "Executable and Linking Format (ELF)":
+    OFFSET = currentProgram.getImageBase().getOffset()
+
+symbol_table = currentProgram.getSymbolTable()
+
+
+def address(va):
+    return toAddr(va + OFFSET)
+
+
+def namespace(path):
+    ns = currentProgram.getGlobalNamespace()
+    for part in path:
+        existing = symbol_table.getNamespace(part, ns)
+        ns = existing if existing is not None else symbol_table.createNameSpace(ns, part, SourceType.IMPORTED)
+    return ns
+
+
+def keep(symbol):
+    return symbol.getSource() == SourceType.USER_DEFINED and not FORCE
+
+
+def apply_comment(ea, comment):
+    current = getPreComment(ea)
+    if current != comment and (not current or FORCE):
+        setPreComment(ea, comment)
+
+
+def apply_function(start, end, path, name, comment):
+    entry = address(start)
+    func = getFunctionAt(entry)
+    if func is None:
+        disassemble(entry)
+        func = createFunction(entry, None)
+    if func is None:
+        print("GoReSym: no function at 0x%x" % start)
+        return
+    body = AddressSet(entry, address(end - 1))
+    if not func.getBody().equals(body):
+        try:
+            func.setBody(body)
+        except Exception as e:
+            print("GoReSym: keeping the body of %s: %s" % (name, e))
+    ns = namespace(path)
+    symbol = func.getSymbol()
+    if (symbol.getName() != name or symbol.getParentNamespace() != ns) and not keep(symbol):
+        symbol.setNameAndNamespace(name, ns, SourceType.IMPORTED)
+    apply_comment(entry, comment)
+
+
+def apply_label(va, path, name, comment):
+    ea = address(va)
+    ns = namespace(path)
+    symbol = getSymbolAt(ea)
+    if symbol is None or ((symbol.getName() != name or symbol.getParentNamespace() != ns) and not keep(symbol)):
+        createLabel(ea, name, ns, True, SourceType.IMPORTED)
+    apply_comment(ea, comment)
+
+
+for start, end, path, name, comment in FUNCTIONS:
+    apply_function(start, end, path, name, comment)
+
+for va, path, name, comment in TYPES:
+    apply_label(va, path, name, comment)
+
+print("GoReSym: applied %d functions and %d types" % (len(FUNCTIONS), len(TYPES)))
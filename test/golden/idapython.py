@@ -0,0 +1,68 @@
+# Generated by GoReSym, run it with File > Script file in the database of implant.exe
+# The addresses are the virtual addresses of the file, rebase the database with Edit > Segments > Rebase program rather than the script.
+# Running it again is harmless: what's already applied is left as is, and names and comments set by hand are only replaced with FORCE.
+import ida_bytes
+import ida_funcs
+import ida_name
+
+FORCE = False
+
+# start, end, name and the comment of each function
+FUNCTIONS = [
+    (0x401000, 0x401040, "main_handleC2", "main.handleC2\n/build/c2.go:12"),
+    (0x401040, 0x401080, "main___Beacon__Send", "main.(*Beacon).Send\n/build/beacon.go:40"),
+    (0x401080, 0x4010c0, "main_Map_int_", "main.Map[int]\n/build/util.go:7"),
+    (0x4010c0, 0x401100, "main_Map_int__4010c0", "main.Map(int)"),
+    (0x401100, 0x401140, "main_handleC2_401100", "main.handleC2"),
+    (0x402000, 0x402010, "fmt_Println", "fmt.Println\n/usr/local/go/src/fmt/print.go:313"),
+]
+
+# va, name and the Go type of each type structure
+TYPES = [
+    (0x4a0000, "type__main_Beacon", "*main.Beacon"),
+    (0x4a0100, "type_map_string__quoted_", "map[string]\"quoted\""),
+]
+
+
+def apply_name(ea, name):
+    current = ida_name.get_name(ea)
+    if current == name:
+        return
+    if current and ida_bytes.has_user_name(ida_bytes.get_flags(ea)) and not FORCE:
+        print("GoReSym: keeping %s at 0x%x, set FORCE to rename it %s" % (current, ea, name))
+        return
+    ida_name.set_name(ea, name, ida_name.SN_NOWARN | ida_name.SN_FORCE)
+
+
+def apply_function(start, end, comment):
+    func = ida_funcs.get_func(start)
+    if func is None:
+        if not ida_funcs.add_func(start, end) and FORCE:
+            ida_bytes.del_items(start, ida_bytes.DELIT_SIMPLE, end - start)
+            ida_funcs.add_func(start, end)
+        func = ida_funcs.get_func(start)
+    elif func.start_ea == start and func.end_ea != end:
+        ida_funcs.set_func_end(start, end)
+    if func is None or func.start_ea != start:
+        print("GoReSym: no function at 0x%x" % start)
+        return
+    current = ida_funcs.get_func_cmt(func, False)
+    if current != comment and (not current or FORCE):
+        ida_funcs.set_func_cmt(func, comment, False)
+
+
+def apply_comment(ea, comment):
+    current = ida_bytes.get_cmt(ea, True)
+    if current != comment and (not current or FORCE):
+        ida_bytes.set_cmt(ea, comment, True)
+
+
+for start, end, name, comment in FUNCTIONS:
+    apply_function(start, end, comment)
+    apply_name(start, name)
+
+for va, name, comment in TYPES:
+    apply_name(va, name)
+    apply_comment(va, comment)
+
+print("GoReSym: applied %d functions and %d types" % (len(FUNCTIONS), len(TYPES)))
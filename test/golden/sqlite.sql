@@ -0,0 +1,56 @@
+-- Generated by GoReSym for it's.exe, load it with 'sqlite3 results.db < <this script>'
+CREATE TABLE IF NOT EXISTS binaries (
+    id INTEGER PRIMARY KEY,
+    sha256 TEXT NOT NULL,
+    arch TEXT NOT NULL,
+    name TEXT,
+    os TEXT,
+    compiler TEXT,
+    go_version TEXT,
+    build_mode TEXT,
+    build_id TEXT,
+    main_path TEXT,
+    buildinfo TEXT,
+    UNIQUE (sha256, arch)
+);
+CREATE TABLE IF NOT EXISTS functions (
+    binary_id INTEGER NOT NULL REFERENCES binaries (id),
+    name TEXT,
+    package TEXT,
+    start_va INTEGER NOT NULL,
+    end_va INTEGER NOT NULL,
+    source_file TEXT,
+    source_line INTEGER,
+    start_line INTEGER,
+    end_line INTEGER,
+    std INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS types (
+    binary_id INTEGER NOT NULL REFERENCES binaries (id),
+    name TEXT,
+    kind TEXT,
+    size INTEGER,
+    va INTEGER NOT NULL,
+    interface INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS functions_name ON functions (name);
+CREATE INDEX IF NOT EXISTS functions_package ON functions (package);
+CREATE INDEX IF NOT EXISTS functions_binary ON functions (binary_id);
+CREATE INDEX IF NOT EXISTS types_name ON types (name);
+CREATE INDEX IF NOT EXISTS types_binary ON types (binary_id);
+BEGIN;
+DELETE FROM functions WHERE binary_id = (SELECT id FROM binaries WHERE sha256 = '5e1f' AND arch = 'amd64');
+DELETE FROM types WHERE binary_id = (SELECT id FROM binaries WHERE sha256 = '5e1f' AND arch = 'amd64');
+DELETE FROM binaries WHERE sha256 = '5e1f' AND arch = 'amd64';
+INSERT INTO binaries (sha256, arch, name, os, compiler, go_version, build_mode, build_id, main_path, buildinfo) VALUES ('5e1f', 'amd64', 'it''s.exe', NULL, NULL, NULL, NULL, NULL, NULL, '{"GoVersion":"","Path":"","Main":{"Path":"","Version":"","Sum":"","Replace":null},"Deps":null,"Settings":null}');
+INSERT INTO functions (binary_id, name, package, start_va, end_va, source_file, source_line, start_line, end_line, std) VALUES ((SELECT id FROM binaries WHERE sha256 = '5e1f' AND arch = 'amd64'), 'main.handleC2', 'main', 4198400, 4198464, '/build/c2.go', 12, NULL, NULL, 0);
+INSERT INTO functions (binary_id, name, package, start_va, end_va, source_file, source_line, start_line, end_line, std) VALUES ((SELECT id FROM binaries WHERE sha256 = '5e1f' AND arch = 'amd64'), 'main.(*Beacon).Send', 'main', 4198464, 4198528, '/build/beacon.go', 40, NULL, NULL, 0);
+INSERT INTO functions (binary_id, name, package, start_va, end_va, source_file, source_line, start_line, end_line, std) VALUES ((SELECT id FROM binaries WHERE sha256 = '5e1f' AND arch = 'amd64'), 'main.Map[int]', 'main', 4198528, 4198592, '/build/util.go', 7, NULL, NULL, 0);
+INSERT INTO functions (binary_id, name, package, start_va, end_va, source_file, source_line, start_line, end_line, std) VALUES ((SELECT id FROM binaries WHERE sha256 = '5e1f' AND arch = 'amd64'), 'main.Map(int)', 'main', 4198592, 4198656, NULL, NULL, NULL, NULL, 0);
+INSERT INTO functions (binary_id, name, package, start_va, end_va, source_file, source_line, start_line, end_line, std) VALUES ((SELECT id FROM binaries WHERE sha256 = '5e1f' AND arch = 'amd64'), 'main.handleC2', 'main', 4198656, 4198720, NULL, NULL, NULL, NULL, 0);
+INSERT INTO functions (binary_id, name, package, start_va, end_va, source_file, source_line, start_line, end_line, std) VALUES ((SELECT id FROM binaries WHERE sha256 = '5e1f' AND arch = 'amd64'), 'main.gone', 'main', 5242880, 5242896, NULL, NULL, NULL, NULL, 0);
+INSERT INTO functions (binary_id, name, package, start_va, end_va, source_file, source_line, start_line, end_line, std) VALUES ((SELECT id FROM binaries WHERE sha256 = '5e1f' AND arch = 'amd64'), 'fmt.Println', 'fmt', 4202496, 4202512, '/usr/local/go/src/fmt/print.go', 313, NULL, NULL, 1);
+INSERT INTO types (binary_id, name, kind, size, va, interface) VALUES ((SELECT id FROM binaries WHERE sha256 = '5e1f' AND arch = 'amd64'), '*main.Beacon', 'Pointer', 8, 4849664, 0);
+INSERT INTO types (binary_id, name, kind, size, va, interface) VALUES ((SELECT id FROM binaries WHERE sha256 = '5e1f' AND arch = 'amd64'), 'map[string]"quoted"', NULL, 0, 4849920, 0);
+INSERT INTO types (binary_id, name, kind, size, va, interface) VALUES ((SELECT id FROM binaries WHERE sha256 = '5e1f' AND arch = 'amd64'), '*main.Beacon', NULL, 0, 4849664, 1);
+COMMIT;
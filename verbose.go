@@ -0,0 +1,14 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import "log"
+
+// set by -verbose to 1 and -vv to 2, the progress of the extraction is logged up to this level
+var verbosity int
+
+// logProgress is the goresym.Options.Log of -verbose, it logs to stderr so the output on stdout stays parseable
+func logProgress(level int, format string, args ...interface{}) {
+	if level <= verbosity {
+		log.Printf(format, args...)
+	}
+}
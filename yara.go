@@ -0,0 +1,249 @@
+/*Copyright (C) 2022 Mandiant, Inc. All Rights Reserved.*/
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mandiant/GoReSym/goresym"
+	"github.com/mandiant/GoReSym/objfile"
+)
+
+// shorter strings match too much unrelated data to be worth an atom
+const yaraMinLength = 8
+
+// the names every Go program has, they tell nothing about the sample
+var yaraGenericFunctions = []string{"main.main", "main.init"}
+
+// yaraSample is the results of one input, one per slice of a fat Mach-O
+type yaraSample struct {
+	fileName string
+	sha256   string
+	results  []goresym.Report
+}
+
+// extractYaraSample extracts the user functions of the file at path with opts, of every slice of a fat Mach-O unless opts.Arch picks one
+func extractYaraSample(path string, opts goresym.Options) (yaraSample, error) {
+	hash, err := fileSha256(path)
+	if err != nil {
+		return yaraSample{}, err
+	}
+	sample := yaraSample{fileName: path, sha256: hash}
+	archs, _ := objfile.FatArchs(path)
+	if len(archs) == 0 || len(opts.Arch) > 0 {
+		archs = []string{opts.Arch}
+	}
+	// a rule is built from the user functions, not from whatever the flags print
+	opts.StdFunctions, opts.FilePaths, opts.Types, opts.NoFunctions = false, false, false, false
+	opts.TypeAddress, opts.Timestamps = 0, false
+	for _, arch := range archs {
+		opts.Arch = arch
+		metadata, err := extractFile(path, opts)
+		if err != nil {
+			return yaraSample{}, err
+		}
+		sample.results = append(sample.results, metadata)
+	}
+	return sample, nil
+}
+
+// yaraAtoms are the candidate strings of a rule by kind, the most distinctive first
+type yaraAtoms struct {
+	modules   []string
+	functions []string
+	files     []string
+}
+
+// yaraString quotes s as a YARA text string, escaping the quotes, backslashes and the bytes that aren't printable
+func yaraString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// yaraIdentifier makes name a rule identifier, letters, digits and _ not starting with a digit, at most 128 characters
+func yaraIdentifier(name string) string {
+	id := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if len(id) == 0 || (id[0] >= '0' && id[0] <= '9') {
+		id = "_" + id
+	}
+	return truncateName(id, 128)
+}
+
+// distinctive orders strings by how well they tell a sample: those of the main module first, then the longest, dropping the
+// duplicates and those shorter than yaraMinLength
+func distinctive(strs map[string]bool) []string {
+	var sorted []string
+	for s := range strs {
+		if len(s) >= yaraMinLength {
+			sorted = append(sorted, s)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if strs[sorted[i]] != strs[sorted[j]] {
+			return strs[sorted[i]]
+		}
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) > len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
+
+// collectYaraAtoms gathers the module paths of the build info and the user function names and source files of results, every
+// candidate rather than the best few so samples can be intersected first
+func collectYaraAtoms(results []goresym.Report) yaraAtoms {
+	var atoms yaraAtoms
+	modules := make(map[string]bool)
+	functions := make(map[string]bool)
+	files := make(map[string]bool)
+	for _, metadata := range results {
+		if len(metadata.BuildInfo.Main.Path) > 0 {
+			modules[metadata.BuildInfo.Main.Path] = true
+		}
+		for _, dep := range metadata.BuildInfo.Deps {
+			if dep != nil && !modules[dep.Path] {
+				modules[dep.Path] = false
+			}
+		}
+		for _, fn := range metadata.UserFunctions {
+			if fn.Unmapped || fn.Origin == goresym.OriginStd || strings.HasPrefix(fn.FullName, "type:") || strings.HasPrefix(fn.FullName, "type..") {
+				continue
+			}
+			generic := false
+			for _, name := range yaraGenericFunctions {
+				generic = generic || fn.FullName == name
+			}
+			if !generic {
+				functions[fn.FullName] = functions[fn.FullName] || fn.Origin == goresym.OriginMain
+			}
+			if len(fn.SourceFile) > 0 && fn.SourceFile != "<autogenerated>" {
+				files[fn.SourceFile] = files[fn.SourceFile] || fn.Origin == goresym.OriginMain
+			}
+		}
+	}
+	atoms.modules = distinctive(modules)
+	atoms.functions = distinctive(functions)
+	atoms.files = distinctive(files)
+	return atoms
+}
+
+// intersect keeps the strings of a that are also in b, in the order of a
+func intersect(a []string, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, s := range b {
+		in[s] = true
+	}
+	var both []string
+	for _, s := range a {
+		if in[s] {
+			both = append(both, s)
+		}
+	}
+	return both
+}
+
+// printYaraRule writes a rule matching the atoms with at least half of them required, up to limit function names and source files
+// are kept and every module path
+func printYaraRule(b *strings.Builder, name string, samples []yaraSample, atoms yaraAtoms, limit int) error {
+	atoms.functions = atoms.functions[:min(limit, len(atoms.functions))]
+	atoms.files = atoms.files[:min(limit, len(atoms.files))]
+	count := len(atoms.modules) + len(atoms.functions) + len(atoms.files)
+	if count == 0 {
+		return fmt.Errorf("no strings for the rule %s, the samples share no module, user function or source file", name)
+	}
+
+	fmt.Fprintf(b, "rule %s\n{\n    meta:\n", yaraIdentifier(name))
+	var fileNames []string
+	versions := make(map[string]bool)
+	for _, sample := range samples {
+		fileNames = append(fileNames, filepath.Base(sample.fileName))
+		for _, metadata := range sample.results {
+			versions[metadata.Version] = true
+		}
+	}
+	fmt.Fprintf(b, "        description = %s\n", yaraString("Go metadata of "+strings.Join(fileNames, ", ")+" recovered by GoReSym"))
+	for _, sample := range samples {
+		fmt.Fprintf(b, "        sha256 = %s\n", yaraString(sample.sha256))
+	}
+	var goVersions []string
+	for version := range versions {
+		if len(version) > 0 {
+			goVersions = append(goVersions, version)
+		}
+	}
+	sort.Strings(goVersions)
+	if len(goVersions) > 0 {
+		fmt.Fprintf(b, "        go_version = %s\n", yaraString(strings.Join(goVersions, ", ")))
+	}
+
+	b.WriteString("    strings:\n")
+	for _, kind := range []struct {
+		prefix string
+		strs   []string
+	}{{"module", atoms.modules}, {"func", atoms.functions}, {"file", atoms.files}} {
+		for i, s := range kind.strs {
+			fmt.Fprintf(b, "        $%s%d = %s ascii\n", kind.prefix, i, yaraString(s))
+		}
+	}
+	fmt.Fprintf(b, "    condition:\n        %d of them\n}\n", max(1, count/2))
+	return nil
+}
+
+// printYara writes a rule per sample, or with a family name one rule of the module paths, function names and source files every
+// sample has
+func printYara(w io.Writer, samples []yaraSample, family string, limit int) error {
+	var b strings.Builder
+	b.WriteString("// Generated by GoReSym\n\n")
+	if len(family) > 0 {
+		var atoms yaraAtoms
+		for i, sample := range samples {
+			sampleAtoms := collectYaraAtoms(sample.results)
+			if i == 0 {
+				atoms = sampleAtoms
+				continue
+			}
+			atoms.modules = intersect(atoms.modules, sampleAtoms.modules)
+			atoms.functions = intersect(atoms.functions, sampleAtoms.functions)
+			atoms.files = intersect(atoms.files, sampleAtoms.files)
+		}
+		if err := printYaraRule(&b, family, samples, atoms, limit); err != nil {
+			return err
+		}
+	} else {
+		names := make(uniqueNames)
+		for i, sample := range samples {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			name := names.name("", yaraIdentifier("GoReSym_"+filepath.Base(sample.fileName)), uint64(i))
+			if err := printYaraRule(&b, name, []yaraSample{sample}, collectYaraAtoms(sample.results), limit); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}